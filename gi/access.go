@@ -0,0 +1,571 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gi
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/goki/ki/bitflag"
+	"github.com/goki/ki/ki"
+	"github.com/goki/ki/kit"
+)
+
+// The accessibility model exposes the semantic information about widgets
+// needed by assistive technologies (screen readers etc): each widget has a
+// role (button, checkbox, textbox...), a name and description, states
+// (checked, selected, focused...) and, for range widgets, a value with min
+// and max.  Widgets provide this via the Accessor interface, which has a
+// default implementation on WidgetBase that is extended by each widget type.
+// Apps can override the role, name and description with the "access-role",
+// "access-name" and "access-desc" properties.
+//
+// Use AccessNodeTree to get the tree of accessible nodes under a given node, and
+// AccessFind to query it.  To be notified of changes, call
+// Window.SetAccessOn(true) and connect to the Window AccessSig.
+
+// AccessRoles are the semantic roles of widgets for the accessibility model
+type AccessRoles int32
+
+const (
+	// AccessNone is for elements that have no semantic role, whose
+	// children are presented directly in their place
+	AccessNone AccessRoles = iota
+
+	// AccessGroup is a generic container of other elements
+	AccessGroup
+
+	// AccessWindow is the top-level node of a window
+	AccessWindow
+
+	// AccessDialog is a dialog window or popup
+	AccessDialog
+
+	// AccessButton is a push button
+	AccessButton
+
+	// AccessToggleButton is a button that can be checked (pressed) or not
+	AccessToggleButton
+
+	// AccessCheckBox is a checkbox
+	AccessCheckBox
+
+	// AccessMenuButton is a button that opens a menu
+	AccessMenuButton
+
+	// AccessMenuBar is the main menu bar
+	AccessMenuBar
+
+	// AccessMenu is a popup menu
+	AccessMenu
+
+	// AccessMenuItem is an item in a menu
+	AccessMenuItem
+
+	// AccessToolBar is a toolbar of actions
+	AccessToolBar
+
+	// AccessSeparator separates groups of items
+	AccessSeparator
+
+	// AccessLabel is static text
+	AccessLabel
+
+	// AccessImage is an image or icon
+	AccessImage
+
+	// AccessTextBox is an editable text field
+	AccessTextBox
+
+	// AccessSpinBox is a numeric entry with increment and decrement buttons
+	AccessSpinBox
+
+	// AccessSlider selects a value in a range
+	AccessSlider
+
+	// AccessScrollBar scrolls a view
+	AccessScrollBar
+
+	// AccessComboBox selects one of a list of items
+	AccessComboBox
+
+	// AccessProgressBar shows the progress of an operation
+	AccessProgressBar
+
+	// AccessTabList is the list of tabs of a tab view
+	AccessTabList
+
+	// AccessTab is one tab in a tab list
+	AccessTab
+
+	// AccessTabPanel is the content shown for the selected tab
+	AccessTabPanel
+
+	// AccessSplitPane is a container of panels with splitters between them
+	AccessSplitPane
+
+	// AccessSplitter is the handle between split panels
+	AccessSplitter
+
+	// AccessTree is a tree of items
+	AccessTree
+
+	// AccessTreeItem is an item in a tree
+	AccessTreeItem
+
+	// AccessList is a list of items
+	AccessList
+
+	// AccessTable is a table of rows and columns
+	AccessTable
+
+	// AccessForm is a set of labeled fields
+	AccessForm
+
+	// AccessDocument is a multi-line text document
+	AccessDocument
+
+	// AccessChart is a chart or plot
+	AccessChart
+
+	AccessRolesN
+)
+
+//go:generate stringer -type=AccessRoles
+
+var KiT_AccessRoles = kit.Enums.AddEnumAltLower(AccessRolesN, kit.NotBitFlag, nil, "Access")
+
+func (ev AccessRoles) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *AccessRoles) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+// AccessStates are the bit flags for the states of widgets in the
+// accessibility model
+type AccessStates int32
+
+const (
+	// AccessFocusable can receive the keyboard focus
+	AccessFocusable AccessStates = iota
+
+	// AccessFocused has the keyboard focus
+	AccessFocused
+
+	// AccessInactive is disabled -- cannot be interacted with
+	AccessInactive
+
+	// AccessInvisible is not currently shown
+	AccessInvisible
+
+	// AccessSelected is selected
+	AccessSelected
+
+	// AccessCheckable can be checked
+	AccessCheckable
+
+	// AccessChecked is checked
+	AccessChecked
+
+	// AccessExpandable can be expanded to show children
+	AccessExpandable
+
+	// AccessExpanded is expanded
+	AccessExpanded
+
+	// AccessReadOnly shows a value that cannot be edited
+	AccessReadOnly
+
+	// AccessHasPopup opens a popup menu or dialog
+	AccessHasPopup
+
+	// AccessMultiLine is a text box with multiple lines
+	AccessMultiLine
+
+	// AccessBusy is busy with an operation of unknown duration
+	AccessBusy
+
+	// AccessInvalid has an invalid value
+	AccessInvalid
+
+	AccessStatesN
+)
+
+//go:generate stringer -type=AccessStates
+
+var KiT_AccessStates = kit.Enums.AddEnumAltLower(AccessStatesN, kit.BitFlag, nil, "Access")
+
+func (ev AccessStates) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *AccessStates) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+// AccessSignals are the signals sent on the Window AccessSig when the
+// accessibility information of a node changes -- the sender is the node,
+// and the data is its new AccessInfo
+type AccessSignals int64
+
+const (
+	// AccessNameChanged means the name or description changed
+	AccessNameChanged AccessSignals = iota
+
+	// AccessStateChanged means the states changed
+	AccessStateChanged
+
+	// AccessValueChanged means the value or text changed
+	AccessValueChanged
+
+	// AccessStructureChanged means the children of the node changed --
+	// re-query its AccessNodeTree
+	AccessStructureChanged
+
+	AccessSignalsN
+)
+
+//go:generate stringer -type=AccessSignals
+
+var KiT_AccessSignals = kit.Enums.AddEnumAltLower(AccessSignalsN, kit.NotBitFlag, nil, "Access")
+
+// AccessInfo is the accessibility information for a node
+type AccessInfo struct {
+	Role     AccessRoles `desc:"semantic role of the node"`
+	Name     string      `desc:"accessible name -- e.g., the text of a button or label, or the placeholder of a text field -- plain text, without any HTML markup"`
+	Desc     string      `desc:"longer description, from the tooltip -- plain text"`
+	States   int64       `desc:"AccessStates bit flags"`
+	HasValue bool        `desc:"Value, Min and Max are valid (range widgets)"`
+	Value    float32     `desc:"current value of a range widget"`
+	Min      float32     `desc:"minimum value of a range widget"`
+	Max      float32     `desc:"maximum value of a range widget"`
+	Text     string      `desc:"current text value, e.g., of a text field or combobox"`
+	Level    int         `desc:"depth of a tree item in its tree, starting at 1 -- 0 for other nodes"`
+}
+
+// HasState returns true if given state flag is set
+func (ai *AccessInfo) HasState(st AccessStates) bool {
+	return bitflag.Has(ai.States, int(st))
+}
+
+// SetState sets given state flag(s) on or off
+func (ai *AccessInfo) SetState(on bool, st ...AccessStates) {
+	for _, s := range st {
+		bitflag.SetState(&ai.States, on, int(s))
+	}
+}
+
+// SetValue sets the value and range
+func (ai *AccessInfo) SetValue(val, min, max float32) {
+	ai.HasValue = true
+	ai.Value = val
+	ai.Min = min
+	ai.Max = max
+}
+
+// String returns a one-line summary of the info, e.g.,
+// button "OK" [focusable focused] -- used for UI tests
+func (ai AccessInfo) String() string {
+	var sb strings.Builder
+	sb.WriteString(kit.Enums.EnumIfaceToAltString(ai.Role))
+	if ai.Name != "" {
+		fmt.Fprintf(&sb, " %q", ai.Name)
+	}
+	if ai.Level > 0 {
+		fmt.Fprintf(&sb, " level=%d", ai.Level)
+	}
+	if ai.HasValue {
+		fmt.Fprintf(&sb, " value=%g (%g..%g)", ai.Value, ai.Min, ai.Max)
+	}
+	if ai.Text != "" {
+		fmt.Fprintf(&sb, " text=%q", ai.Text)
+	}
+	if ai.States != 0 {
+		sts := make([]string, 0, AccessStatesN)
+		for st := AccessFocusable; st < AccessStatesN; st++ {
+			if ai.HasState(st) {
+				sts = append(sts, kit.Enums.EnumIfaceToAltString(st))
+			}
+		}
+		fmt.Fprintf(&sb, " [%s]", strings.Join(sts, " "))
+	}
+	return sb.String()
+}
+
+// Accessor is the interface for nodes that provide accessibility
+// information -- all widgets do, via WidgetBase
+type Accessor interface {
+	// AccessInfo returns the accessibility information for this node
+	AccessInfo() AccessInfo
+}
+
+// AccessPartser is implemented by widgets whose parts are presented as
+// their accessible children, e.g., the checkboxes of a ButtonBox -- the
+// parts of other widgets are presented by the widget itself
+type AccessPartser interface {
+	// AccessParts returns the parts layout holding the accessible children
+	AccessParts() *Layout
+}
+
+// Access returns the accessibility information for given node, including
+// any AccessOverride properties -- nodes that are not Accessor (e.g., SVG
+// elements) have role AccessNone
+func Access(n ki.Ki) AccessInfo {
+	if n == nil || n.This() == nil {
+		return AccessInfo{}
+	}
+	ac, ok := n.This().(Accessor)
+	if !ok {
+		return AccessInfo{}
+	}
+	ai := ac.AccessInfo()
+	AccessOverride(n, &ai)
+	return ai
+}
+
+// AccessInfo returns the accessibility information common to all widgets:
+// the description from the Tooltip, and the focus, inactive, invisible and selected
+// states, with role AccessNone.  Widget types extend this with their own
+// info -- use Access to get the info including any override properties.
+func (wb *WidgetBase) AccessInfo() AccessInfo {
+	ai := AccessInfo{}
+	ai.Desc = AccessPlainText(wb.Tooltip)
+	ai.SetState(wb.CanFocus(), AccessFocusable)
+	ai.SetState(wb.HasFocus(), AccessFocused)
+	ai.SetState(wb.IsInactive(), AccessInactive)
+	ai.SetState(wb.IsInvisible(), AccessInvisible)
+	ai.SetState(wb.IsSelected(), AccessSelected)
+	return ai
+}
+
+// AccessOverride applies the "access-role", "access-name" and
+// "access-desc" properties set on given node to the info
+func AccessOverride(n ki.Ki, ai *AccessInfo) {
+	if rp := n.Prop("access-role"); rp != nil {
+		switch rv := rp.(type) {
+		case AccessRoles:
+			ai.Role = rv
+		case string:
+			kit.Enums.SetEnumIfaceFromStringAltFirst(&ai.Role, rv)
+		}
+	}
+	if np := n.Prop("access-name"); np != nil {
+		ai.Name = kit.ToString(np)
+	}
+	if dp := n.Prop("access-desc"); dp != nil {
+		ai.Desc = kit.ToString(dp)
+	}
+}
+
+// AccessPlainText returns given text (e.g., a label or tooltip, which can
+// have HTML formatting) as plain text, for use as an accessible name
+func AccessPlainText(txt string) string {
+	if !strings.ContainsAny(txt, "<&") {
+		return strings.TrimSpace(txt)
+	}
+	var sb strings.Builder
+	intag := false
+	for _, r := range txt {
+		switch {
+		case r == '<':
+			intag = true
+		case r == '>' && intag:
+			intag = false
+		case !intag:
+			sb.WriteRune(r)
+		}
+	}
+	return strings.TrimSpace(html.UnescapeString(sb.String()))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////
+// AccessNode tree and queries
+
+// AccessNode is a node in the tree of accessible nodes returned by AccessNodeTree
+type AccessNode struct {
+	Node Node2D        `desc:"the node"`
+	Info AccessInfo    `desc:"its accessibility info"`
+	Kids []*AccessNode `desc:"accessible children"`
+}
+
+// AccessNodeTree returns the tree of accessible nodes under given root node
+// (which is always included).  Nodes with role AccessNone are skipped, with
+// their children included in their place, as are the parts of widgets --
+// the widget presents the info for its parts.  Invisible nodes are skipped
+// unless inclInvis is true.
+func AccessNodeTree(root Node2D, inclInvis bool) *AccessNode {
+	an := &AccessNode{Node: root, Info: Access(root)}
+	an.Kids = accessKids(root, inclInvis, nil)
+	return an
+}
+
+// accessChildren returns the children of given node in the accessibility
+// tree, including the parts of an AccessPartser
+func accessChildren(n Node2D) ki.Slice {
+	if ap, ok := n.This().(AccessPartser); ok {
+		kids := append(ki.Slice{}, *n.Children()...)
+		return append(kids, *ap.AccessParts().Children()...)
+	}
+	return *n.Children()
+}
+
+// accessKids appends the accessible children of given node to kids
+func accessKids(n Node2D, inclInvis bool, kids []*AccessNode) []*AccessNode {
+	for _, k := range accessChildren(n) {
+		kn, ok := k.(Node2D)
+		if !ok || kn.IsDeleted() || kn.IsDestroyed() {
+			continue
+		}
+		if !inclInvis && kn.AsNode2D().IsInvisible() {
+			continue
+		}
+		ai := Access(kn)
+		if ai.Role == AccessNone {
+			kids = accessKids(kn, inclInvis, kids)
+			continue
+		}
+		kids = append(kids, &AccessNode{Node: kn, Info: ai, Kids: accessKids(kn, inclInvis, nil)})
+	}
+	return kids
+}
+
+// String returns an indented listing of the tree, one node per line
+// (see AccessInfo String) -- useful for UI tests
+func (an *AccessNode) String() string {
+	var sb strings.Builder
+	an.writeTo(&sb, 0)
+	return sb.String()
+}
+
+func (an *AccessNode) writeTo(sb *strings.Builder, depth int) {
+	sb.WriteString(strings.Repeat("  ", depth))
+	sb.WriteString(an.Info.String())
+	sb.WriteString("\n")
+	for _, k := range an.Kids {
+		k.writeTo(sb, depth+1)
+	}
+}
+
+// AccessFindFunc returns all the accessible nodes under given root
+// (including the root) for which fun returns true, in depth-first order
+func AccessFindFunc(root Node2D, fun func(n Node2D, ai *AccessInfo) bool) []Node2D {
+	var nodes []Node2D
+	var find func(an *AccessNode)
+	find = func(an *AccessNode) {
+		if fun(an.Node, &an.Info) {
+			nodes = append(nodes, an.Node)
+		}
+		for _, k := range an.Kids {
+			find(k)
+		}
+	}
+	find(AccessNodeTree(root, false))
+	return nodes
+}
+
+// AccessFind returns the visible accessible nodes under given root with
+// given role and name -- an empty name matches any name
+func AccessFind(root Node2D, role AccessRoles, name string) []Node2D {
+	return AccessFindFunc(root, func(n Node2D, ai *AccessInfo) bool {
+		return ai.Role == role && (name == "" || ai.Name == name)
+	})
+}
+
+// AccessOwner returns the widget that presents the accessibility info for
+// given node -- for the parts of a widget, that is the widget, otherwise the
+// node itself (parts of an AccessPartser are their own owners)
+func AccessOwner(n Node2D) Node2D {
+	own := n
+	for k := n.This(); k != nil; k = k.Parent() {
+		if !k.IsField() || k.Parent() == nil {
+			continue
+		}
+		if _, ok := k.Parent().This().(AccessPartser); ok {
+			continue
+		}
+		if pn, ok := k.Parent().This().(Node2D); ok {
+			own = pn
+		}
+	}
+	return own
+}
+
+////////////////////////////////////////////////////////////////////////////////////////
+// Window change notification
+
+// SetAccessOn turns on or off the tracking of changes in the accessibility
+// info of the nodes in this window, which are sent on AccessSig
+func (w *Window) SetAccessOn(on bool) {
+	w.AccessMu.Lock()
+	defer w.AccessMu.Unlock()
+	w.AccessOn = on
+	if on {
+		w.accessCache = make(map[Node2D]AccessInfo)
+	} else {
+		w.accessCache = nil
+	}
+}
+
+// IsAccessOn returns true if accessibility changes are being tracked
+func (w *Window) IsAccessOn() bool {
+	w.AccessMu.Lock()
+	defer w.AccessMu.Unlock()
+	return w.AccessOn
+}
+
+// AccessNodeUpdated is called by the viewport when given node has been
+// updated, to send the AccessSig signals for the changes in the
+// accessibility info of the node and its children
+func (w *Window) AccessNodeUpdated(n Node2D, sig int64, data interface{}) {
+	stru := sig != int64(ki.NodeSignalUpdated)
+	if !stru {
+		if dflags, ok := data.(int64); ok {
+			stru = bitflag.HasAnyMask(dflags, ki.StruUpdateFlagsMask)
+		}
+	}
+	own := AccessOwner(n)
+	if stru && own == n {
+		w.accessPrune()
+		w.AccessSig.Emit(own.This(), int64(AccessStructureChanged), Access(own))
+	}
+	w.accessUpdated(own)
+}
+
+// accessPrune removes deleted nodes from the cache
+func (w *Window) accessPrune() {
+	w.AccessMu.Lock()
+	defer w.AccessMu.Unlock()
+	for n := range w.accessCache {
+		if n.This() == nil || n.IsDeleted() || n.IsDestroyed() {
+			delete(w.accessCache, n)
+		}
+	}
+}
+
+// accessUpdated compares the info for given node and its children with the
+// cached info, and emits the changes
+func (w *Window) accessUpdated(n Node2D) {
+	if n.IsDeleted() || n.IsDestroyed() {
+		return
+	}
+	ai := Access(n)
+	w.AccessMu.Lock()
+	if w.accessCache == nil {
+		w.AccessMu.Unlock()
+		return
+	}
+	prv, had := w.accessCache[n]
+	w.accessCache[n] = ai
+	w.AccessMu.Unlock()
+	if had {
+		if prv.Name != ai.Name || prv.Desc != ai.Desc || prv.Role != ai.Role {
+			w.AccessSig.Emit(n.This(), int64(AccessNameChanged), ai)
+		}
+		if prv.States != ai.States {
+			w.AccessSig.Emit(n.This(), int64(AccessStateChanged), ai)
+		}
+		if prv.Value != ai.Value || prv.Min != ai.Min || prv.Max != ai.Max || prv.Text != ai.Text {
+			w.AccessSig.Emit(n.This(), int64(AccessValueChanged), ai)
+		}
+	}
+	for _, k := range accessChildren(n) {
+		if kn, ok := k.(Node2D); ok {
+			w.accessUpdated(kn)
+		}
+	}
+}
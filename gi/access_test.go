@@ -0,0 +1,59 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gi
+
+import (
+	"testing"
+)
+
+func TestAccessNodeTree(t *testing.T) {
+	fr := &Frame{}
+	fr.InitName(fr, "frame")
+	lay := AddNewLayout(fr, "lay", LayoutVert) // no role: flattened
+	lb := AddNewLabel(lay, "lbl", "<b>Name</b> &amp; more")
+	lb.Tooltip = "a label"
+	bt := AddNewButton(lay, "ok")
+	bt.Text = "OK"
+	ib := AddNewButton(lay, "icon")
+	ib.Icon = "close"
+	ib.Tooltip = "Close it"
+	cb := AddNewCheckBox(lay, "check")
+	cb.Text = "Check"
+	cb.SetChecked(true)
+	tf := AddNewTextField(lay, "tf")
+	tf.Placeholder = "enter name"
+	tf.SetText("abc")
+	tf.SetInactive()
+	sl := AddNewSlider(lay, "sl")
+	sl.Min, sl.Max, sl.Value = 0, 10, 3
+	sl.SetProp("access-name", "volume")
+	sep := AddNewSeparator(lay, "sep", true)
+	sep.SetInvisible()
+
+	an := AccessNodeTree(fr, false)
+	exp := `none
+  label "Name & more"
+  button "OK"
+  button "Close it"
+  checkbox "Check" [checkable checked]
+  textbox "enter name" text="abc" [focusable readonly]
+  slider "volume" value=3 (0..10)
+`
+	if str := an.String(); str != exp {
+		t.Errorf("AccessNodeTree:\n%s\nexpected:\n%s", str, exp)
+	}
+	if ns := AccessFind(fr, AccessCheckBox, ""); len(ns) != 1 || ns[0] != cb.This() {
+		t.Errorf("AccessFind checkbox: got %v", ns)
+	}
+	if ns := AccessFind(fr, AccessButton, "Close it"); len(ns) != 1 || ns[0] != ib.This() {
+		t.Errorf("AccessFind button: got %v", ns)
+	}
+	if ai := Access(lb); ai.Desc != "a label" {
+		t.Errorf("label desc: got %q", ai.Desc)
+	}
+	if n := len(AccessNodeTree(fr, true).Kids); n != 7 {
+		t.Errorf("AccessNodeTree with invisible: got %d kids, expected 7", n)
+	}
+}
@@ -48,6 +48,12 @@ var MenuBarProps = ki.Props{
 	"background-color": "linear-gradient(pref(Control), highlight-10)",
 }
 
+func (mb *MenuBar) AccessInfo() AccessInfo {
+	ai := mb.Layout.AccessInfo()
+	ai.Role = AccessMenuBar
+	return ai
+}
+
 // MenuBarStdRender does the standard rendering of the bar
 func (mb *MenuBar) MenuBarStdRender() {
 	rs, pc, st := mb.RenderLock()
@@ -332,6 +338,12 @@ var ToolBarProps = ki.Props{
 	"background-color": "linear-gradient(pref(Control), highlight-10)",
 }
 
+func (tb *ToolBar) AccessInfo() AccessInfo {
+	ai := tb.Layout.AccessInfo()
+	ai.Role = AccessToolBar
+	return ai
+}
+
 // AddAction adds an action to the toolbar using given options, and connects
 // the action signal to given receiver object and function, along with given
 // data which is stored on the action and then passed in the action signal.
@@ -51,6 +51,12 @@ func (bm *Bitmap) CopyFieldsFrom(frm interface{}) {
 	bm.Filename = fr.Filename
 }
 
+func (bm *Bitmap) AccessInfo() AccessInfo {
+	ai := bm.WidgetBase.AccessInfo()
+	ai.Role = AccessImage
+	return ai
+}
+
 // Resize resizes bitmap to given size
 func (bm *Bitmap) Resize(nwsz image.Point) {
 	if nwsz.X == 0 || nwsz.Y == 0 {
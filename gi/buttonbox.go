@@ -41,6 +41,18 @@ func (bb *ButtonBox) CopyFieldsFrom(frm interface{}) {
 	bb.Items = sliceclone.String(fr.Items)
 }
 
+func (bb *ButtonBox) AccessInfo() AccessInfo {
+	ai := bb.PartsWidgetBase.AccessInfo()
+	ai.Role = AccessGroup
+	return ai
+}
+
+// AccessParts returns the parts holding the checkboxes, which are
+// presented as the accessible children of the button box
+func (bb *ButtonBox) AccessParts() *Layout {
+	return &bb.Parts
+}
+
 func (bb *ButtonBox) Disconnect() {
 	bb.PartsWidgetBase.Disconnect()
 	bb.ButtonSig.DisconnectAll()
@@ -60,6 +60,33 @@ func (bb *ButtonBase) CopyFieldsFrom(frm interface{}) {
 	bb.Menu = fr.Menu
 }
 
+// AccessInfo returns the accessibility info for the button, named by its
+// Text, or by its Tooltip if it only has an icon
+func (bb *ButtonBase) AccessInfo() AccessInfo {
+	ai := bb.WidgetBase.AccessInfo()
+	switch {
+	case bb.HasFlag(int(ButtonFlagMenu)):
+		ai.Role = AccessMenuItem
+	case bb.HasMenu():
+		ai.Role = AccessMenuButton
+	case bb.IsCheckable():
+		ai.Role = AccessToggleButton
+	default:
+		ai.Role = AccessButton
+	}
+	ai.Name = AccessPlainText(bb.Text)
+	if ai.Name == "" {
+		ai.Name, ai.Desc = ai.Desc, ""
+		if ai.Name == "" && bb.Icon.IsValid() {
+			ai.Name = string(bb.Icon)
+		}
+	}
+	ai.SetState(bb.HasMenu(), AccessHasPopup)
+	ai.SetState(bb.IsCheckable(), AccessCheckable)
+	ai.SetState(bb.IsChecked(), AccessChecked)
+	return ai
+}
+
 func (bb *ButtonBase) Disconnect() {
 	bb.PartsWidgetBase.Disconnect()
 	bb.ButtonSig.DisconnectAll()
@@ -892,6 +919,13 @@ var CheckBoxProps = ki.Props{
 	},
 }
 
+func (cb *CheckBox) AccessInfo() AccessInfo {
+	ai := cb.ButtonBase.AccessInfo()
+	ai.Role = AccessCheckBox
+	ai.SetState(true, AccessCheckable)
+	return ai
+}
+
 // CheckBoxWidget interface
 
 func (cb *CheckBox) AsButtonBase() *ButtonBase {
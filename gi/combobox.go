@@ -48,6 +48,20 @@ func (cb *ComboBox) CopyFieldsFrom(frm interface{}) {
 	cb.MaxLength = fr.MaxLength
 }
 
+// AccessInfo returns the accessibility info for the combobox, with the
+// label of the current item as its Text
+func (cb *ComboBox) AccessInfo() AccessInfo {
+	ai := cb.ButtonBase.AccessInfo()
+	ai.Role = AccessComboBox
+	ai.Name = ""
+	if tf, ok := cb.TextField(); ok {
+		ai.Text = tf.Text()
+	} else if cb.CurVal != nil {
+		ai.Text = ToLabel(cb.CurVal)
+	}
+	return ai
+}
+
 func (cb *ComboBox) Disconnect() {
 	cb.ButtonBase.Disconnect()
 	cb.ComboSig.DisconnectAll()
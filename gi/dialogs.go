@@ -91,6 +91,14 @@ func ValidViewport(avp *Viewport2D) *Viewport2D {
 	return nil
 }
 
+func (dlg *Dialog) AccessInfo() AccessInfo {
+	ai := dlg.Viewport2D.AccessInfo()
+	ai.Role = AccessDialog
+	ai.Name = AccessPlainText(dlg.Title)
+	ai.Desc = AccessPlainText(dlg.Prompt)
+	return ai
+}
+
 // Open this dialog, in given location (0 = middle of window), finding window
 // from given viewport -- returns false if it fails for any reason.  optional
 // cvgFunc can perform additional configuration after the dialog window has
@@ -79,6 +79,8 @@ type EventMgr struct {
 	LastMousePos    image.Point                             `desc:"Last mouse position from most recent Mouse events"`
 	LagSkipDeltaPos image.Point                             `desc:"change in position accumulated from skipped-over laggy mouse move events"`
 	LagLastSkipped  bool                                    `desc:"true if last event was skipped due to lag"`
+	MacroRec        *KeyMacro                               `desc:"keyboard macro being recorded, if non-nil -- see Window.MacroRecordStart"`
+	macroPlaying    bool
 	startDrag       *mouse.DragEvent
 	dragStarted     bool
 	startDND        *mouse.DragEvent
@@ -68,6 +68,12 @@ var IconProps = ki.Props{
 	"background-color": color.Transparent,
 }
 
+func (ic *Icon) AccessInfo() AccessInfo {
+	ai := ic.WidgetBase.AccessInfo()
+	ai.Role = AccessImage
+	return ai
+}
+
 // SetIcon sets the icon by name into given Icon wrapper, returning error
 // message if not found etc, and returning true if a new icon was actually set
 // -- does nothing if UniqueNm is already == icon name and has children, and deletes
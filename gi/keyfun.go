@@ -88,6 +88,17 @@ const (
 	KeyFunWinClose
 	KeyFunWinSnapshot
 	KeyFunGoGiEditor
+	KeyFunFold           // fold (hide) the region at the cursor, e.g., in a TextView
+	KeyFunUnfold         // unfold the folded region at the cursor
+	KeyFunFoldAll        // fold all foldable regions
+	KeyFunUnfoldAll      // unfold all folded regions
+	KeyFunAddCursorAbove // add a cursor on the line above, e.g., in a TextView
+	KeyFunAddCursorBelow // add a cursor on the line below
+	KeyFunAddCursorNext  // add a cursor at the next occurrence of the selected text
+	KeyFunMacroRecord    // start recording a keyboard macro -- F3 in emacs
+	KeyFunMacroStop      // stop recording the keyboard macro -- F4 also stops it in emacs
+	KeyFunMacroPlay      // play the last recorded keyboard macro, or stop recording it if recording -- F4 in emacs
+	KeyFunMacroPlayN     // prompt for a named macro to play N times or to end of buffer
 	// Below are menu specific functions -- use these as shortcuts for menu actions
 	// allows uniqueness of mapping and easy customization of all key actions
 	KeyFunMenuNew
@@ -123,12 +134,20 @@ var ActiveKeyMap *KeyMap
 // ActiveKeyMapName is the name of the active keymap
 var ActiveKeyMapName KeyMapName
 
+// ActiveKeyMapVim is true if the active keymap uses vim-style modal editing
+// in text views, per its KeyMapsItem Vim flag
+var ActiveKeyMapVim bool
+
 // SetActiveKeyMap sets the current ActiveKeyMap, calling Update on the map
 // prior to setting it to ensure that it is a valid, complete map
 func SetActiveKeyMap(km *KeyMap, kmName KeyMapName) {
 	km.Update(kmName)
 	ActiveKeyMap = km
 	ActiveKeyMapName = kmName
+	ActiveKeyMapVim = false
+	if _, idx, ok := AvailKeyMaps.MapByName(kmName); ok {
+		ActiveKeyMapVim = AvailKeyMaps[idx].Vim
+	}
 }
 
 // SetActiveKeyMapName sets the current ActiveKeyMap by name from those
@@ -277,6 +296,7 @@ type KeyMapsItem struct {
 	Name string `width:"20" desc:"name of keymap"`
 	Desc string `desc:"description of keymap -- good idea to include source it was derived from"`
 	Map  KeyMap `desc:"to edit key sequence click button and type new key combination; to edit function mapped to key sequence choose from menu"`
+	Vim  bool   `desc:"use vim-style modal editing in text views -- the Map is then used in Insert mode, and by all other widgets"`
 }
 
 // Label satisfies the Labeler interface
@@ -599,6 +619,24 @@ var StdKeyMaps = KeyMaps{
 		"Shift+Control+G":         KeyFunWinSnapshot,
 		"Control+Alt+I":           KeyFunGoGiEditor,
 		"Shift+Control+I":         KeyFunGoGiEditor,
+		"Control+Alt+[":           KeyFunFold,
+		"Control+Alt+]":           KeyFunUnfold,
+		"Control+Alt+-":           KeyFunFoldAll,
+		"Control+Alt+=":           KeyFunUnfoldAll,
+		"Control+Alt+UpArrow":     KeyFunAddCursorAbove,
+		"Control+Alt+DownArrow":   KeyFunAddCursorBelow,
+		"Control+Alt+D":           KeyFunAddCursorNext,
+		"Control+Alt+9":           KeyFunMacroRecord,
+		"Control+Alt+0":           KeyFunMacroStop,
+		"Control+Alt+M":           KeyFunMacroPlay,
+		"Shift+Control+Alt+M":     KeyFunMacroPlayN,
+		"Alt+Meta+[":              KeyFunFold,
+		"Alt+Meta+]":              KeyFunUnfold,
+		"Alt+Meta+-":              KeyFunFoldAll,
+		"Alt+Meta+=":              KeyFunUnfoldAll,
+		"Alt+Meta+UpArrow":        KeyFunAddCursorAbove,
+		"Alt+Meta+DownArrow":      KeyFunAddCursorBelow,
+		"Alt+Meta+D":              KeyFunAddCursorNext,
 		"Meta+N":                  KeyFunMenuNew,
 		"Shift+Meta+N":            KeyFunMenuNewAlt1,
 		"Alt+Meta+N":              KeyFunMenuNewAlt2,
@@ -610,7 +648,7 @@ var StdKeyMaps = KeyMaps{
 		"Alt+Meta+S":              KeyFunMenuSaveAlt,
 		"Shift+Meta+W":            KeyFunMenuCloseAlt1,
 		"Alt+Meta+W":              KeyFunMenuCloseAlt2,
-	}},
+	}, false},
 	{"MacEmacs", "Mac with emacs-style navigation -- emacs wins in conflicts", KeyMap{
 		"UpArrow":                 KeyFunMoveUp,
 		"Shift+UpArrow":           KeyFunMoveUp,
@@ -733,6 +771,24 @@ var StdKeyMaps = KeyMaps{
 		"Shift+Control+G":         KeyFunWinSnapshot,
 		"Control+Alt+I":           KeyFunGoGiEditor,
 		"Shift+Control+I":         KeyFunGoGiEditor,
+		"Control+Alt+[":           KeyFunFold,
+		"Control+Alt+]":           KeyFunUnfold,
+		"Control+Alt+-":           KeyFunFoldAll,
+		"Control+Alt+=":           KeyFunUnfoldAll,
+		"Control+Alt+UpArrow":     KeyFunAddCursorAbove,
+		"Control+Alt+DownArrow":   KeyFunAddCursorBelow,
+		"Control+Alt+D":           KeyFunAddCursorNext,
+		"F3":                      KeyFunMacroRecord,
+		"Shift+F3":                KeyFunMacroStop,
+		"F4":                      KeyFunMacroPlay,
+		"Shift+F4":                KeyFunMacroPlayN,
+		"Alt+Meta+[":              KeyFunFold,
+		"Alt+Meta+]":              KeyFunUnfold,
+		"Alt+Meta+-":              KeyFunFoldAll,
+		"Alt+Meta+=":              KeyFunUnfoldAll,
+		"Alt+Meta+UpArrow":        KeyFunAddCursorAbove,
+		"Alt+Meta+DownArrow":      KeyFunAddCursorBelow,
+		"Alt+Meta+D":              KeyFunAddCursorNext,
 		"Meta+N":                  KeyFunMenuNew,
 		"Shift+Meta+N":            KeyFunMenuNewAlt1,
 		"Alt+Meta+N":              KeyFunMenuNewAlt2,
@@ -744,7 +800,7 @@ var StdKeyMaps = KeyMaps{
 		"Alt+Meta+S":              KeyFunMenuSaveAlt,
 		"Shift+Meta+W":            KeyFunMenuCloseAlt1,
 		"Alt+Meta+W":              KeyFunMenuCloseAlt2,
-	}},
+	}, false},
 	{"LinuxEmacs", "Linux with emacs-style navigation -- emacs wins in conflicts", KeyMap{
 		"UpArrow":                 KeyFunMoveUp,
 		"Shift+UpArrow":           KeyFunMoveUp,
@@ -852,6 +908,17 @@ var StdKeyMaps = KeyMaps{
 		"Shift+Control+G":         KeyFunWinSnapshot,
 		"Control+Alt+I":           KeyFunGoGiEditor,
 		"Shift+Control+I":         KeyFunGoGiEditor,
+		"Control+Alt+[":           KeyFunFold,
+		"Control+Alt+]":           KeyFunUnfold,
+		"Control+Alt+-":           KeyFunFoldAll,
+		"Control+Alt+=":           KeyFunUnfoldAll,
+		"Control+Alt+UpArrow":     KeyFunAddCursorAbove,
+		"Control+Alt+DownArrow":   KeyFunAddCursorBelow,
+		"Control+Alt+D":           KeyFunAddCursorNext,
+		"F3":                      KeyFunMacroRecord,
+		"Shift+F3":                KeyFunMacroStop,
+		"F4":                      KeyFunMacroPlay,
+		"Shift+F4":                KeyFunMacroPlayN,
 		"Alt+N":                   KeyFunMenuNew, // ctrl keys conflict..
 		"Shift+Alt+N":             KeyFunMenuNewAlt1,
 		"Control+Alt+N":           KeyFunMenuNewAlt2,
@@ -863,7 +930,7 @@ var StdKeyMaps = KeyMaps{
 		"Control+Alt+S":           KeyFunMenuSaveAlt,
 		"Shift+Alt+W":             KeyFunMenuCloseAlt1,
 		"Control+Alt+W":           KeyFunMenuCloseAlt2,
-	}},
+	}, false},
 	{"LinuxStd", "Standard Linux KeyMap", KeyMap{
 		"UpArrow":                 KeyFunMoveUp,
 		"Shift+UpArrow":           KeyFunMoveUp,
@@ -941,6 +1008,17 @@ var StdKeyMaps = KeyMaps{
 		"Control+Alt+G":           KeyFunWinSnapshot,
 		"Shift+Control+G":         KeyFunWinSnapshot,
 		"Shift+Control+I":         KeyFunGoGiEditor,
+		"Control+Alt+[":           KeyFunFold,
+		"Control+Alt+]":           KeyFunUnfold,
+		"Control+Alt+-":           KeyFunFoldAll,
+		"Control+Alt+=":           KeyFunUnfoldAll,
+		"Control+Alt+UpArrow":     KeyFunAddCursorAbove,
+		"Control+Alt+DownArrow":   KeyFunAddCursorBelow,
+		"Control+Alt+D":           KeyFunAddCursorNext,
+		"Control+Alt+9":           KeyFunMacroRecord,
+		"Control+Alt+0":           KeyFunMacroStop,
+		"Control+Alt+M":           KeyFunMacroPlay,
+		"Shift+Control+Alt+M":     KeyFunMacroPlayN,
 		"Shift+Control+N":         KeyFunMenuNewAlt1,
 		"Control+Alt+N":           KeyFunMenuNewAlt2,
 		"Control+O":               KeyFunMenuOpen,
@@ -951,7 +1029,7 @@ var StdKeyMaps = KeyMaps{
 		"Control+Alt+S":           KeyFunMenuSaveAlt,
 		"Shift+Control+W":         KeyFunMenuCloseAlt1,
 		"Control+Alt+W":           KeyFunMenuCloseAlt2,
-	}},
+	}, false},
 	{"WindowsStd", "Standard Windows KeyMap", KeyMap{
 		"UpArrow":                 KeyFunMoveUp,
 		"Shift+UpArrow":           KeyFunMoveUp,
@@ -1028,6 +1106,17 @@ var StdKeyMaps = KeyMaps{
 		"Control+Alt+G":           KeyFunWinSnapshot,
 		"Shift+Control+G":         KeyFunWinSnapshot,
 		"Shift+Control+I":         KeyFunGoGiEditor,
+		"Control+Alt+[":           KeyFunFold,
+		"Control+Alt+]":           KeyFunUnfold,
+		"Control+Alt+-":           KeyFunFoldAll,
+		"Control+Alt+=":           KeyFunUnfoldAll,
+		"Control+Alt+UpArrow":     KeyFunAddCursorAbove,
+		"Control+Alt+DownArrow":   KeyFunAddCursorBelow,
+		"Control+Alt+D":           KeyFunAddCursorNext,
+		"Control+Alt+9":           KeyFunMacroRecord,
+		"Control+Alt+0":           KeyFunMacroStop,
+		"Control+Alt+M":           KeyFunMacroPlay,
+		"Shift+Control+Alt+M":     KeyFunMacroPlayN,
 		"Control+N":               KeyFunMenuNew,
 		"Shift+Control+N":         KeyFunMenuNewAlt1,
 		"Control+Alt+N":           KeyFunMenuNewAlt2,
@@ -1039,7 +1128,7 @@ var StdKeyMaps = KeyMaps{
 		"Control+Alt+S":           KeyFunMenuSaveAlt,
 		"Shift+Control+W":         KeyFunMenuCloseAlt1,
 		"Control+Alt+W":           KeyFunMenuCloseAlt2,
-	}},
+	}, false},
 	{"ChromeStd", "Standard chrome-browser and linux-under-chrome bindings", KeyMap{
 		"UpArrow":                 KeyFunMoveUp,
 		"Shift+UpArrow":           KeyFunMoveUp,
@@ -1116,7 +1205,261 @@ var StdKeyMaps = KeyMaps{
 		"Control+Alt+G":           KeyFunWinSnapshot,
 		"Shift+Control+G":         KeyFunWinSnapshot,
 		"Shift+Control+I":         KeyFunGoGiEditor,
+		"Control+Alt+[":           KeyFunFold,
+		"Control+Alt+]":           KeyFunUnfold,
+		"Control+Alt+-":           KeyFunFoldAll,
+		"Control+Alt+=":           KeyFunUnfoldAll,
+		"Control+Alt+UpArrow":     KeyFunAddCursorAbove,
+		"Control+Alt+DownArrow":   KeyFunAddCursorBelow,
+		"Control+Alt+D":           KeyFunAddCursorNext,
+		"Control+Alt+9":           KeyFunMacroRecord,
+		"Control+Alt+0":           KeyFunMacroStop,
+		"Control+Alt+M":           KeyFunMacroPlay,
+		"Shift+Control+Alt+M":     KeyFunMacroPlayN,
+		"Control+N":               KeyFunMenuNew,
+		"Shift+Control+N":         KeyFunMenuNewAlt1,
+		"Control+Alt+N":           KeyFunMenuNewAlt2,
+		"Control+O":               KeyFunMenuOpen,
+		"Shift+Control+O":         KeyFunMenuOpenAlt1,
+		"Shift+Alt+O":             KeyFunMenuOpenAlt2,
+		"Control+S":               KeyFunMenuSave,
+		"Shift+Control+S":         KeyFunMenuSaveAs,
+		"Control+Alt+S":           KeyFunMenuSaveAlt,
+		"Shift+Control+W":         KeyFunMenuCloseAlt1,
+		"Control+Alt+W":           KeyFunMenuCloseAlt2,
+	}, false},
+	{"MacVim", "Mac with vim-style modal editing in text views -- MacStd in Insert mode", KeyMap{
+		"UpArrow":                 KeyFunMoveUp,
+		"Shift+UpArrow":           KeyFunMoveUp,
+		"Meta+UpArrow":            KeyFunMoveUp,
+		"Control+P":               KeyFunMoveUp,
+		"Shift+Control+P":         KeyFunMoveUp,
+		"Meta+Control+P":          KeyFunMoveUp,
+		"DownArrow":               KeyFunMoveDown,
+		"Shift+DownArrow":         KeyFunMoveDown,
+		"Meta+DownArrow":          KeyFunMoveDown,
+		"Control+N":               KeyFunMoveDown,
+		"Shift+Control+N":         KeyFunMoveDown,
+		"Meta+Control+N":          KeyFunMoveDown,
+		"RightArrow":              KeyFunMoveRight,
+		"Shift+RightArrow":        KeyFunMoveRight,
+		"Meta+RightArrow":         KeyFunEnd,
+		"Control+F":               KeyFunMoveRight,
+		"Shift+Control+F":         KeyFunMoveRight,
+		"Meta+Control+F":          KeyFunMoveRight,
+		"LeftArrow":               KeyFunMoveLeft,
+		"Shift+LeftArrow":         KeyFunMoveLeft,
+		"Meta+LeftArrow":          KeyFunHome,
+		"Control+B":               KeyFunMoveLeft,
+		"Shift+Control+B":         KeyFunMoveLeft,
+		"Meta+Control+B":          KeyFunMoveLeft,
+		"PageUp":                  KeyFunPageUp,
+		"Shift+PageUp":            KeyFunPageUp,
+		"Control+UpArrow":         KeyFunPageUp,
+		"Control+U":               KeyFunPageUp,
+		"PageDown":                KeyFunPageDown,
+		"Shift+PageDown":          KeyFunPageDown,
+		"Control+DownArrow":       KeyFunPageDown,
+		"Shift+Control+V":         KeyFunPageDown,
+		"Alt+√":                   KeyFunPageDown,
+		"Meta+Home":               KeyFunDocHome,
+		"Shift+Home":              KeyFunDocHome,
+		"Meta+H":                  KeyFunDocHome,
+		"Meta+End":                KeyFunDocEnd,
+		"Shift+End":               KeyFunDocEnd,
+		"Meta+L":                  KeyFunDocEnd,
+		"Control+RightArrow":      KeyFunWordRight,
+		"Control+LeftArrow":       KeyFunWordLeft,
+		"Alt+RightArrow":          KeyFunWordRight,
+		"Shift+Alt+RightArrow":    KeyFunWordRight,
+		"Alt+LeftArrow":           KeyFunWordLeft,
+		"Shift+Alt+LeftArrow":     KeyFunWordLeft,
+		"Home":                    KeyFunHome,
+		"Control+A":               KeyFunHome,
+		"Shift+Control+A":         KeyFunHome,
+		"End":                     KeyFunEnd,
+		"Control+E":               KeyFunEnd,
+		"Shift+Control+E":         KeyFunEnd,
+		"Tab":                     KeyFunFocusNext,
+		"Shift+Tab":               KeyFunFocusPrev,
+		"ReturnEnter":             KeyFunEnter,
+		"KeypadEnter":             KeyFunEnter,
+		"Meta+A":                  KeyFunSelectAll,
+		"Control+G":               KeyFunCancelSelect,
+		"Control+Spacebar":        KeyFunSelectMode,
+		"Control+ReturnEnter":     KeyFunAccept,
+		"Escape":                  KeyFunAbort,
+		"DeleteBackspace":         KeyFunBackspace,
+		"Control+DeleteBackspace": KeyFunBackspaceWord,
+		"Alt+DeleteBackspace":     KeyFunBackspaceWord,
+		"DeleteForward":           KeyFunDelete,
+		"Control+DeleteForward":   KeyFunDeleteWord,
+		"Alt+DeleteForward":       KeyFunDeleteWord,
+		"Control+D":               KeyFunDelete,
+		"Control+K":               KeyFunKill,
+		"Alt+∑":                   KeyFunCopy,
+		"Meta+C":                  KeyFunCopy,
+		"Control+W":               KeyFunCut,
+		"Meta+X":                  KeyFunCut,
+		"Control+Y":               KeyFunPaste,
+		"Control+V":               KeyFunPaste,
+		"Meta+V":                  KeyFunPaste,
+		"Shift+Meta+V":            KeyFunPasteHist,
+		"Alt+D":                   KeyFunDuplicate,
+		"Control+T":               KeyFunTranspose,
+		"Alt+T":                   KeyFunTransposeWord,
+		"Control+Z":               KeyFunUndo,
+		"Meta+Z":                  KeyFunUndo,
+		"Shift+Control+Z":         KeyFunRedo,
+		"Shift+Meta+Z":            KeyFunRedo,
+		"Control+I":               KeyFunInsert,
+		"Control+O":               KeyFunInsertAfter,
+		"Shift+Meta+=":            KeyFunZoomIn,
+		"Meta+=":                  KeyFunZoomIn,
+		"Meta+-":                  KeyFunZoomOut,
+		"Control+=":               KeyFunZoomIn,
+		"Shift+Control++":         KeyFunZoomIn,
+		"Shift+Meta+-":            KeyFunZoomOut,
+		"Control+-":               KeyFunZoomOut,
+		"Shift+Control+_":         KeyFunZoomOut,
+		"Control+Alt+P":           KeyFunPrefs,
+		"F5":                      KeyFunRefresh,
+		"Control+L":               KeyFunRecenter,
+		"Control+.":               KeyFunComplete,
+		"Control+,":               KeyFunLookup,
+		"Control+S":               KeyFunSearch,
+		"Meta+F":                  KeyFunFind,
+		"Meta+R":                  KeyFunReplace,
+		"Control+J":               KeyFunJump,
+		"Control+[":               KeyFunHistPrev,
+		"Control+]":               KeyFunHistNext,
+		"Meta+[":                  KeyFunHistPrev,
+		"Meta+]":                  KeyFunHistNext,
+		"F10":                     KeyFunMenu,
+		"Meta+`":                  KeyFunWinFocusNext,
+		"Meta+W":                  KeyFunWinClose,
+		"Control+Alt+G":           KeyFunWinSnapshot,
+		"Shift+Control+G":         KeyFunWinSnapshot,
+		"Control+Alt+I":           KeyFunGoGiEditor,
+		"Shift+Control+I":         KeyFunGoGiEditor,
+		"Control+Alt+[":           KeyFunFold,
+		"Control+Alt+]":           KeyFunUnfold,
+		"Control+Alt+-":           KeyFunFoldAll,
+		"Control+Alt+=":           KeyFunUnfoldAll,
+		"Control+Alt+UpArrow":     KeyFunAddCursorAbove,
+		"Control+Alt+DownArrow":   KeyFunAddCursorBelow,
+		"Control+Alt+D":           KeyFunAddCursorNext,
+		"Control+Alt+9":           KeyFunMacroRecord,
+		"Control+Alt+0":           KeyFunMacroStop,
+		"Control+Alt+M":           KeyFunMacroPlay,
+		"Shift+Control+Alt+M":     KeyFunMacroPlayN,
+		"Alt+Meta+[":              KeyFunFold,
+		"Alt+Meta+]":              KeyFunUnfold,
+		"Alt+Meta+-":              KeyFunFoldAll,
+		"Alt+Meta+=":              KeyFunUnfoldAll,
+		"Alt+Meta+UpArrow":        KeyFunAddCursorAbove,
+		"Alt+Meta+DownArrow":      KeyFunAddCursorBelow,
+		"Alt+Meta+D":              KeyFunAddCursorNext,
+		"Meta+N":                  KeyFunMenuNew,
+		"Shift+Meta+N":            KeyFunMenuNewAlt1,
+		"Alt+Meta+N":              KeyFunMenuNewAlt2,
+		"Meta+O":                  KeyFunMenuOpen,
+		"Shift+Meta+O":            KeyFunMenuOpenAlt1,
+		"Alt+Meta+O":              KeyFunMenuOpenAlt2,
+		"Meta+S":                  KeyFunMenuSave,
+		"Shift+Meta+S":            KeyFunMenuSaveAs,
+		"Alt+Meta+S":              KeyFunMenuSaveAlt,
+		"Shift+Meta+W":            KeyFunMenuCloseAlt1,
+		"Alt+Meta+W":              KeyFunMenuCloseAlt2,
+	}, true},
+	{"LinuxVim", "Linux with vim-style modal editing in text views -- LinuxStd in Insert mode", KeyMap{
+		"UpArrow":                 KeyFunMoveUp,
+		"Shift+UpArrow":           KeyFunMoveUp,
+		"DownArrow":               KeyFunMoveDown,
+		"Shift+DownArrow":         KeyFunMoveDown,
+		"RightArrow":              KeyFunMoveRight,
+		"Shift+RightArrow":        KeyFunMoveRight,
+		"LeftArrow":               KeyFunMoveLeft,
+		"Shift+LeftArrow":         KeyFunMoveLeft,
+		"PageUp":                  KeyFunPageUp,
+		"Shift+PageUp":            KeyFunPageUp,
+		"Control+UpArrow":         KeyFunPageUp,
+		"PageDown":                KeyFunPageDown,
+		"Shift+PageDown":          KeyFunPageDown,
+		"Control+DownArrow":       KeyFunPageDown,
+		"Home":                    KeyFunHome,
+		"Alt+LeftArrow":           KeyFunHome,
+		"End":                     KeyFunEnd,
+		"Alt+Home":                KeyFunDocHome,
+		"Shift+Home":              KeyFunDocHome,
+		"Alt+End":                 KeyFunDocEnd,
+		"Shift+End":               KeyFunDocEnd,
+		"Control+RightArrow":      KeyFunWordRight,
+		"Control+LeftArrow":       KeyFunWordLeft,
+		"Alt+RightArrow":          KeyFunEnd,
+		"Tab":                     KeyFunFocusNext,
+		"Shift+Tab":               KeyFunFocusPrev,
+		"ReturnEnter":             KeyFunEnter,
+		"KeypadEnter":             KeyFunEnter,
+		"Control+A":               KeyFunSelectAll,
+		"Shift+Control+A":         KeyFunCancelSelect,
+		"Control+G":               KeyFunCancelSelect,
+		"Control+Spacebar":        KeyFunSelectMode, // change input method / keyboard
+		"Control+ReturnEnter":     KeyFunAccept,
+		"Escape":                  KeyFunAbort,
+		"DeleteBackspace":         KeyFunBackspace,
+		"Control+DeleteBackspace": KeyFunBackspaceWord,
+		"DeleteForward":           KeyFunDelete,
+		"Control+DeleteForward":   KeyFunDeleteWord,
+		"Alt+DeleteForward":       KeyFunDeleteWord,
+		"Control+K":               KeyFunKill,
+		"Control+C":               KeyFunCopy,
+		"Control+X":               KeyFunCut,
+		"Control+V":               KeyFunPaste,
+		"Shift+Control+V":         KeyFunPasteHist,
+		"Alt+D":                   KeyFunDuplicate,
+		"Control+T":               KeyFunTranspose,
+		"Alt+T":                   KeyFunTransposeWord,
+		"Control+Z":               KeyFunUndo,
+		"Control+Y":               KeyFunRedo,
+		"Shift+Control+Z":         KeyFunRedo,
+		"Control+Alt+I":           KeyFunInsert,
+		"Control+Alt+O":           KeyFunInsertAfter,
+		"Control+=":               KeyFunZoomIn,
+		"Shift+Control++":         KeyFunZoomIn,
+		"Control+-":               KeyFunZoomOut,
+		"Shift+Control+_":         KeyFunZoomOut,
+		"Shift+Control+P":         KeyFunPrefs,
+		"Control+Alt+P":           KeyFunPrefs,
+		"F5":                      KeyFunRefresh,
+		"Control+L":               KeyFunRecenter,
+		"Control+.":               KeyFunComplete,
+		"Control+,":               KeyFunLookup,
+		"Alt+S":                   KeyFunSearch,
+		"Control+F":               KeyFunFind,
+		"Control+H":               KeyFunReplace,
+		"Control+R":               KeyFunReplace,
+		"Control+J":               KeyFunJump,
+		"Control+[":               KeyFunHistPrev,
+		"Control+]":               KeyFunHistNext,
 		"Control+N":               KeyFunMenuNew,
+		"F10":                     KeyFunMenu,
+		"Alt+F6":                  KeyFunWinFocusNext,
+		"Control+W":               KeyFunWinClose,
+		"Control+Alt+G":           KeyFunWinSnapshot,
+		"Shift+Control+G":         KeyFunWinSnapshot,
+		"Shift+Control+I":         KeyFunGoGiEditor,
+		"Control+Alt+[":           KeyFunFold,
+		"Control+Alt+]":           KeyFunUnfold,
+		"Control+Alt+-":           KeyFunFoldAll,
+		"Control+Alt+=":           KeyFunUnfoldAll,
+		"Control+Alt+UpArrow":     KeyFunAddCursorAbove,
+		"Control+Alt+DownArrow":   KeyFunAddCursorBelow,
+		"Control+Alt+D":           KeyFunAddCursorNext,
+		"Control+Alt+9":           KeyFunMacroRecord,
+		"Control+Alt+0":           KeyFunMacroStop,
+		"Control+Alt+M":           KeyFunMacroPlay,
+		"Shift+Control+Alt+M":     KeyFunMacroPlayN,
 		"Shift+Control+N":         KeyFunMenuNewAlt1,
 		"Control+Alt+N":           KeyFunMenuNewAlt2,
 		"Control+O":               KeyFunMenuOpen,
@@ -1127,5 +1470,5 @@ var StdKeyMaps = KeyMaps{
 		"Control+Alt+S":           KeyFunMenuSaveAlt,
 		"Shift+Control+W":         KeyFunMenuCloseAlt1,
 		"Control+Alt+W":           KeyFunMenuCloseAlt2,
-	}},
+	}, true},
 }
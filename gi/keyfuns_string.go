@@ -68,23 +68,33 @@ func _() {
 	_ = x[KeyFunWinClose-52]
 	_ = x[KeyFunWinSnapshot-53]
 	_ = x[KeyFunGoGiEditor-54]
-	_ = x[KeyFunMenuNew-55]
-	_ = x[KeyFunMenuNewAlt1-56]
-	_ = x[KeyFunMenuNewAlt2-57]
-	_ = x[KeyFunMenuOpen-58]
-	_ = x[KeyFunMenuOpenAlt1-59]
-	_ = x[KeyFunMenuOpenAlt2-60]
-	_ = x[KeyFunMenuSave-61]
-	_ = x[KeyFunMenuSaveAs-62]
-	_ = x[KeyFunMenuSaveAlt-63]
-	_ = x[KeyFunMenuCloseAlt1-64]
-	_ = x[KeyFunMenuCloseAlt2-65]
-	_ = x[KeyFunsN-66]
+	_ = x[KeyFunFold-55]
+	_ = x[KeyFunUnfold-56]
+	_ = x[KeyFunFoldAll-57]
+	_ = x[KeyFunUnfoldAll-58]
+	_ = x[KeyFunAddCursorAbove-59]
+	_ = x[KeyFunAddCursorBelow-60]
+	_ = x[KeyFunAddCursorNext-61]
+	_ = x[KeyFunMacroRecord-62]
+	_ = x[KeyFunMacroStop-63]
+	_ = x[KeyFunMacroPlay-64]
+	_ = x[KeyFunMacroPlayN-65]
+	_ = x[KeyFunMenuNew-66]
+	_ = x[KeyFunMenuNewAlt1-67]
+	_ = x[KeyFunMenuNewAlt2-68]
+	_ = x[KeyFunMenuOpen-69]
+	_ = x[KeyFunMenuOpenAlt1-70]
+	_ = x[KeyFunMenuOpenAlt2-71]
+	_ = x[KeyFunMenuSave-72]
+	_ = x[KeyFunMenuSaveAs-73]
+	_ = x[KeyFunMenuSaveAlt-74]
+	_ = x[KeyFunMenuCloseAlt1-75]
+	_ = x[KeyFunMenuCloseAlt2-76]
 }
 
-const _KeyFuns_name = "KeyFunNilKeyFunMoveUpKeyFunMoveDownKeyFunMoveRightKeyFunMoveLeftKeyFunPageUpKeyFunPageDownKeyFunHomeKeyFunEndKeyFunDocHomeKeyFunDocEndKeyFunWordRightKeyFunWordLeftKeyFunFocusNextKeyFunFocusPrevKeyFunEnterKeyFunAcceptKeyFunCancelSelectKeyFunSelectModeKeyFunSelectAllKeyFunAbortKeyFunCopyKeyFunCutKeyFunPasteKeyFunPasteHistKeyFunBackspaceKeyFunBackspaceWordKeyFunDeleteKeyFunDeleteWordKeyFunKillKeyFunDuplicateKeyFunTransposeKeyFunTransposeWordKeyFunUndoKeyFunRedoKeyFunInsertKeyFunInsertAfterKeyFunZoomOutKeyFunZoomInKeyFunPrefsKeyFunRefreshKeyFunRecenterKeyFunCompleteKeyFunLookupKeyFunSearchKeyFunFindKeyFunReplaceKeyFunJumpKeyFunHistPrevKeyFunHistNextKeyFunMenuKeyFunWinFocusNextKeyFunWinCloseKeyFunWinSnapshotKeyFunGoGiEditorKeyFunMenuNewKeyFunMenuNewAlt1KeyFunMenuNewAlt2KeyFunMenuOpenKeyFunMenuOpenAlt1KeyFunMenuOpenAlt2KeyFunMenuSaveKeyFunMenuSaveAsKeyFunMenuSaveAltKeyFunMenuCloseAlt1KeyFunMenuCloseAlt2KeyFunsN"
+const _KeyFuns_name = "KeyFunNilKeyFunMoveUpKeyFunMoveDownKeyFunMoveRightKeyFunMoveLeftKeyFunPageUpKeyFunPageDownKeyFunHomeKeyFunEndKeyFunDocHomeKeyFunDocEndKeyFunWordRightKeyFunWordLeftKeyFunFocusNextKeyFunFocusPrevKeyFunEnterKeyFunAcceptKeyFunCancelSelectKeyFunSelectModeKeyFunSelectAllKeyFunAbortKeyFunCopyKeyFunCutKeyFunPasteKeyFunPasteHistKeyFunBackspaceKeyFunBackspaceWordKeyFunDeleteKeyFunDeleteWordKeyFunKillKeyFunDuplicateKeyFunTransposeKeyFunTransposeWordKeyFunUndoKeyFunRedoKeyFunInsertKeyFunInsertAfterKeyFunZoomOutKeyFunZoomInKeyFunPrefsKeyFunRefreshKeyFunRecenterKeyFunCompleteKeyFunLookupKeyFunSearchKeyFunFindKeyFunReplaceKeyFunJumpKeyFunHistPrevKeyFunHistNextKeyFunMenuKeyFunWinFocusNextKeyFunWinCloseKeyFunWinSnapshotKeyFunGoGiEditorKeyFunFoldKeyFunUnfoldKeyFunFoldAllKeyFunUnfoldAllKeyFunAddCursorAboveKeyFunAddCursorBelowKeyFunAddCursorNextKeyFunMacroRecordKeyFunMacroStopKeyFunMacroPlayKeyFunMacroPlayNKeyFunMenuNewKeyFunMenuNewAlt1KeyFunMenuNewAlt2KeyFunMenuOpenKeyFunMenuOpenAlt1KeyFunMenuOpenAlt2KeyFunMenuSaveKeyFunMenuSaveAsKeyFunMenuSaveAltKeyFunMenuCloseAlt1KeyFunMenuCloseAlt2KeyFunsN"
 
-var _KeyFuns_index = [...]uint16{0, 9, 21, 35, 50, 64, 76, 90, 100, 109, 122, 134, 149, 163, 178, 193, 204, 216, 234, 250, 265, 276, 286, 295, 306, 321, 336, 355, 367, 383, 393, 408, 423, 442, 452, 462, 474, 491, 504, 516, 527, 540, 554, 568, 580, 592, 602, 615, 625, 639, 653, 663, 681, 695, 712, 728, 741, 758, 775, 789, 807, 825, 839, 855, 872, 891, 910, 918}
+var _KeyFuns_index = [...]uint16{0, 9, 21, 35, 50, 64, 76, 90, 100, 109, 122, 134, 149, 163, 178, 193, 204, 216, 234, 250, 265, 276, 286, 295, 306, 321, 336, 355, 367, 383, 393, 408, 423, 442, 452, 462, 474, 491, 504, 516, 527, 540, 554, 568, 580, 592, 602, 615, 625, 639, 653, 663, 681, 695, 712, 728, 738, 750, 763, 778, 798, 818, 837, 854, 869, 884, 900, 913, 930, 947, 961, 979, 997, 1011, 1027, 1044, 1063, 1082, 1090}
 
 func (i KeyFuns) String() string {
 	if i < 0 || i >= KeyFuns(len(_KeyFuns_index)-1) {
@@ -0,0 +1,343 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/goki/gi/oswin"
+	"github.com/goki/gi/oswin/key"
+	"github.com/goki/gi/units"
+	"github.com/goki/ki/ki"
+)
+
+// KeyMacroStep is one step of a KeyMacro: either a key chord, which is
+// replayed as a key.ChordEvent, or a named command that is performed
+// directly by the focus widget (see KeyMacroTarget) -- commands record
+// interactions such as dialog input that are not themselves key chords.
+type KeyMacroStep struct {
+	Chord key.Chord `json:",omitempty" desc:"key chord replayed as a key event"`
+	Cmd   string    `json:",omitempty" desc:"name of command performed by the focus widget, if Chord is empty"`
+	Args  []string  `json:",omitempty" desc:"arguments for the command"`
+}
+
+// ChordEvent returns a new key.ChordEvent for the Chord of this step
+func (ks *KeyMacroStep) ChordEvent() *key.ChordEvent {
+	ke := &key.ChordEvent{}
+	ke.SetTime()
+	ke.Action = key.Press
+	mods, cs := key.ModsFmString(string(ks.Chord))
+	ke.Modifiers = mods
+	rs := []rune(cs)
+	if len(rs) == 1 {
+		ke.Rune = rs[0]
+		if mods != 0 && !key.HasAnyModifierBits(mods, key.Shift) {
+			ke.Rune = []rune(strings.ToLower(cs))[0] // modified chords are uppercase
+		}
+	} else {
+		ke.Rune = -1
+		ke.Code = KeyCodeByName(cs)
+	}
+	return ke
+}
+
+// keyCodeNames is a lazily-built map from key code names (without the
+// Code prefix) to codes
+var keyCodeNames map[string]key.Codes
+
+// KeyCodeByName returns the key code for given name as used in key chords,
+// i.e., without the Code prefix (e.g., ReturnEnter), or CodeUnknown
+func KeyCodeByName(nm string) key.Codes {
+	if keyCodeNames == nil {
+		keyCodeNames = make(map[string]key.Codes)
+		for c := key.CodeUnknown; c <= key.CodeCompose; c++ {
+			cs := c.String()
+			if strings.HasPrefix(cs, "Code") {
+				keyCodeNames[strings.TrimPrefix(cs, "Code")] = c
+			}
+		}
+	}
+	return keyCodeNames[nm]
+}
+
+// KeyMacro is a named sequence of key chords (and commands) that can be
+// recorded and played back, e.g., emacs-style keyboard macros
+type KeyMacro struct {
+	Name  string         `width:"20" desc:"name of the macro"`
+	Desc  string         `desc:"description of what the macro does"`
+	Steps []KeyMacroStep `desc:"the steps of the macro, in order"`
+}
+
+// Label satisfies the Labeler interface
+func (km KeyMacro) Label() string {
+	return km.Name
+}
+
+// KeyMacros is a list of KeyMacro's -- these are saved and loaded along
+// with the preferences
+type KeyMacros []*KeyMacro
+
+// AvailKeyMacros is the current list of available keyboard macros
+var AvailKeyMacros KeyMacros
+
+// LastKeyMacroName is the name under which the most recently recorded macro
+// is stored -- rename it to keep it around
+var LastKeyMacroName = "Last"
+
+// KeyMacroMaxRepeat is the maximum number of times a macro is repeated when
+// playing it to the end of the buffer
+var KeyMacroMaxRepeat = 10000
+
+// MacroByName returns a macro by name, or nil if not found
+func (km *KeyMacros) MacroByName(name string) *KeyMacro {
+	for _, m := range *km {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// Add adds given macro to the list, replacing any existing one of the same name
+func (km *KeyMacros) Add(mac *KeyMacro) {
+	for i, m := range *km {
+		if m.Name == mac.Name {
+			(*km)[i] = mac
+			return
+		}
+	}
+	*km = append(*km, mac)
+}
+
+// Names returns the names of the macros
+func (km *KeyMacros) Names() []string {
+	nms := make([]string, len(*km))
+	for i, m := range *km {
+		nms[i] = m.Name
+	}
+	return nms
+}
+
+// PrefsKeyMacrosFileName is the name of the preferences file in GoGi prefs
+// directory for saving / loading the AvailKeyMacros list
+var PrefsKeyMacrosFileName = "key_macros_prefs.json"
+
+// OpenJSON opens macros from a JSON-formatted file.
+func (km *KeyMacros) OpenJSON(filename FileName) error {
+	b, err := ioutil.ReadFile(string(filename))
+	if err != nil {
+		return err
+	}
+	*km = make(KeyMacros, 0, 10) // reset
+	return json.Unmarshal(b, km)
+}
+
+// SaveJSON saves macros to a JSON-formatted file.
+func (km *KeyMacros) SaveJSON(filename FileName) error {
+	b, err := json.MarshalIndent(km, "", "  ")
+	if err != nil {
+		log.Println(err) // unlikely
+		return err
+	}
+	err = ioutil.WriteFile(string(filename), b, 0644)
+	if err != nil {
+		log.Println(err)
+	}
+	return err
+}
+
+// OpenPrefs opens KeyMacros from GoGi standard prefs directory, using PrefsKeyMacrosFileName
+func (km *KeyMacros) OpenPrefs() error {
+	pdir := oswin.TheApp.GoGiPrefsDir()
+	pnm := filepath.Join(pdir, PrefsKeyMacrosFileName)
+	return km.OpenJSON(FileName(pnm))
+}
+
+// SavePrefs saves KeyMacros to GoGi standard prefs directory, using PrefsKeyMacrosFileName
+func (km *KeyMacros) SavePrefs() error {
+	pdir := oswin.TheApp.GoGiPrefsDir()
+	pnm := filepath.Join(pdir, PrefsKeyMacrosFileName)
+	return km.SaveJSON(FileName(pnm))
+}
+
+// KeyMacroTarget is an optional interface for focus widgets with
+// macro-specific behavior, e.g., giv.TextView
+type KeyMacroTarget interface {
+	// KeyMacroCmd performs a command step recorded with
+	// EventMgr.RecordMacroCmd, returning false if the command is not known
+	KeyMacroCmd(cmd string, args []string) bool
+
+	// KeyMacroAtEnd returns true if the widget has reached the end of its
+	// content (e.g., the end of the buffer), which stops repeated playback
+	KeyMacroAtEnd() bool
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//   Recording
+
+// IsRecordingMacro returns true if a keyboard macro is being recorded
+func (em *EventMgr) IsRecordingMacro() bool {
+	return em.MacroRec != nil
+}
+
+// RecordMacroChord records the chord of given event if a macro is being recorded
+func (em *EventMgr) RecordMacroChord(e *key.ChordEvent) {
+	if em.MacroRec == nil {
+		return
+	}
+	em.MacroRec.Steps = append(em.MacroRec.Steps, KeyMacroStep{Chord: e.Chord()})
+}
+
+// RecordMacroCmd records a command step if a macro is being recorded,
+// replacing the last recorded chord, which is assumed to have started the
+// command interactively (e.g., by opening a dialog) -- an empty cmd just
+// removes that chord, e.g., when the dialog was canceled.
+func (em *EventMgr) RecordMacroCmd(cmd string, args ...string) {
+	mr := em.MacroRec
+	if mr == nil {
+		return
+	}
+	if n := len(mr.Steps); n > 0 && mr.Steps[n-1].Chord != "" {
+		mr.Steps = mr.Steps[:n-1]
+	}
+	if cmd != "" {
+		mr.Steps = append(mr.Steps, KeyMacroStep{Cmd: cmd, Args: args})
+	}
+}
+
+// MacroRecordStart starts recording a new keyboard macro in this window
+func (w *Window) MacroRecordStart() {
+	w.EventMgr.MacroRec = &KeyMacro{}
+	if KeyEventTrace {
+		fmt.Printf("Win: %v recording key macro\n", w.Nm)
+	}
+}
+
+// MacroRecordStop stops recording the keyboard macro, and stores it in
+// AvailKeyMacros under LastKeyMacroName
+func (w *Window) MacroRecordStop() {
+	mr := w.EventMgr.MacroRec
+	if mr == nil {
+		return
+	}
+	w.EventMgr.MacroRec = nil
+	if len(mr.Steps) == 0 {
+		return
+	}
+	mr.Name = LastKeyMacroName
+	AvailKeyMacros.Add(mr)
+	if KeyEventTrace {
+		fmt.Printf("Win: %v recorded key macro of %d steps\n", w.Nm, len(mr.Steps))
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//   Playback
+
+// PlayKeyMacro plays given macro n times, or if n <= 0, until the focus
+// widget reaches the end of its content (see KeyMacroTarget), to a maximum
+// of KeyMacroMaxRepeat times
+func (w *Window) PlayKeyMacro(km *KeyMacro, n int) {
+	if km == nil || len(km.Steps) == 0 || w.EventMgr.macroPlaying {
+		return
+	}
+	w.EventMgr.macroPlaying = true
+	defer func() { w.EventMgr.macroPlaying = false }()
+	toEnd := n <= 0
+	if toEnd {
+		n = KeyMacroMaxRepeat
+	}
+	updt := w.UpdateStart()
+	defer w.UpdateEnd(updt)
+	for i := 0; i < n; i++ {
+		if toEnd && w.keyMacroAtEnd() {
+			return
+		}
+		for si := range km.Steps {
+			ks := &km.Steps[si]
+			if ks.Chord != "" {
+				w.ProcessEvent(ks.ChordEvent())
+				continue
+			}
+			if kt, ok := w.EventMgr.CurFocus().(KeyMacroTarget); ok {
+				if !kt.KeyMacroCmd(ks.Cmd, ks.Args) {
+					log.Printf("gi.Window PlayKeyMacro: command not known to focus widget: %v\n", ks.Cmd)
+					return
+				}
+				if mr := w.EventMgr.MacroRec; mr != nil {
+					mr.Steps = append(mr.Steps, *ks)
+				}
+			}
+		}
+	}
+}
+
+// keyMacroAtEnd returns true if the focus widget is at the end of its content
+func (w *Window) keyMacroAtEnd() bool {
+	if kt, ok := w.EventMgr.CurFocus().(KeyMacroTarget); ok {
+		return kt.KeyMacroAtEnd()
+	}
+	return false
+}
+
+// KeyMacroPlayPrompt prompts for a macro to play and the number of times to
+// play it, with 0 playing it until the end of the buffer -- the macro can
+// also be saved under a new name, e.g., to keep the Last macro around
+func (w *Window) KeyMacroPlayPrompt() {
+	if len(AvailKeyMacros) == 0 {
+		PromptDialog(w.Viewport, DlgOpts{Title: "No Keyboard Macros", Prompt: "There are no keyboard macros to play -- record one first"}, AddOk, NoCancel, nil, nil)
+		return
+	}
+	dlg := NewStdDialog(DlgOpts{Title: "Play Keyboard Macro", Prompt: "Select the macro to play, and the number of times to play it -- 0 plays it repeatedly until the end of the buffer"}, AddOk, AddCancel)
+	dlg.Modal = true
+
+	frame := dlg.Frame()
+	_, prIdx := dlg.PromptWidget(frame)
+	cb := frame.InsertNewChild(KiT_ComboBox, prIdx+1, "macro").(*ComboBox)
+	cb.SetMinPrefWidth(units.NewCh(30))
+	cb.ItemsFromStringList(AvailKeyMacros.Names(), true, 0)
+	if AvailKeyMacros.MacroByName(LastKeyMacroName) != nil {
+		cb.SetCurVal(LastKeyMacroName)
+	}
+	sb := frame.InsertNewChild(KiT_SpinBox, prIdx+2, "count").(*SpinBox)
+	sb.Defaults()
+	sb.SetMin(0)
+	sb.SetValue(1)
+	tf := frame.InsertNewChild(KiT_TextField, prIdx+3, "save-as").(*TextField)
+	tf.Placeholder = "optional name to save a copy of the macro under"
+	tf.SetMinPrefWidth(units.NewCh(30))
+
+	dlg.DialogSig.Connect(w.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+		if sig != int64(DialogAccepted) {
+			return
+		}
+		nm, _ := cb.CurVal.(string)
+		w.KeyMacroPlayAccept(nm, int(sb.Value), tf.Text())
+	})
+	dlg.UpdateEndNoSig(true)
+	dlg.Open(0, 0, w.Viewport, nil)
+}
+
+// KeyMacroPlayAccept plays the macro of given name n times, as accepted in
+// KeyMacroPlayPrompt, first saving a copy of it under saveAs if that is a
+// different name.  The macro plays after the prompt has closed (using
+// PostUIFunc), so that its key events go to the focus widget of the window,
+// and not to the prompt, which is still open when it is accepted.
+func (w *Window) KeyMacroPlayAccept(nm string, n int, saveAs string) {
+	km := AvailKeyMacros.MacroByName(nm)
+	if km != nil && saveAs != "" && saveAs != nm {
+		cp := *km
+		cp.Name = saveAs
+		AvailKeyMacros.Add(&cp)
+	}
+	PostUIFunc(w, func() {
+		w.PlayKeyMacro(km, n)
+	})
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/goki/gi/oswin/key"
+)
+
+func TestKeyMacroChords(t *testing.T) {
+	for _, ch := range []key.Chord{"a", "Shift+A", "Control+S", "Shift+Control+Alt+M", "ReturnEnter", "Shift+DownArrow", "Control+Spacebar", "+", "Shift++"} {
+		ks := KeyMacroStep{Chord: ch}
+		ke := ks.ChordEvent()
+		if got := ke.Chord(); got != ch {
+			t.Errorf("chord %q replayed as: %q", ch, got)
+		}
+	}
+	ks := KeyMacroStep{Chord: "Control+S"}
+	if r := ks.ChordEvent().Rune; r != 's' {
+		t.Errorf("Control+S rune: %q", r)
+	}
+}
+
+func TestKeyMacroRecord(t *testing.T) {
+	em := &EventMgr{}
+	rec := func(ch key.Chord) {
+		ks := KeyMacroStep{Chord: ch}
+		em.RecordMacroChord(ks.ChordEvent())
+	}
+	rec("x") // not recording
+	em.MacroRec = &KeyMacro{}
+	rec("a")
+	rec("Meta+R") // opens query-replace dialog
+	em.RecordMacroCmd("QReplace", "a", "b", "false")
+	rec("!")
+	rec("Control+X") // dialog canceled
+	em.RecordMacroCmd("")
+	exp := []KeyMacroStep{{Chord: "a"}, {Cmd: "QReplace", Args: []string{"a", "b", "false"}}, {Chord: "!"}}
+	b, _ := json.Marshal(em.MacroRec.Steps)
+	eb, _ := json.Marshal(exp)
+	if string(b) != string(eb) {
+		t.Errorf("recorded steps:\ngot: %s\nexp: %s", b, eb)
+	}
+
+	var kms KeyMacros
+	kms.Add(&KeyMacro{Name: LastKeyMacroName, Steps: em.MacroRec.Steps})
+	kms.Add(&KeyMacro{Name: "other"})
+	kms.Add(&KeyMacro{Name: LastKeyMacroName, Steps: exp[:1]})
+	if len(kms) != 2 || len(kms.MacroByName(LastKeyMacroName).Steps) != 1 {
+		t.Errorf("Add did not replace macro of same name: %v", kms.Names())
+	}
+}
+
+func TestKeyMacroEmacsKeys(t *testing.T) {
+	for _, nm := range []KeyMapName{"MacEmacs", "LinuxEmacs"} {
+		km, _, ok := StdKeyMaps.MapByName(nm)
+		if !ok {
+			t.Fatalf("no keymap: %v", nm)
+		}
+		if (*km)["F3"] != KeyFunMacroRecord || (*km)["F4"] != KeyFunMacroPlay || (*km)["Shift+F4"] != KeyFunMacroPlayN {
+			t.Errorf("%v: no emacs-style keys for keyboard macros", nm)
+		}
+	}
+}
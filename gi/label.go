@@ -54,6 +54,13 @@ func (lb *Label) CopyFieldsFrom(frm interface{}) {
 	lb.Redrawable = fr.Redrawable
 }
 
+func (lb *Label) AccessInfo() AccessInfo {
+	ai := lb.WidgetBase.AccessInfo()
+	ai.Role = AccessLabel
+	ai.Name = AccessPlainText(lb.Text)
+	return ai
+}
+
 func (lb *Label) Disconnect() {
 	lb.WidgetBase.Disconnect()
 	lb.LinkSig.DisconnectAll()
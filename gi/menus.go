@@ -650,6 +650,12 @@ var SeparatorProps = ki.Props{
 	// todo: dotted
 }
 
+func (sp *Separator) AccessInfo() AccessInfo {
+	ai := sp.WidgetBase.AccessInfo()
+	ai.Role = AccessSeparator
+	return ai
+}
+
 func (sp *Separator) Style2D() {
 	sp.StyMu.Lock()
 	if sp.Horiz {
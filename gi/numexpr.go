@@ -0,0 +1,301 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gi
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"unicode"
+
+	"github.com/goki/gi/units"
+)
+
+// NumExprSuffixes are the multipliers for the SI (and binary) suffixes that
+// can follow a number in a numeric expression, e.g., 2.5k = 2500,
+// 4Ki = 4096 -- suffixes are case sensitive (m = milli, M = mega)
+var NumExprSuffixes = map[string]float64{
+	"p":  1e-12,
+	"n":  1e-9,
+	"u":  1e-6,
+	"µ":  1e-6,
+	"m":  1e-3,
+	"k":  1e3,
+	"K":  1e3,
+	"M":  1e6,
+	"G":  1e9,
+	"T":  1e12,
+	"Ki": 1 << 10,
+	"Mi": 1 << 20,
+	"Gi": 1 << 30,
+	"Ti": 1 << 40,
+}
+
+// EvalNumExpr evaluates a numeric expression as typed into a SpinBox, e.g.,
+// 3*1024, 1/3, (2+3)^2, 2.5k or 12pt.  Supported are the + - * / % and ^
+// (power) operators, parentheses, decimal, hex (0x), octal (0o) and binary
+// (0b) numbers, the NumExprSuffixes, and the units.UnitNames as suffixes.
+// Numbers with units are converted into the given unit using the given
+// units context -- if uc is nil, units are not allowed.
+func EvalNumExpr(expr string, un units.Unit, uc *units.Context) (float64, error) {
+	ne := &numExpr{src: []rune(expr), un: un, uc: uc}
+	ne.next()
+	val, err := ne.sum()
+	if err != nil {
+		return 0, err
+	}
+	if ne.tok != numExprEOF {
+		return 0, ne.errorf("unexpected %q", ne.tokStr())
+	}
+	if math.IsInf(val, 0) || math.IsNaN(val) {
+		return 0, fmt.Errorf("result is not a finite number")
+	}
+	return val, nil
+}
+
+// numExprTok is a numExpr token type
+type numExprTok int
+
+const (
+	numExprEOF numExprTok = iota
+	numExprNum
+	numExprIdent
+	numExprOp
+)
+
+// numExpr is the state for evaluating a numeric expression by recursive
+// descent, directly computing values as it parses
+type numExpr struct {
+	src []rune
+	pos int
+	un  units.Unit
+	uc  *units.Context
+
+	tok   numExprTok
+	tokSt int
+	num   float64
+	str   string
+	err   error
+}
+
+func (ne *numExpr) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("at %d: %s", ne.tokSt+1, fmt.Sprintf(format, args...))
+}
+
+func (ne *numExpr) tokStr() string {
+	if ne.tok == numExprEOF {
+		return "end"
+	}
+	return string(ne.src[ne.tokSt:ne.pos])
+}
+
+// next reads the next token -- any lexing error is recorded in err
+func (ne *numExpr) next() {
+	for ne.pos < len(ne.src) && unicode.IsSpace(ne.src[ne.pos]) {
+		ne.pos++
+	}
+	ne.tokSt = ne.pos
+	if ne.pos >= len(ne.src) {
+		ne.tok = numExprEOF
+		return
+	}
+	r := ne.src[ne.pos]
+	switch {
+	case unicode.IsDigit(r) || (r == '.' && ne.pos+1 < len(ne.src) && unicode.IsDigit(ne.src[ne.pos+1])):
+		ne.tok = numExprNum
+		ne.lexNum()
+	case unicode.IsLetter(r):
+		ne.tok = numExprIdent
+		for ne.pos < len(ne.src) && unicode.IsLetter(ne.src[ne.pos]) {
+			ne.pos++
+		}
+		ne.str = string(ne.src[ne.tokSt:ne.pos])
+	default:
+		ne.tok = numExprOp
+		ne.pos++
+		ne.str = string(r)
+	}
+}
+
+// lexNum reads a number token
+func (ne *numExpr) lexNum() {
+	src := ne.src
+	if src[ne.pos] == '0' && ne.pos+1 < len(src) {
+		switch src[ne.pos+1] {
+		case 'x', 'X', 'o', 'O', 'b', 'B':
+			ne.pos += 2
+			for ne.pos < len(src) && (unicode.IsDigit(src[ne.pos]) || unicode.In(src[ne.pos], unicode.ASCII_Hex_Digit)) {
+				ne.pos++
+			}
+			iv, err := strconv.ParseInt(string(src[ne.tokSt:ne.pos]), 0, 64)
+			if err != nil {
+				ne.err = ne.errorf("invalid number %q", string(src[ne.tokSt:ne.pos]))
+			}
+			ne.num = float64(iv)
+			return
+		}
+	}
+	for ne.pos < len(src) && (unicode.IsDigit(src[ne.pos]) || src[ne.pos] == '.') {
+		ne.pos++
+	}
+	// exponent only if followed by digits, so that 2em is 2 em
+	if ne.pos < len(src) && (src[ne.pos] == 'e' || src[ne.pos] == 'E') {
+		ep := ne.pos + 1
+		if ep < len(src) && (src[ep] == '+' || src[ep] == '-') {
+			ep++
+		}
+		if ep < len(src) && unicode.IsDigit(src[ep]) {
+			ne.pos = ep
+			for ne.pos < len(src) && unicode.IsDigit(src[ne.pos]) {
+				ne.pos++
+			}
+		}
+	}
+	fv, err := strconv.ParseFloat(string(src[ne.tokSt:ne.pos]), 64)
+	if err != nil {
+		ne.err = ne.errorf("invalid number %q", string(src[ne.tokSt:ne.pos]))
+	}
+	ne.num = fv
+}
+
+// isOp returns true if the current token is the given operator
+func (ne *numExpr) isOp(op string) bool {
+	return ne.tok == numExprOp && ne.str == op
+}
+
+// sum = product { ("+" | "-") product }
+func (ne *numExpr) sum() (float64, error) {
+	val, err := ne.product()
+	if err != nil {
+		return 0, err
+	}
+	for ne.isOp("+") || ne.isOp("-") {
+		op := ne.str
+		ne.next()
+		rv, err := ne.product()
+		if err != nil {
+			return 0, err
+		}
+		if op == "+" {
+			val += rv
+		} else {
+			val -= rv
+		}
+	}
+	return val, nil
+}
+
+// product = unary { ("*" | "/" | "%") unary }
+func (ne *numExpr) product() (float64, error) {
+	val, err := ne.unary()
+	if err != nil {
+		return 0, err
+	}
+	for ne.isOp("*") || ne.isOp("/") || ne.isOp("%") {
+		op := ne.str
+		ne.next()
+		rv, err := ne.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case "*":
+			val *= rv
+		case "/":
+			if rv == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			val /= rv
+		case "%":
+			if rv == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			val = math.Mod(val, rv)
+		}
+	}
+	return val, nil
+}
+
+// unary = ("-" | "+") unary | power
+func (ne *numExpr) unary() (float64, error) {
+	if ne.isOp("-") || ne.isOp("+") {
+		neg := ne.str == "-"
+		ne.next()
+		val, err := ne.unary()
+		if neg {
+			val = -val
+		}
+		return val, err
+	}
+	return ne.power()
+}
+
+// power = suffixed [ "^" unary ] -- right associative
+func (ne *numExpr) power() (float64, error) {
+	val, err := ne.suffixed()
+	if err != nil {
+		return 0, err
+	}
+	if ne.isOp("^") {
+		ne.next()
+		ex, err := ne.unary()
+		if err != nil {
+			return 0, err
+		}
+		val = math.Pow(val, ex)
+	}
+	return val, nil
+}
+
+// suffixed = primary [ suffix | unit ]
+func (ne *numExpr) suffixed() (float64, error) {
+	val, err := ne.primary()
+	if err != nil {
+		return 0, err
+	}
+	if ne.tok != numExprIdent {
+		return val, nil
+	}
+	if mult, ok := NumExprSuffixes[ne.str]; ok {
+		ne.next()
+		return val * mult, nil
+	}
+	un, ok := units.UnitFromName(ne.str)
+	if !ok {
+		return 0, ne.errorf("unknown suffix or unit %q", ne.str)
+	}
+	if ne.uc == nil {
+		return 0, ne.errorf("units (%s) are not supported here", ne.str)
+	}
+	ne.next()
+	return val * float64(ne.uc.ToDotsFactor(un)) / float64(ne.uc.ToDotsFactor(ne.un)), nil
+}
+
+// primary = number | "(" sum ")"
+func (ne *numExpr) primary() (float64, error) {
+	if ne.err != nil {
+		return 0, ne.err
+	}
+	switch {
+	case ne.tok == numExprNum:
+		val := ne.num
+		ne.next()
+		return val, ne.err
+	case ne.isOp("("):
+		ne.next()
+		val, err := ne.sum()
+		if err != nil {
+			return 0, err
+		}
+		if !ne.isOp(")") {
+			return 0, ne.errorf("missing )")
+		}
+		ne.next()
+		return val, ne.err
+	case ne.tok == numExprEOF:
+		return 0, ne.errorf("missing number")
+	}
+	return 0, ne.errorf("unexpected %q", ne.tokStr())
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gi
+
+import (
+	"math"
+	"testing"
+
+	"github.com/goki/gi/units"
+)
+
+func TestEvalNumExpr(t *testing.T) {
+	var uc units.Context
+	uc.Defaults()
+	tests := []struct {
+		expr string
+		val  float64
+	}{
+		{"42", 42},
+		{" -1.5 ", -1.5},
+		{"3*1024", 3072},
+		{"1/4", 0.25},
+		{"2+3*4", 14},
+		{"(2+3)*4", 20},
+		{"2^3^2", 512},
+		{"-2^2", -4},
+		{"7 % 4", 3},
+		{"2.5k", 2500},
+		{"4Ki", 4096},
+		{"3m", 0.003},
+		{"1e3", 1000},
+		{"0x1F", 31},
+		{"0b101", 5},
+		{"72pt", 96},
+		{"1in + 12pt", 112},
+		{"2*(1in)", 192},
+	}
+	for _, ts := range tests {
+		val, err := EvalNumExpr(ts.expr, units.Px, &uc)
+		if err != nil {
+			t.Errorf("EvalNumExpr(%q): unexpected error: %v", ts.expr, err)
+			continue
+		}
+		if math.Abs(val-ts.val) > 1e-6*math.Max(1, math.Abs(ts.val)) { // units factors are float32
+			t.Errorf("EvalNumExpr(%q) = %v, want %v", ts.expr, val, ts.val)
+		}
+	}
+	errs := []string{"", "1+", "(1+2", "1/0", "2 3", "3 foo", "1..2", "*2"}
+	for _, ex := range errs {
+		if val, err := EvalNumExpr(ex, units.Px, &uc); err == nil {
+			t.Errorf("EvalNumExpr(%q) = %v, expected error", ex, val)
+		}
+	}
+	if _, err := EvalNumExpr("12pt", units.Px, nil); err == nil {
+		t.Errorf("EvalNumExpr with units and nil context: expected error")
+	}
+}
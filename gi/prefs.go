@@ -96,6 +96,7 @@ func (pf *Preferences) Open() error {
 			pf.SaveKeyMaps = false
 		}
 	}
+	AvailKeyMacros.OpenPrefs() // ok to be non-existent
 	if pf.SaveDetailed {
 		PrefsDet.Open()
 	}
@@ -122,6 +123,9 @@ func (pf *Preferences) Save() error {
 	if pf.SaveKeyMaps {
 		AvailKeyMaps.SavePrefs()
 	}
+	if len(AvailKeyMacros) > 0 {
+		AvailKeyMacros.SavePrefs()
+	}
 	if pf.SaveDetailed {
 		PrefsDet.Save()
 	}
@@ -641,6 +645,13 @@ type EditorPrefs struct {
 	AutoIndent   bool `xml:"auto-indent" desc:"automatically indent lines when enter, tab, }, etc pressed"`
 	EmacsUndo    bool `xml:"emacs-undo" desc:"use emacs-style undo, where after a non-undo command, all the current undo actions are added to the undo stack, such that a subsequent undo is actually a redo"`
 	DepthColor   bool `xml:"depth-color" desc:"colorize the background according to nesting depth"`
+	Minimap      bool `xml:"minimap" desc:"show a minimap of the whole file at the right side of text views, with the visible part marked -- click or drag in it to scroll"`
+	VcsChanges   bool `xml:"vcs-changes" desc:"show markers next to the line numbers for lines added, modified or deleted since the last commit to version control -- hover over a marker to see the change, and click on it to revert it"`
+	VcsBlame     bool `xml:"vcs-blame" desc:"show who last changed the cursor line in version control, when, and why, at the end of the line -- needs VcsChanges, for the lines to be matched to those committed as the text is edited"`
+	UndoHist     bool `xml:"undo-hist" desc:"save the undo history of files when they are saved or closed, and restore it when they are opened again unchanged -- the histories are saved in the undo directory of the GoGi prefs directory, and have the text of the edits, including any that was deleted"`
+	UndoHistMax  int  `xml:"undo-hist-max" desc:"maximum number of edits kept in the saved undo history of a file -- older ones are dropped"`
+	UndoHistKB   int  `xml:"undo-hist-kb" desc:"maximum size of the text of the edits kept in the saved undo history of a file, in kilobytes -- older ones are dropped"`
+	UndoHistDays int  `xml:"undo-hist-days" desc:"number of days after which edits are dropped from the saved undo history of a file -- the histories of files that are not saved for this long are deleted"`
 }
 
 // Defaults are the defaults for EditorPrefs
@@ -652,6 +663,10 @@ func (pf *EditorPrefs) Defaults() {
 	pf.SpellCorrect = true
 	pf.AutoIndent = true
 	pf.DepthColor = true
+	pf.VcsChanges = true
+	pf.UndoHistMax = 1000
+	pf.UndoHistKB = 256
+	pf.UndoHistDays = 30
 }
 
 // StyleFromProps styles Slider-specific fields from ki.Prop properties
@@ -701,6 +716,10 @@ func (pf *EditorPrefs) StyleFromProps(props ki.Props) {
 			if iv, ok := kit.ToBool(val); ok {
 				pf.DepthColor = iv
 			}
+		case "minimap":
+			if iv, ok := kit.ToBool(val); ok {
+				pf.Minimap = iv
+			}
 		}
 	}
 }
@@ -0,0 +1,422 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gi
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/goki/gi/units"
+	"github.com/goki/ki/ki"
+	"github.com/goki/ki/kit"
+	"github.com/goki/mat32"
+)
+
+// ProgressAnimMSec is the number of milliseconds between animation steps
+// of indeterminate ProgressBars and Spinners
+var ProgressAnimMSec = 50
+
+////////////////////////////////////////////////////////////////////////////////////////
+// ProgressBar
+
+// ProgressBar shows the progress of an operation as a bar filled in
+// proportion to Value / Max, with an optional text label (the percentage by
+// default) -- when the amount of progress is not known, it is Indeterminate
+// and shows a block moving back and forth.  All the Set methods can be
+// called from any goroutine.
+type ProgressBar struct {
+	WidgetBase
+	Value         float32 `xml:"value" desc:"current progress value, between 0 and Max"`
+	Max           float32 `xml:"max" desc:"value at which the operation is complete"`
+	Indeterminate bool    `xml:"indeterminate" desc:"the amount of progress is not known -- shows a block moving back and forth instead of the Value"`
+	ShowText      bool    `xml:"show-text" desc:"show Text, or the percentage complete if Text is empty, centered over the bar"`
+	Text          string  `xml:"text" desc:"text to show over the bar if ShowText is on -- the percentage is shown if empty"`
+	ValueStyle    Style   `copy:"-" json:"-" xml:"-" desc:"style for the filled value bar, from the :value selector properties"`
+	AnimPos       float32 `copy:"-" json:"-" xml:"-" view:"-" desc:"position of the moving block when Indeterminate -- goes from 0 to 2 and back to 0"`
+}
+
+var KiT_ProgressBar = kit.Types.AddType(&ProgressBar{}, ProgressBarProps)
+
+// AddNewProgressBar adds a new progress bar to given parent node, with given name.
+func AddNewProgressBar(parent ki.Ki, name string) *ProgressBar {
+	pb := parent.AddNewChild(KiT_ProgressBar, name).(*ProgressBar)
+	pb.Max = 1
+	return pb
+}
+
+func (pb *ProgressBar) CopyFieldsFrom(frm interface{}) {
+	fr := frm.(*ProgressBar)
+	pb.WidgetBase.CopyFieldsFrom(&fr.WidgetBase)
+	pb.Value = fr.Value
+	pb.Max = fr.Max
+	pb.Indeterminate = fr.Indeterminate
+	pb.ShowText = fr.ShowText
+	pb.Text = fr.Text
+}
+
+var ProgressBarProps = ki.Props{
+	"EnumType:Flag":    KiT_NodeFlags,
+	"min-width":        units.NewEm(6),
+	"width":            units.NewEm(12),
+	"min-height":       units.NewEm(1),
+	"border-width":     units.NewPx(1),
+	"border-radius":    units.NewPx(2),
+	"border-color":     &Prefs.Colors.Border,
+	"border-style":     BorderSolid,
+	"padding":          units.NewPx(1),
+	"margin":           units.NewPx(2),
+	"color":            &Prefs.Colors.Font,
+	"background-color": &Prefs.Colors.Control,
+	"font-size":        units.NewPt(9),
+	"text-align":       AlignCenter,
+	":value": ki.Props{
+		"background-color": &Prefs.Colors.Select,
+	},
+}
+
+func (pb *ProgressBar) AccessInfo() AccessInfo {
+	ai := pb.WidgetBase.AccessInfo()
+	ai.Role = AccessProgressBar
+	if pb.Indeterminate {
+		ai.SetState(true, AccessBusy)
+	} else {
+		ai.SetValue(pb.Value, 0, pb.Max)
+	}
+	if pb.ShowText {
+		ai.Text = pb.Text
+	}
+	return ai
+}
+
+// Frac returns the fraction of the operation that is complete, from 0 to 1
+func (pb *ProgressBar) Frac() float32 {
+	if pb.Max <= 0 {
+		return 0
+	}
+	return mat32.Clamp(pb.Value/pb.Max, 0, 1)
+}
+
+// SetValue sets the progress value, which is clamped to 0..Max, and updates
+// the display
+func (pb *ProgressBar) SetValue(val float32) {
+	updt := pb.UpdateStart()
+	pb.Value = mat32.Clamp(val, 0, pb.Max)
+	pb.UpdateEnd(updt)
+}
+
+// SetMax sets the value at which the operation is complete
+func (pb *ProgressBar) SetMax(max float32) {
+	updt := pb.UpdateStart()
+	pb.Max = max
+	pb.UpdateEnd(updt)
+}
+
+// SetIndeterminate sets whether the amount of progress is known -- if not,
+// a block moving back and forth is shown instead of the Value
+func (pb *ProgressBar) SetIndeterminate(ind bool) {
+	updt := pb.UpdateStart()
+	pb.Indeterminate = ind
+	pb.AnimPos = 0
+	if ind {
+		progressAnimStart(pb.This().(progressAnimator))
+	}
+	pb.UpdateEnd(updt)
+}
+
+// SetText sets the text shown over the bar, and turns on ShowText -- an
+// empty string shows the percentage
+func (pb *ProgressBar) SetText(txt string) {
+	updt := pb.UpdateStart()
+	pb.Text = txt
+	pb.ShowText = true
+	pb.UpdateEnd(updt)
+}
+
+// AnimStep advances the moving block of an Indeterminate progress bar
+func (pb *ProgressBar) AnimStep() {
+	if !pb.Indeterminate {
+		progressAnimStop(pb.This().(progressAnimator))
+		return
+	}
+	pb.AnimPos += 0.04
+	if pb.AnimPos >= 2 {
+		pb.AnimPos -= 2
+	}
+	pb.UpdateSig()
+}
+
+func (pb *ProgressBar) Style2D() {
+	pb.StyMu.Lock()
+	defer pb.StyMu.Unlock()
+
+	pb.Style2DWidget()
+	pst := &(pb.Par.(Node2D).AsWidget().Sty)
+	pb.ValueStyle.CopyFrom(&pb.Sty)
+	pb.ValueStyle.SetStyleProps(pst, pb.StyleProps(":value"), pb.Viewport)
+	pb.ValueStyle.CopyUnitContext(&pb.Sty.UnContext)
+	pb.LayState.SetFromStyle(&pb.Sty.Layout) // also does reset
+}
+
+// RenderProgress renders the bar and text
+func (pb *ProgressBar) RenderProgress() {
+	rs, pc, st := pb.RenderLock()
+	defer pb.RenderUnlock(rs)
+
+	pb.RenderStdBox(st)
+	spc := st.BoxSpace()
+	pos := pb.LayState.Alloc.Pos.AddScalar(spc)
+	sz := pb.LayState.Alloc.Size.AddScalar(-2 * spc)
+	if sz.X <= 0 || sz.Y <= 0 {
+		return
+	}
+	vclr := &pb.ValueStyle.Font.BgColor.Color
+	if pb.Indeterminate {
+		bw := 0.25 * sz.X
+		t := pb.AnimPos
+		if t > 1 {
+			t = 2 - t
+		}
+		pc.FillBoxColor(rs, mat32.Vec2{pos.X + t*(sz.X-bw), pos.Y}, mat32.Vec2{bw, sz.Y}, vclr)
+	} else if fr := pb.Frac(); fr > 0 {
+		pc.FillBoxColor(rs, pos, mat32.Vec2{fr * sz.X, sz.Y}, vclr)
+	}
+	if !pb.ShowText {
+		return
+	}
+	txt := pb.Text
+	if txt == "" {
+		if pb.Indeterminate {
+			return
+		}
+		txt = fmt.Sprintf("%d%%", int(100*pb.Frac()+0.5))
+	}
+	var tr TextRender
+	tr.SetString(txt, &st.Font, &st.UnContext, &st.Text, true, 0, 0)
+	tpos := mat32.Vec2{pos.X + 0.5*(sz.X-tr.Size.X), pos.Y + 0.5*(sz.Y-tr.Size.Y)}
+	tr.RenderTopPos(rs, tpos)
+}
+
+func (pb *ProgressBar) Render2D() {
+	if pb.FullReRenderIfNeeded() {
+		return
+	}
+	if pb.PushBounds() {
+		if pb.Indeterminate {
+			progressAnimStart(pb.This().(progressAnimator))
+		}
+		pb.This().(Node2D).ConnectEvents2D()
+		pb.RenderProgress()
+		pb.Render2DChildren()
+		pb.PopBounds()
+	} else {
+		pb.DisconnectAllEvents(RegPri)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////
+// Spinner
+
+// SpinnerNDots is the number of dots in the circle drawn by a Spinner
+var SpinnerNDots = 8
+
+// Spinner is a busy indicator showing a circle of dots that spins while
+// Spinning is on -- use Start and Stop, which can be called from any
+// goroutine.
+type Spinner struct {
+	WidgetBase
+	Spinning bool    `xml:"spinning" desc:"the spinner is spinning"`
+	AnimPos  int     `copy:"-" json:"-" xml:"-" view:"-" desc:"index of the leading dot"`
+	DotSize  float32 `xml:"dot-size" desc:"size of the dots, as a proportion of the radius of the circle"`
+}
+
+var KiT_Spinner = kit.Types.AddType(&Spinner{}, SpinnerProps)
+
+// AddNewSpinner adds a new spinner to given parent node, with given name.
+func AddNewSpinner(parent ki.Ki, name string) *Spinner {
+	sp := parent.AddNewChild(KiT_Spinner, name).(*Spinner)
+	sp.DotSize = 0.25
+	return sp
+}
+
+func (sp *Spinner) CopyFieldsFrom(frm interface{}) {
+	fr := frm.(*Spinner)
+	sp.WidgetBase.CopyFieldsFrom(&fr.WidgetBase)
+	sp.Spinning = fr.Spinning
+	sp.DotSize = fr.DotSize
+}
+
+var SpinnerProps = ki.Props{
+	"EnumType:Flag":    KiT_NodeFlags,
+	"width":            units.NewEm(1.25),
+	"height":           units.NewEm(1.25),
+	"margin":           units.NewPx(2),
+	"padding":          units.NewPx(0),
+	"color":            &Prefs.Colors.Icon,
+	"background-color": "none",
+}
+
+func (sp *Spinner) AccessInfo() AccessInfo {
+	ai := sp.WidgetBase.AccessInfo()
+	ai.Role = AccessProgressBar
+	ai.SetState(sp.Spinning, AccessBusy)
+	return ai
+}
+
+// Start starts the spinner spinning
+func (sp *Spinner) Start() {
+	updt := sp.UpdateStart()
+	sp.Spinning = true
+	progressAnimStart(sp.This().(progressAnimator))
+	sp.UpdateEnd(updt)
+}
+
+// Stop stops the spinner
+func (sp *Spinner) Stop() {
+	updt := sp.UpdateStart()
+	sp.Spinning = false
+	progressAnimStop(sp.This().(progressAnimator))
+	sp.UpdateEnd(updt)
+}
+
+// AnimStep advances the spinner to the next dot
+func (sp *Spinner) AnimStep() {
+	if !sp.Spinning {
+		progressAnimStop(sp.This().(progressAnimator))
+		return
+	}
+	sp.AnimPos = (sp.AnimPos + 1) % SpinnerNDots
+	sp.UpdateSig()
+}
+
+// RenderSpinner renders the circle of dots, fading behind the leading dot
+func (sp *Spinner) RenderSpinner() {
+	rs, pc, st := sp.RenderLock()
+	defer sp.RenderUnlock(rs)
+
+	sp.RenderStdBox(st)
+	spc := st.BoxSpace()
+	pos := sp.LayState.Alloc.Pos.AddScalar(spc)
+	sz := sp.LayState.Alloc.Size.AddScalar(-2 * spc)
+	rad := 0.5 * mat32.Min(sz.X, sz.Y)
+	if rad <= 0 {
+		return
+	}
+	ctr := pos.Add(sz.MulScalar(0.5))
+	dsz := sp.DotSize
+	if dsz <= 0 {
+		dsz = 0.25
+	}
+	drad := 0.5 * dsz * rad
+	orad := rad - drad
+	nd := SpinnerNDots
+	pc.StrokeStyle.SetColor(nil)
+	for i := 0; i < nd; i++ {
+		age := (sp.AnimPos - i + nd) % nd // 0 = leading dot
+		clr := st.Font.Color
+		if sp.Spinning {
+			clr = clr.Clearer(80 * float32(age) / float32(nd))
+		} else {
+			clr = clr.Clearer(60)
+		}
+		ang := 2 * mat32.Pi * float32(i) / float32(nd)
+		pc.FillStyle.SetColor(clr)
+		pc.DrawCircle(rs, ctr.X+orad*mat32.Sin(ang), ctr.Y-orad*mat32.Cos(ang), drad)
+		pc.Fill(rs)
+	}
+}
+
+func (sp *Spinner) Render2D() {
+	if sp.FullReRenderIfNeeded() {
+		return
+	}
+	if sp.PushBounds() {
+		if sp.Spinning {
+			progressAnimStart(sp.This().(progressAnimator))
+		}
+		sp.This().(Node2D).ConnectEvents2D()
+		sp.RenderSpinner()
+		sp.Render2DChildren()
+		sp.PopBounds()
+	} else {
+		sp.DisconnectAllEvents(RegPri)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////
+// Animation
+
+// progressAnimator is a widget animated by the progress animation ticker
+type progressAnimator interface {
+	Node2D
+
+	// AnimStep advances the animation by one step
+	AnimStep()
+}
+
+var (
+	// progressAnims are the widgets currently being animated
+	progressAnims = map[progressAnimator]struct{}{}
+
+	// progressTicker drives the animation -- nil if nothing is animated
+	progressTicker *time.Ticker
+
+	// progressAnimMu protects progressAnims and progressTicker
+	progressAnimMu sync.Mutex
+)
+
+// progressAnimStart adds given widget to the animated widgets, starting
+// the animation ticker if not already running
+func progressAnimStart(pa progressAnimator) {
+	progressAnimMu.Lock()
+	defer progressAnimMu.Unlock()
+	progressAnims[pa] = struct{}{}
+	if progressTicker == nil {
+		progressTicker = time.NewTicker(time.Duration(ProgressAnimMSec) * time.Millisecond)
+		go progressAnimate(progressTicker)
+	}
+}
+
+// progressAnimStop removes given widget from the animated widgets -- the
+// ticker stops when there are none left
+func progressAnimStop(pa progressAnimator) {
+	progressAnimMu.Lock()
+	delete(progressAnims, pa)
+	progressAnimMu.Unlock()
+}
+
+// progressAnimate runs the animation steps for the visible animated
+// widgets on each tick, until there are none left
+func progressAnimate(tick *time.Ticker) {
+	var steps []progressAnimator
+	for range tick.C {
+		progressAnimMu.Lock()
+		if len(progressAnims) == 0 {
+			tick.Stop()
+			progressTicker = nil
+			progressAnimMu.Unlock()
+			return
+		}
+		steps = steps[:0]
+		for pa := range progressAnims {
+			if pa.This() == nil || pa.IsDestroyed() || pa.IsDeleted() {
+				delete(progressAnims, pa)
+				continue
+			}
+			if !pa.IsVisible() {
+				continue
+			}
+			win := pa.AsNode2D().ParentWindow()
+			if win == nil || win.IsClosed() || win.IsResizing() || win.IsWinUpdating() {
+				continue
+			}
+			steps = append(steps, pa)
+		}
+		progressAnimMu.Unlock()
+		for _, pa := range steps {
+			pa.AnimStep()
+		}
+	}
+}
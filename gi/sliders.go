@@ -92,6 +92,13 @@ func (sb *SliderBase) CopyFieldsFrom(frm interface{}) {
 	sb.Off = fr.Off
 }
 
+func (sb *SliderBase) AccessInfo() AccessInfo {
+	ai := sb.WidgetBase.AccessInfo()
+	ai.Role = AccessSlider
+	ai.SetValue(sb.Value, sb.Min, sb.Max)
+	return ai
+}
+
 func (sb *SliderBase) Disconnect() {
 	sb.WidgetBase.Disconnect()
 	sb.SliderSig.DisconnectAll()
@@ -287,6 +294,13 @@ func (sb *SliderBase) SetSliderPos(pos float32) {
 	sb.UpdateEnd(updt)
 }
 
+// PressPos returns the position of the slider (see SetSliderPos) for a
+// mouse press at given pixel offset along Dim, relative to the start of the
+// window bounding box of the slider -- the thumb is centered on the press.
+func (sb *SliderBase) PressPos(px float32) float32 {
+	return px - sb.Sty.Layout.Margin.Dots - 0.5*sb.ThSizeReal
+}
+
 // SliderMove called when slider moved along relevant axis
 func (sb *SliderBase) SliderMove(start, end float32) {
 	del := end - start
@@ -436,12 +450,10 @@ func (sb *SliderBase) MouseEvent() {
 				me.SetProcessed()
 				if me.Action == mouse.Press {
 					ed := sbb.This().(SliderPositioner).PointToRelPos(me.Where)
-					st := &sbb.Sty
-					spc := st.Layout.Margin.Dots + 0.5*sbb.ThSizeReal
 					if sbb.Dim == mat32.X {
-						sbb.SliderPress(float32(ed.X) - spc)
+						sbb.SliderPress(sbb.PressPos(float32(ed.X)))
 					} else {
-						sbb.SliderPress(float32(ed.Y) - spc)
+						sbb.SliderPress(sbb.PressPos(float32(ed.Y)))
 					}
 				} else {
 					sbb.SliderRelease()
@@ -841,6 +853,15 @@ func (sr *Slider) FocusChanged2D(change FocusChanges) {
 // ScrollBar has a proportional thumb size reflecting amount of content visible
 type ScrollBar struct {
 	SliderBase
+	Marks []ScrollMark `json:"-" xml:"-" desc:"marks shown along the scrollbar, e.g., as an overview ruler of search matches in a text view"`
+}
+
+// ScrollMark is a mark shown along a ScrollBar at a given value -- e.g., as
+// an overview ruler showing the locations of search matches or errors
+type ScrollMark struct {
+	Val   float32 `desc:"value where the mark starts, in the same units as the scrollbar Value"`
+	Size  float32 `desc:"size of the mark in value units -- marks are always at least a couple of pixels"`
+	Color Color   `desc:"color of the mark"`
 }
 
 var KiT_ScrollBar = kit.Types.AddType(&ScrollBar{}, ScrollBarProps)
@@ -853,6 +874,7 @@ func AddNewScrollBar(parent ki.Ki, name string) *ScrollBar {
 func (sb *ScrollBar) CopyFieldsFrom(frm interface{}) {
 	fr := frm.(*ScrollBar)
 	sb.SliderBase.CopyFieldsFrom(&fr.SliderBase)
+	sb.Marks = append(sb.Marks[:0], fr.Marks...)
 }
 
 var ScrollBarProps = ki.Props{
@@ -891,6 +913,12 @@ var ScrollBarProps = ki.Props{
 	},
 }
 
+func (sb *ScrollBar) AccessInfo() AccessInfo {
+	ai := sb.SliderBase.AccessInfo()
+	ai.Role = AccessScrollBar
+	return ai
+}
+
 func (sb *ScrollBar) Defaults() { // todo: should just get these from props
 	sb.ValThumb = true
 	sb.ThumbSize = units.NewEx(1)
@@ -967,6 +995,62 @@ func (sb *ScrollBar) Render2DDefaultStyle() {
 	sz.SetDim(sb.Dim, sb.ThSize)
 	pc.FillStyle.SetColorSpec(&sb.StateStyles[SliderValue].Font.BgColor)
 	sb.RenderBoxImpl(pos, sz, st.Border.Radius.Dots)
+	sb.RenderMarks()
+}
+
+// ScrollMarkMinSize is the minimum size of a ScrollMark in pixels
+var ScrollMarkMinSize = float32(2)
+
+// MarkPixel returns the pixel offset along Dim, relative to the start of
+// the scrollbar, of given value for rendering marks -- it is the inverse of
+// the mapping from mouse presses to values (see PressPos and SetSliderPos),
+// so that pressing on a mark scrolls to center its value in view.
+func (sb *ScrollBar) MarkPixel(val float32) float32 {
+	effSz := sb.Size
+	if sb.ValThumb {
+		val -= 0.5 * sb.ThumbVal
+		if sb.ThSize != sb.ThSizeReal { // as in SetSliderPos
+			effSz -= sb.ThSize - sb.ThSizeReal
+			effSz -= .5
+		}
+	}
+	pos := effSz * (val - sb.Min) / (sb.Max - sb.Min)
+	return pos + sb.Sty.Layout.Margin.Dots + 0.5*sb.ThSizeReal
+}
+
+// RenderMarks renders the Marks on top of the scrollbar, at the positions
+// where pressing on them jumps to them (see MarkPixel)
+func (sb *ScrollBar) RenderMarks() {
+	if len(sb.Marks) == 0 || sb.Max <= sb.Min {
+		return
+	}
+	rs := &sb.Viewport.Render
+	pc := &rs.Paint
+	spc := sb.Sty.BoxSpace()
+	pos := sb.LayState.Alloc.Pos.AddScalar(spc)
+	sz := sb.LayState.Alloc.Size.SubScalar(2.0 * spc)
+	odim := mat32.OtherDim(sb.Dim)
+	inset := mat32.Min(2, 0.25*sz.Dim(odim))
+	pos.SetAddDim(odim, inset)
+	sz.SetSubDim(odim, 2*inset)
+	st := pos.Dim(sb.Dim)
+	ed := st + sz.Dim(sb.Dim)
+	base := sb.LayState.Alloc.Pos.Dim(sb.Dim) // MarkPixel is relative to start
+	for _, mk := range sb.Marks {
+		mst := base + sb.MarkPixel(mk.Val)
+		med := base + sb.MarkPixel(mk.Val+mk.Size)
+		med = mat32.Max(med, mst+ScrollMarkMinSize)
+		mst = mat32.Max(mst, st)
+		med = mat32.Min(med, ed)
+		if med <= mst {
+			continue
+		}
+		mp := pos
+		mp.SetDim(sb.Dim, mst)
+		ms := sz
+		ms.SetDim(sb.Dim, med-mst)
+		pc.FillBoxColor(rs, mp, ms, mk.Color)
+	}
 }
 
 func (sb *ScrollBar) ConnectEvents2D() {
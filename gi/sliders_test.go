@@ -0,0 +1,41 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gi
+
+import (
+	"testing"
+
+	"github.com/goki/mat32"
+)
+
+func TestScrollBarMarkJump(t *testing.T) {
+	for _, max := range []float32{10000, 100000} { // second has a minimum-size thumb
+		sb := &ScrollBar{}
+		sb.InitName(sb, "scroll")
+		sb.Defaults()
+		sb.Dim = mat32.Y
+		sb.Max = max
+		sb.ThumbVal = 500
+		sb.Size = 400
+		sb.UpdateThumbValSize()
+		onePix := max / sb.Size
+		for _, val := range []float32{0, 300, 2000, 5000, 9000, max - 300, max} {
+			px := sb.MarkPixel(val)
+			if px < 0 || px > sb.Size+sb.ThSize {
+				t.Errorf("max %v: mark at %v is off the bar: %v", max, val, px)
+			}
+			sb.SetSliderPos(sb.PressPos(px))
+			if val < sb.Value-onePix || val > sb.Value+sb.ThumbVal+onePix {
+				t.Errorf("max %v: press on mark at %v scrolled to %v, which does not show it", max, val, sb.Value)
+			}
+			if val < sb.ThumbVal || val > max-sb.ThumbVal {
+				continue // at the ends, the view can't be centered
+			}
+			if ctr := sb.Value + 0.5*sb.ThumbVal; mat32.Abs(ctr-val) > onePix {
+				t.Errorf("max %v: press on mark at %v centered view at %v", max, val, ctr)
+			}
+		}
+	}
+}
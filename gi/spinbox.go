@@ -8,9 +8,10 @@ import (
 	"fmt"
 	"image"
 	"log"
-	"strconv"
+	"math"
 
 	"github.com/goki/gi/oswin"
+	"github.com/goki/gi/oswin/key"
 	"github.com/goki/gi/oswin/mouse"
 	"github.com/goki/gi/units"
 	"github.com/goki/ki/ki"
@@ -25,18 +26,24 @@ import (
 // decrementing values -- all configured within the Parts of the widget
 type SpinBox struct {
 	PartsWidgetBase
-	Value      float32   `xml:"value" desc:"current value"`
-	HasMin     bool      `xml:"has-min" desc:"is there a minimum value to enforce"`
-	Min        float32   `xml:"min" desc:"minimum value in range"`
-	HasMax     bool      `xml:"has-max" desc:"is there a maximumvalue to enforce"`
-	Max        float32   `xml:"max" desc:"maximum value in range"`
-	Step       float32   `xml:"step" desc:"smallest step size to increment"`
-	PageStep   float32   `xml:"pagestep" desc:"larger PageUp / Dn step size"`
-	Prec       int       `desc:"specifies the precision of decimal places (total, not after the decimal point) to use in representing the number -- this helps to truncate small weird floating point values in the nether regions"`
-	Format     string    `xml:"format" desc:"prop = format -- format string for printing the value -- blank defaults to %g.  If decimal based (ends in d, b, c, o, O, q, x, X, or U) then value is converted to decimal prior to printing"`
-	UpIcon     IconName  `view:"show-name" desc:"icon to use for up button -- defaults to wedge-up"`
-	DownIcon   IconName  `view:"show-name" desc:"icon to use for down button -- defaults to wedge-down"`
-	SpinBoxSig ki.Signal `copy:"-" json:"-" xml:"-" view:"-" desc:"signal for spin box -- has no signal types, just emitted when the value changes"`
+	Value      float32    `xml:"value" desc:"current value"`
+	HasMin     bool       `xml:"has-min" desc:"is there a minimum value to enforce"`
+	Min        float32    `xml:"min" desc:"minimum value in range"`
+	HasMax     bool       `xml:"has-max" desc:"is there a maximumvalue to enforce"`
+	Max        float32    `xml:"max" desc:"maximum value in range"`
+	Step       float32    `xml:"step" desc:"smallest step size to increment"`
+	PageStep   float32    `xml:"pagestep" desc:"larger PageUp / Dn step size"`
+	Prec       int        `desc:"specifies the precision of decimal places (total, not after the decimal point) to use in representing the number -- this helps to truncate small weird floating point values in the nether regions"`
+	Format     string     `xml:"format" desc:"prop = format -- format string for printing the value -- blank defaults to %g.  If decimal based (ends in d, b, c, o, O, q, x, X, or U) then value is converted to decimal prior to printing"`
+	UpIcon     IconName   `view:"show-name" desc:"icon to use for up button -- defaults to wedge-up"`
+	DownIcon   IconName   `view:"show-name" desc:"icon to use for down button -- defaults to wedge-down"`
+	HasUnit    bool       `xml:"has-unit" desc:"if true, numbers entered with units (e.g., 12pt) are converted into Unit -- otherwise units are an error"`
+	Unit       units.Unit `xml:"unit" desc:"units of the value, used for converting numbers entered with other units -- only if HasUnit"`
+	HistoryMax int        `xml:"history" desc:"maximum number of recently-entered values to keep in History, available from the text field context menu and the history prev / next keys -- 0 = no history"`
+	History    []float32  `copy:"-" json:"-" xml:"-" view:"-" desc:"recently-entered values, most recent first"`
+	HistIdx    int        `copy:"-" json:"-" xml:"-" view:"-" desc:"number of steps back in History of the value last recalled by history prev / next -- 0 = the current value"`
+	ErrMsg     string     `copy:"-" json:"-" xml:"-" view:"-" desc:"error message if the text last entered could not be evaluated -- the text field is marked with SpinBoxErrColor and shows it as its tooltip"`
+	SpinBoxSig ki.Signal  `copy:"-" json:"-" xml:"-" view:"-" desc:"signal for spin box -- has no signal types, just emitted when the value changes"`
 }
 
 var KiT_SpinBox = kit.Types.AddType(&SpinBox{}, SpinBoxProps)
@@ -59,6 +66,30 @@ func (sb *SpinBox) CopyFieldsFrom(frm interface{}) {
 	sb.Prec = fr.Prec
 	sb.UpIcon = fr.UpIcon
 	sb.DownIcon = fr.DownIcon
+	sb.HasUnit = fr.HasUnit
+	sb.Unit = fr.Unit
+	sb.HistoryMax = fr.HistoryMax
+}
+
+// AccessInfo returns the accessibility info for the spinbox, with the
+// value and range, and the error message if the last entry was invalid
+func (sb *SpinBox) AccessInfo() AccessInfo {
+	ai := sb.WidgetBase.AccessInfo()
+	ai.Role = AccessSpinBox
+	min, max := sb.Min, sb.Max
+	if !sb.HasMin {
+		min = -mat32.Infinity
+	}
+	if !sb.HasMax {
+		max = mat32.Infinity
+	}
+	ai.SetValue(sb.Value, min, max)
+	ai.Text = sb.ValToString(sb.Value)
+	if sb.ErrMsg != "" {
+		ai.SetState(true, AccessInvalid)
+		ai.Desc = sb.ErrMsg
+	}
+	return ai
 }
 
 func (sb *SpinBox) Disconnect() {
@@ -99,6 +130,10 @@ var SpinBoxProps = ki.Props{
 	},
 }
 
+// SpinBoxErrColor is the border color of the text field of a SpinBox
+// when the text entered could not be evaluated
+var SpinBoxErrColor = "#E33"
+
 func (sb *SpinBox) Defaults() { // todo: should just get these from props
 	sb.Step = 0.1
 	sb.PageStep = 0.2
@@ -146,6 +181,14 @@ func (sb *SpinBox) SetValue(val float32) {
 		sb.Value = mat32.Max(sb.Value, sb.Min)
 	}
 	sb.Value = mat32.Truncate(sb.Value, sb.Prec)
+	sb.SetErrMsg("")
+}
+
+// SetUnit sets the units of the value, enabling entry of numbers with
+// other units, which are converted into these units
+func (sb *SpinBox) SetUnit(un units.Unit) {
+	sb.HasUnit = true
+	sb.Unit = un
 }
 
 // SetValueAction calls SetValue and also emits the signal
@@ -228,6 +271,10 @@ func (sb *SpinBox) ConfigParts() {
 		// todo: see TreeView for extra steps needed to generally support styling of parts..
 		// doing it manually for now..
 		tf.SetProp("clear-act", false)
+		tf.CtxtMenuFunc = func(g Node2D, m *Menu) {
+			sbb := g.ParentByType(KiT_SpinBox, ki.Embeds).Embed(KiT_SpinBox).(*SpinBox)
+			sbb.MakeHistoryMenu(m)
+		}
 		if sb.Sty.Template != "" {
 			tf.Sty.Template = sb.Sty.Template + ".text"
 		}
@@ -238,10 +285,13 @@ func (sb *SpinBox) ConfigParts() {
 				if sig == int64(TextFieldDone) || sig == int64(TextFieldDeFocused) {
 					sbb := recv.Embed(KiT_SpinBox).(*SpinBox)
 					tf := send.(*TextField)
-					vl, err := sb.StringToVal(tf.Text())
-					if err == nil {
-						sbb.SetValueAction(vl)
+					vl, err := sbb.StringToVal(tf.Text())
+					if err != nil {
+						sbb.SetErrMsg(err.Error())
+						return
 					}
+					sbb.SetValueAction(vl)
+					sbb.AddHistory(sbb.Value)
 				}
 			})
 		}
@@ -273,29 +323,112 @@ func (sb *SpinBox) ValToString(val float32) string {
 	return fmt.Sprintf(sb.Format, val)
 }
 
-// StringToVal converts the string field back to float value
+// StringToVal converts the string field back to float value -- the string
+// is evaluated as an expression by EvalNumExpr, so it can contain
+// arithmetic, SI suffixes and, if HasUnit, units (e.g., 3*1024, 2.5k, 12pt).
+// Values are rounded if the format requires an integer.
 func (sb *SpinBox) StringToVal(str string) (float32, error) {
-	var fval float32
-	var err error
+	var uc *units.Context
+	if sb.HasUnit {
+		sb.StyMu.RLock()
+		ucv := sb.Sty.UnContext
+		sb.StyMu.RUnlock()
+		uc = &ucv
+	}
+	fv, err := EvalNumExpr(str, sb.Unit, uc)
+	if err != nil {
+		return 0, err
+	}
 	if sb.FormatIsInt() {
-		var iv int64
-		iv, err = strconv.ParseInt(str, 0, 64)
-		fval = float32(iv)
+		fv = math.Round(fv)
+	}
+	return float32(fv), nil
+}
+
+// SetErrMsg sets the error message for text that could not be evaluated,
+// marking the text field with SpinBoxErrColor and showing the message as
+// its tooltip -- an empty message clears the error
+func (sb *SpinBox) SetErrMsg(msg string) {
+	if msg == sb.ErrMsg {
+		return
+	}
+	sb.ErrMsg = msg
+	if !sb.Parts.HasChildren() {
+		return
+	}
+	tf := sb.Parts.ChildByName("text-field", 0).(*TextField)
+	if msg == "" {
+		tf.DeleteProp("border-color")
+		tf.Tooltip = ""
 	} else {
-		var fv float64
-		fv, err = strconv.ParseFloat(str, 32)
-		fval = float32(fv)
+		tf.SetProp("border-color", SpinBoxErrColor)
+		tf.Tooltip = msg
 	}
-	if err != nil {
-		log.Println(err)
+	sb.SetFullReRender()
+	sb.UpdateSig()
+}
+
+// AddHistory adds given value to the front of the History, if HistoryMax > 0
+func (sb *SpinBox) AddHistory(val float32) {
+	sb.HistIdx = 0
+	if sb.HistoryMax <= 0 {
+		return
+	}
+	for i, hv := range sb.History {
+		if hv == val {
+			sb.History = append(sb.History[:i], sb.History[i+1:]...)
+			break
+		}
+	}
+	sb.History = append([]float32{val}, sb.History...)
+	if len(sb.History) > sb.HistoryMax {
+		sb.History = sb.History[:sb.HistoryMax]
+	}
+}
+
+// HistoryText sets the text field to the History value at given offset
+// relative to the one last recalled (+1 = older, -1 = newer), or to the
+// current value when moving back past the newest -- the value is only set
+// when the text is entered
+func (sb *SpinBox) HistoryText(delta int) {
+	idx := sb.HistIdx + delta
+	if idx < 0 || idx > len(sb.History) {
+		return
+	}
+	sb.HistIdx = idx
+	val := sb.Value
+	if idx > 0 {
+		val = sb.History[idx-1]
+	}
+	tf := sb.Parts.ChildByName("text-field", 0).(*TextField)
+	tf.SetText(sb.ValToString(val))
+	tf.CursorEnd()
+}
+
+// MakeHistoryMenu adds the History values to given text field context menu
+func (sb *SpinBox) MakeHistoryMenu(m *Menu) {
+	if len(sb.History) == 0 {
+		return
+	}
+	m.AddSeparator("sep-hist")
+	for _, hv := range sb.History {
+		val := hv
+		m.AddAction(ActOpts{Label: sb.ValToString(val)},
+			sb.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+				sbb := recv.Embed(KiT_SpinBox).(*SpinBox)
+				sbb.SetValueAction(val)
+				sbb.AddHistory(val)
+			})
 	}
-	return fval, err
 }
 
 func (sb *SpinBox) ConfigPartsIfNeeded() {
 	if !sb.Parts.HasChildren() {
 		sb.ConfigParts()
 	}
+	if sb.ErrMsg != "" { // keep the erroneous text for editing
+		return
+	}
 	tf := sb.Parts.ChildByName("text-field", 0).(*TextField)
 	txt := sb.ValToString(sb.Value)
 	if tf.Txt != txt {
@@ -326,10 +459,33 @@ func (sb *SpinBox) TextFieldEvent() {
 	})
 }
 
+// TextFieldKeyEvent handles the history prev / next keys in the text field
+func (sb *SpinBox) TextFieldKeyEvent() {
+	if sb.HistoryMax <= 0 {
+		return
+	}
+	tf := sb.Parts.ChildByName("text-field", 0).(*TextField)
+	// HiPri is needed to get the keys before the text field's native processing
+	tf.ConnectEvent(oswin.KeyChordEvent, HiPri, func(recv, send ki.Ki, sig int64, d interface{}) {
+		tff := recv.Embed(KiT_TextField).(*TextField)
+		sbb := tff.ParentByType(KiT_SpinBox, ki.Embeds).Embed(KiT_SpinBox).(*SpinBox)
+		kt := d.(*key.ChordEvent)
+		switch KeyFun(kt.Chord()) {
+		case KeyFunHistPrev:
+			kt.SetProcessed()
+			sbb.HistoryText(1)
+		case KeyFunHistNext:
+			kt.SetProcessed()
+			sbb.HistoryText(-1)
+		}
+	})
+}
+
 func (sb *SpinBox) SpinBoxEvents() {
 	sb.HoverTooltipEvent()
 	sb.MouseScrollEvent()
 	sb.TextFieldEvent()
+	sb.TextFieldKeyEvent()
 }
 
 func (sb *SpinBox) Init2D() {
@@ -382,6 +538,14 @@ func (sb *SpinBox) StyleFromProps(props ki.Props, vp *Viewport2D) {
 			}
 		case "format":
 			sb.Format = kit.ToString(val)
+		case "unit":
+			if un, ok := units.UnitFromName(kit.ToString(val)); ok {
+				sb.SetUnit(un)
+			}
+		case "history":
+			if iv, ok := kit.ToInt(val); ok {
+				sb.HistoryMax = int(iv)
+			}
 		}
 	}
 }
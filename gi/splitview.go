@@ -65,6 +65,12 @@ var SplitViewProps = ki.Props{
 	"padding":       0,
 }
 
+func (sv *SplitView) AccessInfo() AccessInfo {
+	ai := sv.PartsWidgetBase.AccessInfo()
+	ai.Role = AccessSplitPane
+	return ai
+}
+
 // UpdateSplits updates the splits to be same length as number of children,
 // and normalized
 func (sv *SplitView) UpdateSplits() {
@@ -496,6 +502,12 @@ func (sr *Splitter) Defaults() {
 	sr.SetFlag(int(InstaDrag))
 }
 
+func (sr *Splitter) AccessInfo() AccessInfo {
+	ai := sr.SliderBase.AccessInfo()
+	ai.Role = AccessSplitter
+	return ai
+}
+
 func (sr *Splitter) Init2D() {
 	sr.Init2DSlider()
 	sr.Defaults()
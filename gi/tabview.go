@@ -47,6 +47,12 @@ func (tv *TabView) CopyFieldsFrom(frm interface{}) {
 	tv.NewTabType = fr.NewTabType
 }
 
+func (tv *TabView) AccessInfo() AccessInfo {
+	ai := tv.Layout.AccessInfo()
+	ai.Role = AccessGroup
+	return ai
+}
+
 func (tv *TabView) Disconnect() {
 	tv.Layout.Disconnect()
 	tv.TabViewSig.DisconnectAll()
@@ -425,12 +431,14 @@ func (tv *TabView) InitTabView() {
 	tabs.SetProp("margin", units.NewPx(0))
 	tabs.SetProp("spacing", units.NewPx(4))
 	tabs.SetProp("background-color", "linear-gradient(pref(Control), highlight-10)")
+	tabs.SetProp("access-role", AccessTabList)
 
 	frame := AddNewFrame(tv, "frame", LayoutStacked)
 	frame.SetMinPrefWidth(units.NewEm(10))
 	frame.SetMinPrefHeight(units.NewEm(7))
 	frame.SetStretchMax()
 	frame.SetReRenderAnchor()
+	frame.SetProp("access-role", AccessTabPanel)
 
 	tv.ConfigNewTabButton()
 
@@ -604,6 +612,12 @@ func (tb *TabButton) TabView() *TabView {
 	return tv.Embed(KiT_TabView).(*TabView)
 }
 
+func (tb *TabButton) AccessInfo() AccessInfo {
+	ai := tb.Action.AccessInfo()
+	ai.Role = AccessTab
+	return ai
+}
+
 func (tb *TabButton) ConfigParts() {
 	tb.Parts.SetProp("overflow", OverflowHidden) // no scrollbars!
 	if !tb.NoDelete {
@@ -0,0 +1,257 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goki/ki/ki"
+)
+
+// TaskUpdateMSec is the minimum number of milliseconds between updates of
+// the status display of a Task -- progress updates in between are coalesced
+var TaskUpdateMSec = 100
+
+// TaskFunc is a function run as a background Task -- it should check
+// ctx.Done() regularly and return ctx.Err() when cancelled, and report
+// its progress with tk.SetProgress and tk.SetMessage.
+type TaskFunc func(ctx context.Context, tk *Task) error
+
+// Task is a function running on its own goroutine in the background of a
+// Window (see Window.RunTask), with progress and a cancel button shown in
+// a status row at the bottom of the window.  All the methods are safe to
+// call from any goroutine -- the status row is only updated on the event
+// goroutine of the window (see PostUIFunc).
+type Task struct {
+	Name   string             `desc:"name of the task, shown in the status row"`
+	Win    *Window            `desc:"window running the task"`
+	Ctx    context.Context    `desc:"context for the task, which is cancelled by Cancel"`
+	Cancel context.CancelFunc `desc:"cancels the task -- the task function must check its context to actually stop"`
+	Err    error              `desc:"error returned by the task function -- only valid after it is done"`
+
+	id       int64
+	mu       sync.Mutex
+	progress float32
+	msg      string
+	updtTmr  *time.Timer
+	done     chan struct{}
+	status   *Layout
+	bar      *ProgressBar
+	spin     *Spinner
+	label    *Label
+}
+
+// SetProgress sets the fraction of the task that is complete, from 0 to 1
+// -- a negative value means the amount of progress is not known, which is
+// the initial state.
+func (tk *Task) SetProgress(frac float32) {
+	tk.mu.Lock()
+	tk.progress = frac
+	tk.mu.Unlock()
+	tk.updateLater()
+}
+
+// SetMessage sets the message shown with the progress of the task
+func (tk *Task) SetMessage(msg string) {
+	tk.mu.Lock()
+	tk.msg = msg
+	tk.mu.Unlock()
+	tk.updateLater()
+}
+
+// Progress returns the current progress (negative if not known) and message
+func (tk *Task) Progress() (frac float32, msg string) {
+	tk.mu.Lock()
+	defer tk.mu.Unlock()
+	return tk.progress, tk.msg
+}
+
+// IsDone returns true if the task function has returned
+func (tk *Task) IsDone() bool {
+	select {
+	case <-tk.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait waits until the task function has returned, and returns its error
+func (tk *Task) Wait() error {
+	<-tk.done
+	return tk.Err
+}
+
+// updateLater schedules an update of the status display on the event
+// goroutine of the window, if not already pending, so that frequent
+// progress updates do not swamp the window
+func (tk *Task) updateLater() {
+	tk.mu.Lock()
+	defer tk.mu.Unlock()
+	if tk.updtTmr != nil || tk.status == nil {
+		return
+	}
+	tk.updtTmr = PostUIFuncAfter(tk.Win, time.Duration(TaskUpdateMSec)*time.Millisecond, func() {
+		tk.mu.Lock()
+		tk.updtTmr = nil
+		tk.mu.Unlock()
+		tk.updateStatus()
+	})
+}
+
+// updateStatus updates the status display to the current progress -- only
+// called on the event goroutine of the window
+func (tk *Task) updateStatus() {
+	tk.mu.Lock()
+	frac, msg, stat := tk.progress, tk.msg, tk.status
+	tk.mu.Unlock()
+	if stat == nil || stat.IsDestroyed() || stat.IsDeleted() || tk.Win.IsClosed() {
+		return
+	}
+	if frac >= 0 {
+		if tk.bar.Indeterminate {
+			tk.bar.SetIndeterminate(false)
+		}
+		tk.bar.SetValue(frac)
+	}
+	txt := tk.Name
+	if msg != "" {
+		txt += ": " + msg
+	}
+	if txt != tk.label.Text {
+		tk.label.SetText(txt)
+	}
+}
+
+// configStatus adds the status row for the task to the window, unless it
+// is already done -- the row shows a spinner while the task runs, and a
+// progress bar that is indeterminate until progress is known.  Only called
+// on the event goroutine of the window.
+func (tk *Task) configStatus() {
+	w := tk.Win
+	if w.MasterVLay == nil || w.IsClosed() || tk.IsDone() {
+		return
+	}
+	vp := w.Viewport
+	vp.BlockUpdates()
+	updt := w.MasterVLay.UpdateStart()
+	stat := AddNewLayout(w.MasterVLay, fmt.Sprintf("task-%d", tk.id), LayoutHoriz)
+	stat.SetStretchMaxWidth()
+	stat.SetProp("spacing", StdDialogVSpaceUnits)
+	tk.spin = AddNewSpinner(stat, "spinner")
+	tk.spin.Spinning = true
+	tk.bar = AddNewProgressBar(stat, "progress")
+	tk.bar.Indeterminate = true
+	tk.label = AddNewLabel(stat, "message", tk.Name)
+	tk.label.Redrawable = true
+	tk.label.SetStretchMaxWidth()
+	cb := AddNewAction(stat, "cancel")
+	cb.Icon = "close"
+	cb.Tooltip = fmt.Sprintf("cancel %v", tk.Name)
+	cb.ActionSig.Connect(w.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+		tk.Cancel()
+	})
+	tk.mu.Lock()
+	tk.status = stat
+	tk.mu.Unlock()
+	tk.updateStatus()
+	vp.UnblockUpdates()
+	w.MasterVLay.UpdateEnd(updt)
+}
+
+// deleteStatus removes the status row for the task from the window -- only
+// called on the event goroutine of the window
+func (tk *Task) deleteStatus() {
+	tk.mu.Lock()
+	stat := tk.status
+	tk.status = nil
+	if tk.updtTmr != nil {
+		tk.updtTmr.Stop()
+		tk.updtTmr = nil
+	}
+	tk.mu.Unlock()
+	w := tk.Win
+	if stat == nil || w.IsClosed() {
+		return
+	}
+	tk.spin.Stop()
+	tk.bar.SetIndeterminate(false)
+	vp := w.Viewport
+	vp.BlockUpdates()
+	updt := w.MasterVLay.UpdateStart()
+	w.MasterVLay.DeleteChild(stat.This(), true)
+	vp.UnblockUpdates()
+	w.MasterVLay.UpdateEnd(updt)
+}
+
+// taskID is the id of the last Task, for the unique names of their status
+// rows
+var taskID int64
+
+// RunTask runs given function on its own goroutine, with a status row at
+// the bottom of the window showing the given name, its progress, and a
+// button to cancel it.  The done function, if non-nil, is called on the
+// event goroutine of the window (see PostUIFunc) after the function has
+// returned and the status row has been removed -- tk.Err holds the
+// returned error.  Closing the window cancels all of its tasks.  It can be
+// called from any goroutine.
+func (w *Window) RunTask(name string, fun TaskFunc, done func(tk *Task)) *Task {
+	tk := &Task{Name: name, Win: w, id: atomic.AddInt64(&taskID, 1), progress: -1, done: make(chan struct{})}
+	tk.Ctx, tk.Cancel = context.WithCancel(context.Background())
+	w.TaskMu.Lock()
+	w.Tasks = append(w.Tasks, tk)
+	w.TaskMu.Unlock()
+	PostUIFunc(w, tk.configStatus)
+	go func() {
+		defer tk.Cancel()
+		tk.Err = fun(tk.Ctx, tk)
+		w.TaskMu.Lock()
+		for i, t := range w.Tasks {
+			if t == tk {
+				w.Tasks = append(w.Tasks[:i], w.Tasks[i+1:]...)
+				break
+			}
+		}
+		w.TaskMu.Unlock()
+		close(tk.done)
+		PostUIFunc(w, func() {
+			tk.deleteStatus()
+			if done != nil {
+				done(tk)
+			}
+		})
+	}()
+	return tk
+}
+
+// TaskWindow returns the window of given viewport, or, if it is nil or not
+// in a window, the focused window or the first one -- returns nil if there
+// are no open windows, in which case work is usually done synchronously
+// instead of in a Task.
+func TaskWindow(vp *Viewport2D) *Window {
+	if vp != nil && vp.Win != nil && !vp.Win.IsClosed() {
+		return vp.Win
+	}
+	if w, _ := AllWindows.Focused(); w != nil && !w.IsClosed() {
+		return w
+	}
+	if w := AllWindows.Win(0); w != nil && !w.IsClosed() {
+		return w
+	}
+	return nil
+}
+
+// CancelTasks cancels all the tasks running in this window
+func (w *Window) CancelTasks() {
+	w.TaskMu.Lock()
+	defer w.TaskMu.Unlock()
+	for _, tk := range w.Tasks {
+		tk.Cancel()
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestTaskWindow returns a window without an OS window, with just the
+// layout that holds the status rows of tasks -- its posted functions run
+// in RunPendingUIFuncs
+func newTestTaskWindow() *Window {
+	w := &Window{}
+	w.InitName(w, "task-win")
+	w.Viewport = NewViewport2D(100, 100)
+	w.Viewport.InitName(w.Viewport, "task-vp")
+	w.Viewport.Win = w
+	w.MasterVLay = AddNewLayout(w.Viewport, "main-vlay", LayoutVert)
+	return w
+}
+
+// runUIFuncsUntil runs the pending functions posted to the test window
+// until cond is true, failing after a while
+func runUIFuncsUntil(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for i := 0; i < 500; i++ {
+		RunPendingUIFuncs()
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func TestTaskWait(t *testing.T) {
+	w := newTestTaskWindow()
+	start := make(chan struct{})
+	errTest := errors.New("test error")
+	done := 0
+	tk := w.RunTask("wait", func(ctx context.Context, tk *Task) error {
+		<-start
+		return errTest
+	}, func(tk *Task) {
+		done++
+	})
+	if tk.IsDone() {
+		t.Errorf("done before started")
+	}
+	RunPendingUIFuncs()
+	if w.MasterVLay.NumChildren() != 1 {
+		t.Fatalf("no status row: %v", w.MasterVLay.Kids)
+	}
+	close(start)
+	if err := tk.Wait(); err != errTest {
+		t.Errorf("wait error: %v", err)
+	}
+	runUIFuncsUntil(t, "done", func() bool { return done == 1 })
+	if w.MasterVLay.NumChildren() != 0 {
+		t.Errorf("status row not removed: %v", w.MasterVLay.Kids)
+	}
+	w.TaskMu.Lock()
+	n := len(w.Tasks)
+	w.TaskMu.Unlock()
+	if n != 0 {
+		t.Errorf("tasks left: %d", n)
+	}
+}
+
+func TestTaskCancel(t *testing.T) {
+	w := newTestTaskWindow()
+	var done *Task
+	tk := w.RunTask("cancel", func(ctx context.Context, tk *Task) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, func(tk *Task) {
+		done = tk
+	})
+	RunPendingUIFuncs()
+	w.CancelTasks()
+	if err := tk.Wait(); err != context.Canceled {
+		t.Errorf("cancel error: %v", err)
+	}
+	runUIFuncsUntil(t, "done", func() bool { return done == tk })
+
+	// done before its status row is added, so it never is
+	tk = w.RunTask("quick", func(ctx context.Context, tk *Task) error { return nil }, nil)
+	tk.Wait()
+	RunPendingUIFuncs()
+	if w.MasterVLay.NumChildren() != 0 {
+		t.Errorf("status row of done task: %v", w.MasterVLay.Kids)
+	}
+}
+
+func TestTaskProgress(t *testing.T) {
+	w := newTestTaskWindow()
+	step := make(chan struct{})
+	finish := make(chan struct{})
+	tka := w.RunTask("same", func(ctx context.Context, tk *Task) error {
+		<-step
+		tk.SetProgress(.5)
+		<-finish
+		return nil
+	}, nil)
+	tkb := w.RunTask("same", func(ctx context.Context, tk *Task) error {
+		<-finish
+		return nil
+	}, nil)
+	RunPendingUIFuncs()
+	if w.MasterVLay.NumChildren() != 2 || w.MasterVLay.Child(0).Name() == w.MasterVLay.Child(1).Name() {
+		t.Fatalf("status rows of tasks with same name: %v", w.MasterVLay.Kids)
+	}
+	if !tka.bar.Indeterminate || tka.label.Text != "same" {
+		t.Errorf("initial status: %v %q", tka.bar.Indeterminate, tka.label.Text)
+	}
+	close(step)
+	runUIFuncsUntil(t, "progress", func() bool { return !tka.bar.Indeterminate })
+	if tka.bar.Value != .5 {
+		t.Errorf("progress: %v", tka.bar.Value)
+	}
+	tka.SetMessage("half") // labels can't be rendered without fonts
+	if frac, msg := tka.Progress(); frac != .5 || msg != "half" {
+		t.Errorf("progress: %v %q", frac, msg)
+	}
+	if frac, msg := tkb.Progress(); frac >= 0 || msg != "" {
+		t.Errorf("progress of other task: %v %q", frac, msg)
+	}
+	close(finish)
+	tka.Wait()
+	tkb.Wait()
+	runUIFuncsUntil(t, "rows removed", func() bool { return w.MasterVLay.NumChildren() == 0 })
+}
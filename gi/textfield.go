@@ -85,6 +85,18 @@ func (tf *TextField) CopyFieldsFrom(frm interface{}) {
 	tf.MaxWidthReq = fr.MaxWidthReq
 }
 
+// AccessInfo returns the accessibility info for the text field, named by
+// its Placeholder -- an inactive field is read-only
+func (tf *TextField) AccessInfo() AccessInfo {
+	ai := tf.WidgetBase.AccessInfo()
+	ai.Role = AccessTextBox
+	ai.Name = AccessPlainText(tf.Placeholder)
+	ai.Text = tf.Text()
+	ai.SetState(tf.IsInactive(), AccessReadOnly)
+	ai.SetState(false, AccessInactive)
+	return ai
+}
+
 func (tf *TextField) Disconnect() {
 	tf.PartsWidgetBase.Disconnect()
 	tf.TextFieldSig.DisconnectAll()
@@ -651,6 +663,9 @@ func (tf *TextField) MakeContextMenu(m *Menu) {
 			})
 		ac.SetInactiveState(oswin.TheApp.ClipBoard(tf.ParentWindow().OSWin).IsEmpty())
 	}
+	if tf.CtxtMenuFunc != nil {
+		tf.CtxtMenuFunc(tf.This().(Node2D), m)
+	}
 }
 
 ///////////////////////////////////////////////////////////////////////////////
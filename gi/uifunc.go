@@ -0,0 +1,101 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gi
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// pendingUIFuncs are the functions posted when there is no window to run
+// them -- see RunPendingUIFuncs
+var (
+	pendingUIFuncs  []func()
+	pendingUIFuncMu sync.Mutex
+)
+
+// PostUIFunc arranges for given function to run on the event goroutine of
+// given window, before it processes its next event, and wakes the window up
+// -- this is the way for other goroutines, e.g., of timers, file watchers,
+// network connections or background tasks, to update widgets and the trees
+// that they view, which must only be changed on the event goroutine.  If
+// the window is closed, the function is dropped, as the widgets that it
+// would update are gone.  If the window is nil, the function runs in the
+// focused window, or the first one -- if there are no windows, or the
+// window has no OS window (e.g., in tests), it runs at the next call of
+// RunPendingUIFuncs.  Functions run in the order posted.
+func PostUIFunc(w *Window, fun func()) {
+	if w != nil && w.IsClosed() {
+		log.Printf("gi.PostUIFunc: window %v is closed -- function dropped\n", w.Nm)
+		return
+	}
+	if w == nil {
+		if fw, _ := AllWindows.Focused(); fw != nil && !fw.IsClosed() {
+			w = fw
+		} else if fw := AllWindows.Win(0); fw != nil && !fw.IsClosed() {
+			w = fw
+		}
+	}
+	if w == nil || w.OSWin == nil {
+		pendingUIFuncMu.Lock()
+		pendingUIFuncs = append(pendingUIFuncs, fun)
+		pendingUIFuncMu.Unlock()
+		return
+	}
+	w.UIFuncMu.Lock()
+	w.UIFuncs = append(w.UIFuncs, fun)
+	w.UIFuncMu.Unlock()
+	w.OSWin.SendEmptyEvent()
+}
+
+// PostUIFuncAfter calls PostUIFunc with given window and function after
+// given duration, returning the timer, which can be stopped to not post it
+// -- for delayed updates, e.g., to coalesce a burst of changes.
+func PostUIFuncAfter(w *Window, d time.Duration, fun func()) *time.Timer {
+	return time.AfterFunc(d, func() {
+		PostUIFunc(w, fun)
+	})
+}
+
+// RunUIFuncs runs the functions posted to the window with PostUIFunc, and
+// those posted when there was no window -- called by the event loop before
+// processing each event.
+func (w *Window) RunUIFuncs() {
+	for {
+		w.UIFuncMu.Lock()
+		funs := w.UIFuncs
+		w.UIFuncs = nil
+		w.UIFuncMu.Unlock()
+		if len(funs) == 0 {
+			break
+		}
+		for _, fun := range funs {
+			fun()
+		}
+	}
+	RunPendingUIFuncs()
+}
+
+// RunPendingUIFuncs runs the functions posted with PostUIFunc when there
+// was no window to run them, e.g., in programs or tests without windows,
+// which must call it from the goroutine that uses the widgets and trees --
+// returns true if any were run.
+func RunPendingUIFuncs() bool {
+	ran := false
+	for {
+		pendingUIFuncMu.Lock()
+		funs := pendingUIFuncs
+		pendingUIFuncs = nil
+		pendingUIFuncMu.Unlock()
+		if len(funs) == 0 {
+			return ran
+		}
+		for _, fun := range funs {
+			fun()
+		}
+		ran = true
+	}
+}
@@ -0,0 +1,25 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gi
+
+import "testing"
+
+func TestPostUIFuncClosedWindow(t *testing.T) {
+	w := newTestTaskWindow()
+	ran := false
+	PostUIFunc(w, func() { ran = true })
+	RunPendingUIFuncs()
+	if !ran {
+		t.Errorf("function posted to an open window did not run")
+	}
+
+	w.SetInactive() // marks as closed, as in Window.Close
+	ran = false
+	PostUIFunc(w, func() { ran = true })
+	RunPendingUIFuncs()
+	if ran {
+		t.Errorf("function posted to a closed window ran")
+	}
+}
@@ -105,6 +105,23 @@ func (vp *Viewport2D) CopyFieldsFrom(frm interface{}) {
 	vp.Geom = fr.Geom
 }
 
+// AccessInfo returns the accessibility info for the viewport: the main
+// viewport of a window is the window, named by its title, and popups are
+// menus or dialogs -- other viewports have no role of their own
+func (vp *Viewport2D) AccessInfo() AccessInfo {
+	ai := vp.WidgetBase.AccessInfo()
+	switch {
+	case vp.IsMenu():
+		ai.Role = AccessMenu
+	case vp.IsPopup():
+		ai.Role = AccessDialog
+	case vp.Win != nil && vp.Win.Viewport == vp:
+		ai.Role = AccessWindow
+		ai.Name = vp.Win.Title
+	}
+	return ai
+}
+
 // NewViewport2D creates a new Pixels Image with the specified width and height,
 // and initializes the renderer etc
 func NewViewport2D(width, height int) *Viewport2D {
@@ -748,6 +765,9 @@ func (vp *Viewport2D) NodeUpdated(nii Node2D, sig int64, data interface{}) {
 	if !vp.IsUpdatingNode() {
 		vp.UpdateNodes() // do all pending nodes
 	}
+	if vp.Win != nil && vp.Win.IsAccessOn() {
+		vp.Win.AccessNodeUpdated(nii, sig, data)
+	}
 }
 
 // UpdateLevel deteremines what level of updating a node requires
@@ -155,6 +155,14 @@ type Window struct {
 	PopupFocus        ki.Ki             `json:"-" xml:"-" desc:"node to focus on when next popup is activated -- use SetNextPopup"`
 	DelPopup          ki.Ki             `json:"-" xml:"-" desc:"this popup will be popped at the end of the current event cycle -- use SetDelPopup"`
 	PopMu             sync.RWMutex      `json:"-" xml:"-" view:"-" desc:"read-write mutex that protects popup updating and access"`
+	Tasks             []*Task           `json:"-" xml:"-" view:"-" desc:"background tasks currently running in this window -- see RunTask"`
+	TaskMu            sync.Mutex        `json:"-" xml:"-" view:"-" desc:"mutex that protects Tasks"`
+	UIFuncs           []func()          `json:"-" xml:"-" view:"-" desc:"functions to run on the event goroutine of the window, before processing the next event -- see PostUIFunc"`
+	UIFuncMu          sync.Mutex        `json:"-" xml:"-" view:"-" desc:"mutex that protects UIFuncs"`
+	AccessSig         ki.Signal         `json:"-" xml:"-" view:"-" desc:"signal for changes in the accessibility info of nodes in this window, when AccessOn -- see AccessSignals for the types"`
+	AccessOn          bool              `json:"-" xml:"-" view:"-" desc:"track changes in the accessibility info of nodes, sending AccessSig -- use SetAccessOn"`
+	AccessMu          sync.Mutex        `json:"-" xml:"-" view:"-" desc:"mutex that protects the accessibility info"`
+	accessCache       map[Node2D]AccessInfo
 	lastWinMenuUpdate time.Time
 	// below are internal vars used during the event loop
 	delPop        bool
@@ -732,6 +740,7 @@ func (w *Window) Closed() {
 		return
 	}
 	w.SetInactive() // marks as closed
+	w.CancelTasks()
 	w.FocusInactivate()
 	WindowGlobalMu.Lock()
 	if len(FocusWindows) > 0 {
@@ -1467,6 +1476,7 @@ func (w *Window) EventLoop() {
 
 // ProcessEvent processes given oswin.Event
 func (w *Window) ProcessEvent(evi oswin.Event) {
+	w.RunUIFuncs()
 	et := evi.Type()
 	w.delPop = false                     // if true, delete this popup after event loop
 	if et > oswin.EventTypeN || et < 0 { // we don't handle other types of events here
@@ -2100,6 +2110,29 @@ func (w *Window) KeyChordEventHiPri(e *key.ChordEvent) bool {
 	kf := KeyFun(cs)
 	cpop := w.CurPopup()
 	switch kf {
+	case KeyFunMacroRecord:
+		w.MacroRecordStart()
+		e.SetProcessed()
+		return false
+	case KeyFunMacroStop:
+		w.MacroRecordStop()
+		e.SetProcessed()
+		return false
+	case KeyFunMacroPlay:
+		if w.EventMgr.IsRecordingMacro() { // as in emacs, which has one key for both
+			w.MacroRecordStop()
+		} else {
+			w.PlayKeyMacro(AvailKeyMacros.MacroByName(LastKeyMacroName), 1)
+		}
+		e.SetProcessed()
+		return false
+	case KeyFunMacroPlayN:
+		w.KeyMacroPlayPrompt()
+		e.SetProcessed()
+		return false
+	}
+	w.EventMgr.RecordMacroChord(e)
+	switch kf {
 	case KeyFunWinClose:
 		w.CloseReq()
 		e.SetProcessed()
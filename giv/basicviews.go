@@ -557,7 +557,10 @@ func (vv *BoolValueView) ConfigWidget(widg gi.Node2D) {
 ////////////////////////////////////////////////////////////////////////////////////////
 //  IntValueView
 
-// IntValueView presents a spinbox
+// IntValueView presents a spinbox -- text entered is evaluated as an
+// expression (see gi.EvalNumExpr).  In addition to min, max, step and format
+// tags, a unit tag (e.g., unit:"px") allows entry of numbers with other
+// units, and a history tag (e.g., history:"10") keeps recent values.
 type IntValueView struct {
 	ValueViewBase
 }
@@ -618,6 +621,16 @@ func (vv *IntValueView) ConfigWidget(widg gi.Node2D) {
 	if fmttag, ok := vv.Tag("format"); ok {
 		sb.Format = fmttag
 	}
+	if untag, ok := vv.Tag("unit"); ok {
+		if un, ok := units.UnitFromName(untag); ok {
+			sb.SetUnit(un)
+		}
+	}
+	if histag, ok := vv.Tag("history"); ok {
+		if hmax, ok := kit.ToInt(histag); ok {
+			sb.HistoryMax = int(hmax)
+		}
+	}
 	sb.SpinBoxSig.ConnectOnly(vv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
 		vvv, _ := recv.Embed(KiT_IntValueView).(*IntValueView)
 		sbb := vvv.Widget.(*gi.SpinBox)
@@ -631,7 +644,8 @@ func (vv *IntValueView) ConfigWidget(widg gi.Node2D) {
 ////////////////////////////////////////////////////////////////////////////////////////
 //  FloatValueView
 
-// FloatValueView presents a spinbox
+// FloatValueView presents a spinbox -- text entered is evaluated as an
+// expression (see gi.EvalNumExpr), with the same tags as IntValueView.
 type FloatValueView struct {
 	ValueViewBase
 }
@@ -688,6 +702,16 @@ func (vv *FloatValueView) ConfigWidget(widg gi.Node2D) {
 	if fmttag, ok := vv.Tag("format"); ok {
 		sb.Format = fmttag
 	}
+	if untag, ok := vv.Tag("unit"); ok {
+		if un, ok := units.UnitFromName(untag); ok {
+			sb.SetUnit(un)
+		}
+	}
+	if histag, ok := vv.Tag("history"); ok {
+		if hmax, ok := kit.ToInt(histag); ok {
+			sb.HistoryMax = int(hmax)
+		}
+	}
 
 	sb.SpinBoxSig.ConnectOnly(vv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
 		vvv, _ := recv.Embed(KiT_FloatValueView).(*FloatValueView)
@@ -0,0 +1,100 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package collab
+
+import "errors"
+
+// Client is the state of a client of a Server for the ops on its copy of
+// the document: the op that it has sent and the server has not yet
+// acknowledged, and the ops made since then, which are sent when it is --
+// so there is at most one op in flight.  Ops from the server are
+// transformed against these, and they against the ops from the server.
+// The Client does not communicate itself: the ops it returns are sent by
+// its user, which also applies the ops from the server that it returns.
+type Client struct {
+	Rev         int `desc:"revision of the server document that the client has: the number of ops from the server applied to it"`
+	Outstanding *Op `desc:"op that has been sent to the server and not yet acknowledged, if any"`
+	Buffer      *Op `desc:"ops made while waiting for the acknowledgement of the outstanding op, if any, to be sent when it is"`
+}
+
+// NewClient returns a new client for given revision of the server document
+func NewClient(rev int) *Client {
+	return &Client{Rev: rev}
+}
+
+// Synced returns true if the client has no ops that have not been
+// acknowledged by the server
+func (cl *Client) Synced() bool {
+	return cl.Outstanding == nil
+}
+
+// ApplyLocal records an op made to the client's document, returning the
+// op to send to the server, or nil if it is to be sent later
+func (cl *Client) ApplyLocal(op *Op) (*Op, error) {
+	switch {
+	case cl.Outstanding == nil:
+		cl.Outstanding = op
+		return op, nil
+	case cl.Buffer == nil:
+		cl.Buffer = op
+	default:
+		bop, err := Compose(cl.Buffer, op)
+		if err != nil {
+			return nil, err
+		}
+		cl.Buffer = bop
+	}
+	return nil, nil
+}
+
+// ApplyServer records an op from the server, made by another client,
+// returning the op to apply to the client's document, which has been
+// transformed against the ops of the client that the server does not yet
+// have
+func (cl *Client) ApplyServer(op *Op) (*Op, error) {
+	cl.Rev++
+	if cl.Outstanding == nil {
+		return op, nil
+	}
+	out, op, err := Transform(cl.Outstanding, op)
+	if err != nil {
+		return nil, err
+	}
+	cl.Outstanding = out
+	if cl.Buffer != nil {
+		var buf *Op
+		buf, op, err = Transform(cl.Buffer, op)
+		if err != nil {
+			return nil, err
+		}
+		cl.Buffer = buf
+	}
+	return op, nil
+}
+
+// ServerAck records the acknowledgement by the server of the outstanding
+// op, returning the op to send next, or nil if there is none
+func (cl *Client) ServerAck() (*Op, error) {
+	if cl.Outstanding == nil {
+		return nil, errors.New("collab.Client ServerAck: there is no outstanding op")
+	}
+	cl.Rev++
+	cl.Outstanding = cl.Buffer
+	cl.Buffer = nil
+	return cl.Outstanding, nil
+}
+
+// TransformIndex returns given offset in the server document, e.g., of the
+// cursor of another client, adjusted for the ops of the client that the
+// server does not yet have
+func (cl *Client) TransformIndex(idx int) int {
+	if cl.Outstanding != nil {
+		idx = cl.Outstanding.TransformIndex(idx)
+	}
+	if cl.Buffer != nil {
+		idx = cl.Buffer.TransformIndex(idx)
+	}
+	return idx
+}
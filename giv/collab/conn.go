@@ -0,0 +1,185 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package collab
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"sync"
+)
+
+// the types of messages between clients and the server
+const (
+	// MsgJoin is sent by a client to join the session, with its Name
+	MsgJoin = "join"
+
+	// MsgInit is the reply of the server to MsgJoin, with the Site of the
+	// client, the Text of the document at revision Rev, and the Peers
+	MsgInit = "init"
+
+	// MsgOp is an Op, sent by a client for revision Rev of the server
+	// document, and by the server to the other clients, from the client
+	// at Site, as revision Rev
+	MsgOp = "op"
+
+	// MsgAck is sent by the server to acknowledge the op of a client,
+	// which is revision Rev
+	MsgAck = "ack"
+
+	// MsgSel is a selection, sent by a client for revision Rev of the
+	// server document, and by the server to the other clients, from the
+	// client at Site, with its Name
+	MsgSel = "sel"
+
+	// MsgLeave is sent by the server when the client at Site has left
+	MsgLeave = "leave"
+)
+
+// Sel is a selection in the document, from the Anchor to the Head, which
+// is the position of the cursor -- they are the same if nothing is selected
+type Sel struct {
+	Anchor int `json:"a"`
+	Head   int `json:"h"`
+}
+
+// Transform returns the selection adjusted for given op
+func (sl Sel) Transform(op *Op) Sel {
+	return Sel{Anchor: op.TransformIndex(sl.Anchor), Head: op.TransformIndex(sl.Head)}
+}
+
+// Peer is another client in the session
+type Peer struct {
+	Site int    `json:"site" desc:"unique number of the client in the session"`
+	Name string `json:"name" desc:"name of the user of the client"`
+	Sel  Sel    `json:"sel" desc:"selection of the client"`
+}
+
+// Msg is a message between a client and the server -- see the Msg types
+// for the fields used by each
+type Msg struct {
+	Type  string `json:"type"`
+	Site  int    `json:"site,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Rev   int    `json:"rev"`
+	Op    *Op    `json:"op,omitempty"`
+	Sel   *Sel   `json:"sel,omitempty"`
+	Text  string `json:"text,omitempty"`
+	Peers []Peer `json:"peers,omitempty"`
+}
+
+// Conn is a connection between a client and the server, which is the
+// transport for their messages
+type Conn interface {
+	// Send sends a message
+	Send(msg *Msg) error
+
+	// Recv waits for the next message, returning io.EOF when the
+	// connection has been closed
+	Recv() (*Msg, error)
+
+	// Close closes the connection
+	Close() error
+}
+
+// ErrClosed is returned when sending on a closed connection
+var ErrClosed = errors.New("collab: connection is closed")
+
+// PipeBuffer is the number of messages that can be sent on a Pipe
+// connection before they are received, after which Send waits
+var PipeBuffer = 1024
+
+// pipeConn is one end of an in-process connection
+type pipeConn struct {
+	in, out chan *Msg
+	done    chan struct{} // closed when either end is closed
+	once    *sync.Once
+}
+
+// Pipe returns the two ends of an in-process connection
+func Pipe() (Conn, Conn) {
+	a, b := make(chan *Msg, PipeBuffer), make(chan *Msg, PipeBuffer)
+	done := make(chan struct{})
+	once := &sync.Once{}
+	return &pipeConn{in: a, out: b, done: done, once: once}, &pipeConn{in: b, out: a, done: done, once: once}
+}
+
+func (pc *pipeConn) Send(msg *Msg) error {
+	select {
+	case <-pc.done:
+		return ErrClosed
+	default:
+	}
+	select {
+	case pc.out <- msg:
+		return nil
+	case <-pc.done:
+		return ErrClosed
+	}
+}
+
+func (pc *pipeConn) Recv() (*Msg, error) {
+	select {
+	case msg := <-pc.in:
+		return msg, nil
+	case <-pc.done:
+		select { // deliver messages sent before the close
+		case msg := <-pc.in:
+			return msg, nil
+		default:
+			return nil, io.EOF
+		}
+	}
+}
+
+func (pc *pipeConn) Close() error {
+	pc.once.Do(func() { close(pc.done) })
+	return nil
+}
+
+// streamConn is a connection over a stream, with messages encoded as
+// lines of JSON
+type streamConn struct {
+	rwc io.ReadWriteCloser
+	dec *json.Decoder
+	mu  sync.Mutex // protects writing
+	enc *json.Encoder
+}
+
+// NewStreamConn returns a connection over given stream, e.g., a network
+// connection, with messages encoded as lines of JSON
+func NewStreamConn(rwc io.ReadWriteCloser) Conn {
+	return &streamConn{rwc: rwc, dec: json.NewDecoder(bufio.NewReader(rwc)), enc: json.NewEncoder(rwc)}
+}
+
+func (sc *streamConn) Send(msg *Msg) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.enc.Encode(msg)
+}
+
+func (sc *streamConn) Recv() (*Msg, error) {
+	msg := &Msg{}
+	if err := sc.dec.Decode(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (sc *streamConn) Close() error {
+	return sc.rwc.Close()
+}
+
+// Dial connects to a server listening on given TCP network address, e.g.,
+// "localhost:7412" -- see Server.Listen
+func Dial(addr string) (Conn, error) {
+	nc, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewStreamConn(nc), nil
+}
@@ -0,0 +1,332 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package collab is real-time collaborative editing of a text document by
+// operational transformation.  Each change to the document is an Op, which
+// clients send to a Server that puts the ops of all clients in order,
+// transforming each against the ops that were concurrent with it, and
+// sends them on to the other clients, which transform them against their
+// own ops that the server has not yet acknowledged -- see Client.  Clients
+// also send their selections, which are shown to the other clients.
+// Clients and the server communicate over a Conn, which can be in-process
+// (see Pipe) or over the network (see Dial and Server.Listen).
+//
+// Positions in the document are offsets in characters (runes), with lines
+// ending in a single newline character.
+package collab
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/goki/ki/ints"
+)
+
+// Comp is a component of an Op: it either retains (skips over) characters
+// of the document, deletes characters, or inserts text -- exactly one of
+// the fields is set
+type Comp struct {
+	Retain int    `json:"r,omitempty" desc:"number of characters to keep"`
+	Delete int    `json:"d,omitempty" desc:"number of characters to delete"`
+	Insert string `json:"i,omitempty" desc:"text to insert"`
+}
+
+// IsRetain returns true if the component retains characters
+func (c Comp) IsRetain() bool {
+	return c.Retain > 0
+}
+
+// IsDelete returns true if the component deletes characters
+func (c Comp) IsDelete() bool {
+	return c.Delete > 0
+}
+
+// IsInsert returns true if the component inserts text
+func (c Comp) IsInsert() bool {
+	return c.Insert != ""
+}
+
+// Op is an operation on a text document: a sequence of components that
+// together span the whole document, which is the unit of change sent
+// between clients and the server.  Ops are built with Retain, Insert and
+// Delete, which merge consecutive components of the same kind.
+type Op struct {
+	Comps     []Comp `json:"c" desc:"components of the operation, in order through the document"`
+	BaseLen   int    `json:"b" desc:"length in characters of the document that the op applies to"`
+	TargetLen int    `json:"t" desc:"length in characters of the document after the op is applied"`
+}
+
+// ReplaceOp returns an op on a document of given length that replaces the
+// n characters at offset off with given text
+func ReplaceOp(baseLen, off, n int, text string) *Op {
+	op := &Op{}
+	return op.Retain(off).Delete(n).Insert(text).Retain(baseLen - off - n)
+}
+
+// Retain adds a component that retains n characters, returning the op
+func (op *Op) Retain(n int) *Op {
+	if n <= 0 {
+		return op
+	}
+	op.BaseLen += n
+	op.TargetLen += n
+	if l := len(op.Comps); l > 0 && op.Comps[l-1].IsRetain() {
+		op.Comps[l-1].Retain += n
+	} else {
+		op.Comps = append(op.Comps, Comp{Retain: n})
+	}
+	return op
+}
+
+// Insert adds a component that inserts given text, returning the op --
+// an insert directly after a delete is put before it, so that ops that
+// have the same effect have the same components
+func (op *Op) Insert(text string) *Op {
+	if text == "" {
+		return op
+	}
+	op.TargetLen += utf8.RuneCountInString(text)
+	l := len(op.Comps)
+	switch {
+	case l > 0 && op.Comps[l-1].IsInsert():
+		op.Comps[l-1].Insert += text
+	case l > 0 && op.Comps[l-1].IsDelete():
+		if l > 1 && op.Comps[l-2].IsInsert() {
+			op.Comps[l-2].Insert += text
+		} else {
+			op.Comps = append(op.Comps, op.Comps[l-1])
+			op.Comps[l-1] = Comp{Insert: text}
+		}
+	default:
+		op.Comps = append(op.Comps, Comp{Insert: text})
+	}
+	return op
+}
+
+// Delete adds a component that deletes n characters, returning the op
+func (op *Op) Delete(n int) *Op {
+	if n <= 0 {
+		return op
+	}
+	op.BaseLen += n
+	if l := len(op.Comps); l > 0 && op.Comps[l-1].IsDelete() {
+		op.Comps[l-1].Delete += n
+	} else {
+		op.Comps = append(op.Comps, Comp{Delete: n})
+	}
+	return op
+}
+
+// IsNoop returns true if the op does not change the document
+func (op *Op) IsNoop() bool {
+	return len(op.Comps) == 0 || (len(op.Comps) == 1 && op.Comps[0].IsRetain())
+}
+
+// String returns a compact representation of the op, for debugging
+func (op *Op) String() string {
+	s := ""
+	for i, c := range op.Comps {
+		if i > 0 {
+			s += ", "
+		}
+		switch {
+		case c.IsRetain():
+			s += fmt.Sprintf("retain %d", c.Retain)
+		case c.IsDelete():
+			s += fmt.Sprintf("delete %d", c.Delete)
+		default:
+			s += fmt.Sprintf("insert %q", c.Insert)
+		}
+	}
+	return s
+}
+
+// Apply returns the document resulting from applying the op to given
+// document, which must have the base length of the op
+func (op *Op) Apply(doc []rune) ([]rune, error) {
+	if len(doc) != op.BaseLen {
+		return nil, fmt.Errorf("collab.Op Apply: document length %d is not the base length of the op: %d", len(doc), op.BaseLen)
+	}
+	nd := make([]rune, 0, op.TargetLen)
+	i := 0
+	for _, c := range op.Comps {
+		switch {
+		case c.IsRetain():
+			nd = append(nd, doc[i:i+c.Retain]...)
+			i += c.Retain
+		case c.IsDelete():
+			i += c.Delete
+		default:
+			nd = append(nd, []rune(c.Insert)...)
+		}
+	}
+	return nd, nil
+}
+
+// TransformIndex returns given offset in the document that the op applies
+// to, e.g., of a cursor, adjusted to the document after the op -- text
+// inserted at the offset is put before it
+func (op *Op) TransformIndex(idx int) int {
+	nidx := idx
+	for _, c := range op.Comps {
+		if idx < 0 {
+			break
+		}
+		switch {
+		case c.IsRetain():
+			idx -= c.Retain
+		case c.IsInsert():
+			nidx += utf8.RuneCountInString(c.Insert)
+		default:
+			nidx -= ints.MinInt(idx, c.Delete)
+			idx -= c.Delete
+		}
+	}
+	return nidx
+}
+
+// opIter iterates over the components of an op, splitting them as needed
+type opIter struct {
+	comps []Comp
+	i     int
+	cur   Comp
+	ok    bool
+}
+
+func newOpIter(op *Op) *opIter {
+	it := &opIter{comps: op.Comps}
+	it.next()
+	return it
+}
+
+// next moves to the next component
+func (it *opIter) next() {
+	it.ok = it.i < len(it.comps)
+	if it.ok {
+		it.cur = it.comps[it.i]
+		it.i++
+	}
+}
+
+// length returns the length of the current component
+func (it *opIter) length() int {
+	switch {
+	case it.cur.IsRetain():
+		return it.cur.Retain
+	case it.cur.IsDelete():
+		return it.cur.Delete
+	}
+	return utf8.RuneCountInString(it.cur.Insert)
+}
+
+// take consumes n characters of the current retain or delete component,
+// moving to the next component when it is used up
+func (it *opIter) take(n int) {
+	switch {
+	case it.cur.IsRetain():
+		it.cur.Retain -= n
+		if it.cur.Retain == 0 {
+			it.next()
+		}
+	case it.cur.IsDelete():
+		it.cur.Delete -= n
+		if it.cur.Delete == 0 {
+			it.next()
+		}
+	}
+}
+
+// Compose returns the op that has the effect of applying a and then b
+func Compose(a, b *Op) (*Op, error) {
+	if a.TargetLen != b.BaseLen {
+		return nil, fmt.Errorf("collab.Compose: the target length of the first op: %d is not the base length of the second: %d", a.TargetLen, b.BaseLen)
+	}
+	op := &Op{}
+	ia, ib := newOpIter(a), newOpIter(b)
+	for ia.ok || ib.ok {
+		switch {
+		case ia.ok && ia.cur.IsDelete():
+			op.Delete(ia.cur.Delete)
+			ia.next()
+			continue
+		case ib.ok && ib.cur.IsInsert():
+			op.Insert(ib.cur.Insert)
+			ib.next()
+			continue
+		case !ia.ok || !ib.ok:
+			return nil, fmt.Errorf("collab.Compose: ops have different lengths")
+		}
+		switch {
+		case ia.cur.IsRetain() && ib.cur.IsRetain():
+			n := ints.MinInt(ia.length(), ib.length())
+			op.Retain(n)
+			ia.take(n)
+			ib.take(n)
+		case ia.cur.IsInsert() && ib.cur.IsDelete():
+			rs := []rune(ia.cur.Insert)
+			n := ints.MinInt(len(rs), ib.cur.Delete)
+			ia.cur.Insert = string(rs[n:])
+			if ia.cur.Insert == "" {
+				ia.next()
+			}
+			ib.take(n)
+		case ia.cur.IsInsert() && ib.cur.IsRetain():
+			rs := []rune(ia.cur.Insert)
+			n := ints.MinInt(len(rs), ib.cur.Retain)
+			op.Insert(string(rs[:n]))
+			ia.cur.Insert = string(rs[n:])
+			if ia.cur.Insert == "" {
+				ia.next()
+			}
+			ib.take(n)
+		default: // retain in a and delete in b
+			n := ints.MinInt(ia.cur.Retain, ib.cur.Delete)
+			op.Delete(n)
+			ia.take(n)
+			ib.take(n)
+		}
+	}
+	return op, nil
+}
+
+// Transform transforms two concurrent ops a and b, which apply to the same
+// document, into ops ap and bp such that applying a and then bp has the
+// same result as applying b and then ap.  Text inserted by both at the
+// same place is put in the order a, b.
+func Transform(a, b *Op) (ap, bp *Op, err error) {
+	if a.BaseLen != b.BaseLen {
+		return nil, nil, fmt.Errorf("collab.Transform: ops have different base lengths: %d, %d", a.BaseLen, b.BaseLen)
+	}
+	ap, bp = &Op{}, &Op{}
+	ia, ib := newOpIter(a), newOpIter(b)
+	for ia.ok || ib.ok {
+		switch {
+		case ia.ok && ia.cur.IsInsert():
+			ap.Insert(ia.cur.Insert)
+			bp.Retain(ia.length())
+			ia.next()
+			continue
+		case ib.ok && ib.cur.IsInsert():
+			ap.Retain(ib.length())
+			bp.Insert(ib.cur.Insert)
+			ib.next()
+			continue
+		case !ia.ok || !ib.ok:
+			return nil, nil, fmt.Errorf("collab.Transform: ops have different lengths")
+		}
+		n := ints.MinInt(ia.length(), ib.length())
+		switch {
+		case ia.cur.IsRetain() && ib.cur.IsRetain():
+			ap.Retain(n)
+			bp.Retain(n)
+		case ia.cur.IsDelete() && ib.cur.IsRetain():
+			ap.Delete(n)
+		case ia.cur.IsRetain() && ib.cur.IsDelete():
+			bp.Delete(n)
+		} // both delete: already deleted by the other
+		ia.take(n)
+		ib.take(n)
+	}
+	return ap, bp, nil
+}
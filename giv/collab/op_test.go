@@ -0,0 +1,80 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package collab
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// randomOp returns a random op on given document
+func randomOp(rnd *rand.Rand, doc []rune) *Op {
+	op := &Op{}
+	for i := 0; i < len(doc); {
+		n := 1 + rnd.Intn(len(doc)-i)
+		switch rnd.Intn(3) {
+		case 0:
+			op.Retain(n)
+		case 1:
+			op.Delete(n)
+		default:
+			op.Insert(string([]rune("abcé\nxyz")[:1+rnd.Intn(8)]))
+			op.Retain(n)
+		}
+		i += n
+	}
+	if rnd.Intn(2) == 0 {
+		op.Insert("ÿ")
+	}
+	return op
+}
+
+func TestOpApply(t *testing.T) {
+	op := ReplaceOp(11, 6, 5, "there")
+	doc, err := op.Apply([]rune("hello world"))
+	if err != nil || string(doc) != "hello there" {
+		t.Errorf("apply: %q %v", string(doc), err)
+	}
+	if _, err := op.Apply([]rune("hello")); err == nil {
+		t.Errorf("apply to wrong length should fail")
+	}
+	op = (&Op{}).Retain(2).Delete(3).Insert("ab").Insert("c")
+	if len(op.Comps) != 3 || op.Comps[1].Insert != "abc" || op.Comps[2].Delete != 3 {
+		t.Errorf("insert is put before delete: %v", op)
+	}
+	if idx := ReplaceOp(10, 2, 3, "xy").TransformIndex(4); idx != 4 {
+		t.Errorf("index in deleted text: %d", idx)
+	}
+	if idx := ReplaceOp(10, 2, 0, "xy").TransformIndex(2); idx != 4 {
+		t.Errorf("index at insert: %d", idx)
+	}
+}
+
+func TestOpTransform(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		doc := []rune("lorem ipsum\ndolor sit amet"[:rnd.Intn(26)])
+		a, b := randomOp(rnd, doc), randomOp(rnd, doc)
+		ap, bp, err := Transform(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		da, _ := a.Apply(doc)
+		db, _ := b.Apply(doc)
+		dab, err1 := bp.Apply(da)
+		dba, err2 := ap.Apply(db)
+		if err1 != nil || err2 != nil || string(dab) != string(dba) {
+			t.Fatalf("transform of %v and %v on %q: %q != %q %v %v", a, b, string(doc), string(dab), string(dba), err1, err2)
+		}
+		ab, err := Compose(a, bp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dc, _ := ab.Apply(doc)
+		if string(dc) != string(dab) {
+			t.Fatalf("compose of %v and %v on %q: %q != %q", a, bp, string(doc), string(dc), string(dab))
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package collab
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+)
+
+// Server is the server of a collaborative editing session, which has the
+// reference copy of the document and the history of ops made to it.  It
+// serves any number of clients, each on its own Conn (see Serve): ops
+// from a client are transformed against the ops made since the revision
+// that the client had, applied, acknowledged, and sent on to the other
+// clients.  A client that falls more than PipeBuffer messages behind is
+// disconnected, so that it does not hold up the others.
+type Server struct {
+	Doc      []rune `desc:"the document, at the current revision"`
+	History  []*Op  `desc:"all the ops applied to the document, in order -- the revision is the number of ops"`
+	mu       sync.Mutex
+	sites    map[int]*serverSite
+	nextSite int
+	ln       net.Listener
+}
+
+// serverSite is a client of the server
+type serverSite struct {
+	Peer
+	out  chan *Msg // messages to send, by the writer goroutine -- see send
+	conn Conn
+	full bool // out was full, and the connection has been closed
+}
+
+// NewServer returns a new server for a document with given text
+func NewServer(text string) *Server {
+	return &Server{Doc: []rune(text), sites: make(map[int]*serverSite), nextSite: 1}
+}
+
+// Rev returns the current revision of the document
+func (sv *Server) Rev() int {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	return len(sv.History)
+}
+
+// Text returns the current text of the document
+func (sv *Server) Text() string {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	return string(sv.Doc)
+}
+
+// Peers returns the clients in the session, in order of site
+func (sv *Server) Peers() []Peer {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	return sv.peers(0)
+}
+
+// peers returns the clients other than given site, in order -- must be
+// called under mu lock
+func (sv *Server) peers(site int) []Peer {
+	var ps []Peer
+	for _, st := range sv.sites {
+		if st.Site != site {
+			ps = append(ps, st.Peer)
+		}
+	}
+	sort.Slice(ps, func(i, j int) bool {
+		return ps[i].Site < ps[j].Site
+	})
+	return ps
+}
+
+// Listen accepts connections from clients on given network listener, and
+// serves each of them, until the listener or the server is closed
+func (sv *Server) Listen(ln net.Listener) error {
+	sv.mu.Lock()
+	sv.ln = ln
+	sv.mu.Unlock()
+	for {
+		nc, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go sv.Serve(NewStreamConn(nc))
+	}
+}
+
+// Close closes the listener, if any, and the connections of all clients
+func (sv *Server) Close() {
+	sv.mu.Lock()
+	if sv.ln != nil {
+		sv.ln.Close()
+		sv.ln = nil
+	}
+	var conns []Conn
+	for _, st := range sv.sites {
+		conns = append(conns, st.conn)
+	}
+	sv.mu.Unlock()
+	for _, c := range conns {
+		c.Close()
+	}
+}
+
+// Serve serves a client on given connection, until it is closed: it must
+// first send MsgJoin, and then sends ops and selections
+func (sv *Server) Serve(conn Conn) error {
+	defer conn.Close()
+	msg, err := conn.Recv()
+	if err != nil {
+		return err
+	}
+	if msg.Type != MsgJoin {
+		return fmt.Errorf("collab.Server: client must join first, not send: %v", msg.Type)
+	}
+	sv.mu.Lock()
+	st := &serverSite{Peer: Peer{Site: sv.nextSite, Name: msg.Name}, out: make(chan *Msg, PipeBuffer), conn: conn}
+	sv.nextSite++
+	st.send(&Msg{Type: MsgInit, Site: st.Site, Rev: len(sv.History), Text: string(sv.Doc), Peers: sv.peers(0)})
+	sv.sites[st.Site] = st
+	sv.sendOthers(st.Site, &Msg{Type: MsgSel, Site: st.Site, Name: st.Name, Rev: len(sv.History), Sel: &Sel{}})
+	sv.mu.Unlock()
+	go st.write()
+
+	for {
+		msg, err = conn.Recv()
+		if err != nil {
+			break
+		}
+		if err = sv.handle(st, msg); err != nil {
+			break
+		}
+	}
+	sv.mu.Lock()
+	delete(sv.sites, st.Site)
+	close(st.out)
+	sv.sendOthers(st.Site, &Msg{Type: MsgLeave, Site: st.Site, Rev: len(sv.History)})
+	sv.mu.Unlock()
+	if err == io.EOF {
+		err = nil
+	}
+	return err
+}
+
+// write sends the messages for the site, until its out channel is closed
+func (st *serverSite) write() {
+	for msg := range st.out {
+		if st.conn.Send(msg) != nil {
+			break
+		}
+	}
+	for range st.out { // drain if the connection failed
+	}
+}
+
+// send queues given message for the writer goroutine of the site,
+// without waiting, as it is called under the mu lock of the server: if
+// the queue is full, the client is not keeping up, and its connection is
+// closed, so it leaves the session (it can join again, and get the
+// current text) instead of blocking the server and all the other clients
+func (st *serverSite) send(msg *Msg) {
+	if st.full {
+		return
+	}
+	select {
+	case st.out <- msg:
+	default:
+		st.full = true
+		st.conn.Close()
+	}
+}
+
+// sendOthers sends given message to the sites other than given one --
+// must be called under mu lock
+func (sv *Server) sendOthers(site int, msg *Msg) {
+	for _, st := range sv.sites {
+		if st.Site != site {
+			st.send(msg)
+		}
+	}
+}
+
+// handle handles a message from given site
+func (sv *Server) handle(st *serverSite, msg *Msg) error {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	rev := len(sv.History)
+	if msg.Rev < 0 || msg.Rev > rev {
+		return fmt.Errorf("collab.Server: invalid revision: %d, current is: %d", msg.Rev, rev)
+	}
+	switch msg.Type {
+	case MsgOp:
+		if msg.Op == nil {
+			return fmt.Errorf("collab.Server: op message without op")
+		}
+		op := msg.Op
+		for _, hop := range sv.History[msg.Rev:] {
+			var err error
+			op, _, err = Transform(op, hop)
+			if err != nil {
+				return err
+			}
+		}
+		doc, err := op.Apply(sv.Doc)
+		if err != nil {
+			return err
+		}
+		sv.Doc = doc
+		sv.History = append(sv.History, op)
+		rev++
+		for _, ost := range sv.sites {
+			ost.Sel = ost.Sel.Transform(op)
+		}
+		st.send(&Msg{Type: MsgAck, Rev: rev})
+		sv.sendOthers(st.Site, &Msg{Type: MsgOp, Site: st.Site, Rev: rev, Op: op})
+	case MsgSel:
+		if msg.Sel == nil {
+			return fmt.Errorf("collab.Server: selection message without selection")
+		}
+		sel := *msg.Sel
+		for _, hop := range sv.History[msg.Rev:] {
+			sel = sel.Transform(hop)
+		}
+		st.Sel = sel
+		sv.sendOthers(st.Site, &Msg{Type: MsgSel, Site: st.Site, Name: st.Name, Rev: rev, Sel: &sel})
+	default:
+		return fmt.Errorf("collab.Server: unexpected message: %v", msg.Type)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package collab
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSite is a client with its document, which applies the messages
+// from the server as they are received
+type testSite struct {
+	conn  Conn
+	cl    *Client
+	site  int
+	mu    sync.Mutex
+	doc   []rune
+	peers map[int]Sel
+}
+
+func joinTest(t *testing.T, conn Conn, name string) *testSite {
+	if err := conn.Send(&Msg{Type: MsgJoin, Name: name}); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := conn.Recv()
+	if err != nil || msg.Type != MsgInit {
+		t.Fatalf("init: %+v %v", msg, err)
+	}
+	ts := &testSite{conn: conn, cl: NewClient(msg.Rev), site: msg.Site, doc: []rune(msg.Text), peers: map[int]Sel{}}
+	go ts.recv(t)
+	return ts
+}
+
+func (ts *testSite) recv(t *testing.T) {
+	for {
+		msg, err := ts.conn.Recv()
+		if err != nil {
+			return
+		}
+		ts.mu.Lock()
+		switch msg.Type {
+		case MsgOp:
+			op, err := ts.cl.ApplyServer(msg.Op)
+			if err == nil {
+				ts.doc, err = op.Apply(ts.doc)
+			}
+			if err != nil {
+				t.Error(err)
+			}
+			for s, sel := range ts.peers {
+				ts.peers[s] = sel.Transform(op)
+			}
+		case MsgAck:
+			if send, _ := ts.cl.ServerAck(); send != nil {
+				ts.conn.Send(&Msg{Type: MsgOp, Rev: ts.cl.Rev, Op: send})
+			}
+		case MsgSel:
+			ts.peers[msg.Site] = Sel{Anchor: ts.cl.TransformIndex(msg.Sel.Anchor), Head: ts.cl.TransformIndex(msg.Sel.Head)}
+		case MsgLeave:
+			delete(ts.peers, msg.Site)
+		}
+		ts.mu.Unlock()
+	}
+}
+
+// edit replaces n characters at off with text
+func (ts *testSite) edit(off, n int, text string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	op := ReplaceOp(len(ts.doc), off, n, text)
+	ts.doc, _ = op.Apply(ts.doc)
+	for s, sel := range ts.peers {
+		ts.peers[s] = sel.Transform(op)
+	}
+	if send, _ := ts.cl.ApplyLocal(op); send != nil {
+		ts.conn.Send(&Msg{Type: MsgOp, Rev: ts.cl.Rev, Op: send})
+	}
+}
+
+func (ts *testSite) text() (string, bool) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return string(ts.doc), ts.cl.Synced()
+}
+
+// waitSynced waits until all the sites have the text of the server
+func waitSynced(t *testing.T, sv *Server, sites ...*testSite) {
+	for i := 0; i < 500; i++ {
+		ok := true
+		for _, ts := range sites {
+			txt, synced := ts.text()
+			if !synced || txt != sv.Text() {
+				ok = false
+			}
+		}
+		if ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, ts := range sites {
+		txt, _ := ts.text()
+		t.Errorf("site %d: %q", ts.site, txt)
+	}
+	t.Fatalf("not synced with server: %q", sv.Text())
+}
+
+func TestServer(t *testing.T) {
+	sv := NewServer("hello world")
+	var sites []*testSite
+	for _, nm := range []string{"ann", "bob", "cat"} {
+		c, s := Pipe()
+		go sv.Serve(s)
+		sites = append(sites, joinTest(t, c, nm))
+	}
+	if ps := sv.Peers(); len(ps) != 3 || ps[1].Name != "bob" {
+		t.Errorf("peers: %+v", ps)
+	}
+	var wg sync.WaitGroup
+	for i, ts := range sites {
+		wg.Add(1)
+		go func(ts *testSite, seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+			for j := 0; j < 100; j++ {
+				txt, _ := ts.text()
+				l := len([]rune(txt))
+				off := rnd.Intn(l + 1)
+				n := 0
+				if off < l && rnd.Intn(3) == 0 {
+					n = 1 + rnd.Intn(l-off)
+					if n > 3 {
+						n = 3
+					}
+				}
+				ts.edit(off, n, "xy\n"[:rnd.Intn(4)])
+				if rnd.Intn(5) == 0 {
+					time.Sleep(time.Millisecond)
+				}
+			}
+		}(ts, int64(i))
+	}
+	wg.Wait()
+	waitSynced(t, sv, sites...)
+	if sv.Rev() == 0 {
+		t.Errorf("no ops applied")
+	}
+
+	// selections are transformed by later ops
+	ann, bob := sites[0], sites[1]
+	ann.edit(0, len(ann.doc), "abcdef")
+	waitSynced(t, sv, sites...)
+	ann.mu.Lock()
+	ann.conn.Send(&Msg{Type: MsgSel, Rev: ann.cl.Rev, Sel: &Sel{Anchor: 2, Head: 4}})
+	ann.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	bob.edit(0, 0, "zz")
+	waitSynced(t, sv, sites...)
+	bob.mu.Lock()
+	if sel := bob.peers[ann.site]; sel != (Sel{Anchor: 4, Head: 6}) {
+		t.Errorf("selection of ann at bob: %+v", sel)
+	}
+	bob.mu.Unlock()
+	ann.conn.Close()
+	time.Sleep(20 * time.Millisecond)
+	bob.mu.Lock()
+	if _, has := bob.peers[ann.site]; has {
+		t.Errorf("ann has not left")
+	}
+	bob.mu.Unlock()
+}
+
+func TestServerTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	sv := NewServer("one\ntwo")
+	go sv.Listen(ln)
+	defer sv.Close()
+	var sites []*testSite
+	for _, nm := range []string{"ann", "bob"} {
+		c, err := Dial(ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		sites = append(sites, joinTest(t, c, nm))
+	}
+	sites[0].edit(3, 0, " 1")
+	sites[1].edit(7, 0, " 2")
+	waitSynced(t, sv, sites...)
+	if txt := sv.Text(); txt != "one 1\ntwo 2" {
+		t.Errorf("text: %q", txt)
+	}
+}
+
+func TestServerSlowSite(t *testing.T) {
+	defer func(n int) { PipeBuffer = n }(PipeBuffer)
+	PipeBuffer = 4
+	sv := NewServer("")
+	c, s := Pipe()
+	go sv.Serve(s)
+	ann := joinTest(t, c, "ann")
+	// bob joins, but never reads what the server sends
+	bc, bs := Pipe()
+	go sv.Serve(bs)
+	bc.Send(&Msg{Type: MsgJoin, Name: "bob"})
+	for i := 0; i < 50 && len(sv.Peers()) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			ann.edit(i, 0, "x")
+			for _, synced := ann.text(); !synced; _, synced = ann.text() {
+				time.Sleep(time.Millisecond)
+			}
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("edits blocked by a client that is not reading")
+	}
+	waitSynced(t, sv, ann)
+	if ps := sv.Peers(); len(ps) != 1 || ps[0].Name != "ann" {
+		t.Errorf("client that is not reading was not disconnected: %+v", ps)
+	}
+}
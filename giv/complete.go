@@ -2,8 +2,10 @@ package giv
 
 import (
 	"fmt"
+	"unicode"
 
 	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/lsp"
 	"github.com/goki/gi/giv/textbuf"
 	"github.com/goki/pi/complete"
 	"github.com/goki/pi/lex"
@@ -21,20 +23,14 @@ func CompletePi(data interface{}, text string, posLn, posCh int) (md complete.Ma
 		return md
 	}
 	lp, err := pi.LangSupport.Props(sfs.Sup)
-	if err != nil {
-		// log.Printf("CompletePi: %v\n", err)
-		return md
-	}
-	if lp.Lang == nil {
-		return md
-	}
-
-	// note: must have this set to ture to allow viewing of AST
-	// must set it in pi/parse directly -- so it is changed in the fileparse too
-	parse.GuiActive = true // note: this is key for debugging -- runs slower but makes the tree unique
+	if err == nil && lp.Lang != nil {
+		// note: must have this set to ture to allow viewing of AST
+		// must set it in pi/parse directly -- so it is changed in the fileparse too
+		parse.GuiActive = true // note: this is key for debugging -- runs slower but makes the tree unique
 
-	md = lp.Lang.CompleteLine(sfs, text, lex.Pos{posLn, posCh})
-	return md
+		md = lp.Lang.CompleteLine(sfs, text, lex.Pos{posLn, posCh})
+	}
+	return AddSnippetMatches(sfs.Sup, text, md)
 }
 
 // CompleteEditPi uses the selected completion to edit the text
@@ -118,3 +114,109 @@ func CompleteTextEdit(data interface{}, text string, cursorPos int, completion c
 	ed = gi.CompleteEditText(text, cursorPos, completion.Text, seed)
 	return ed
 }
+
+// CompleteLSP uses the language server of the TextBuf -- the string is a
+// line of text up to point where user has typed.
+// The data must be the *TextBuf.
+func CompleteLSP(data interface{}, text string, posLn, posCh int) (md complete.Matches) {
+	tb := data.(*TextBuf)
+	if tb == nil || tb.LSP == nil {
+		return md
+	}
+	tb.LinesMu.RLock()
+	lp := tb.LSPPos(lex.Pos{posLn, posCh})
+	tb.LinesMu.RUnlock()
+	items, err := tb.LSP.Completion(tb.LSPURI(), lp)
+	if err != nil {
+		// log.Printf("CompleteLSP: %v\n", err)
+		return AddSnippetMatches(tb.Info.Sup, text, md)
+	}
+	md.Seed = SeedIdent(text)
+	for i := range items {
+		it := &items[i]
+		desc := it.Detail
+		if it.Documentation != "" {
+			desc += "\n\n" + string(it.Documentation)
+		}
+		md.Matches = append(md.Matches, complete.Completion{Text: it.Text(), Label: it.Label, Icon: LSPKindIcon(it.Kind), Desc: desc})
+	}
+	return AddSnippetMatches(tb.Info.Sup, text, md)
+}
+
+// CompleteEditLSP uses the selected completion to edit the text
+func CompleteEditLSP(data interface{}, text string, cursorPos int, comp complete.Completion, seed string) (ed complete.Edit) {
+	return gi.CompleteEditText(text, cursorPos, comp.Text, seed)
+}
+
+// LookupLSP uses the language server of the TextBuf to go to the definition
+// of the symbol at the given position -- in the current view if it is in
+// the same file, and otherwise in a dialog -- or to show its hover
+// information if the definition is not available.
+// The data must be the *TextBuf.
+func LookupLSP(data interface{}, text string, posLn, posCh int) (ld complete.Lookup) {
+	tb := data.(*TextBuf)
+	if tb == nil || tb.LSP == nil {
+		return ld
+	}
+	tb.LinesMu.RLock()
+	lp := tb.LSPPos(lex.Pos{posLn, posCh})
+	tb.LinesMu.RUnlock()
+	uri := tb.LSPURI()
+	locs, _ := tb.LSP.Definition(uri, lp)
+	if len(locs) == 0 {
+		hv, _ := tb.LSP.Hover(uri, lp)
+		if hv != "" {
+			ld.Text = []byte(hv)
+			TextViewDialog(nil, ld.Text, DlgOpts{Title: "Lookup: " + text})
+		}
+		return ld
+	}
+	loc := locs[0]
+	if loc.URI == uri && tb.CurView != nil {
+		tb.LinesMu.RLock()
+		pos := tb.PosFromLSP(loc.Range.Start)
+		tb.LinesMu.RUnlock()
+		tb.CurView.SavePosHistory(tb.CurView.CursorPos)
+		tb.CurView.SetCursorShow(pos)
+		return ld
+	}
+	ld.Filename = lsp.URIFile(loc.URI)
+	ld.StLine = loc.Range.Start.Line
+	ld.EdLine = loc.Range.End.Line + 20 // show some of the body
+
+	txt := textbuf.FileRegionBytes(ld.Filename, ld.StLine, ld.EdLine, true, 10) // comments, 10 lines back max
+	prmpt := fmt.Sprintf("%v [%d:%d]", ld.Filename, ld.StLine+1, loc.Range.Start.Character+1)
+	TextViewDialog(nil, txt, DlgOpts{Title: "Lookup: " + text, Prompt: prmpt, Filename: ld.Filename, LineNos: true, Data: prmpt})
+	return ld
+}
+
+// SeedIdent returns the identifier at the end of given text, which is
+// the seed for code completion
+func SeedIdent(text string) string {
+	rs := []rune(text)
+	st := len(rs)
+	for st > 0 && (unicode.IsLetter(rs[st-1]) || unicode.IsDigit(rs[st-1]) || rs[st-1] == '_') {
+		st--
+	}
+	return string(rs[st:])
+}
+
+// LSPKindIcon returns the completion icon for given kind of language
+// server completion item
+func LSPKindIcon(kind lsp.CompletionItemKind) string {
+	switch kind {
+	case lsp.CompletionMethod, lsp.CompletionFunction, lsp.CompletionConstructor:
+		return "function"
+	case lsp.CompletionField, lsp.CompletionProperty:
+		return "field"
+	case lsp.CompletionVariable:
+		return "var"
+	case lsp.CompletionClass, lsp.CompletionInterface, lsp.CompletionStruct, lsp.CompletionEnum, lsp.CompletionTypeParameter:
+		return "type"
+	case lsp.CompletionConstant, lsp.CompletionEnumMember, lsp.CompletionValue:
+		return "const"
+	case lsp.CompletionModule:
+		return "types"
+	}
+	return ""
+}
@@ -6,6 +6,8 @@ package giv
 
 import (
 	"bytes"
+	"context"
+	"log"
 
 	"github.com/goki/gi/gi"
 	"github.com/goki/gi/giv/textbuf"
@@ -27,33 +29,88 @@ import (
 // if empty, defaults to: A = current HEAD, B = current WC file.
 // -1, -2 etc also work as universal ways of specifying prior revisions.
 func DiffViewDialogFromRevs(avp *gi.Viewport2D, repo vci.Repo, file string, fbuf *TextBuf, rev_a, rev_b string) (*DiffView, error) {
-	var astr, bstr []string
-	if rev_b == "" { // default to current file
-		if fbuf != nil {
-			bstr = fbuf.Strings(false)
-		} else {
-			fb, err := textbuf.FileBytes(file)
-			if err != nil {
-				return nil, err
+	bstr, err := diffRevCurLines(file, fbuf, rev_b)
+	if err != nil {
+		return nil, err
+	}
+	astr, bstr, err := diffRevLines(repo, file, bstr, rev_a, rev_b)
+	if err != nil {
+		return nil, err
+	}
+	if rev_a == "" {
+		rev_a = "HEAD"
+	}
+	return DiffViewDialog(nil, astr, bstr, file, file, rev_a, rev_b, DlgOpts{Title: "DiffVcs: " + DirAndFile(file)}), nil
+}
+
+// DiffViewDialogFromRevsTask is DiffViewDialogFromRevs with the revisions
+// retrieved from the repository in a Task of the window of given viewport
+// (see gi.TaskWindow), so that slow repositories do not block the window --
+// the dialog opens when they have been retrieved, or a dialog shows the
+// error.  If there is no window, it runs synchronously and returns nil.
+func DiffViewDialogFromRevsTask(avp *gi.Viewport2D, repo vci.Repo, file string, fbuf *TextBuf, rev_a, rev_b string) *gi.Task {
+	win := gi.TaskWindow(avp)
+	if win == nil {
+		if _, err := DiffViewDialogFromRevs(avp, repo, file, fbuf, rev_a, rev_b); err != nil {
+			log.Println(err)
+		}
+		return nil
+	}
+	bstr, err := diffRevCurLines(file, fbuf, rev_b) // buffer only read here
+	if err != nil {
+		gi.PromptDialog(avp, gi.DlgOpts{Title: "DiffVcs Error", Prompt: err.Error()}, gi.AddOk, gi.NoCancel, nil, nil)
+		return nil
+	}
+	var astr []string
+	return win.RunTask("Retrieving revisions of "+DirAndFile(file), func(ctx context.Context, tk *gi.Task) error {
+		var err error
+		astr, bstr, err = diffRevLines(repo, file, bstr, rev_a, rev_b)
+		return err
+	}, func(tk *gi.Task) {
+		switch {
+		case tk.Ctx.Err() != nil:
+		case tk.Err != nil:
+			gi.PromptDialog(win.Viewport, gi.DlgOpts{Title: "DiffVcs Error", Prompt: tk.Err.Error()}, gi.AddOk, gi.NoCancel, nil, nil)
+		default:
+			if rev_a == "" {
+				rev_a = "HEAD"
 			}
-			bstr = textbuf.BytesToLineStrings(fb, false) // don't add new lines
+			DiffViewDialog(win.Viewport, astr, bstr, file, file, rev_a, rev_b, DlgOpts{Title: "DiffVcs: " + DirAndFile(file)})
 		}
-	} else {
+	})
+}
+
+// diffRevCurLines returns the lines of the current file, from its buffer if
+// non-nil, if rev_b is empty, and nil otherwise
+func diffRevCurLines(file string, fbuf *TextBuf, rev_b string) ([]string, error) {
+	if rev_b != "" {
+		return nil, nil
+	}
+	if fbuf != nil {
+		return fbuf.Strings(false), nil
+	}
+	fb, err := textbuf.FileBytes(file)
+	if err != nil {
+		return nil, err
+	}
+	return textbuf.BytesToLineStrings(fb, false), nil // don't add new lines
+}
+
+// diffRevLines returns the lines of the file at revisions rev_a and rev_b
+// of the repository, with bstr used for rev_b if it is empty
+func diffRevLines(repo vci.Repo, file string, bstr []string, rev_a, rev_b string) (astr, bs []string, err error) {
+	if rev_b != "" {
 		fb, err := repo.FileContents(file, rev_b)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		bstr = textbuf.BytesToLineStrings(fb, false) // don't add new lines
 	}
 	fb, err := repo.FileContents(file, rev_a)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	astr = textbuf.BytesToLineStrings(fb, false) // don't add new lines
-	if rev_a == "" {
-		rev_a = "HEAD"
-	}
-	return DiffViewDialog(nil, astr, bstr, file, file, rev_a, rev_b, DlgOpts{Title: "DiffVcs: " + DirAndFile(file)}), nil
+	return textbuf.BytesToLineStrings(fb, false), bstr, nil
 }
 
 // DiffViewDialog opens a dialog for displaying diff between two files as line-strings
@@ -369,8 +426,8 @@ func (dv *DiffView) TagWordDiffs() {
 		stln := df.I1
 		for i := 0; i < mx; i++ {
 			ln := stln + i
-			ra := dv.BufA.Lines[ln]
-			rb := dv.BufB.Lines[ln]
+			ra := dv.BufA.Line(ln)
+			rb := dv.BufB.Line(ln)
 			lna := lex.RuneFields(ra)
 			lnb := lex.RuneFields(rb)
 			fla := lna.RuneStrings(ra)
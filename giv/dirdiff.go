@@ -0,0 +1,715 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/textbuf"
+	"github.com/goki/gi/giv/vfs"
+	"github.com/goki/ki/kit"
+	"github.com/goki/vci"
+)
+
+// DirDiffStatus is the status of a file or directory in a comparison of
+// two trees of files, A and B
+type DirDiffStatus int32
+
+const (
+	// DirDiffSame is a file with the same contents in A and B, or a
+	// directory with only such files in it
+	DirDiffSame DirDiffStatus = iota
+
+	// DirDiffChanged is a file with different contents in A and B, or a
+	// directory with files in it that are changed, added or removed
+	DirDiffChanged
+
+	// DirDiffAdded is a file or directory that is only in B
+	DirDiffAdded
+
+	// DirDiffRemoved is a file or directory that is only in A
+	DirDiffRemoved
+
+	// DirDiffStatusN is the number of statuses
+	DirDiffStatusN
+)
+
+//go:generate stringer -type=DirDiffStatus
+
+var KiT_DirDiffStatus = kit.Enums.AddEnum(DirDiffStatusN, kit.NotBitFlag, nil)
+
+func (ev DirDiffStatus) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *DirDiffStatus) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+// DirDiffOpts are the options for comparing two trees of files
+type DirDiffOpts struct {
+	Same      bool   `desc:"show the files that are the same in both trees"`
+	Changed   bool   `desc:"show the files that are different in the two trees"`
+	Added     bool   `desc:"show the files that are only in B"`
+	Removed   bool   `desc:"show the files that are only in A"`
+	Exclude   string `desc:"space-separated glob patterns of files and directories to skip -- patterns containing a / are relative to the roots, others match names at any depth"`
+	GitIgnore bool   `desc:"skip files and directories ignored by the .gitignore files in either tree"`
+}
+
+// Defaults sets the default options
+func (do *DirDiffOpts) Defaults() {
+	do.Changed = true
+	do.Added = true
+	do.Removed = true
+	do.GitIgnore = true
+}
+
+// Show returns true if files of given status are shown
+func (do *DirDiffOpts) Show(st DirDiffStatus) bool {
+	switch st {
+	case DirDiffSame:
+		return do.Same
+	case DirDiffChanged:
+		return do.Changed
+	case DirDiffAdded:
+		return do.Added
+	case DirDiffRemoved:
+		return do.Removed
+	}
+	return false
+}
+
+// DirDiffEntry is a file or directory in a comparison of two trees of
+// files, with the entries of the files and directories in it
+type DirDiffEntry struct {
+	Name   string          `desc:"name of the file or directory"`
+	Path   string          `desc:"slash-separated path of the file or directory, relative to the roots of the trees -- empty for the roots"`
+	IsDir  bool            `desc:"whether this is a directory -- if it is a directory in one tree and a file in the other, it is a changed file"`
+	Status DirDiffStatus   `desc:"status of the file or directory"`
+	SizeA  int64           `desc:"size of the file in A, -1 if it is not in A"`
+	SizeB  int64           `desc:"size of the file in B, -1 if it is not in B"`
+	Kids   []*DirDiffEntry `desc:"entries in a directory, with the directories first, then the files, each sorted by name"`
+}
+
+// InA returns true if the file or directory is in tree A
+func (de *DirDiffEntry) InA() bool {
+	return de.Status != DirDiffAdded
+}
+
+// InB returns true if the file or directory is in tree B
+func (de *DirDiffEntry) InB() bool {
+	return de.Status != DirDiffRemoved
+}
+
+// Walk calls fun for given entry and then recursively for the entries in
+// it, stopping if fun returns false
+func (de *DirDiffEntry) Walk(fun func(de *DirDiffEntry) bool) bool {
+	if !fun(de) {
+		return false
+	}
+	for _, k := range de.Kids {
+		if !k.Walk(fun) {
+			return false
+		}
+	}
+	return true
+}
+
+// dirStatus sets the status of a directory in both trees from the entries
+// in it
+func (de *DirDiffEntry) dirStatus() {
+	de.Status = DirDiffSame
+	for _, k := range de.Kids {
+		if k.Status != DirDiffSame {
+			de.Status = DirDiffChanged
+			return
+		}
+	}
+}
+
+// DirDiff compares two trees of files, A and B, which can be in different
+// file systems, e.g., a directory in the OS and a revision of a repository
+// (see VCSRevFS), or the files of an archive.  The files are compared by
+// their contents, and the trees can be made the same, file by file or
+// directory by directory, by copying from one to the other.
+type DirDiff struct {
+	FSA   vfs.FS        `json:"-" xml:"-" desc:"file system of tree A -- nil for the OS"`
+	RootA string        `desc:"path of the root of tree A in FSA"`
+	FSB   vfs.FS        `json:"-" xml:"-" desc:"file system of tree B -- nil for the OS"`
+	RootB string        `desc:"path of the root of tree B in FSB"`
+	RevA  string        `desc:"revision of tree A, if it is a revision of a repository, for showing it"`
+	RevB  string        `desc:"revision of tree B, if it is a revision of a repository, for showing it"`
+	Opts  DirDiffOpts   `desc:"options for the comparison"`
+	Root  *DirDiffEntry `json:"-" xml:"-" desc:"entry of the roots, with the entries of all the files and directories in them, after Compare"`
+}
+
+// NewDirDiff returns a comparison of the trees at rootA in fsA and rootB in
+// fsB, with the default options -- call Compare to compare them
+func NewDirDiff(fsA vfs.FS, rootA string, fsB vfs.FS, rootB string) *DirDiff {
+	dd := &DirDiff{FSA: fsA, RootA: rootA, FSB: fsB, RootB: rootB}
+	dd.Opts.Defaults()
+	return dd
+}
+
+// PathA returns the path in FSA of the file at given slash-separated path
+// relative to the roots
+func (dd *DirDiff) PathA(rel string) string {
+	return filepath.Join(dd.RootA, filepath.FromSlash(rel))
+}
+
+// PathB returns the path in FSB of the file at given slash-separated path
+// relative to the roots
+func (dd *DirDiff) PathB(rel string) string {
+	return filepath.Join(dd.RootB, filepath.FromSlash(rel))
+}
+
+// Compare compares the two trees, setting Root -- returns an error if
+// neither root is a directory
+func (dd *DirDiff) Compare() error {
+	root, err := dd.CompareTask(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	dd.Root = root
+	return nil
+}
+
+// CompareTask compares the two trees like Compare, but returns the entry
+// of the roots instead of setting Root, so that it can run in a Task
+// while the current comparison is shown -- the directories being compared
+// are reported as the progress of the task, if non-nil, and ctx.Err() is
+// returned if it is cancelled.  The DirDiff must not be changed while it
+// runs (see DirDiffView.Compare, which uses a copy).
+func (dd *DirDiff) CompareTask(ctx context.Context, tk *gi.Task) (*DirDiffEntry, error) {
+	fiA, errA := vfs.OrOS(dd.FSA).Stat(dd.RootA)
+	fiB, errB := vfs.OrOS(dd.FSB).Stat(dd.RootB)
+	if errA != nil && errB != nil {
+		return nil, errA
+	}
+	if (fiA == nil || !fiA.IsDir()) && (fiB == nil || !fiB.IsDir()) {
+		return nil, fmt.Errorf("giv.DirDiff: neither %v nor %v is a directory", dd.RootA, dd.RootB)
+	}
+	root := dd.compare(ctx, tk, "", fiA, fiB, dd.ignore(""))
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return root, nil
+}
+
+// ignore returns the rules for the files to skip in given directory: the
+// Exclude patterns, and those of the .gitignore files in the directories
+// down to it, in both trees
+func (dd *DirDiff) ignore(dir string) textbuf.Ignore {
+	var ign textbuf.Ignore
+	ign.AddPatterns(strings.Fields(dd.Opts.Exclude)...)
+	if !dd.Opts.GitIgnore {
+		return ign
+	}
+	dirs := []string{""}
+	if dir != "" {
+		for d := dir; d != "."; d = path.Dir(d) {
+			dirs = append(dirs, d)
+		}
+		sort.Strings(dirs) // parents first
+	}
+	for _, d := range dirs {
+		ign = dd.addGitIgnore(ign, d)
+	}
+	return ign
+}
+
+// addGitIgnore returns given rules with those of the .gitignore files in
+// given directory of both trees added, without changing the given ones
+func (dd *DirDiff) addGitIgnore(ign textbuf.Ignore, dir string) textbuf.Ignore {
+	ign = ign[:len(ign):len(ign)] // appends copy, so that sibling dirs do not share rules
+	gfn := path.Join(dir, ".gitignore")
+	if b, err := vfs.ReadFile(vfs.OrOS(dd.FSA), dd.PathA(gfn)); err == nil {
+		ign.Parse(b, dir)
+	}
+	if b, err := vfs.ReadFile(vfs.OrOS(dd.FSB), dd.PathB(gfn)); err == nil {
+		ign.Parse(b, dir)
+	}
+	return ign
+}
+
+// skip returns true if the file or directory with given info and path is
+// not compared
+func (dd *DirDiff) skip(fi os.FileInfo, rel string, ign textbuf.Ignore) bool {
+	if fi.IsDir() {
+		return FileSearchSkipDirs[fi.Name()] || ign.Ignored(rel, true)
+	}
+	return !fi.Mode().IsRegular() || ign.Ignored(rel, false)
+}
+
+// compare returns the entry comparing the file or directory at given path,
+// with given infos in A and B, nil if it is not in that tree, and given
+// rules for the files to skip in the directory it is in -- the entry is
+// incomplete if ctx is cancelled
+func (dd *DirDiff) compare(ctx context.Context, tk *gi.Task, rel string, fiA, fiB os.FileInfo, ign textbuf.Ignore) *DirDiffEntry {
+	de := &DirDiffEntry{Name: path.Base(rel), Path: rel, SizeA: -1, SizeB: -1}
+	if rel == "" {
+		de.Name = ""
+	}
+	dirA := fiA != nil && fiA.IsDir()
+	dirB := fiB != nil && fiB.IsDir()
+	switch {
+	case fiA == nil:
+		de.Status = DirDiffAdded
+	case fiB == nil:
+		de.Status = DirDiffRemoved
+	case dirA != dirB:
+		de.Status = DirDiffChanged
+	}
+	if (dirA || fiA == nil) && (dirB || fiB == nil) {
+		de.IsDir = true
+		dd.compareDir(ctx, tk, de, ign)
+		return de
+	}
+	if fiA != nil && !dirA {
+		de.SizeA = fiA.Size()
+	}
+	if fiB != nil && !dirB {
+		de.SizeB = fiB.Size()
+	}
+	if fiA != nil && fiB != nil && dirA == dirB {
+		if de.SizeA != de.SizeB || !dd.sameFiles(ctx, rel) {
+			de.Status = DirDiffChanged
+		}
+	}
+	return de
+}
+
+// compareDir sets the entries in given directory entry, and its status if
+// it is in both trees
+func (dd *DirDiff) compareDir(ctx context.Context, tk *gi.Task, de *DirDiffEntry, ign textbuf.Ignore) {
+	if ctx.Err() != nil {
+		return
+	}
+	if tk != nil {
+		tk.SetMessage(de.Path)
+	}
+	if de.Path != "" && dd.Opts.GitIgnore {
+		ign = dd.addGitIgnore(ign, de.Path)
+	}
+	type pair struct{ a, b os.FileInfo }
+	infos := make(map[string]*pair)
+	if de.InA() {
+		fis, _ := vfs.OrOS(dd.FSA).ReadDir(dd.PathA(de.Path))
+		for _, fi := range fis {
+			infos[fi.Name()] = &pair{a: fi}
+		}
+	}
+	if de.InB() {
+		fis, _ := vfs.OrOS(dd.FSB).ReadDir(dd.PathB(de.Path))
+		for _, fi := range fis {
+			if p, has := infos[fi.Name()]; has {
+				p.b = fi
+			} else {
+				infos[fi.Name()] = &pair{b: fi}
+			}
+		}
+	}
+	de.Kids = nil
+	i := 0
+	for nm, p := range infos {
+		if tk != nil && de.Path == "" { // progress is by the entries at the roots
+			tk.SetProgress(float32(i) / float32(len(infos)))
+		}
+		i++
+		rel := path.Join(de.Path, nm)
+		if (p.a != nil && dd.skip(p.a, rel, ign)) || (p.b != nil && dd.skip(p.b, rel, ign)) {
+			continue
+		}
+		de.Kids = append(de.Kids, dd.compare(ctx, tk, rel, p.a, p.b, ign))
+	}
+	sortDirDiff(de.Kids)
+	if de.InA() && de.InB() {
+		de.dirStatus()
+	}
+}
+
+// sortDirDiff sorts given entries with the directories first, then the
+// files, each by name
+func sortDirDiff(des []*DirDiffEntry) {
+	sort.Slice(des, func(i, j int) bool {
+		if des[i].IsDir != des[j].IsDir {
+			return des[i].IsDir
+		}
+		return des[i].Name < des[j].Name
+	})
+}
+
+// sameFiles returns true if the files at given path in A and B, which have
+// the same size, have the same contents -- false if ctx is cancelled
+func (dd *DirDiff) sameFiles(ctx context.Context, rel string) bool {
+	fa, err := vfs.OrOS(dd.FSA).Open(dd.PathA(rel))
+	if err != nil {
+		return false
+	}
+	defer fa.Close()
+	fb, err := vfs.OrOS(dd.FSB).Open(dd.PathB(rel))
+	if err != nil {
+		return false
+	}
+	defer fb.Close()
+	ba := make([]byte, 32*1024)
+	bb := make([]byte, len(ba))
+	for {
+		if ctx.Err() != nil {
+			return false
+		}
+		na, erra := io.ReadFull(fa, ba)
+		nb, errb := io.ReadFull(fb, bb)
+		if na != nb || !bytes.Equal(ba[:na], bb[:nb]) {
+			return false
+		}
+		if erra != nil || errb != nil {
+			return (erra == io.EOF || erra == io.ErrUnexpectedEOF) && (errb == io.EOF || errb == io.ErrUnexpectedEOF)
+		}
+	}
+}
+
+// Entry returns the entry at given slash-separated path relative to the
+// roots, and the directory entries down to it, or nil if there is none
+func (dd *DirDiff) Entry(rel string) (*DirDiffEntry, []*DirDiffEntry) {
+	if dd.Root == nil {
+		return nil, nil
+	}
+	de := dd.Root
+	var dirs []*DirDiffEntry
+	if rel == "" {
+		return de, nil
+	}
+	for _, nm := range strings.Split(rel, "/") {
+		dirs = append(dirs, de)
+		var kid *DirDiffEntry
+		for _, k := range de.Kids {
+			if k.Name == nm {
+				kid = k
+				break
+			}
+		}
+		if kid == nil {
+			return nil, nil
+		}
+		de = kid
+	}
+	return de, dirs
+}
+
+// Update compares the file or directory at given slash-separated path
+// relative to the roots again, e.g., after it is changed, updating the
+// status of the directories it is in
+func (dd *DirDiff) Update(rel string) error {
+	set, err := dd.update(context.Background(), nil, rel)
+	if set != nil {
+		set()
+	}
+	return err
+}
+
+// update compares the file or directory at given path again like Update,
+// returning the function that sets the new comparison, so that comparing
+// can be done in a Task and setting on the event goroutine -- returns
+// ctx.Err() if it is cancelled
+func (dd *DirDiff) update(ctx context.Context, tk *gi.Task, rel string) (func(), error) {
+	de, dirs := dd.Entry(rel)
+	if de == nil {
+		return nil, fmt.Errorf("giv.DirDiff: %v is not in the comparison", rel)
+	}
+	for _, d := range dirs {
+		if !d.InA() || !d.InB() { // now may be in both
+			return dd.update(ctx, tk, d.Path)
+		}
+	}
+	if rel == "" {
+		root, err := dd.CompareTask(ctx, tk)
+		if err != nil {
+			return nil, err
+		}
+		return func() { dd.Root = root }, nil
+	}
+	fiA, _ := vfs.OrOS(dd.FSA).Stat(dd.PathA(rel))
+	fiB, _ := vfs.OrOS(dd.FSB).Stat(dd.PathB(rel))
+	par := dirs[len(dirs)-1]
+	var nde *DirDiffEntry
+	if fiA != nil || fiB != nil {
+		nde = dd.compare(ctx, tk, rel, fiA, fiB, dd.ignore(par.Path))
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return func() {
+		for i, k := range par.Kids {
+			if k != de {
+				continue
+			}
+			if nde == nil {
+				par.Kids = append(par.Kids[:i], par.Kids[i+1:]...)
+			} else {
+				par.Kids[i] = nde
+			}
+			break
+		}
+		for i := len(dirs) - 1; i >= 0; i-- {
+			if dirs[i].InA() && dirs[i].InB() {
+				dirs[i].dirStatus()
+			}
+		}
+	}, nil
+}
+
+// CopyToA makes the file or directory at given slash-separated path
+// relative to the roots in A the same as in B, copying the files that are
+// changed or added, and removing those that are only in A, and then
+// updates the comparison
+func (dd *DirDiff) CopyToA(rel string) error {
+	return dd.copyToNow(rel, false)
+}
+
+// CopyToB makes the file or directory at given slash-separated path
+// relative to the roots in B the same as in A, copying the files that are
+// changed or removed, and removing those that are only in B, and then
+// updates the comparison
+func (dd *DirDiff) CopyToB(rel string) error {
+	return dd.copyToNow(rel, true)
+}
+
+func (dd *DirDiff) copyToNow(rel string, toB bool) error {
+	set, err := dd.copyTo(context.Background(), nil, rel, toB)
+	if set != nil {
+		set()
+	}
+	return err
+}
+
+// copyTo copies the file or directory at given path like CopyToB (if toB)
+// or CopyToA, in a Task if tk is non-nil, reporting the files copied as
+// its progress, and stopping if it is cancelled -- it returns the function
+// that updates the comparison (see update), if it could be compared again
+func (dd *DirDiff) copyTo(ctx context.Context, tk *gi.Task, rel string, toB bool) (func(), error) {
+	de, _ := dd.Entry(rel)
+	if de == nil {
+		return nil, fmt.Errorf("giv.DirDiff: %v is not in the comparison", rel)
+	}
+	dc := &dirDiffCopy{ctx: ctx, tk: tk, toB: toB, sfs: vfs.OrOS(dd.FSA), sroot: dd.RootA, dfs: vfs.OrOS(dd.FSB), droot: dd.RootB}
+	if !toB {
+		dc.sfs, dc.sroot, dc.dfs, dc.droot = dc.dfs, dc.droot, dc.sfs, dc.sroot
+	}
+	de.Walk(func(k *DirDiffEntry) bool {
+		if k.Status != DirDiffSame {
+			dc.n++
+		}
+		return true
+	})
+	err := dc.copy(de)
+	set, uerr := dd.update(ctx, tk, rel)
+	if err == nil {
+		err = uerr
+	}
+	return set, err
+}
+
+// dirDiffCopy copies the files of entries of a DirDiff from one tree, in
+// sfs at sroot, to the other, in dfs at droot
+type dirDiffCopy struct {
+	ctx         context.Context
+	tk          *gi.Task
+	toB         bool
+	sfs, dfs    vfs.FS
+	sroot       string
+	droot       string
+	n, finished int // number of entries to copy or remove, and done so far, for progress
+}
+
+// copy makes the file or directory of given entry in the destination tree
+// the same as in the source tree
+func (dc *dirDiffCopy) copy(de *DirDiffEntry) error {
+	if de.Status == DirDiffSame {
+		return nil
+	}
+	if err := dc.ctx.Err(); err != nil {
+		return err
+	}
+	if dc.tk != nil {
+		dc.tk.SetMessage(de.Path)
+		dc.tk.SetProgress(float32(dc.finished) / float32(dc.n))
+		dc.finished++
+	}
+	toB, sfs, sroot, dfs, droot := dc.toB, dc.sfs, dc.sroot, dc.dfs, dc.droot
+	src := filepath.Join(sroot, filepath.FromSlash(de.Path))
+	dst := filepath.Join(droot, filepath.FromSlash(de.Path))
+	inSrc, inDst := de.InA(), de.InB()
+	if !toB {
+		inSrc, inDst = inDst, inSrc
+	}
+	if !inSrc {
+		return vfs.RemoveAll(dfs, dst)
+	}
+	sfi, err := sfs.Stat(src)
+	if err != nil {
+		return err
+	}
+	if inDst {
+		if dfi, err := dfs.Stat(dst); err == nil && dfi.IsDir() != sfi.IsDir() {
+			if err := vfs.RemoveAll(dfs, dst); err != nil {
+				return err
+			}
+			inDst = false
+		}
+	}
+	if !sfi.IsDir() || !de.IsDir {
+		// the directories it is in may only be in the source tree, if they
+		// are not copied along with it
+		if err := vfs.MkdirAll(dfs, filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+	}
+	if !sfi.IsDir() {
+		return vfs.CopyFile(dfs, dst, sfs, src, sfi.Mode().Perm())
+	}
+	if !de.IsDir { // a file in the other tree, so its files were not compared
+		return vfs.CopyAll(dfs, dst, sfs, src)
+	}
+	if !inDst {
+		if err := vfs.MkdirAll(dfs, dst, sfi.Mode().Perm()|0700); err != nil {
+			return err
+		}
+	}
+	for _, k := range de.Kids {
+		if err := dc.copy(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Filtered returns the entries in given directory entry whose status is
+// shown by the Opts, and the directories with such entries in them
+func (dd *DirDiff) Filtered(de *DirDiffEntry) []*DirDiffEntry {
+	var fdes []*DirDiffEntry
+	for _, k := range de.Kids {
+		if !k.IsDir {
+			if dd.Opts.Show(k.Status) {
+				fdes = append(fdes, k)
+			}
+			continue
+		}
+		kids := dd.Filtered(k)
+		if len(kids) > 0 || (len(k.Kids) == 0 && dd.Opts.Show(k.Status)) {
+			fk := *k
+			fk.Kids = kids
+			fdes = append(fdes, &fk)
+		}
+	}
+	return fdes
+}
+
+// Counts returns the number of files of each status
+func (dd *DirDiff) Counts() [DirDiffStatusN]int {
+	var n [DirDiffStatusN]int
+	if dd.Root == nil {
+		return n
+	}
+	dd.Root.Walk(func(de *DirDiffEntry) bool {
+		if !de.IsDir {
+			n[de.Status]++
+		}
+		return true
+	})
+	return n
+}
+
+// FileLines returns the lines of the file at given slash-separated path
+// relative to the roots in A and B, with no lines for a tree that it is
+// not in -- returns an error if a file can not be read, or if either is
+// binary, i.e., has a 0 byte in it
+func (dd *DirDiff) FileLines(rel string) (astr, bstr []string, err error) {
+	de, _ := dd.Entry(rel)
+	if de == nil || de.IsDir {
+		return nil, nil, fmt.Errorf("giv.DirDiff: %v is not a file in the comparison", rel)
+	}
+	read := func(fsys vfs.FS, fpath string, size int64) ([]string, error) {
+		if size < 0 {
+			return nil, nil
+		}
+		b, err := vfs.ReadFile(vfs.OrOS(fsys), fpath)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.IndexByte(b, 0) >= 0 {
+			return nil, fmt.Errorf("giv.DirDiff: %v is a binary file", fpath)
+		}
+		return textbuf.BytesToLineStrings(b, false), nil
+	}
+	if astr, err = read(dd.FSA, dd.PathA(rel), de.SizeA); err != nil {
+		return
+	}
+	bstr, err = read(dd.FSB, dd.PathB(rel), de.SizeB)
+	return
+}
+
+// VCSRevFS returns the read-only file system of the files of given
+// repository at given revision, with the root of the repository at the
+// root of the file system: "" is the current HEAD, and -1, -2 etc are the
+// revisions before it.  Only git repositories are supported.
+func VCSRevFS(repo vci.Repo, rev string) (*vfs.ArchiveFS, error) {
+	return VCSRevFSTask(context.Background(), nil, repo, rev)
+}
+
+// VCSRevFSTask returns the file system of the files of given repository at
+// given revision like VCSRevFS, in a Task if tk is non-nil -- the files
+// are read as they are retrieved, and retrieving them is stopped if ctx
+// is cancelled, returning ctx.Err().
+func VCSRevFSTask(ctx context.Context, tk *gi.Task, repo vci.Repo, rev string) (*vfs.ArchiveFS, error) {
+	if repo.Vcs() != "git" {
+		return nil, fmt.Errorf("giv.VCSRevFS: revisions of %v repositories are not supported", repo.Vcs())
+	}
+	if rev == "" {
+		rev = "HEAD"
+	} else if n, err := strconv.Atoi(rev); err == nil && n < 0 {
+		rev = fmt.Sprintf("HEAD~%d", -n)
+	}
+	if tk != nil {
+		tk.SetMessage(rev)
+	}
+	cmd := repo.CmdFromDir("git", "archive", "--format=tar", rev)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			cmd.Process.Kill()
+		case <-stop:
+		}
+	}()
+	afs, terr := vfs.NewTarFS(out)
+	io.Copy(ioutil.Discard, out) // rest of the output, if the archive is bad, so that git can exit
+	err = cmd.Wait()
+	close(stop)
+	switch {
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case err != nil:
+		return nil, fmt.Errorf("giv.VCSRevFS: %v: %v", err, strings.TrimSpace(stderr.String()))
+	case terr != nil:
+		return nil, terr
+	}
+	return afs, nil
+}
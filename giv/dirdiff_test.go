@@ -0,0 +1,315 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/vfs"
+	"github.com/goki/vci"
+)
+
+// newTestDirFS returns a MemFS with given files, by slash-separated path,
+// under /root
+func newTestDirFS(t *testing.T, files map[string]string) vfs.FS {
+	t.Helper()
+	fsys := vfs.NewMemFS()
+	for fp, txt := range files {
+		fp = filepath.Join("/root", filepath.FromSlash(fp))
+		if err := vfs.MkdirAll(fsys, filepath.Dir(fp), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := vfs.WriteFile(fsys, fp, []byte(txt), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fsys
+}
+
+// dumpDirDiff returns the paths and statuses of given entries and those
+// in them
+func dumpDirDiff(des []*DirDiffEntry) string {
+	var s []string
+	for _, de := range des {
+		de.Walk(func(de *DirDiffEntry) bool {
+			s = append(s, fmt.Sprintf("%s:%v", de.Path, strings.TrimPrefix(de.Status.String(), "DirDiff")))
+			return true
+		})
+	}
+	return strings.Join(s, " ")
+}
+
+func TestDirDiff(t *testing.T) {
+	fsA := newTestDirFS(t, map[string]string{
+		"same.txt":      "same",
+		"changed.txt":   "one",
+		"resized.txt":   "a",
+		"removed.txt":   "gone",
+		"sub/same.go":   "package sub",
+		"old/x.txt":     "x",
+		"kind":          "file in A",
+		".gitignore":    "*.log\n",
+		"skip.log":      "log",
+		".git/HEAD":     "ref",
+		"same-dir/a.go": "a",
+	})
+	fsB := newTestDirFS(t, map[string]string{
+		"same.txt":      "same",
+		"changed.txt":   "two",
+		"resized.txt":   "abc",
+		"added.txt":     "new",
+		"sub/same.go":   "package sub",
+		"sub/new/y.txt": "y",
+		"kind/z.txt":    "dir in B",
+		".gitignore":    "*.log\n",
+		"other.log":     "log",
+		"same-dir/a.go": "a",
+	})
+	dd := NewDirDiff(fsA, "/root", fsB, "/root")
+	if err := dd.Compare(); err != nil {
+		t.Fatal(err)
+	}
+	// kind is a file in A and a dir in B, so it is a changed file
+	want := ":Changed old:Removed old/x.txt:Removed same-dir:Same same-dir/a.go:Same " +
+		"sub:Changed sub/new:Added sub/new/y.txt:Added sub/same.go:Same .gitignore:Same added.txt:Added " +
+		"changed.txt:Changed kind:Changed removed.txt:Removed resized.txt:Changed same.txt:Same"
+	if got := dumpDirDiff([]*DirDiffEntry{dd.Root}); got != want {
+		t.Errorf("compare:\n got %s\nwant %s", got, want)
+	}
+	if n := dd.Counts(); n != [DirDiffStatusN]int{4, 3, 2, 2} {
+		t.Errorf("counts: %v", n)
+	}
+
+	dd.Opts.Changed, dd.Opts.Removed = false, false
+	if got := dumpDirDiff(dd.Filtered(dd.Root)); got != "sub:Changed sub/new:Added sub/new/y.txt:Added added.txt:Added" {
+		t.Errorf("filtered: %s", got)
+	}
+
+	if _, _, err := dd.FileLines("sub"); err == nil {
+		t.Errorf("no error for diff of dir")
+	}
+	astr, bstr, err := dd.FileLines("added.txt")
+	if err != nil || len(astr) != 0 || len(bstr) != 1 || bstr[0] != "new" {
+		t.Errorf("lines: %q %q %v", astr, bstr, err)
+	}
+
+	// copy whole dirs and files both ways
+	for _, rel := range []string{"sub", "changed.txt", "kind", "removed.txt"} {
+		if err := dd.CopyToB(rel); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := dd.CopyToA("added.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dd.CopyToA("old"); err != nil { // only in A, so removed
+		t.Fatal(err)
+	}
+	want = ":Changed same-dir:Same same-dir/a.go:Same sub:Same sub/same.go:Same .gitignore:Same added.txt:Same " +
+		"changed.txt:Same kind:Same removed.txt:Same resized.txt:Changed same.txt:Same"
+	if got := dumpDirDiff([]*DirDiffEntry{dd.Root}); got != want {
+		t.Errorf("copied:\n got %s\nwant %s", got, want)
+	}
+	if b, _ := vfs.ReadFile(fsB, "/root/changed.txt"); string(b) != "one" {
+		t.Errorf("copied contents: %q", b)
+	}
+	if _, err := fsB.Stat("/root/sub/new"); !os.IsNotExist(err) {
+		t.Errorf("dir only in B not removed: %v", err)
+	}
+	if err := dd.CopyToB("resized.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if dd.Root.Status != DirDiffSame {
+		t.Errorf("root not same after copying all: %v", dumpDirDiff([]*DirDiffEntry{dd.Root}))
+	}
+
+	// no .gitignore, so ignored files and dirs are compared
+	dd.Opts.GitIgnore = false
+	dd.Opts.Exclude = "same-dir"
+	dd.Compare()
+	if de, _ := dd.Entry("skip.log"); de == nil || de.Status != DirDiffRemoved {
+		t.Errorf("log not compared without gitignore: %v", de)
+	}
+	if de, _ := dd.Entry("same-dir"); de != nil {
+		t.Errorf("excluded dir compared")
+	}
+}
+
+func TestDirDiffCopyNested(t *testing.T) {
+	fsA := newTestDirFS(t, map[string]string{"a.txt": "a"})
+	fsB := newTestDirFS(t, map[string]string{"a.txt": "a", "new/deep/f.txt": "f", "new/deep/g/h.txt": "h"})
+	dd := NewDirDiff(fsA, "/root", fsB, "/root")
+	if err := dd.Compare(); err != nil {
+		t.Fatal(err)
+	}
+	// just the files, not the dirs that they are in, which are only in B
+	for _, rel := range []string{"new/deep/f.txt", "new/deep/g/h.txt"} {
+		if err := dd.CopyToA(rel); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b, err := vfs.ReadFile(fsA, "/root/new/deep/f.txt"); err != nil || string(b) != "f" {
+		t.Errorf("copied nested file: %q %v", b, err)
+	}
+	if b, err := vfs.ReadFile(fsA, "/root/new/deep/g/h.txt"); err != nil || string(b) != "h" {
+		t.Errorf("copied nested file: %q %v", b, err)
+	}
+	if dd.Root.Status != DirDiffSame {
+		t.Errorf("not same after copying: %v", dumpDirDiff([]*DirDiffEntry{dd.Root}))
+	}
+}
+
+func TestDirDiffNode(t *testing.T) {
+	fsA := newTestDirFS(t, map[string]string{"a.txt": "a", "d/b.txt": "b", "d/c.txt": "c"})
+	fsB := newTestDirFS(t, map[string]string{"a.txt": "a", "d/b.txt": "bb"})
+	dd := NewDirDiff(fsA, "/root", fsB, "/root")
+	if err := dd.Compare(); err != nil {
+		t.Fatal(err)
+	}
+	dv := &DirDiffView{Diff: dd}
+	if got := dv.SummaryText(); !strings.HasSuffix(got, "1 changed, 0 added, 1 removed, 1 same") {
+		t.Errorf("summary: %v", got)
+	}
+	dn := &DirDiffNode{}
+	dn.InitName(dn, "dir-diff")
+	dn.ConfigEntries(dd.Filtered(dd.Root))
+	if dn.NumChildren() != 1 || dn.Child(0).NumChildren() != 2 {
+		t.Fatalf("tree: %v", dn.Kids)
+	}
+	dir := dn.Child(0).(*DirDiffNode)
+	dd.Opts.Same = true
+	dn.ConfigEntries(dd.Filtered(dd.Root))
+	if dn.NumChildren() != 2 || dn.Child(0) != dir || dn.Child(1).(*DirDiffNode).Label() != "a.txt" {
+		t.Errorf("tree with same: %v", dn.Kids)
+	}
+	if err := dd.CopyToB("d"); err != nil {
+		t.Fatal(err)
+	}
+	dd.Opts.Same = false
+	dn.ConfigEntries(dd.Filtered(dd.Root))
+	if dn.NumChildren() != 0 {
+		t.Errorf("tree after copy: %v", dn.Kids)
+	}
+}
+
+func TestDirDiffTask(t *testing.T) {
+	fsA := newTestDirFS(t, map[string]string{"a.txt": "a", "d/b.txt": "b"})
+	fsB := newTestDirFS(t, map[string]string{"a.txt": "aa"})
+	dd := NewDirDiff(fsA, "/root", fsB, "/root")
+	w := &gi.Window{} // no status row, as it has no layout
+	w.InitName(w, "dir-diff-win")
+	w.Viewport = gi.NewViewport2D(100, 100)
+	w.Viewport.Win = w
+	var root *DirDiffEntry
+	tk := w.RunTask("Comparing", func(ctx context.Context, tk *gi.Task) error {
+		var err error
+		root, err = dd.CompareTask(ctx, tk)
+		return err
+	}, nil)
+	if err := tk.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	gi.RunPendingUIFuncs()
+	if dd.Root != nil {
+		t.Errorf("root set by compare task")
+	}
+	dd.Root = root
+	if n := dd.Counts(); n != [DirDiffStatusN]int{0, 1, 0, 1} {
+		t.Fatalf("counts: %v", n)
+	}
+
+	// the comparison is only updated by the returned function
+	set, err := dd.copyTo(context.Background(), nil, "d", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := vfs.ReadFile(fsB, "/root/d/b.txt"); string(b) != "b" {
+		t.Errorf("not copied: %q", b)
+	}
+	if n := dd.Counts(); n != [DirDiffStatusN]int{0, 1, 0, 1} {
+		t.Errorf("counts updated before set: %v", n)
+	}
+	set()
+	if n := dd.Counts(); n != [DirDiffStatusN]int{1, 1, 0, 0} {
+		t.Errorf("counts after copy: %v", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := dd.CompareTask(ctx, nil); err != context.Canceled {
+		t.Errorf("cancelled compare: %v", err)
+	}
+	if _, err := dd.copyTo(ctx, nil, "a.txt", true); err != context.Canceled {
+		t.Errorf("cancelled copy: %v", err)
+	}
+	if b, _ := vfs.ReadFile(fsB, "/root/a.txt"); string(b) != "aa" {
+		t.Errorf("copied when cancelled: %q", b)
+	}
+}
+
+func TestVCSRevFS(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("remote", "add", "origin", "origin") // as used to open the repo
+	ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\n"), 0644)
+	git("add", "a.txt")
+	git("commit", "-q", "-m", "first")
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("two\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("b\n"), 0644)
+	git("add", ".")
+	git("commit", "-q", "-m", "second")
+	ioutil.WriteFile(filepath.Join(dir, "c.txt"), []byte("wc\n"), 0644)
+
+	repo, err := vci.NewRepo("origin", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsA, err := VCSRevFS(repo, "-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := vfs.ReadFile(fsA, "/a.txt"); err != nil || string(b) != "one\n" {
+		t.Errorf("rev -1: %q %v", b, err)
+	}
+	dd := NewDirDiff(fsA, "", nil, dir)
+	dd.Opts.Exclude = ".git"
+	if err := dd.Compare(); err != nil {
+		t.Fatal(err)
+	}
+	if got := dumpDirDiff(dd.Root.Kids); got != "sub:Added sub/b.txt:Added a.txt:Changed c.txt:Added" {
+		t.Errorf("rev -1 vs working copy: %s", got)
+	}
+	if err := dd.CopyToA("a.txt"); err == nil {
+		t.Errorf("no error copying to revision")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := VCSRevFSTask(ctx, nil, repo, ""); err != context.Canceled {
+		t.Errorf("cancelled retrieving revision: %v", err)
+	}
+	if _, err := VCSRevFS(repo, "nosuchrev"); err == nil || !strings.Contains(err.Error(), "nosuchrev") {
+		t.Errorf("bad revision: %v", err)
+	}
+}
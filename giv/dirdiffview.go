@@ -0,0 +1,597 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"context"
+	"fmt"
+	"image/color"
+	"log"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/vfs"
+	"github.com/goki/gi/units"
+	"github.com/goki/ki/ki"
+	"github.com/goki/ki/kit"
+	"github.com/goki/vci"
+)
+
+// DirDiffViewDialog opens a dialog comparing the directories dirA and dirB
+// in the OS
+func DirDiffViewDialog(avp *gi.Viewport2D, dirA, dirB string, opts DlgOpts) *DirDiffView {
+	return DirDiffViewDialogFS(avp, NewDirDiff(nil, dirA, nil, dirB), opts)
+}
+
+// DirDiffViewDialogFromRevs opens a dialog comparing the files of given
+// repository at two revisions: if empty, A is the current HEAD and B the
+// working copy -- -1, -2 etc are the revisions before HEAD.  Copying is
+// only possible to the working copy.  The revisions are retrieved and
+// compared in a Task of the window of given viewport (see gi.TaskWindow),
+// so that large repositories do not block the window -- the dialog opens
+// when they have been compared, or a dialog shows the error.  If there is
+// no window, it runs synchronously and returns nil.
+func DirDiffViewDialogFromRevs(avp *gi.Viewport2D, repo vci.Repo, revA, revB string) *gi.Task {
+	dd := NewDirDiff(nil, "", nil, repo.LocalPath())
+	dd.RevA, dd.RevB = revA, revB
+	if revA == "" {
+		dd.RevA = "HEAD"
+	}
+	opts := DlgOpts{Title: "DirDiffVcs: " + DirAndFile(repo.LocalPath())}
+	var root *DirDiffEntry
+	retrieve := func(ctx context.Context, tk *gi.Task) error {
+		var err error
+		if dd.FSA, err = VCSRevFSTask(ctx, tk, repo, revA); err != nil {
+			return err
+		}
+		if revB != "" {
+			if dd.FSB, err = VCSRevFSTask(ctx, tk, repo, revB); err != nil {
+				return err
+			}
+			dd.RootB = ""
+		}
+		root, err = dd.CompareTask(ctx, tk)
+		return err
+	}
+	win := gi.TaskWindow(avp)
+	if win == nil {
+		if err := retrieve(context.Background(), nil); err != nil {
+			log.Println(err)
+			return nil
+		}
+		dd.Root = root
+		DirDiffViewDialogFS(avp, dd, opts)
+		return nil
+	}
+	return win.RunTask("Comparing revisions of "+DirAndFile(repo.LocalPath()), retrieve, func(tk *gi.Task) {
+		switch {
+		case tk.Ctx.Err() != nil:
+		case tk.Err != nil:
+			gi.PromptDialog(win.Viewport, gi.DlgOpts{Title: "DirDiffVcs Error", Prompt: tk.Err.Error()}, gi.AddOk, gi.NoCancel, nil, nil)
+		default:
+			dd.Root = root
+			DirDiffViewDialogFS(win.Viewport, dd, opts)
+		}
+	})
+}
+
+// DirDiffViewDialogFS opens a dialog showing given comparison of two trees
+// of files, comparing them first if they have not been compared (see
+// DirDiffView.Compare)
+func DirDiffViewDialogFS(avp *gi.Viewport2D, dd *DirDiff, opts DlgOpts) *DirDiffView {
+	dlg := gi.NewStdDialog(opts.ToGiOpts(), opts.Ok, opts.Cancel)
+
+	frame := dlg.Frame()
+	_, prIdx := dlg.PromptWidget(frame)
+
+	dv := frame.InsertNewChild(KiT_DirDiffView, prIdx+1, "dir-diff-view").(*DirDiffView)
+	dv.SetStretchMax()
+	dv.SetDiff(dd)
+
+	dlg.UpdateEndNoSig(true) // going to be shown
+	dlg.Open(0, 0, avp, nil)
+	if dd.Root == nil {
+		dv.Compare()
+	}
+	return dv
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//   DirDiffNode
+
+// DirDiffNode is a node of the tree of a DirDiffView, for a file or
+// directory in the comparison, with the nodes of the files and directories
+// in it as its children
+type DirDiffNode struct {
+	ki.Node
+	Entry *DirDiffEntry `json:"-" xml:"-" desc:"entry of the file or directory in the comparison"`
+}
+
+var KiT_DirDiffNode = kit.Types.AddType(&DirDiffNode{}, nil)
+
+// Label satisfies the gi.Labeler interface
+func (dn *DirDiffNode) Label() string {
+	if dn.Entry == nil {
+		return dn.Nm
+	}
+	return dn.Entry.Name
+}
+
+// ConfigEntries configures the children of the node for given entries,
+// keeping the existing nodes for the same names, so that their views are
+// too
+func (dn *DirDiffNode) ConfigEntries(des []*DirDiffEntry) {
+	config := kit.TypeAndNameList{}
+	for _, de := range des {
+		config.Add(KiT_DirDiffNode, de.Name)
+	}
+	mods, updt := dn.ConfigChildren(config, ki.UniqueNames)
+	for i, de := range des {
+		cn := dn.Kids[i].(*DirDiffNode)
+		cn.Entry = de
+		cn.ConfigEntries(de.Kids)
+	}
+	if mods {
+		dn.UpdateEndNoSig(updt)
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//   DirDiffView
+
+// DirDiffView compares two trees of files, A and B, showing the files and
+// directories of both in one tree, colored by whether they are changed,
+// added (only in B), removed (only in A) or the same, filtered by status.
+// The differences of a changed file are shown in a DiffView, and files or
+// whole directories can be copied from one tree to the other.  Comparing
+// and copying are done in a Task of the window, one at a time.
+type DirDiffView struct {
+	gi.Layout
+	Diff      *DirDiff     `json:"-" xml:"-" desc:"the comparison"`
+	Tree      *DirDiffNode `json:"-" xml:"-" desc:"root node of the tree that is shown, with the entries shown by the options"`
+	Cur       *DirDiffNode `json:"-" xml:"-" desc:"selected node, if any"`
+	Task      *gi.Task     `json:"-" xml:"-" view:"-" desc:"task comparing the trees or copying files, while it runs"`
+	cmpOpts   DirDiffOpts
+	recompare bool // compare again when the Task is done
+}
+
+var KiT_DirDiffView = kit.Types.AddType(&DirDiffView{}, DirDiffViewProps)
+
+// AddNewDirDiffView adds a new directory diff view to given parent node,
+// with given name.
+func AddNewDirDiffView(parent ki.Ki, name string) *DirDiffView {
+	return parent.AddNewChild(KiT_DirDiffView, name).(*DirDiffView)
+}
+
+// DirDiffViewProps are style properties for DirDiffView
+var DirDiffViewProps = ki.Props{
+	"EnumType:Flag": gi.KiT_NodeFlags,
+	"max-width":     -1,
+	"max-height":    -1,
+}
+
+// Config configures the view, if needed
+func (dv *DirDiffView) Config() {
+	dv.Lay = gi.LayoutVert
+	config := kit.TypeAndNameList{}
+	config.Add(KiT_StructViewInline, "opts")
+	config.Add(gi.KiT_ToolBar, "toolbar")
+	config.Add(gi.KiT_Label, "summary")
+	config.Add(gi.KiT_Frame, "tree-frame")
+	mods, updt := dv.ConfigChildren(config, ki.UniqueNames)
+	if !mods {
+		return
+	}
+	if dv.Tree == nil {
+		dv.Tree = &DirDiffNode{}
+		dv.Tree.InitName(dv.Tree, "dir-diff")
+	}
+	dv.OptsView().ViewSig.ConnectOnly(dv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+		dvv := recv.Embed(KiT_DirDiffView).(*DirDiffView)
+		dvv.OptsChanged()
+	})
+	dv.ConfigToolBar()
+	dv.Summary().SetStretchMaxWidth()
+	tf := dv.ChildByName("tree-frame", 3).(*gi.Frame)
+	tf.Lay = gi.LayoutVert
+	tf.SetStretchMax()
+	tf.SetMinPrefWidth(units.NewCh(50))
+	tf.SetMinPrefHeight(units.NewEm(30))
+	tv := AddNewDirDiffTreeView(tf, "tree")
+	tv.SetInactive()
+	tv.SetRootNode(dv.Tree)
+	tv.TreeViewSig.Connect(dv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+		dvv := recv.Embed(KiT_DirDiffView).(*DirDiffView)
+		dn, _ := data.(ki.Ki).Embed(KiT_TreeView).(*TreeView).SrcNode.(*DirDiffNode)
+		switch TreeViewSignals(sig) {
+		case TreeViewSelected:
+			dvv.Cur = dn
+			dvv.UpdateToolBar()
+		case TreeViewOpened:
+			if dn != nil && dn.Entry != nil && !dn.Entry.IsDir {
+				dvv.OpenDiff(dn.Entry.Path)
+			}
+		}
+	})
+	dv.UpdateEnd(updt)
+}
+
+// OptsView returns the view of the options
+func (dv *DirDiffView) OptsView() *StructViewInline {
+	return dv.ChildByName("opts", 0).(*StructViewInline)
+}
+
+// ToolBar returns the toolbar
+func (dv *DirDiffView) ToolBar() *gi.ToolBar {
+	return dv.ChildByName("toolbar", 1).(*gi.ToolBar)
+}
+
+// Summary returns the label with the roots and the numbers of files of
+// each status
+func (dv *DirDiffView) Summary() *gi.Label {
+	return dv.ChildByName("summary", 2).(*gi.Label)
+}
+
+// TreeView returns the tree view of the files
+func (dv *DirDiffView) TreeView() *DirDiffTreeView {
+	return dv.ChildByName("tree-frame", 3).Child(0).(*DirDiffTreeView) // named by SetRootNode
+}
+
+// CurEntry returns the entry of the selected node, if any
+func (dv *DirDiffView) CurEntry() *DirDiffEntry {
+	if dv.Cur == nil || dv.Cur.Entry == nil || dv.Cur.Entry.Path == "" {
+		return nil
+	}
+	return dv.Cur.Entry
+}
+
+// NoTaskUpdate enables an action if no Task is running
+func (dv *DirDiffView) NoTaskUpdate(act *gi.Action) {
+	act.SetActiveStateUpdt(dv.Task == nil)
+}
+
+// CurFileUpdate enables an action if a file is selected
+func (dv *DirDiffView) CurFileUpdate(act *gi.Action) {
+	de := dv.CurEntry()
+	act.SetActiveStateUpdt(de != nil && !de.IsDir)
+}
+
+// CurChangedUpdate enables an action if a file or directory is selected
+// that is not the same in both trees, and no Task is running
+func (dv *DirDiffView) CurChangedUpdate(act *gi.Action) {
+	de := dv.CurEntry()
+	act.SetActiveStateUpdt(de != nil && de.Status != DirDiffSame && dv.Task == nil)
+}
+
+// ConfigToolBar adds the actions to the toolbar
+func (dv *DirDiffView) ConfigToolBar() {
+	tb := dv.ToolBar()
+	tb.AddAction(gi.ActOpts{Label: "Refresh", Icon: "update", Tooltip: "compare the trees again, e.g., after files are changed", UpdateFunc: dv.NoTaskUpdate}, dv.This(),
+		func(recv, send ki.Ki, sig int64, data interface{}) {
+			dvv := recv.Embed(KiT_DirDiffView).(*DirDiffView)
+			dvv.Compare()
+		})
+	tb.AddAction(gi.ActOpts{Label: "Diff", Icon: "file-text", Tooltip: "show the differences of the selected file in A and B -- also by double-clicking on it", UpdateFunc: dv.CurFileUpdate}, dv.This(),
+		func(recv, send ki.Ki, sig int64, data interface{}) {
+			dvv := recv.Embed(KiT_DirDiffView).(*DirDiffView)
+			if de := dvv.CurEntry(); de != nil {
+				dvv.OpenDiff(de.Path)
+			}
+		})
+	tb.AddSeparator("copy-sep")
+	tb.AddAction(gi.ActOpts{Label: "Copy Left", Icon: "wedge-left", Tooltip: "make the selected file or directory in A the same as in B (A <- B): copy the files that are changed or only in B, and remove those only in A", UpdateFunc: dv.CurChangedUpdate}, dv.This(),
+		func(recv, send ki.Ki, sig int64, data interface{}) {
+			dvv := recv.Embed(KiT_DirDiffView).(*DirDiffView)
+			dvv.CopyPrompt(false)
+		})
+	tb.AddAction(gi.ActOpts{Label: "Copy Right", Icon: "wedge-right", Tooltip: "make the selected file or directory in B the same as in A (A -> B): copy the files that are changed or only in A, and remove those only in B", UpdateFunc: dv.CurChangedUpdate}, dv.This(),
+		func(recv, send ki.Ki, sig int64, data interface{}) {
+			dvv := recv.Embed(KiT_DirDiffView).(*DirDiffView)
+			dvv.CopyPrompt(true)
+		})
+}
+
+// UpdateToolBar updates the state of the actions
+func (dv *DirDiffView) UpdateToolBar() {
+	dv.ToolBar().UpdateActions()
+}
+
+// SetDiff sets the comparison that is shown, and updates the view -- call
+// Compare if it has not been compared
+func (dv *DirDiffView) SetDiff(dd *DirDiff) {
+	dv.Diff = dd
+	dv.cmpOpts = dd.Opts
+	dv.Config()
+	dv.OptsView().SetStruct(&dd.Opts)
+	dv.UpdateTree()
+}
+
+// Compare compares the trees again in a Task (see RunTask), and updates
+// the view when they have been compared -- if a Task is already running,
+// they are compared again after it is done
+func (dv *DirDiffView) Compare() {
+	if dv.Diff == nil {
+		return
+	}
+	if dv.Task != nil {
+		dv.recompare = true
+		return
+	}
+	dv.cmpOpts = dv.Diff.Opts
+	cdd := *dv.Diff // the options can be changed while comparing
+	var root *DirDiffEntry
+	dv.RunTask("Comparing", func(ctx context.Context, tk *gi.Task) error {
+		var err error
+		root, err = cdd.CompareTask(ctx, tk)
+		return err
+	}, func(err error) {
+		switch {
+		case err == context.Canceled:
+		case err != nil:
+			gi.PromptDialog(dv.ViewportSafe(), gi.DlgOpts{Title: "Compare Failed", Prompt: err.Error()}, gi.AddOk, gi.NoCancel, nil, nil)
+		default:
+			dv.Diff.Root = root
+		}
+		dv.UpdateTree()
+	})
+}
+
+// RunTask runs given function in a Task of the window of the view (see
+// gi.TaskWindow), calling done with its error on the event goroutine of
+// the window when it has returned -- context.Canceled if it was cancelled.
+// If there is no window, it runs synchronously, with a nil Task.
+func (dv *DirDiffView) RunTask(name string, fun gi.TaskFunc, done func(err error)) {
+	finish := func(err error) {
+		dv.Task = nil
+		done(err)
+		if dv.recompare {
+			dv.recompare = false
+			dv.Compare()
+		}
+	}
+	win := gi.TaskWindow(dv.ViewportSafe())
+	if win == nil {
+		finish(fun(context.Background(), nil))
+		return
+	}
+	dv.Task = win.RunTask(name, fun, func(tk *gi.Task) {
+		finish(tk.Err)
+	})
+	dv.UpdateToolBar()
+}
+
+// OptsChanged updates the view after the options are changed: comparing
+// the trees again if the files that are skipped have changed, and
+// otherwise just showing the files of the statuses that are now shown
+func (dv *DirDiffView) OptsChanged() {
+	if dv.Diff == nil {
+		return
+	}
+	do := &dv.Diff.Opts
+	if do.Exclude != dv.cmpOpts.Exclude || do.GitIgnore != dv.cmpOpts.GitIgnore {
+		dv.Compare()
+		return
+	}
+	dv.UpdateTree()
+}
+
+// UpdateTree updates the tree and summary from the comparison
+func (dv *DirDiffView) UpdateTree() {
+	dv.Config()
+	wupdt := dv.TopUpdateStart()
+	defer dv.TopUpdateEnd(wupdt)
+	dv.Summary().SetText(dv.SummaryText())
+	updt := dv.Tree.UpdateStart()
+	if dv.Diff == nil || dv.Diff.Root == nil {
+		dv.Tree.DeleteChildren(ki.DestroyKids)
+	} else {
+		dv.Tree.Entry = dv.Diff.Root
+		dv.Tree.ConfigEntries(dv.Diff.Filtered(dv.Diff.Root))
+	}
+	dv.Tree.UpdateEndNoSig(updt)
+	tv := dv.TreeView()
+	tvIdx := 0
+	tv.SyncToSrc(&tvIdx, false, 0)
+	tv.SetFullReRender()
+	dv.Cur = nil
+	if sv := tv.SelectedViews(); len(sv) > 0 {
+		dv.Cur, _ = sv[0].SrcNode.(*DirDiffNode)
+	}
+	dv.UpdateToolBar()
+}
+
+// SummaryText returns the text of the summary: the roots of the trees and
+// the numbers of files of each status
+func (dv *DirDiffView) SummaryText() string {
+	dd := dv.Diff
+	if dd == nil {
+		return ""
+	}
+	root := func(fsys vfs.FS, root, rev string) string {
+		if rev != "" {
+			return rev
+		}
+		if root == "" && fsys != nil {
+			return "/"
+		}
+		return root
+	}
+	n := dd.Counts()
+	return fmt.Sprintf("<b>A:</b> %v  <b>B:</b> %v  --  %d changed, %d added, %d removed, %d same",
+		string(HTMLEscapeBytes([]byte(root(dd.FSA, dd.RootA, dd.RevA)))), string(HTMLEscapeBytes([]byte(root(dd.FSB, dd.RootB, dd.RevB)))),
+		n[DirDiffChanged], n[DirDiffAdded], n[DirDiffRemoved], n[DirDiffSame])
+}
+
+// OpenDiff opens a DiffView of the file at given slash-separated path
+// relative to the roots in A and B
+func (dv *DirDiffView) OpenDiff(rel string) *DiffView {
+	dd := dv.Diff
+	astr, bstr, err := dd.FileLines(rel)
+	if err != nil {
+		gi.PromptDialog(dv.ViewportSafe(), gi.DlgOpts{Title: "Can Not Diff", Prompt: err.Error()}, gi.AddOk, gi.NoCancel, nil, nil)
+		return nil
+	}
+	return DiffViewDialog(dv.ViewportSafe(), astr, bstr, dd.PathA(rel), dd.PathB(rel), dd.RevA, dd.RevB, DlgOpts{Title: "Diff: " + rel})
+}
+
+// CopyPrompt asks for confirmation, and then makes the selected file or
+// directory the same in B as in A (if toB), or vice-versa
+func (dv *DirDiffView) CopyPrompt(toB bool) {
+	de := dv.CurEntry()
+	if de == nil || de.Status == DirDiffSame || dv.Task != nil {
+		return
+	}
+	from, to := "B", "A"
+	if toB {
+		from, to = "A", "B"
+	}
+	what := "Copy %v from %v to %v, replacing it there?"
+	if (toB && !de.InA()) || (!toB && !de.InB()) {
+		what = "Remove %[1]v from %[3]v, as it is not in %[2]v?"
+	}
+	rel := de.Path
+	gi.PromptDialog(dv.ViewportSafe(), gi.DlgOpts{Title: "Copy to " + to, Prompt: fmt.Sprintf(what, rel, from, to)}, gi.AddOk, gi.AddCancel,
+		dv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			if sig != int64(gi.DialogAccepted) {
+				return
+			}
+			dvv := recv.Embed(KiT_DirDiffView).(*DirDiffView)
+			dvv.Copy(rel, toB)
+		})
+}
+
+// Copy makes the file or directory at given slash-separated path relative
+// to the roots the same in B as in A (if toB), or vice-versa, in a Task
+// (see RunTask), and updates the view when it is done -- if it is
+// cancelled, the trees are compared again, to show what was copied
+func (dv *DirDiffView) Copy(rel string, toB bool) {
+	if dv.Diff == nil || dv.Task != nil {
+		return
+	}
+	cdd := *dv.Diff // the options can be changed while copying
+	var set func()
+	dv.RunTask("Copying "+rel, func(ctx context.Context, tk *gi.Task) error {
+		var err error
+		set, err = cdd.copyTo(ctx, tk, rel, toB)
+		return err
+	}, func(err error) {
+		if set != nil {
+			set()
+			dv.Diff.Root = cdd.Root // if compared again from the roots
+		}
+		switch {
+		case err == context.Canceled:
+			dv.recompare = true
+		case err != nil:
+			gi.PromptDialog(dv.ViewportSafe(), gi.DlgOpts{Title: "Copy Failed", Prompt: err.Error()}, gi.AddOk, gi.NoCancel, nil, nil)
+		}
+		dv.UpdateTree()
+	})
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//   DirDiffTreeView
+
+// DirDiffTreeView is a TreeView of a DirDiffView, coloring the files and
+// directories by their status
+type DirDiffTreeView struct {
+	TreeView
+}
+
+var KiT_DirDiffTreeView = kit.Types.AddType(&DirDiffTreeView{}, nil)
+
+// AddNewDirDiffTreeView adds a new directory diff tree view to given parent
+// node, with given name.
+func AddNewDirDiffTreeView(parent ki.Ki, name string) *DirDiffTreeView {
+	tv := parent.AddNewChild(KiT_DirDiffTreeView, name).(*DirDiffTreeView)
+	tv.OpenDepth = 2
+	return tv
+}
+
+func init() {
+	kit.Types.SetProps(KiT_DirDiffTreeView, DirDiffTreeViewProps)
+}
+
+func (tv *DirDiffTreeView) Style2D() {
+	tv.Class = ""
+	if dn, ok := tv.SrcNode.(*DirDiffNode); ok && dn.Entry != nil {
+		if dn.Entry.IsDir {
+			if dn.HasChildren() {
+				tv.Icon = gi.IconName("")
+			} else {
+				tv.Icon = gi.IconName("folder")
+			}
+			tv.SetProp("#branch", fnFolderProps)
+		} else {
+			tv.Icon = "blank"
+		}
+		switch dn.Entry.Status {
+		case DirDiffChanged:
+			tv.AddClass("changed")
+		case DirDiffAdded:
+			tv.AddClass("added")
+		case DirDiffRemoved:
+			tv.AddClass("removed")
+		}
+	}
+	tv.StyleTreeView()
+	tv.LayState.SetFromStyle(&tv.Sty.Layout) // also does reset
+}
+
+// DirDiffTreeViewProps are style properties for DirDiffTreeView
+var DirDiffTreeViewProps = ki.Props{
+	"EnumType:Flag":    KiT_TreeViewFlags,
+	"indent":           units.NewCh(2),
+	"spacing":          units.NewCh(.5),
+	"border-width":     units.NewPx(0),
+	"border-radius":    units.NewPx(0),
+	"padding":          units.NewPx(0),
+	"margin":           units.NewPx(1),
+	"text-align":       gi.AlignLeft,
+	"vertical-align":   gi.AlignTop,
+	"color":            &gi.Prefs.Colors.Font,
+	"background-color": "inherit",
+	"no-templates":     true,
+	".changed": ki.Props{
+		"color": "#4b7fd1",
+	},
+	".added": ki.Props{
+		"color": "#008800",
+	},
+	".removed": ki.Props{
+		"color": "#ff4252",
+	},
+	"#icon": ki.Props{
+		"width":   units.NewEm(1),
+		"height":  units.NewEm(1),
+		"margin":  units.NewPx(0),
+		"padding": units.NewPx(0),
+		"fill":    &gi.Prefs.Colors.Icon,
+		"stroke":  &gi.Prefs.Colors.Font,
+	},
+	"#branch": ki.Props{
+		"icon":             "wedge-down",
+		"icon-off":         "wedge-right",
+		"margin":           units.NewPx(0),
+		"padding":          units.NewPx(0),
+		"background-color": color.Transparent,
+		"max-width":        units.NewEm(.8),
+		"max-height":       units.NewEm(.8),
+	},
+	"#space": ki.Props{
+		"width": units.NewEm(.5),
+	},
+	"#label": ki.Props{
+		"margin":    units.NewPx(0),
+		"padding":   units.NewPx(0),
+		"min-width": units.NewCh(16),
+	},
+	TreeViewSelectors[TreeViewActive]: ki.Props{},
+	TreeViewSelectors[TreeViewSel]: ki.Props{
+		"background-color": &gi.Prefs.Colors.Select,
+	},
+	TreeViewSelectors[TreeViewFocus]: ki.Props{
+		"background-color": &gi.Prefs.Colors.Control,
+	},
+}
@@ -7,8 +7,6 @@ package giv
 import (
 	"errors"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
@@ -17,6 +15,7 @@ import (
 
 	"github.com/c2h5oh/datasize"
 	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/vfs"
 	"github.com/goki/ki/ki"
 	"github.com/goki/ki/kit"
 	"github.com/goki/pi/filecat"
@@ -37,6 +36,7 @@ type FileInfo struct {
 	ModTime FileTime          `desc:"time that contents (only) were last modified"`
 	Vcs     vci.FileStatus    `tableview:"-" desc:"version control system status, when enabled"`
 	Path    string            `tableview:"-" desc:"full path to file, including name -- for file functions"`
+	FS      vfs.FS            `tableview:"-" view:"-" json:"-" xml:"-" desc:"file system of the file -- nil for the OS"`
 }
 
 var KiT_FileInfo = kit.Types.AddType(&FileInfo{}, FileInfoProps)
@@ -51,12 +51,21 @@ func NewFileInfo(fname string) (*FileInfo, error) {
 	return fi, err
 }
 
+// NewFileInfoFS returns a new FileInfo for a file in given file system --
+// see NewFileInfo
+func NewFileInfoFS(fsys vfs.FS, fname string) (*FileInfo, error) {
+	fi := &FileInfo{FS: fsys}
+	err := fi.InitFile(fname)
+	return fi, err
+}
+
 // InitFile initializes a FileInfo based on a filename -- directly returns
 // filepath.Abs or os.Stat error on the given file.  filename can be anything
 // that works given current directory -- Path will contain the full
-// filepath.Abs path, and Name will be just the filename.
+// filepath.Abs path, and Name will be just the filename.  The file is in
+// the FS file system.
 func (fi *FileInfo) InitFile(fname string) error {
-	path, err := filepath.Abs(fname)
+	path, err := vfs.Abs(fi.FS, fname)
 	if err != nil {
 		return err
 	}
@@ -67,9 +76,10 @@ func (fi *FileInfo) InitFile(fname string) error {
 
 // Stat runs os.Stat on file, returns any error directly but otherwise updates
 // file info, including mime type, which then drives Kind and Icon -- this is
-// the main function to call to update state.
+// the main function to call to update state.  The mime type of files not in
+// the OS is determined from their names.
 func (fi *FileInfo) Stat() error {
-	info, err := os.Stat(fi.Path)
+	info, err := vfs.OrOS(fi.FS).Stat(fi.Path)
 	if err != nil {
 		return err
 	}
@@ -84,7 +94,11 @@ func (fi *FileInfo) Stat() error {
 		fi.Cat = filecat.Unknown
 		fi.Sup = filecat.NoSupport
 		fi.Kind = ""
-		mtyp, _, err := filecat.MimeFromFile(fi.Path)
+		mpath := fi.Path
+		if !vfs.IsOS(fi.FS) {
+			mpath = fi.Name // not a path in the OS
+		}
+		mtyp, _, err := filecat.MimeFromFile(mpath)
 		if err == nil {
 			fi.Mime = mtyp
 			fi.Cat = filecat.CatFromMime(fi.Mime)
@@ -152,39 +166,19 @@ func (fi *FileInfo) Duplicate() (string, error) {
 	dst := noext + "_Copy" + ext
 	cpcnt := 0
 	for {
-		if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		if _, err := vfs.OrOS(fi.FS).Stat(dst); !os.IsNotExist(err) {
 			cpcnt++
 			dst = noext + fmt.Sprintf("_Copy%d", cpcnt) + ext
 		} else {
 			break
 		}
 	}
-	return dst, CopyFile(dst, fi.Path, fi.Mode)
+	return dst, vfs.CopyFile(fi.FS, dst, fi.FS, fi.Path, fi.Mode)
 }
 
 // Delete deletes the file or if a directory the directory and all files and subdirectories
 func (fi *FileInfo) Delete() error {
-	if fi.IsDir() {
-		path := fi.Path
-		d, err := os.Open(path)
-		if err != nil {
-			return err
-		}
-		defer d.Close()
-		names, err := d.Readdirnames(-1)
-		if err != nil {
-			return err
-		}
-		for _, name := range names {
-			err = os.RemoveAll(filepath.Join(path, name))
-			if err != nil {
-				return err
-			}
-		}
-	}
-	// remove file or directory
-	return os.Remove(fi.Path)
-	// note: we should be deleted now!
+	return vfs.RemoveAll(fi.FS, fi.Path)
 }
 
 // FileNames recursively adds fullpath filenames within the starting directory to the "names" slice.
@@ -220,15 +214,12 @@ func (fi *FileInfo) FileNames(names *[]string) (err error) {
 		err = errors.New("Not a directory: FileNames returns a list of files within a directory")
 		return err
 	}
-	path := fi.Path
-	d, err := os.Open(path)
-	if err != nil {
+	return vfs.Walk(fi.FS, fi.Path, func(fp string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			*names = append(*names, fp)
+		}
 		return err
-	}
-	defer d.Close()
-
-	err = FileNames(*d, names)
-	return err
+	})
 }
 
 // Rename returns the proposed path or the new full path
@@ -395,29 +386,7 @@ func (fs FileSize) String() string {
 // CopyFile copies the contents from src to dst atomically.
 // If dst does not exist, CopyFile creates it with permissions perm.
 // If the copy fails, CopyFile aborts and dst is preserved.
+// See vfs.CopyFile for copying between file systems.
 func CopyFile(dst, src string, perm os.FileMode) error {
-	in, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer in.Close()
-	tmp, err := ioutil.TempFile(filepath.Dir(dst), "")
-	if err != nil {
-		return err
-	}
-	_, err = io.Copy(tmp, in)
-	if err != nil {
-		tmp.Close()
-		os.Remove(tmp.Name())
-		return err
-	}
-	if err = tmp.Close(); err != nil {
-		os.Remove(tmp.Name())
-		return err
-	}
-	if err = os.Chmod(tmp.Name(), perm); err != nil {
-		os.Remove(tmp.Name())
-		return err
-	}
-	return os.Rename(tmp.Name(), dst)
+	return vfs.CopyFile(vfs.OS, dst, vfs.OS, src, perm)
 }
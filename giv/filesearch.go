@@ -0,0 +1,481 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goki/gi/giv/textbuf"
+	"github.com/goki/gi/giv/vfs"
+	"github.com/goki/ki/ints"
+	"github.com/goki/ki/ki"
+	"github.com/goki/pi/lex"
+)
+
+// FileSearchOpts are the options for searching the files of a FileTree
+type FileSearchOpts struct {
+	Find       string `desc:"text to find"`
+	Regexp     bool   `desc:"Find is a regular expression -- otherwise it is literal text -- the replacement can then use $1 etc for submatches"`
+	WholeWord  bool   `desc:"only match whole words"`
+	IgnoreCase bool   `desc:"match regardless of upper / lower case"`
+	Include    string `desc:"space-separated glob patterns of the files to search, e.g., *.go -- all files if empty -- patterns containing a / are relative to the root of the tree, others match names at any depth"`
+	Exclude    string `desc:"space-separated glob patterns of files and directories to skip, as for Include"`
+	GitIgnore  bool   `desc:"skip files and directories ignored by the .gitignore files in the tree"`
+	SkipClosed bool   `desc:"skip directories that are closed in the tree"`
+	External   bool   `desc:"also search the external files of the tree"`
+}
+
+// Defaults sets the default options
+func (fo *FileSearchOpts) Defaults() {
+	fo.GitIgnore = true
+	fo.External = true
+}
+
+// FileSearchMatch is one match of a FileSearch within a file
+type FileSearchMatch struct {
+	textbuf.Match
+	Line   string   `desc:"the full line of text containing the match"`
+	Before []string `desc:"lines of context before the match line"`
+	After  []string `desc:"lines of context after the match line"`
+	Repl   []byte   `desc:"replacement text for the match, as set by PreviewReplace"`
+	Skip   bool     `desc:"skip this match when replacing"`
+}
+
+// FileSearchResult is the set of matches of a FileSearch within one file
+type FileSearchResult struct {
+	Path    string            `desc:"full path to the file"`
+	FS      vfs.FS            `view:"-" json:"-" xml:"-" desc:"file system of the file -- that of the tree, or the OS for its external files"`
+	Buf     *TextBuf          `json:"-" xml:"-" desc:"open buffer for the file, which is searched instead of the file"`
+	ModTime time.Time         `desc:"modification time of the file when it was searched -- it is not replaced if changed since then"`
+	Matches []FileSearchMatch `desc:"the matches, in order"`
+}
+
+// FileSearch searches for text across all of the files of a FileTree,
+// searching the open buffers of the tree instead of their files, and can
+// then replace the matches.  Files are searched concurrently, with the
+// results for each file streamed out as soon as it has been searched.
+type FileSearch struct {
+	Tree    *FileTree           `json:"-" xml:"-" desc:"the tree to search the files of"`
+	Opts    FileSearchOpts      `desc:"search options"`
+	Re      *regexp.Regexp      `json:"-" xml:"-" desc:"the regexp for the current search"`
+	Results []*FileSearchResult `desc:"results of the current search so far, for the files with matches, in the order they were found"`
+	Mu      sync.Mutex          `json:"-" xml:"-" view:"-" desc:"mutex protecting Results"`
+	stop    chan struct{}
+}
+
+var (
+	// FileSearchWorkers is the number of files that are searched concurrently
+	FileSearchWorkers = runtime.NumCPU()
+
+	// FileSearchContextLines is the number of lines of context before and
+	// after each match that are recorded in results
+	FileSearchContextLines = 1
+
+	// FileSearchMaxFileSize is the size in bytes above which files are not searched
+	FileSearchMaxFileSize int64 = 10 << 20
+
+	// FileSearchSkipDirs are the names of directories that are never searched
+	FileSearchSkipDirs = map[string]bool{".git": true, ".hg": true, ".svn": true, ".bzr": true, "CVS": true}
+)
+
+// errFileSearchStopped is returned in walking files when search is canceled
+var errFileSearchStopped = errors.New("giv.FileSearch: stopped")
+
+// OpenBufs returns the buffers of all the files open in the tree, by full path
+func (ft *FileTree) OpenBufs() map[string]*TextBuf {
+	bufs := make(map[string]*TextBuf)
+	ft.FuncDownMeFirst(0, ft, func(k ki.Ki, level int, d interface{}) bool {
+		sfni := k.Embed(KiT_FileNode)
+		if sfni == nil {
+			return ki.Continue
+		}
+		sfn := sfni.(*FileNode)
+		if sfn.Buf != nil && sfn.Buf.Filename == sfn.FPath {
+			bufs[string(sfn.FPath)] = sfn.Buf
+		}
+		return ki.Continue
+	})
+	return bufs
+}
+
+// Start starts searching the files of given tree using current Opts,
+// canceling any search in progress.  Returns a channel on which the result
+// for each file with matches is sent as it is found, which is closed when
+// the search is complete -- it must be read until closed.  Returns an
+// error if the search text is not valid.
+func (fs *FileSearch) Start(tree *FileTree) (<-chan *FileSearchResult, error) {
+	fs.Cancel()
+	re, err := textbuf.SearchRegexpFor(fs.Opts.Find, fs.Opts.Regexp, fs.Opts.WholeWord, fs.Opts.IgnoreCase)
+	if err != nil {
+		return nil, err
+	}
+	stop := make(chan struct{})
+	fs.Mu.Lock()
+	fs.Tree = tree
+	fs.Re = re
+	fs.Results = nil
+	fs.stop = stop
+	fs.Mu.Unlock()
+
+	// note: everything from the tree is gathered here, so the tree is not
+	// accessed by the search goroutines
+	root := string(tree.FPath)
+	fsys := tree.FileSys()
+	bufs := tree.OpenBufs()
+	dirs := make(DirFlagMap, len(tree.Dirs))
+	for k, v := range tree.Dirs {
+		dirs[k] = v
+	}
+	var exts []string
+	extfs := make(map[string]bool)
+	if fs.Opts.External {
+		exts = append(exts, tree.ExtFiles...)
+		for _, ef := range exts {
+			extfs[ef] = true
+		}
+	}
+
+	files := make(chan string, 100)
+	res := make(chan *FileSearchResult, 100)
+	go fs.walkFiles(fsys, root, dirs, exts, files, stop)
+	var wg sync.WaitGroup
+	nw := ints.MaxInt(FileSearchWorkers, 1)
+	for i := 0; i < nw; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for fpath := range files {
+				select {
+				case <-stop:
+					continue // drain
+				default:
+				}
+				ffs := fsys
+				if extfs[fpath] { // see FileNode.FileSys
+					ffs = vfs.OS
+				}
+				fr := fs.SearchFile(ffs, fpath, bufs[fpath], re)
+				if fr == nil {
+					continue
+				}
+				fs.Mu.Lock()
+				fs.Results = append(fs.Results, fr)
+				fs.Mu.Unlock()
+				select {
+				case res <- fr:
+				case <-stop:
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(res)
+	}()
+	return res, nil
+}
+
+// Search runs the search over the files of given tree to completion,
+// returning the results.
+func (fs *FileSearch) Search(tree *FileTree) ([]*FileSearchResult, error) {
+	res, err := fs.Start(tree)
+	if err != nil {
+		return nil, err
+	}
+	for range res {
+	}
+	fs.Mu.Lock()
+	defer fs.Mu.Unlock()
+	return fs.Results, nil
+}
+
+// Cancel cancels any search in progress -- the results so far are retained
+func (fs *FileSearch) Cancel() {
+	fs.Mu.Lock()
+	defer fs.Mu.Unlock()
+	if fs.stop != nil {
+		close(fs.stop)
+		fs.stop = nil
+	}
+}
+
+// NMatches returns the total number of matches in the results
+func (fs *FileSearch) NMatches() int {
+	fs.Mu.Lock()
+	defer fs.Mu.Unlock()
+	n := 0
+	for _, fr := range fs.Results {
+		n += len(fr.Matches)
+	}
+	return n
+}
+
+// walkFiles sends the files to search to given channel, closing it when
+// done -- the files of the tree at root, in given file system, and the
+// given external files, in the OS
+func (fs *FileSearch) walkFiles(fsys vfs.FS, root string, dirs DirFlagMap, exts []string, files chan<- string, stop <-chan struct{}) {
+	defer close(files)
+	var inc, exc, ign textbuf.Ignore
+	inc.AddPatterns(strings.Fields(fs.Opts.Include)...)
+	exc.AddPatterns(strings.Fields(fs.Opts.Exclude)...)
+	send := func(fpath, rel string) error {
+		if exc.Ignored(rel, false) || (len(inc) > 0 && !inc.Ignored(rel, false)) {
+			return nil
+		}
+		select {
+		case files <- fpath:
+			return nil
+		case <-stop:
+			return errFileSearchStopped
+		}
+	}
+	err := vfs.Walk(fsys, root, func(fpath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // unreadable -- just skip
+		}
+		rel, _ := filepath.Rel(root, fpath)
+		srel := filepath.ToSlash(rel)
+		if info.IsDir() {
+			if rel != "." {
+				if FileSearchSkipDirs[info.Name()] || exc.Ignored(srel, true) ||
+					(fs.Opts.GitIgnore && ign.Ignored(srel, true)) ||
+					(fs.Opts.SkipClosed && !dirs.IsOpen(rel)) {
+					return filepath.SkipDir
+				}
+			} else {
+				srel = ""
+			}
+			if fs.Opts.GitIgnore {
+				if b, err := vfs.ReadFile(fsys, filepath.Join(fpath, ".gitignore")); err == nil {
+					ign.Parse(b, srel)
+				}
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || (fs.Opts.GitIgnore && ign.Ignored(srel, false)) {
+			return nil
+		}
+		return send(fpath, srel)
+	})
+	if err != nil {
+		return
+	}
+	for _, ef := range exts {
+		if info, err := vfs.OS.Stat(ef); err == nil && info.Mode().IsRegular() {
+			if send(ef, filepath.Base(ef)) != nil {
+				return
+			}
+		}
+	}
+}
+
+// SearchFile searches given file in given file system for given regexp,
+// searching the given buffer instead if non-nil -- returns nil if there
+// are no matches, or if the file can not be read, or is binary or too
+// large.
+func (fs *FileSearch) SearchFile(fsys vfs.FS, fpath string, tb *TextBuf, re *regexp.Regexp) *FileSearchResult {
+	fr := &FileSearchResult{Path: fpath, FS: fsys, Buf: tb}
+	if tb != nil {
+		tb.LinesMu.RLock()
+		_, ms := tb.SearchRegexpImpl(re)
+		fr.Matches = NewFileSearchMatches(tb.Store, ms)
+		tb.LinesMu.RUnlock()
+	} else {
+		info, err := vfs.OrOS(fsys).Stat(fpath)
+		if err != nil || info.Size() > FileSearchMaxFileSize {
+			return nil
+		}
+		b, err := vfs.ReadFile(fsys, fpath)
+		if err != nil {
+			return nil
+		}
+		txt, _, _ := textbuf.DecodeText(b)
+		if bytes.IndexByte(txt, 0) >= 0 { // binary
+			return nil
+		}
+		lns := bytes.Split(txt, []byte("\n"))
+		_, ms := textbuf.SearchByteLinesRegexp(lns, re)
+		fr.Matches = NewFileSearchMatches(textbuf.LinesBytes(lns), ms)
+		fr.ModTime = info.ModTime()
+	}
+	if len(fr.Matches) == 0 {
+		return nil
+	}
+	return fr
+}
+
+// NewFileSearchMatches returns matches for given search matches within
+// given lines, with FileSearchContextLines lines of context
+func NewFileSearchMatches(lns textbuf.ByteLines, ms []textbuf.Match) []FileSearchMatch {
+	if len(ms) == 0 {
+		return nil
+	}
+	nln := lns.NumLines()
+	fms := make([]FileSearchMatch, len(ms))
+	for i, m := range ms {
+		fm := &fms[i]
+		fm.Match = m
+		ln := m.Reg.Start.Ln
+		fm.Line = string(lns.Line(ln))
+		for cl := ints.MaxInt(ln-FileSearchContextLines, 0); cl < ln; cl++ {
+			fm.Before = append(fm.Before, string(lns.Line(cl)))
+		}
+		for cl := ln + 1; cl < nln && cl <= ln+FileSearchContextLines; cl++ {
+			fm.After = append(fm.After, string(lns.Line(cl)))
+		}
+	}
+	return fms
+}
+
+// PreviewReplace sets the Repl replacement text for all the matches in
+// the results, which expands $1 etc for submatches if Opts.Regexp is set
+func (fs *FileSearch) PreviewReplace(repl string) {
+	fs.Mu.Lock()
+	defer fs.Mu.Unlock()
+	for _, fr := range fs.Results {
+		fr.PreviewReplace(fs.Re, []byte(repl), fs.Opts.Regexp)
+	}
+}
+
+// PreviewReplace sets the Repl replacement text for the matches in the
+// file, using given regexp the matches were found with.
+func (fr *FileSearchResult) PreviewReplace(re *regexp.Regexp, repl []byte, expand bool) {
+	nm := len(fr.Matches)
+	if nm == 0 {
+		return
+	}
+	src := make([][]byte, fr.Matches[nm-1].Reg.Start.Ln+1)
+	ms := make([]textbuf.Match, nm)
+	for i := range fr.Matches {
+		fm := &fr.Matches[i]
+		src[fm.Reg.Start.Ln] = []byte(fm.Line)
+		ms[i] = fm.Match
+	}
+	repls := textbuf.ReplaceMatches(src, re, ms, repl, expand)
+	for i := range fr.Matches {
+		fr.Matches[i].Repl = repls[i]
+	}
+}
+
+// Replace replaces all the matches in the results that are not marked
+// Skip with given replacement text, as computed by PreviewReplace.  Files
+// open in buffers in the tree are replaced in the buffer, as a single undo
+// group, and the others are replaced in the file directly, unless it has
+// been changed since it was searched.  Matches on lines that have changed
+// since the search are not replaced.  Returns the number of files
+// changed and matches replaced, and an error for the first file that
+// could not be replaced, if any.
+func (fs *FileSearch) Replace(repl string) (nfiles, nrepl int, err error) {
+	fs.PreviewReplace(repl)
+	var bufs map[string]*TextBuf
+	if fs.Tree != nil {
+		bufs = fs.Tree.OpenBufs()
+	}
+	fs.Mu.Lock()
+	defer fs.Mu.Unlock()
+	for _, fr := range fs.Results {
+		var n int
+		var ferr error
+		if tb := bufs[fr.Path]; tb != nil {
+			n = fr.ReplaceBuf(tb)
+		} else {
+			n, ferr = fr.ReplaceFile()
+		}
+		if ferr != nil && err == nil {
+			err = ferr
+		}
+		if n > 0 {
+			nfiles++
+			nrepl += n
+		}
+	}
+	return
+}
+
+// ReplaceBuf replaces the matches in given buffer for the file, as one
+// undo group -- returns number replaced
+func (fr *FileSearchResult) ReplaceBuf(tb *TextBuf) int {
+	tb.Undos.BatchStart()
+	defer tb.Undos.BatchEnd()
+	lns := make(map[int]string) // before replacing, which changes them
+	for i := range fr.Matches {
+		ln := fr.Matches[i].Reg.Start.Ln
+		if _, has := lns[ln]; !has && ln < tb.NumLines() {
+			lns[ln] = string(tb.BytesLine(ln))
+		}
+	}
+	n := 0
+	for i := len(fr.Matches) - 1; i >= 0; i-- {
+		fm := &fr.Matches[i]
+		if fm.Skip || fm.Repl == nil {
+			continue
+		}
+		ln := fm.Reg.Start.Ln
+		if l, has := lns[ln]; !has || l != fm.Line {
+			continue
+		}
+		st := lex.Pos{Ln: ln, Ch: fm.Reg.Start.Ch}
+		ed := lex.Pos{Ln: ln, Ch: fm.Reg.End.Ch}
+		tb.ReplaceText(st, ed, st, string(fm.Repl), EditSignal, false)
+		n++
+	}
+	return n
+}
+
+// ReplaceFile replaces the matches in the file itself, keeping its
+// encoding and line endings -- returns number replaced, and an error
+// without replacing any if the replacements can not be represented in the
+// encoding
+func (fr *FileSearchResult) ReplaceFile() (int, error) {
+	fsys := vfs.OrOS(fr.FS)
+	info, err := fsys.Stat(fr.Path)
+	if err != nil {
+		return 0, err
+	}
+	if !info.ModTime().Equal(fr.ModTime) {
+		return 0, fmt.Errorf("giv.FileSearch: file has changed since it was searched -- not replaced: %v", fr.Path)
+	}
+	b, err := vfs.ReadFile(fsys, fr.Path)
+	if err != nil {
+		return 0, err
+	}
+	txt, enc, le := textbuf.DecodeText(b)
+	lns := bytes.Split(txt, []byte("\n"))
+	var regs []textbuf.Region
+	var repls [][]byte
+	for i := range fr.Matches {
+		fm := &fr.Matches[i]
+		ln := fm.Reg.Start.Ln
+		if fm.Skip || fm.Repl == nil || ln >= len(lns) || string(lns[ln]) != fm.Line {
+			continue
+		}
+		regs = append(regs, fm.Reg)
+		repls = append(repls, fm.Repl)
+	}
+	n := textbuf.ReplaceLines(lns, regs, repls)
+	if n == 0 {
+		return 0, nil
+	}
+	b, nbad := textbuf.EncodeText(bytes.Join(lns, []byte("\n")), enc, le)
+	if nbad > 0 {
+		return 0, fmt.Errorf("giv.FileSearch: %d replaced characters can not be represented in encoding: %v -- not replaced: %v", nbad, enc.Name(), fr.Path)
+	}
+	if err := vfs.WriteFile(fsys, fr.Path, b, info.Mode().Perm()); err != nil {
+		return 0, err
+	}
+	if info, err := fsys.Stat(fr.Path); err == nil {
+		fr.ModTime = info.ModTime()
+	}
+	return n, nil
+}
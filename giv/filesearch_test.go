@@ -0,0 +1,162 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/vfs"
+)
+
+func TestFileSearch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filesearch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		".gitignore":      "ignored/\n*.log\n",
+		"a.go":            "package a\n\n// Foo is foo\nfunc Foo() {}\n",
+		"b.txt":           "foo food Foo\n",
+		"run.log":         "Foo\n",
+		"ignored/c.go":    "Foo\n",
+		"sub/d.go":        "x := Foo() + Foo()\n",
+		"sub/open.go":     "Foo on disk\n",
+		"sub/latin1.go":   "caf\xe9 Foo\n",
+		"sub/binary.dat":  "Foo\x00\x01\x02\n",
+		".git/refs/Foo.g": "Foo\n",
+	}
+	for fn, txt := range files {
+		fp := filepath.Join(dir, fn)
+		os.MkdirAll(filepath.Dir(fp), 0755)
+		if err := ioutil.WriteFile(fp, []byte(txt), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ft := &FileTree{}
+	ft.InitName(ft, "tree")
+	ft.FPath = gi.FileName(dir)
+	opfn := ft.AddNewChild(KiT_FileNode, "open.go").(*FileNode)
+	opfn.FPath = gi.FileName(filepath.Join(dir, "sub", "open.go"))
+	opfn.Buf = newTestTextBuf()
+	opfn.Buf.Filename = opfn.FPath
+	if info, err := os.Stat(string(opfn.FPath)); err == nil {
+		opfn.Buf.Info.ModTime = FileTime(info.ModTime()) // as if opened
+	}
+	opfn.Buf.SetText([]byte("edited Foo in buffer\nand Foo and Foo\n"))
+
+	fs := &FileSearch{}
+	fs.Opts.Defaults()
+	fs.Opts.Find = "Foo"
+	fs.Opts.WholeWord = true
+	res, err := fs.Search(ft)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int{}
+	for _, fr := range res {
+		rel, _ := filepath.Rel(dir, fr.Path)
+		got[filepath.ToSlash(rel)] = len(fr.Matches)
+	}
+	exp := map[string]int{"a.go": 2, "b.txt": 1, "sub/d.go": 2, "sub/latin1.go": 1, "sub/open.go": 3}
+	if len(got) != len(exp) {
+		t.Errorf("files found: %v, expected: %v", got, exp)
+	}
+	for fn, n := range exp {
+		if got[fn] != n {
+			t.Errorf("%v: got %d matches, expected %d", fn, got[fn], n)
+		}
+	}
+
+	fs.Opts.Include = "*.go"
+	fs.Opts.Exclude = "sub/open.go"
+	fs.Opts.IgnoreCase = true
+	res, _ = fs.Search(ft)
+	var fns []string
+	for _, fr := range res {
+		fns = append(fns, filepath.Base(fr.Path))
+	}
+	sort.Strings(fns)
+	if len(fns) != 3 || fns[0] != "a.go" || fns[1] != "d.go" || fns[2] != "latin1.go" {
+		t.Errorf("include / exclude: got files: %v", fns)
+	}
+
+	fs.Opts = FileSearchOpts{Find: `Foo\(\)`, Regexp: true, GitIgnore: true, Include: "sub/**"}
+	res, _ = fs.Search(ft)
+	if len(res) != 1 || len(res[0].Matches) != 2 {
+		t.Fatalf("regexp: expected 2 matches in 1 file, got: %v", res)
+	}
+
+	fs.Opts = FileSearchOpts{Find: "(F)oo", Regexp: true, GitIgnore: true, Include: "sub/[do]*.go"}
+	res, _ = fs.Search(ft)
+	for _, fr := range res {
+		if fr.Buf == nil {
+			fr.Matches[1].Skip = true
+		}
+	}
+	nfiles, nrepl, err := fs.Replace("${1}ix")
+	if err != nil {
+		t.Error(err)
+	}
+	if nfiles != 2 || nrepl != 4 {
+		t.Errorf("replace: got %d files %d replaced, expected 2 files 4 replaced", nfiles, nrepl)
+	}
+	b, _ := ioutil.ReadFile(filepath.Join(dir, "sub", "d.go"))
+	if string(b) != "x := Fix() + Foo()\n" {
+		t.Errorf("replaced file: %q", b)
+	}
+	if txt := string(opfn.Buf.Text()); txt != "edited Fix in buffer\nand Fix and Fix\n" {
+		t.Errorf("replaced buffer: %q", txt)
+	}
+	b, _ = ioutil.ReadFile(filepath.Join(dir, "sub", "open.go"))
+	if string(b) != files["sub/open.go"] {
+		t.Errorf("open file should not be changed on disk: %q", b)
+	}
+	opfn.Buf.Undo()
+	if txt := string(opfn.Buf.Text()); txt != "edited Foo in buffer\nand Foo and Foo\n" {
+		t.Errorf("undo of replace in buffer: %q", txt)
+	}
+
+	fs.Opts = FileSearchOpts{Find: "Foo", Include: "sub/latin1.go"}
+	fs.Search(ft)
+	if _, nrepl, err := fs.Replace("F€€"); err == nil || nrepl != 0 {
+		t.Errorf("replace not representable in encoding: %d %v", nrepl, err)
+	}
+	b, _ = ioutil.ReadFile(filepath.Join(dir, "sub", "latin1.go"))
+	if string(b) != files["sub/latin1.go"] {
+		t.Errorf("file not representable in encoding should not be changed: %q", b)
+	}
+}
+
+func TestFileSearchFS(t *testing.T) {
+	if gi.TheIconMgr == nil {
+		gi.TheIconMgr = &testIconMgr{}
+	}
+	fsys := newTestDirFS(t, map[string]string{".gitignore": "*.log\n", "a.go": "Foo Foo\n", "b.log": "Foo\n", "sub/c.go": "no\n"})
+	ft := &FileTree{}
+	ft.InitName(ft, "tree")
+	ft.OpenPathFS(fsys, "/root")
+	fs := &FileSearch{}
+	fs.Opts.Defaults()
+	fs.Opts.Find = "Foo"
+	res, err := fs.Search(ft)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0].Path != vfs.Clean("/root/a.go") || len(res[0].Matches) != 2 {
+		t.Fatalf("results in file system of tree: %v", res)
+	}
+	if _, nrepl, err := fs.Replace("Bar"); err != nil || nrepl != 2 {
+		t.Errorf("replace: %d %v", nrepl, err)
+	}
+	if b, _ := vfs.ReadFile(fsys, "/root/a.go"); string(b) != "Bar Bar\n" {
+		t.Errorf("replaced file: %q", b)
+	}
+}
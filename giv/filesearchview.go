@@ -0,0 +1,447 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/textbuf"
+	"github.com/goki/gi/units"
+	"github.com/goki/ki/ki"
+	"github.com/goki/ki/kit"
+)
+
+const (
+	// FileSearchLinkScheme is the URL scheme of links to matches in
+	// FileSearchView results, which are of the form:
+	// filesearch:///full/path/to/file#LxxCxx-LxxCxx
+	FileSearchLinkScheme = "filesearch:///"
+
+	// FileSearchSkipScheme is the URL scheme of the links in replace
+	// previews that toggle skipping a match, of the form:
+	// filesearchskip:///fileidx/matchidx
+	FileSearchSkipScheme = "filesearchskip:///"
+)
+
+// FileSearchLink returns the link to given region of given file, as used
+// in FileSearchView results
+func FileSearchLink(fpath string, reg textbuf.Region) string {
+	return fmt.Sprintf("%s%s#L%dC%d-L%dC%d", FileSearchLinkScheme, fpath, reg.Start.Ln+1, reg.Start.Ch+1, reg.End.Ln+1, reg.End.Ch+1)
+}
+
+// ParseFileSearchLink returns the file path and region from a link in
+// FileSearchView results -- false if it is not such a link.
+func ParseFileSearchLink(url string) (string, textbuf.Region, bool) {
+	var reg textbuf.Region
+	if !strings.HasPrefix(url, FileSearchLinkScheme) {
+		return "", reg, false
+	}
+	url = strings.TrimPrefix(url, FileSearchLinkScheme)
+	hi := strings.LastIndex(url, "#")
+	if hi < 0 {
+		return url, reg, true
+	}
+	reg.FromString(url[hi+1:])
+	return url[:hi], reg, true
+}
+
+// FileSearchView is a view for finding and replacing text across all the
+// files of a FileTree, showing the results grouped by file with lines of
+// context, as links that open the file at the match.  Replacing is first
+// previewed, where each match can be skipped, before it is applied.
+type FileSearchView struct {
+	gi.Layout
+	Search  FileSearch `desc:"the search, with its options and results"`
+	Repl    string     `desc:"text to replace matches with -- can use $1 etc for submatches of a regexp"`
+	Preview bool       `desc:"results are a preview of replacing with Repl"`
+	ResBuf  *TextBuf   `json:"-" xml:"-" desc:"buffer holding the results"`
+	LinkSig ki.Signal  `json:"-" xml:"-" view:"-" desc:"signal for clicking on a link to a match in the results -- data is the URL string, which can be decoded by ParseFileSearchLink -- if nobody is receiving this signal, the file is opened in a dialog"`
+	resMu   sync.Mutex
+}
+
+var KiT_FileSearchView = kit.Types.AddType(&FileSearchView{}, FileSearchViewProps)
+
+// AddNewFileSearchView adds a new file search view to given parent node,
+// with given name.
+func AddNewFileSearchView(parent ki.Ki, name string) *FileSearchView {
+	return parent.AddNewChild(KiT_FileSearchView, name).(*FileSearchView)
+}
+
+// FileSearchViewProps are style properties for FileSearchView
+var FileSearchViewProps = ki.Props{
+	"EnumType:Flag": gi.KiT_NodeFlags,
+	"max-width":     -1,
+	"max-height":    -1,
+}
+
+func (fsv *FileSearchView) Disconnect() {
+	fsv.Layout.Disconnect()
+	fsv.LinkSig.DisconnectAll()
+}
+
+// Config configures the view to search the files of given tree
+func (fsv *FileSearchView) Config(tree *FileTree) {
+	fsv.Search.Tree = tree
+	fsv.Lay = gi.LayoutVert
+	config := kit.TypeAndNameList{}
+	config.Add(KiT_StructViewInline, "opts")
+	config.Add(gi.KiT_ToolBar, "toolbar")
+	config.Add(gi.KiT_Layout, "results-lay")
+	mods, updt := fsv.ConfigChildren(config, ki.UniqueNames)
+	if !mods {
+		updt = fsv.UpdateStart()
+	} else {
+		fsv.ConfigToolBar()
+		rl := fsv.ChildByName("results-lay", 2).(*gi.Layout)
+		rl.SetStretchMax()
+		rl.SetMinPrefWidth(units.NewCh(60))
+		rl.SetMinPrefHeight(units.NewEm(20))
+		if fsv.ResBuf == nil {
+			fsv.ResBuf = &TextBuf{}
+			fsv.ResBuf.InitName(fsv.ResBuf, "file-search-results")
+			fsv.ResBuf.Undos.Off = true
+		}
+		tv := AddNewTextView(rl, "results")
+		tv.SetInactive()
+		tv.SetProp("font-family", gi.Prefs.MonoFont)
+		tv.SetBuf(fsv.ResBuf)
+		tv.LinkSig.Connect(fsv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			fsvv := recv.Embed(KiT_FileSearchView).(*FileSearchView)
+			fsvv.OpenLink(data.(string))
+		})
+	}
+	fsv.OptsView().SetStruct(&fsv.Search.Opts)
+	fsv.UpdateEnd(updt)
+}
+
+// OptsView returns the view of the search options
+func (fsv *FileSearchView) OptsView() *StructViewInline {
+	return fsv.ChildByName("opts", 0).(*StructViewInline)
+}
+
+// ToolBar returns the toolbar
+func (fsv *FileSearchView) ToolBar() *gi.ToolBar {
+	return fsv.ChildByName("toolbar", 1).(*gi.ToolBar)
+}
+
+// ResultsView returns the text view showing the results
+func (fsv *FileSearchView) ResultsView() *TextView {
+	return fsv.ChildByName("results-lay", 2).ChildByName("results", 0).(*TextView)
+}
+
+// ConfigToolBar adds the actions to the toolbar
+func (fsv *FileSearchView) ConfigToolBar() {
+	tb := fsv.ToolBar()
+	tb.AddAction(gi.ActOpts{Label: "Find", Icon: "search", Tooltip: "find all matches in the files of the tree"}, fsv.This(),
+		func(recv, send ki.Ki, sig int64, data interface{}) {
+			fsvv := recv.Embed(KiT_FileSearchView).(*FileSearchView)
+			fsvv.Find()
+		})
+	tb.AddAction(gi.ActOpts{Label: "Cancel", Icon: "close", Tooltip: "stop the search in progress"}, fsv.This(),
+		func(recv, send ki.Ki, sig int64, data interface{}) {
+			fsvv := recv.Embed(KiT_FileSearchView).(*FileSearchView)
+			fsvv.Search.Cancel()
+		})
+	tb.AddSeparator("repl-sep")
+	gi.AddNewLabel(tb, "repl-lbl", "Replace:")
+	tf := gi.AddNewTextField(tb, "repl")
+	tf.SetProp("width", "20em")
+	tf.Tooltip = "text to replace matches with -- can use $1 etc for submatches of a regexp"
+	tf.TextFieldSig.Connect(fsv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+		if sig == int64(gi.TextFieldDone) || sig == int64(gi.TextFieldDeFocused) {
+			fsvv := recv.Embed(KiT_FileSearchView).(*FileSearchView)
+			fsvv.Repl = send.(*gi.TextField).Text()
+		}
+	})
+	tb.AddAction(gi.ActOpts{Label: "Preview", Icon: "edit", Tooltip: "show what replacing all the matches would do -- matches can then be skipped before replacing"}, fsv.This(),
+		func(recv, send ki.Ki, sig int64, data interface{}) {
+			fsvv := recv.Embed(KiT_FileSearchView).(*FileSearchView)
+			fsvv.PreviewReplace()
+		})
+	tb.AddAction(gi.ActOpts{Label: "Replace All", Icon: "checkmark", Tooltip: "replace all the matches that are not skipped -- open files are replaced in their buffers, where it can be undone, and others are replaced in the files directly"}, fsv.This(),
+		func(recv, send ki.Ki, sig int64, data interface{}) {
+			fsvv := recv.Embed(KiT_FileSearchView).(*FileSearchView)
+			fsvv.ReplaceAll()
+		})
+}
+
+// Find starts searching with the current options, streaming the results
+// into the view as they are found
+func (fsv *FileSearchView) Find() {
+	if fsv.Search.Tree == nil {
+		return
+	}
+	fsv.Preview = false
+	res, err := fsv.Search.Start(fsv.Search.Tree)
+	if err != nil {
+		gi.PromptDialog(fsv.ViewportSafe(), gi.DlgOpts{Title: "Invalid Search", Prompt: err.Error()}, gi.AddOk, gi.NoCancel, nil, nil)
+		return
+	}
+	fsv.ResetResults()
+	go func() {
+		for fr := range res {
+			fsv.AppendResult(fr)
+		}
+		fsv.AppendSummary()
+	}()
+}
+
+// PreviewReplace shows the results of replacing all matches with Repl
+func (fsv *FileSearchView) PreviewReplace() {
+	fsv.Repl = fsv.ToolBar().ChildByName("repl", 3).(*gi.TextField).Text()
+	fsv.Search.PreviewReplace(fsv.Repl)
+	fsv.Preview = true
+	fsv.RenderResults()
+}
+
+// ReplaceAll replaces all the matches that are not skipped with Repl,
+// and then searches again
+func (fsv *FileSearchView) ReplaceAll() {
+	fsv.Repl = fsv.ToolBar().ChildByName("repl", 3).(*gi.TextField).Text()
+	nfiles, nrepl, err := fsv.Search.Replace(fsv.Repl)
+	msg := fmt.Sprintf("Replaced %d matches in %d files", nrepl, nfiles)
+	if err != nil {
+		msg += "\nError: " + err.Error()
+	}
+	gi.PromptDialog(fsv.ViewportSafe(), gi.DlgOpts{Title: "Replace All", Prompt: msg}, gi.AddOk, gi.NoCancel, nil, nil)
+	fsv.Find()
+}
+
+// ResetResults clears the results view
+func (fsv *FileSearchView) ResetResults() {
+	fsv.resMu.Lock()
+	defer fsv.resMu.Unlock()
+	fsv.ResBuf.New(0)
+	so := &fsv.Search.Opts
+	hdr := fmt.Sprintf("Find: %q in: %v", so.Find, fsv.Search.Tree.FPath)
+	if fsv.Preview {
+		hdr += fmt.Sprintf("  Replace with: %q  (click [x] to skip a match)", fsv.Repl)
+	}
+	fsv.ResBuf.AppendTextLineMarkup([]byte(hdr), []byte("<b>"+string(HTMLEscapeBytes([]byte(hdr)))+"</b>"), EditSignal)
+}
+
+// RenderResults shows all the current results in the view
+func (fsv *FileSearchView) RenderResults() {
+	fsv.ResetResults()
+	fsv.Search.Mu.Lock()
+	res := append([]*FileSearchResult{}, fsv.Search.Results...)
+	fsv.Search.Mu.Unlock()
+	for _, fr := range res {
+		fsv.AppendResult(fr)
+	}
+	fsv.AppendSummary()
+}
+
+// AppendResult adds given result for a file to the results view
+func (fsv *FileSearchView) AppendResult(fr *FileSearchResult) {
+	fsv.Search.Mu.Lock()
+	ri := -1
+	for i, r := range fsv.Search.Results {
+		if r == fr {
+			ri = i
+			break
+		}
+	}
+	fsv.Search.Mu.Unlock()
+
+	var txt, mu bytes.Buffer
+	rp := RelFilePath(fr.Path, string(fsv.Search.Tree.FPath))
+	hdr := fmt.Sprintf("\n%v: %d matches\n", rp, len(fr.Matches))
+	txt.WriteString(hdr)
+	mu.WriteString("\n<b>" + string(HTMLEscapeBytes([]byte(hdr[1:len(hdr)-1]))) + "</b>\n")
+	lastLn := -1
+	for mi := range fr.Matches {
+		fm := &fr.Matches[mi]
+		ln := fm.Reg.Start.Ln
+		if ln != lastLn {
+			for bi, bl := range fm.Before {
+				fsv.appendContext(&txt, &mu, &lastLn, ln-len(fm.Before)+bi, bl)
+			}
+		}
+		var pfx, pfxmu string
+		if fsv.Preview {
+			pfx = "[ ] "
+			if !fm.Skip {
+				pfx = "[x] "
+			}
+			pfxmu = fmt.Sprintf(`<a href="%s%d/%d">%s</a>`, FileSearchSkipScheme, ri, mi, strings.TrimSpace(pfx)) + " "
+		}
+		lnstr := fmt.Sprintf("%6d", ln+1)
+		pre, mat, post := fileSearchSplitLine(fm)
+		txt.WriteString(pfx + lnstr + ": " + pre)
+		mu.WriteString(pfxmu + fmt.Sprintf(`<a href="%s">%s</a>: `, FileSearchLink(fr.Path, fm.Reg), lnstr) + fileSearchEsc(pre))
+		if fsv.Preview && !fm.Skip {
+			txt.WriteString(mat + string(fm.Repl))
+			mu.WriteString("<del>" + fileSearchEsc(mat) + "</del><ins>" + fileSearchEsc(string(fm.Repl)) + "</ins>")
+		} else {
+			txt.WriteString(mat)
+			mu.WriteString("<mark>" + fileSearchEsc(mat) + "</mark>")
+		}
+		txt.WriteString(post + "\n")
+		mu.WriteString(fileSearchEsc(post) + "\n")
+		lastLn = ln
+		nxln := -1
+		if mi+1 < len(fr.Matches) {
+			nxln = fr.Matches[mi+1].Reg.Start.Ln
+		}
+		if nxln != ln {
+			for ai, al := range fm.After {
+				cl := ln + 1 + ai
+				if nxln >= 0 && cl >= nxln {
+					break
+				}
+				fsv.appendContext(&txt, &mu, &lastLn, cl, al)
+			}
+		}
+	}
+	fsv.resMu.Lock()
+	fsv.ResBuf.AppendTextMarkup(txt.Bytes(), mu.Bytes(), EditSignal)
+	fsv.resMu.Unlock()
+}
+
+// appendContext adds given line of context at given line number, if not
+// already shown, as recorded in lastLn
+func (fsv *FileSearchView) appendContext(txt, mu *bytes.Buffer, lastLn *int, ln int, line string) {
+	if ln <= *lastLn {
+		return
+	}
+	pfx := ""
+	if fsv.Preview {
+		pfx = "    "
+	}
+	lt := fmt.Sprintf("%s%6d  %s", pfx, ln+1, line)
+	txt.WriteString(lt + "\n")
+	mu.WriteString(`<span style="color:gray">` + fileSearchEsc(lt) + "</span>\n")
+	*lastLn = ln
+}
+
+// AppendSummary adds the summary line at the end of the results
+func (fsv *FileSearchView) AppendSummary() {
+	fsv.Search.Mu.Lock()
+	nf := len(fsv.Search.Results)
+	fsv.Search.Mu.Unlock()
+	sum := fmt.Sprintf("\n%d matches in %d files", fsv.Search.NMatches(), nf)
+	fsv.resMu.Lock()
+	fsv.ResBuf.AppendTextMarkup([]byte(sum+"\n"), []byte("\n<b>"+sum[1:]+"</b>\n"), EditSignal)
+	fsv.resMu.Unlock()
+}
+
+// fileSearchSplitLine returns the line of given match split into the
+// text before, of, and after the match
+func fileSearchSplitLine(fm *FileSearchMatch) (pre, mat, post string) {
+	rn := []rune(fm.Line)
+	st := fm.Reg.Start.Ch
+	ed := fm.Reg.End.Ch
+	if st > ed || ed > len(rn) {
+		return fm.Line, "", ""
+	}
+	return string(rn[:st]), string(rn[st:ed]), string(rn[ed:])
+}
+
+// fileSearchEsc returns the html-escaped version of given string
+func fileSearchEsc(s string) string {
+	return string(HTMLEscapeBytes([]byte(s)))
+}
+
+// OpenLink opens given link from the results: toggling a skip in a
+// replace preview, or sending the LinkSig for a link to a match, or
+// opening the file at the match if there are no receivers
+func (fsv *FileSearchView) OpenLink(url string) {
+	if strings.HasPrefix(url, FileSearchSkipScheme) {
+		var ri, mi int
+		fmt.Sscanf(strings.TrimPrefix(url, FileSearchSkipScheme), "%d/%d", &ri, &mi)
+		fsv.Search.Mu.Lock()
+		if ri >= 0 && ri < len(fsv.Search.Results) && mi >= 0 && mi < len(fsv.Search.Results[ri].Matches) {
+			fm := &fsv.Search.Results[ri].Matches[mi]
+			fm.Skip = !fm.Skip
+		}
+		fsv.Search.Mu.Unlock()
+		fsv.RenderResults()
+		return
+	}
+	if len(fsv.LinkSig.Cons) > 0 {
+		fsv.LinkSig.Emit(fsv.This(), 0, url)
+		return
+	}
+	fpath, reg, ok := ParseFileSearchLink(url)
+	if !ok {
+		return
+	}
+	fsv.OpenFile(fpath, reg)
+}
+
+// OpenFile opens given file in a dialog, using the buffer of its node in
+// the tree so edits are in sync, and selects given region -- the dialog
+// opens once the file has been read, for large files
+func (fsv *FileSearchView) OpenFile(fpath string, reg textbuf.Region) {
+	if tree := fsv.Search.Tree; tree != nil {
+		if fn, ok := tree.FindFile(fpath); ok && !fn.IsDir() {
+			fn.OpenBufFunc(func(err error) {
+				if err == nil {
+					fsv.openFileDialog(fpath, fn.Buf, reg)
+				}
+			})
+			return
+		}
+	}
+	tb := &TextBuf{}
+	tb.InitName(tb, "file-search-buf")
+	if err := tb.Open(gi.FileName(fpath)); err != nil {
+		return
+	}
+	fsv.openFileDialog(fpath, tb, reg)
+}
+
+// openFileDialog opens a dialog viewing given buffer of given file, and
+// selects given region
+func (fsv *FileSearchView) openFileDialog(fpath string, tb *TextBuf, reg textbuf.Region) {
+	dlg := gi.NewStdDialog(gi.DlgOpts{Title: DirAndFile(fpath), Prompt: filepath.Dir(fpath)}, gi.NoOk, gi.NoCancel)
+	frame := dlg.Frame()
+	_, prIdx := dlg.PromptWidget(frame)
+	tlv := frame.InsertNewChild(gi.KiT_Layout, prIdx+1, "text-lay").(*gi.Layout)
+	tlv.SetProp("width", units.NewCh(80))
+	tlv.SetProp("height", units.NewEm(40))
+	tlv.SetStretchMax()
+	tv := AddNewTextView(tlv, "text-view")
+	tv.Viewport = dlg.Embed(gi.KiT_Viewport2D).(*gi.Viewport2D)
+	tv.SetProp("font-family", gi.Prefs.MonoFont)
+	tv.SetBuf(tb)
+	dlg.UpdateEndNoSig(true)
+	dlg.Open(0, 0, fsv.ViewportSafe(), nil)
+	tv.HighlightRegion(reg)
+	tv.SetCursorShow(reg.Start)
+}
+
+// FileSearchViewDialog opens a dialog for finding and replacing text in
+// the files of given tree, with given options (nil for defaults)
+func FileSearchViewDialog(avp *gi.Viewport2D, tree *FileTree, opts *FileSearchOpts) *FileSearchView {
+	dlg := gi.NewStdDialog(gi.DlgOpts{Title: "Find In Files: " + tree.Nm}, gi.NoOk, gi.NoCancel)
+	frame := dlg.Frame()
+	_, prIdx := dlg.PromptWidget(frame)
+
+	fsv := frame.InsertNewChild(KiT_FileSearchView, prIdx+1, "file-search").(*FileSearchView)
+	fsv.Viewport = dlg.Embed(gi.KiT_Viewport2D).(*gi.Viewport2D)
+	if opts != nil {
+		fsv.Search.Opts = *opts
+	} else {
+		fsv.Search.Opts.Defaults()
+	}
+	fsv.Config(tree)
+
+	dlg.DialogSig.Connect(fsv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+		if sig == int64(gi.DialogAccepted) || sig == int64(gi.DialogCanceled) {
+			fsvv := recv.Embed(KiT_FileSearchView).(*FileSearchView)
+			fsvv.Search.Cancel()
+		}
+	})
+	dlg.UpdateEndNoSig(true)
+	dlg.Open(0, 0, avp, nil)
+	return fsv
+}
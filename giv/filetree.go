@@ -6,10 +6,10 @@ package giv
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"image/color"
-	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
@@ -17,11 +17,14 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Masterminds/vcs"
 	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/fswatch"
 	"github.com/goki/gi/giv/textbuf"
+	"github.com/goki/gi/giv/vfs"
 	"github.com/goki/gi/histyle"
 	"github.com/goki/gi/oswin"
 	"github.com/goki/gi/oswin/dnd"
@@ -64,11 +67,15 @@ const (
 // interface into it.
 type FileTree struct {
 	FileNode
-	ExtFiles  []string     `desc:"external files outside the root path of the tree -- abs paths are stored -- these are shown in the first sub-node if present -- use AddExtFile to add and update"`
-	Dirs      DirFlagMap   `desc:"records state of directories within the tree (encoded using paths relative to root), e.g., open (have been opened by the user) -- can persist this to restore prior view of a tree"`
-	DirsOnTop bool         `desc:"if true, then all directories are placed at the top of the tree view -- otherwise everything is mixed"`
-	NodeType  reflect.Type `view:"-" json:"-" xml:"-" desc:"type of node to create -- defaults to giv.FileNode but can use custom node types"`
-	InOpenAll bool         `desc:"if true, we are in midst of an OpenAll call -- nodes should open all dirs"`
+	ExtFiles      []string                  `desc:"external files outside the root path of the tree -- abs paths are stored -- these are shown in the first sub-node if present -- use AddExtFile to add and update"`
+	Dirs          DirFlagMap                `desc:"records state of directories within the tree (encoded using paths relative to root), e.g., open (have been opened by the user) -- can persist this to restore prior view of a tree"`
+	DirsOnTop     bool                      `desc:"if true, then all directories are placed at the top of the tree view -- otherwise everything is mixed"`
+	NodeType      reflect.Type              `view:"-" json:"-" xml:"-" desc:"type of node to create -- defaults to giv.FileNode but can use custom node types"`
+	FS            vfs.FS                    `view:"-" json:"-" xml:"-" desc:"file system of the files of the tree -- nil for the OS -- the external files are always in the OS"`
+	InOpenAll     bool                      `desc:"if true, we are in midst of an OpenAll call -- nodes should open all dirs"`
+	Watches       map[string]*fswatch.Watch `view:"-" json:"-" xml:"-" desc:"watches of the open directories, by path, if watching for changes -- see Watch"`
+	WatchMu       sync.Mutex                `view:"-" json:"-" xml:"-" desc:"mutex for Watches"`
+	InWatchUpdate bool                      `view:"-" json:"-" xml:"-" desc:"if true, we are in midst of updating for changes to files -- directories that have been read are not read again"`
 }
 
 var KiT_FileTree = kit.Types.AddType(&FileTree{}, FileTreeProps)
@@ -82,6 +89,7 @@ func (ft *FileTree) CopyFieldsFrom(frm interface{}) {
 	ft.FileNode.CopyFieldsFrom(&fr.FileNode)
 	ft.DirsOnTop = fr.DirsOnTop
 	ft.NodeType = fr.NodeType
+	ft.FS = fr.FS
 }
 
 // OpenPath opens a filetree at given directory path -- reads all the files at
@@ -96,6 +104,23 @@ func (ft *FileTree) OpenPath(path string) {
 	ft.UpdateAll()
 }
 
+// OpenPathFS opens a filetree at given directory path in given file system
+// -- see OpenPath
+func (ft *FileTree) OpenPathFS(fsys vfs.FS, path string) {
+	ft.FS = fsys
+	ft.OpenPath(path)
+}
+
+// FileSysOf returns the file system of the file at given path, which is
+// that of the tree if the file is in it, and otherwise the OS -- e.g., for
+// files that are pasted or dropped into the tree
+func (ft *FileTree) FileSysOf(path string) vfs.FS {
+	if _, ok := ft.NodeByPath(path); ok {
+		return ft.FileSys()
+	}
+	return vfs.OS
+}
+
 // UpdateAll does a full update of the tree -- calls ReadDir on current path
 func (ft *FileTree) UpdateAll() {
 	ft.Dirs.ClearMarks()
@@ -168,7 +193,7 @@ func (ft *FileTree) AddExtFile(fpath string) (*FileNode, error) {
 	if err != nil {
 		return nil, err
 	}
-	if _, err := os.Stat(pth); err != nil {
+	if _, err := vfs.OS.Stat(pth); err != nil { // external files are in the OS -- see FileNode.FileSys
 		return nil, err
 	}
 	if has, _ := ft.HasExtFile(pth); has {
@@ -362,6 +387,15 @@ func (fn *FileNode) IsAutoSave() bool {
 	return false
 }
 
+// FileSys returns the file system of the file -- that of the tree, except
+// for external files, which are in the OS
+func (fn *FileNode) FileSys() vfs.FS {
+	if fn.FRoot == nil || fn.IsExternal() {
+		return vfs.OS
+	}
+	return vfs.OrOS(fn.FRoot.FS)
+}
+
 // MyRelPath returns the relative path from root for this node
 func (fn *FileNode) MyRelPath() string {
 	if fn.IsIrregular() {
@@ -377,11 +411,13 @@ func (fn *FileNode) MyRelPath() string {
 func (fn *FileNode) ReadDir(path string) error {
 	_, fnm := filepath.Split(path)
 	fn.SetName(fnm)
-	pth, err := filepath.Abs(path)
+	fsys := fn.FileSys()
+	pth, err := vfs.Abs(fsys, path)
 	if err != nil {
 		return err
 	}
 	fn.FPath = gi.FileName(pth)
+	fn.Info.FS = fsys
 	err = fn.Info.InitFile(string(fn.FPath))
 	if err != nil {
 		log.Printf("giv.FileTree: could not read directory: %v err: %v\n", fn.FPath, err)
@@ -403,6 +439,9 @@ func (fn *FileNode) DetectVcsRepo(updateFiles bool) bool {
 		}
 		return false
 	}
+	if !vfs.IsOS(fn.FileSys()) {
+		return false
+	}
 	path := string(fn.FPath)
 	rtyp := vci.DetectRepo(path)
 	if rtyp == vcs.NoVCS {
@@ -429,6 +468,7 @@ func (fn *FileNode) UpdateDir() {
 	repo, rnode := fn.Repo()
 	fn.SetOpen()
 	fn.FRoot.SetDirOpen(fn.FPath)
+	fn.FRoot.WatchDir(fn.FPath)
 	config := fn.ConfigOfFiles(path)
 	hasExtFiles := false
 	if fn.This() == fn.FRoot.This() {
@@ -474,7 +514,7 @@ func (fn *FileNode) ConfigOfFiles(path string) kit.TypeAndNameList {
 	config1 := kit.TypeAndNameList{}
 	config2 := kit.TypeAndNameList{}
 	typ := fn.FRoot.NodeType
-	filepath.Walk(path, func(pth string, info os.FileInfo, err error) error {
+	vfs.Walk(fn.FileSys(), path, func(pth string, info os.FileInfo, err error) error {
 		if err != nil {
 			emsg := fmt.Sprintf("giv.FileNode ConfigFilesIn Path %q: Error: %v", path, err)
 			log.Println(emsg)
@@ -517,15 +557,16 @@ func (fn *FileNode) ConfigOfFiles(path string) kit.TypeAndNameList {
 // SortConfigByModTime sorts given config list by mod time
 func (fn *FileNode) SortConfigByModTime(confg kit.TypeAndNameList) {
 	sort.Slice(confg, func(i, j int) bool {
-		ifn, _ := os.Stat(filepath.Join(string(fn.FPath), confg[i].Name))
-		jfn, _ := os.Stat(filepath.Join(string(fn.FPath), confg[j].Name))
+		fsys := fn.FileSys()
+		ifn, _ := fsys.Stat(filepath.Join(string(fn.FPath), confg[i].Name))
+		jfn, _ := fsys.Stat(filepath.Join(string(fn.FPath), confg[j].Name))
 		return ifn.ModTime().After(jfn.ModTime()) // descending
 	})
 }
 
 // SetNodePath sets the path for given node and updates it based on associated file
 func (fn *FileNode) SetNodePath(path string) error {
-	pth, err := filepath.Abs(path)
+	pth, err := vfs.Abs(fn.FileSys(), path)
 	if err != nil {
 		return err
 	}
@@ -536,6 +577,9 @@ func (fn *FileNode) SetNodePath(path string) error {
 	}
 	if fn.IsDir() && !fn.IsIrregular() {
 		openAll := fn.FRoot.InOpenAll && !fn.Info.IsHidden()
+		if fn.FRoot.InWatchUpdate && fn.HasChildren() {
+			return nil // only the changed dirs are updated
+		}
 		if openAll || fn.FRoot.IsDirOpen(fn.FPath) {
 			fn.ReadDir(string(fn.FPath)) // keep going down..
 		}
@@ -545,12 +589,14 @@ func (fn *FileNode) SetNodePath(path string) error {
 
 // InitFileInfo initializes file info
 func (fn *FileNode) InitFileInfo() error {
-	effpath, err := filepath.EvalSymlinks(string(fn.FPath))
+	fsys := fn.FileSys()
+	effpath, err := vfs.EvalSymlinks(fsys, string(fn.FPath))
 	if err != nil {
 		log.Printf("giv.FileNode Path: %v could not be opened -- error: %v\n", effpath, err)
 		return err
 	}
 	fn.FPath = gi.FileName(effpath)
+	fn.Info.FS = fsys
 	err = fn.Info.InitFile(string(fn.FPath))
 	if err != nil {
 		emsg := fmt.Errorf("giv.FileNode InitFileInfo Path %q: Error: %v", fn.FPath, err)
@@ -604,6 +650,7 @@ func (fn *FileNode) OpenDir() {
 func (fn *FileNode) CloseDir() {
 	fn.SetClosed()
 	fn.FRoot.SetDirClosed(fn.FPath)
+	fn.FRoot.UnwatchDir(fn.FPath)
 	// note: not doing anything with open files within directory..
 }
 
@@ -641,15 +688,35 @@ func (fn *FileNode) CloseAll() {
 }
 
 // OpenBuf opens the file in its buffer if it is not already open.
-// returns true if file is newly opened
+// returns true if file is newly opened.  Files larger than
+// TextBufOpenTaskBytes are read in a Task of the window, and their text
+// appears in the buffer once it has been read -- see OpenBufFunc.
 func (fn *FileNode) OpenBuf() (bool, error) {
+	return fn.OpenBufFunc(nil)
+}
+
+// OpenBufFunc opens the file in its buffer if it is not already open, like
+// OpenBuf, and calls fun, if non-nil, once the file is open in the buffer,
+// which is after the Task reading it has finished for large files -- with
+// the error if it could not be opened.  returns true if file is newly
+// opened, or being opened.
+func (fn *FileNode) OpenBufFunc(fun func(err error)) (bool, error) {
 	if fn.IsDir() {
 		err := fmt.Errorf("giv.FileNode cannot open directory in editor: %v", fn.FPath)
 		log.Println(err.Error())
 		return false, err
 	}
 	if fn.Buf != nil {
+		if fn.Buf.Opening != nil { // already being opened
+			if fun != nil {
+				fn.Buf.OpeningFuncs = append(fn.Buf.OpeningFuncs, fun)
+			}
+			return false, nil
+		}
 		if fn.Buf.Filename == fn.FPath { // close resets filename
+			if fun != nil {
+				fun(nil)
+			}
 			return false, nil
 		}
 	} else {
@@ -658,7 +725,41 @@ func (fn *FileNode) OpenBuf() (bool, error) {
 		fn.Buf.AddFileNode(fn)
 	}
 	fn.Buf.Hi.Style = FileNodeHiStyle
-	return true, fn.Buf.Open(fn.FPath)
+	fn.Buf.FS = fn.FileSys()
+	if int(fn.Info.Size) > TextBufOpenTaskBytes {
+		fn.Buf.OpenTask(fn.FPath, func(err error) {
+			if err == nil {
+				fn.UpdateBufVcs()
+			}
+			if fun != nil {
+				fun(err)
+			}
+		})
+		return true, nil
+	}
+	err := fn.Buf.Open(fn.FPath)
+	if err == nil {
+		fn.UpdateBufVcs()
+	}
+	if fun != nil {
+		fun(err)
+	}
+	return true, err
+}
+
+// UpdateBufVcs updates the changes since the last commit to version control
+// and the blame shown in the views of the buffer of this file, if it is open
+// and has been committed.
+func (fn *FileNode) UpdateBufVcs() {
+	if fn.Buf == nil {
+		return
+	}
+	repo, _ := fn.Repo()
+	if repo == nil || fn.Info.Vcs == vci.Untracked || fn.Info.Vcs == vci.Added {
+		fn.Buf.SetVcsBase(nil)
+		return
+	}
+	fn.Buf.UpdateVcs(repo)
 }
 
 // CloseBuf closes the file in its buffer if it is open -- returns true if closed
@@ -916,6 +1017,37 @@ func (fn *FileNode) OpenFileWith(command string) error {
 	return err
 }
 
+// FileViewerFunc is a function that opens a viewer for given file,
+// in the context of given viewport
+type FileViewerFunc func(fn *FileNode, vp *gi.Viewport2D)
+
+// FileViewers are the built-in viewers for supported file types, used by
+// ViewFile -- e.g., the ImageView for images
+var FileViewers = map[filecat.Supported]FileViewerFunc{}
+
+// HasViewer returns true if there is a FileViewers entry for this file
+func (fn *FileNode) HasViewer() bool {
+	if fn.IsDir() {
+		return false
+	}
+	_, has := FileViewers[fn.Info.Sup]
+	return has
+}
+
+// ViewFile opens the FileViewers viewer for this file, returning false
+// if there is none for its type
+func (fn *FileNode) ViewFile(vp *gi.Viewport2D) bool {
+	if fn.IsDir() {
+		return false
+	}
+	vf, has := FileViewers[fn.Info.Sup]
+	if !has {
+		return false
+	}
+	vf(fn, vp)
+	return true
+}
+
 // Duplicate creates a copy of given file -- only works for regular files, not
 // directories
 func (fn *FileNode) DuplicateFile() error {
@@ -965,13 +1097,14 @@ func (fn *FileNode) RenameFile(newpath string) (err error) {
 	}
 	repo, _ := fn.Repo()
 	stored := false
+	fsys := fn.FileSys()
 	if fn.IsDir() && !fn.HasChildren() {
-		err = os.Rename(string(orgpath), newpath)
+		err = fsys.Rename(string(orgpath), newpath)
 	} else if repo != nil && fn.Info.Vcs >= vci.Stored {
 		stored = true
 		err = repo.Move(string(orgpath), newpath)
 	} else {
-		err = os.Rename(string(orgpath), newpath)
+		err = fsys.Rename(string(orgpath), newpath)
 	}
 	if err == nil {
 		err = fn.Info.InitFile(newpath)
@@ -999,7 +1132,7 @@ func (fn *FileNode) NewFile(filename string, addToVcs bool) {
 		ppath, _ = filepath.Split(ppath)
 	}
 	np := filepath.Join(ppath, filename)
-	_, err := os.Create(np)
+	err := vfs.WriteFile(fn.FileSys(), np, nil, 0666)
 	if err != nil {
 		gi.PromptDialog(nil, gi.DlgOpts{Title: "Couldn't Make File", Prompt: fmt.Sprintf("Could not make new file at: %v, err: %v", np, err)}, gi.AddOk, gi.NoCancel, nil, nil)
 		return
@@ -1023,7 +1156,7 @@ func (fn *FileNode) NewFolder(foldername string) {
 		ppath, _ = filepath.Split(ppath)
 	}
 	np := filepath.Join(ppath, foldername)
-	err := os.MkdirAll(np, 0775)
+	err := vfs.MkdirAll(fn.FileSys(), np, 0775)
 	if err != nil {
 		emsg := fmt.Sprintf("giv.FileNode at: %q: Error: %v", ppath, err)
 		gi.PromptDialog(nil, gi.DlgOpts{Title: "Couldn't Make Folder", Prompt: emsg}, gi.AddOk, gi.NoCancel, nil, nil)
@@ -1041,7 +1174,7 @@ func (fn *FileNode) CopyFileToDir(filename string, perm os.FileMode) {
 	ppath := string(fn.FPath)
 	sfn := filepath.Base(filename)
 	tpath := filepath.Join(ppath, sfn)
-	CopyFile(tpath, filename, perm)
+	vfs.CopyFile(fn.FileSys(), tpath, fn.FRoot.FileSysOf(filename), filename, perm)
 	fn.FRoot.UpdateNewFile(ppath)
 	ofn, ok := fn.FRoot.FindFile(filename)
 	if ok && ofn.Info.Vcs >= vci.Stored {
@@ -1142,6 +1275,7 @@ func (fn *FileNode) CommitToVcs(message string) (err error) {
 		return err
 	}
 	fn.Info.Vcs = vci.Stored
+	fn.UpdateBufVcs()
 	fn.UpdateSig()
 	fn.FRoot.UpdateSig()
 	return err
@@ -1176,7 +1310,8 @@ func (fn *FileNode) RevertVcs() (err error) {
 // DiffVcs shows the diffs between two versions of this file, given by the
 // revision specifiers -- if empty, defaults to A = current HEAD, B = current WC file.
 // -1, -2 etc also work as universal ways of specifying prior revisions.
-// Diffs are shown in a DiffViewDialog.
+// Diffs are shown in a DiffViewDialog, once the revisions have been
+// retrieved in a Task of the window.
 func (fn *FileNode) DiffVcs(rev_a, rev_b string) error {
 	repo, _ := fn.Repo()
 	if repo == nil {
@@ -1185,7 +1320,22 @@ func (fn *FileNode) DiffVcs(rev_a, rev_b string) error {
 	if fn.Info.Vcs == vci.Untracked {
 		return errors.New("file not in vcs repo: " + string(fn.FPath))
 	}
-	_, err := DiffViewDialogFromRevs(nil, repo, string(fn.FPath), fn.Buf, rev_a, rev_b)
+	DiffViewDialogFromRevsTask(nil, repo, string(fn.FPath), fn.Buf, rev_a, rev_b)
+	return nil
+}
+
+// MergeVcs opens a MergeView for resolving the conflicts in this file,
+// which marks the file as resolved in version control when the result is
+// saved without conflicts.
+func (fn *FileNode) MergeVcs() error {
+	repo, _ := fn.Repo()
+	if repo == nil {
+		return errors.New("file not in vcs repo: " + string(fn.FPath))
+	}
+	if fn.Info.Vcs != vci.Conflicted {
+		return errors.New("file does not have conflicts: " + string(fn.FPath))
+	}
+	_, err := MergeViewDialogFromVcs(nil, repo, fn.FileSys(), string(fn.FPath), fn.Buf)
 	return err
 }
 
@@ -1288,8 +1438,13 @@ func (fn *FileNode) BlameVcs() ([]byte, error) {
 	return blm, nil
 }
 
-// UpdateAllVcs does an update on any repositories below this one in file tree
+// UpdateAllVcs does an update on any repositories below this one in file
+// tree, in a Task of the focused window (see gi.TaskWindow) that shows
+// which one is being updated and can be cancelled between them -- if
+// there is no window, they are updated synchronously.
 func (fn *FileNode) UpdateAllVcs() {
+	var repos []vci.Repo
+	var paths []string
 	fn.FuncDownMeFirst(0, fn, func(k ki.Ki, level int, d interface{}) bool {
 		sfn := k.Embed(KiT_FileNode).(*FileNode)
 		if !sfn.IsDir() {
@@ -1300,14 +1455,44 @@ func (fn *FileNode) UpdateAllVcs() {
 				return ki.Continue
 			}
 		}
-		repo := sfn.DirRepo
-		fmt.Printf("Updating %v repository: %s from: %s\n", repo.Vcs(), sfn.MyRelPath(), repo.Remote())
-		err := repo.Update()
-		if err != nil {
-			fmt.Printf("error: %v\n", err)
-		}
+		repos = append(repos, sfn.DirRepo)
+		paths = append(paths, sfn.MyRelPath())
 		return ki.Break
 	})
+	if len(repos) == 0 {
+		return
+	}
+	update := func(ctx context.Context, tk *gi.Task) error {
+		var errs []string
+		for i, repo := range repos {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if tk != nil {
+				tk.SetMessage(paths[i])
+				tk.SetProgress(float32(i) / float32(len(repos)))
+			}
+			fmt.Printf("Updating %v repository: %s from: %s\n", repo.Vcs(), paths[i], repo.Remote())
+			if err := repo.Update(); err != nil {
+				fmt.Printf("error: %v\n", err)
+				errs = append(errs, fmt.Sprintf("%s: %v", paths[i], err))
+			}
+		}
+		if len(errs) > 0 {
+			return errors.New(strings.Join(errs, "\n"))
+		}
+		return nil
+	}
+	win := gi.TaskWindow(nil)
+	if win == nil {
+		update(context.Background(), nil)
+		return
+	}
+	win.RunTask("Updating repositories", update, func(tk *gi.Task) {
+		if tk.Err != nil && tk.Ctx.Err() == nil {
+			gi.PromptDialog(win.Viewport, gi.DlgOpts{Title: "Update Errors", Prompt: tk.Err.Error()}, gi.AddOk, gi.NoCancel, nil, nil)
+		}
+	})
 }
 
 var FileNodeProps = ki.Props{
@@ -1613,7 +1798,10 @@ func (ftv *FileTreeView) FileTreeViewEvents() {
 			case mouse.Left:
 				switch me.Action {
 				case mouse.DoubleClick:
-					ftvv.ToggleClose()
+					fn := ftvv.FileNode()
+					if fn == nil || !fn.ViewFile(ftvv.ViewportSafe()) {
+						ftvv.ToggleClose()
+					}
 					me.SetProcessed()
 				case mouse.Release:
 					ftvv.SelectAction(me.SelectMode())
@@ -1695,6 +1883,20 @@ func (ftv *FileTreeView) OpenFileDefault() {
 	}
 }
 
+// ViewFiles opens the built-in viewer for selected files (e.g., ImageView for
+// images), or the default app if there is no viewer for the file type
+func (ftv *FileTreeView) ViewFiles() {
+	sels := ftv.SelectedViews()
+	for i := len(sels) - 1; i >= 0; i-- {
+		sn := sels[i]
+		fftv := sn.Embed(KiT_FileTreeView).(*FileTreeView)
+		fn := fftv.FileNode()
+		if fn != nil && !fn.ViewFile(ftv.ViewportSafe()) {
+			fn.OpenFileDefault()
+		}
+	}
+}
+
 // OpenFileWith opens file with user-specified command.
 func (ftv *FileTreeView) OpenFileWith() {
 	sels := ftv.SelectedViews()
@@ -1821,6 +2023,22 @@ func (ftv *FileTreeView) CloseAll() {
 	}
 }
 
+// FindInFiles opens a view for finding and replacing text in the files of
+// the tree, limited to the files in this directory if it is not the root
+func (ftv *FileTreeView) FindInFiles() {
+	fn := ftv.FileNode()
+	if fn == nil || fn.FRoot == nil {
+		return
+	}
+	opts := &FileSearchOpts{}
+	opts.Defaults()
+	if fn.IsDir() && fn.This() != fn.FRoot.This() && !fn.IsExternal() {
+		opts.Include = filepath.ToSlash(fn.MyRelPath()) + "/**"
+		opts.External = false
+	}
+	FileSearchViewDialog(ftv.ViewportSafe(), fn.FRoot, opts)
+}
+
 // SortBy determines how to sort the files in the directory -- default is alpha by name,
 // optionally can be sorted by modification time.
 func (ftv *FileTreeView) SortBy(modTime bool) {
@@ -1951,6 +2169,25 @@ func (ftv *FileTreeView) DiffVcs(rev_a, rev_b string) {
 	}
 }
 
+// MergeVcs opens a MergeView for resolving the conflicts in this file,
+// which marks the file as resolved in version control when the result is
+// saved without conflicts.
+func (ftv *FileTreeView) MergeVcs() {
+	sels := ftv.SelectedViews()
+	sz := len(sels)
+	if sz == 0 { // shouldn't happen
+		return
+	}
+	for i := len(sels) - 1; i >= 0; i-- {
+		sn := sels[i]
+		ftvv := sn.Embed(KiT_FileTreeView).(*FileTreeView)
+		fn := ftvv.FileNode()
+		if fn != nil {
+			fn.MergeVcs()
+		}
+	}
+}
+
 // LogVcs shows the VCS log of commits for this file, optionally with a
 // since date qualifier: If since is non-empty, it should be
 // a date-like expression that the VCS will understand, such as
@@ -2020,12 +2257,7 @@ func (ftv *FileTreeView) MimeData(md *mimedata.Mimes) {
 	*md = append(*md, mimedata.NewTextData(fn.PathFromUnique(sroot)))
 	*md = append(*md, mimedata.NewTextData(path))
 	if int(fn.Info.Size) < gi.Prefs.Params.BigFileSize {
-		in, err := os.Open(path)
-		if err != nil {
-			log.Println(err)
-			return
-		}
-		b, err := ioutil.ReadAll(in)
+		b, err := vfs.ReadFile(fn.FileSys(), path)
 		if err != nil {
 			log.Println(err)
 			return
@@ -2082,6 +2314,10 @@ func (ftv *FileTreeView) PasteCheckExisting(tfn *FileNode, md mimedata.Mimes) ([
 	if intl {
 		nf /= 3
 	}
+	fsys := vfs.OS
+	if tfn != nil {
+		fsys = tfn.FileSys()
+	}
 	var sfn *FileNode
 	var existing []string
 	for i := 0; i < nf; i++ {
@@ -2107,7 +2343,7 @@ func (ftv *FileTreeView) PasteCheckExisting(tfn *FileNode, md mimedata.Mimes) ([
 			_, fnm := filepath.Split(path)
 			path = filepath.Join(tpath, fnm)
 		}
-		if _, err := os.Stat(path); !os.IsNotExist(err) {
+		if _, err := fsys.Stat(path); !os.IsNotExist(err) {
 			existing = append(existing, path)
 		}
 	}
@@ -2212,6 +2448,7 @@ func (ftv *FileTreeView) PasteMime(md mimedata.Mimes) {
 	if sfn != nil {
 		mode = sfn.Info.Mode
 	}
+	tfs, sfs := tfn.FileSys(), tfn.FRoot.FileSysOf(srcpath)
 	if len(existing) > 0 {
 		gi.ChoiceDialog(nil, gi.DlgOpts{Title: "Overwrite?",
 			Prompt: fmt.Sprintf("Overwrite target file: %s with source file, overwrite other existing file with same name as source file (%s), or cancel?", tfn.Nm, fname)},
@@ -2219,11 +2456,11 @@ func (ftv *FileTreeView) PasteMime(md mimedata.Mimes) {
 			ftv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
 				switch sig {
 				case 0:
-					CopyFile(tpath, srcpath, mode)
+					vfs.CopyFile(tfs, tpath, sfs, srcpath, mode)
 					ftv.DragNDropFinalizeDefMod()
 				case 1:
 					npath := filepath.Join(string(tdir.FPath), fname)
-					CopyFile(npath, srcpath, mode)
+					vfs.CopyFile(tfs, npath, sfs, srcpath, mode)
 					ftv.DragNDropFinalizeDefMod()
 				case 2:
 					ftv.DropCancel()
@@ -2236,7 +2473,7 @@ func (ftv *FileTreeView) PasteMime(md mimedata.Mimes) {
 			ftv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
 				switch sig {
 				case 0:
-					CopyFile(tpath, srcpath, mode)
+					vfs.CopyFile(tfs, tpath, sfs, srcpath, mode)
 					ftv.DragNDropFinalizeDefMod()
 				case 1:
 					tdir.CopyFileToDir(srcpath, mode) // does updating, vcs stuff
@@ -2315,6 +2552,15 @@ var FileTreeActiveDirFunc = ActionUpdateFunc(func(fni interface{}, act *gi.Actio
 	}
 })
 
+// FileTreeActiveViewerFunc is an ActionUpdateFunc that activates action if node has a FileViewers viewer
+var FileTreeActiveViewerFunc = ActionUpdateFunc(func(fni interface{}, act *gi.Action) {
+	ftv := fni.(ki.Ki).Embed(KiT_FileTreeView).(*FileTreeView)
+	fn := ftv.FileNode()
+	if fn != nil {
+		act.SetActiveState(fn.HasViewer())
+	}
+})
+
 // FileTreeActiveNotInVcsFunc is an ActionUpdateFunc that inactivates action if node is not under version control
 var FileTreeActiveNotInVcsFunc = ActionUpdateFunc(func(fni interface{}, act *gi.Action) {
 	ftv := fni.(ki.Ki).Embed(KiT_FileTreeView).(*FileTreeView)
@@ -2358,6 +2604,21 @@ var FileTreeActiveInVcsModifiedFunc = ActionUpdateFunc(func(fni interface{}, act
 	}
 })
 
+// FileTreeActiveInVcsConflictedFunc is an ActionUpdateFunc that activates action if node is under version control
+// and the file has merge conflicts
+var FileTreeActiveInVcsConflictedFunc = ActionUpdateFunc(func(fni interface{}, act *gi.Action) {
+	ftv := fni.(ki.Ki).Embed(KiT_FileTreeView).(*FileTreeView)
+	fn := ftv.FileNode()
+	if fn != nil {
+		repo, _ := fn.Repo()
+		if repo == nil || fn.IsDir() {
+			act.SetActiveState((false))
+			return
+		}
+		act.SetActiveState((fn.Info.Vcs == vci.Conflicted))
+	}
+})
+
 // VcsGetRemoveLabelFunc gets the appropriate label for removing from version control
 var VcsLabelFunc = LabelFunc(func(fni interface{}, act *gi.Action) string {
 	ftv := fni.(ki.Ki).Embed(KiT_FileTreeView).(*FileTreeView)
@@ -2451,6 +2712,10 @@ var FileTreeViewProps = ki.Props{
 		{"OpenFileDefault", ki.Props{
 			"label": "Open (w/default app)",
 		}},
+		{"ViewFiles", ki.Props{
+			"label":    "View",
+			"updtfunc": FileTreeActiveViewerFunc,
+		}},
 		{"sep-act", ki.BlankProp{}},
 		{"DuplicateFiles", ki.Props{
 			"label":    "Duplicate",
@@ -2482,6 +2747,10 @@ var FileTreeViewProps = ki.Props{
 				{"Modification Time", ki.Props{}},
 			},
 		}},
+		{"FindInFiles", ki.Props{
+			"label": "Find In Files...",
+			"desc":  "find and replace text in the files of the tree -- in this folder if it is a folder",
+		}},
 		{"sep-new", ki.BlankProp{}},
 		{"NewFile", ki.Props{
 			"label":    "New File...",
@@ -2537,6 +2806,11 @@ var FileTreeViewProps = ki.Props{
 				{"Revision B", ki.Props{}},
 			},
 		}},
+		{"MergeVcs", ki.Props{
+			"desc":       "opens a three-way merge view for resolving the conflicts in this file, which marks the file as resolved in version control when the result is saved without conflicts.",
+			"updtfunc":   FileTreeActiveInVcsConflictedFunc,
+			"label-func": VcsLabelFunc,
+		}},
 		{"LogVcs", ki.Props{
 			"desc":       "shows the VCS log of commits for this file, optionally with a since date qualifier: If since is non-empty, it should be a date-like expression that the VCS will understand, such as 1/1/2020, yesterday, last year, etc (SVN only supports a max number of entries).  If allFiles is true, then the log will show revisions for all files, not just this one.",
 			"updtfunc":   FileTreeActiveInVcsFunc,
@@ -0,0 +1,79 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"archive/zip"
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/vfs"
+	"github.com/goki/pi/lex"
+)
+
+func TestFileTreeFS(t *testing.T) {
+	if gi.TheIconMgr == nil {
+		gi.TheIconMgr = &testIconMgr{}
+	}
+	var zb bytes.Buffer
+	zw := zip.NewWriter(&zb)
+	for nm, txt := range map[string]string{"a.xyz": "one\n", "sub/b.xyz": "two\n"} {
+		w, _ := zw.Create(nm)
+		w.Write([]byte(txt))
+	}
+	zw.Close()
+	arch, err := vfs.NewZipFS(bytes.NewReader(zb.Bytes()), int64(zb.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	upper := vfs.NewMemFS()
+	fsys := vfs.NewOverlayFS(upper, arch)
+	root := vfs.Clean("")
+
+	ft := &FileTree{}
+	ft.InitName(ft, "tree")
+	ft.Dirs.SetOpen("sub", true)
+	ft.OpenPathFS(fsys, root)
+	node := func(nm string) *FileNode {
+		fn, _ := ft.NodeByPath(filepath.Join(root, nm))
+		return fn
+	}
+	fa := node("a.xyz")
+	if fa == nil || node("sub") == nil || node("sub/b.xyz") == nil {
+		t.Fatalf("tree not read: %v", ft.Kids)
+	}
+	if fa.Info.Size != 4 || fa.FileSys() != fsys {
+		t.Errorf("file info: %v", fa.Info)
+	}
+
+	// buffers are opened and saved through the file system
+	if _, err := fa.OpenBuf(); err != nil {
+		t.Fatal(err)
+	}
+	buf := fa.Buf
+	if got := string(buf.LinesToBytesCopy()); got != "one\n" {
+		t.Errorf("opened: %q", got)
+	}
+	buf.InsertText(lex.Pos{}, []byte("more\n"), false)
+	if err := buf.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := vfs.ReadFile(upper, filepath.Join(root, "a.xyz")); string(b) != "more\none\n" {
+		t.Errorf("saved: %q", b)
+	}
+	if b, _ := vfs.ReadFile(arch, filepath.Join(root, "a.xyz")); string(b) != "one\n" {
+		t.Errorf("archive changed: %q", b)
+	}
+
+	fa.NewFile("c.xyz", false)
+	if node("c.xyz") == nil {
+		t.Errorf("new file not in tree")
+	}
+	if _, err := upper.Stat(filepath.Join(root, "c.xyz")); err != nil {
+		t.Errorf("new file not made: %v", err)
+	}
+}
@@ -6,7 +6,6 @@ package giv
 
 import (
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
@@ -15,6 +14,7 @@ import (
 	"unicode"
 
 	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/vfs"
 	"github.com/goki/gi/oswin"
 	"github.com/goki/gi/oswin/cursor"
 	"github.com/goki/gi/oswin/key"
@@ -38,6 +38,7 @@ import (
 type FileView struct {
 	gi.Frame
 	DirPath     string             `desc:"path to directory of files to display"`
+	FS          vfs.FS             `view:"-" json:"-" xml:"-" desc:"file system of the files -- nil for the OS -- the saved paths and favorites are only used for the OS"`
 	SelFile     string             `desc:"selected file"`
 	Ext         string             `desc:"target extension(s) (comma separated if multiple, including initial .), if any"`
 	FilterFunc  FileViewFilterFunc `view:"-" json:"-" xml:"-" desc:"optional styling function"`
@@ -431,6 +432,10 @@ func (fv *FileView) ExtField() *gi.TextField {
 
 // UpdatePath ensures that path is in abs form and ready to be used..
 func (fv *FileView) UpdatePath() {
+	if !vfs.IsOS(fv.FS) {
+		fv.DirPath = vfs.Clean(fv.DirPath)
+		return
+	}
 	if fv.DirPath == "" {
 		fv.DirPath, _ = os.Getwd()
 	}
@@ -463,32 +468,35 @@ func (fv *FileView) UpdateFiles() {
 
 	fv.UpdatePath()
 	pf := fv.PathField()
-	if len(gi.SavedPaths) == 0 {
-		gi.OpenPaths()
+	fsys := vfs.OrOS(fv.FS)
+	if vfs.IsOS(fsys) {
+		if len(gi.SavedPaths) == 0 {
+			gi.OpenPaths()
+		}
+		gi.SavedPaths.AddPath(fv.DirPath, gi.Prefs.Params.SavedPathsMax)
+		gi.SavePaths()
+		sp := []string(gi.SavedPaths)
+		pf.ItemsFromStringList(sp, true, 0)
 	}
-	gi.SavedPaths.AddPath(fv.DirPath, gi.Prefs.Params.SavedPathsMax)
-	gi.SavePaths()
-	sp := []string(gi.SavedPaths)
-	pf.ItemsFromStringList(sp, true, 0)
 	pf.SetText(fv.DirPath)
 	sf := fv.SelField()
 	sf.SetText(fv.SelFile)
 	oswin.TheApp.Cursor(owin).Push(cursor.Wait)
 	defer oswin.TheApp.Cursor(owin).Pop()
 
-	effpath, err := filepath.EvalSymlinks(fv.DirPath)
+	effpath, err := vfs.EvalSymlinks(fsys, fv.DirPath)
 	if err != nil {
 		log.Printf("gi.FileView Path: %v could not be opened -- error: %v\n", effpath, err)
 		return
 	}
-	_, err = os.Lstat(effpath)
+	_, err = fsys.Stat(effpath)
 	if err != nil {
 		log.Printf("gi.FileView Path: %v could not be opened -- error: %v\n", effpath, err)
 		return
 	}
 
 	fv.Files = make([]*FileInfo, 0, 1000)
-	filepath.Walk(effpath, func(path string, info os.FileInfo, err error) error {
+	vfs.Walk(fsys, effpath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			emsg := fmt.Sprintf("Path %q: Error: %v", effpath, err)
 			// if fv.Viewport != nil {
@@ -501,7 +509,7 @@ func (fv *FileView) UpdateFiles() {
 		if path == effpath { // proceed..
 			return nil
 		}
-		fi, ferr := NewFileInfo(path)
+		fi, ferr := NewFileInfoFS(fv.FS, path)
 		keep := ferr == nil
 		if fv.FilterFunc != nil {
 			keep = fv.FilterFunc(fv, fi)
@@ -596,7 +604,7 @@ func (fv *FileView) NewFolder() {
 		return
 	}
 	np := filepath.Join(dp, "NewFolder")
-	err := os.MkdirAll(np, 0775)
+	err := vfs.MkdirAll(fv.FS, np, 0775)
 	if err != nil {
 		emsg := fmt.Sprintf("NewFolder at: %q: Error: %v", fv.DirPath, err)
 		gi.PromptDialog(fv.Viewport, gi.DlgOpts{Title: "FileView Error", Prompt: emsg}, gi.AddOk, gi.NoCancel, nil, nil)
@@ -774,13 +782,10 @@ func (fv *FileView) FileComplete(data interface{}, text string, posLn, posCh int
 func (fv *FileView) PathComplete(data interface{}, path string, posLn, posCh int) (md complete.Matches) {
 	dir, seed := filepath.Split(path)
 	md.Seed = seed
-	d, err := os.Open(dir)
+	files, err := vfs.OrOS(fv.FS).ReadDir(dir)
 	if err != nil {
 		return md
 	}
-	defer d.Close()
-
-	files, err := ioutil.ReadDir(dir)
 	var dirs = []string{}
 	for _, f := range files {
 		if f.IsDir() && !strings.HasPrefix(f.Name(), ".") {
@@ -0,0 +1,355 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/fswatch"
+	"github.com/goki/gi/giv/textbuf"
+	"github.com/goki/gi/giv/vfs"
+	"github.com/goki/ki/ki"
+)
+
+/////////////////////////////////////////////////////////////////////////////
+//   File Watching
+
+var (
+	fileWatcher     *fswatch.Watcher
+	fileWatcherOnce sync.Once
+)
+
+// FileWatcher returns the watcher of the file system that is shared by all
+// the FileTrees and TextBufs that are watching for changes to their files
+// -- only one is used, as the number of watchers of a user is limited
+func FileWatcher() *fswatch.Watcher {
+	fileWatcherOnce.Do(func() {
+		fileWatcher = fswatch.NewWatcher()
+	})
+	return fileWatcher
+}
+
+// TextBufWatch causes Open to watch the file for changes made by other
+// programs -- see WatchFile
+var TextBufWatch = false
+
+// WatchFile starts watching the file of the buffer for changes made by
+// other programs, which are reloaded if the buffer has not been changed, and
+// otherwise prompted about -- see FileChanged.  If the file is renamed, the
+// buffer follows it.  Only files in the OS are watched.
+func (tb *TextBuf) WatchFile() error {
+	if tb.Filename == "" || !vfs.IsOS(tb.FS) {
+		return nil
+	}
+	dir, _ := filepath.Abs(filepath.Dir(string(tb.Filename)))
+	if tb.FileWatch != nil {
+		if tb.FileWatch.Dir == dir {
+			return nil
+		}
+		tb.UnwatchFile()
+	}
+	var win *gi.Window
+	if vp := tb.ViewportFromView(); vp != nil {
+		win = vp.Win
+	}
+	var wt *fswatch.Watch
+	wt, err := FileWatcher().Watch(dir, func(evs []fswatch.Event) {
+		gi.PostUIFunc(win, func() {
+			if tb.FileWatch == wt { // not stopped since
+				tb.filesChanged(evs)
+			}
+		})
+	})
+	if err != nil {
+		return err
+	}
+	tb.FileWatch = wt
+	return nil
+}
+
+// UnwatchFile stops watching the file of the buffer
+func (tb *TextBuf) UnwatchFile() {
+	if tb.FileWatch == nil {
+		return
+	}
+	tb.FileWatch.Stop()
+	tb.FileWatch = nil
+}
+
+// filesChanged is called for the events of the watch of the directory of
+// the file, on the event goroutine
+func (tb *TextBuf) filesChanged(evs []fswatch.Event) {
+	fnm, _ := filepath.Abs(string(tb.Filename))
+	for _, ev := range evs {
+		switch {
+		case ev.OldPath == fnm && ev.Path != fnm:
+			tb.FileRenamed(gi.FileName(ev.Path))
+			return
+		case ev.Path == fnm && ev.Op&fswatch.Remove == 0:
+			tb.FileChanged()
+		}
+	}
+}
+
+// FileChanged is called when the file of the buffer may have been changed by
+// another program: if its contents are not those last opened or saved, the
+// buffer is reverted if it has not been changed, and otherwise the user is
+// prompted about it (see FileModCheck).  Returns true if reverted.
+func (tb *TextBuf) FileChanged() bool {
+	if tb.Filename == "" {
+		return false
+	}
+	raw, err := vfs.ReadFile(tb.FileSys(), string(tb.Filename))
+	if err != nil { // e.g., removed -- the text is kept, to save again
+		return false
+	}
+	if textbuf.ContentHash(raw) == tb.FileHash { // e.g., saved by us
+		return false
+	}
+	if !tb.IsChanged() {
+		return tb.Revert()
+	}
+	tb.FileModCheck()
+	return false
+}
+
+// FileRenamed is called when the file of the buffer has been renamed by
+// another program, to continue with it under its new name
+func (tb *TextBuf) FileRenamed(filename gi.FileName) {
+	if filename == tb.Filename {
+		return
+	}
+	if tb.LSP != nil { // the server knows the file by name
+		tb.DeleteLSP()
+		defer tb.StartLSP()
+	}
+	tb.Filename = filename
+	tb.SetName(string(filename))
+	tb.Stat()
+	if tb.FileWatch != nil {
+		tb.WatchFile()
+	}
+}
+
+// Watch starts watching the open directories of the tree for changes made
+// by other programs, updating the nodes of the files that changed, with
+// the status of the files in version control, and the buffers of the files
+// that are open -- see FilesChanged.  Directories that are opened later are
+// watched too, until Unwatch.  Only trees in the OS are watched.
+func (ft *FileTree) Watch() {
+	if !vfs.IsOS(ft.FS) {
+		return
+	}
+	ft.WatchMu.Lock()
+	if ft.Watches == nil {
+		ft.Watches = make(map[string]*fswatch.Watch)
+	}
+	ft.WatchMu.Unlock()
+	ft.FuncDownMeFirst(0, ft, func(k ki.Ki, level int, d interface{}) bool {
+		sfn := k.Embed(KiT_FileNode).(*FileNode)
+		if sfn.IsIrregular() || !sfn.IsDir() {
+			return ki.Break
+		}
+		if !ft.IsDirOpen(sfn.FPath) {
+			return ki.Break
+		}
+		ft.WatchDir(sfn.FPath)
+		return ki.Continue
+	})
+}
+
+// Unwatch stops watching the directories of the tree
+func (ft *FileTree) Unwatch() {
+	ft.WatchMu.Lock()
+	defer ft.WatchMu.Unlock()
+	for _, wt := range ft.Watches {
+		wt.Stop()
+	}
+	ft.Watches = nil
+}
+
+// IsWatching returns true if the tree is watching its open directories --
+// see Watch
+func (ft *FileTree) IsWatching() bool {
+	ft.WatchMu.Lock()
+	defer ft.WatchMu.Unlock()
+	return ft.Watches != nil
+}
+
+// WatchDir starts watching given directory of the tree, if the tree is
+// watching -- called when a directory is opened
+func (ft *FileTree) WatchDir(fpath gi.FileName) {
+	ft.WatchMu.Lock()
+	defer ft.WatchMu.Unlock()
+	if ft.Watches == nil {
+		return
+	}
+	dir := string(fpath)
+	if _, has := ft.Watches[dir]; has {
+		return
+	}
+	wt, err := FileWatcher().Watch(dir, func(evs []fswatch.Event) {
+		gi.PostUIFunc(nil, func() {
+			if ft.IsWatching() {
+				ft.FilesChanged(evs)
+			}
+		})
+	})
+	if err != nil {
+		log.Printf("giv.FileTree: could not watch directory: %v err: %v\n", dir, err)
+		return
+	}
+	ft.Watches[dir] = wt
+}
+
+// UnwatchDir stops watching given directory of the tree, and the ones
+// under it -- called when a directory is closed or removed
+func (ft *FileTree) UnwatchDir(fpath gi.FileName) {
+	ft.WatchMu.Lock()
+	defer ft.WatchMu.Unlock()
+	dir := string(fpath)
+	for wdir, wt := range ft.Watches {
+		if wdir == dir || strings.HasPrefix(wdir, dir+string(filepath.Separator)) {
+			wt.Stop()
+			delete(ft.Watches, wdir)
+		}
+	}
+}
+
+// NodeByPath returns the node for the file at given absolute path, if it
+// is in the tree -- unlike FindFile, it does not open any directories
+func (ft *FileTree) NodeByPath(path string) (*FileNode, bool) {
+	rpath := ft.RelPath(gi.FileName(path))
+	if rpath == "." {
+		return &ft.FileNode, true
+	}
+	if rpath == ".." || strings.HasPrefix(rpath, ".."+string(filepath.Separator)) {
+		return nil, false
+	}
+	cfn := &ft.FileNode
+	for _, dr := range strings.Split(rpath, string(filepath.Separator)) {
+		sfni, err := cfn.ChildByNameTry(dr, 0)
+		if err != nil {
+			return nil, false
+		}
+		cfn = sfni.Embed(KiT_FileNode).(*FileNode)
+	}
+	return cfn, true
+}
+
+// fileTreeMovedBuf is a buffer of a file that has been renamed, to give to
+// the node for its new path
+type fileTreeMovedBuf struct {
+	buf  *TextBuf
+	path string
+}
+
+// FilesChanged updates the tree for given changes to its files, made by
+// other programs: the directories in which files were created, removed or
+// renamed are updated, keeping the nodes and state of the files that are
+// still there, renamed directories stay open, and open buffers of renamed
+// files move to the new nodes.  Open buffers of the files that changed are
+// reverted, or the user prompted if they have been changed -- see
+// TextBuf.FileChanged.  Called for the events of the watches of the
+// directories, on the event goroutine of the focused window (see
+// gi.PostUIFunc), as it updates the tree and prompts the user.
+func (ft *FileTree) FilesChanged(evs []fswatch.Event) {
+	var bufs []fileTreeMovedBuf
+	dirs := make(map[string]bool)
+	ft.WatchMu.Lock()
+	for _, ev := range evs {
+		if _, has := ft.Watches[ev.Path]; has { // the directory itself
+			dirs[ev.Path] = true
+		}
+		dirs[filepath.Dir(ev.Path)] = true
+		if ev.OldPath != "" {
+			dirs[filepath.Dir(ev.OldPath)] = true
+		}
+	}
+	ft.WatchMu.Unlock()
+	for _, ev := range evs {
+		if ev.Op&fswatch.Rename != 0 && ev.OldPath != "" {
+			bufs = append(bufs, ft.renamedPath(ev.OldPath, ev.Path)...)
+		}
+	}
+	paths := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		paths = append(paths, dir)
+	}
+	sort.Strings(paths) // parents first
+
+	updt := ft.UpdateStart()
+	ft.InWatchUpdate = true
+	repos := make(map[*FileNode]bool)
+	for _, dir := range paths {
+		if _, err := ft.FileSys().Stat(dir); err != nil {
+			ft.UnwatchDir(gi.FileName(dir)) // removed -- parent update removes node
+			continue
+		}
+		dn, ok := ft.NodeByPath(dir)
+		if !ok || !dn.IsDir() || !ft.IsDirOpen(dn.FPath) {
+			continue
+		}
+		if _, rnode := dn.Repo(); rnode != nil && !repos[rnode] {
+			rnode.UpdateRepoFiles()
+			repos[rnode] = true
+		}
+		dn.UpdateDir()
+	}
+	ft.InWatchUpdate = false
+	for _, mb := range bufs {
+		if nfn, ok := ft.NodeByPath(mb.path); ok && nfn.Buf == nil {
+			nfn.Buf = mb.buf
+			mb.buf.AddFileNode(nfn)
+		}
+		mb.buf.FileRenamed(gi.FileName(mb.path))
+	}
+	for _, ev := range evs {
+		if ev.Op&fswatch.Remove != 0 && ev.Op&(fswatch.Create|fswatch.Rename) == 0 {
+			continue
+		}
+		if fn, ok := ft.NodeByPath(ev.Path); ok && fn.Buf != nil {
+			if fn.Buf.FileChanged() {
+				fn.UpdateBufVcs()
+			}
+		}
+	}
+	ft.UpdateEnd(updt)
+}
+
+// renamedPath moves the state of the nodes at and under oldpath, which has
+// been renamed to path, to the new path: directories stay open, and the
+// open buffers are returned, to give to the new nodes
+func (ft *FileTree) renamedPath(oldpath, path string) []fileTreeMovedBuf {
+	orp, nrp := ft.RelPath(gi.FileName(oldpath)), ft.RelPath(gi.FileName(path))
+	ft.Dirs.Init()
+	for key, df := range ft.Dirs {
+		if key == orp || strings.HasPrefix(key, orp+string(filepath.Separator)) {
+			delete(ft.Dirs, key)
+			ft.Dirs[nrp+key[len(orp):]] = df
+		}
+	}
+	ft.UnwatchDir(gi.FileName(oldpath))
+	ofn, ok := ft.NodeByPath(oldpath)
+	if !ok {
+		return nil
+	}
+	var bufs []fileTreeMovedBuf
+	ofn.FuncDownMeFirst(0, ofn, func(k ki.Ki, level int, d interface{}) bool {
+		sfn := k.Embed(KiT_FileNode).(*FileNode)
+		if sfn.Buf != nil && strings.HasPrefix(string(sfn.FPath), oldpath) {
+			bufs = append(bufs, fileTreeMovedBuf{sfn.Buf, path + string(sfn.FPath)[len(oldpath):]})
+			sfn.Buf.TextBufSig.Disconnect(sfn.This())
+			sfn.Buf = nil
+		}
+		return ki.Continue
+	})
+	return bufs
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/pi/lex"
+)
+
+func TestFileTreeWatch(t *testing.T) {
+	if gi.TheIconMgr == nil {
+		gi.TheIconMgr = &testIconMgr{}
+	}
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(nm, txt string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, nm), []byte(txt), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.xyz", "one\n")
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	write("sub/b.xyz", "two\n")
+
+	ft := &FileTree{}
+	ft.InitName(ft, "tree")
+	ft.Dirs.SetOpen("sub", true)
+	ft.OpenPath(dir)
+	ft.Watch()
+	defer ft.Unwatch()
+	node := func(nm string) *FileNode {
+		fn, _ := ft.NodeByPath(filepath.Join(dir, nm))
+		return fn
+	}
+	sub := node("sub")
+	fa := node("a.xyz")
+	if sub == nil || node("sub/b.xyz") == nil || fa == nil {
+		t.Fatalf("tree not read: %v", ft.Kids)
+	}
+	if _, err := fa.OpenBuf(); err != nil {
+		t.Fatal(err)
+	}
+	buf := fa.Buf
+	text := func() string {
+		return strings.Join(buf.Strings(false), "\n")
+	}
+
+	// created and removed files
+	write("sub/c.xyz", "three\n")
+	waitFor(t, "created", func() bool { return node("sub/c.xyz") != nil })
+	os.Remove(filepath.Join(dir, "sub/b.xyz"))
+	waitFor(t, "removed", func() bool { return node("sub/b.xyz") == nil })
+	if node("sub") != sub {
+		t.Errorf("directory node was replaced")
+	}
+
+	// unchanged buffers are reverted
+	write("a.xyz", "one\nmore\n")
+	waitFor(t, "reverted", func() bool { return text() == "one\nmore" })
+
+	// buffers and open directories move with renames
+	os.Rename(filepath.Join(dir, "a.xyz"), filepath.Join(dir, "d.xyz"))
+	waitFor(t, "renamed", func() bool {
+		fd := node("d.xyz")
+		return node("a.xyz") == nil && fd != nil && fd.Buf == buf
+	})
+	if buf.Filename != gi.FileName(filepath.Join(dir, "d.xyz")) {
+		t.Errorf("buffer filename: %v", buf.Filename)
+	}
+	os.Rename(filepath.Join(dir, "sub"), filepath.Join(dir, "sub2"))
+	waitFor(t, "renamed dir", func() bool {
+		return node("sub2/c.xyz") != nil && ft.IsDirOpen(gi.FileName(filepath.Join(dir, "sub2")))
+	})
+	write("sub2/e.xyz", "four\n")
+	waitFor(t, "created in renamed dir", func() bool { return node("sub2/e.xyz") != nil })
+
+	// changed buffers are not reverted (the user is asked, unless they
+	// already said to ignore changes)
+	buf.SetFlag(int(TextBufFileModOk))
+	buf.InsertText(lex.Pos{}, []byte("x"), true)
+	write("d.xyz", "other\n")
+	write("sub2/f.xyz", "five\n")
+	waitFor(t, "created after change", func() bool { return node("sub2/f.xyz") != nil })
+	if text() != "xone\nmore" {
+		t.Errorf("changed buffer was reverted: %q", text())
+	}
+}
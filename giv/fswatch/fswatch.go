@@ -0,0 +1,336 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fswatch watches directories for changes to the files in them,
+// made by any program, using the native notification mechanism of the
+// operating system where there is one (inotify on Linux), and otherwise
+// polling the directories.  The changes are delivered in batches, after
+// they have stopped coming for a short time, so that bulk changes, e.g.,
+// from a version control checkout or a build, are handled together.
+package fswatch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Op is the kind of change made to a file, as bit flags that are combined
+// when there are several changes to the same file in a batch
+type Op int32
+
+const (
+	// Create is the creation of a file
+	Create Op = 1 << iota
+
+	// Write is a change to the contents of a file
+	Write
+
+	// Remove is the deletion of a file
+	Remove
+
+	// Rename is the moving of a file from OldPath to Path
+	Rename
+)
+
+var opNames = []string{"Create", "Write", "Remove", "Rename"}
+
+func (op Op) String() string {
+	var nms []string
+	for i, nm := range opNames {
+		if op&(1<<uint(i)) != 0 {
+			nms = append(nms, nm)
+		}
+	}
+	return strings.Join(nms, "|")
+}
+
+// Event is a change to a file -- as events are combined, and the file
+// may have changed again since, its current state should be obtained from
+// the file system.  An event whose Path is a watched directory itself means
+// that anything in the directory may have changed (Write), e.g., when
+// events were lost, or that the directory was removed (Remove).
+type Event struct {
+	Path    string `desc:"path of the file"`
+	Op      Op     `desc:"the change or changes made to the file"`
+	OldPath string `desc:"previous path of the file, for Rename -- renames that are not detected as such are a Remove of the old path and a Create of the new one"`
+}
+
+func (ev Event) String() string {
+	if ev.OldPath != "" {
+		return fmt.Sprintf("%v: %v -> %v", ev.Op, ev.OldPath, ev.Path)
+	}
+	return fmt.Sprintf("%v: %v", ev.Op, ev.Path)
+}
+
+// Debounce is how long there must be no changes for a batch of changes to
+// be delivered
+var Debounce = 100 * time.Millisecond
+
+// MaxDelay is the longest that changes wait to be delivered, when changes
+// keep coming
+var MaxDelay = time.Second
+
+// PollInterval is the interval between checks of directories that are
+// polled for changes
+var PollInterval = time.Second
+
+// backend is a mechanism for watching directories, which posts the
+// changes to its Watcher
+type backend interface {
+	add(dir string) error
+	remove(dir string)
+	close()
+}
+
+// Watcher watches directories for changes to their files, calling the
+// functions of the Watches of each directory with them.  The native
+// mechanism is used for a directory if it can be, otherwise it is polled
+// -- e.g., when the limit on the number of watched directories is reached.
+type Watcher struct {
+	mu      sync.Mutex
+	native  backend
+	poll    *poller
+	watches map[string][]*Watch
+	polled  map[string]bool
+	pend    []Event
+	first   time.Time
+	timer   *time.Timer
+	closed  bool
+	callMu  sync.Mutex // serializes calls of the Watch functions
+}
+
+// Watch is the watching of a directory, calling a function with the
+// changes to its files -- see Watcher.Watch
+type Watch struct {
+	Dir     string `desc:"the directory being watched"`
+	w       *Watcher
+	fun     func(evs []Event)
+	stopped bool
+}
+
+// ErrClosed is returned when watching with a closed Watcher
+var ErrClosed = errors.New("fswatch: watcher is closed")
+
+// NewWatcher returns a new watcher, using the native mechanism of the
+// operating system if there is one, and otherwise polling
+func NewWatcher() *Watcher {
+	w := &Watcher{watches: make(map[string][]*Watch), polled: make(map[string]bool)}
+	if nb, err := newNative(w); err == nil {
+		w.native = nb
+	}
+	return w
+}
+
+// NewPollWatcher returns a new watcher that only polls, e.g., for network
+// file systems that do not report changes made by other machines
+func NewPollWatcher() *Watcher {
+	return &Watcher{watches: make(map[string][]*Watch), polled: make(map[string]bool)}
+}
+
+// Watch starts watching given directory, calling fun with the changes to
+// the files in it (not those in subdirectories, which must be watched
+// themselves), until Stop is called on the returned Watch.  The functions
+// of all the watches of a Watcher are called in turn, from a goroutine of
+// the Watcher.
+func (w *Watcher) Watch(dir string, fun func(evs []Event)) (*Watch, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("fswatch: not a directory: %v", dir)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil, ErrClosed
+	}
+	if len(w.watches[dir]) == 0 {
+		if w.native == nil || w.native.add(dir) != nil {
+			if w.poll == nil {
+				w.poll = newPoller(w, PollInterval)
+			}
+			if err := w.poll.add(dir); err != nil {
+				return nil, err
+			}
+			w.polled[dir] = true
+		}
+	}
+	wt := &Watch{Dir: dir, w: w, fun: fun}
+	w.watches[dir] = append(w.watches[dir], wt)
+	return wt, nil
+}
+
+// Stop stops the watch -- its function is not called after Stop returns,
+// unless Stop is called from it
+func (wt *Watch) Stop() {
+	w := wt.w
+	w.mu.Lock()
+	wt.stopped = true
+	wts := w.watches[wt.Dir]
+	for i, owt := range wts {
+		if owt == wt {
+			wts = append(wts[:i:i], wts[i+1:]...)
+			break
+		}
+	}
+	if len(wts) > 0 {
+		w.watches[wt.Dir] = wts
+	} else {
+		delete(w.watches, wt.Dir)
+		if w.polled[wt.Dir] {
+			delete(w.polled, wt.Dir)
+			w.poll.remove(wt.Dir)
+		} else if w.native != nil && !w.closed {
+			w.native.remove(wt.Dir)
+		}
+	}
+	w.mu.Unlock()
+}
+
+// Dirs returns the directories being watched, sorted
+func (w *Watcher) Dirs() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	dirs := make([]string, 0, len(w.watches))
+	for dir := range w.watches {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// IsPolled returns true if given directory is being polled, rather than
+// watched by the native mechanism
+func (w *Watcher) IsPolled(dir string) bool {
+	dir, _ = filepath.Abs(dir)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.polled[dir]
+}
+
+// Close stops all watching
+func (w *Watcher) Close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	for _, wts := range w.watches {
+		for _, wt := range wts {
+			wt.stopped = true
+		}
+	}
+	w.watches = make(map[string][]*Watch)
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.pend = nil
+	native, poll := w.native, w.poll
+	w.mu.Unlock()
+	if native != nil {
+		native.close()
+	}
+	if poll != nil {
+		poll.close()
+	}
+}
+
+// post adds given changes to those to be delivered, which is done after
+// Debounce with no further changes, or MaxDelay
+func (w *Watcher) post(evs ...Event) {
+	if len(evs) == 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	now := time.Now()
+	if len(w.pend) == 0 {
+		w.first = now
+	}
+	w.pend = append(w.pend, evs...)
+	delay := Debounce
+	if rem := MaxDelay - now.Sub(w.first); rem < delay {
+		delay = rem
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	if w.timer == nil {
+		w.timer = time.AfterFunc(delay, w.deliver)
+	} else {
+		w.timer.Reset(delay)
+	}
+}
+
+// deliver calls the functions of the watches with the pending changes to
+// the files in their directories
+func (w *Watcher) deliver() {
+	w.callMu.Lock()
+	defer w.callMu.Unlock()
+	w.mu.Lock()
+	evs := Combine(w.pend)
+	w.pend = nil
+	type call struct {
+		wt  *Watch
+		evs []Event
+	}
+	var calls []call
+	for dir, wts := range w.watches {
+		var devs []Event
+		for _, ev := range evs {
+			if ev.Path == dir || filepath.Dir(ev.Path) == dir || (ev.OldPath != "" && filepath.Dir(ev.OldPath) == dir) {
+				devs = append(devs, ev)
+			}
+		}
+		if len(devs) == 0 {
+			continue
+		}
+		for _, wt := range wts {
+			calls = append(calls, call{wt, devs})
+		}
+	}
+	w.mu.Unlock()
+	for _, c := range calls {
+		w.mu.Lock()
+		stopped := c.wt.stopped
+		w.mu.Unlock()
+		if !stopped {
+			c.wt.fun(c.evs)
+		}
+	}
+}
+
+// Combine combines the events for the same file into one, in the order of
+// their first event
+func Combine(evs []Event) []Event {
+	var cevs []Event
+	idx := make(map[string]int, len(evs))
+	for _, ev := range evs {
+		if i, has := idx[ev.Path]; has {
+			cevs[i].Op |= ev.Op
+			if ev.OldPath != "" {
+				cevs[i].OldPath = ev.OldPath
+			}
+			continue
+		}
+		idx[ev.Path] = len(cevs)
+		cevs = append(cevs, ev)
+	}
+	return cevs
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fswatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testWatcher(t *testing.T, w *Watcher) {
+	defer w.Close()
+	dir, err := ioutil.TempDir("", "fswatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var mu sync.Mutex
+	var got []Event
+	wt, err := w.Watch(dir, func(evs []Event) {
+		mu.Lock()
+		got = append(got, evs...)
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := func(what string, exp ...Event) {
+		t.Helper()
+		tm := time.Now().Add(5 * time.Second)
+		for {
+			mu.Lock()
+			evs := Combine(got)
+			mu.Unlock()
+			ok := len(evs) == len(exp)
+			for i := 0; ok && i < len(evs); i++ {
+				ok = evs[i] == exp[i]
+			}
+			if ok {
+				break
+			}
+			if time.Now().After(tm) {
+				t.Fatalf("%v: got: %v, expected: %v", what, evs, exp)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		mu.Lock()
+		got = nil
+		mu.Unlock()
+	}
+
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	ioutil.WriteFile(a, []byte("a"), 0644)
+	if w.IsPolled(dir) {
+		expect("create", Event{Path: a, Op: Create})
+		time.Sleep(20 * time.Millisecond) // past mod time resolution
+	} else {
+		expect("create", Event{Path: a, Op: Create | Write})
+	}
+	ioutil.WriteFile(a, []byte("aa"), 0644)
+	expect("write", Event{Path: a, Op: Write})
+	os.Rename(a, b)
+	if w.IsPolled(dir) {
+		expect("rename", Event{Path: a, Op: Remove}, Event{Path: b, Op: Create})
+	} else {
+		expect("rename", Event{Path: b, Op: Rename, OldPath: a})
+	}
+	os.Remove(b)
+	expect("remove", Event{Path: b, Op: Remove})
+
+	wt.Stop()
+	if len(w.Dirs()) != 0 {
+		t.Errorf("dirs after stop: %v", w.Dirs())
+	}
+	ioutil.WriteFile(a, []byte("a"), 0644)
+	time.Sleep(3 * Debounce)
+	mu.Lock()
+	if len(got) != 0 {
+		t.Errorf("events after stop: %v", got)
+	}
+	mu.Unlock()
+}
+
+func TestWatcher(t *testing.T) {
+	testWatcher(t, NewWatcher())
+}
+
+func TestPollWatcher(t *testing.T) {
+	pi := PollInterval
+	PollInterval = 20 * time.Millisecond
+	defer func() { PollInterval = pi }()
+	testWatcher(t, NewPollWatcher())
+}
+
+func TestCombine(t *testing.T) {
+	evs := Combine([]Event{{Path: "a", Op: Create}, {Path: "b", Op: Remove}, {Path: "a", Op: Write}})
+	if len(evs) != 2 || evs[0] != (Event{Path: "a", Op: Create | Write}) || evs[1] != (Event{Path: "b", Op: Remove}) {
+		t.Errorf("combined: %v", evs)
+	}
+	if s := (Create | Write).String(); s != "Create|Write" {
+		t.Errorf("op string: %v", s)
+	}
+}
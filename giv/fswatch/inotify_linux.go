@@ -0,0 +1,154 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux
+
+package fswatch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyMask is the changes that directories are watched for
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY |
+	syscall.IN_CLOSE_WRITE | syscall.IN_ATTRIB | syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_ONLYDIR
+
+// inotify is the backend using the inotify mechanism of Linux
+type inotify struct {
+	w    *Watcher
+	fd   int
+	file *os.File
+	mu   sync.Mutex
+	wds  map[string]int
+	dirs map[int]string
+}
+
+func newNative(w *Watcher) (backend, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	in := &inotify{w: w, fd: fd, wds: make(map[string]int), dirs: make(map[int]string)}
+	// reading through os.File uses the poller of the runtime, so that close
+	// ends the reading
+	in.file = os.NewFile(uintptr(fd), "inotify")
+	go in.read()
+	return in, nil
+}
+
+func (in *inotify) add(dir string) error {
+	wd, err := syscall.InotifyAddWatch(in.fd, dir, inotifyMask)
+	if err != nil {
+		return err // e.g., ENOSPC when max_user_watches is reached
+	}
+	in.mu.Lock()
+	in.wds[dir] = wd
+	in.dirs[wd] = dir
+	in.mu.Unlock()
+	return nil
+}
+
+func (in *inotify) remove(dir string) {
+	in.mu.Lock()
+	wd, has := in.wds[dir]
+	if has {
+		delete(in.wds, dir)
+		if in.dirs[wd] == dir {
+			delete(in.dirs, wd)
+		}
+		// a directory that was renamed has the same watch under its new path
+		for _, owd := range in.wds {
+			if owd == wd {
+				has = false
+				break
+			}
+		}
+	}
+	in.mu.Unlock()
+	if has {
+		syscall.InotifyRmWatch(in.fd, uint32(wd))
+	}
+}
+
+func (in *inotify) close() {
+	in.file.Close()
+}
+
+// read reads the events until the backend is closed
+func (in *inotify) read() {
+	var buf [syscall.SizeofInotifyEvent * 512]byte
+	for {
+		n, err := in.file.Read(buf[:])
+		if err != nil {
+			return
+		}
+		in.w.post(in.parse(buf[:n])...)
+	}
+}
+
+// parse returns the changes for given inotify events
+func (in *inotify) parse(b []byte) []Event {
+	type move struct {
+		path string
+		idx  int
+	}
+	var evs []Event
+	moves := make(map[uint32]move)
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	for off := 0; off+syscall.SizeofInotifyEvent <= len(b); {
+		raw := (*syscall.InotifyEvent)(unsafe.Pointer(&b[off]))
+		nb := b[off+syscall.SizeofInotifyEvent : off+syscall.SizeofInotifyEvent+int(raw.Len)]
+		off += syscall.SizeofInotifyEvent + int(raw.Len)
+		mask := raw.Mask
+		if mask&syscall.IN_Q_OVERFLOW != 0 {
+			// events were lost -- everything may have changed
+			for dir := range in.wds {
+				evs = append(evs, Event{Path: dir, Op: Write})
+			}
+			continue
+		}
+		dir, has := in.dirs[int(raw.Wd)]
+		if !has {
+			continue
+		}
+		if mask&syscall.IN_IGNORED != 0 { // watch was removed, and wd can be reused
+			delete(in.dirs, int(raw.Wd))
+			if in.wds[dir] == int(raw.Wd) {
+				delete(in.wds, dir)
+			}
+			continue
+		}
+		if mask&syscall.IN_DELETE_SELF != 0 {
+			evs = append(evs, Event{Path: dir, Op: Remove})
+			continue
+		}
+		path := filepath.Join(dir, strings.TrimRight(string(nb), "\x00"))
+		switch {
+		case mask&syscall.IN_CREATE != 0:
+			evs = append(evs, Event{Path: path, Op: Create})
+		case mask&syscall.IN_DELETE != 0:
+			evs = append(evs, Event{Path: path, Op: Remove})
+		case mask&syscall.IN_MOVED_FROM != 0:
+			moves[raw.Cookie] = move{path, len(evs)}
+			evs = append(evs, Event{Path: path, Op: Remove})
+		case mask&syscall.IN_MOVED_TO != 0:
+			if mv, has := moves[raw.Cookie]; has {
+				evs[mv.idx] = Event{Path: path, Op: Rename, OldPath: mv.path}
+				delete(moves, raw.Cookie)
+			} else {
+				evs = append(evs, Event{Path: path, Op: Create})
+			}
+		case mask&(syscall.IN_MODIFY|syscall.IN_CLOSE_WRITE|syscall.IN_ATTRIB) != 0:
+			evs = append(evs, Event{Path: path, Op: Write})
+		}
+	}
+	return evs
+}
@@ -0,0 +1,15 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package fswatch
+
+import "errors"
+
+// newNative returns an error, as there is no native backend for this
+// operating system yet -- directories are polled
+func newNative(w *Watcher) (backend, error) {
+	return nil, errors.New("fswatch: no native backend")
+}
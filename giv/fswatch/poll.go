@@ -0,0 +1,132 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fswatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// fileStat is the state of a file that is compared to detect changes
+type fileStat struct {
+	mod  time.Time
+	size int64
+	dir  bool
+}
+
+// poller is a backend that checks directories for changes at an interval
+type poller struct {
+	w    *Watcher
+	mu   sync.Mutex
+	dirs map[string]map[string]fileStat
+	stop chan struct{}
+}
+
+func newPoller(w *Watcher, interval time.Duration) *poller {
+	p := &poller{w: w, dirs: make(map[string]map[string]fileStat), stop: make(chan struct{})}
+	go p.run(interval)
+	return p
+}
+
+// scanDir returns the state of the files in given directory
+func scanDir(dir string) (map[string]fileStat, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	sts := make(map[string]fileStat, len(fis))
+	for _, fi := range fis {
+		sts[fi.Name()] = fileStat{mod: fi.ModTime(), size: fi.Size(), dir: fi.IsDir()}
+	}
+	return sts, nil
+}
+
+func (p *poller) add(dir string) error {
+	sts, err := scanDir(dir)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.dirs[dir] = sts
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *poller) remove(dir string) {
+	p.mu.Lock()
+	delete(p.dirs, dir)
+	p.mu.Unlock()
+}
+
+func (p *poller) close() {
+	close(p.stop)
+}
+
+func (p *poller) run(interval time.Duration) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-tick.C:
+			p.check()
+		}
+	}
+}
+
+// check checks all the directories for changes since the last check
+func (p *poller) check() {
+	p.mu.Lock()
+	dirs := make([]string, 0, len(p.dirs))
+	for dir := range p.dirs {
+		dirs = append(dirs, dir)
+	}
+	p.mu.Unlock()
+	var evs []Event
+	for _, dir := range dirs {
+		sts, err := scanDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			continue
+		}
+		p.mu.Lock()
+		old, has := p.dirs[dir]
+		if has {
+			p.dirs[dir] = sts
+		}
+		p.mu.Unlock()
+		if has {
+			evs = append(evs, diffDir(dir, old, sts)...)
+		}
+	}
+	p.w.post(evs...)
+}
+
+// diffDir returns the changes between given states of the files in dir
+func diffDir(dir string, old, cur map[string]fileStat) []Event {
+	var evs []Event
+	for nm, st := range cur {
+		ost, has := old[nm]
+		switch {
+		case !has:
+			evs = append(evs, Event{Path: filepath.Join(dir, nm), Op: Create})
+		case !st.dir && (st.size != ost.size || !st.mod.Equal(ost.mod)):
+			evs = append(evs, Event{Path: filepath.Join(dir, nm), Op: Write})
+		}
+	}
+	for nm := range old {
+		if _, has := cur[nm]; !has {
+			evs = append(evs, Event{Path: filepath.Join(dir, nm), Op: Remove})
+		}
+	}
+	sort.Slice(evs, func(i, j int) bool {
+		return evs[i].Path < evs[j].Path
+	})
+	return evs
+}
@@ -36,6 +36,7 @@ type HiMarkup struct {
 	PiLang    pi.Lang        `desc:"if supported, this is the pi Lang support for parsing"`
 	HiStyle   *histyle.Style `desc:"current highlighting style"`
 	Off       bool           `desc:"external toggle to turn off automatic highlighting"`
+	LinesOnly bool           `desc:"if true, each line is highlighted on its own using chroma, without using Pi, which needs to parse the entire text -- for very large texts"`
 	lastLang  string
 	lastStyle gi.HiStyleName
 	lexer     chroma.Lexer
@@ -58,7 +59,9 @@ func (hm *HiMarkup) Init(info *FileInfo, pist *pi.FileStates) {
 	hm.Info = info
 	hm.PiState = pist
 
-	if hm.Info.Sup != filecat.NoSupport {
+	if hm.LinesOnly {
+		hm.PiLang = nil
+	} else if hm.Info.Sup != filecat.NoSupport {
 		if lp, err := pi.LangSupport.Props(hm.Info.Sup); err == nil {
 			if lp.Lang != nil {
 				hm.lexer = nil
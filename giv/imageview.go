@@ -0,0 +1,807 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/vfs"
+	"github.com/goki/gi/oswin"
+	"github.com/goki/gi/oswin/key"
+	"github.com/goki/gi/oswin/mouse"
+	"github.com/goki/gi/units"
+	"github.com/goki/ki/ki"
+	"github.com/goki/ki/kit"
+	"github.com/goki/mat32"
+	"github.com/goki/pi/filecat"
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+	_ "golang.org/x/image/tiff"
+)
+
+// ImageViewDialog opens a dialog for viewing the given image file, in
+// given file system (nil for the OS)
+func ImageViewDialog(avp *gi.Viewport2D, fsys vfs.FS, filename gi.FileName, opts DlgOpts) *ImageView {
+	dlg := gi.NewStdDialog(opts.ToGiOpts(), opts.Ok, opts.Cancel)
+
+	frame := dlg.Frame()
+	_, prIdx := dlg.PromptWidget(frame)
+
+	iv := frame.InsertNewChild(KiT_ImageView, prIdx+1, "image-view").(*ImageView)
+	iv.Defaults()
+	iv.SetStretchMax()
+	iv.FS = fsys
+	iv.OpenImage(filename)
+
+	tb := frame.InsertNewChild(gi.KiT_ToolBar, prIdx+1, "toolbar").(*gi.ToolBar)
+	ToolBarView(iv, avp, tb)
+
+	dlg.UpdateEndNoSig(true) // going to be shown
+	dlg.Open(0, 0, avp, nil)
+	if iv.NFrames() > 1 {
+		iv.Play()
+	}
+	return iv
+}
+
+func init() {
+	vf := func(fn *FileNode, vp *gi.Viewport2D) {
+		ImageViewDialog(vp, fn.FileSys(), gi.FileName(fn.FPath), DlgOpts{Title: "Image: " + DirAndFile(string(fn.FPath))})
+	}
+	for _, sup := range []filecat.Supported{filecat.Gif, filecat.Jpeg, filecat.Png, filecat.Tiff, filecat.Bmp} {
+		FileViewers[sup] = vf
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//  ImageView
+
+// ImageView is a widget for inspecting images, extending gi.Bitmap with
+// zooming (to fit, 1:1 or any factor) using either smooth or nearest-neighbor
+// sampling, panning by dragging, a pixel grid at high zoom, a readout of the
+// coordinates and RGBA value of the pixel under the mouse, and playback of
+// animated GIF images.  The Bitmap Pixels always hold the current frame.
+type ImageView struct {
+	gi.Bitmap
+	Zoom      float32       `min:"0.01" step:"0.25" desc:"zoom factor -- 1 = one image pixel per display dot -- set automatically when Fit is on"`
+	Fit       bool          `desc:"zoom so that the entire image fits in the view -- turned off by any explicit zoom or pan"`
+	Smooth    bool          `desc:"use smooth (bilinear) sampling when zooming -- otherwise nearest-neighbor sampling is used, which shows individual pixels as blocks"`
+	PixelGrid bool          `desc:"draw a grid around each pixel when the zoom is at least GridZoom"`
+	GridZoom  float32       `min:"2" desc:"minimum zoom at which the pixel grid is drawn"`
+	Center    mat32.Vec2    `desc:"image position (in image pixels) shown at the center of the view -- changed by panning"`
+	FS        vfs.FS        `copy:"-" view:"-" json:"-" xml:"-" desc:"file system that OpenImage opens files in -- nil for the OS"`
+	Frames    []*image.RGBA `copy:"-" view:"-" json:"-" xml:"-" desc:"frames of an animated image -- nil for a still image"`
+	Delays    []int         `copy:"-" view:"-" json:"-" xml:"-" desc:"delay after each frame, in 100ths of a second (as in image/gif)"`
+	Frame     int           `copy:"-" json:"-" xml:"-" inactive:"+" desc:"index of the frame currently shown"`
+	Playing   bool          `copy:"-" json:"-" xml:"-" inactive:"+" desc:"true while an animation is playing"`
+	HoverPix  image.Point   `copy:"-" json:"-" xml:"-" view:"-" desc:"image coordinates of the pixel under the mouse -- outside the image if none"`
+	ViewPos   mat32.Vec2    `copy:"-" json:"-" xml:"-" view:"-" desc:"position of the image area within the viewport, as of last render"`
+	ViewSize  mat32.Vec2    `copy:"-" json:"-" xml:"-" view:"-" desc:"size of the image area, as of last render"`
+	PlayMu    sync.Mutex    `copy:"-" json:"-" xml:"-" view:"-" desc:"mutex protecting the animation timer"`
+	PlayTimer *time.Timer   `copy:"-" json:"-" xml:"-" view:"-" desc:"timer for advancing to the next animation frame"`
+	PlayGen   int           `copy:"-" json:"-" xml:"-" view:"-" desc:"generation of the PlayTimer, incremented by Play and Stop, so that a frame advance posted by a stopped timer is ignored"`
+}
+
+var KiT_ImageView = kit.Types.AddType(&ImageView{}, ImageViewProps)
+
+// AddNewImageView adds a new imageview to given parent node, with given name.
+func AddNewImageView(parent ki.Ki, name string) *ImageView {
+	iv := parent.AddNewChild(KiT_ImageView, name).(*ImageView)
+	iv.Defaults()
+	return iv
+}
+
+func (iv *ImageView) CopyFieldsFrom(frm interface{}) {
+	fr := frm.(*ImageView)
+	iv.Bitmap.CopyFieldsFrom(&fr.Bitmap)
+	iv.Zoom = fr.Zoom
+	iv.Fit = fr.Fit
+	iv.Smooth = fr.Smooth
+	iv.PixelGrid = fr.PixelGrid
+	iv.GridZoom = fr.GridZoom
+	iv.Center = fr.Center
+}
+
+func (iv *ImageView) Disconnect() {
+	iv.Stop()
+	iv.Bitmap.Disconnect()
+}
+
+// Defaults sets the default view parameters: zoom to fit, with smooth
+// sampling and the pixel grid shown at 8x zoom and above
+func (iv *ImageView) Defaults() {
+	iv.Zoom = 1
+	iv.Fit = true
+	iv.Smooth = true
+	iv.PixelGrid = true
+	iv.GridZoom = 8
+	iv.HoverPix = image.Point{-1, -1}
+}
+
+// OpenImage opens an image file in the FS for viewing -- all the frames
+// of an animated GIF are loaded, and can be shown with Play
+func (iv *ImageView) OpenImage(filename gi.FileName) error {
+	if strings.ToLower(filepath.Ext(string(filename))) == ".gif" {
+		err := iv.OpenGIF(filename)
+		if err != nil {
+			log.Printf("giv.ImageView.OpenImage -- could not open file: %v, err: %v\n", filename, err)
+		}
+		return err
+	}
+	var img image.Image
+	file, err := vfs.OrOS(iv.FS).Open(string(filename))
+	if err == nil {
+		defer file.Close()
+		img, _, err = image.Decode(file)
+	}
+	if err != nil {
+		log.Printf("giv.ImageView.OpenImage -- could not open file: %v, err: %v\n", filename, err)
+		return err
+	}
+	iv.SetImage(img)
+	iv.Filename = filename
+	return nil
+}
+
+// OpenGIF opens a GIF image file in the FS, including all of its
+// animation frames
+func (iv *ImageView) OpenGIF(filename gi.FileName) error {
+	file, err := vfs.OrOS(iv.FS).Open(string(filename))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	g, err := gif.DecodeAll(file)
+	if err != nil {
+		return err
+	}
+	iv.SetFrames(GIFFrames(g), g.Delay)
+	iv.Filename = filename
+	return nil
+}
+
+// SetImage sets a still image to view as the Bitmap Pixels (converted to
+// RGBA if needed), and resets the view to fit the image
+func (iv *ImageView) SetImage(img image.Image) {
+	iv.SetFrames([]*image.RGBA{ImageToRGBA(img)}, nil)
+}
+
+// SetFrames sets the frames of an animated image to view, with the delay
+// after each frame in 100ths of a second, and resets the view to fit the
+// image.  Any animation currently playing is stopped.
+func (iv *ImageView) SetFrames(frames []*image.RGBA, delays []int) {
+	iv.Stop()
+	updt := iv.UpdateStart()
+	if len(frames) > 1 {
+		iv.Frames = frames
+		iv.Delays = delays
+	} else {
+		iv.Frames = nil
+		iv.Delays = nil
+	}
+	iv.Frame = 0
+	if len(frames) > 0 {
+		iv.Pixels = frames[0]
+		iv.Size = frames[0].Bounds().Size()
+	} else {
+		iv.Pixels = nil
+		iv.Size = image.Point{}
+	}
+	iv.Fit = true
+	iv.Center = mat32.NewVec2FmPoint(iv.Size).MulScalar(0.5)
+	iv.SetFullReRender()
+	iv.UpdateEnd(updt)
+}
+
+// ImageToRGBA returns the given image as an *image.RGBA with bounds
+// starting at 0,0, copying it if it is not already in that form
+func ImageToRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == image.ZP {
+		return rgba
+	}
+	sz := img.Bounds().Size()
+	rgba := image.NewRGBA(image.Rectangle{Max: sz})
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba
+}
+
+// GIFFrames returns the fully composited frames of an animated GIF,
+// applying the disposal method of each frame to compute the next
+func GIFFrames(g *gif.GIF) []*image.RGBA {
+	bnds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bnds.Empty() && len(g.Image) > 0 {
+		bnds = image.Rectangle{Max: g.Image[0].Bounds().Max}
+	}
+	canvas := image.NewRGBA(bnds)
+	frames := make([]*image.RGBA, len(g.Image))
+	for i, fr := range g.Image {
+		disp := byte(0)
+		if i < len(g.Disposal) {
+			disp = g.Disposal[i]
+		}
+		var prev *image.RGBA
+		if disp == gif.DisposalPrevious {
+			prev = image.NewRGBA(bnds)
+			copy(prev.Pix, canvas.Pix)
+		}
+		draw.Draw(canvas, fr.Bounds(), fr, fr.Bounds().Min, draw.Over)
+		frames[i] = image.NewRGBA(bnds)
+		copy(frames[i].Pix, canvas.Pix)
+		switch disp {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, fr.Bounds(), image.Transparent, image.ZP, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = prev
+		}
+	}
+	return frames
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//  Zoom and Pan
+
+// ZoomFit zooms so that the entire image fits in the view
+func (iv *ImageView) ZoomFit() {
+	iv.Fit = true
+	iv.Center = mat32.NewVec2FmPoint(iv.Size).MulScalar(0.5)
+	iv.UpdateSig()
+}
+
+// Zoom1to1 zooms so that each image pixel is one display dot, keeping
+// the current center of the view
+func (iv *ImageView) Zoom1to1() {
+	iv.SetZoom(1)
+}
+
+// ZoomIn zooms in by a factor of 2
+func (iv *ImageView) ZoomIn() {
+	iv.SetZoom(iv.Zoom * 2)
+}
+
+// ZoomOut zooms out by a factor of 2
+func (iv *ImageView) ZoomOut() {
+	iv.SetZoom(iv.Zoom / 2)
+}
+
+// SetZoom sets the zoom factor, keeping the current center of the view
+func (iv *ImageView) SetZoom(zoom float32) {
+	iv.Fit = false
+	iv.Zoom = mat32.Clamp(zoom, 0.01, 512)
+	iv.UpdateSig()
+}
+
+// ZoomAt zooms by given factor, keeping the image position under given
+// viewport position fixed
+func (iv *ImageView) ZoomAt(pt mat32.Vec2, factor float32) {
+	ip := iv.PixelToImage(pt)
+	iv.Fit = false
+	iv.Zoom = mat32.Clamp(iv.Zoom*factor, 0.01, 512)
+	iv.Center = ip.Sub(pt.Sub(iv.ViewCenter()).DivScalar(iv.Zoom))
+	iv.UpdateSig()
+}
+
+// Pan moves the image by given amount in display dots
+func (iv *ImageView) Pan(dx, dy float32) {
+	iv.Fit = false
+	iv.Center = iv.Center.Sub(mat32.Vec2{dx, dy}.DivScalar(iv.Zoom))
+	iv.UpdateSig()
+}
+
+// FitZoom returns the zoom factor at which the entire image fits in the view
+func (iv *ImageView) FitZoom() float32 {
+	if iv.Size.X == 0 || iv.Size.Y == 0 || iv.ViewSize.X <= 0 || iv.ViewSize.Y <= 0 {
+		return 1
+	}
+	return mat32.Min(iv.ViewSize.X/float32(iv.Size.X), iv.ViewSize.Y/float32(iv.Size.Y))
+}
+
+// ViewCenter returns the center of the image area in viewport coordinates
+func (iv *ImageView) ViewCenter() mat32.Vec2 {
+	return iv.ViewPos.Add(iv.ViewSize.MulScalar(0.5))
+}
+
+// ImageToPixel converts an image position into viewport coordinates
+func (iv *ImageView) ImageToPixel(ip mat32.Vec2) mat32.Vec2 {
+	return ip.Sub(iv.Center).MulScalar(iv.Zoom).Add(iv.ViewCenter())
+}
+
+// PixelToImage converts viewport coordinates into an image position
+func (iv *ImageView) PixelToImage(pt mat32.Vec2) mat32.Vec2 {
+	return pt.Sub(iv.ViewCenter()).DivScalar(iv.Zoom).Add(iv.Center)
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//  Animation
+
+// NFrames returns the number of frames -- 1 for a still image
+func (iv *ImageView) NFrames() int {
+	if len(iv.Frames) == 0 {
+		if iv.Pixels == nil {
+			return 0
+		}
+		return 1
+	}
+	return len(iv.Frames)
+}
+
+// SetFrame shows the given animation frame
+func (iv *ImageView) SetFrame(frame int) {
+	if frame < 0 || frame >= len(iv.Frames) {
+		return
+	}
+	iv.Frame = frame
+	iv.Pixels = iv.Frames[frame]
+	iv.UpdateSig()
+}
+
+// NextFrame shows the next animation frame, wrapping around at the end
+func (iv *ImageView) NextFrame() {
+	if len(iv.Frames) == 0 {
+		return
+	}
+	iv.SetFrame((iv.Frame + 1) % len(iv.Frames))
+}
+
+// PrevFrame shows the previous animation frame, wrapping around at the start
+func (iv *ImageView) PrevFrame() {
+	if len(iv.Frames) == 0 {
+		return
+	}
+	iv.SetFrame((iv.Frame + len(iv.Frames) - 1) % len(iv.Frames))
+}
+
+// Play starts playing the animation frames, looping continuously
+func (iv *ImageView) Play() {
+	iv.PlayMu.Lock()
+	defer iv.PlayMu.Unlock()
+	if len(iv.Frames) < 2 || iv.Playing {
+		return
+	}
+	iv.Playing = true
+	iv.PlayGen++
+	iv.playLater()
+}
+
+// Stop stops playing the animation
+func (iv *ImageView) Stop() {
+	iv.PlayMu.Lock()
+	defer iv.PlayMu.Unlock()
+	iv.Playing = false
+	iv.PlayGen++
+	if iv.PlayTimer != nil {
+		iv.PlayTimer.Stop()
+		iv.PlayTimer = nil
+	}
+}
+
+// TogglePlay starts or stops playing the animation
+func (iv *ImageView) TogglePlay() {
+	if iv.Playing {
+		iv.Stop()
+	} else {
+		iv.Play()
+	}
+}
+
+// FrameDelay returns the time to show given frame for -- GIF delays of
+// less than 2 (i.e., 20 msec) are treated as 10, as web browsers do
+func (iv *ImageView) FrameDelay(frame int) time.Duration {
+	d := 10
+	if frame < len(iv.Delays) && iv.Delays[frame] >= 2 {
+		d = iv.Delays[frame]
+	}
+	return time.Duration(d) * 10 * time.Millisecond
+}
+
+// playLater starts the PlayTimer to advance to the next frame after the
+// delay of the current one, on the event goroutine of the window (see
+// gi.PostUIFunc) -- PlayMu must be locked
+func (iv *ImageView) playLater() {
+	gen := iv.PlayGen
+	iv.PlayTimer = gi.PostUIFuncAfter(iv.ParentWindow(), iv.FrameDelay(iv.Frame), func() {
+		iv.playNext(gen)
+	})
+}
+
+// playNext advances to the next frame, if still playing the animation of
+// given generation, and starts the timer for the one after that -- it runs
+// on the event goroutine of the window
+func (iv *ImageView) playNext(gen int) {
+	iv.PlayMu.Lock()
+	if !iv.Playing || iv.PlayGen != gen {
+		iv.PlayMu.Unlock()
+		return
+	}
+	if iv.This() == nil || iv.IsDestroyed() || iv.IsDeleted() {
+		iv.Playing = false
+		iv.PlayMu.Unlock()
+		return
+	}
+	iv.PlayMu.Unlock()
+	win := iv.ParentWindow()
+	if win == nil || (!win.IsClosed() && !win.IsResizing() && iv.IsVisible()) {
+		iv.NextFrame()
+	}
+	iv.PlayMu.Lock()
+	if iv.Playing && iv.PlayGen == gen {
+		iv.playLater()
+	}
+	iv.PlayMu.Unlock()
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//  Rendering
+
+// PixelInfo returns a readout of the zoom and, if the mouse is over the
+// image, the coordinates and (non-premultiplied) RGBA value of the pixel
+// under it
+func (iv *ImageView) PixelInfo() string {
+	zs := fmt.Sprintf("%d x %d  %g%%", iv.Size.X, iv.Size.Y, mat32.Round(iv.Zoom*1000)/10)
+	if len(iv.Frames) > 1 {
+		zs += fmt.Sprintf("  frame %d / %d", iv.Frame+1, len(iv.Frames))
+	}
+	if iv.Pixels == nil || !iv.HoverPix.In(iv.Pixels.Bounds()) {
+		return zs
+	}
+	c := color.NRGBAModel.Convert(iv.Pixels.RGBAAt(iv.HoverPix.X, iv.HoverPix.Y)).(color.NRGBA)
+	return fmt.Sprintf("%d, %d: RGBA(%d, %d, %d, %d) #%02x%02x%02x%02x  %s", iv.HoverPix.X, iv.HoverPix.Y, c.R, c.G, c.B, c.A, c.R, c.G, c.B, c.A, zs)
+}
+
+// RenderImage draws the image into the viewport at the current zoom and
+// center, clipped to the image area and the viewport bounding box
+func (iv *ImageView) RenderImage() {
+	if iv.Pixels == nil {
+		return
+	}
+	vr := image.Rectangle{Min: iv.ViewPos.ToPointCeil(), Max: iv.ViewPos.Add(iv.ViewSize).ToPointFloor()}
+	iv.BBoxMu.RLock()
+	vr = vr.Intersect(iv.VpBBox)
+	iv.BBoxMu.RUnlock()
+	if vr.Empty() {
+		return
+	}
+	dst := iv.Viewport.Pixels.SubImage(vr).(*image.RGBA)
+	org := iv.ImageToPixel(mat32.Vec2{})
+	s2d := f64.Aff3{float64(iv.Zoom), 0, float64(org.X), 0, float64(iv.Zoom), float64(org.Y)}
+	var tr draw.Transformer = draw.NearestNeighbor
+	if iv.Smooth && iv.Zoom < iv.GridZoom {
+		tr = draw.BiLinear
+	}
+	tr.Transform(dst, s2d, iv.Pixels, iv.Pixels.Bounds(), draw.Over, nil)
+}
+
+// RenderGrid draws a grid around each of the visible image pixels
+func (iv *ImageView) RenderGrid(rs *gi.RenderState, pc *gi.Paint, clr gi.Color) {
+	if iv.Pixels == nil {
+		return
+	}
+	mn := iv.PixelToImage(iv.ViewPos)
+	mx := iv.PixelToImage(iv.ViewPos.Add(iv.ViewSize))
+	x0 := int(mat32.Max(mat32.Floor(mn.X), 0))
+	y0 := int(mat32.Max(mat32.Floor(mn.Y), 0))
+	x1 := int(mat32.Min(mat32.Ceil(mx.X), float32(iv.Size.X)))
+	y1 := int(mat32.Min(mat32.Ceil(mx.Y), float32(iv.Size.Y)))
+	if x1 <= x0 || y1 <= y0 {
+		return
+	}
+	st := iv.ImageToPixel(mat32.Vec2{float32(x0), float32(y0)})
+	ed := iv.ImageToPixel(mat32.Vec2{float32(x1), float32(y1)})
+	pc.FillStyle.SetColor(nil)
+	pc.StrokeStyle.SetColor(clr)
+	pc.StrokeStyle.Width.Dots = 1
+	for x := x0; x <= x1; x++ {
+		sx := st.X + float32(x-x0)*iv.Zoom
+		pc.DrawLine(rs, sx, st.Y, sx, ed.Y)
+	}
+	for y := y0; y <= y1; y++ {
+		sy := st.Y + float32(y-y0)*iv.Zoom
+		pc.DrawLine(rs, st.X, sy, ed.X, sy)
+	}
+	pc.Stroke(rs)
+}
+
+// RenderInfo draws the PixelInfo readout at the bottom left of the image area
+func (iv *ImageView) RenderInfo(rs *gi.RenderState, pc *gi.Paint, st *gi.Style) {
+	var tr gi.TextRender
+	tr.SetString(iv.PixelInfo(), &st.Font, &st.UnContext, &st.Text, true, 0, 0)
+	pos := mat32.Vec2{iv.ViewPos.X, iv.ViewPos.Y + iv.ViewSize.Y - tr.Size.Y}
+	pc.FillBoxColor(rs, pos, tr.Size, st.Font.BgColor.Color)
+	tr.RenderTopPos(rs, pos)
+}
+
+func (iv *ImageView) Style2D() {
+	iv.SetCanFocusIfActive()
+	iv.Bitmap.Style2D()
+}
+
+func (iv *ImageView) Render2D() {
+	if iv.FullReRenderIfNeeded() {
+		return
+	}
+	if iv.PushBounds() {
+		iv.This().(gi.Node2D).ConnectEvents2D()
+		rs, pc, st := iv.RenderLock()
+		rs.BackupPaint()
+		iv.RenderStdBox(st)
+		spc := st.BoxSpace()
+		iv.ViewPos = iv.LayState.Alloc.Pos.AddScalar(spc)
+		iv.ViewSize = iv.LayState.Alloc.Size.AddScalar(-2 * spc)
+		if iv.Fit {
+			iv.Zoom = iv.FitZoom()
+		}
+		iv.RenderImage()
+		if iv.PixelGrid && iv.Zoom >= iv.GridZoom {
+			iv.RenderGrid(rs, pc, st.Border.Color)
+		}
+		iv.RenderInfo(rs, pc, st)
+		rs.RestorePaint()
+		iv.RenderUnlock(rs)
+		iv.PopBounds()
+	} else {
+		iv.DisconnectAllEvents(gi.RegPri)
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//  Events
+
+// PointToVp converts a window position into a position within the viewport
+func (iv *ImageView) PointToVp(pt image.Point) mat32.Vec2 {
+	iv.BBoxMu.RLock()
+	defer iv.BBoxMu.RUnlock()
+	return mat32.NewVec2FmPoint(pt.Sub(iv.WinBBox.Min).Add(iv.VpBBox.Min))
+}
+
+// SetHover sets the HoverPix to the image pixel under given window
+// position, updating the readout if it changed
+func (iv *ImageView) SetHover(pt image.Point) {
+	ip := iv.PixelToImage(iv.PointToVp(pt))
+	hp := image.Point{int(mat32.Floor(ip.X)), int(mat32.Floor(ip.Y))}
+	if hp == iv.HoverPix {
+		return
+	}
+	iv.HoverPix = hp
+	iv.UpdateSig()
+}
+
+// MouseEvent grabs focus on click, and toggles between fit and 1:1 zoom
+// on double-click
+func (iv *ImageView) MouseEvent() {
+	iv.ConnectEvent(oswin.MouseEvent, gi.RegPri, func(recv, send ki.Ki, sig int64, d interface{}) {
+		me := d.(*mouse.Event)
+		ivv := recv.Embed(KiT_ImageView).(*ImageView)
+		if me.Button != mouse.Left {
+			return
+		}
+		switch me.Action {
+		case mouse.Press:
+			me.SetProcessed()
+			ivv.GrabFocus()
+		case mouse.DoubleClick:
+			me.SetProcessed()
+			if ivv.Fit {
+				ivv.Zoom1to1()
+			} else {
+				ivv.ZoomFit()
+			}
+		}
+	})
+}
+
+// MouseMoveEvent updates the pixel readout
+func (iv *ImageView) MouseMoveEvent() {
+	iv.ConnectEvent(oswin.MouseMoveEvent, gi.RegPri, func(recv, send ki.Ki, sig int64, d interface{}) {
+		me := d.(*mouse.MoveEvent)
+		me.SetProcessed()
+		ivv := recv.Embed(KiT_ImageView).(*ImageView)
+		ivv.SetHover(me.Where)
+	})
+}
+
+// MouseFocusEvent clears the pixel readout when the mouse leaves
+func (iv *ImageView) MouseFocusEvent() {
+	iv.ConnectEvent(oswin.MouseFocusEvent, gi.RegPri, func(recv, send ki.Ki, sig int64, d interface{}) {
+		me := d.(*mouse.FocusEvent)
+		ivv := recv.Embed(KiT_ImageView).(*ImageView)
+		if me.Action == mouse.Exit {
+			ivv.HoverPix = image.Point{-1, -1}
+			ivv.UpdateSig()
+		}
+	})
+}
+
+// MouseDragEvent pans the image
+func (iv *ImageView) MouseDragEvent() {
+	iv.ConnectEvent(oswin.MouseDragEvent, gi.RegPri, func(recv, send ki.Ki, sig int64, d interface{}) {
+		me := d.(*mouse.DragEvent)
+		me.SetProcessed()
+		ivv := recv.Embed(KiT_ImageView).(*ImageView)
+		del := me.Delta()
+		ivv.Pan(float32(del.X), float32(del.Y))
+	})
+}
+
+// MouseScrollEvent zooms around the mouse position
+func (iv *ImageView) MouseScrollEvent() {
+	iv.ConnectEvent(oswin.MouseScrollEvent, gi.RegPri, func(recv, send ki.Ki, sig int64, d interface{}) {
+		me := d.(*mouse.ScrollEvent)
+		me.SetProcessed()
+		ivv := recv.Embed(KiT_ImageView).(*ImageView)
+		del := me.NonZeroDelta(false)
+		if del == 0 {
+			return
+		}
+		fact := float32(1.1)
+		if del < 0 {
+			fact = 1 / fact
+		}
+		ivv.ZoomAt(ivv.PointToVp(me.Where), fact)
+	})
+}
+
+// KeyInput handles keyboard zooming and panning, and animation control:
+// space toggles play, and the page keys step through frames
+func (iv *ImageView) KeyInput(kt *key.ChordEvent) {
+	if gi.KeyEventTrace {
+		fmt.Printf("ImageView KeyInput: %v\n", iv.PathUnique())
+	}
+	kf := gi.KeyFun(kt.Chord())
+	pstep := float32(32)
+	switch kf {
+	case gi.KeyFunZoomIn:
+		iv.ZoomIn()
+		kt.SetProcessed()
+	case gi.KeyFunZoomOut:
+		iv.ZoomOut()
+		kt.SetProcessed()
+	case gi.KeyFunMoveUp:
+		iv.Pan(0, pstep)
+		kt.SetProcessed()
+	case gi.KeyFunMoveDown:
+		iv.Pan(0, -pstep)
+		kt.SetProcessed()
+	case gi.KeyFunMoveLeft:
+		iv.Pan(pstep, 0)
+		kt.SetProcessed()
+	case gi.KeyFunMoveRight:
+		iv.Pan(-pstep, 0)
+		kt.SetProcessed()
+	case gi.KeyFunPageUp:
+		iv.Stop()
+		iv.PrevFrame()
+		kt.SetProcessed()
+	case gi.KeyFunPageDown:
+		iv.Stop()
+		iv.NextFrame()
+		kt.SetProcessed()
+	}
+	if kt.IsProcessed() {
+		return
+	}
+	switch kt.Chord() {
+	case "f":
+		iv.ZoomFit()
+		kt.SetProcessed()
+	case "1":
+		iv.Zoom1to1()
+		kt.SetProcessed()
+	case " ":
+		iv.TogglePlay()
+		kt.SetProcessed()
+	}
+}
+
+// KeyChordEvent processes key events
+func (iv *ImageView) KeyChordEvent() {
+	iv.ConnectEvent(oswin.KeyChordEvent, gi.RegPri, func(recv, send ki.Ki, sig int64, d interface{}) {
+		ivv := recv.Embed(KiT_ImageView).(*ImageView)
+		ivv.KeyInput(d.(*key.ChordEvent))
+	})
+}
+
+func (iv *ImageView) ConnectEvents2D() {
+	iv.MouseEvent()
+	iv.MouseMoveEvent()
+	iv.MouseFocusEvent()
+	iv.MouseDragEvent()
+	iv.MouseScrollEvent()
+	iv.KeyChordEvent()
+}
+
+var ImageViewProps = ki.Props{
+	"EnumType:Flag":    gi.KiT_NodeFlags,
+	"min-width":        units.NewEm(10),
+	"min-height":       units.NewEm(10),
+	"width":            units.NewEm(40),
+	"height":           units.NewEm(30),
+	"padding":          units.NewPx(2),
+	"margin":           units.NewPx(2),
+	"border-width":     units.NewPx(1),
+	"border-color":     &gi.Prefs.Colors.Border,
+	"border-style":     gi.BorderSolid,
+	"color":            &gi.Prefs.Colors.Font,
+	"background-color": &gi.Prefs.Colors.Background,
+	"ToolBar": ki.PropSlice{
+		{"OpenImage", ki.Props{
+			"desc": "Open an image file to view -- all the frames of an animated GIF are loaded",
+			"icon": "file-open",
+			"Args": ki.PropSlice{
+				{"File Name", ki.Props{
+					"default-field": "Filename",
+					"ext":           ".png,.jpg,.jpeg,.gif,.tif,.tiff,.bmp",
+				}},
+			},
+		}},
+		{"sep-zoom", ki.BlankProp{}},
+		{"ZoomFit", ki.Props{
+			"label": "Fit",
+			"desc":  "zoom so the entire image fits in the view",
+		}},
+		{"Zoom1to1", ki.Props{
+			"label": "1:1",
+			"desc":  "zoom to one image pixel per display dot",
+		}},
+		{"ZoomIn", ki.Props{
+			"icon": "zoom-in",
+			"desc": "zoom in by a factor of 2",
+		}},
+		{"ZoomOut", ki.Props{
+			"icon": "zoom-out",
+			"desc": "zoom out by a factor of 2",
+		}},
+		{"SetZoom", ki.Props{
+			"label": "Zoom...",
+			"desc":  "set the zoom factor -- 1 = one image pixel per display dot",
+			"Args": ki.PropSlice{
+				{"Zoom", ki.Props{
+					"default-field": "Zoom",
+				}},
+			},
+		}},
+		{"sep-play", ki.BlankProp{}},
+		{"Play", ki.Props{
+			"icon": "play",
+			"desc": "play the frames of an animated image",
+			"updtfunc": ActionUpdateFunc(func(ivi interface{}, act *gi.Action) {
+				iv := ivi.(*ImageView)
+				act.SetActiveStateUpdt(len(iv.Frames) > 1 && !iv.Playing)
+			}),
+		}},
+		{"Stop", ki.Props{
+			"icon": "stop",
+			"desc": "stop playing the animation",
+			"updtfunc": ActionUpdateFunc(func(ivi interface{}, act *gi.Action) {
+				iv := ivi.(*ImageView)
+				act.SetActiveStateUpdt(iv.Playing)
+			}),
+		}},
+		{"PrevFrame", ki.Props{
+			"icon": "step-bkwd",
+			"desc": "show the previous animation frame",
+			"updtfunc": ActionUpdateFunc(func(ivi interface{}, act *gi.Action) {
+				iv := ivi.(*ImageView)
+				act.SetActiveStateUpdt(len(iv.Frames) > 1)
+			}),
+		}},
+		{"NextFrame", ki.Props{
+			"icon": "step-fwd",
+			"desc": "show the next animation frame",
+			"updtfunc": ActionUpdateFunc(func(ivi interface{}, act *gi.Action) {
+				iv := ivi.(*ImageView)
+				act.SetActiveStateUpdt(len(iv.Frames) > 1)
+			}),
+		}},
+	},
+}
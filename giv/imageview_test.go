@@ -0,0 +1,200 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/vfs"
+	"github.com/goki/pi/filecat"
+)
+
+// gifTestPalette is the palette of the test GIF frames, with index 0 as
+// transparent
+var gifTestPalette = color.Palette{color.Transparent, color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}, color.RGBA{0, 255, 0, 255}, color.White}
+
+// gifTestFrame returns a GIF frame covering given rectangle, filled with
+// given palette index
+func gifTestFrame(r image.Rectangle, idx uint8) *image.Paletted {
+	fr := image.NewPaletted(r, gifTestPalette)
+	for i := range fr.Pix {
+		fr.Pix[i] = idx
+	}
+	return fr
+}
+
+// gifTestRow returns the palette indexes of the colors of the pixels of
+// given frame, -1 if not in the palette
+func gifTestRow(fr *image.RGBA) []int {
+	row := make([]int, fr.Bounds().Dx())
+	for x := range row {
+		row[x] = -1
+		for i, c := range gifTestPalette {
+			if color.RGBAModel.Convert(c) == fr.RGBAAt(x, 0) {
+				row[x] = i
+			}
+		}
+	}
+	return row
+}
+
+func TestGIFFrames(t *testing.T) {
+	g := &gif.GIF{
+		Image: []*image.Paletted{
+			gifTestFrame(image.Rect(0, 0, 4, 1), 1), // red background
+			gifTestFrame(image.Rect(1, 0, 2, 1), 2), // blue, then cleared
+			gifTestFrame(image.Rect(2, 0, 3, 1), 3), // green, then restored to previous
+			gifTestFrame(image.Rect(0, 0, 1, 1), 4), // white, kept
+			gifTestFrame(image.Rect(3, 0, 4, 1), 0), // transparent over red
+		},
+		Delay:    []int{0, 1, 5, 10, 50},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalBackground, gif.DisposalPrevious, gif.DisposalNone, 0},
+		Config:   image.Config{Width: 4, Height: 1},
+	}
+	frames := GIFFrames(g)
+	want := [][]int{
+		{1, 1, 1, 1},
+		{1, 2, 1, 1},
+		{1, 0, 3, 1},
+		{4, 0, 1, 1},
+		{4, 0, 1, 1},
+	}
+	if len(frames) != len(want) {
+		t.Fatalf("frames: %d", len(frames))
+	}
+	for i, fr := range frames {
+		if got := gifTestRow(fr); !intsEqual(got, want[i]) {
+			t.Errorf("frame %d: %v want %v", i, got, want[i])
+		}
+	}
+
+	iv := &ImageView{}
+	iv.InitName(iv, "image-view")
+	iv.Defaults()
+	iv.SetFrames(frames, g.Delay)
+	if iv.NFrames() != 5 || iv.Size != image.Pt(4, 1) {
+		t.Errorf("frames set: %d %v", iv.NFrames(), iv.Size)
+	}
+	if d := iv.FrameDelay(1); d != 100*time.Millisecond {
+		t.Errorf("short delay: %v", d)
+	}
+	if d := iv.FrameDelay(2); d != 50*time.Millisecond {
+		t.Errorf("delay: %v", d)
+	}
+	iv.PrevFrame()
+	if iv.Frame != 4 || iv.Pixels != frames[4] {
+		t.Errorf("prev frame: %d", iv.Frame)
+	}
+	iv.SetImage(frames[0])
+	if iv.NFrames() != 1 || iv.Frames != nil {
+		t.Errorf("still image: %d", iv.NFrames())
+	}
+}
+
+// intsEqual returns true if the slices have the same values
+func intsEqual(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestImageViewOpenFS(t *testing.T) {
+	var pb, gb bytes.Buffer
+	png.Encode(&pb, gifTestFrame(image.Rect(0, 0, 3, 1), 2))
+	gif.EncodeAll(&gb, &gif.GIF{
+		Image: []*image.Paletted{gifTestFrame(image.Rect(0, 0, 2, 1), 1), gifTestFrame(image.Rect(0, 0, 2, 1), 3)},
+		Delay: []int{10, 10},
+	})
+	fsys := newTestDirFS(t, map[string]string{"a.png": pb.String(), "b.gif": gb.String()})
+	iv := &ImageView{}
+	iv.InitName(iv, "image-view")
+	iv.Defaults()
+	iv.FS = fsys
+	if err := iv.OpenImage("/root/a.png"); err != nil {
+		t.Fatal(err)
+	}
+	if got := gifTestRow(iv.Pixels); !intsEqual(got, []int{2, 2, 2}) {
+		t.Errorf("png: %v", got)
+	}
+	if err := iv.OpenImage("/root/b.gif"); err != nil {
+		t.Fatal(err)
+	}
+	if iv.NFrames() != 2 || !intsEqual(gifTestRow(iv.Frames[1]), []int{3, 3}) {
+		t.Errorf("gif: %d frames", iv.NFrames())
+	}
+	if err := iv.OpenImage("/root/c.png"); err == nil {
+		t.Errorf("opened a file that is not in the file system")
+	}
+}
+
+func TestImageViewPlay(t *testing.T) {
+	frames := make([]*image.RGBA, 3)
+	for i := range frames {
+		frames[i] = image.NewRGBA(image.Rect(0, 0, 2, 2))
+	}
+	iv := &ImageView{}
+	iv.InitName(iv, "image-view")
+	iv.Defaults()
+	iv.SetFrames(frames, []int{2, 2, 2})
+	iv.Play()
+	waitFor(t, "frames to advance", func() bool { return iv.Frame == 2 })
+	iv.Stop()
+	iv.Play()
+	iv.Stop() // timers of both plays stopped, or their frames ignored
+	fr := iv.Frame
+	time.Sleep(60 * time.Millisecond)
+	gi.RunPendingUIFuncs()
+	if iv.Frame != fr || iv.Playing {
+		t.Errorf("advanced after stop: %d %d", iv.Frame, fr)
+	}
+}
+
+func TestFileViewers(t *testing.T) {
+	if gi.TheIconMgr == nil {
+		gi.TheIconMgr = &testIconMgr{}
+	}
+	fsys := newTestDirFS(t, map[string]string{"a.png": "png", "b.gif": "gif", "c.txt": "text", "d/e.txt": "text"})
+	ft := &FileTree{}
+	ft.InitName(ft, "tree")
+	ft.OpenPathFS(fsys, "/root")
+	var viewed []string
+	for _, sup := range []filecat.Supported{filecat.Png, filecat.Gif} {
+		if FileViewers[sup] == nil {
+			t.Fatalf("no viewer for %v", sup)
+		}
+		defer func(sup filecat.Supported, vf FileViewerFunc) { FileViewers[sup] = vf }(sup, FileViewers[sup])
+		FileViewers[sup] = func(fn *FileNode, vp *gi.Viewport2D) {
+			viewed = append(viewed, fn.Nm)
+		}
+	}
+	for _, tt := range []struct {
+		path string
+		has  bool
+	}{{"a.png", true}, {"b.gif", true}, {"c.txt", false}, {"d", false}} {
+		fn, ok := ft.NodeByPath(vfs.Clean("/root/" + tt.path))
+		if !ok {
+			t.Fatalf("no node for %v", tt.path)
+		}
+		if fn.HasViewer() != tt.has || fn.ViewFile(nil) != tt.has {
+			t.Errorf("viewer for %v: %v", tt.path, fn.HasViewer())
+		}
+	}
+	if len(viewed) != 2 || viewed[0] != "a.png" || viewed[1] != "b.gif" {
+		t.Errorf("viewed: %v", viewed)
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package lsp is a client for the Language Server Protocol, which gives
+// editors completion, diagnostics, navigation and refactoring for a
+// language from a separate language server process, communicating over
+// its stdin and stdout.
+package lsp
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// Client is a client for one language server, for the documents in one
+// workspace.  Documents are opened with DidOpen and then kept in sync with
+// DidChange, and the requests take a document URI and Position.
+type Client struct {
+	Root  string             `desc:"workspace root directory"`
+	Caps  ServerCapabilities `desc:"capabilities of the server"`
+	Conn  *Conn              `desc:"connection to the server"`
+	Cmd   *exec.Cmd          `desc:"server process, if started by Start"`
+	mu    sync.Mutex
+	diags map[string]func(diags []Diagnostic)
+}
+
+// NewClient returns a new client communicating with a server over given
+// stream, for given workspace root directory -- Initialize must be
+// called before any other requests.
+func NewClient(rwc io.ReadWriteCloser, root string) *Client {
+	cl := &Client{Root: root}
+	cl.diags = make(map[string]func(diags []Diagnostic))
+	cl.Conn = NewConn(rwc, cl.handle)
+	return cl
+}
+
+// Start starts given language server command for given workspace root
+// directory, and returns an initialized client for it
+func Start(root string, command string, args ...string) (*Client, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Dir = root
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	cl := NewClient(&procStream{ReadCloser: out, WriteCloser: in}, root)
+	cl.Cmd = cmd
+	if err := cl.Initialize(); err != nil {
+		cl.Conn.Close()
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+	return cl, nil
+}
+
+// procStream combines the stdout and stdin pipes of a server process
+type procStream struct {
+	io.ReadCloser
+	io.WriteCloser
+}
+
+func (ps *procStream) Close() error {
+	err := ps.WriteCloser.Close()
+	ps.ReadCloser.Close()
+	return err
+}
+
+// Initialize does the initialize handshake with the server, which sets
+// the server capabilities
+func (cl *Client) Initialize() error {
+	params := map[string]interface{}{
+		"processId": os.Getpid(),
+		"rootUri":   FileURI(cl.Root),
+		"capabilities": map[string]interface{}{
+			"textDocument": map[string]interface{}{
+				"synchronization": map[string]interface{}{"didSave": true},
+				"completion": map[string]interface{}{
+					"completionItem": map[string]interface{}{"documentationFormat": []string{"plaintext"}},
+				},
+				"hover":              map[string]interface{}{"contentFormat": []string{"plaintext"}},
+				"signatureHelp":      map[string]interface{}{},
+				"definition":         map[string]interface{}{},
+				"references":         map[string]interface{}{},
+				"rename":             map[string]interface{}{},
+				"publishDiagnostics": map[string]interface{}{},
+			},
+		},
+	}
+	var res struct {
+		Capabilities ServerCapabilities `json:"capabilities"`
+	}
+	if err := cl.Conn.Call("initialize", params, &res); err != nil {
+		return err
+	}
+	cl.Caps = res.Capabilities
+	return cl.Conn.Notify("initialized", struct{}{})
+}
+
+// Shutdown asks the server to shut down and exit, and closes the connection
+func (cl *Client) Shutdown() error {
+	err := cl.Conn.Call("shutdown", nil, nil)
+	cl.Conn.Notify("exit", nil)
+	cl.Conn.Close()
+	if cl.Cmd != nil {
+		cl.Cmd.Wait()
+	}
+	return err
+}
+
+// OnDiagnostics sets the function called with the diagnostics published by
+// the server for given document -- nil removes it.  The function is called
+// from the goroutine reading from the server, so it must not block on
+// anything that waits for the server.
+func (cl *Client) OnDiagnostics(uri string, fun func(diags []Diagnostic)) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if fun == nil {
+		delete(cl.diags, uri)
+	} else {
+		cl.diags[uri] = fun
+	}
+}
+
+// handle handles requests and notifications from the server
+func (cl *Client) handle(method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "textDocument/publishDiagnostics":
+		var pd PublishDiagnosticsParams
+		if err := json.Unmarshal(params, &pd); err != nil {
+			return nil, err
+		}
+		cl.mu.Lock()
+		fun := cl.diags[pd.URI]
+		cl.mu.Unlock()
+		if fun != nil {
+			fun(pd.Diagnostics)
+		}
+	case "workspace/configuration":
+		var cp struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		json.Unmarshal(params, &cp)
+		return make([]interface{}, len(cp.Items)), nil
+	case "workspace/applyEdit":
+		return map[string]bool{"applied": false}, nil
+	}
+	return nil, nil // progress, registration, log messages etc
+}
+
+// IncrementalSync returns true if the server accepts incremental changes
+// to documents -- otherwise DidChange must send the full text
+func (cl *Client) IncrementalSync() bool {
+	return cl.Caps.SyncKind() == SyncIncremental
+}
+
+// DidOpen tells the server that given document is open, with given
+// language identifier (e.g., "go"), version and text
+func (cl *Client) DidOpen(uri, langID string, version int, text []byte) error {
+	return cl.Conn.Notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": TextDocumentItem{URI: uri, LanguageID: langID, Version: version, Text: string(text)},
+	})
+}
+
+// DidChange sends changes to given document, which now has given version
+func (cl *Client) DidChange(uri string, version int, changes ...TextDocumentContentChangeEvent) error {
+	return cl.Conn.Notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   VersionedTextDocumentIdentifier{URI: uri, Version: version},
+		"contentChanges": changes,
+	})
+}
+
+// DidSave tells the server that given document was saved
+func (cl *Client) DidSave(uri string) error {
+	return cl.Conn.Notify("textDocument/didSave", map[string]interface{}{
+		"textDocument": TextDocumentIdentifier{URI: uri},
+	})
+}
+
+// DidClose tells the server that given document was closed
+func (cl *Client) DidClose(uri string) error {
+	return cl.Conn.Notify("textDocument/didClose", map[string]interface{}{
+		"textDocument": TextDocumentIdentifier{URI: uri},
+	})
+}
+
+// posParams returns the params for a request at given position
+func posParams(uri string, pos Position) TextDocumentPositionParams {
+	return TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: pos}
+}
+
+// Completion returns the possible completions at given position
+func (cl *Client) Completion(uri string, pos Position) ([]CompletionItem, error) {
+	var res CompletionList
+	err := cl.Conn.Call("textDocument/completion", posParams(uri, pos), &res)
+	return res.Items, err
+}
+
+// Hover returns the hover text for given position, which is empty if
+// there is nothing there
+func (cl *Client) Hover(uri string, pos Position) (string, error) {
+	var res *Hover
+	err := cl.Conn.Call("textDocument/hover", posParams(uri, pos), &res)
+	if err != nil || res == nil {
+		return "", err
+	}
+	return string(res.Contents), nil
+}
+
+// SignatureHelp returns the signatures of the function being called at
+// given position, or nil if not in a call
+func (cl *Client) SignatureHelp(uri string, pos Position) (*SignatureHelp, error) {
+	var res *SignatureHelp
+	err := cl.Conn.Call("textDocument/signatureHelp", posParams(uri, pos), &res)
+	return res, err
+}
+
+// Definition returns the location(s) where the symbol at given position
+// is defined
+func (cl *Client) Definition(uri string, pos Position) ([]Location, error) {
+	var res Locations
+	err := cl.Conn.Call("textDocument/definition", posParams(uri, pos), &res)
+	return res, err
+}
+
+// References returns the locations of all references to the symbol at
+// given position, including its declaration if includeDecl
+func (cl *Client) References(uri string, pos Position, includeDecl bool) ([]Location, error) {
+	params := struct {
+		TextDocumentPositionParams
+		Context struct {
+			IncludeDeclaration bool `json:"includeDeclaration"`
+		} `json:"context"`
+	}{TextDocumentPositionParams: posParams(uri, pos)}
+	params.Context.IncludeDeclaration = includeDecl
+	var res Locations
+	err := cl.Conn.Call("textDocument/references", params, &res)
+	return res, err
+}
+
+// Rename returns the edits to rename the symbol at given position to given
+// new name, throughout the workspace
+func (cl *Client) Rename(uri string, pos Position, newName string) (*WorkspaceEdit, error) {
+	params := struct {
+		TextDocumentPositionParams
+		NewName string `json:"newName"`
+	}{TextDocumentPositionParams: posParams(uri, pos), NewName: newName}
+	var res WorkspaceEdit
+	err := cl.Conn.Call("textDocument/rename", params, &res)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer is a minimal in-process language server, which tracks the
+// text of one document and reports a diagnostic for each "bad" word
+type fakeServer struct {
+	conn *Conn
+	mu   sync.Mutex
+	text string
+	vers int
+}
+
+func newFakeServer(rwc net.Conn) *fakeServer {
+	fs := &fakeServer{}
+	fs.conn = NewConn(rwc, fs.handle)
+	return fs
+}
+
+func (fs *fakeServer) Text() string {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.text
+}
+
+func (fs *fakeServer) handle(method string, params json.RawMessage) (interface{}, error) {
+	var pp TextDocumentPositionParams
+	json.Unmarshal(params, &pp)
+	loc := Location{URI: pp.TextDocument.URI, Range: Range{End: Position{Character: 4}}}
+	switch method {
+	case "initialize":
+		return map[string]interface{}{"capabilities": map[string]interface{}{
+			"textDocumentSync":   map[string]interface{}{"openClose": true, "change": SyncIncremental},
+			"completionProvider": map[string]interface{}{},
+			"hoverProvider":      true,
+		}}, nil
+	case "textDocument/didOpen":
+		var p struct {
+			TextDocument TextDocumentItem `json:"textDocument"`
+		}
+		json.Unmarshal(params, &p)
+		fs.mu.Lock()
+		fs.text, fs.vers = p.TextDocument.Text, p.TextDocument.Version
+		fs.mu.Unlock()
+		fs.publish(p.TextDocument.URI)
+	case "textDocument/didChange":
+		var p struct {
+			TextDocument   VersionedTextDocumentIdentifier  `json:"textDocument"`
+			ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
+		}
+		json.Unmarshal(params, &p)
+		fs.mu.Lock()
+		for _, ch := range p.ContentChanges {
+			if ch.Range == nil {
+				fs.text = ch.Text
+			} else {
+				fs.text = string(ApplyEdits([]byte(fs.text), []TextEdit{{Range: *ch.Range, NewText: ch.Text}}))
+			}
+		}
+		fs.vers = p.TextDocument.Version
+		fs.mu.Unlock()
+		fs.publish(p.TextDocument.URI)
+	case "textDocument/completion":
+		return []CompletionItem{{Label: "Println", Kind: CompletionFunction}, {Label: "Printf", Kind: CompletionFunction}}, nil
+	case "textDocument/hover":
+		return Hover{Contents: "func Println(a ...interface{})"}, nil
+	case "textDocument/definition":
+		return loc, nil
+	case "textDocument/references":
+		return []Location{loc, loc}, nil
+	case "textDocument/rename":
+		var p struct {
+			NewName string `json:"newName"`
+		}
+		json.Unmarshal(params, &p)
+		return WorkspaceEdit{Changes: map[string][]TextEdit{loc.URI: {{Range: loc.Range, NewText: p.NewName}}}}, nil
+	case "shutdown":
+		return nil, nil
+	}
+	return nil, nil
+}
+
+// publish sends a diagnostic for each occurrence of "bad"
+func (fs *fakeServer) publish(uri string) {
+	var diags []Diagnostic
+	for ln, l := range strings.Split(fs.Text(), "\n") {
+		if ch := strings.Index(l, "bad"); ch >= 0 {
+			diags = append(diags, Diagnostic{Range: Range{Start: Position{ln, ch}, End: Position{ln, ch + 3}}, Severity: SeverityError, Message: "bad word"})
+		}
+	}
+	fs.conn.Notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: diags})
+}
+
+func TestClient(t *testing.T) {
+	cc, sc := net.Pipe()
+	fs := newFakeServer(sc)
+	cl := NewClient(cc, "/work")
+	if err := cl.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if !cl.IncrementalSync() || !Has(cl.Caps.HoverProvider) || Has(cl.Caps.RenameProvider) {
+		t.Errorf("capabilities not as expected: %+v", cl.Caps)
+	}
+
+	uri := FileURI("/work/main.go")
+	dch := make(chan []Diagnostic, 10)
+	cl.OnDiagnostics(uri, func(diags []Diagnostic) { dch <- diags })
+	nextDiags := func() []Diagnostic {
+		select {
+		case d := <-dch:
+			return d
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for diagnostics")
+		}
+		return nil
+	}
+
+	cl.DidOpen(uri, "go", 1, []byte("package main\n\nfunc main() {\n}\n"))
+	if d := nextDiags(); len(d) != 0 {
+		t.Errorf("expected no diagnostics, got: %v", d)
+	}
+	// insert "bad 😀" on line 3, then delete the emoji (2 UTF-16 units)
+	cl.DidChange(uri, 2, TextDocumentContentChangeEvent{Range: &Range{Start: Position{3, 0}, End: Position{3, 0}}, Text: "\tbad 😀x\n"})
+	d := nextDiags()
+	if len(d) != 1 || d[0].Range.Start != (Position{3, 1}) || d[0].Severity != SeverityError {
+		t.Errorf("expected one bad diagnostic on line 3, got: %v", d)
+	}
+	cl.DidChange(uri, 3, TextDocumentContentChangeEvent{Range: &Range{Start: Position{3, 5}, End: Position{3, 7}}, Text: ""})
+	nextDiags()
+	exp := "package main\n\nfunc main() {\n\tbad x\n}\n"
+	if got := fs.Text(); got != exp {
+		t.Errorf("server text after changes:\ngot: %q\nexp: %q", got, exp)
+	}
+
+	items, err := cl.Completion(uri, Position{3, 1})
+	if err != nil || len(items) != 2 || items[1].Text() != "Printf" {
+		t.Errorf("completion: %v %v", items, err)
+	}
+	hv, err := cl.Hover(uri, Position{3, 1})
+	if err != nil || hv != "func Println(a ...interface{})" {
+		t.Errorf("hover: %q %v", hv, err)
+	}
+	defs, err := cl.Definition(uri, Position{3, 1})
+	if err != nil || len(defs) != 1 || defs[0].URI != uri {
+		t.Errorf("definition: %v %v", defs, err)
+	}
+	refs, err := cl.References(uri, Position{3, 1}, true)
+	if err != nil || len(refs) != 2 {
+		t.Errorf("references: %v %v", refs, err)
+	}
+	we, err := cl.Rename(uri, Position{3, 1}, "bundle")
+	if err != nil || len(we.Edits()[uri]) != 1 {
+		t.Fatalf("rename: %v %v", we, err)
+	}
+	if got := string(ApplyEdits([]byte(exp), we.Edits()[uri])); !strings.HasPrefix(got, "bundleage main\n") {
+		t.Errorf("rename edit result: %q", got)
+	}
+	if err := cl.Shutdown(); err != nil {
+		t.Error(err)
+	}
+	if _, err := cl.Hover(uri, Position{}); err == nil {
+		t.Errorf("expected error after shutdown")
+	}
+}
+
+func TestConnQueue(t *testing.T) {
+	cc, sc := net.Pipe()
+	cn := NewConn(cc, nil)
+	sent := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ { // the other end is not reading yet
+			cn.Notify("test", i)
+		}
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("notify blocked on the other end")
+	}
+	var got []int
+	done := make(chan struct{})
+	sn := NewConn(sc, func(method string, params json.RawMessage) (interface{}, error) {
+		var i int
+		json.Unmarshal(params, &i)
+		got = append(got, i)
+		if len(got) == 10 {
+			close(done)
+		}
+		return nil, nil
+	})
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out reading notifications: %v", got)
+	}
+	for i, v := range got {
+		if v != i {
+			t.Errorf("notifications out of order: %v", got)
+			break
+		}
+	}
+	cn.Close()
+	sn.Close()
+	if err := cn.Notify("test", 0); err != ErrClosed {
+		t.Errorf("notify after close: %v", err)
+	}
+}
+
+func TestUTF16(t *testing.T) {
+	rs := []rune("a😀b")
+	if n := UTF16Len(rs); n != 4 {
+		t.Errorf("UTF16Len: got %d, expected 4", n)
+	}
+	for u16, exp := range []int{0, 1, 2, 2, 3, 3} {
+		if ri := RuneIdx(rs, u16); ri != exp {
+			t.Errorf("RuneIdx(%d): got %d, expected %d", u16, ri, exp)
+		}
+	}
+}
+
+func TestApplyEdits(t *testing.T) {
+	txt := []byte("one two\nthree\n")
+	eds := []TextEdit{
+		{Range: Range{Start: Position{1, 0}, End: Position{1, 5}}, NewText: "3"},
+		{Range: Range{Start: Position{0, 4}, End: Position{0, 7}}, NewText: "2"},
+	}
+	if got := string(ApplyEdits(txt, eds)); got != "one 2\n3\n" {
+		t.Errorf("ApplyEdits: got %q", got)
+	}
+}
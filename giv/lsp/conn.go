@@ -0,0 +1,338 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/textproto"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CallTimeout is the maximum time to wait for the response to a request
+// sent to a language server
+var CallTimeout = 10 * time.Second
+
+// ErrClosed is returned for calls on a connection that has been closed
+var ErrClosed = errors.New("lsp: connection closed")
+
+// ErrTimeout is returned for calls that did not get a response within CallTimeout
+var ErrTimeout = errors.New("lsp: timed out waiting for response")
+
+// Handler handles a request or notification received from the other end
+// of a Conn -- the result is only used for requests, and is sent back as
+// the response.
+type Handler func(method string, params json.RawMessage) (result interface{}, err error)
+
+// Error is a JSON-RPC error response
+type Error struct {
+	Code    int64  `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("lsp: %v (code: %v)", e.Message, e.Code)
+}
+
+// JSON-RPC error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// message is any JSON-RPC message -- requests have an ID and Method,
+// notifications only a Method, and responses only an ID
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *Error           `json:"error,omitempty"`
+}
+
+// Conn is a JSON-RPC 2.0 connection using the base protocol of the
+// Language Server Protocol, where each message is preceded by a
+// Content-Length header, e.g., over the stdin and stdout of a server
+// process.  Messages received from the other end are read in a separate
+// goroutine: responses are matched to pending calls, and requests and
+// notifications are passed to the Handler, in the order received.
+// Messages sent are queued and written in another goroutine, in the order
+// sent, so sending never blocks on the other end reading them.
+type Conn struct {
+	Handler Handler
+	rd      *bufio.Reader
+	wr      io.Writer
+	cl      io.Closer
+	mu      sync.Mutex
+	seq     int64
+	pending map[int64]chan *message
+	err     error
+	outMu   sync.Mutex
+	outCond *sync.Cond
+	out     [][]byte
+	outDone bool
+	wrDone  chan struct{}
+}
+
+// NewConn returns a new Conn reading and writing on given stream, and
+// starts reading from it.  The handler may be nil, in which case requests
+// from the other end get a method not found error.
+func NewConn(rwc io.ReadWriteCloser, handler Handler) *Conn {
+	cn := &Conn{Handler: handler, rd: bufio.NewReader(rwc), wr: rwc, cl: rwc}
+	cn.pending = make(map[int64]chan *message)
+	cn.outCond = sync.NewCond(&cn.outMu)
+	cn.wrDone = make(chan struct{})
+	go cn.readLoop()
+	go cn.writeLoop()
+	return cn
+}
+
+// Call sends a request with given method and params, and waits for the
+// response, which is decoded into result unless it is nil
+func (cn *Conn) Call(method string, params, result interface{}) error {
+	cn.mu.Lock()
+	if cn.err != nil {
+		cn.mu.Unlock()
+		return cn.err
+	}
+	cn.seq++
+	id := cn.seq
+	rch := make(chan *message, 1)
+	cn.pending[id] = rch
+	cn.mu.Unlock()
+
+	rid := json.RawMessage(strconv.FormatInt(id, 10))
+	err := cn.send(&message{ID: &rid, Method: method}, params)
+	if err != nil {
+		cn.removePending(id)
+		return err
+	}
+	select {
+	case rsp := <-rch:
+		if rsp == nil {
+			return cn.Err()
+		}
+		if rsp.Error != nil {
+			return rsp.Error
+		}
+		if result == nil || len(rsp.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(rsp.Result, result)
+	case <-time.After(CallTimeout):
+		cn.removePending(id)
+		return ErrTimeout
+	}
+}
+
+// Notify sends a notification with given method and params
+func (cn *Conn) Notify(method string, params interface{}) error {
+	if err := cn.Err(); err != nil {
+		return err
+	}
+	return cn.send(&message{Method: method}, params)
+}
+
+// Close closes the connection, failing any pending calls -- the messages
+// already sent are written first, waiting up to CallTimeout for that
+func (cn *Conn) Close() error {
+	cn.stopWrite()
+	select {
+	case <-cn.wrDone:
+	case <-time.After(CallTimeout):
+	}
+	err := cn.cl.Close()
+	cn.fail(ErrClosed)
+	return err
+}
+
+// Err returns the error that closed the connection, or nil if it is open
+func (cn *Conn) Err() error {
+	cn.mu.Lock()
+	defer cn.mu.Unlock()
+	return cn.err
+}
+
+// send encodes params into given message and writes it
+func (cn *Conn) send(msg *message, params interface{}) error {
+	msg.JSONRPC = "2.0"
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = b
+	}
+	return cn.write(msg)
+}
+
+// write queues given message with its header, for writeLoop to write
+func (cn *Conn) write(msg *message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	hdr := fmt.Sprintf("Content-Length: %d\r\n\r\n", len(b))
+	cn.outMu.Lock()
+	defer cn.outMu.Unlock()
+	if cn.outDone {
+		return ErrClosed
+	}
+	cn.out = append(cn.out, append([]byte(hdr), b...))
+	cn.outCond.Signal()
+	return nil
+}
+
+// writeLoop writes the queued messages until stopWrite is called and the
+// queue is empty -- after a write fails, the rest are dropped
+func (cn *Conn) writeLoop() {
+	defer close(cn.wrDone)
+	var werr error
+	for {
+		cn.outMu.Lock()
+		for len(cn.out) == 0 && !cn.outDone {
+			cn.outCond.Wait()
+		}
+		out := cn.out
+		cn.out = nil
+		cn.outMu.Unlock()
+		if len(out) == 0 {
+			return
+		}
+		for _, b := range out {
+			if werr != nil {
+				break
+			}
+			if _, werr = cn.wr.Write(b); werr != nil {
+				cn.fail(werr)
+			}
+		}
+	}
+}
+
+// stopWrite stops queueing messages, and has writeLoop return once it has
+// written those already queued
+func (cn *Conn) stopWrite() {
+	cn.outMu.Lock()
+	cn.outDone = true
+	cn.outCond.Signal()
+	cn.outMu.Unlock()
+}
+
+// read reads the next message
+func (cn *Conn) read() (*message, error) {
+	hdr, err := textproto.NewReader(cn.rd).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(hdr.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("lsp: invalid Content-Length header: %v", err)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(cn.rd, b); err != nil {
+		return nil, err
+	}
+	msg := &message{}
+	err = json.Unmarshal(b, msg)
+	return msg, err
+}
+
+// readLoop reads and dispatches messages until the connection fails
+func (cn *Conn) readLoop() {
+	for {
+		msg, err := cn.read()
+		if err != nil {
+			if _, ok := err.(*json.SyntaxError); ok {
+				log.Printf("lsp: invalid message: %v\n", err)
+				continue
+			}
+			if err == io.EOF {
+				err = ErrClosed
+			}
+			cn.fail(err)
+			return
+		}
+		switch {
+		case msg.Method != "":
+			cn.handle(msg)
+		case msg.ID != nil:
+			id, err := strconv.ParseInt(string(*msg.ID), 10, 64)
+			if err != nil {
+				continue
+			}
+			cn.mu.Lock()
+			rch, ok := cn.pending[id]
+			delete(cn.pending, id)
+			cn.mu.Unlock()
+			if ok {
+				rch <- msg
+			}
+		}
+	}
+}
+
+// handle passes a request or notification to the Handler, and sends the
+// response for requests
+func (cn *Conn) handle(msg *message) {
+	var res interface{}
+	var err error
+	if cn.Handler != nil {
+		res, err = cn.Handler(msg.Method, msg.Params)
+	} else {
+		err = &Error{Code: CodeMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+	if msg.ID == nil { // notification
+		return
+	}
+	rsp := &message{JSONRPC: "2.0", ID: msg.ID}
+	if err != nil {
+		rerr, ok := err.(*Error)
+		if !ok {
+			rerr = &Error{Code: CodeInternalError, Message: err.Error()}
+		}
+		rsp.Error = rerr
+	} else {
+		b, err := json.Marshal(res)
+		if err != nil {
+			rsp.Error = &Error{Code: CodeInternalError, Message: err.Error()}
+		} else {
+			rsp.Result = b
+		}
+	}
+	cn.write(rsp)
+}
+
+// removePending removes the pending call with given id
+func (cn *Conn) removePending(id int64) {
+	cn.mu.Lock()
+	delete(cn.pending, id)
+	cn.mu.Unlock()
+}
+
+// fail records the error closing the connection, fails pending calls, and
+// stops queueing messages
+func (cn *Conn) fail(err error) {
+	cn.stopWrite()
+	cn.mu.Lock()
+	defer cn.mu.Unlock()
+	if cn.err == nil {
+		cn.err = err
+	}
+	for id, rch := range cn.pending {
+		delete(cn.pending, id)
+		rch <- nil
+	}
+}
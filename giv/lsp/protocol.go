@@ -0,0 +1,354 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// This file has the subset of the Language Server Protocol types used by
+// the Client -- see https://microsoft.github.io/language-server-protocol
+
+// Position is a position in a text document, as a zero-based line, and
+// character offset within the line in UTF-16 code units -- see UTF16Len
+// and RuneIdx for converting to and from rune offsets.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a range of text in a document, with an exclusive End
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range of text in a given document
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+// UnmarshalJSON also accepts a LocationLink, which servers can return
+// instead of a Location for definitions
+func (lc *Location) UnmarshalJSON(b []byte) error {
+	var ll struct {
+		URI                  string `json:"uri"`
+		Range                Range  `json:"range"`
+		TargetURI            string `json:"targetUri"`
+		TargetSelectionRange Range  `json:"targetSelectionRange"`
+	}
+	if err := json.Unmarshal(b, &ll); err != nil {
+		return err
+	}
+	if ll.TargetURI != "" {
+		lc.URI = ll.TargetURI
+		lc.Range = ll.TargetSelectionRange
+	} else {
+		lc.URI = ll.URI
+		lc.Range = ll.Range
+	}
+	return nil
+}
+
+// Locations is a list of locations, which unmarshals from a single
+// location, a list, or null
+type Locations []Location
+
+func (ls *Locations) UnmarshalJSON(b []byte) error {
+	*ls = nil
+	switch {
+	case string(b) == "null":
+		return nil
+	case len(b) > 0 && b[0] == '[':
+		return json.Unmarshal(b, (*[]Location)(ls))
+	}
+	var lc Location
+	if err := json.Unmarshal(b, &lc); err != nil {
+		return err
+	}
+	*ls = Locations{lc}
+	return nil
+}
+
+// MarkupText is documentation or other text from the server, which
+// unmarshals from a plain string, a MarkupContent or MarkedString object,
+// or a list of those, which are joined by blank lines
+type MarkupText string
+
+func (mt *MarkupText) UnmarshalJSON(b []byte) error {
+	*mt = ""
+	if len(b) == 0 || string(b) == "null" {
+		return nil
+	}
+	switch b[0] {
+	case '"':
+		var s string
+		err := json.Unmarshal(b, &s)
+		*mt = MarkupText(s)
+		return err
+	case '[':
+		var mts []MarkupText
+		if err := json.Unmarshal(b, &mts); err != nil {
+			return err
+		}
+		strs := make([]string, len(mts))
+		for i, m := range mts {
+			strs[i] = string(m)
+		}
+		*mt = MarkupText(strings.Join(strs, "\n\n"))
+		return nil
+	}
+	var mc struct {
+		Value string `json:"value"`
+	}
+	err := json.Unmarshal(b, &mc)
+	*mt = MarkupText(mc.Value)
+	return err
+}
+
+// TextDocumentItem is a document transferred when it is opened
+type TextDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+// TextDocumentIdentifier identifies a document by its URI
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+// VersionedTextDocumentIdentifier identifies a specific version of a document
+type VersionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+}
+
+// TextDocumentContentChangeEvent is a change to a document -- the range
+// is replaced with the text, or the entire document if range is nil
+type TextDocumentContentChangeEvent struct {
+	Range *Range `json:"range,omitempty"`
+	Text  string `json:"text"`
+}
+
+// TextDocumentPositionParams is a position in a document, for requests
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+// TextEdit replaces given range of text with new text
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// TextDocumentEdit is a list of edits to a given document
+type TextDocumentEdit struct {
+	TextDocument VersionedTextDocumentIdentifier `json:"textDocument"`
+	Edits        []TextEdit                      `json:"edits"`
+}
+
+// WorkspaceEdit is a set of edits to documents in the workspace, e.g., for
+// a rename -- servers use either Changes or DocumentChanges
+type WorkspaceEdit struct {
+	Changes         map[string][]TextEdit `json:"changes,omitempty"`
+	DocumentChanges []TextDocumentEdit    `json:"documentChanges,omitempty"`
+}
+
+// Edits returns all the edits in the workspace edit, by document URI
+func (we *WorkspaceEdit) Edits() map[string][]TextEdit {
+	eds := make(map[string][]TextEdit, len(we.Changes)+len(we.DocumentChanges))
+	for uri, ed := range we.Changes {
+		eds[uri] = append(eds[uri], ed...)
+	}
+	for _, dc := range we.DocumentChanges {
+		eds[dc.TextDocument.URI] = append(eds[dc.TextDocument.URI], dc.Edits...)
+	}
+	return eds
+}
+
+// CompletionItemKind is the kind of a completion item
+type CompletionItemKind int
+
+// CompletionItemKinds
+const (
+	CompletionText CompletionItemKind = iota + 1
+	CompletionMethod
+	CompletionFunction
+	CompletionConstructor
+	CompletionField
+	CompletionVariable
+	CompletionClass
+	CompletionInterface
+	CompletionModule
+	CompletionProperty
+	CompletionUnit
+	CompletionValue
+	CompletionEnum
+	CompletionKeyword
+	CompletionSnippet
+	CompletionColor
+	CompletionFile
+	CompletionReference
+	CompletionFolder
+	CompletionEnumMember
+	CompletionConstant
+	CompletionStruct
+	CompletionEvent
+	CompletionOperator
+	CompletionTypeParameter
+)
+
+// CompletionItem is one possible completion
+type CompletionItem struct {
+	Label         string             `json:"label"`
+	Kind          CompletionItemKind `json:"kind,omitempty"`
+	Detail        string             `json:"detail,omitempty"`
+	Documentation MarkupText         `json:"documentation,omitempty"`
+	InsertText    string             `json:"insertText,omitempty"`
+	TextEdit      *TextEdit          `json:"textEdit,omitempty"`
+}
+
+// Text returns the text to insert for the completion
+func (ci *CompletionItem) Text() string {
+	switch {
+	case ci.TextEdit != nil:
+		return ci.TextEdit.NewText
+	case ci.InsertText != "":
+		return ci.InsertText
+	}
+	return ci.Label
+}
+
+// CompletionList is the list of completions returned by the server
+type CompletionList struct {
+	IsIncomplete bool             `json:"isIncomplete"`
+	Items        []CompletionItem `json:"items"`
+}
+
+// UnmarshalJSON also accepts a plain list of items, or null
+func (cl *CompletionList) UnmarshalJSON(b []byte) error {
+	*cl = CompletionList{}
+	switch {
+	case string(b) == "null":
+		return nil
+	case len(b) > 0 && b[0] == '[':
+		return json.Unmarshal(b, &cl.Items)
+	}
+	type list CompletionList
+	return json.Unmarshal(b, (*list)(cl))
+}
+
+// Hover is the information shown when hovering over a symbol
+type Hover struct {
+	Contents MarkupText `json:"contents"`
+	Range    *Range     `json:"range,omitempty"`
+}
+
+// ParameterInformation is one parameter of a signature -- the label is
+// either a string, or start and end offsets within the signature label
+type ParameterInformation struct {
+	Label         json.RawMessage `json:"label"`
+	Documentation MarkupText      `json:"documentation,omitempty"`
+}
+
+// SignatureInformation is the signature of a function or method
+type SignatureInformation struct {
+	Label         string                 `json:"label"`
+	Documentation MarkupText             `json:"documentation,omitempty"`
+	Parameters    []ParameterInformation `json:"parameters,omitempty"`
+}
+
+// SignatureHelp is the signatures for the function being called at a
+// position, with the active one and its active parameter
+type SignatureHelp struct {
+	Signatures      []SignatureInformation `json:"signatures"`
+	ActiveSignature int                    `json:"activeSignature"`
+	ActiveParameter int                    `json:"activeParameter"`
+}
+
+// DiagnosticSeverity is the severity of a diagnostic
+type DiagnosticSeverity int
+
+// DiagnosticSeverities
+const (
+	SeverityError DiagnosticSeverity = iota + 1
+	SeverityWarning
+	SeverityInformation
+	SeverityHint
+)
+
+var severityNames = [...]string{"", "error", "warning", "info", "hint"}
+
+func (ds DiagnosticSeverity) String() string {
+	if ds < SeverityError || ds > SeverityHint {
+		return severityNames[SeverityError]
+	}
+	return severityNames[ds]
+}
+
+// Diagnostic is an error, warning, etc for a range of a document
+type Diagnostic struct {
+	Range    Range              `json:"range"`
+	Severity DiagnosticSeverity `json:"severity,omitempty"`
+	Source   string             `json:"source,omitempty"`
+	Message  string             `json:"message"`
+}
+
+// PublishDiagnosticsParams are sent by the server with all the current
+// diagnostics for a document
+type PublishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Version     int          `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// TextDocumentSyncKind is how documents are synced with the server
+type TextDocumentSyncKind int
+
+// TextDocumentSyncKinds
+const (
+	SyncNone TextDocumentSyncKind = iota
+	SyncFull
+	SyncIncremental
+)
+
+// ServerCapabilities are the features supported by the server -- the
+// providers are either a bool or an options object, so use Has to test
+// them.
+type ServerCapabilities struct {
+	TextDocumentSync      json.RawMessage `json:"textDocumentSync,omitempty"`
+	CompletionProvider    json.RawMessage `json:"completionProvider,omitempty"`
+	HoverProvider         json.RawMessage `json:"hoverProvider,omitempty"`
+	SignatureHelpProvider json.RawMessage `json:"signatureHelpProvider,omitempty"`
+	DefinitionProvider    json.RawMessage `json:"definitionProvider,omitempty"`
+	ReferencesProvider    json.RawMessage `json:"referencesProvider,omitempty"`
+	RenameProvider        json.RawMessage `json:"renameProvider,omitempty"`
+}
+
+// Has returns true if given provider capability is set
+func Has(prov json.RawMessage) bool {
+	return len(prov) > 0 && string(prov) != "false" && string(prov) != "null"
+}
+
+// SyncKind returns the kind of document sync the server supports
+func (sc *ServerCapabilities) SyncKind() TextDocumentSyncKind {
+	if len(sc.TextDocumentSync) == 0 {
+		return SyncNone
+	}
+	var sk TextDocumentSyncKind
+	if err := json.Unmarshal(sc.TextDocumentSync, &sk); err == nil {
+		return sk
+	}
+	var opts struct {
+		Change TextDocumentSyncKind `json:"change"`
+	}
+	json.Unmarshal(sc.TextDocumentSync, &opts)
+	return opts.Change
+}
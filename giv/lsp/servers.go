@@ -0,0 +1,83 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ServerConfig specifies the command that runs a language server, which
+// must use its stdin and stdout for the protocol
+type ServerConfig struct {
+	Cmd  string   `desc:"name or path of the server executable"`
+	Args []string `desc:"command line arguments for the server"`
+}
+
+// ServerConfigs are the servers to use for each language, by LSP language
+// identifier -- modify to add or change servers
+var ServerConfigs = map[string]ServerConfig{
+	"go":         {Cmd: "gopls"},
+	"python":     {Cmd: "pylsp"},
+	"c":          {Cmd: "clangd"},
+	"cpp":        {Cmd: "clangd"},
+	"rust":       {Cmd: "rust-analyzer"},
+	"javascript": {Cmd: "typescript-language-server", Args: []string{"--stdio"}},
+	"typescript": {Cmd: "typescript-language-server", Args: []string{"--stdio"}},
+}
+
+// Servers manages the running language servers, with one server per
+// workspace root directory and language.  The servers are started as
+// needed by Client.
+type Servers struct {
+	mu      sync.Mutex
+	clients map[serverKey]*Client
+}
+
+// serverKey identifies a server
+type serverKey struct {
+	root string
+	lang string
+}
+
+// TheServers are the language servers used by giv.TextBuf
+var TheServers Servers
+
+// Client returns the client for the server for given workspace root
+// directory and language, starting the server if it is not running yet
+func (sv *Servers) Client(root, lang string) (*Client, error) {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	key := serverKey{root: root, lang: lang}
+	if cl, ok := sv.clients[key]; ok {
+		if cl.Conn.Err() == nil {
+			return cl, nil
+		}
+		delete(sv.clients, key) // server died -- restart
+	}
+	cfg, ok := ServerConfigs[lang]
+	if !ok {
+		return nil, fmt.Errorf("lsp: no language server configured for language: %v", lang)
+	}
+	cl, err := Start(root, cfg.Cmd, cfg.Args...)
+	if err != nil {
+		return nil, err
+	}
+	if sv.clients == nil {
+		sv.clients = make(map[serverKey]*Client)
+	}
+	sv.clients[key] = cl
+	return cl, nil
+}
+
+// ShutdownAll shuts down all the running servers
+func (sv *Servers) ShutdownAll() {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	for key, cl := range sv.clients {
+		cl.Shutdown()
+		delete(sv.clients, key)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// UTF16Len returns the number of UTF-16 code units in given runes, which
+// is how LSP positions measure characters within a line
+func UTF16Len(rs []rune) int {
+	n := 0
+	for _, r := range rs {
+		n++
+		if r >= 0x10000 {
+			n++
+		}
+	}
+	return n
+}
+
+// RuneIdx returns the index of the rune in given line at given UTF-16
+// offset, limited to the length of the line
+func RuneIdx(rs []rune, u16 int) int {
+	n := 0
+	for i, r := range rs {
+		if n >= u16 {
+			return i
+		}
+		n++
+		if r >= 0x10000 {
+			n++
+		}
+	}
+	return len(rs)
+}
+
+// FileURI returns the file:// URI for given file path
+func FileURI(path string) string {
+	if ap, err := filepath.Abs(path); err == nil {
+		path = ap
+	}
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") { // windows drive
+		path = "/" + path
+	}
+	u := url.URL{Scheme: "file", Path: path}
+	return u.String()
+}
+
+// URIFile returns the file path for given file:// URI, or "" if it is not
+// a file URI
+func URIFile(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	path := u.Path
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' { // windows drive
+		path = path[1:]
+	}
+	return filepath.FromSlash(path)
+}
+
+// RootMarkers are the names of files or directories that mark the root
+// directory of a workspace -- see FindRoot
+var RootMarkers = []string{".git", ".hg", ".svn", "go.mod", "package.json", "Cargo.toml", "setup.py", "pyproject.toml"}
+
+// FindRoot returns the workspace root directory for given file, which is
+// the closest enclosing directory that contains one of the RootMarkers, or
+// the directory of the file if there is none
+func FindRoot(fname string) string {
+	if af, err := filepath.Abs(fname); err == nil {
+		fname = af
+	}
+	fdir := filepath.Dir(fname)
+	for dir := fdir; ; {
+		for _, m := range RootMarkers {
+			if _, err := os.Stat(filepath.Join(dir, m)); err == nil {
+				return dir
+			}
+		}
+		pdir := filepath.Dir(dir)
+		if pdir == dir {
+			return fdir
+		}
+		dir = pdir
+	}
+}
+
+// ApplyEdits applies given edits to given text, returning the new text --
+// the edit ranges all refer to the original text, as in LSP
+func ApplyEdits(txt []byte, edits []TextEdit) []byte {
+	eds := make([]TextEdit, len(edits))
+	copy(eds, edits)
+	sort.SliceStable(eds, func(i, j int) bool {
+		a, b := eds[i].Range.Start, eds[j].Range.Start
+		return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+	})
+	// byte offsets of line starts
+	lns := []int{0}
+	for i, c := range txt {
+		if c == '\n' {
+			lns = append(lns, i+1)
+		}
+	}
+	off := func(p Position) int {
+		if p.Line >= len(lns) {
+			return len(txt)
+		}
+		st := lns[p.Line]
+		ed := len(txt)
+		if p.Line+1 < len(lns) {
+			ed = lns[p.Line+1] - 1
+		}
+		o := st
+		for u := 0; u < p.Character && o < ed; {
+			r, sz := utf8.DecodeRune(txt[o:ed])
+			o += sz
+			u++
+			if r >= 0x10000 {
+				u++
+			}
+		}
+		return o
+	}
+	var nt []byte
+	cp := 0
+	for _, ed := range eds {
+		st := off(ed.Range.Start)
+		en := off(ed.Range.End)
+		if st < cp {
+			continue // overlapping
+		}
+		nt = append(nt, txt[cp:st]...)
+		nt = append(nt, ed.NewText...)
+		cp = en
+	}
+	return append(nt, txt[cp:]...)
+}
@@ -0,0 +1,576 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package markdown
+
+import (
+	"strconv"
+	"strings"
+)
+
+// line is a line of source within a container block, with the markers
+// and indentation of its containers removed
+type line struct {
+	s string // text of the line
+	n int    // line number in the source
+}
+
+// parseBlocks parses the blocks in given lines
+func (doc *Document) parseBlocks(lns []line) []*Block {
+	var blks []*Block
+	var para []line
+	endPara := func() {
+		if bl := doc.paragraph(para); bl != nil {
+			blks = append(blks, bl)
+		}
+		para = nil
+	}
+	for i := 0; i < len(lns); {
+		ln := lns[i]
+		if isBlank(ln.s) {
+			endPara()
+			i++
+			continue
+		}
+		ind := indent(ln.s)
+		if ind >= 4 {
+			if len(para) > 0 { // lazy continuation
+				para = append(para, line{strings.TrimLeft(ln.s, " \t"), ln.n})
+				i++
+				continue
+			}
+			bl, n := codeBlock(lns[i:])
+			blks = append(blks, bl)
+			i += n
+			continue
+		}
+		s := dedent(ln.s, ind)
+		if ch, fn, info, ok := openFence(s); ok {
+			endPara()
+			bl, n := fencedBlock(lns[i:], ind, ch, fn, info)
+			blks = append(blks, bl)
+			i += n
+			continue
+		}
+		if lev, txt, ok := atxHeading(s); ok {
+			endPara()
+			blks = append(blks, &Block{Kind: Heading, Level: lev, Text: txt, Line: ln.n, EndLine: ln.n + 1})
+			i++
+			continue
+		}
+		if len(para) > 0 {
+			if lev := setextLevel(s); lev > 0 {
+				bl := doc.paragraph(para)
+				para = nil
+				if bl != nil {
+					bl.Kind = Heading
+					bl.Level = lev
+					bl.EndLine = ln.n + 1
+					blks = append(blks, bl)
+					i++
+					continue
+				}
+			}
+		}
+		if thematicBreak(s) {
+			endPara()
+			blks = append(blks, &Block{Kind: Rule, Line: ln.n, EndLine: ln.n + 1})
+			i++
+			continue
+		}
+		if s[0] == '>' {
+			endPara()
+			bl, n := doc.quote(lns[i:])
+			blks = append(blks, bl)
+			i += n
+			continue
+		}
+		if mk, ok := listMarker(s); ok && (len(para) == 0 || (!mk.empty && (!mk.ordered || mk.start == 1))) {
+			endPara()
+			bl, n := doc.list(lns[i:])
+			blks = append(blks, bl)
+			i += n
+			continue
+		}
+		if i+1 < len(lns) && strings.Contains(s, "|") {
+			if aligns := delimRow(lns[i+1].s); aligns != nil && len(aligns) == len(splitRow(s)) {
+				endPara()
+				bl, n := table(lns[i:], aligns)
+				blks = append(blks, bl)
+				i += n
+				continue
+			}
+		}
+		para = append(para, line{s, ln.n})
+		i++
+	}
+	endPara()
+	return blks
+}
+
+// paragraph returns a Paragraph block for given lines, after removing any
+// link reference definitions at its start, or nil if nothing is left
+func (doc *Document) paragraph(lns []line) *Block {
+	if len(lns) == 0 {
+		return nil
+	}
+	txts := make([]string, len(lns))
+	for i, ln := range lns {
+		txts[i] = ln.s
+	}
+	txt := strings.Join(txts, "\n")
+	for {
+		label, ref, rest, ok := parseRefDef(txt)
+		if !ok {
+			break
+		}
+		if _, has := doc.Refs[label]; !has {
+			doc.Refs[label] = ref
+		}
+		txt = rest
+	}
+	txt = strings.TrimRight(txt, " \t\n")
+	if txt == "" {
+		return nil
+	}
+	return &Block{Kind: Paragraph, Text: txt, Line: lns[len(lns)-1].n - strings.Count(txt, "\n"), EndLine: lns[len(lns)-1].n + 1}
+}
+
+// codeBlock returns an indented CodeBlock starting at the first of given
+// lines, and the number of lines in it
+func codeBlock(lns []line) (*Block, int) {
+	var txts []string
+	n := 0
+	for i, ln := range lns {
+		if isBlank(ln.s) {
+			txts = append(txts, dedent(ln.s, 4))
+			continue
+		}
+		if indent(ln.s) < 4 {
+			break
+		}
+		txts = append(txts, dedent(ln.s, 4))
+		n = i + 1
+	}
+	txts = txts[:n]
+	return &Block{Kind: CodeBlock, Text: strings.Join(txts, "\n"), Line: lns[0].n, EndLine: lns[n-1].n + 1}, n
+}
+
+// fencedBlock returns a fenced CodeBlock starting at the first of given
+// lines, which opens it with given indent and fence, and the number of
+// lines in it, including its closing fence
+func fencedBlock(lns []line, ind int, ch byte, fn int, info string) (*Block, int) {
+	var txts []string
+	i := 1
+	for ; i < len(lns); i++ {
+		s := lns[i].s
+		if closesFence(s, ch, fn) {
+			i++
+			break
+		}
+		txts = append(txts, dedent(s, ind))
+	}
+	return &Block{Kind: CodeBlock, Info: unescape(info), Text: strings.Join(txts, "\n"), Line: lns[0].n, EndLine: lns[i-1].n + 1}, i
+}
+
+// quote returns a Quote starting at the first of given lines, and the
+// number of lines in it
+func (doc *Document) quote(lns []line) (*Block, int) {
+	var inner []line
+	lazy := false
+	i := 0
+	for ; i < len(lns); i++ {
+		s := lns[i].s
+		if ind := indent(s); ind < 4 {
+			if t := dedent(s, ind); t != "" && t[0] == '>' {
+				t = dedent(t[1:], 1)
+				inner = append(inner, line{t, lns[i].n})
+				lazy = !isBlank(t) && !interrupts(t)
+				continue
+			}
+		}
+		if lazy && !isBlank(s) && !interrupts(s) {
+			inner = append(inner, line{s, lns[i].n})
+			continue
+		}
+		break
+	}
+	return &Block{Kind: Quote, Kids: doc.parseBlocks(inner), Line: lns[0].n, EndLine: lns[i-1].n + 1}, i
+}
+
+// list returns a List starting at the first of given lines, and the number
+// of lines in it
+func (doc *Document) list(lns []line) (*Block, int) {
+	s0 := lns[0].s
+	first, _ := listMarker(dedent(s0, indent(s0)))
+	bl := &Block{Kind: List, Ordered: first.ordered, Start: first.start, Line: lns[0].n}
+	i := 0
+	for i < len(lns) {
+		ind := indent(lns[i].s)
+		mk, ok := itemOf(lns[i].s, first)
+		if !ok {
+			break
+		}
+		it, n := doc.item(lns[i:], ind, mk)
+		bl.Kids = append(bl.Kids, it)
+		i += n
+		j := i
+		for j < len(lns) && isBlank(lns[j].s) {
+			j++
+		}
+		if j == i {
+			continue
+		}
+		if j == len(lns) {
+			break
+		}
+		if _, ok := itemOf(lns[j].s, first); !ok {
+			break
+		}
+		i = j
+	}
+	bl.EndLine = bl.Kids[len(bl.Kids)-1].EndLine
+	return bl, i
+}
+
+// itemOf returns the list marker of given line if it starts an item of the
+// same list as an item with given marker
+func itemOf(s string, first marker) (marker, bool) {
+	ind := indent(s)
+	if ind >= 4 {
+		return marker{}, false
+	}
+	s = dedent(s, ind)
+	if thematicBreak(s) {
+		return marker{}, false
+	}
+	mk, ok := listMarker(s)
+	if !ok || mk.ordered != first.ordered || mk.delim != first.delim {
+		return marker{}, false
+	}
+	return mk, true
+}
+
+// item returns an Item starting at the first of given lines, which has the
+// given list marker at given indent, and the number of lines in it
+func (doc *Document) item(lns []line, ind int, mk marker) (*Block, int) {
+	col := ind + mk.n + mk.sp
+	s0 := dedent(lns[0].s, ind)
+	t0 := dedent(s0[mk.n:], mk.sp)
+	inner := []line{{t0, lns[0].n}}
+	lazy := !mk.empty && !interrupts(t0)
+	i := 1
+	for ; i < len(lns); i++ {
+		s := lns[i].s
+		if isBlank(s) {
+			j := i
+			for j < len(lns) && isBlank(lns[j].s) {
+				j++
+			}
+			if j == len(lns) || indent(lns[j].s) < col || (mk.empty && i == 1) {
+				break
+			}
+			for ; i < j; i++ {
+				inner = append(inner, line{"", lns[i].n})
+			}
+			i--
+			lazy = false
+			continue
+		}
+		if indent(s) >= col {
+			t := dedent(s, col)
+			inner = append(inner, line{t, lns[i].n})
+			lazy = !interrupts(t)
+			continue
+		}
+		if lazy && !interrupts(s) {
+			inner = append(inner, line{strings.TrimLeft(s, " \t"), lns[i].n})
+			continue
+		}
+		break
+	}
+	return &Block{Kind: Item, Kids: doc.parseBlocks(inner), Line: lns[0].n, EndLine: lns[i-1].n + 1}, i
+}
+
+// table returns a Table starting at the first of given lines, which is its
+// header row followed by a delimiter row with given alignments, and the
+// number of lines in it
+func table(lns []line, aligns []Aligns) (*Block, int) {
+	nc := len(aligns)
+	bl := &Block{Kind: Table, Aligns: aligns, Line: lns[0].n}
+	row := func(s string) []string {
+		cells := splitRow(s)
+		if len(cells) > nc {
+			cells = cells[:nc]
+		}
+		for len(cells) < nc {
+			cells = append(cells, "")
+		}
+		return cells
+	}
+	bl.Rows = append(bl.Rows, row(lns[0].s))
+	i := 2
+	for ; i < len(lns); i++ {
+		s := lns[i].s
+		if isBlank(s) || interrupts(s) {
+			break
+		}
+		bl.Rows = append(bl.Rows, row(s))
+	}
+	bl.EndLine = lns[i-1].n + 1
+	return bl, i
+}
+
+// interrupts returns true if given line starts a block that ends a
+// paragraph, so it can not be a lazy continuation line of it
+func interrupts(s string) bool {
+	ind := indent(s)
+	if ind >= 4 {
+		return false
+	}
+	s = dedent(s, ind)
+	if s == "" {
+		return false
+	}
+	if _, _, _, ok := openFence(s); ok {
+		return true
+	}
+	if _, _, ok := atxHeading(s); ok {
+		return true
+	}
+	if thematicBreak(s) || s[0] == '>' {
+		return true
+	}
+	if mk, ok := listMarker(s); ok && !mk.empty {
+		return true
+	}
+	return false
+}
+
+// openFence returns the fence character and length, and the info string,
+// if given line opens a fenced code block
+func openFence(s string) (ch byte, n int, info string, ok bool) {
+	if len(s) < 3 || (s[0] != '`' && s[0] != '~') {
+		return 0, 0, "", false
+	}
+	ch = s[0]
+	n = runLen(s, 0, ch)
+	if n < 3 {
+		return 0, 0, "", false
+	}
+	info = strings.TrimSpace(s[n:])
+	if ch == '`' && strings.Contains(info, "`") {
+		return 0, 0, "", false
+	}
+	return ch, n, info, true
+}
+
+// closesFence returns true if given line closes a fenced code block opened
+// with given fence
+func closesFence(s string, ch byte, n int) bool {
+	if indent(s) >= 4 {
+		return false
+	}
+	s = strings.TrimSpace(s)
+	return len(s) >= n && runLen(s, 0, ch) == len(s)
+}
+
+// atxHeading returns the level and text of an ATX heading, if given line is one
+func atxHeading(s string) (level int, text string, ok bool) {
+	level = runLen(s, 0, '#')
+	if level == 0 || level > 6 || (level < len(s) && s[level] != ' ' && s[level] != '\t') {
+		return 0, "", false
+	}
+	text = strings.TrimSpace(s[level:])
+	t := strings.TrimRight(text, "#")
+	switch {
+	case t == "":
+		text = ""
+	case t[len(t)-1] == ' ' || t[len(t)-1] == '\t':
+		text = strings.TrimSpace(t)
+	}
+	return level, text, true
+}
+
+// setextLevel returns the level of the heading that given line underlines,
+// if it is a setext heading underline, or 0 otherwise
+func setextLevel(s string) int {
+	s = strings.TrimRight(s, " \t")
+	if s == "" || runLen(s, 0, s[0]) != len(s) {
+		return 0
+	}
+	switch s[0] {
+	case '=':
+		return 1
+	case '-':
+		return 2
+	}
+	return 0
+}
+
+// thematicBreak returns true if given line is a thematic break: three or
+// more of the same one of -, * or _, with optional spaces
+func thematicBreak(s string) bool {
+	if s == "" || (s[0] != '-' && s[0] != '*' && s[0] != '_') {
+		return false
+	}
+	n := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case s[0]:
+			n++
+		case ' ', '\t':
+		default:
+			return false
+		}
+	}
+	return n >= 3
+}
+
+// marker is a list item marker
+type marker struct {
+	ordered bool // whether it is an ordered list number
+	start   int  // number of an ordered marker
+	delim   byte // bullet character, or the . or ) after the number
+	n       int  // length of the marker
+	sp      int  // width of the white space after the marker that is part of it
+	empty   bool // whether the line has nothing after the marker
+}
+
+// listMarker returns the list item marker that given line starts with, if any
+func listMarker(s string) (mk marker, ok bool) {
+	i := 0
+	if s != "" && (s[0] == '-' || s[0] == '+' || s[0] == '*') {
+		mk.delim = s[0]
+		i = 1
+	} else {
+		for i < len(s) && i < 9 && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		if i == 0 || i >= len(s) || (s[i] != '.' && s[i] != ')') {
+			return mk, false
+		}
+		mk.ordered = true
+		mk.start, _ = strconv.Atoi(s[:i])
+		mk.delim = s[i]
+		i++
+	}
+	rest := s[i:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return mk, false
+	}
+	mk.n = i
+	mk.empty = isBlank(rest)
+	mk.sp = indent(rest)
+	if mk.empty || mk.sp > 4 {
+		mk.sp = 1
+	}
+	return mk, true
+}
+
+// splitRow splits a table row into the text of its cells
+func splitRow(s string) []string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "|")
+	if strings.HasSuffix(s, "|") && !strings.HasSuffix(s, `\|`) {
+		s = s[:len(s)-1]
+	}
+	var cells []string
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == '|':
+			sb.WriteByte('|')
+			i++
+		case s[i] == '|':
+			cells = append(cells, strings.TrimSpace(sb.String()))
+			sb.Reset()
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(sb.String()))
+}
+
+// delimRow returns the column alignments of a table delimiter row, or nil
+// if given line is not one
+func delimRow(s string) []Aligns {
+	if !strings.Contains(s, "|") || indent(s) >= 4 {
+		return nil
+	}
+	cells := splitRow(s)
+	aligns := make([]Aligns, len(cells))
+	for i, c := range cells {
+		l := strings.HasPrefix(c, ":")
+		r := strings.HasSuffix(c, ":")
+		d := strings.Trim(c, ":")
+		if d == "" || strings.Trim(d, "-") != "" {
+			return nil
+		}
+		switch {
+		case l && r:
+			aligns[i] = AlignCenter
+		case l:
+			aligns[i] = AlignLeft
+		case r:
+			aligns[i] = AlignRight
+		}
+	}
+	return aligns
+}
+
+// isBlank returns true if given line has only white space
+func isBlank(s string) bool {
+	return strings.Trim(s, " \t") == ""
+}
+
+// indent returns the width of the leading white space of given line, with
+// tabs to the next multiple of 4 columns
+func indent(s string) int {
+	w := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case ' ':
+			w++
+		case '\t':
+			w += 4 - w%4
+		default:
+			return w
+		}
+	}
+	return w
+}
+
+// dedent removes up to n columns of leading white space from given line,
+// replacing a tab that is only partly removed with spaces
+func dedent(s string, n int) string {
+	w := 0
+	for i := 0; i < len(s); i++ {
+		if w >= n {
+			return s[i:]
+		}
+		switch s[i] {
+		case ' ':
+			w++
+		case '\t':
+			tw := 4 - w%4
+			if w+tw > n {
+				return strings.Repeat(" ", w+tw-n) + s[i+1:]
+			}
+			w += tw
+		default:
+			return s[i:]
+		}
+	}
+	return ""
+}
+
+// runLen returns the length of the run of given character at s[i]
+func runLen(s string, i int, ch byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == ch {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,800 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package markdown
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/goki/ki/kit"
+)
+
+// InlineKinds are the kinds of Inline elements of the text of a block
+type InlineKinds int32
+
+const (
+	// Text is literal text
+	Text InlineKinds = iota
+
+	// Code is a code span
+	Code
+
+	// Emph is emphasized text
+	Emph
+
+	// Strong is strongly emphasized text
+	Strong
+
+	// Strike is struck-through text
+	Strike
+
+	// Link is a link
+	Link
+
+	// Image is an image, whose elements are its description
+	Image
+
+	// Break is a hard line break
+	Break
+
+	// SoftBreak is a soft line break, which is rendered as a space
+	SoftBreak
+
+	// RawHTML is a raw inline HTML tag or comment
+	RawHTML
+
+	// InlineKindsN is the number of kinds of inline elements
+	InlineKindsN
+)
+
+//go:generate stringer -type=InlineKinds
+
+var KiT_InlineKinds = kit.Enums.AddEnum(InlineKindsN, kit.NotBitFlag, nil)
+
+func (ev InlineKinds) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *InlineKinds) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+// Inline is an inline element of the text of a block
+type Inline struct {
+	Kind  InlineKinds `desc:"kind of element"`
+	Text  string      `desc:"literal text of a Text, Code or RawHTML element"`
+	URL   string      `desc:"destination of a Link, or source of an Image"`
+	Title string      `desc:"title of a Link or Image, if any"`
+	Kids  []*Inline   `desc:"elements within an Emph, Strong, Strike or Link, or the description of an Image"`
+}
+
+// ParseInline parses given text of a block into Inline elements, resolving
+// reference links with given definitions, which may be nil
+func ParseInline(text string, refs map[string]LinkRef) []*Inline {
+	return parseInlines(text, refs, false)
+}
+
+// parseInlines parses given text into Inline elements -- links are not
+// parsed within the text of another link
+func parseInlines(s string, refs map[string]LinkRef, inLink bool) []*Inline {
+	var out []*Inline
+	var txt strings.Builder
+	add := func(in *Inline) {
+		if txt.Len() > 0 {
+			out = append(out, &Inline{Kind: Text, Text: txt.String()})
+			txt.Reset()
+		}
+		if in != nil {
+			out = append(out, in)
+		}
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch c {
+		case '\\':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				add(&Inline{Kind: Break})
+				i = skipLead(s, i+2)
+				continue
+			}
+			if i+1 < len(s) && isPunct(s[i+1]) {
+				txt.WriteByte(s[i+1])
+				i += 2
+				continue
+			}
+		case '\n':
+			t := txt.String()
+			txt.Reset()
+			txt.WriteString(strings.TrimRight(t, " "))
+			if strings.HasSuffix(t, "  ") {
+				add(&Inline{Kind: Break})
+			} else {
+				add(&Inline{Kind: SoftBreak})
+			}
+			i = skipLead(s, i+1)
+			continue
+		case '`':
+			n := runLen(s, i, '`')
+			if end := codeEnd(s, i+n, n); end >= 0 {
+				add(&Inline{Kind: Code, Text: codeText(s[i+n : end])})
+				i = end + n
+				continue
+			}
+			txt.WriteString(s[i : i+n])
+			i += n
+			continue
+		case '!':
+			if i+1 < len(s) && s[i+1] == '[' {
+				if in, n := parseLink(s, i+1, refs); in != nil {
+					in.Kind = Image
+					add(in)
+					i += 1 + n
+					continue
+				}
+			}
+		case '[':
+			if !inLink {
+				if in, n := parseLink(s, i, refs); in != nil {
+					add(in)
+					i += n
+					continue
+				}
+			}
+		case '<':
+			if in, n := autoLink(s[i:]); in != nil {
+				add(in)
+				i += n
+				continue
+			}
+			if n := rawHTML(s[i:]); n > 0 {
+				add(&Inline{Kind: RawHTML, Text: s[i : i+n]})
+				i += n
+				continue
+			}
+		case '&':
+			if n := entity(s[i:]); n > 0 {
+				txt.WriteString(html.UnescapeString(s[i : i+n]))
+				i += n
+				continue
+			}
+		case '*', '_', '~':
+			lit, in, n := parseEmph(s, i, refs, inLink)
+			if in != nil {
+				txt.WriteString(s[i : i+lit])
+				add(in)
+				i += n
+				continue
+			}
+			n = runLen(s, i, c)
+			txt.WriteString(s[i : i+n])
+			i += n
+			continue
+		case 'h', 'w':
+			if !inLink && (i == 0 || !isAlnumByte(s[i-1])) {
+				if in, n := bareLink(s[i:]); in != nil {
+					add(in)
+					i += n
+					continue
+				}
+			}
+		}
+		txt.WriteByte(c)
+		i++
+	}
+	add(nil)
+	return out
+}
+
+// skipLead returns the index of the first character at or after i that is
+// not a space
+func skipLead(s string, i int) int {
+	for i < len(s) && s[i] == ' ' {
+		i++
+	}
+	return i
+}
+
+// codeEnd returns the start of the run of exactly n backticks that closes a
+// code span whose text starts at s[i], or -1 if there is none
+func codeEnd(s string, i, n int) int {
+	for i < len(s) {
+		j := strings.IndexByte(s[i:], '`')
+		if j < 0 {
+			return -1
+		}
+		i += j
+		m := runLen(s, i, '`')
+		if m == n {
+			return i
+		}
+		i += m
+	}
+	return -1
+}
+
+// codeText returns the text of a code span: line endings become spaces,
+// and a single space is stripped from each end if there is one at both
+func codeText(s string) string {
+	s = strings.Replace(s, "\n", " ", -1)
+	if len(s) >= 2 && s[0] == ' ' && s[len(s)-1] == ' ' && strings.Trim(s, " ") != "" {
+		s = s[1 : len(s)-1]
+	}
+	return s
+}
+
+// parseLink parses an inline or reference link whose text starts with the
+// [ at s[i], returning the link and its length, or nil if it is not one
+func parseLink(s string, i int, refs map[string]LinkRef) (*Inline, int) {
+	end := labelEnd(s, i)
+	if end < 0 {
+		return nil, 0
+	}
+	text := s[i+1 : end]
+	p := end + 1
+	if p < len(s) && s[p] == '(' {
+		q := skipSpace(s, p+1)
+		if dest, q2, ok := linkDest(s, q); ok {
+			r := skipSpace(s, q2)
+			title := ""
+			if r > q2 {
+				if t, r2, ok := linkTitle(s, r); ok {
+					title = t
+					r = skipSpace(s, r2)
+				}
+			}
+			if r < len(s) && s[r] == ')' {
+				return &Inline{Kind: Link, URL: dest, Title: title, Kids: parseInlines(text, refs, true)}, r + 1 - i
+			}
+		}
+	}
+	label := text
+	n := p - i
+	if p < len(s) && s[p] == '[' {
+		if e2 := labelEnd(s, p); e2 >= 0 {
+			if l := s[p+1 : e2]; strings.TrimSpace(l) != "" {
+				label = l
+			}
+			n = e2 + 1 - i
+		}
+	}
+	if ref, ok := refs[NormLabel(label)]; ok {
+		return &Inline{Kind: Link, URL: ref.URL, Title: ref.Title, Kids: parseInlines(text, refs, true)}, n
+	}
+	return nil, 0
+}
+
+// labelEnd returns the index of the ] that matches the [ at s[i], skipping
+// escapes and code spans, or -1 if there is none
+func labelEnd(s string, i int) int {
+	depth := 0
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '`':
+			n := runLen(s, j, '`')
+			if end := codeEnd(s, j+n, n); end >= 0 {
+				j = end + n - 1
+			} else {
+				j += n - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// skipSpace returns the index of the first character at or after i that is
+// not a space or tab, skipping at most one line ending
+func skipSpace(s string, i int) int {
+	nl := false
+	for i < len(s) {
+		switch s[i] {
+		case ' ', '\t':
+		case '\n':
+			if nl {
+				return i
+			}
+			nl = true
+		default:
+			return i
+		}
+		i++
+	}
+	return i
+}
+
+// linkDest parses a link destination starting at s[i], returning it and
+// the index after it
+func linkDest(s string, i int) (dest string, end int, ok bool) {
+	if i < len(s) && s[i] == '<' {
+		for j := i + 1; j < len(s); j++ {
+			switch s[j] {
+			case '\\':
+				j++
+			case '\n', '<':
+				return "", i, false
+			case '>':
+				return unescape(s[i+1 : j]), j + 1, true
+			}
+		}
+		return "", i, false
+	}
+	depth := 0
+	j := i
+	for ; j < len(s); j++ {
+		c := s[j]
+		if c == '\\' && j+1 < len(s) && isPunct(s[j+1]) {
+			j++
+			continue
+		}
+		if c <= ' ' {
+			break
+		}
+		if c == '(' {
+			depth++
+		} else if c == ')' {
+			if depth == 0 {
+				break
+			}
+			depth--
+		}
+	}
+	if depth != 0 {
+		return "", i, false
+	}
+	return unescape(s[i:j]), j, true
+}
+
+// linkTitle parses a quoted link title starting at s[i], returning it and
+// the index after it
+func linkTitle(s string, i int) (title string, end int, ok bool) {
+	if i >= len(s) {
+		return "", i, false
+	}
+	var cl byte
+	switch s[i] {
+	case '"', '\'':
+		cl = s[i]
+	case '(':
+		cl = ')'
+	default:
+		return "", i, false
+	}
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case cl:
+			return unescape(s[i+1 : j]), j + 1, true
+		}
+	}
+	return "", i, false
+}
+
+// parseRefDef parses a link reference definition at the start of given
+// paragraph text, returning its normalized label and destination, and the
+// rest of the text after it
+func parseRefDef(s string) (label string, ref LinkRef, rest string, ok bool) {
+	if !strings.HasPrefix(s, "[") {
+		return "", ref, s, false
+	}
+	end := labelEnd(s, 0)
+	if end < 0 || end+1 >= len(s) || s[end+1] != ':' {
+		return "", ref, s, false
+	}
+	label = NormLabel(s[1:end])
+	if label == "" {
+		return "", ref, s, false
+	}
+	p := skipSpace(s, end+2)
+	dest, p2, ok := linkDest(s, p)
+	if !ok || p2 == p {
+		return "", ref, s, false
+	}
+	ref.URL = dest
+	if q := skipSpace(s, p2); q > p2 {
+		if title, q2, ok := linkTitle(s, q); ok {
+			if r, eol := lineEnd(s, q2); eol {
+				ref.Title = title
+				return label, ref, s[r:], true
+			}
+		}
+	}
+	r, eol := lineEnd(s, p2)
+	if !eol {
+		return "", LinkRef{}, s, false
+	}
+	return label, ref, s[r:], true
+}
+
+// lineEnd returns the index after the end of the line at s[i], and
+// whether there is only white space before it
+func lineEnd(s string, i int) (int, bool) {
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case ' ', '\t':
+		case '\n':
+			return i + 1, true
+		default:
+			return i, false
+		}
+	}
+	return i, true
+}
+
+// autoLink parses an autolink, a URL or email address in angle brackets,
+// at the start of s, returning the link and its length, or nil if none
+func autoLink(s string) (*Inline, int) {
+	end := strings.IndexAny(s[1:], "> \t\n<") + 1
+	if end <= 1 || s[end] != '>' {
+		return nil, 0
+	}
+	u := s[1:end]
+	switch {
+	case isScheme(u):
+		return &Inline{Kind: Link, URL: u, Kids: []*Inline{{Kind: Text, Text: u}}}, end + 1
+	case isEmail(u):
+		return &Inline{Kind: Link, URL: "mailto:" + u, Kids: []*Inline{{Kind: Text, Text: u}}}, end + 1
+	}
+	return nil, 0
+}
+
+// isScheme returns true if given text starts with a URL scheme and colon
+func isScheme(u string) bool {
+	c := strings.IndexByte(u, ':')
+	if c < 2 || c > 32 {
+		return false
+	}
+	for i := 0; i < c; i++ {
+		b := u[i]
+		if !(isAlnumByte(b) || (i > 0 && (b == '+' || b == '.' || b == '-'))) || (i == 0 && !unicode.IsLetter(rune(b))) {
+			return false
+		}
+	}
+	return true
+}
+
+// isEmail returns true if given text is a plausible email address
+func isEmail(u string) bool {
+	at := strings.IndexByte(u, '@')
+	return at > 0 && at < len(u)-1 && strings.Count(u, "@") == 1 && strings.Contains(u[at:], ".")
+}
+
+// bareLink parses a bare http, https or www URL at the start of s,
+// returning the link and its length, or nil if none
+func bareLink(s string) (*Inline, int) {
+	var pre string
+	for _, p := range []string{"https://", "http://", "www."} {
+		if strings.HasPrefix(s, p) {
+			pre = p
+			break
+		}
+	}
+	if pre == "" {
+		return nil, 0
+	}
+	end := strings.IndexAny(s, " \t\n<")
+	if end < 0 {
+		end = len(s)
+	}
+	end = len(strings.TrimRight(s[:end], ".,:;!?*_~'\""))
+	if strings.HasSuffix(s[:end], ")") && strings.Count(s[:end], "(") < strings.Count(s[:end], ")") {
+		end--
+	}
+	if end <= len(pre) {
+		return nil, 0
+	}
+	u := s[:end]
+	url := u
+	if pre == "www." {
+		url = "http://" + u
+	}
+	return &Inline{Kind: Link, URL: url, Kids: []*Inline{{Kind: Text, Text: u}}}, end
+}
+
+// rawHTML returns the length of a raw HTML tag or comment at the start of
+// s, or 0 if there is none
+func rawHTML(s string) int {
+	if strings.HasPrefix(s, "<!--") {
+		if end := strings.Index(s[4:], "-->"); end >= 0 {
+			return end + 7
+		}
+		return 0
+	}
+	i := 1
+	if i < len(s) && s[i] == '/' {
+		i++
+	}
+	st := i
+	for i < len(s) && (isAlnumByte(s[i]) || (i > st && s[i] == '-')) {
+		i++
+	}
+	if i == st || !unicode.IsLetter(rune(s[st])) {
+		return 0
+	}
+	q := byte(0)
+	for ; i < len(s); i++ {
+		switch c := s[i]; {
+		case q != 0:
+			if c == q {
+				q = 0
+			}
+		case c == '"' || c == '\'':
+			q = c
+		case c == '<':
+			return 0
+		case c == '>':
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// entity returns the length of an HTML entity or numeric character
+// reference at the start of s, or 0 if there is none
+func entity(s string) int {
+	end := strings.IndexByte(s, ';')
+	if end < 2 || end > 32 {
+		return 0
+	}
+	for i := 1; i < end; i++ {
+		if !isAlnumByte(s[i]) && !(i == 1 && s[i] == '#') {
+			return 0
+		}
+	}
+	if html.UnescapeString(s[:end+1]) == s[:end+1] {
+		return 0
+	}
+	return end + 1
+}
+
+// parseEmph parses emphasis, strong emphasis or strikethrough opened by
+// the delimiter run at s[i], returning the number of characters of the run
+// that are literal text before the opener, the element, and the length of
+// it all, or a nil element if the run does not open one
+func parseEmph(s string, i int, refs map[string]LinkRef, inLink bool) (int, *Inline, int) {
+	c := s[i]
+	n := runLen(s, i, c)
+	if !leftFlanking(s, i, n) {
+		return 0, nil, 0
+	}
+	if c == '_' && rightFlanking(s, i, n) && i > 0 && !isPunct(s[i-1]) {
+		return 0, nil, 0
+	}
+	if c == '~' {
+		if n > 2 {
+			return 0, nil, 0
+		}
+		if j := closer(s, i+n, c, n); j >= 0 {
+			return 0, &Inline{Kind: Strike, Kids: parseInlines(s[i+n:j], refs, inLink)}, j + n - i
+		}
+		return 0, nil, 0
+	}
+	for k := 3; k >= 1; k-- {
+		if n < k {
+			continue
+		}
+		j := closer(s, i+n, c, k)
+		if j < 0 {
+			continue
+		}
+		kids := parseInlines(s[i+n:j], refs, inLink)
+		var in *Inline
+		switch k {
+		case 3:
+			in = &Inline{Kind: Emph, Kids: []*Inline{{Kind: Strong, Kids: kids}}}
+		case 2:
+			in = &Inline{Kind: Strong, Kids: kids}
+		default:
+			in = &Inline{Kind: Emph, Kids: kids}
+		}
+		return n - k, in, j + k - i
+	}
+	return 0, nil, 0
+}
+
+// closer returns the index of the delimiter run of given character, of at
+// least k characters (exactly k for strikethrough), that closes emphasis
+// whose text starts at s[i], or -1 if there is none -- runs that open
+// nested emphasis are skipped along with their text, up to the run that
+// closes them
+func closer(s string, i int, c byte, k int) int {
+	nest := 0
+	for j := i; j < len(s); {
+		switch s[j] {
+		case '\\':
+			j += 2
+			continue
+		case '`':
+			n := runLen(s, j, '`')
+			if end := codeEnd(s, j+n, n); end >= 0 {
+				j = end + n
+			} else {
+				j += n
+			}
+			continue
+		case c:
+			m := runLen(s, j, c)
+			lf := leftFlanking(s, j, m)
+			rf := rightFlanking(s, j, m)
+			if c == '_' && lf && rf && j+m < len(s) && !isPunct(s[j+m]) {
+				rf = false
+			}
+			switch {
+			case rf && nest > 0:
+				nest--
+			case rf && (m == k || (m > k && c != '~')):
+				return j
+			case lf && c != '~':
+				nest++
+			}
+			j += m
+			continue
+		}
+		j++
+	}
+	return -1
+}
+
+// leftFlanking returns true if the delimiter run of length n at s[i] is
+// left-flanking, so it can open emphasis
+func leftFlanking(s string, i, n int) bool {
+	if i+n >= len(s) {
+		return false
+	}
+	next, _ := utf8.DecodeRuneInString(s[i+n:])
+	if unicode.IsSpace(next) {
+		return false
+	}
+	if !isPunctRune(next) {
+		return true
+	}
+	if i == 0 {
+		return true
+	}
+	prev, _ := utf8.DecodeLastRuneInString(s[:i])
+	return unicode.IsSpace(prev) || isPunctRune(prev)
+}
+
+// rightFlanking returns true if the delimiter run of length n at s[i] is
+// right-flanking, so it can close emphasis
+func rightFlanking(s string, i, n int) bool {
+	if i == 0 {
+		return false
+	}
+	prev, _ := utf8.DecodeLastRuneInString(s[:i])
+	if unicode.IsSpace(prev) {
+		return false
+	}
+	if !isPunctRune(prev) {
+		return true
+	}
+	if i+n >= len(s) {
+		return true
+	}
+	next, _ := utf8.DecodeRuneInString(s[i+n:])
+	return unicode.IsSpace(next) || isPunctRune(next)
+}
+
+// unescape removes backslash escapes and decodes entities in given text
+func unescape(s string) string {
+	if !strings.ContainsAny(s, `\&`) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && isPunct(s[i+1]):
+			i++
+			sb.WriteByte(s[i])
+		case s[i] == '&':
+			if n := entity(s[i:]); n > 0 {
+				sb.WriteString(html.UnescapeString(s[i : i+n]))
+				i += n - 1
+				continue
+			}
+			sb.WriteByte(s[i])
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
+// isPunct returns true if given byte is ASCII punctuation
+func isPunct(c byte) bool {
+	return c < utf8.RuneSelf && c > ' ' && c != 127 && !isAlnumByte(c)
+}
+
+// isPunctRune returns true if given rune is punctuation or a symbol
+func isPunctRune(r rune) bool {
+	if r < utf8.RuneSelf {
+		return isPunct(byte(r))
+	}
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// isAlnumByte returns true if given byte is an ASCII letter or digit
+func isAlnumByte(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// isAlnumRune returns true if given rune is a letter or digit
+func isAlnumRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// HTML renders given Inline elements as the simple inline HTML understood
+// by gi.TextRender -- Images are rendered as their description
+func HTML(ins []*Inline) string {
+	var sb strings.Builder
+	writeHTML(&sb, ins)
+	return sb.String()
+}
+
+// writeHTML writes the HTML of given elements to given builder
+func writeHTML(sb *strings.Builder, ins []*Inline) {
+	for _, in := range ins {
+		switch in.Kind {
+		case Text:
+			sb.WriteString(html.EscapeString(in.Text))
+		case Code:
+			sb.WriteString("<code>" + html.EscapeString(in.Text) + "</code>")
+		case Emph:
+			sb.WriteString("<em>")
+			writeHTML(sb, in.Kids)
+			sb.WriteString("</em>")
+		case Strong:
+			sb.WriteString("<strong>")
+			writeHTML(sb, in.Kids)
+			sb.WriteString("</strong>")
+		case Strike:
+			sb.WriteString("<del>")
+			writeHTML(sb, in.Kids)
+			sb.WriteString("</del>")
+		case Link:
+			sb.WriteString(`<a href="` + html.EscapeString(in.URL) + `">`)
+			writeHTML(sb, in.Kids)
+			sb.WriteString("</a>")
+		case Image:
+			sb.WriteString(html.EscapeString(PlainText(in.Kids)))
+		case Break:
+			sb.WriteString("<br>")
+		case SoftBreak:
+			sb.WriteString(" ")
+		case RawHTML:
+			sb.WriteString(in.Text)
+		}
+	}
+}
+
+// PlainText returns the plain text of given Inline elements, without any
+// markup
+func PlainText(ins []*Inline) string {
+	var sb strings.Builder
+	writePlain(&sb, ins)
+	return sb.String()
+}
+
+// writePlain writes the plain text of given elements to given builder
+func writePlain(sb *strings.Builder, ins []*Inline) {
+	for _, in := range ins {
+		switch in.Kind {
+		case Text, Code:
+			sb.WriteString(in.Text)
+		case Break:
+			sb.WriteString("\n")
+		case SoftBreak:
+			sb.WriteString(" ")
+		case RawHTML:
+		default:
+			writePlain(sb, in.Kids)
+		}
+	}
+}
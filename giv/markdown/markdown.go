@@ -0,0 +1,204 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package markdown parses Markdown documents, in the CommonMark dialect with
+// the GitHub extensions for tables, strikethrough and bare URL links, into a
+// tree of Blocks.  The text of blocks is parsed into Inline elements, which
+// render as the simple inline HTML understood by gi.TextRender, so that a
+// document can be laid out with standard widgets.
+package markdown
+
+import (
+	"strings"
+
+	"github.com/goki/ki/kit"
+)
+
+// Kinds are the kinds of Blocks in a Markdown document
+type Kinds int32
+
+const (
+	// Paragraph is a paragraph of text
+	Paragraph Kinds = iota
+
+	// Heading is an ATX (#) or setext (underlined) heading
+	Heading
+
+	// Rule is a thematic break, drawn as a horizontal rule
+	Rule
+
+	// CodeBlock is a fenced or indented block of code
+	CodeBlock
+
+	// Quote is a block quote, containing other blocks
+	Quote
+
+	// List is a bullet or ordered list, containing Items
+	List
+
+	// Item is an item of a List, containing other blocks
+	Item
+
+	// Table is a table of cells
+	Table
+
+	// KindsN is the number of kinds of blocks
+	KindsN
+)
+
+//go:generate stringer -type=Kinds
+
+var KiT_Kinds = kit.Enums.AddEnum(KindsN, kit.NotBitFlag, nil)
+
+func (ev Kinds) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *Kinds) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+// Aligns are the alignments of the columns of a Table
+type Aligns int32
+
+const (
+	// AlignDefault is the default alignment, with no colons in the delimiter row
+	AlignDefault Aligns = iota
+
+	// AlignLeft is left alignment, with a colon on the left
+	AlignLeft
+
+	// AlignCenter is center alignment, with colons on both sides
+	AlignCenter
+
+	// AlignRight is right alignment, with a colon on the right
+	AlignRight
+
+	// AlignsN is the number of alignments
+	AlignsN
+)
+
+//go:generate stringer -type=Aligns
+
+var KiT_Aligns = kit.Enums.AddEnum(AlignsN, kit.NotBitFlag, nil)
+
+func (ev Aligns) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *Aligns) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+// Block is a block of a Markdown document: either a leaf block of text, or
+// a container of other blocks.
+type Block struct {
+	Kind    Kinds      `desc:"kind of block"`
+	Line    int        `desc:"line of the source where the block starts, 0-based"`
+	EndLine int        `desc:"line of the source after the last line of the block"`
+	Level   int        `desc:"level of a Heading, 1-6"`
+	Text    string     `desc:"text of a Paragraph or Heading, to be parsed into Inline elements, or the verbatim text of a CodeBlock"`
+	Info    string     `desc:"info string of a fenced CodeBlock, the first word of which is its language"`
+	Ordered bool       `desc:"whether a List is ordered, or has bullets"`
+	Start   int        `desc:"number of the first Item of an ordered List"`
+	Aligns  []Aligns   `desc:"alignments of the columns of a Table"`
+	Rows    [][]string `desc:"text of the cells of the rows of a Table, to be parsed into Inline elements -- the first row is the header"`
+	Kids    []*Block   `desc:"blocks in a Quote, List or Item"`
+}
+
+// Lang returns the language of a fenced CodeBlock, from its info string
+func (bl *Block) Lang() string {
+	if fs := strings.Fields(bl.Info); len(fs) > 0 {
+		return strings.ToLower(fs[0])
+	}
+	return ""
+}
+
+// Walk calls fun for given block and then recursively for the blocks that
+// it contains, stopping if fun returns false
+func (bl *Block) Walk(fun func(bl *Block) bool) bool {
+	if !fun(bl) {
+		return false
+	}
+	for _, kb := range bl.Kids {
+		if !kb.Walk(fun) {
+			return false
+		}
+	}
+	return true
+}
+
+// LinkRef is the destination of a link reference definition
+type LinkRef struct {
+	URL   string `desc:"destination of the link"`
+	Title string `desc:"title of the link, if any"`
+}
+
+// Document is a parsed Markdown document
+type Document struct {
+	Blocks []*Block           `desc:"top-level blocks of the document"`
+	Refs   map[string]LinkRef `desc:"link reference definitions, by normalized label"`
+}
+
+// Parse parses given Markdown source into a Document
+func Parse(src []byte) *Document {
+	doc := &Document{Refs: make(map[string]LinkRef)}
+	txt := strings.Replace(string(src), "\r\n", "\n", -1)
+	txt = strings.TrimSuffix(txt, "\n")
+	if txt == "" {
+		return doc
+	}
+	sl := strings.Split(txt, "\n")
+	lns := make([]line, len(sl))
+	for i, s := range sl {
+		lns[i] = line{s: s, n: i}
+	}
+	doc.Blocks = doc.parseBlocks(lns)
+	return doc
+}
+
+// Inlines parses given text of a block of the document into Inline
+// elements, resolving reference links with the document's definitions
+func (doc *Document) Inlines(text string) []*Inline {
+	return ParseInline(text, doc.Refs)
+}
+
+// Walk calls fun for each block of the document, in document order,
+// stopping if fun returns false
+func (doc *Document) Walk(fun func(bl *Block) bool) {
+	for _, bl := range doc.Blocks {
+		if !bl.Walk(fun) {
+			return
+		}
+	}
+}
+
+// Headings returns the headings of the document, in document order
+func (doc *Document) Headings() []*Block {
+	var hds []*Block
+	doc.Walk(func(bl *Block) bool {
+		if bl.Kind == Heading {
+			hds = append(hds, bl)
+		}
+		return true
+	})
+	return hds
+}
+
+// HeadingID returns the anchor ID of a Heading, which links to it with a #
+// fragment: the GitHub-style slug of its plain text
+func (doc *Document) HeadingID(bl *Block) string {
+	return Slug(PlainText(doc.Inlines(bl.Text)))
+}
+
+// Slug returns the GitHub-style anchor slug of given text: lower case, with
+// spaces replaced by dashes and other punctuation removed
+func Slug(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		switch {
+		case r == ' ':
+			sb.WriteRune('-')
+		case r == '-' || r == '_' || isAlnumRune(r):
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// NormLabel normalizes a link label for matching reference links to their
+// definitions: case is folded and runs of white space collapsed
+func NormLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package markdown
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// dump returns a compact description of given blocks, with the inline
+// text of leaf blocks rendered as HTML
+func dump(doc *Document, blks []*Block) string {
+	var sb strings.Builder
+	for _, bl := range blks {
+		switch bl.Kind {
+		case Paragraph:
+			sb.WriteString("p(" + HTML(doc.Inlines(bl.Text)) + ")")
+		case Heading:
+			sb.WriteString(fmt.Sprintf("h%d(%s)", bl.Level, HTML(doc.Inlines(bl.Text))))
+		case Rule:
+			sb.WriteString("hr")
+		case CodeBlock:
+			sb.WriteString(fmt.Sprintf("code[%s](%q)", bl.Lang(), bl.Text))
+		case Quote:
+			sb.WriteString("quote(" + dump(doc, bl.Kids) + ")")
+		case List:
+			if bl.Ordered {
+				sb.WriteString(fmt.Sprintf("ol%d(", bl.Start))
+			} else {
+				sb.WriteString("ul(")
+			}
+			sb.WriteString(dump(doc, bl.Kids) + ")")
+		case Item:
+			sb.WriteString("li(" + dump(doc, bl.Kids) + ")")
+		case Table:
+			sb.WriteString(fmt.Sprintf("table%v%q", bl.Aligns, bl.Rows))
+		}
+	}
+	return sb.String()
+}
+
+func TestParseBlocks(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"# Title\n\nSome *text*\nmore.\n", "h1(Title)p(Some <em>text</em> more.)"},
+		{"Title\n=====\nSub\n---\n## Closed ##\n", "h1(Title)h2(Sub)h2(Closed)"},
+		{"a\n\n---\n\n* * *\n", "p(a)hrhr"},
+		{"```go\nfunc f() {\n\treturn\n}\n```\nafter\n", `code[go]("func f() {\n\treturn\n}")p(after)`},
+		{"    indented\n\n    code\n", `code[]("indented\n\ncode")`},
+		{"> quoted\nlazy\n> > nested\n", "quote(p(quoted lazy)quote(p(nested)))"},
+		{"- one\n- two\n  continued\n\n  para\n- three\n", "ul(li(p(one))li(p(two continued)p(para))li(p(three)))"},
+		{"3. a\n4. b\n\n* c\n  1) d\n", "ol3(li(p(a))li(p(b)))ul(li(p(c)ol1(li(p(d)))))"},
+		{"text\n2. not a list\n", "p(text 2. not a list)"},
+		{"| a | b | c |\n|:--|:-:|--:|\n| 1 | 2 \\| x |\n", `table[AlignLeft AlignCenter AlignRight][["a" "b" "c"] ["1" "2 | x" ""]]`},
+		{"[ref]: /url \"T\"\n\nsee [it][ref] and [ref]\n", `p(see <a href="/url">it</a> and <a href="/url">ref</a>)`},
+	}
+	for _, tt := range tests {
+		doc := Parse([]byte(tt.src))
+		if got := dump(doc, doc.Blocks); got != tt.want {
+			t.Errorf("Parse(%q):\n got %s\nwant %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestParseLines(t *testing.T) {
+	doc := Parse([]byte("# A\n\npara\ngraph\n\n- x\n- y\n\n```\ncode\n```\n"))
+	var got []string
+	doc.Walk(func(bl *Block) bool {
+		got = append(got, fmt.Sprintf("%v:%d-%d", bl.Kind, bl.Line, bl.EndLine))
+		return true
+	})
+	want := "Heading:0-1 Paragraph:2-4 List:5-7 Item:5-6 Paragraph:5-6 Item:6-7 Paragraph:6-7 CodeBlock:8-11"
+	if s := strings.Join(got, " "); s != want {
+		t.Errorf("lines:\n got %s\nwant %s", s, want)
+	}
+}
+
+func TestInlineHTML(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"*em* **strong** ***both*** _u_ __uu__", "<em>em</em> <strong>strong</strong> <em><strong>both</strong></em> <em>u</em> <strong>uu</strong>"},
+		{"*a **b** c*", "<em>a <strong>b</strong> c</em>"},
+		{"snake_case_name and 2*3*4", "snake_case_name and 2<em>3</em>4"},
+		{"* not em *", "* not em *"},
+		{"~~gone~~", "<del>gone</del>"},
+		{"`a < b` and `` x`y ``", "<code>a &lt; b</code> and <code>x`y</code>"},
+		{`\*lit\* &amp; &copy; 1 < 2`, "*lit* &amp; © 1 &lt; 2"},
+		{"[link *text*](http://x.org/a_b \"title\")", `<a href="http://x.org/a_b">link <em>text</em></a>`},
+		{"<https://goki.dev> and <me@x.org>", `<a href="https://goki.dev">https://goki.dev</a> and <a href="mailto:me@x.org">me@x.org</a>`},
+		{"see https://x.org/p. and www.y.com", `see <a href="https://x.org/p">https://x.org/p</a>. and <a href="http://www.y.com">www.y.com</a>`},
+		{"![alt *text*](img.png)", "alt text"},
+		{"line  \nbreak\\\nand soft\nbreak", "line<br>break<br>and soft break"},
+		{"raw <b>html</b>", "raw <b>html</b>"},
+	}
+	for _, tt := range tests {
+		if got := HTML(ParseInline(tt.src, nil)); got != tt.want {
+			t.Errorf("HTML(%q):\n got %s\nwant %s", tt.src, got, tt.want)
+		}
+	}
+	ins := ParseInline("![alt](a/b.png \"T\")", nil)
+	if len(ins) != 1 || ins[0].Kind != Image || ins[0].URL != "a/b.png" || ins[0].Title != "T" {
+		t.Errorf("image: %+v", ins)
+	}
+}
+
+func TestHeadingID(t *testing.T) {
+	doc := Parse([]byte("## Getting `Started`, *Now*!\n"))
+	if id := doc.HeadingID(doc.Headings()[0]); id != "getting-started-now" {
+		t.Errorf("id: %q", id)
+	}
+}
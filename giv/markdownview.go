@@ -0,0 +1,637 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/markdown"
+	"github.com/goki/gi/giv/vfs"
+	"github.com/goki/gi/units"
+	"github.com/goki/ki/ki"
+	"github.com/goki/ki/kit"
+	"github.com/goki/mat32"
+)
+
+// MarkdownViewUpdateMSec is the number of milliseconds to wait after an
+// edit of the buffer that a MarkdownView is previewing before updating it,
+// so that a burst of typing results in one update
+var MarkdownViewUpdateMSec = 500
+
+// MarkdownImageMaxSize is the maximum size, in pixels, of the larger
+// dimension of the images shown in a MarkdownView -- larger ones are
+// scaled down
+var MarkdownImageMaxSize = 800
+
+// MarkdownBullets are the bullets of the items of unordered lists in a
+// MarkdownView, by the depth of the list
+var MarkdownBullets = []string{"•", "◦", "▪"}
+
+// MarkdownCSS is the style sheet of a MarkdownView, which styles its
+// widgets by their class: h1 .. h6 for headings, para for paragraphs,
+// quote for block quotes, marker for list item markers, code for code
+// blocks, th and td for table cells, and image-alt for the descriptions
+// of images that can not be shown
+var MarkdownCSS = ki.Props{
+	".h1":     ki.Props{"font-size": "xx-large", "font-weight": "bold", "margin": units.NewEx(0.5)},
+	".h2":     ki.Props{"font-size": "x-large", "font-weight": "bold", "margin": units.NewEx(0.4)},
+	".h3":     ki.Props{"font-size": "large", "font-weight": "bold", "margin": units.NewEx(0.3)},
+	".h4":     ki.Props{"font-weight": "bold"},
+	".h5":     ki.Props{"font-weight": "bold"},
+	".h6":     ki.Props{"font-weight": "bold", "font-size": "small"},
+	".para":   ki.Props{"margin": units.NewEx(0.3)},
+	".marker": ki.Props{"min-width": units.NewCh(2), "text-align": gi.AlignRight},
+	".quote": ki.Props{
+		"border-width":     units.NewPx(0),
+		"background-color": &gi.Prefs.Colors.Control,
+		"padding":          units.NewEx(0.5),
+		"margin":           units.NewEx(0.5),
+	},
+	".code": ki.Props{
+		"max-width": -1,
+		"margin":    units.NewEx(0.5),
+	},
+	".th": ki.Props{"font-weight": "bold", "white-space": gi.WhiteSpacePre},
+	".td": ki.Props{"white-space": gi.WhiteSpacePre},
+	".image-alt": ki.Props{
+		"font-style": "italic",
+	},
+}
+
+///////////////////////////////////////////////////////////////////////////
+//   MarkdownView
+
+// MarkdownView shows a Markdown document, laid out as a column of widgets:
+// word-wrapped Labels for headings and paragraphs, with the links in them
+// opened through OpenLink, inactive TextViews for code blocks, highlighted
+// according to their language, grids of Labels for tables, and Bitmaps
+// for images.  It can show a file or Markdown source, or follow a TextBuf
+// as a live preview of it while it is being edited.
+type MarkdownView struct {
+	gi.Layout
+	Filename  gi.FileName          `desc:"file that the document was opened from -- relative links and images are resolved against its directory"`
+	FS        vfs.FS               `json:"-" xml:"-" desc:"file system that the file and the images are in -- the OS if nil"`
+	Src       []byte               `json:"-" xml:"-" desc:"Markdown source being shown, if not from a buffer"`
+	Buf       *TextBuf             `json:"-" xml:"-" desc:"buffer being previewed, if any -- the view is updated as it is edited"`
+	View      *TextView            `json:"-" xml:"-" desc:"view of the buffer being previewed, if any -- the preview scrolls to follow its cursor"`
+	Doc       *markdown.Document   `json:"-" xml:"-" desc:"parsed document being shown"`
+	HiStyle   gi.HiStyleName       `desc:"highlighting style for code blocks -- the default style if empty"`
+	Anchors   map[string]gi.Node2D `json:"-" xml:"-" desc:"heading widgets by their anchor ID, to which # links go"`
+	LinkSig   ki.Signal            `copy:"-" json:"-" xml:"-" view:"-" desc:"signal for clicking on a link that is not to a heading of the document, or to another Markdown file that is opened in the view -- data is the URL, as a file:// URL for a local file -- if nobody is receiving the signal, the link is opened with gi.URLHandler"`
+	updtTimer *time.Timer
+	updtMu    sync.Mutex
+}
+
+var KiT_MarkdownView = kit.Types.AddType(&MarkdownView{}, MarkdownViewProps)
+
+// AddNewMarkdownView adds a new markdown view to given parent node, with
+// given name.
+func AddNewMarkdownView(parent ki.Ki, name string) *MarkdownView {
+	return parent.AddNewChild(KiT_MarkdownView, name).(*MarkdownView)
+}
+
+// MarkdownViewProps are style properties for MarkdownView
+var MarkdownViewProps = ki.Props{
+	"EnumType:Flag":    gi.KiT_NodeFlags,
+	"max-width":        -1,
+	"max-height":       -1,
+	"padding":          units.NewEx(1),
+	"background-color": &gi.Prefs.Colors.Background,
+	"color":            &gi.Prefs.Colors.Font,
+}
+
+func (mv *MarkdownView) Disconnect() {
+	mv.Layout.Disconnect()
+	mv.LinkSig.DisconnectAll()
+	if mv.View != nil {
+		mv.View.TextViewSig.Disconnect(mv.This())
+	}
+	if mv.Buf != nil {
+		mv.Buf.TextBufSig.Disconnect(mv.This())
+	}
+}
+
+// SetMarkdown shows given Markdown source, with relative links and images
+// resolved against the current directory, or that of the Filename
+func (mv *MarkdownView) SetMarkdown(src []byte) {
+	mv.SetBuf(nil)
+	mv.Src = src
+	mv.UpdateView()
+}
+
+// OpenFile opens and shows given Markdown file
+func (mv *MarkdownView) OpenFile(fname gi.FileName) error {
+	src, err := vfs.ReadFile(mv.FileSys(), string(fname))
+	if err != nil {
+		return err
+	}
+	mv.Filename = fname
+	mv.SetMarkdown(src)
+	return nil
+}
+
+// FileSys returns the file system of the file, which is the OS if FS is nil
+func (mv *MarkdownView) FileSys() vfs.FS {
+	return vfs.OrOS(mv.FS)
+}
+
+// SetBuf sets the buffer to preview, which is then followed as it is
+// edited -- nil to stop previewing
+func (mv *MarkdownView) SetBuf(tb *TextBuf) {
+	if mv.Buf != nil && mv.Buf != tb {
+		mv.Buf.TextBufSig.Disconnect(mv.This())
+	}
+	if tb != nil && mv.Buf != tb {
+		tb.TextBufSig.Connect(mv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			mvv := recv.Embed(KiT_MarkdownView).(*MarkdownView)
+			switch TextBufSignals(sig) {
+			case TextBufInsert, TextBufDelete:
+				mvv.UpdateLater()
+			case TextBufNew:
+				mvv.Filename = mvv.Buf.Filename
+				mvv.FS = mvv.Buf.FS
+				mvv.UpdateView()
+			case TextBufClosed:
+				mvv.SetBuf(nil)
+			}
+		})
+	}
+	mv.Buf = tb
+	if tb == nil {
+		mv.SetTextView(nil)
+		return
+	}
+	mv.Filename = tb.Filename
+	mv.FS = tb.FS
+	mv.Src = nil
+	mv.UpdateView()
+}
+
+// SetTextView sets the view of the buffer being previewed, whose cursor
+// the preview scrolls to follow
+func (mv *MarkdownView) SetTextView(tv *TextView) {
+	if mv.View != nil && mv.View != tv {
+		mv.View.TextViewSig.Disconnect(mv.This())
+	}
+	if tv != nil && mv.View != tv {
+		tv.TextViewSig.Connect(mv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			if TextViewSignals(sig) == TextViewCursorMoved {
+				mvv := recv.Embed(KiT_MarkdownView).(*MarkdownView)
+				mvv.ScrollToLine(send.Embed(KiT_TextView).(*TextView).CursorPos.Ln)
+			}
+		})
+	}
+	mv.View = tv
+}
+
+// UpdateLater updates the view after MarkdownViewUpdateMSec, combining
+// the edits made in the meantime into one update, on the event goroutine of
+// the window (see gi.PostUIFunc) -- can be called from any goroutine
+func (mv *MarkdownView) UpdateLater() {
+	mv.updtMu.Lock()
+	defer mv.updtMu.Unlock()
+	if mv.updtTimer != nil {
+		return
+	}
+	mv.updtTimer = gi.PostUIFuncAfter(mv.ParentWindow(), time.Duration(MarkdownViewUpdateMSec)*time.Millisecond, func() {
+		mv.updtMu.Lock()
+		mv.updtTimer = nil
+		mv.updtMu.Unlock()
+		if mv.This() != nil {
+			mv.UpdateView()
+		}
+	})
+}
+
+// UpdateView parses the Markdown of the buffer, or the source, and
+// rebuilds the widgets that show it
+func (mv *MarkdownView) UpdateView() {
+	var src []byte
+	if mv.Buf != nil {
+		src = mv.Buf.LinesToBytesCopy()
+	} else {
+		src = mv.Src
+	}
+	mv.Doc = markdown.Parse(src)
+	mv.ConfigView()
+}
+
+// ConfigView rebuilds the widgets that show the current document
+func (mv *MarkdownView) ConfigView() {
+	wupdt := mv.TopUpdateStart()
+	defer mv.TopUpdateEnd(wupdt)
+	updt := mv.UpdateStart()
+	defer mv.UpdateEnd(updt)
+	mv.Lay = gi.LayoutVert
+	mv.CSS = MarkdownCSS
+	mv.DeleteChildren(ki.DestroyKids)
+	mv.Anchors = make(map[string]gi.Node2D)
+	if mv.Doc != nil {
+		mv.configBlocks(mv.This(), mv.Doc.Blocks, 0)
+	}
+	mv.SetFullReRender()
+}
+
+// configBlocks adds the widgets for given blocks to given parent, one for
+// each block -- depth is the number of lists that they are in
+func (mv *MarkdownView) configBlocks(par ki.Ki, blks []*markdown.Block, depth int) {
+	for i, bl := range blks {
+		nm := "blk-" + strconv.Itoa(i)
+		switch bl.Kind {
+		case markdown.Paragraph:
+			mv.configText(par, nm, bl.Text, "para")
+		case markdown.Heading:
+			lb := mv.addLabel(par, nm, markdown.HTML(mv.Doc.Inlines(bl.Text)), fmt.Sprintf("h%d", bl.Level))
+			id := mv.Doc.HeadingID(bl)
+			if _, has := mv.Anchors[id]; !has {
+				mv.Anchors[id] = lb
+			}
+		case markdown.Rule:
+			gi.AddNewSeparator(par, nm, true)
+		case markdown.CodeBlock:
+			mv.addCode(par, nm, bl)
+		case markdown.Quote:
+			fr := gi.AddNewFrame(par, nm, gi.LayoutVert)
+			fr.Class = "quote"
+			fr.SetStretchMaxWidth()
+			mv.configBlocks(fr, bl.Kids, depth)
+		case markdown.List:
+			mv.configList(par, nm, bl, depth)
+		case markdown.Table:
+			mv.configTable(par, nm, bl)
+		}
+	}
+}
+
+// configList adds a layout with the items of given list to given parent
+func (mv *MarkdownView) configList(par ki.Ki, nm string, bl *markdown.Block, depth int) {
+	ly := gi.AddNewLayout(par, nm, gi.LayoutVert)
+	ly.SetStretchMaxWidth()
+	for i, it := range bl.Kids {
+		mk := MarkdownBullets[depth%len(MarkdownBullets)]
+		if bl.Ordered {
+			mk = strconv.Itoa(bl.Start+i) + "."
+		}
+		row := gi.AddNewLayout(ly, "item-"+strconv.Itoa(i), gi.LayoutHoriz)
+		row.SetStretchMaxWidth()
+		gi.AddNewLabel(row, "marker", mk).Class = "marker"
+		body := gi.AddNewLayout(row, "body", gi.LayoutVert)
+		body.SetStretchMaxWidth()
+		mv.configBlocks(body, it.Kids, depth+1)
+	}
+}
+
+// configTable adds a grid with the cells of given table to given parent
+func (mv *MarkdownView) configTable(par ki.Ki, nm string, bl *markdown.Block) {
+	gr := gi.AddNewFrame(par, nm, gi.LayoutGrid)
+	gr.Stripes = gi.RowStripes
+	gr.SetProp("columns", len(bl.Aligns))
+	gr.SetProp("margin", units.NewEx(0.5))
+	for r, row := range bl.Rows {
+		cls := "td"
+		if r == 0 {
+			cls = "th"
+		}
+		for c, cell := range row {
+			lb := mv.addLabel(gr, fmt.Sprintf("cell-%d-%d", r, c), markdown.HTML(mv.Doc.Inlines(cell)), cls)
+			lb.SetProp("white-space", gi.WhiteSpacePre)
+			lb.SetProp("max-width", 0)
+			switch bl.Aligns[c] {
+			case markdown.AlignCenter:
+				lb.SetProp("text-align", gi.AlignCenter)
+			case markdown.AlignRight:
+				lb.SetProp("text-align", gi.AlignRight)
+			}
+		}
+	}
+}
+
+// configText adds the widgets for given text of a block to given parent:
+// a label with the text, with any images in it shown below it, in a
+// layout if there is more than one widget
+func (mv *MarkdownView) configText(par ki.Ki, nm string, text string, class string) {
+	ins := mv.Doc.Inlines(text)
+	var parts [][]*markdown.Inline
+	st := 0
+	for i, in := range ins {
+		if in.Kind != markdown.Image {
+			continue
+		}
+		parts = append(parts, ins[st:i], ins[i:i+1])
+		st = i + 1
+	}
+	parts = append(parts, ins[st:])
+	if len(parts) == 1 {
+		mv.addLabel(par, nm, markdown.HTML(ins), class)
+		return
+	}
+	var nonEmpty [][]*markdown.Inline
+	for _, pt := range parts {
+		if strings.TrimSpace(markdown.PlainText(pt)) != "" || (len(pt) == 1 && pt[0].Kind == markdown.Image) {
+			nonEmpty = append(nonEmpty, pt)
+		}
+	}
+	if len(nonEmpty) > 1 {
+		ly := gi.AddNewLayout(par, nm, gi.LayoutVert)
+		ly.SetStretchMaxWidth()
+		par = ly
+	}
+	for i, pt := range nonEmpty {
+		pnm := nm
+		if len(nonEmpty) > 1 {
+			pnm = "part-" + strconv.Itoa(i)
+		}
+		if len(pt) == 1 && pt[0].Kind == markdown.Image {
+			mv.addImage(par, pnm, pt[0])
+		} else {
+			mv.addLabel(par, pnm, markdown.HTML(pt), class)
+		}
+	}
+}
+
+// addLabel adds a word-wrapped label with given HTML and class to given
+// parent, with its links opened by OpenLink
+func (mv *MarkdownView) addLabel(par ki.Ki, nm string, html string, class string) *gi.Label {
+	lb := gi.AddNewLabel(par, nm, html)
+	lb.Class = class
+	lb.SetProp("white-space", gi.WhiteSpaceNormal)
+	lb.SetProp("max-width", -1)
+	lb.SetProp("width", units.NewCh(20))
+	lb.LinkSig.Connect(mv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+		mvv := recv.Embed(KiT_MarkdownView).(*MarkdownView)
+		mvv.OpenLink(data.(string))
+	})
+	return lb
+}
+
+// addImage adds a bitmap with given image to given parent, or a label with
+// its description, linked to it, if it can not be opened
+func (mv *MarkdownView) addImage(par ki.Ki, nm string, in *markdown.Inline) {
+	alt := markdown.PlainText(in.Kids)
+	if path := mv.LocalPath(in.URL); path != "" {
+		if img, err := mv.OpenImage(path); err == nil {
+			if sz := img.Bounds().Size(); sz.X > MarkdownImageMaxSize || sz.Y > MarkdownImageMaxSize {
+				img = gi.ImageResizeMax(img, MarkdownImageMaxSize)
+			}
+			bm := gi.AddNewBitmap(par, nm)
+			bm.SetImage(img, 0, 0)
+			bm.LayoutToImgSize()
+			bm.Tooltip = in.Title
+			if bm.Tooltip == "" {
+				bm.Tooltip = alt
+			}
+			return
+		}
+	}
+	if alt == "" {
+		alt = in.URL
+	}
+	mv.addLabel(par, nm, markdown.HTML([]*markdown.Inline{{Kind: markdown.Link, URL: in.URL, Kids: []*markdown.Inline{{Kind: markdown.Text, Text: alt}}}}), "image-alt")
+}
+
+// OpenImage opens the image at given path in the file system
+func (mv *MarkdownView) OpenImage(path string) (image.Image, error) {
+	b, err := vfs.ReadFile(mv.FileSys(), path)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(b))
+	return img, err
+}
+
+// addCode adds an inactive TextView with given code block to given parent,
+// highlighted according to its language
+func (mv *MarkdownView) addCode(par ki.Ki, nm string, bl *markdown.Block) *TextView {
+	tb := &TextBuf{}
+	tb.InitName(tb, nm+"-buf")
+	tb.Defaults()
+	if mv.HiStyle != "" {
+		tb.SetHiStyle(mv.HiStyle)
+	}
+	tb.Info.Name = MarkdownCodeFileName(bl.Lang())
+	tb.Opts.LineNos = false
+	tv := AddNewTextView(par, nm)
+	tv.Class = "code"
+	tv.SetProp("font-family", gi.Prefs.MonoFont)
+	tv.SetInactive()
+	tv.SetBuf(tb)
+	tb.SetText([]byte(bl.Text))
+	return tv
+}
+
+// MarkdownCodeFileName returns a file name for code in given language of
+// a fenced code block, from which its highlighting is chosen
+func MarkdownCodeFileName(lang string) string {
+	if lang == "" {
+		return ""
+	}
+	lx := lexers.Get(lang)
+	if lx == nil {
+		return ""
+	}
+	for _, fn := range lx.Config().Filenames {
+		if !strings.ContainsAny(fn, "[?") {
+			return "code" + strings.TrimPrefix(fn, "*")
+		}
+	}
+	return ""
+}
+
+// LocalPath returns the path of the local file that given link or image
+// URL refers to, resolved against the directory of the Filename, or "" if
+// it is not to a local file
+func (mv *MarkdownView) LocalPath(url string) string {
+	if i := strings.IndexByte(url, '#'); i >= 0 {
+		url = url[:i]
+	}
+	if strings.HasPrefix(url, "file://") {
+		return strings.TrimPrefix(url, "file://")
+	}
+	if url == "" || strings.Contains(url, "://") || strings.HasPrefix(url, "mailto:") {
+		return ""
+	}
+	if filepath.IsAbs(url) || mv.Filename == "" {
+		return url
+	}
+	return filepath.Join(filepath.Dir(string(mv.Filename)), url)
+}
+
+// OpenLink opens given link: a # link scrolls to the heading with that
+// anchor, a link to another local Markdown file opens it in the view
+// unless a buffer is being previewed, and any other link is sent by the
+// LinkSig, or opened with gi.URLHandler if nobody is receiving it
+func (mv *MarkdownView) OpenLink(url string) {
+	if strings.HasPrefix(url, "#") {
+		mv.ScrollToAnchor(url[1:])
+		return
+	}
+	if path := mv.LocalPath(url); path != "" {
+		if mv.Buf == nil && IsMarkdownFile(path) {
+			if err := mv.OpenFile(gi.FileName(path)); err == nil {
+				if i := strings.IndexByte(url, '#'); i >= 0 {
+					mv.ScrollToAnchor(url[i+1:])
+				}
+				return
+			}
+		}
+		url = "file://" + path
+	}
+	if len(mv.LinkSig.Cons) > 0 {
+		mv.LinkSig.Emit(mv.This(), 0, url)
+		return
+	}
+	if gi.URLHandler != nil {
+		gi.URLHandler(url)
+	}
+}
+
+// IsMarkdownFile returns true if given file has a Markdown extension
+func IsMarkdownFile(fname string) bool {
+	switch strings.ToLower(filepath.Ext(fname)) {
+	case ".md", ".markdown", ".mdown", ".mkd":
+		return true
+	}
+	return false
+}
+
+// ScrollToAnchor scrolls to the heading with given anchor ID, returning
+// false if there is none
+func (mv *MarkdownView) ScrollToAnchor(id string) bool {
+	w, ok := mv.Anchors[id]
+	if !ok {
+		return false
+	}
+	w.AsNode2D().ScrollToMe()
+	return true
+}
+
+// ScrollToLine scrolls to the widget of the top-level block at given line
+// of the source
+func (mv *MarkdownView) ScrollToLine(ln int) {
+	if mv.Doc == nil {
+		return
+	}
+	idx := -1
+	for i, bl := range mv.Doc.Blocks {
+		if bl.Line > ln {
+			break
+		}
+		idx = i
+	}
+	if idx < 0 || idx >= mv.NumChildren() {
+		return
+	}
+	if nii, ok := mv.Child(idx).(gi.Node2D); ok {
+		nii.AsNode2D().ScrollToMe()
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////
+//   MarkdownEditView
+
+// MarkdownEditView edits Markdown in a TextView side-by-side with a
+// MarkdownView showing a live preview of it, which scrolls to follow the
+// cursor
+type MarkdownEditView struct {
+	gi.SplitView
+	Buf *TextBuf `json:"-" xml:"-" desc:"buffer of the Markdown being edited"`
+}
+
+var KiT_MarkdownEditView = kit.Types.AddType(&MarkdownEditView{}, MarkdownEditViewProps)
+
+// AddNewMarkdownEditView adds a new markdown edit view to given parent
+// node, with given name.
+func AddNewMarkdownEditView(parent ki.Ki, name string) *MarkdownEditView {
+	return parent.AddNewChild(KiT_MarkdownEditView, name).(*MarkdownEditView)
+}
+
+// MarkdownEditViewProps are style properties for MarkdownEditView
+var MarkdownEditViewProps = ki.Props{
+	"EnumType:Flag":    gi.KiT_NodeFlags,
+	"max-width":        -1,
+	"max-height":       -1,
+	"background-color": &gi.Prefs.Colors.Background,
+	"color":            &gi.Prefs.Colors.Font,
+}
+
+// SetBuf sets the buffer to edit and preview
+func (me *MarkdownEditView) SetBuf(tb *TextBuf) {
+	me.Config()
+	me.Buf = tb
+	tv := me.TextView()
+	tv.SetBuf(tb)
+	pv := me.Preview()
+	pv.SetBuf(tb)
+	pv.SetTextView(tv)
+}
+
+// Config configures the text view and preview
+func (me *MarkdownEditView) Config() {
+	me.Dim = mat32.X
+	me.SetStretchMax()
+	config := kit.TypeAndNameList{}
+	config.Add(gi.KiT_Layout, "text-lay")
+	config.Add(KiT_MarkdownView, "preview")
+	mods, updt := me.ConfigChildren(config, ki.UniqueNames)
+	if !mods {
+		return
+	}
+	tl := me.Child(0).(*gi.Layout)
+	tl.SetStretchMax()
+	tl.SetMinPrefWidth(units.NewCh(40))
+	tl.SetMinPrefHeight(units.NewEm(20))
+	tv := AddNewTextView(tl, "text-view")
+	tv.SetProp("font-family", gi.Prefs.MonoFont)
+	pv := me.Preview()
+	pv.SetMinPrefWidth(units.NewCh(40))
+	pv.SetMinPrefHeight(units.NewEm(20))
+	me.SetSplits(.5, .5)
+	me.UpdateEnd(updt)
+}
+
+// TextView returns the text view of the Markdown being edited
+func (me *MarkdownEditView) TextView() *TextView {
+	return me.Child(0).Child(0).(*TextView)
+}
+
+// Preview returns the preview of the Markdown being edited
+func (me *MarkdownEditView) Preview() *MarkdownView {
+	return me.Child(1).(*MarkdownView)
+}
+
+// MarkdownViewDialog opens a dialog showing the given Markdown file, or
+// the given Markdown source if the file name is empty -- for help and
+// other documentation
+func MarkdownViewDialog(avp *gi.Viewport2D, fname gi.FileName, src []byte, opts DlgOpts) *MarkdownView {
+	dlg := gi.NewStdDialog(opts.ToGiOpts(), opts.Ok, opts.Cancel)
+	frame := dlg.Frame()
+	_, prIdx := dlg.PromptWidget(frame)
+
+	mv := frame.InsertNewChild(KiT_MarkdownView, prIdx+1, "markdown-view").(*MarkdownView)
+	mv.Viewport = dlg.Embed(gi.KiT_Viewport2D).(*gi.Viewport2D)
+	mv.SetProp("width", units.NewCh(80))
+	mv.SetProp("height", units.NewEm(40))
+	mv.SetStretchMax()
+	if fname != "" {
+		if err := mv.OpenFile(fname); err != nil {
+			mv.SetMarkdown([]byte(fmt.Sprintf("Could not open `%v`: %v", fname, err)))
+		}
+	} else {
+		mv.SetMarkdown(src)
+	}
+
+	dlg.UpdateEndNoSig(true) // going to be shown
+	dlg.Open(0, 0, avp, nil)
+	return mv
+}
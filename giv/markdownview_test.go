@@ -0,0 +1,83 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"image"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/histyle"
+	"github.com/goki/ki/ki"
+	"github.com/goki/pi/lex"
+)
+
+func TestMarkdownView(t *testing.T) {
+	if histyle.AvailStyles == nil {
+		histyle.StdStyles.OpenDefaults()
+		histyle.AvailStyles = histyle.StdStyles
+	}
+	dir := t.TempDir()
+	gi.SavePNG(filepath.Join(dir, "img.png"), image.NewRGBA(image.Rect(0, 0, 4, 3)))
+	ioutil.WriteFile(filepath.Join(dir, "other.md"), []byte("# Other\n"), 0644)
+	fname := filepath.Join(dir, "doc.md")
+	src := "# Title\n\nSee [other](other.md), [top](#title) and [site](https://goki.dev).\n\n" +
+		"- a\n- b\n\n```go\nfunc f() {}\n```\n\n| x | y |\n|---|--:|\n| 1 | 2 |\n\n![pic](img.png)\n"
+	ioutil.WriteFile(fname, []byte(src), 0644)
+
+	mv := &MarkdownView{}
+	mv.InitName(mv, "md-view")
+	if err := mv.OpenFile(gi.FileName(fname)); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Label", "Label", "Layout", "TextView", "Frame", "Bitmap"}
+	if len(mv.Kids) != len(want) {
+		t.Fatalf("widgets: %v", mv.Kids)
+	}
+	for i, w := range want {
+		if typ := mv.Child(i).Type().Name(); typ != w {
+			t.Errorf("widget %d: %v, want %v", i, typ, w)
+		}
+	}
+	if lb := mv.Child(0).(*gi.Label); lb.Class != "h1" || mv.Anchors["title"] != lb {
+		t.Errorf("heading: %v %v", lb.Class, mv.Anchors)
+	}
+	if tv := mv.Child(3).(*TextView); tv.Buf.Hi.Lang != "Go" || tv.Buf.NLines != 1 {
+		t.Errorf("code: %v %v", tv.Buf.Hi.Lang, tv.Buf.NLines)
+	}
+	if bm := mv.Child(5).(*gi.Bitmap); bm.Size != image.Pt(4, 3) {
+		t.Errorf("image size: %v", bm.Size)
+	}
+
+	var got string
+	mv.LinkSig.Connect(mv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+		got = data.(string)
+	})
+	mv.OpenLink("https://goki.dev")
+	mv.OpenLink("img.png")
+	if got != "file://"+filepath.Join(dir, "img.png") {
+		t.Errorf("link: %v", got)
+	}
+	mv.OpenLink("other.md")
+	if mv.Filename != gi.FileName(filepath.Join(dir, "other.md")) || mv.NumChildren() != 1 {
+		t.Errorf("markdown link not opened: %v", mv.Filename)
+	}
+
+	// live preview of a buffer
+	tb := newTestTextBuf()
+	tb.SetText([]byte("# One\n"))
+	mv.SetBuf(tb)
+	if mv.NumChildren() != 1 {
+		t.Fatalf("preview: %v", mv.Kids)
+	}
+	tb.InsertText(lex.Pos{Ln: 1}, []byte("\npara\n"), true)
+	waitFor(t, "preview update", func() bool {
+		mv.updtMu.Lock()
+		defer mv.updtMu.Unlock()
+		return mv.updtTimer == nil && mv.NumChildren() == 2
+	})
+}
@@ -0,0 +1,564 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Masterminds/vcs"
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/textbuf"
+	"github.com/goki/gi/giv/vfs"
+	"github.com/goki/gi/units"
+	"github.com/goki/ki/ki"
+	"github.com/goki/ki/kit"
+	"github.com/goki/pi/lex"
+	"github.com/goki/vci"
+)
+
+// MergeViewDialogFromVcs opens a dialog for merging the conflicts in given
+// file of given repository, in given file system (nil for the OS).  For
+// git, the base, ours and theirs versions are obtained from the repository
+// and merged anew -- otherwise the conflict markers in the file (or fbuf
+// if non-nil) are parsed.
+func MergeViewDialogFromVcs(avp *gi.Viewport2D, repo vci.Repo, fsys vfs.FS, file string, fbuf *TextBuf) (*MergeView, error) {
+	mg, err := VcsMerge(repo, fsys, file, fbuf)
+	if err != nil {
+		return nil, err
+	}
+	mv := MergeViewDialog(avp, mg, fsys, file, DlgOpts{Title: "Merge" + string(repo.Vcs()) + ": " + DirAndFile(file)})
+	mv.Repo = repo
+	return mv, nil
+}
+
+// VcsMerge returns the merge of the conflicts in given file of given
+// repository (if non-nil), in given file system (nil for the OS) -- see
+// MergeViewDialogFromVcs
+func VcsMerge(repo vci.Repo, fsys vfs.FS, file string, fbuf *TextBuf) (*textbuf.Merge, error) {
+	var mg *textbuf.Merge
+	if repo != nil && repo.Vcs() == vcs.Git {
+		var vers [3][]string
+		for i, stage := range []string{":1", ":2", ":3"} {
+			fb, err := repo.FileContents(file, stage)
+			if err != nil {
+				break
+			}
+			txt, _, _ := textbuf.DecodeText(fb)
+			vers[i] = textbuf.BytesToLineStrings(txt, false)
+		}
+		if vers[2] != nil {
+			mg = textbuf.Merge3(vers[0], vers[1], vers[2])
+		}
+	}
+	if mg == nil {
+		var lns []string
+		if fbuf != nil {
+			lns = fbuf.Strings(false)
+		} else {
+			fb, err := vfs.ReadFile(fsys, file)
+			if err != nil {
+				return nil, err
+			}
+			txt, _, _ := textbuf.DecodeText(fb)
+			lns = textbuf.BytesToLineStrings(txt, false)
+		}
+		var ok bool
+		mg, ok = textbuf.ParseConflicts(lns)
+		if !ok {
+			return nil, fmt.Errorf("no conflicts found in file: %v", file)
+		}
+	}
+	return mg, nil
+}
+
+// MergeViewDialogFromBufs opens a dialog for the three-way merge of given
+// buffers with the ours and theirs versions of a text derived from the
+// base version -- the result is saved to given file, in given file system
+// (nil for the OS)
+func MergeViewDialogFromBufs(avp *gi.Viewport2D, base, ours, theirs *TextBuf, fsys vfs.FS, file string) *MergeView {
+	mg := textbuf.Merge3(base.Strings(false), ours.Strings(false), theirs.Strings(false))
+	return MergeViewDialog(avp, mg, fsys, file, DlgOpts{Title: "Merge: " + DirAndFile(file)})
+}
+
+// MergeViewDialog opens a dialog for resolving the conflicts in given
+// merge, with the result saved to given file, in given file system (nil
+// for the OS)
+func MergeViewDialog(avp *gi.Viewport2D, mg *textbuf.Merge, fsys vfs.FS, file string, opts DlgOpts) *MergeView {
+	dlg := gi.NewStdDialog(opts.ToGiOpts(), opts.Ok, opts.Cancel)
+
+	frame := dlg.Frame()
+	_, prIdx := dlg.PromptWidget(frame)
+
+	mv := frame.InsertNewChild(KiT_MergeView, prIdx+1, "merge-view").(*MergeView)
+	mv.Viewport = dlg.Embed(gi.KiT_Viewport2D).(*gi.Viewport2D)
+	mv.SetStretchMax()
+	mv.FS = fsys
+	mv.File = file
+	mv.Merge = mg
+	mv.Config()
+	mv.SetMerge(mg)
+
+	dlg.UpdateEndNoSig(true) // going to be shown
+	dlg.Open(0, 0, avp, nil)
+	return mv
+}
+
+// VcsResolve marks given file as resolved in given repository, after its
+// conflicts have been merged.  Repositories can provide this with a
+// Resolve(fname string) error method -- otherwise git adds the file, and
+// svn accepts the working copy.
+func VcsResolve(repo vci.Repo, fname string) error {
+	if rr, ok := repo.(interface{ Resolve(fname string) error }); ok {
+		return rr.Resolve(fname)
+	}
+	switch repo.Vcs() {
+	case vcs.Git:
+		return repo.Add(fname)
+	case vcs.Svn:
+		out, err := repo.RunFromDir("svn", "resolve", "--accept", "working", vci.RelPath(repo, fname))
+		if err != nil {
+			log.Println(string(out))
+		}
+		return err
+	}
+	return fmt.Errorf("giv.VcsResolve: resolving conflicts not supported for: %v", repo.Vcs())
+}
+
+///////////////////////////////////////////////////////////////////
+// MergeView
+
+// MergeConflict records where a conflict of a MergeView is shown
+type MergeConflict struct {
+	Chunk int            `desc:"index of the conflict in the Merge Chunks"`
+	Lns   [3]int         `desc:"starting lines of the conflict in the base, ours and theirs buffers"`
+	Reg   textbuf.Region `desc:"region of the conflict in the result buffer, as whole lines -- time stamped, so it can be adjusted for later edits"`
+}
+
+// MergeView presents a three-way merge of the base, ours and theirs
+// versions of a file, in side-by-side TextViews, above an editable result
+// that starts out with everything that merges cleanly and the conflicts
+// between ours and theirs.  Each conflict can be resolved by choosing
+// ours, theirs, both or the base, or by editing the result.  Saving the
+// result without any conflicts marks the file as resolved in its Repo.
+type MergeView struct {
+	gi.Frame
+	File      string          `desc:"file that the result of the merge is saved to"`
+	FS        vfs.FS          `view:"-" json:"-" xml:"-" desc:"file system of the File -- nil for the OS"`
+	Repo      vci.Repo        `json:"-" xml:"-" desc:"version control repository of the file -- if set, the file is marked as resolved when saved without conflicts"`
+	Merge     *textbuf.Merge  `json:"-" xml:"-" desc:"the merge"`
+	Conflicts []MergeConflict `json:"-" xml:"-" desc:"the conflicts in the merge"`
+	CurConf   int             `desc:"index of the current conflict"`
+	BufBase   *TextBuf        `json:"-" xml:"-" desc:"buffer for the base version"`
+	BufOurs   *TextBuf        `json:"-" xml:"-" desc:"buffer for our version"`
+	BufTheirs *TextBuf        `json:"-" xml:"-" desc:"buffer for their version"`
+	BufResult *TextBuf        `json:"-" xml:"-" desc:"buffer for the result of the merge"`
+}
+
+var KiT_MergeView = kit.Types.AddType(&MergeView{}, MergeViewProps)
+
+// AddNewMergeView adds a new merge view to given parent node, with given name.
+func AddNewMergeView(parent ki.Ki, name string) *MergeView {
+	return parent.AddNewChild(KiT_MergeView, name).(*MergeView)
+}
+
+// MergeViewProps are style properties for MergeView
+var MergeViewProps = ki.Props{
+	"EnumType:Flag":    gi.KiT_NodeFlags,
+	"max-width":        -1,
+	"max-height":       -1,
+	"background-color": &gi.Prefs.Colors.Background,
+	"color":            &gi.Prefs.Colors.Font,
+}
+
+var (
+	// MergeViewConflictColor is the line color for unresolved conflicts
+	MergeViewConflictColor = "red"
+
+	// MergeViewResolvedColor is the line color for resolved conflicts
+	MergeViewResolvedColor = "green"
+
+	// MergeViewChangeColor is the line color for changes from the base
+	// that merge cleanly
+	MergeViewChangeColor = "blue"
+)
+
+// SetMerge sets the merge to show, and sets the text of all the buffers
+func (mv *MergeView) SetMerge(mg *textbuf.Merge) {
+	mv.Merge = mg
+	mv.ConfigBufs()
+	updt := mv.UpdateStart()
+	defer mv.UpdateEnd(updt)
+	mv.Conflicts = nil
+	mv.CurConf = 0
+	bufs := []*TextBuf{mv.BufBase, mv.BufOurs, mv.BufTheirs}
+	vers := []textbuf.MergeChoices{textbuf.MergeBase, textbuf.MergeOurs, textbuf.MergeTheirs}
+	for _, tb := range bufs {
+		tb.LineColors = nil
+	}
+	var lns [3]int
+	rln := 0
+	for ci, mc := range mg.Chunks {
+		nr := len(mg.ChunkLines(mc))
+		if mc.Conflict {
+			mv.Conflicts = append(mv.Conflicts, MergeConflict{Chunk: ci, Lns: lns, Reg: textbuf.NewRegion(rln, 0, rln+nr, 0)})
+		}
+		for vi, ver := range vers {
+			nv := len(mc.Version(ver))
+			if mc.Conflict || (ver != textbuf.MergeBase && mc.IsChanged(ver)) {
+				clr := MergeViewChangeColor
+				if mc.Conflict {
+					clr = MergeViewConflictColor
+				}
+				for ln := lns[vi]; ln < lns[vi]+nv; ln++ {
+					bufs[vi].SetLineColor(ln, clr)
+				}
+			}
+			lns[vi] += nv
+		}
+		rln += nr
+	}
+	for vi, ver := range vers {
+		bufs[vi].SetText([]byte(strings.Join(mg.Version(ver), "\n")))
+	}
+	mv.BufResult.SetText([]byte(strings.Join(mg.Result(), "\n")))
+	for i := range mv.Conflicts {
+		mv.Conflicts[i].Reg.TimeNow()
+	}
+	mv.UpdateResultColors()
+	mv.SetStatus()
+}
+
+// ConflictReg returns the current region of given conflict in the result
+// buffer, adjusted for the edits made since it was recorded
+func (mv *MergeView) ConflictReg(ci int) textbuf.Region {
+	reg := mv.Conflicts[ci].Reg
+	t := reg.Time.Time()
+	reg.Start = mv.BufResult.AdjustPos(reg.Start, t, textbuf.AdjustPosDelEnd)
+	reg.End = mv.BufResult.AdjustPos(reg.End, t, textbuf.AdjustPosDelStart)
+	if reg.End.IsLess(reg.Start) {
+		reg.End = reg.Start
+	}
+	return reg
+}
+
+// ConflictAtLine returns the index of the conflict that contains given line
+// of the result buffer, or -1 if none
+func (mv *MergeView) ConflictAtLine(ln int) int {
+	for ci := range mv.Conflicts {
+		reg := mv.ConflictReg(ci)
+		if ln >= reg.Start.Ln && (ln < reg.End.Ln || ln == reg.Start.Ln) {
+			return ci
+		}
+	}
+	return -1
+}
+
+// ChooseConflict resolves given conflict with given choice, replacing its
+// lines in the result buffer -- this can be undone in the result
+func (mv *MergeView) ChooseConflict(ci int, choice textbuf.MergeChoices) {
+	if ci < 0 || ci >= len(mv.Conflicts) {
+		return
+	}
+	cf := &mv.Conflicts[ci]
+	mc := mv.Merge.Chunks[cf.Chunk]
+	tb := mv.BufResult
+	reg := mv.ConflictReg(ci)
+	mc.Choice = choice
+	lns := mv.Merge.ChunkLines(mc)
+	txt := strings.Join(lns, "\n")
+	if len(lns) > 0 {
+		txt += "\n"
+	}
+	if reg.End.Ln >= tb.NumLines() { // at the end, without a final newline
+		reg.End = tb.EndPos()
+		txt = strings.TrimSuffix(txt, "\n")
+	}
+	tb.Undos.BatchStart()
+	tb.ReplaceText(reg.Start, reg.End, reg.Start, txt, EditSignal, false)
+	tb.Undos.BatchEnd()
+	cf.Reg = textbuf.NewRegion(reg.Start.Ln, 0, reg.Start.Ln+len(lns), 0)
+	cf.Reg.TimeNow()
+	mv.UpdateResultColors()
+	mv.SetStatus()
+}
+
+// ChooseCurConflict resolves the conflict at the cursor in the result, or
+// the current conflict, with given choice, and moves to the next conflict
+func (mv *MergeView) ChooseCurConflict(choice textbuf.MergeChoices) {
+	ci := mv.ConflictAtLine(mv.ResultView().CursorPos.Ln)
+	if ci < 0 {
+		ci = mv.CurConf
+	}
+	mv.ChooseConflict(ci, choice)
+	mv.CurConf = ci
+	mv.NextConflict()
+}
+
+// UpdateResultColors sets the line colors of the conflicts in the result
+func (mv *MergeView) UpdateResultColors() {
+	tb := mv.BufResult
+	tb.LineColors = nil
+	for ci := range mv.Conflicts {
+		clr := MergeViewConflictColor
+		if mv.Merge.Chunks[mv.Conflicts[ci].Chunk].Choice != textbuf.MergeUnresolved {
+			clr = MergeViewResolvedColor
+		}
+		reg := mv.ConflictReg(ci)
+		for ln := reg.Start.Ln; ln < reg.End.Ln; ln++ {
+			tb.SetLineColor(ln, clr)
+		}
+	}
+	if mv.IsConfiged() {
+		mv.ResultView().UpdateSig()
+	}
+}
+
+// ShowConflict makes given conflict the current one and scrolls all the
+// views to show it
+func (mv *MergeView) ShowConflict(ci int) {
+	if ci < 0 || ci >= len(mv.Conflicts) {
+		return
+	}
+	mv.CurConf = ci
+	cf := &mv.Conflicts[ci]
+	tvs := mv.TextViews()
+	for vi := 0; vi < 3; vi++ {
+		tvs[vi].SetCursorShow(lex.Pos{Ln: cf.Lns[vi]})
+	}
+	tvs[3].SetCursorShow(mv.ConflictReg(ci).Start)
+	mv.SetStatus()
+}
+
+// NextConflict shows the next conflict after the current one, preferring
+// unresolved ones -- returns false if there are no other conflicts
+func (mv *MergeView) NextConflict() bool {
+	return mv.stepConflict(1)
+}
+
+// PrevConflict shows the conflict before the current one, preferring
+// unresolved ones -- returns false if there are no other conflicts
+func (mv *MergeView) PrevConflict() bool {
+	return mv.stepConflict(-1)
+}
+
+// stepConflict moves to the next conflict in given direction, wrapping around
+func (mv *MergeView) stepConflict(dir int) bool {
+	nc := len(mv.Conflicts)
+	if nc == 0 {
+		return false
+	}
+	first := -1
+	for i := 1; i <= nc; i++ {
+		ci := (mv.CurConf + dir*i + 2*nc) % nc
+		if first < 0 && ci != mv.CurConf {
+			first = ci
+		}
+		if mv.Merge.Chunks[mv.Conflicts[ci].Chunk].Choice == textbuf.MergeUnresolved {
+			mv.ShowConflict(ci)
+			return true
+		}
+	}
+	if first < 0 {
+		return false
+	}
+	mv.ShowConflict(first)
+	return true
+}
+
+// SaveResult saves the result to File, and marks the file as resolved in
+// the Repo if there are no conflict markers left -- prompts first if there
+// are any
+func (mv *MergeView) SaveResult() {
+	if mv.File == "" {
+		return
+	}
+	if textbuf.HasConflictMarkers(mv.BufResult.Strings(false)) {
+		gi.ChoiceDialog(mv.ViewportSafe(), gi.DlgOpts{Title: "Save With Conflicts?",
+			Prompt: "The result still has conflict markers -- save anyway, without marking the file as resolved?"},
+			[]string{"Save", "Cancel"},
+			mv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+				if sig == 0 {
+					mvv := recv.Embed(KiT_MergeView).(*MergeView)
+					mvv.BufResult.SaveFile(gi.FileName(mvv.File))
+				}
+			})
+		return
+	}
+	mv.BufResult.SaveFileFunc(gi.FileName(mv.File), func(err error) {
+		if err != nil { // including canceled by the user
+			return
+		}
+		if mv.Repo != nil {
+			if err := VcsResolve(mv.Repo, mv.File); err != nil {
+				gi.PromptDialog(mv.ViewportSafe(), gi.DlgOpts{Title: "Could Not Mark File as Resolved", Prompt: err.Error()}, gi.AddOk, gi.NoCancel, nil, nil)
+			}
+		}
+		mv.SetStatus()
+	})
+}
+
+// SetStatus updates the status label in the toolbar
+func (mv *MergeView) SetStatus() {
+	if !mv.IsConfiged() || mv.Merge == nil {
+		return
+	}
+	nc, nu := mv.Merge.NConflicts()
+	st := fmt.Sprintf("Conflicts: %d  Unresolved: %d", nc, nu)
+	if nc > 0 {
+		st = fmt.Sprintf("Conflict %d of %d  Unresolved: %d", mv.CurConf+1, nc, nu)
+	}
+	mv.ToolBar().ChildByName("status", 0).(*gi.Label).SetText(st)
+}
+
+// Config configures the view
+func (mv *MergeView) Config() {
+	mv.Lay = gi.LayoutVert
+	config := kit.TypeAndNameList{}
+	config.Add(gi.KiT_ToolBar, "toolbar")
+	config.Add(gi.KiT_Layout, "versions")
+	config.Add(gi.KiT_Layout, "result-pane")
+	mods, updt := mv.ConfigChildren(config, ki.UniqueNames)
+	if !mods {
+		updt = mv.UpdateStart()
+	} else {
+		mv.ConfigToolBar()
+		mv.ConfigTexts()
+	}
+	mv.SetFullReRender()
+	mv.UpdateEnd(updt)
+}
+
+// IsConfiged returns true if the view has been configured
+func (mv *MergeView) IsConfiged() bool {
+	return mv.NumChildren() > 0
+}
+
+// ToolBar returns the toolbar
+func (mv *MergeView) ToolBar() *gi.ToolBar {
+	return mv.ChildByName("toolbar", 0).(*gi.ToolBar)
+}
+
+// TextViews returns the text views for the base, ours, theirs and result
+func (mv *MergeView) TextViews() [4]*TextView {
+	var tvs [4]*TextView
+	vl := mv.ChildByName("versions", 1)
+	for i, nm := range []string{"base", "ours", "theirs"} {
+		tvs[i] = vl.ChildByName(nm+"-pane", i).ChildByName(nm+"-lay", 1).Child(0).(*TextView)
+	}
+	tvs[3] = mv.ChildByName("result-pane", 2).ChildByName("result-lay", 1).Child(0).(*TextView)
+	return tvs
+}
+
+// ResultView returns the text view for the result
+func (mv *MergeView) ResultView() *TextView {
+	return mv.TextViews()[3]
+}
+
+func (mv *MergeView) HasConflictsUpdate(act *gi.Action) {
+	act.SetActiveStateUpdt(len(mv.Conflicts) > 0)
+}
+
+func (mv *MergeView) HasBaseUpdate(act *gi.Action) {
+	act.SetActiveStateUpdt(len(mv.Conflicts) > 0 && mv.Merge.HasBase)
+}
+
+func (mv *MergeView) ConfigToolBar() {
+	tb := mv.ToolBar()
+	tb.SetStretchMaxWidth()
+	gi.AddNewLabel(tb, "status", "")
+	tb.AddAction(gi.ActOpts{Label: "Next", Icon: "wedge-down", Tooltip: "move down to next conflict, skipping resolved ones", UpdateFunc: mv.HasConflictsUpdate},
+		mv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			mvv := recv.Embed(KiT_MergeView).(*MergeView)
+			mvv.NextConflict()
+		})
+	tb.AddAction(gi.ActOpts{Label: "Prev", Icon: "wedge-up", Tooltip: "move up to previous conflict, skipping resolved ones", UpdateFunc: mv.HasConflictsUpdate},
+		mv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			mvv := recv.Embed(KiT_MergeView).(*MergeView)
+			mvv.PrevConflict()
+		})
+	tb.AddSeparator("sep-use")
+	uses := []struct {
+		label  string
+		choice textbuf.MergeChoices
+		tip    string
+	}{
+		{"Use Ours", textbuf.MergeOurs, "resolve the conflict at the cursor in the result, or the current one, with our version"},
+		{"Use Theirs", textbuf.MergeTheirs, "resolve the conflict at the cursor in the result, or the current one, with their version"},
+		{"Use Both", textbuf.MergeBoth, "resolve the conflict at the cursor in the result, or the current one, with our version followed by theirs"},
+		{"Use Base", textbuf.MergeBase, "resolve the conflict at the cursor in the result, or the current one, with the base version, discarding both changes"},
+		{"Unresolve", textbuf.MergeUnresolved, "restore the conflict at the cursor in the result, or the current one, with conflict markers"},
+	}
+	for _, us := range uses {
+		updf := mv.HasConflictsUpdate
+		if us.choice == textbuf.MergeBase {
+			updf = mv.HasBaseUpdate
+		}
+		tb.AddAction(gi.ActOpts{Label: us.label, Tooltip: us.tip, Data: us.choice, UpdateFunc: updf},
+			mv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+				mvv := recv.Embed(KiT_MergeView).(*MergeView)
+				mvv.ChooseCurConflict(data.(textbuf.MergeChoices))
+			})
+	}
+	tb.AddSeparator("sep-save")
+	tb.AddAction(gi.ActOpts{Label: "Save", Icon: "file-save", Tooltip: "save the result to the file, and mark it as resolved in version control if no conflicts remain"},
+		mv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			mvv := recv.Embed(KiT_MergeView).(*MergeView)
+			mvv.SaveResult()
+		})
+}
+
+// configPane configures a pane with a label above a text view of given
+// buffer, named by given name
+func (mv *MergeView) configPane(pane *gi.Layout, nm, label string, tb *TextBuf) {
+	pane.Lay = gi.LayoutVert
+	pane.SetStretchMax()
+	gi.AddNewLabel(pane, nm+"-label", label)
+	tl := gi.AddNewLayout(pane, nm+"-lay", gi.LayoutVert)
+	tl.SetStretchMax()
+	tl.SetMinPrefWidth(units.NewCh(40))
+	tl.SetMinPrefHeight(units.NewEm(15))
+	tv := AddNewTextView(tl, nm+"-text")
+	tv.SetProp("font-family", gi.Prefs.MonoFont)
+	if nm != "result" {
+		tv.SetInactive()
+	}
+	tv.SetBuf(tb)
+}
+
+// ConfigBufs makes the buffers if not yet made
+func (mv *MergeView) ConfigBufs() {
+	bufs := []**TextBuf{&mv.BufBase, &mv.BufOurs, &mv.BufTheirs, &mv.BufResult}
+	for i, nm := range []string{"base", "ours", "theirs", "result"} {
+		if *bufs[i] != nil {
+			continue
+		}
+		tb := &TextBuf{}
+		tb.InitName(tb, "merge-buf-"+nm)
+		tb.Filename = gi.FileName(mv.File)
+		tb.FS = mv.FS
+		tb.Opts.LineNos = true
+		tb.Stat() // update markup
+		*bufs[i] = tb
+	}
+}
+
+func (mv *MergeView) ConfigTexts() {
+	mv.ConfigBufs()
+	bufs := []*TextBuf{mv.BufBase, mv.BufOurs, mv.BufTheirs}
+	labels := []string{"Base", "Ours", "Theirs"}
+	if mv.Merge != nil {
+		labels = []string{"Base: " + mv.Merge.BaseLabel, "Ours: " + mv.Merge.OursLabel, "Theirs: " + mv.Merge.TheirsLabel}
+	}
+	vl := mv.ChildByName("versions", 1).(*gi.Layout)
+	vl.Lay = gi.LayoutHoriz
+	vl.SetStretchMax()
+	for i, nm := range []string{"base", "ours", "theirs"} {
+		mv.configPane(gi.AddNewLayout(vl, nm+"-pane", gi.LayoutVert), nm, labels[i], bufs[i])
+	}
+	rp := mv.ChildByName("result-pane", 2).(*gi.Layout)
+	mv.configPane(rp, "result", "Result: "+DirAndFile(mv.File), mv.BufResult)
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goki/gi/giv/textbuf"
+	"github.com/goki/pi/lex"
+)
+
+func TestMergeViewChoose(t *testing.T) {
+	base := []string{"a", "b", "c", "d", "e"}
+	ours := []string{"a", "B1", "c", "d", "E1"}
+	theirs := []string{"a", "B2", "c", "d", "E2"}
+	mg := textbuf.Merge3(base, ours, theirs)
+
+	mv := &MergeView{}
+	mv.InitName(mv, "merge-view")
+	mv.BufBase = newTestTextBuf()
+	mv.BufOurs = newTestTextBuf()
+	mv.BufTheirs = newTestTextBuf()
+	mv.BufResult = newTestTextBuf()
+	mv.SetMerge(mg)
+	if len(mv.Conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts, got: %d", len(mv.Conflicts))
+	}
+
+	// edit above the second conflict, which must track it
+	mv.BufResult.InsertText(lex.Pos{Ln: 0}, []byte("top\n"), EditNoSignal)
+
+	mv.ChooseConflict(1, textbuf.MergeTheirs)
+	mv.ChooseConflict(0, textbuf.MergeBoth)
+	got := strings.Join(mv.BufResult.Strings(false), "\n")
+	want := strings.Join([]string{"top", "a", "B1", "B2", "c", "d", "E2"}, "\n")
+	if got != want {
+		t.Errorf("result:\n%s\nwant:\n%s", got, want)
+	}
+	if nc, nu := mg.NConflicts(); nc != 2 || nu != 0 {
+		t.Errorf("conflicts: %d unresolved: %d", nc, nu)
+	}
+
+	mv.ChooseConflict(1, textbuf.MergeUnresolved)
+	if !textbuf.HasConflictMarkers(mv.BufResult.Strings(false)) {
+		t.Errorf("expected conflict markers after unresolving:\n%s", strings.Join(mv.BufResult.Strings(false), "\n"))
+	}
+}
+
+func TestVcsMergeFS(t *testing.T) {
+	fsys := newTestDirFS(t, map[string]string{"a.txt": "a\n<<<<<<< ours\nb1\n=======\nb2\n>>>>>>> theirs\nc\n"})
+	mg, err := VcsMerge(nil, fsys, "/root/a.txt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nc, _ := mg.NConflicts(); nc != 1 {
+		t.Errorf("conflicts: %d", nc)
+	}
+	if _, err := VcsMerge(nil, fsys, "/root/b.txt", nil); err == nil {
+		t.Errorf("merged a file that is not in the file system")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/goki/gi/giv/term"
 	"github.com/goki/ki/sliceclone"
 )
 
@@ -22,12 +23,14 @@ type OutBufMarkupFunc func(line []byte) []byte
 // OutBuf is a TextBuf that records the output from an io.Reader using
 // bufio.Scanner -- optimized to combine fast chunks of output into
 // large blocks of updating.  Also supports arbitrary markup function
-// that operates on each line of output bytes.
+// that operates on each line of output bytes, and optionally translates
+// the ANSI color escape sequences in the output into markup.
 type OutBuf struct {
 	Out        io.Reader        `desc:"the output that we are reading from, as an io.Reader"`
 	Buf        *TextBuf         `desc:"the TextBuf that we output to"`
 	BatchMSec  int              `desc:"default 200: how many milliseconds to wait while batching output"`
 	MarkupFun  OutBufMarkupFunc `desc:"optional markup function that adds html tags to given line of output -- essential that it ONLY adds tags, and otherwise has the exact same visible bytes as the input"`
+	AnsiColors bool             `desc:"translate the ANSI SGR color and attribute escape sequences in the output (e.g., colored compiler errors) into markup, and remove other escape sequences -- a carriage return within a line discards the text before it, as on a terminal -- otherwise escape sequences are shown as is"`
 	CurOutLns  [][]byte         `desc:"current buffered output raw lines -- not yet sent to Buf"`
 	CurOutMus  [][]byte         `desc:"current buffered output markup lines -- not yet sent to Buf"`
 	Mu         sync.Mutex       `desc:"mutex protecting updating of CurOutLns and Buf, and timer"`
@@ -54,8 +57,13 @@ func (ob *OutBuf) MonOut() {
 	ob.CurOutMus = make([][]byte, 0, 100)
 	for outscan.Scan() {
 		b := outscan.Bytes()
-		bc := sliceclone.Byte(b) // outscan bytes are temp
-		bec := HTMLEscapeBytes(bc)
+		var bc, bec []byte
+		if ob.AnsiColors {
+			bc, bec = term.MarkupSGR(b)
+		} else {
+			bc = sliceclone.Byte(b) // outscan bytes are temp
+			bec = HTMLEscapeBytes(bc)
+		}
 
 		ob.Mu.Lock()
 		if ob.AfterTimer != nil {
@@ -0,0 +1,24 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOutBufAnsiColors(t *testing.T) {
+	tb := newTestTextBuf()
+	ob := &OutBuf{}
+	ob.Init(strings.NewReader("\x1b[31merror\x1b[0m: bad\n 10%\r100%\n"), tb, 0, nil)
+	ob.AnsiColors = true
+	ob.MonOut()
+	if got := strings.Join(tb.Strings(false), "|"); got != "error: bad|100%|" {
+		t.Errorf("text: %q", got)
+	}
+	if got := string(tb.LineMarkup(0)); got != `<span style="color:#cd3131">error</span>: bad` {
+		t.Errorf("markup: %q", got)
+	}
+}
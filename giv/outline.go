@@ -0,0 +1,565 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strings"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/textbuf"
+	"github.com/goki/gi/units"
+	"github.com/goki/ki/ki"
+	"github.com/goki/ki/kit"
+	"github.com/goki/pi/lex"
+	"github.com/goki/pi/syms"
+	"github.com/goki/pi/token"
+)
+
+/////////////////////////////////////////////////////////////////////////////
+//   OutlineNode
+
+// OutlineNode is a node of the outline of a TextBuf: a symbol of the code,
+// such as a function, type, method or field, from the pi parse of the
+// buffer, or a heading of a Markdown document, with the symbols and
+// headings within it as its children
+type OutlineNode struct {
+	ki.Node
+	Kind      token.Tokens `desc:"kind of symbol, e.g., token.NameFunction, or token.TextStyleHeading for headings"`
+	Lbl       string       `desc:"label shown in the outline -- e.g., the name and signature of functions"`
+	Region    lex.Reg      `desc:"region of the symbol in the buffer"`
+	SelectReg lex.Reg      `desc:"region selected when going to the symbol -- e.g., its name"`
+}
+
+var KiT_OutlineNode = kit.Types.AddType(&OutlineNode{}, nil)
+
+// Label satisfies the gi.Labeler interface
+func (on *OutlineNode) Label() string {
+	return on.Lbl
+}
+
+// OutlineKindIcon returns the icon for given kind of symbol in the outline
+func OutlineKindIcon(kind token.Tokens) gi.IconName {
+	switch {
+	case kind == token.NameMethod:
+		return "method"
+	case kind.SubCat() == token.NameFunction:
+		return "function"
+	case kind == token.NameField || kind == token.NameProperty:
+		return "field"
+	case kind == token.NameConstant || kind == token.NameEnumMember:
+		return "const"
+	case kind.SubCat() == token.NameType:
+		return "type"
+	case kind.SubCat() == token.NameScope:
+		return "package"
+	case kind.SubCat() == token.NameVar:
+		return "var"
+	case kind.InSubCat(token.TextStyleHeading):
+		return "file-markdown"
+	}
+	return "blank"
+}
+
+// NodeAt returns the innermost node of the symbol at given position in the
+// buffer, or nil if there is none
+func (on *OutlineNode) NodeAt(pos lex.Pos) *OutlineNode {
+	for _, k := range on.Kids {
+		if cn := k.(*OutlineNode).NodeAt(pos); cn != nil {
+			return cn
+		}
+	}
+	if on.Par != nil && on.Region.Contains(pos) {
+		return on
+	}
+	return nil
+}
+
+// outlineItem is an item of an outline, before it is made into nodes
+type outlineItem struct {
+	name   string
+	kind   token.Tokens
+	lbl    string
+	reg    lex.Reg
+	selReg lex.Reg
+	kids   []*outlineItem
+}
+
+// ConfigOutline configures the children of the node as the outline of given
+// buffer, with only the symbols whose names contain filter (ignoring case),
+// and the symbols they are in, if it is not empty.  Existing nodes are kept
+// for the same symbols, so that their views are too.
+func (on *OutlineNode) ConfigOutline(tb *TextBuf, filter string) {
+	var its []*outlineItem
+	if tb.Hi.UsingPi() {
+		pfs := tb.PiState.Done()
+		pfs.SymsMu.RLock()
+		its = outlineSyms(pfs.Syms, string(tb.Filename))
+		pfs.SymsMu.RUnlock()
+	}
+	its = append(its, outlineHeadings(tb)...)
+	sortOutline(its)
+	if filter != "" {
+		its = filterOutline(its, strings.ToLower(filter))
+	}
+	on.configItems(its)
+}
+
+// configItems configures the children of the node from given items
+func (on *OutlineNode) configItems(its []*outlineItem) {
+	config := kit.TypeAndNameList{}
+	names := make(map[string]int, len(its))
+	for _, it := range its {
+		nm := it.name
+		if n := names[it.name]; n > 0 { // e.g., methods of the same name
+			nm = fmt.Sprintf("%s_%d", it.name, n)
+		}
+		names[it.name]++
+		config.Add(KiT_OutlineNode, nm)
+	}
+	mods, updt := on.ConfigChildren(config, ki.UniqueNames)
+	for i, it := range its {
+		cn := on.Kids[i].(*OutlineNode)
+		cn.Kind, cn.Lbl, cn.Region, cn.SelectReg = it.kind, it.lbl, it.reg, it.selReg
+		cn.configItems(it.kids)
+	}
+	if mods {
+		on.UpdateEndNoSig(updt)
+	}
+}
+
+// outlineSyms returns the outline items of the symbols of given file, in
+// given symbols and those within them.  The symbols of other files, such as
+// packages, are left out, but the symbols within them that are in the file
+// take their place.  The symbols within functions and variables, such as
+// parameters and local variables, are left out.
+func outlineSyms(sm syms.SymMap, fname string) []*outlineItem {
+	var its []*outlineItem
+	for _, sy := range sm {
+		var kids []*outlineItem
+		if !sy.Kind.InSubCat(token.NameFunction) && !sy.Kind.InSubCat(token.NameVar) {
+			kids = outlineSyms(sy.Children, fname)
+		}
+		if sy.Filename != fname || sy.IsTemp() || sy.Kind.InSubCat(token.NameScope) {
+			its = append(its, kids...)
+			continue
+		}
+		lbl := sy.Label()
+		if sy.Kind.InSubCat(token.NameType) { // type of a type is itself
+			lbl = sy.Name
+		}
+		its = append(its, &outlineItem{name: sy.Name, kind: sy.Kind, lbl: lbl, reg: sy.Region, selReg: sy.SelectReg, kids: kids})
+	}
+	return its
+}
+
+// outlineHeadings returns the outline items of the headings of the buffer,
+// as lexed by pi for Markdown, with the headings within them by level
+func outlineHeadings(tb *TextBuf) []*outlineItem {
+	type heading struct {
+		it    *outlineItem
+		level int
+	}
+	var top []*outlineItem
+	var stack []heading
+	end := func(lvl int, ln int) { // ends the headings at or below given level
+		for len(stack) > 0 && stack[len(stack)-1].level >= lvl {
+			stack[len(stack)-1].it.reg.Ed = lex.Pos{Ln: ln}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	tb.LinesMu.RLock()
+	defer tb.LinesMu.RUnlock()
+	tb.MarkupMu.RLock()
+	defer tb.MarkupMu.RUnlock()
+	nln := tb.NLines
+	for _, bl := range tb.lineState { // only the lines with tags
+		ln, tags := bl.ln, bl.hi
+		if len(tags) == 0 || tags[0].St != 0 || !tags[0].Tok.Tok.InSubCat(token.TextStyleHeading) {
+			continue
+		}
+		txt := strings.TrimRight(string(tb.LineImpl(ln)), " \t")
+		lvl := len(txt) - len(strings.TrimLeft(txt, "#"))
+		if lvl == 0 {
+			lvl = 1
+		}
+		title := strings.TrimSpace(strings.TrimRight(txt[lvl:], "#"))
+		if title == "" {
+			continue
+		}
+		end(lvl, ln)
+		rl := []rune(txt)
+		it := &outlineItem{name: title, kind: tags[0].Tok.Tok, lbl: title, reg: lex.Reg{St: lex.Pos{Ln: ln}}, selReg: lex.Reg{St: lex.Pos{Ln: ln}, Ed: lex.Pos{Ln: ln, Ch: len(rl)}}}
+		if len(stack) == 0 {
+			top = append(top, it)
+		} else {
+			par := stack[len(stack)-1].it
+			par.kids = append(par.kids, it)
+		}
+		stack = append(stack, heading{it, lvl})
+	}
+	end(0, nln)
+	return top
+}
+
+// sortOutline sorts given items, and those within them, by their position
+func sortOutline(its []*outlineItem) {
+	sort.Slice(its, func(i, j int) bool {
+		return its[i].reg.St.IsLess(its[j].reg.St)
+	})
+	for _, it := range its {
+		sortOutline(it.kids)
+	}
+}
+
+// filterOutline returns the items whose names contain given lower-case
+// filter, or that have items within them that do
+func filterOutline(its []*outlineItem, filter string) []*outlineItem {
+	var fits []*outlineItem
+	for _, it := range its {
+		kids := filterOutline(it.kids, filter)
+		if len(kids) > 0 || strings.Contains(strings.ToLower(it.name), filter) {
+			fit := *it
+			fit.kids = kids
+			fits = append(fits, &fit)
+		}
+	}
+	return fits
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//   OutlineView
+
+// OutlineView shows the outline of a TextBuf: the functions, types,
+// methods, fields etc of the code, from the pi parse of the buffer, or the
+// headings of a Markdown document.  It is updated as the buffer is
+// re-parsed, follows the cursor of the TextView by selecting the symbol
+// under it, can be filtered by name, and goes to a symbol when it is
+// clicked.
+type OutlineView struct {
+	gi.Layout
+	Buf     *TextBuf     `json:"-" xml:"-" desc:"the buffer that is outlined"`
+	View    *TextView    `json:"-" xml:"-" desc:"the view of the buffer whose cursor is followed, and in which symbols are shown when clicked"`
+	Filter  string       `desc:"if non-empty, only the symbols whose names contain this text (ignoring case) are shown, with the symbols they are in"`
+	Outline *OutlineNode `json:"-" xml:"-" desc:"root node of the outline, whose children are the top-level symbols"`
+	Cur     *OutlineNode `json:"-" xml:"-" desc:"node of the symbol under the cursor, if any"`
+}
+
+var KiT_OutlineView = kit.Types.AddType(&OutlineView{}, OutlineViewProps)
+
+// AddNewOutlineView adds a new outline view to given parent node, with
+// given name.
+func AddNewOutlineView(parent ki.Ki, name string) *OutlineView {
+	return parent.AddNewChild(KiT_OutlineView, name).(*OutlineView)
+}
+
+// OutlineViewProps are style properties for OutlineView
+var OutlineViewProps = ki.Props{
+	"EnumType:Flag": gi.KiT_NodeFlags,
+	"max-width":     -1,
+	"max-height":    -1,
+}
+
+func (ov *OutlineView) Disconnect() {
+	ov.Layout.Disconnect()
+	if ov.View != nil {
+		ov.View.TextViewSig.Disconnect(ov.This())
+	}
+	if ov.Buf != nil {
+		ov.Buf.TextBufSig.Disconnect(ov.This())
+	}
+}
+
+// Config configures the view, if needed
+func (ov *OutlineView) Config() {
+	ov.Lay = gi.LayoutVert
+	config := kit.TypeAndNameList{}
+	config.Add(gi.KiT_TextField, "filter")
+	config.Add(gi.KiT_Frame, "tree-frame")
+	mods, updt := ov.ConfigChildren(config, ki.UniqueNames)
+	if !mods {
+		return
+	}
+	if ov.Outline == nil {
+		ov.Outline = &OutlineNode{}
+		ov.Outline.InitName(ov.Outline, "outline")
+	}
+	ff := ov.FilterField()
+	ff.Placeholder = "filter"
+	ff.Tooltip = "only show the symbols whose names contain this text, with the symbols they are in"
+	ff.SetStretchMaxWidth()
+	ff.TextFieldSig.Connect(ov.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+		switch gi.TextFieldSignals(sig) {
+		case gi.TextFieldDone, gi.TextFieldInsert, gi.TextFieldBackspace, gi.TextFieldDelete, gi.TextFieldCleared:
+			ovv := recv.Embed(KiT_OutlineView).(*OutlineView)
+			ovv.SetFilter(string(send.(*gi.TextField).EditTxt)) // live text, as it is typed
+		}
+	})
+	tf := ov.ChildByName("tree-frame", 1).(*gi.Frame)
+	tf.Lay = gi.LayoutVert
+	tf.SetStretchMax()
+	tf.SetMinPrefWidth(units.NewCh(30))
+	tf.SetMinPrefHeight(units.NewEm(20))
+	tv := AddNewOutlineTreeView(tf, "tree")
+	tv.SetInactive()
+	tv.SetRootNode(ov.Outline)
+	tv.TreeViewSig.Connect(ov.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+		if sig != int64(TreeViewSelected) {
+			return
+		}
+		ovv := recv.Embed(KiT_OutlineView).(*OutlineView)
+		if on, ok := data.(ki.Ki).Embed(KiT_TreeView).(*TreeView).SrcNode.(*OutlineNode); ok {
+			ovv.GoToNode(on)
+		}
+	})
+	ov.UpdateEnd(updt)
+}
+
+// FilterField returns the text field for the Filter
+func (ov *OutlineView) FilterField() *gi.TextField {
+	return ov.ChildByName("filter", 0).(*gi.TextField)
+}
+
+// TreeView returns the tree view of the outline
+func (ov *OutlineView) TreeView() *OutlineTreeView {
+	return ov.ChildByName("tree-frame", 1).Child(0).(*OutlineTreeView) // named by SetRootNode
+}
+
+// SetTextView sets the view whose buffer is outlined, whose cursor is
+// followed, and in which symbols are shown -- nil to stop outlining
+func (ov *OutlineView) SetTextView(tv *TextView) {
+	if ov.View != nil {
+		ov.View.TextViewSig.Disconnect(ov.This())
+	}
+	ov.View = tv
+	var tb *TextBuf
+	if tv != nil {
+		tb = tv.Buf
+		tv.TextViewSig.Connect(ov.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			if sig == int64(TextViewCursorMoved) {
+				ovv := recv.Embed(KiT_OutlineView).(*OutlineView)
+				ovv.UpdateCur()
+			}
+		})
+	}
+	ov.SetBuf(tb)
+}
+
+// SetBuf sets the buffer that is outlined, and updates the outline -- nil to
+// stop outlining
+func (ov *OutlineView) SetBuf(tb *TextBuf) {
+	if ov.Buf != nil && ov.Buf != tb {
+		ov.Buf.TextBufSig.Disconnect(ov.This())
+	}
+	if tb != nil && ov.Buf != tb {
+		tb.TextBufSig.Connect(ov.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			ovv := recv.Embed(KiT_OutlineView).(*OutlineView)
+			switch TextBufSignals(sig) {
+			case TextBufMarkUpdt, TextBufNew:
+				// markup is updated in another goroutine
+				gi.PostUIFunc(nil, func() {
+					if ovv.Buf == tb {
+						ovv.UpdateOutline()
+					}
+				})
+			case TextBufClosed:
+				ovv.SetBuf(nil)
+			}
+		})
+	}
+	ov.Buf = tb
+	if tb == nil {
+		ov.View = nil
+	}
+	ov.UpdateOutline()
+}
+
+// SetFilter sets the Filter and updates the outline
+func (ov *OutlineView) SetFilter(filter string) {
+	if filter == ov.Filter {
+		return
+	}
+	ov.Filter = filter
+	ov.UpdateOutline()
+}
+
+// UpdateOutline updates the outline from the buffer -- it must be called on
+// the UI goroutine (see gi.PostUIFunc): the buffer is re-parsed in another
+// goroutine, and its updates are posted to the UI goroutine
+func (ov *OutlineView) UpdateOutline() {
+	ov.Config()
+	wupdt := ov.TopUpdateStart()
+	defer ov.TopUpdateEnd(wupdt)
+	updt := ov.Outline.UpdateStart()
+	if ov.Buf == nil {
+		ov.Outline.DeleteChildren(ki.DestroyKids)
+	} else {
+		ov.Outline.ConfigOutline(ov.Buf, ov.Filter)
+	}
+	ov.Outline.UpdateEndNoSig(updt)
+	tv := ov.TreeView()
+	detachDeletedViews(&tv.TreeView)
+	tvIdx := 0
+	tv.SyncToSrc(&tvIdx, false, 0)
+	tv.SetFullReRender()
+	ov.Cur = nil
+	ov.UpdateCur()
+}
+
+// detachDeletedViews deletes the views of the deleted nodes of the outline
+// from the tree without destroying them, so SyncToSrc has none to destroy --
+// ki destroys nodes by walking their fields with pointer arithmetic that the
+// checkptr checks of -race reject, and detached views are not referenced
+func detachDeletedViews(tv *TreeView) {
+	for i := len(tv.Kids) - 1; i >= 0; i-- {
+		vk := tv.Kids[i].Embed(KiT_TreeView).(*TreeView)
+		if sk := vk.SrcNode; sk == nil || sk.IsDeleted() || sk.IsDestroyed() {
+			tv.DeleteChildAtIndex(i, ki.NoDestroyKids)
+			continue
+		}
+		detachDeletedViews(vk)
+	}
+}
+
+// UpdateCur selects the symbol under the cursor of the View in the outline,
+// if it has changed
+func (ov *OutlineView) UpdateCur() {
+	if ov.View == nil || ov.Outline == nil {
+		return
+	}
+	cur := ov.Outline.NodeAt(ov.View.CursorPos)
+	if cur == ov.Cur {
+		return
+	}
+	ov.Cur = cur
+	tv := ov.TreeView()
+	wupdt := tv.TopUpdateStart()
+	defer tv.TopUpdateEnd(wupdt)
+	for _, sv := range tv.SelectedViews() {
+		sv.Unselect()
+	}
+	if cv := tv.NodeView(cur); cv != nil {
+		cv.Select()
+		cv.ScrollToMe()
+	}
+}
+
+// GoToNode shows the symbol of given node in the View (or the first view of
+// the buffer, if not set), selecting it and moving the cursor to it
+func (ov *OutlineView) GoToNode(on *OutlineNode) {
+	tv := ov.View
+	if tv == nil && ov.Buf != nil && len(ov.Buf.Views) > 0 {
+		tv = ov.Buf.Views[0]
+	}
+	if tv == nil {
+		return
+	}
+	reg := textbuf.Region{Start: on.SelectReg.St, End: on.SelectReg.Ed}
+	tv.HighlightRegion(reg)
+	tv.SetCursorShow(reg.Start)
+	tv.GrabFocus()
+	ov.Cur = on
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//   OutlineTreeView
+
+// OutlineTreeView is a TreeView of an outline, showing the icons of the
+// kinds of symbols
+type OutlineTreeView struct {
+	TreeView
+}
+
+var KiT_OutlineTreeView = kit.Types.AddType(&OutlineTreeView{}, nil)
+
+// AddNewOutlineTreeView adds a new outline tree view to given parent node,
+// with given name.
+func AddNewOutlineTreeView(parent ki.Ki, name string) *OutlineTreeView {
+	tv := parent.AddNewChild(KiT_OutlineTreeView, name).(*OutlineTreeView)
+	tv.OpenDepth = 2
+	return tv
+}
+
+func init() {
+	kit.Types.SetProps(KiT_OutlineTreeView, OutlineTreeViewProps)
+}
+
+// NodeView returns the tree view of given outline node, within this one,
+// or nil if there is none
+func (tv *OutlineTreeView) NodeView(on *OutlineNode) *TreeView {
+	if on == nil {
+		return nil
+	}
+	var nv *TreeView
+	tv.FuncDownMeFirst(0, nil, func(k ki.Ki, level int, d interface{}) bool {
+		if nv != nil {
+			return ki.Break
+		}
+		if tvi := k.Embed(KiT_TreeView); tvi != nil && tvi.(*TreeView).SrcNode == on.This() {
+			nv = tvi.(*TreeView)
+			return ki.Break
+		}
+		return ki.Continue
+	})
+	return nv
+}
+
+func (tv *OutlineTreeView) Style2D() {
+	if on, ok := tv.SrcNode.(*OutlineNode); ok && on.Par != nil {
+		tv.Icon = OutlineKindIcon(on.Kind)
+	}
+	tv.StyleTreeView()
+	tv.LayState.SetFromStyle(&tv.Sty.Layout) // also does reset
+}
+
+// OutlineTreeViewProps are style properties for OutlineTreeView
+var OutlineTreeViewProps = ki.Props{
+	"EnumType:Flag":    KiT_TreeViewFlags,
+	"indent":           units.NewCh(2),
+	"spacing":          units.NewCh(.5),
+	"border-width":     units.NewPx(0),
+	"border-radius":    units.NewPx(0),
+	"padding":          units.NewPx(0),
+	"margin":           units.NewPx(1),
+	"text-align":       gi.AlignLeft,
+	"vertical-align":   gi.AlignTop,
+	"color":            &gi.Prefs.Colors.Font,
+	"background-color": "inherit",
+	"no-templates":     true,
+	"#icon": ki.Props{
+		"width":   units.NewEm(1),
+		"height":  units.NewEm(1),
+		"margin":  units.NewPx(0),
+		"padding": units.NewPx(0),
+		"fill":    &gi.Prefs.Colors.Icon,
+		"stroke":  &gi.Prefs.Colors.Font,
+	},
+	"#branch": ki.Props{
+		"icon":             "wedge-down",
+		"icon-off":         "wedge-right",
+		"margin":           units.NewPx(0),
+		"padding":          units.NewPx(0),
+		"background-color": color.Transparent,
+		"max-width":        units.NewEm(.8),
+		"max-height":       units.NewEm(.8),
+	},
+	"#space": ki.Props{
+		"width": units.NewEm(.5),
+	},
+	"#label": ki.Props{
+		"margin":    units.NewPx(0),
+		"padding":   units.NewPx(0),
+		"min-width": units.NewCh(16),
+	},
+	TreeViewSelectors[TreeViewActive]: ki.Props{},
+	TreeViewSelectors[TreeViewSel]: ki.Props{
+		"background-color": &gi.Prefs.Colors.Select,
+	},
+	TreeViewSelectors[TreeViewFocus]: ki.Props{
+		"background-color": &gi.Prefs.Colors.Control,
+	},
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/histyle"
+	"github.com/goki/pi/lex"
+	"github.com/goki/pi/pi"
+)
+
+// openTestPiBuf opens a buffer of given file, with given text, that is
+// parsed by pi -- the histyle styles are set up without the prefs
+func openTestPiBuf(t *testing.T, fname, txt string) *TextBuf {
+	if gi.TheIconMgr == nil {
+		gi.TheIconMgr = &testIconMgr{}
+	}
+	if histyle.AvailStyles == nil {
+		pi.LangSupport.OpenStd()
+		histyle.StdStyles.OpenDefaults()
+		histyle.AvailStyles = histyle.StdStyles
+	}
+	fn := filepath.Join(t.TempDir(), fname)
+	if err := ioutil.WriteFile(fn, []byte(txt), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tb := newTestTextBuf()
+	tb.Spell = &gi.Spell{} // no spelling model without the app
+	if err := tb.Open(gi.FileName(fn)); err != nil {
+		t.Fatal(err)
+	}
+	tb.MarkupAllLines(-1)
+	return tb
+}
+
+// outlineString returns the names of the nodes of the outline, and their
+// icons, indented by depth -- the labels can change as types are resolved
+func outlineString(on *OutlineNode, depth int) string {
+	s := ""
+	for _, k := range on.Kids {
+		cn := k.(*OutlineNode)
+		s += strings.Repeat("  ", depth) + cn.Nm + " " + string(OutlineKindIcon(cn.Kind)) + "\n"
+		s += outlineString(cn, depth+1)
+	}
+	return s
+}
+
+func TestOutlineGo(t *testing.T) {
+	tb := openTestPiBuf(t, "a.go", "package a\n\ntype T struct {\n\tA int\n}\n\nfunc (t *T) M(x int) int {\n\ty := 2\n\treturn y\n}\n\nfunc F() {\n}\n\nconst C = 1\n")
+	on := &OutlineNode{}
+	on.InitName(on, "outline")
+	on.ConfigOutline(tb, "")
+	exp := "T type\n  A field\n  M method\nF function\nC const\n"
+	if got := outlineString(on, 0); got != exp {
+		t.Errorf("outline:\n%v\nexpected:\n%v", got, exp)
+	}
+	kids := on.Kids
+	if cur := on.NodeAt(lex.Pos{Ln: 7, Ch: 2}); cur == nil || cur.Nm != "M" {
+		t.Errorf("node in method: %v", cur)
+	}
+	if cur := on.NodeAt(lex.Pos{Ln: 1}); cur != nil {
+		t.Errorf("node between symbols: %v", cur)
+	}
+
+	on.ConfigOutline(tb, "m")
+	if got := outlineString(on, 0); got != "T type\n  M method\n" {
+		t.Errorf("filtered outline:\n%v", got)
+	}
+	on.ConfigOutline(tb, "")
+	if on.Kids[0] != kids[0] {
+		t.Errorf("nodes not kept")
+	}
+}
+
+func TestOutlineMarkdown(t *testing.T) {
+	tb := openTestPiBuf(t, "a.md", "# Title\n\ntext\n\n## One\n\n```\n# not\n```\n\n### Deep\n\n## Two\n")
+	on := &OutlineNode{}
+	on.InitName(on, "outline")
+	on.ConfigOutline(tb, "")
+	exp := "Title file-markdown\n  One file-markdown\n    Deep file-markdown\n  Two file-markdown\n"
+	if got := outlineString(on, 0); got != exp {
+		t.Errorf("outline:\n%v\nexpected:\n%v", got, exp)
+	}
+	if cur := on.NodeAt(lex.Pos{Ln: 7}); cur == nil || cur.Nm != "One" {
+		t.Errorf("node in section: %v", cur)
+	}
+}
+
+func TestOutlineView(t *testing.T) {
+	tb := openTestPiBuf(t, "a.md", "# Title\n\n## One\n\n## Two\n")
+	ov := &OutlineView{}
+	ov.InitName(ov, "outline-view")
+	ov.SetBuf(tb)
+	tv := ov.TreeView()
+	if nv := tv.NodeView(ov.Outline.Child(0).Child(1).(*OutlineNode)); nv == nil || nv.Label() != "Two" {
+		t.Errorf("tree view not synced: %v", nv)
+	}
+	ov.SetFilter("one")
+	if n := len(tv.Kids); n != 1 || len(*tv.Child(0).Children()) != 1 {
+		t.Errorf("filtered tree view: %v", tv.Kids)
+	}
+
+	// markup is updated in another goroutine, and the outline on the UI one
+	ov.Filter = "two"
+	go tb.TextBufSig.Emit(tb.This(), int64(TextBufMarkUpdt), nil)
+	waitFor(t, "outline update", func() bool { return ov.Outline.Child(0).Child(0).Name() == "Two" })
+	if nv := tv.NodeView(ov.Outline.Child(0).Child(0).(*OutlineNode)); nv == nil || nv.Label() != "Two" {
+		t.Errorf("tree view not updated: %v", nv)
+	}
+}
@@ -0,0 +1,1179 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"log"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/oswin"
+	"github.com/goki/gi/oswin/mouse"
+	"github.com/goki/gi/units"
+	"github.com/goki/ki/ints"
+	"github.com/goki/ki/ki"
+	"github.com/goki/ki/kit"
+	"github.com/goki/mat32"
+)
+
+// PlotTypes are the different ways of drawing a PlotSeries
+type PlotTypes int32
+
+const (
+	// PlotLine draws the series as a line connecting the points
+	PlotLine PlotTypes = iota
+
+	// PlotScatter draws each point as a separate marker
+	PlotScatter
+
+	// PlotBar draws a bar from the zero baseline to each point
+	PlotBar
+
+	PlotTypesN
+)
+
+//go:generate stringer -type=PlotTypes
+
+var KiT_PlotTypes = kit.Enums.AddEnumAltLower(PlotTypesN, kit.NotBitFlag, nil, "Plot")
+
+func (ev PlotTypes) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *PlotTypes) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+// PlotSeries is one series of data to plot, as X, Y pairs of values
+type PlotSeries struct {
+	Name      string    `desc:"name of the series, shown in the legend and in hover tooltips"`
+	Type      PlotTypes `desc:"how to draw this series"`
+	X         []float64 `desc:"X axis values -- must be same length as Y"`
+	Y         []float64 `desc:"Y axis values"`
+	Color     gi.Color  `desc:"color to draw with -- if nil (transparent) then a color is chosen from the Plot ColorMap"`
+	LineWidth float32   `desc:"width of lines in raw display dots (for PlotLine)"`
+	PointSize float32   `desc:"radius of point markers in raw display dots (PlotScatter, and PlotLine if > 0)"`
+	Off       bool      `desc:"if true, this series is not drawn"`
+}
+
+// Len returns the number of points in the series
+func (ps *PlotSeries) Len() int {
+	return ints.MinInt(len(ps.X), len(ps.Y))
+}
+
+// PlotRange is a range of data values along both plot axes
+type PlotRange struct {
+	XMin float64
+	XMax float64
+	YMin float64
+	YMax float64
+}
+
+// PlotAxis contains the parameters for one axis of a Plot
+type PlotAxis struct {
+	Label  string  `desc:"label shown alongside the axis"`
+	Log    bool    `desc:"use a log10 scale -- values <= 0 are not plotted"`
+	FixMin bool    `desc:"use Min as the minimum instead of the minimum of the data"`
+	Min    float64 `desc:"fixed minimum value, if FixMin is set"`
+	FixMax bool    `desc:"use Max as the maximum instead of the maximum of the data"`
+	Max    float64 `desc:"fixed maximum value, if FixMax is set"`
+	NTicks int     `desc:"target number of tick marks along the axis"`
+}
+
+// Defaults sets default parameters
+func (pa *PlotAxis) Defaults() {
+	if pa.NTicks == 0 {
+		pa.NTicks = 6
+	}
+}
+
+// Fwd transforms a data value into axis space (log10 for Log axes).
+// Values that cannot be shown return NaN.
+func (pa *PlotAxis) Fwd(v float64) float64 {
+	if pa.Log {
+		if v <= 0 {
+			return math.NaN()
+		}
+		return math.Log10(v)
+	}
+	return v
+}
+
+// Inv transforms an axis-space value back into a data value
+func (pa *PlotAxis) Inv(t float64) float64 {
+	if pa.Log {
+		return math.Pow(10, t)
+	}
+	return t
+}
+
+// Ticks returns the positions of the tick marks for given range of data values
+func (pa *PlotAxis) Ticks(min, max float64) []float64 {
+	nt := pa.NTicks
+	if nt < 2 {
+		nt = 2
+	}
+	if pa.Log && min > 0 {
+		lmin := math.Floor(math.Log10(min))
+		lmax := math.Ceil(math.Log10(max))
+		if lmax-lmin >= 1 {
+			step := math.Max(1, math.Ceil((lmax-lmin)/float64(nt)))
+			var ticks []float64
+			for t := lmin; t <= lmax; t += step {
+				v := math.Pow(10, t)
+				if v >= min && v <= max {
+					ticks = append(ticks, v)
+				}
+			}
+			if len(ticks) >= 2 {
+				return ticks
+			}
+		}
+	}
+	return PlotNiceTicks(min, max, nt)
+}
+
+// PlotNiceNum returns a "nice" number approximately equal to x: 1, 2, or 5
+// times a power of 10.  If round then the number is rounded, otherwise
+// the ceiling is taken.
+func PlotNiceNum(x float64, round bool) float64 {
+	if x <= 0 || math.IsNaN(x) || math.IsInf(x, 0) {
+		return 0
+	}
+	exp := math.Floor(math.Log10(x))
+	f := x / math.Pow(10, exp)
+	var nf float64
+	if round {
+		switch {
+		case f < 1.5:
+			nf = 1
+		case f < 3:
+			nf = 2
+		case f < 7:
+			nf = 5
+		default:
+			nf = 10
+		}
+	} else {
+		switch {
+		case f <= 1:
+			nf = 1
+		case f <= 2:
+			nf = 2
+		case f <= 5:
+			nf = 5
+		default:
+			nf = 10
+		}
+	}
+	return nf * math.Pow(10, exp)
+}
+
+// PlotNiceStep returns the spacing between nice tick values for given range
+// and target number of ticks
+func PlotNiceStep(min, max float64, nticks int) float64 {
+	if nticks < 2 {
+		nticks = 2
+	}
+	rng := PlotNiceNum(max-min, false)
+	return PlotNiceNum(rng/float64(nticks-1), true)
+}
+
+// PlotNiceTicks returns tick mark values within min..max inclusive, spaced at
+// nice round numbers, with approximately nticks values.
+func PlotNiceTicks(min, max float64, nticks int) []float64 {
+	if max < min {
+		min, max = max, min
+	}
+	step := PlotNiceStep(min, max, nticks)
+	if step == 0 {
+		return []float64{min}
+	}
+	st := math.Ceil(min/step-1.0e-9) * step
+	var ticks []float64
+	for i := 0; i < 1000; i++ {
+		v := st + float64(i)*step
+		if v > max+step*1.0e-9 {
+			break
+		}
+		if math.Abs(v) < step*1.0e-9 {
+			v = 0 // avoid -0 and 1e-17 labels
+		}
+		ticks = append(ticks, v)
+	}
+	return ticks
+}
+
+// PlotTickLabel returns the label string for a tick at value v, for ticks
+// spaced at given step (0 = unknown spacing)
+func PlotTickLabel(v, step float64) string {
+	if v == 0 {
+		return "0"
+	}
+	av := math.Abs(v)
+	if av >= 1.0e6 || av < 1.0e-4 || step <= 0 {
+		return strconv.FormatFloat(v, 'g', 4, 64)
+	}
+	prec := int(math.Max(0, -math.Floor(math.Log10(step)+1.0e-9)))
+	return strconv.FormatFloat(v, 'f', prec, 64)
+}
+
+// PlotFloats converts a slice of any numeric type (e.g., []float32,
+// []float64, []int) into a []float64 -- returns nil if not a slice
+func PlotFloats(slc interface{}) []float64 {
+	if slc == nil {
+		return nil
+	}
+	switch fs := slc.(type) {
+	case []float64:
+		return fs
+	case []float32:
+		fl := make([]float64, len(fs))
+		for i, f := range fs {
+			fl[i] = float64(f)
+		}
+		return fl
+	}
+	sv := kit.NonPtrValue(reflect.ValueOf(slc))
+	if sv.Kind() != reflect.Slice && sv.Kind() != reflect.Array {
+		return nil
+	}
+	n := sv.Len()
+	fl := make([]float64, n)
+	for i := 0; i < n; i++ {
+		f, ok := kit.ToFloat(sv.Index(i).Interface())
+		if !ok {
+			f = math.NaN()
+		}
+		fl[i] = f
+	}
+	return fl
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//  Plot
+
+// Plot is a widget that draws one or more series of data as line, scatter,
+// or bar charts, with axes, tick labels, an optional legend and grid lines.
+// The mouse can be dragged to pan, the scroll wheel zooms around the mouse
+// position, double-click resets the view, and hovering over a point shows
+// its value.  Series without an explicit color are colored using ColorMap.
+type Plot struct {
+	gi.WidgetBase
+	Title     string        `desc:"title shown above the plot"`
+	Series    []*PlotSeries `desc:"the data series to plot"`
+	XAxis     PlotAxis      `desc:"parameters for the X (horizontal) axis"`
+	YAxis     PlotAxis      `desc:"parameters for the Y (vertical) axis"`
+	Legend    bool          `desc:"show a legend listing the series"`
+	Grid      bool          `desc:"draw grid lines at each tick mark"`
+	ColorMap  ColorMapName  `desc:"color map used for series that do not have a Color set"`
+	HoverDist float32       `desc:"maximum distance in raw display dots from a point for hover to show its value"`
+	View      PlotRange     `json:"-" xml:"-" view:"-" desc:"current range of data values in view -- set by pan / zoom, and otherwise automatically from the data"`
+	Zoomed    bool          `json:"-" xml:"-" view:"-" desc:"true if View has been set by panning or zooming"`
+	PlotPos   mat32.Vec2    `json:"-" xml:"-" view:"-" desc:"position of the plotting area within the viewport, from the last render"`
+	PlotSize  mat32.Vec2    `json:"-" xml:"-" view:"-" desc:"size of the plotting area, from the last render"`
+}
+
+var KiT_Plot = kit.Types.AddType(&Plot{}, PlotProps)
+
+// AddNewPlot adds a new plot to given parent node, with given name.
+func AddNewPlot(parent ki.Ki, name string) *Plot {
+	pl := parent.AddNewChild(KiT_Plot, name).(*Plot)
+	pl.Defaults()
+	return pl
+}
+
+func (pl *Plot) CopyFieldsFrom(frm interface{}) {
+	fr := frm.(*Plot)
+	pl.WidgetBase.CopyFieldsFrom(&fr.WidgetBase)
+	pl.Title = fr.Title
+	pl.Series = make([]*PlotSeries, len(fr.Series))
+	for i, ps := range fr.Series {
+		cp := *ps
+		pl.Series[i] = &cp
+	}
+	pl.XAxis = fr.XAxis
+	pl.YAxis = fr.YAxis
+	pl.Legend = fr.Legend
+	pl.Grid = fr.Grid
+	pl.ColorMap = fr.ColorMap
+	pl.HoverDist = fr.HoverDist
+}
+
+func (pl *Plot) AccessInfo() gi.AccessInfo {
+	ai := pl.WidgetBase.AccessInfo()
+	ai.Role = gi.AccessChart
+	ai.Name = gi.AccessPlainText(pl.Title)
+	return ai
+}
+
+// Defaults sets default parameters
+func (pl *Plot) Defaults() {
+	pl.XAxis.Defaults()
+	pl.YAxis.Defaults()
+	pl.Legend = true
+	pl.Grid = true
+	if pl.ColorMap == "" {
+		pl.ColorMap = "JetMuted"
+	}
+	if pl.HoverDist == 0 {
+		pl.HoverDist = 8
+	}
+}
+
+// AddSeries adds a new series of data to the plot.  x and y can be slices
+// of any numeric type (e.g., []float32, []float64) -- if x is nil, then
+// the index of each y value is used.  Call Update() after adding data
+// to redraw the plot.
+func (pl *Plot) AddSeries(name string, typ PlotTypes, x, y interface{}) *PlotSeries {
+	ps := &PlotSeries{Name: name, Type: typ, LineWidth: 2, PointSize: 3}
+	ps.Y = PlotFloats(y)
+	if x == nil {
+		ps.X = make([]float64, len(ps.Y))
+		for i := range ps.X {
+			ps.X[i] = float64(i)
+		}
+	} else {
+		ps.X = PlotFloats(x)
+	}
+	if len(ps.X) != len(ps.Y) {
+		log.Printf("giv.Plot.AddSeries -- series: %v has different number of X: %v and Y: %v values\n", name, len(ps.X), len(ps.Y))
+	}
+	pl.Series = append(pl.Series, ps)
+	return ps
+}
+
+// SetStructSlice sets the series of the plot from a slice of structs (or
+// pointers to structs), in the same way that TableView presents such a
+// slice.  Each numeric field becomes a separate series, except for the
+// field used for the X axis, and fields tagged with plot:"-" or view:"-".
+// The X axis field is xField if non-empty, otherwise the field tagged
+// plot:"x", and otherwise the row index.  A plot:"line", plot:"scatter"
+// or plot:"bar" tag sets the type of the series, otherwise typ is used.
+// Returns an error if the X axis field is missing or not a number.
+func (pl *Plot) SetStructSlice(slc interface{}, xField string, typ PlotTypes) error {
+	sv := kit.NonPtrValue(reflect.ValueOf(slc))
+	if sv.Kind() != reflect.Slice {
+		return fmt.Errorf("giv.Plot.SetStructSlice: value is not a slice: %v", sv.Kind())
+	}
+	styp := kit.NonPtrType(kit.SliceElType(slc))
+	if styp.Kind() != reflect.Struct {
+		return fmt.Errorf("giv.Plot.SetStructSlice: slice elements are not structs: %v", styp.Kind())
+	}
+	n := sv.Len()
+	if xField == "" {
+		for fi := 0; fi < styp.NumField(); fi++ {
+			if styp.Field(fi).Tag.Get("plot") == "x" {
+				xField = styp.Field(fi).Name
+				break
+			}
+		}
+	}
+	var xs []float64
+	if xField != "" {
+		xf, has := styp.FieldByName(xField)
+		if !has {
+			return fmt.Errorf("giv.Plot.SetStructSlice: X field: %v not found in type: %v", xField, styp.Name())
+		}
+		if fk := xf.Type.Kind(); fk < reflect.Int || fk > reflect.Float64 {
+			return fmt.Errorf("giv.Plot.SetStructSlice: X field: %v in type: %v is not a number: %v", xField, styp.Name(), fk)
+		}
+	}
+	pl.Series = nil
+	for fi := 0; fi < styp.NumField(); fi++ {
+		f := styp.Field(fi)
+		ptag := f.Tag.Get("plot")
+		if f.PkgPath != "" || ptag == "-" || f.Tag.Get("view") == "-" {
+			continue
+		}
+		if fk := f.Type.Kind(); fk < reflect.Int || fk > reflect.Float64 {
+			continue // not a number
+		}
+		vals := make([]float64, n)
+		for i := 0; i < n; i++ {
+			fv := kit.NonPtrValue(sv.Index(i)).Field(fi)
+			v, ok := kit.ToFloat(fv.Interface())
+			if !ok {
+				v = math.NaN()
+			}
+			vals[i] = v
+		}
+		if f.Name == xField {
+			xs = vals
+			continue
+		}
+		ptyp := typ
+		if ptag != "" && ptag != "x" {
+			if err := ptyp.FromString("Plot" + strings.Title(ptag)); err != nil {
+				log.Printf("giv.Plot.SetStructSlice -- field: %v has invalid plot tag: %v\n", f.Name, ptag)
+				ptyp = typ
+			}
+		}
+		pl.AddSeries(f.Name, ptyp, nil, vals)
+	}
+	if xs != nil {
+		for _, ps := range pl.Series {
+			ps.X = xs
+		}
+		if pl.XAxis.Label == "" {
+			pl.XAxis.Label = xField
+		}
+	}
+	return nil
+}
+
+// Update updates the display of the plot after the data has changed,
+// resetting any pan / zoom
+func (pl *Plot) Update() {
+	pl.ResetView()
+}
+
+// ResetView resets any pan / zoom, so the view shows all the data
+func (pl *Plot) ResetView() {
+	pl.Zoomed = false
+	pl.UpdateSig()
+}
+
+// SeriesColor returns the color to use for given series index
+func (pl *Plot) SeriesColor(idx int) gi.Color {
+	ps := pl.Series[idx]
+	if !ps.Color.IsNil() {
+		return ps.Color
+	}
+	cm, ok := AvailColorMaps[string(pl.ColorMap)]
+	if !ok {
+		cm = StdColorMaps["JetMuted"]
+	}
+	n := len(pl.Series)
+	if n <= 1 {
+		return cm.Map(0)
+	}
+	return cm.Map(float64(idx) / float64(n-1))
+}
+
+// DataRange returns the range of values across all the series that are on,
+// taking into account the fixed axis settings and the axis scales.
+// Bar series include the zero baseline.
+func (pl *Plot) DataRange() PlotRange {
+	xmin, ymin := math.Inf(1), math.Inf(1)
+	xmax, ymax := math.Inf(-1), math.Inf(-1)
+	for _, ps := range pl.Series {
+		if ps.Off {
+			continue
+		}
+		n := ps.Len()
+		for i := 0; i < n; i++ {
+			x, y := ps.X[i], ps.Y[i]
+			if math.IsNaN(pl.XAxis.Fwd(x)) || math.IsNaN(pl.YAxis.Fwd(y)) || math.IsInf(x, 0) || math.IsInf(y, 0) {
+				continue
+			}
+			xmin = math.Min(xmin, x)
+			xmax = math.Max(xmax, x)
+			ymin = math.Min(ymin, y)
+			ymax = math.Max(ymax, y)
+		}
+		if ps.Type == PlotBar && !pl.YAxis.Log && n > 0 {
+			ymin = math.Min(ymin, 0)
+			ymax = math.Max(ymax, 0)
+		}
+	}
+	if math.IsInf(xmin, 1) {
+		xmin, xmax = pl.XAxis.Inv(0), pl.XAxis.Inv(1)
+	}
+	if math.IsInf(ymin, 1) {
+		ymin, ymax = pl.YAxis.Inv(0), pl.YAxis.Inv(1)
+	}
+	xmin, xmax = plotPadRange(&pl.XAxis, xmin, xmax)
+	ymin, ymax = plotPadRange(&pl.YAxis, ymin, ymax)
+	return PlotRange{XMin: xmin, XMax: xmax, YMin: ymin, YMax: ymax}
+}
+
+// plotPadRange adds a small margin around the data range, in axis space,
+// and applies any fixed min / max for the axis
+func plotPadRange(ax *PlotAxis, min, max float64) (float64, float64) {
+	tmin, tmax := ax.Fwd(min), ax.Fwd(max)
+	if tmax == tmin {
+		d := math.Max(math.Abs(tmin)*.1, .5)
+		tmin -= d
+		tmax += d
+	} else {
+		d := (tmax - tmin) * 0.05
+		if !(min == 0 && !ax.Log) { // keep zero baseline at the edge
+			tmin -= d
+		}
+		tmax += d
+	}
+	min, max = ax.Inv(tmin), ax.Inv(tmax)
+	if ax.FixMin {
+		min = ax.Min
+	}
+	if ax.FixMax {
+		max = ax.Max
+	}
+	return min, max
+}
+
+// CurView returns the current range of data in view -- the panned /
+// zoomed View if Zoomed, and otherwise the DataRange
+func (pl *Plot) CurView() PlotRange {
+	if !pl.Zoomed {
+		pl.View = pl.DataRange()
+	}
+	return pl.View
+}
+
+// DataToPixel converts data values into position within the viewport,
+// based on the plotting area from the last render
+func (pl *Plot) DataToPixel(x, y float64) mat32.Vec2 {
+	vw := &pl.View
+	xmin, xmax := pl.XAxis.Fwd(vw.XMin), pl.XAxis.Fwd(vw.XMax)
+	ymin, ymax := pl.YAxis.Fwd(vw.YMin), pl.YAxis.Fwd(vw.YMax)
+	xn := (pl.XAxis.Fwd(x) - xmin) / (xmax - xmin)
+	yn := (pl.YAxis.Fwd(y) - ymin) / (ymax - ymin)
+	return mat32.Vec2{pl.PlotPos.X + float32(xn)*pl.PlotSize.X, pl.PlotPos.Y + (1-float32(yn))*pl.PlotSize.Y}
+}
+
+// PixelToData converts a position within the viewport into data values,
+// based on the plotting area from the last render
+func (pl *Plot) PixelToData(pt mat32.Vec2) (x, y float64) {
+	vw := &pl.View
+	xmin, xmax := pl.XAxis.Fwd(vw.XMin), pl.XAxis.Fwd(vw.XMax)
+	ymin, ymax := pl.YAxis.Fwd(vw.YMin), pl.YAxis.Fwd(vw.YMax)
+	xn := float64((pt.X - pl.PlotPos.X) / pl.PlotSize.X)
+	yn := float64(1 - (pt.Y-pl.PlotPos.Y)/pl.PlotSize.Y)
+	x = pl.XAxis.Inv(xmin + xn*(xmax-xmin))
+	y = pl.YAxis.Inv(ymin + yn*(ymax-ymin))
+	return
+}
+
+// Pan shifts the view by given number of raw display dots
+func (pl *Plot) Pan(dx, dy float32) {
+	if pl.PlotSize.X <= 0 || pl.PlotSize.Y <= 0 {
+		return
+	}
+	vw := pl.CurView()
+	xmin, xmax := pl.XAxis.Fwd(vw.XMin), pl.XAxis.Fwd(vw.XMax)
+	ymin, ymax := pl.YAxis.Fwd(vw.YMin), pl.YAxis.Fwd(vw.YMax)
+	xd := -float64(dx/pl.PlotSize.X) * (xmax - xmin)
+	yd := float64(dy/pl.PlotSize.Y) * (ymax - ymin)
+	pl.View = PlotRange{XMin: pl.XAxis.Inv(xmin + xd), XMax: pl.XAxis.Inv(xmax + xd),
+		YMin: pl.YAxis.Inv(ymin + yd), YMax: pl.YAxis.Inv(ymax + yd)}
+	pl.Zoomed = true
+	pl.UpdateSig()
+}
+
+// Zoom scales the view around given position in the viewport by given
+// factor -- factors > 1 zoom out, < 1 zoom in
+func (pl *Plot) Zoom(pt mat32.Vec2, factor float64) {
+	if pl.PlotSize.X <= 0 || pl.PlotSize.Y <= 0 || factor <= 0 {
+		return
+	}
+	vw := pl.CurView()
+	cx, cy := pl.PixelToData(pt)
+	zm := func(ax *PlotAxis, min, max, c float64) (float64, float64) {
+		tc := ax.Fwd(c)
+		tmin, tmax := ax.Fwd(min), ax.Fwd(max)
+		if math.IsNaN(tc) {
+			tc = 0.5 * (tmin + tmax)
+		}
+		return ax.Inv(tc + (tmin-tc)*factor), ax.Inv(tc + (tmax-tc)*factor)
+	}
+	pl.View.XMin, pl.View.XMax = zm(&pl.XAxis, vw.XMin, vw.XMax, cx)
+	pl.View.YMin, pl.View.YMax = zm(&pl.YAxis, vw.YMin, vw.YMax, cy)
+	pl.Zoomed = true
+	pl.UpdateSig()
+}
+
+// PointAt returns the series and index of the data point closest to given
+// position in the viewport, within HoverDist -- returns nil if none
+func (pl *Plot) PointAt(pt mat32.Vec2) (*PlotSeries, int) {
+	var best *PlotSeries
+	bi := -1
+	bd := pl.HoverDist * pl.HoverDist
+	for _, ps := range pl.Series {
+		if ps.Off {
+			continue
+		}
+		n := ps.Len()
+		for i := 0; i < n; i++ {
+			pp := pl.DataToPixel(ps.X[i], ps.Y[i])
+			if mat32.IsNaN(pp.X) || mat32.IsNaN(pp.Y) {
+				continue
+			}
+			d := pp.Sub(pt)
+			dd := d.X*d.X + d.Y*d.Y
+			if dd <= bd {
+				best = ps
+				bi = i
+				bd = dd
+			}
+		}
+	}
+	return best, bi
+}
+
+// SavePNG saves the currently rendered plot to a PNG image file
+func (pl *Plot) SavePNG(filename gi.FileName) error {
+	img := gi.GrabRenderFrom(pl.This().(gi.Node2D))
+	if img == nil {
+		err := fmt.Errorf("giv.Plot.SavePNG: plot is not currently rendered -- could not save: %v", filename)
+		log.Println(err)
+		return err
+	}
+	err := gi.SaveImage(string(filename), img)
+	if err != nil {
+		log.Println(err)
+	}
+	return err
+}
+
+// SaveSVG saves the plot as an SVG vector graphics file, at the size of
+// the last render
+func (pl *Plot) SaveSVG(filename gi.FileName) error {
+	b := pl.SVG()
+	err := ioutil.WriteFile(string(filename), b, 0644)
+	if err != nil {
+		log.Println(err)
+	}
+	return err
+}
+
+// SVG returns the plot as SVG vector graphics, at the size of the last render
+func (pl *Plot) SVG() []byte {
+	pl.StyMu.RLock()
+	defer pl.StyMu.RUnlock()
+	st := &pl.Sty
+	sz := pl.LayState.Alloc.Size
+	if sz.X <= 0 || sz.Y <= 0 {
+		sz = mat32.Vec2{640, 480}
+	}
+	sp := &plotSVGPainter{plotTextMeas: plotTextMeas{sty: st}}
+	fmt.Fprintf(&sp.buf, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%g\" height=\"%g\" viewBox=\"0 0 %g %g\">\n", sz.X, sz.Y, sz.X, sz.Y)
+	sp.Rect(0, 0, sz.X, sz.Y, st.Font.BgColor.Color)
+	vp := pl.PlotPos
+	vs := pl.PlotSize
+	pl.RenderPlot(sp, mat32.Vec2{}, sz)
+	pl.PlotPos, pl.PlotSize = vp, vs // restore on-screen geometry for mouse events
+	sp.buf.WriteString("</svg>\n")
+	return sp.buf.Bytes()
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//  Rendering
+
+// plotPainter is the drawing interface used by RenderPlot, so that the same
+// layout code drives both on-screen rendering and SVG export.
+// All positions and sizes are in raw display dots.
+type plotPainter interface {
+	Line(x1, y1, x2, y2 float32, clr gi.Color, width float32)
+	Polyline(pts []mat32.Vec2, clr gi.Color, width float32)
+	Rect(x, y, w, h float32, fill gi.Color)
+	Circle(x, y, r float32, fill gi.Color)
+
+	// Text draws text with its upper-left corner at x, y -- vert rotates it
+	// to read from bottom to top
+	Text(str string, x, y float32, vert bool)
+
+	// TextSize returns the size of given text as it would be drawn horizontally
+	TextSize(str string) mat32.Vec2
+}
+
+// plotTextMeas measures text using the style of the plot
+type plotTextMeas struct {
+	sty *gi.Style
+	tr  gi.TextRender
+}
+
+func (tm *plotTextMeas) TextSize(str string) mat32.Vec2 {
+	tm.tr.SetString(str, &tm.sty.Font, &tm.sty.UnContext, &tm.sty.Text, true, 0, 0)
+	return tm.tr.Size
+}
+
+// plotPaintPainter renders to the viewport using gi.Paint
+type plotPaintPainter struct {
+	plotTextMeas
+	rs *gi.RenderState
+	pc *gi.Paint
+}
+
+func (pp *plotPaintPainter) Line(x1, y1, x2, y2 float32, clr gi.Color, width float32) {
+	pp.pc.FillStyle.SetColor(nil)
+	pp.pc.StrokeStyle.SetColor(clr)
+	pp.pc.StrokeStyle.Width.Dots = width
+	pp.pc.DrawLine(pp.rs, x1, y1, x2, y2)
+	pp.pc.Stroke(pp.rs)
+}
+
+func (pp *plotPaintPainter) Polyline(pts []mat32.Vec2, clr gi.Color, width float32) {
+	pp.pc.FillStyle.SetColor(nil)
+	pp.pc.StrokeStyle.SetColor(clr)
+	pp.pc.StrokeStyle.Width.Dots = width
+	pp.pc.StrokeStyle.Join = gi.LineJoinRound
+	pp.pc.DrawPolyline(pp.rs, pts)
+	pp.pc.Stroke(pp.rs)
+}
+
+func (pp *plotPaintPainter) Rect(x, y, w, h float32, fill gi.Color) {
+	pp.pc.FillBoxColor(pp.rs, mat32.Vec2{x, y}, mat32.Vec2{w, h}, fill)
+}
+
+func (pp *plotPaintPainter) Circle(x, y, r float32, fill gi.Color) {
+	pp.pc.StrokeStyle.SetColor(nil)
+	pp.pc.FillStyle.SetColor(fill)
+	pp.pc.DrawCircle(pp.rs, x, y, r)
+	pp.pc.Fill(pp.rs)
+}
+
+func (pp *plotPaintPainter) Text(str string, x, y float32, vert bool) {
+	st := pp.sty
+	if vert {
+		pp.tr.SetStringRot90(str, &st.Font, &st.UnContext, &st.Text, true, 0)
+		pp.tr.Render(pp.rs, mat32.Vec2{x, y})
+		return
+	}
+	pp.tr.SetString(str, &st.Font, &st.UnContext, &st.Text, true, 0, 0)
+	pp.tr.RenderTopPos(pp.rs, mat32.Vec2{x, y})
+}
+
+// plotSVGPainter writes SVG elements to a buffer
+type plotSVGPainter struct {
+	plotTextMeas
+	buf bytes.Buffer
+}
+
+func (sp *plotSVGPainter) Line(x1, y1, x2, y2 float32, clr gi.Color, width float32) {
+	fmt.Fprintf(&sp.buf, "<line x1=\"%g\" y1=\"%g\" x2=\"%g\" y2=\"%g\" stroke=\"%s\" stroke-width=\"%g\" />\n", x1, y1, x2, y2, clr.HexString(), width)
+}
+
+func (sp *plotSVGPainter) Polyline(pts []mat32.Vec2, clr gi.Color, width float32) {
+	sp.buf.WriteString("<polyline points=\"")
+	for i, p := range pts {
+		if i > 0 {
+			sp.buf.WriteString(" ")
+		}
+		fmt.Fprintf(&sp.buf, "%g,%g", p.X, p.Y)
+	}
+	fmt.Fprintf(&sp.buf, "\" fill=\"none\" stroke=\"%s\" stroke-width=\"%g\" stroke-linejoin=\"round\" />\n", clr.HexString(), width)
+}
+
+func (sp *plotSVGPainter) Rect(x, y, w, h float32, fill gi.Color) {
+	fmt.Fprintf(&sp.buf, "<rect x=\"%g\" y=\"%g\" width=\"%g\" height=\"%g\" fill=\"%s\" />\n", x, y, w, h, fill.HexString())
+}
+
+func (sp *plotSVGPainter) Circle(x, y, r float32, fill gi.Color) {
+	fmt.Fprintf(&sp.buf, "<circle cx=\"%g\" cy=\"%g\" r=\"%g\" fill=\"%s\" />\n", x, y, r, fill.HexString())
+}
+
+func (sp *plotSVGPainter) Text(str string, x, y float32, vert bool) {
+	st := sp.sty
+	tsz := sp.TextSize(str)
+	asc := 0.8 * tsz.Y
+	var b, fb bytes.Buffer
+	xmlEscapeText(&b, str)
+	fam := st.Font.Family
+	if fam == "" {
+		fam = "sans-serif"
+	}
+	xmlEscapeText(&fb, fam)
+	if vert {
+		fmt.Fprintf(&sp.buf, "<text transform=\"translate(%g,%g) rotate(-90)\" font-family=\"%s\" font-size=\"%g\" fill=\"%s\">%s</text>\n", x+asc, y+tsz.X, fb.String(), st.Font.Size.Dots, st.Font.Color.HexString(), b.String())
+		return
+	}
+	fmt.Fprintf(&sp.buf, "<text x=\"%g\" y=\"%g\" font-family=\"%s\" font-size=\"%g\" fill=\"%s\">%s</text>\n", x, y+asc, fb.String(), st.Font.Size.Dots, st.Font.Color.HexString(), b.String())
+}
+
+// xmlEscapeText writes given string to buffer with XML special chars escaped
+func xmlEscapeText(b *bytes.Buffer, str string) {
+	for _, r := range str {
+		switch r {
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		case '&':
+			b.WriteString("&amp;")
+		case '"':
+			b.WriteString("&quot;")
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+// RenderPlot lays out and draws the plot within given box, using given
+// painter -- sets PlotPos and PlotSize to the resulting plotting area
+func (pl *Plot) RenderPlot(pp plotPainter, pos, sz mat32.Vec2) {
+	st := &pl.Sty
+	fg := st.Font.Color
+	bg := st.Font.BgColor.Color
+	gridClr := fg.Blend(85, bg)
+	axClr := fg.Blend(30, bg)
+	vw := pl.CurView()
+
+	xticks := pl.XAxis.Ticks(vw.XMin, vw.XMax)
+	yticks := pl.YAxis.Ticks(vw.YMin, vw.YMax)
+	xstep := plotTickStep(&pl.XAxis, xticks)
+	ystep := plotTickStep(&pl.YAxis, yticks)
+
+	lh := pp.TextSize("0").Y
+	tick := 0.3 * lh
+
+	top := pos.Y
+	if pl.Title != "" {
+		tsz := pp.TextSize(pl.Title)
+		pp.Text(pl.Title, pos.X+0.5*(sz.X-tsz.X), top, false)
+		top += 1.5 * lh
+	} else {
+		top += 0.5 * lh
+	}
+	left := pos.X
+	if pl.YAxis.Label != "" {
+		left += 1.5 * lh
+	}
+	ylbls := make([]string, len(yticks))
+	ylw := float32(0)
+	for i, t := range yticks {
+		ylbls[i] = PlotTickLabel(t, ystep)
+		ylw = mat32.Max(ylw, pp.TextSize(ylbls[i]).X)
+	}
+	left += ylw + tick + 0.25*lh
+	bot := pos.Y + sz.Y - (lh + tick + 0.25*lh)
+	if pl.XAxis.Label != "" {
+		bot -= 1.5 * lh
+	}
+	right := pos.X + sz.X - 0.5*lh
+	pl.PlotPos = mat32.Vec2{left, top}
+	pl.PlotSize = mat32.Vec2{right - left, bot - top}
+	if pl.PlotSize.X <= 1 || pl.PlotSize.Y <= 1 {
+		return
+	}
+
+	if pl.YAxis.Label != "" {
+		lsz := pp.TextSize(pl.YAxis.Label)
+		pp.Text(pl.YAxis.Label, pos.X, top+0.5*(pl.PlotSize.Y-lsz.X), true)
+	}
+	if pl.XAxis.Label != "" {
+		lsz := pp.TextSize(pl.XAxis.Label)
+		pp.Text(pl.XAxis.Label, left+0.5*(pl.PlotSize.X-lsz.X), pos.Y+sz.Y-1.25*lh, false)
+	}
+
+	// ticks, tick labels and grid
+	for _, t := range xticks {
+		px := pl.DataToPixel(t, vw.YMin).X
+		if pl.Grid {
+			pp.Line(px, top, px, bot, gridClr, 1)
+		}
+		pp.Line(px, bot, px, bot+tick, axClr, 1)
+		lbl := PlotTickLabel(t, xstep)
+		lsz := pp.TextSize(lbl)
+		pp.Text(lbl, px-0.5*lsz.X, bot+tick+0.1*lh, false)
+	}
+	for i, t := range yticks {
+		py := pl.DataToPixel(vw.XMin, t).Y
+		if pl.Grid {
+			pp.Line(left, py, right, py, gridClr, 1)
+		}
+		pp.Line(left-tick, py, left, py, axClr, 1)
+		lsz := pp.TextSize(ylbls[i])
+		pp.Text(ylbls[i], left-tick-0.25*lh-lsz.X, py-0.5*lsz.Y, false)
+	}
+
+	pl.RenderSeries(pp)
+
+	pp.Line(left, top, left, bot, axClr, 1)
+	pp.Line(left, bot, right, bot, axClr, 1)
+
+	if pl.Legend {
+		pl.RenderLegend(pp, lh)
+	}
+}
+
+// plotTickStep returns the spacing of linear ticks, for formatting labels
+func plotTickStep(ax *PlotAxis, ticks []float64) float64 {
+	if ax.Log || len(ticks) < 2 {
+		return 0
+	}
+	return ticks[1] - ticks[0]
+}
+
+// RenderSeries draws all the data series, clipped to the plotting area
+func (pl *Plot) RenderSeries(pp plotPainter) {
+	ppos, psz := pl.PlotPos, pl.PlotSize
+	inArea := func(p mat32.Vec2) bool {
+		return p.X >= ppos.X-.5 && p.X <= ppos.X+psz.X+.5 && p.Y >= ppos.Y-.5 && p.Y <= ppos.Y+psz.Y+.5
+	}
+	nbar := 0
+	for _, ps := range pl.Series {
+		if !ps.Off && ps.Type == PlotBar {
+			nbar++
+		}
+	}
+	bari := 0
+	for si, ps := range pl.Series {
+		if ps.Off {
+			continue
+		}
+		clr := pl.SeriesColor(si)
+		n := ps.Len()
+		switch ps.Type {
+		case PlotLine:
+			var pts []mat32.Vec2
+			flush := func() {
+				if len(pts) > 1 {
+					pp.Polyline(pts, clr, ps.LineWidth)
+				}
+				pts = pts[:0]
+			}
+			for i := 0; i < n; i++ {
+				p := pl.DataToPixel(ps.X[i], ps.Y[i])
+				if mat32.IsNaN(p.X) || mat32.IsNaN(p.Y) || !inArea(p) {
+					flush() // missing or out of view: break the line
+					continue
+				}
+				pts = append(pts, p)
+				if ps.PointSize > 0 {
+					pp.Circle(p.X, p.Y, ps.PointSize, clr)
+				}
+			}
+			flush()
+		case PlotScatter:
+			for i := 0; i < n; i++ {
+				p := pl.DataToPixel(ps.X[i], ps.Y[i])
+				if mat32.IsNaN(p.X) || mat32.IsNaN(p.Y) || !inArea(p) {
+					continue
+				}
+				pp.Circle(p.X, p.Y, mat32.Max(ps.PointSize, 1), clr)
+			}
+		case PlotBar:
+			bw := pl.barWidth(ps) / float32(nbar)
+			off := (float32(bari) - 0.5*float32(nbar-1)) * bw
+			base := pl.DataToPixel(0, 0).Y
+			if pl.YAxis.Log || mat32.IsNaN(base) {
+				base = ppos.Y + psz.Y
+			}
+			base = mat32.Clamp(base, ppos.Y, ppos.Y+psz.Y)
+			for i := 0; i < n; i++ {
+				p := pl.DataToPixel(ps.X[i], ps.Y[i])
+				if mat32.IsNaN(p.X) || mat32.IsNaN(p.Y) {
+					continue
+				}
+				x0 := mat32.Max(p.X+off-0.4*bw, ppos.X)
+				x1 := mat32.Min(p.X+off+0.4*bw, ppos.X+psz.X)
+				y := mat32.Clamp(p.Y, ppos.Y, ppos.Y+psz.Y)
+				if x1 <= x0 {
+					continue
+				}
+				pp.Rect(x0, mat32.Min(y, base), x1-x0, mat32.Abs(base-y), clr)
+			}
+			bari++
+		}
+	}
+}
+
+// barWidth returns the width in dots allotted to each bar of given series,
+// based on the minimum spacing between X values
+func (pl *Plot) barWidth(ps *PlotSeries) float32 {
+	n := ps.Len()
+	mind := float32(math.MaxFloat32)
+	for i := 1; i < n; i++ {
+		d := mat32.Abs(pl.DataToPixel(ps.X[i], 0).X - pl.DataToPixel(ps.X[i-1], 0).X)
+		if d > 0 && d < mind {
+			mind = d
+		}
+	}
+	if mind == float32(math.MaxFloat32) {
+		mind = 0.1 * pl.PlotSize.X
+	}
+	return mind
+}
+
+// RenderLegend draws the legend in the upper right of the plotting area
+func (pl *Plot) RenderLegend(pp plotPainter, lh float32) {
+	st := &pl.Sty
+	var nms []string
+	var idxs []int
+	mw := float32(0)
+	for si, ps := range pl.Series {
+		if ps.Off || ps.Name == "" {
+			continue
+		}
+		nms = append(nms, ps.Name)
+		idxs = append(idxs, si)
+		mw = mat32.Max(mw, pp.TextSize(ps.Name).X)
+	}
+	if len(nms) == 0 {
+		return
+	}
+	sw := 1.5 * lh
+	w := mw + sw + 1.0*lh
+	h := float32(len(nms))*lh + 0.5*lh
+	x := pl.PlotPos.X + pl.PlotSize.X - w - 0.5*lh
+	y := pl.PlotPos.Y + 0.5*lh
+	fg := st.Font.Color
+	bg := st.Font.BgColor.Color
+	pp.Rect(x, y, w, h, bg)
+	bd := fg.Blend(70, bg)
+	pp.Line(x, y, x+w, y, bd, 1)
+	pp.Line(x+w, y, x+w, y+h, bd, 1)
+	pp.Line(x+w, y+h, x, y+h, bd, 1)
+	pp.Line(x, y+h, x, y, bd, 1)
+	for i, nm := range nms {
+		ps := pl.Series[idxs[i]]
+		clr := pl.SeriesColor(idxs[i])
+		ly := y + 0.25*lh + float32(i)*lh
+		cy := ly + 0.5*lh
+		sx := x + 0.25*lh
+		switch ps.Type {
+		case PlotLine:
+			pp.Line(sx, cy, sx+sw, cy, clr, mat32.Max(ps.LineWidth, 1))
+		case PlotScatter:
+			pp.Circle(sx+0.5*sw, cy, mat32.Max(ps.PointSize, 1), clr)
+		case PlotBar:
+			pp.Rect(sx+0.2*sw, ly+0.2*lh, 0.6*sw, 0.6*lh, clr)
+		}
+		pp.Text(nm, sx+sw+0.5*lh, ly, false)
+	}
+}
+
+func (pl *Plot) Render2D() {
+	if pl.FullReRenderIfNeeded() {
+		return
+	}
+	if pl.PushBounds() {
+		pl.This().(gi.Node2D).ConnectEvents2D()
+		rs, pc, st := pl.RenderLock()
+		rs.BackupPaint()
+		pl.RenderStdBox(st)
+		spc := st.BoxSpace()
+		pos := pl.LayState.Alloc.Pos.AddScalar(spc)
+		sz := pl.LayState.Alloc.Size.AddScalar(-2 * spc)
+		pp := &plotPaintPainter{plotTextMeas: plotTextMeas{sty: st}, rs: rs, pc: pc}
+		pl.RenderPlot(pp, pos, sz)
+		rs.RestorePaint()
+		pl.RenderUnlock(rs)
+		pl.Render2DChildren()
+		pl.PopBounds()
+	} else {
+		pl.DisconnectAllEvents(gi.RegPri)
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//  Events
+
+// PointToVp converts a window position into a position within the viewport
+func (pl *Plot) PointToVp(pt image.Point) mat32.Vec2 {
+	pl.BBoxMu.RLock()
+	defer pl.BBoxMu.RUnlock()
+	return mat32.NewVec2FmPoint(pt.Sub(pl.WinBBox.Min).Add(pl.VpBBox.Min))
+}
+
+// MouseEvent handles double-click to reset the view
+func (pl *Plot) MouseEvent() {
+	pl.ConnectEvent(oswin.MouseEvent, gi.RegPri, func(recv, send ki.Ki, sig int64, d interface{}) {
+		me := d.(*mouse.Event)
+		plv := recv.Embed(KiT_Plot).(*Plot)
+		if me.Button == mouse.Left && me.Action == mouse.DoubleClick {
+			me.SetProcessed()
+			plv.ResetView()
+		}
+	})
+}
+
+// MouseDragEvent pans the view
+func (pl *Plot) MouseDragEvent() {
+	pl.ConnectEvent(oswin.MouseDragEvent, gi.RegPri, func(recv, send ki.Ki, sig int64, d interface{}) {
+		me := d.(*mouse.DragEvent)
+		me.SetProcessed()
+		plv := recv.Embed(KiT_Plot).(*Plot)
+		del := me.Delta()
+		plv.Pan(float32(del.X), float32(del.Y))
+	})
+}
+
+// MouseScrollEvent zooms the view around the mouse position
+func (pl *Plot) MouseScrollEvent() {
+	pl.ConnectEvent(oswin.MouseScrollEvent, gi.RegPri, func(recv, send ki.Ki, sig int64, d interface{}) {
+		me := d.(*mouse.ScrollEvent)
+		me.SetProcessed()
+		plv := recv.Embed(KiT_Plot).(*Plot)
+		del := me.NonZeroDelta(false)
+		if del == 0 {
+			return
+		}
+		fact := 1.1
+		if del < 0 {
+			fact = 1 / fact
+		}
+		plv.Zoom(plv.PointToVp(me.Where), fact)
+	})
+}
+
+// MouseHoverEvent shows the value of the data point under the mouse,
+// or the Tooltip if there is none
+func (pl *Plot) MouseHoverEvent() {
+	pl.ConnectEvent(oswin.MouseHoverEvent, gi.RegPri, func(recv, send ki.Ki, sig int64, d interface{}) {
+		me := d.(*mouse.HoverEvent)
+		plv := recv.Embed(KiT_Plot).(*Plot)
+		ps, idx := plv.PointAt(plv.PointToVp(me.Where))
+		tt := plv.Tooltip
+		if ps != nil {
+			tt = fmt.Sprintf("%s: (%s, %s)", ps.Name, PlotTickLabel(ps.X[idx], 0), PlotTickLabel(ps.Y[idx], 0))
+		}
+		if tt == "" {
+			return
+		}
+		me.SetProcessed()
+		gi.PopupTooltip(tt, me.Where.X, me.Where.Y, plv.ViewportSafe(), plv.Nm)
+	})
+}
+
+func (pl *Plot) ConnectEvents2D() {
+	pl.MouseEvent()
+	pl.MouseDragEvent()
+	pl.MouseScrollEvent()
+	pl.MouseHoverEvent()
+}
+
+var PlotProps = ki.Props{
+	"EnumType:Flag":    gi.KiT_NodeFlags,
+	"min-width":        units.NewCh(40),
+	"min-height":       units.NewEm(15),
+	"width":            units.NewCh(80),
+	"height":           units.NewEm(30),
+	"padding":          units.NewPx(4),
+	"margin":           units.NewPx(2),
+	"border-width":     units.NewPx(1),
+	"border-color":     &gi.Prefs.Colors.Border,
+	"border-style":     gi.BorderSolid,
+	"color":            &gi.Prefs.Colors.Font,
+	"background-color": &gi.Prefs.Colors.Background,
+	"ToolBar": ki.PropSlice{
+		{"ResetView", ki.Props{
+			"desc": "reset any pan / zoom, to show all the data",
+			"icon": "update",
+		}},
+		{"SavePNG", ki.Props{
+			"desc": "save the plot as currently rendered to a PNG image file",
+			"icon": "file-save",
+			"Args": ki.PropSlice{
+				{"File Name", ki.Props{
+					"ext": ".png",
+				}},
+			},
+		}},
+		{"SaveSVG", ki.Props{
+			"desc": "save the plot to an SVG vector graphics file",
+			"icon": "file-save",
+			"Args": ki.PropSlice{
+				{"File Name", ki.Props{
+					"ext": ".svg",
+				}},
+			},
+		}},
+	},
+}
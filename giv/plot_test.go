@@ -0,0 +1,164 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"math"
+	"testing"
+)
+
+// plotFloatsEq returns true if the given values are equal to within
+// rounding, with NaNs equal to each other
+func plotFloatsEq(a, b []float64) bool {
+	if len(a) != len(b) || (a == nil) != (b == nil) {
+		return false
+	}
+	for i := range a {
+		if math.IsNaN(a[i]) && math.IsNaN(b[i]) {
+			continue
+		}
+		if math.Abs(a[i]-b[i]) > 1.0e-9*math.Max(1, math.Abs(b[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPlotNiceTicks(t *testing.T) {
+	tests := []struct {
+		min, max float64
+		nticks   int
+		want     []float64
+	}{
+		{0, 10, 6, []float64{0, 2, 4, 6, 8, 10}},
+		{10, 0, 6, []float64{0, 2, 4, 6, 8, 10}},
+		{-1, 1, 5, []float64{-1, -.5, 0, .5, 1}},
+		{0.13, 0.87, 4, []float64{.5}},
+		{1, 1000, 4, []float64{500, 1000}},
+		{-0.3, 0.3, 1, []float64{0}}, // nticks < 2 is 2
+		{3, 3, 6, []float64{3}},
+	}
+	for _, tt := range tests {
+		if got := PlotNiceTicks(tt.min, tt.max, tt.nticks); !plotFloatsEq(got, tt.want) {
+			t.Errorf("PlotNiceTicks(%v, %v, %v) = %v, want %v", tt.min, tt.max, tt.nticks, got, tt.want)
+		}
+	}
+}
+
+func TestPlotTickLabel(t *testing.T) {
+	tests := []struct {
+		v, step float64
+		want    string
+	}{
+		{0, 1, "0"},
+		{2, 2, "2"},
+		{-10, 10, "-10"},
+		{0.5, 0.5, "0.5"},
+		{0.25, 0.05, "0.25"},
+		{0.2, 0.1, "0.2"},
+		{3, 0, "3"},
+		{1.5e6, 5e5, "1.5e+06"},
+		{0.00005, 1e-5, "5e-05"},
+	}
+	for _, tt := range tests {
+		if got := PlotTickLabel(tt.v, tt.step); got != tt.want {
+			t.Errorf("PlotTickLabel(%v, %v) = %q, want %q", tt.v, tt.step, got, tt.want)
+		}
+	}
+}
+
+func TestPlotFloats(t *testing.T) {
+	tests := []struct {
+		name string
+		slc  interface{}
+		want []float64
+	}{
+		{"nil", nil, nil},
+		{"not slice", 3, nil},
+		{"float64", []float64{1, 2}, []float64{1, 2}},
+		{"float32", []float32{1.5, -2}, []float64{1.5, -2}},
+		{"int", []int{1, 2, 3}, []float64{1, 2, 3}},
+		{"array", [2]uint8{4, 5}, []float64{4, 5}},
+		{"ptr", &[]int64{7}, []float64{7}},
+		{"empty", []int{}, []float64{}},
+		{"not numbers", []interface{}{1, "x"}, []float64{1, math.NaN()}},
+	}
+	for _, tt := range tests {
+		if got := PlotFloats(tt.slc); !plotFloatsEq(got, tt.want) {
+			t.Errorf("PlotFloats %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPlotDataRange(t *testing.T) {
+	tests := []struct {
+		name   string
+		config func(pl *Plot)
+		want   PlotRange
+	}{
+		{"empty", func(pl *Plot) {}, PlotRange{0, 1.05, 0, 1.05}},
+		{"line", func(pl *Plot) {
+			pl.AddSeries("y", PlotLine, nil, []float64{1, 2, 3})
+		}, PlotRange{0, 2.1, .9, 3.1}},
+		{"bar", func(pl *Plot) {
+			pl.AddSeries("y", PlotBar, nil, []float64{2, 4})
+		}, PlotRange{0, 1.05, 0, 4.2}},
+		{"single", func(pl *Plot) {
+			pl.AddSeries("y", PlotScatter, []float64{0}, []float64{5})
+		}, PlotRange{-.5, .5, 4.5, 5.5}},
+		{"off", func(pl *Plot) {
+			pl.AddSeries("y", PlotLine, nil, []float64{1, 2, 3})
+			pl.AddSeries("off", PlotLine, nil, []float64{-100, 100}).Off = true
+		}, PlotRange{0, 2.1, .9, 3.1}},
+		{"nan and inf", func(pl *Plot) {
+			pl.AddSeries("y", PlotLine, nil, []float64{1, math.NaN(), math.Inf(1), 3})
+		}, PlotRange{0, 3.15, .9, 3.1}},
+		{"log", func(pl *Plot) {
+			pl.YAxis.Log = true
+			pl.AddSeries("y", PlotLine, nil, []float64{0, 1, 100})
+		}, PlotRange{.95, 2.05, math.Pow(10, -.1), math.Pow(10, 2.1)}},
+		{"fixed", func(pl *Plot) {
+			pl.YAxis.FixMin, pl.YAxis.Min = true, -1
+			pl.YAxis.FixMax, pl.YAxis.Max = true, 10
+			pl.AddSeries("y", PlotLine, nil, []float64{1, 2, 3})
+		}, PlotRange{0, 2.1, -1, 10}},
+	}
+	for _, tt := range tests {
+		pl := &Plot{}
+		pl.InitName(pl, "plot")
+		pl.Defaults()
+		tt.config(pl)
+		got := pl.DataRange()
+		if !plotFloatsEq([]float64{got.XMin, got.XMax, got.YMin, got.YMax}, []float64{tt.want.XMin, tt.want.XMax, tt.want.YMin, tt.want.YMax}) {
+			t.Errorf("DataRange %s = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPlotSetStructSlice(t *testing.T) {
+	type row struct {
+		Time  float64 `plot:"x"`
+		Name  string
+		Value int
+		Rate  float32 `plot:"bar"`
+		Skip  float64 `plot:"-"`
+	}
+	rows := []row{{0, "a", 1, .5, 9}, {1, "b", 3, .25, 9}}
+	pl := &Plot{}
+	pl.InitName(pl, "plot")
+	pl.Defaults()
+	if err := pl.SetStructSlice(rows, "", PlotLine); err != nil {
+		t.Fatal(err)
+	}
+	if len(pl.Series) != 2 || pl.Series[0].Name != "Value" || pl.Series[1].Type != PlotBar || !plotFloatsEq(pl.Series[1].Y, []float64{.5, .25}) || pl.XAxis.Label != "Time" {
+		t.Errorf("series: %+v %+v", pl.Series[0], pl.Series[1])
+	}
+	if err := pl.SetStructSlice(rows, "Name", PlotLine); err == nil {
+		t.Errorf("no error for non-numeric X field")
+	}
+	if err := pl.SetStructSlice(rows, "Missing", PlotLine); err == nil {
+		t.Errorf("no error for missing X field")
+	}
+}
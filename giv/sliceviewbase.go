@@ -162,6 +162,13 @@ func (sv *SliceViewBase) Disconnect() {
 	sv.ViewSig.DisconnectAll()
 }
 
+func (sv *SliceViewBase) AccessInfo() gi.AccessInfo {
+	ai := sv.Frame.AccessInfo()
+	ai.Role = gi.AccessList
+	ai.SetState(sv.IsInactive(), gi.AccessReadOnly)
+	return ai
+}
+
 func (sv *SliceViewBase) AsSliceViewBase() *SliceViewBase {
 	return sv
 }
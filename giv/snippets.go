@@ -0,0 +1,556 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/textbuf"
+	"github.com/goki/gi/oswin"
+	"github.com/goki/ki/indent"
+	"github.com/goki/ki/ki"
+	"github.com/goki/pi/complete"
+	"github.com/goki/pi/filecat"
+	"github.com/goki/pi/lex"
+)
+
+// Snippet is a template of text that is inserted by the completer when
+// its prefix is typed, with numbered fields that are visited in turn with
+// Tab -- see textbuf.ExpandSnippet for the syntax of the body, which is
+// that of TextMate and VS Code snippets
+type Snippet struct {
+	Prefix string `json:"prefix" desc:"text that is completed to the snippet"`
+	Body   string `json:"body" desc:"body of the snippet -- in a JSON file it can also be a list of lines"`
+	Desc   string `json:"description,omitempty" desc:"description of the snippet, shown in the completer"`
+}
+
+// UnmarshalJSON accepts the body as either a string or a list of lines
+func (sn *Snippet) UnmarshalJSON(b []byte) error {
+	var js struct {
+		Prefix string          `json:"prefix"`
+		Body   json.RawMessage `json:"body"`
+		Desc   string          `json:"description"`
+	}
+	if err := json.Unmarshal(b, &js); err != nil {
+		return err
+	}
+	sn.Prefix = js.Prefix
+	sn.Desc = js.Desc
+	sn.Body = ""
+	if len(js.Body) == 0 {
+		return nil
+	}
+	var lns []string
+	if err := json.Unmarshal(js.Body, &lns); err == nil {
+		sn.Body = strings.Join(lns, "\n")
+		return nil
+	}
+	return json.Unmarshal(js.Body, &sn.Body)
+}
+
+// Snippets is a set of snippets, by name
+type Snippets map[string]*Snippet
+
+// OpenJSON opens snippets from a JSON-formatted file.
+func (sm *Snippets) OpenJSON(filename gi.FileName) error {
+	b, err := ioutil.ReadFile(string(filename))
+	if err != nil {
+		return err
+	}
+	*sm = make(Snippets) // reset
+	return json.Unmarshal(b, sm)
+}
+
+// SaveJSON saves snippets to a JSON-formatted file.
+func (sm *Snippets) SaveJSON(filename gi.FileName) error {
+	b, err := json.MarshalIndent(sm, "", "  ")
+	if err != nil {
+		log.Println(err) // unlikely
+		return err
+	}
+	err = ioutil.WriteFile(string(filename), b, 0644)
+	if err != nil {
+		log.Println(err)
+	}
+	return err
+}
+
+// AvailSnippets are the snippets available for each language -- they are
+// opened from the prefs directory when first needed, see LangSnippets
+var AvailSnippets = map[filecat.Supported]Snippets{}
+
+// PrefsSnippetsDir is the directory in GoGi prefs directory with the
+// snippets for each language, in files named for the language, e.g., Go.json
+var PrefsSnippetsDir = "snippets"
+
+// LangSnippets returns the snippets for given language, opening them from
+// the prefs directory if they have not yet been opened
+func LangSnippets(sup filecat.Supported) Snippets {
+	if sup == filecat.NoSupport {
+		return nil
+	}
+	if sm, has := AvailSnippets[sup]; has {
+		return sm
+	}
+	var sm Snippets
+	if oswin.TheApp != nil {
+		if err := sm.OpenPrefs(sup); err != nil && !os.IsNotExist(err) {
+			log.Println(err)
+		}
+	}
+	AvailSnippets[sup] = sm
+	return sm
+}
+
+// OpenPrefs opens the snippets for given language from GoGi standard prefs
+// directory, in PrefsSnippetsDir
+func (sm *Snippets) OpenPrefs(sup filecat.Supported) error {
+	pdir := filepath.Join(oswin.TheApp.GoGiPrefsDir(), PrefsSnippetsDir)
+	pnm := filepath.Join(pdir, sup.String()+".json")
+	return sm.OpenJSON(gi.FileName(pnm))
+}
+
+// SavePrefs saves the snippets for given language to GoGi standard prefs
+// directory, in PrefsSnippetsDir
+func (sm *Snippets) SavePrefs(sup filecat.Supported) error {
+	pdir := filepath.Join(oswin.TheApp.GoGiPrefsDir(), PrefsSnippetsDir)
+	if err := os.MkdirAll(pdir, 0755); err != nil {
+		log.Println(err)
+		return err
+	}
+	pnm := filepath.Join(pdir, sup.String()+".json")
+	return sm.SaveJSON(gi.FileName(pnm))
+}
+
+// SnippetCompleteKey is the key in the Extra of a completion for a
+// snippet, with the body of the snippet
+const SnippetCompleteKey = "snippet"
+
+// AddSnippetMatches adds the snippets for given language with prefixes
+// matching the seed of the completion matches to them, before the other
+// matches -- if there is no seed, the identifier at the end of text is
+// used.  Other matches with the same text as a snippet are removed, so
+// that the snippet is used when it is selected.
+func AddSnippetMatches(sup filecat.Supported, text string, md complete.Matches) complete.Matches {
+	sm := LangSnippets(sup)
+	if len(sm) == 0 {
+		return md
+	}
+	if md.Seed == "" {
+		if len(md.Matches) > 0 {
+			return md
+		}
+		md.Seed = SeedIdent(text)
+		if md.Seed == "" {
+			return md
+		}
+	}
+	nms := make([]string, 0, len(sm))
+	for nm, sn := range sm {
+		if sn != nil && sn.Prefix != "" && strings.HasPrefix(sn.Prefix, md.Seed) {
+			nms = append(nms, nm)
+		}
+	}
+	if len(nms) == 0 {
+		return md
+	}
+	sort.Slice(nms, func(i, j int) bool {
+		pi, pj := sm[nms[i]].Prefix, sm[nms[j]].Prefix
+		if pi != pj {
+			return pi < pj
+		}
+		return nms[i] < nms[j]
+	})
+	prefs := map[string]bool{}
+	var cm complete.Completions
+	for _, nm := range nms {
+		sn := sm[nm]
+		if prefs[sn.Prefix] { // only the first snippet for a prefix can be selected
+			continue
+		}
+		prefs[sn.Prefix] = true
+		desc := sn.Desc
+		if desc == "" {
+			desc = nm
+		}
+		cm = append(cm, complete.Completion{Text: sn.Prefix, Label: sn.Prefix + " -- " + desc, Icon: "code", Desc: sn.Body, Extra: map[string]string{SnippetCompleteKey: sn.Body}})
+	}
+	for _, c := range md.Matches {
+		if !prefs[c.Text] {
+			cm = append(cm, c)
+		}
+	}
+	md.Matches = cm
+	return md
+}
+
+// TextViewSnippet is the state of a snippet being edited in a TextView:
+// its fields, in the buffer, and the one being edited
+type TextViewSnippet struct {
+	Fields    []textbuf.SnippetField `desc:"fields of the snippet, with regions in the buffer"`
+	Order     []int                  `desc:"indexes of the fields that are edited, in order -- the first field for each number, in order of number, with 0 last"`
+	Cur       int                    `desc:"index in Order of the field being edited"`
+	Reg       textbuf.Region         `desc:"region of the snippet in the buffer"`
+	mirroring int                    // index of the mirror being updated, or -1
+}
+
+// InsertSnippet replaces the text between st and ed with the expansion
+// of the snippet body, and selects its first field -- Tab and Shift+Tab
+// then move between its fields, and mirrors of the field being edited
+// are updated as it is edited.  All the edits of the snippet are one
+// undo group.
+func (tv *TextView) InsertSnippet(body string, st, ed lex.Pos) {
+	tb := tv.Buf
+	if tb == nil {
+		return
+	}
+	tv.SnippetEnd()
+	ind := ""
+	for _, r := range tb.Line(st.Ln) {
+		if r != ' ' && r != '\t' {
+			break
+		}
+		ind += string(r)
+	}
+	tab := indent.String(tb.Opts.IndentChar(), 1, tb.Opts.TabSize)
+	txt, flds := textbuf.ExpandSnippet(body, ind, tab, tv.SnippetVar)
+
+	wupdt := tv.TopUpdateStart()
+	defer tv.TopUpdateEnd(wupdt)
+	tb.Undos.BatchStart()
+	tv.SelectReset()
+	if st != ed {
+		tb.DeleteText(st, ed, EditSignal)
+	}
+	tbe := tb.InsertText(st, []byte(txt), EditSignal)
+	end := st
+	if tbe != nil {
+		end = tbe.Reg.End
+	}
+	abs := func(pos lex.Pos) lex.Pos {
+		if pos.Ln == 0 {
+			pos.Ch += st.Ch
+		}
+		pos.Ln += st.Ln
+		return pos
+	}
+	sn := &TextViewSnippet{Fields: flds, Reg: textbuf.Region{Start: st, End: end}, mirroring: -1}
+	fin := -1
+	for i := range sn.Fields {
+		fld := &sn.Fields[i]
+		fld.Reg.Start = abs(fld.Reg.Start)
+		fld.Reg.End = abs(fld.Reg.End)
+		if fld.Mirror {
+			continue
+		}
+		if fld.Num == 0 {
+			if fin < 0 {
+				fin = i
+			}
+			continue
+		}
+		sn.Order = append(sn.Order, i)
+	}
+	sort.SliceStable(sn.Order, func(i, j int) bool {
+		return sn.Fields[sn.Order[i]].Num < sn.Fields[sn.Order[j]].Num
+	})
+	sn.Order = append(sn.Order, fin)
+	tv.Snippet = sn
+	tv.SnippetSelectField()
+	if len(sn.Order) == 1 { // nothing to edit
+		tv.SnippetEnd()
+	}
+}
+
+// SnippetField returns the field of the snippet being edited, or nil if
+// there is none
+func (tv *TextView) SnippetField() *textbuf.SnippetField {
+	sn := tv.Snippet
+	if sn == nil || sn.Cur < 0 || sn.Cur >= len(sn.Order) {
+		return nil
+	}
+	return &sn.Fields[sn.Order[sn.Cur]]
+}
+
+// SnippetSelectField selects the text of the snippet field being edited,
+// and pops up a menu of its choices, if it has any
+func (tv *TextView) SnippetSelectField() {
+	fld := tv.SnippetField()
+	if fld == nil {
+		return
+	}
+	tv.SelectReset()
+	if fld.Reg.Start != fld.Reg.End {
+		tv.SelectStart = fld.Reg.Start
+		tv.SelectReg = fld.Reg
+	}
+	tv.SetCursorShow(fld.Reg.End)
+	tv.SetCursorCol(tv.CursorPos)
+	tv.RenderSelectLines()
+	if len(fld.Choices) > 0 && tv.Viewport != nil && tv.ParentWindow() != nil {
+		tv.SnippetChoicesMenu()
+	}
+}
+
+// SnippetChoicesMenu pops up a menu of the choices for the snippet field
+// being edited
+func (tv *TextView) SnippetChoicesMenu() {
+	fld := tv.SnippetField()
+	if fld == nil {
+		return
+	}
+	var men gi.Menu
+	for _, ch := range fld.Choices {
+		men.AddAction(gi.ActOpts{Label: ch, Data: ch}, tv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			txf := recv.Embed(KiT_TextView).(*TextView)
+			txf.SnippetChoose(data.(string))
+		})
+	}
+	cpos := tv.CharStartPos(tv.CursorPos).ToPoint()
+	cpos.Y += int(tv.LineHeight)
+	gi.PopupMenu(men, cpos.X, cpos.Y, tv.ViewportSafe(), tv.Nm+"-snippet-menu")
+}
+
+// SnippetChoose replaces the text of the snippet field being edited with
+// given text, and updates its mirrors
+func (tv *TextView) SnippetChoose(txt string) {
+	fld := tv.SnippetField()
+	if fld == nil {
+		return
+	}
+	tv.SelectReset()
+	if fld.Reg.Start != fld.Reg.End {
+		tv.SelectStart = fld.Reg.Start
+		tv.SelectReg = fld.Reg
+	}
+	tv.CursorPos = fld.Reg.Start
+	tv.InsertAtCursor([]byte(txt))
+	tv.SnippetMirrors()
+}
+
+// SnippetNext moves to the next field of the snippet, or the previous one
+// if back -- the snippet ends when moving to its final field, $0
+func (tv *TextView) SnippetNext(back bool) {
+	sn := tv.Snippet
+	if sn == nil {
+		return
+	}
+	tv.SnippetMirrors()
+	if back {
+		if sn.Cur > 0 {
+			sn.Cur--
+		}
+	} else if sn.Cur < len(sn.Order)-1 {
+		sn.Cur++
+	}
+	tv.SnippetSelectField()
+	if sn.Cur == len(sn.Order)-1 {
+		tv.SnippetEnd()
+	}
+}
+
+// SnippetEnd ends the editing of the snippet, if any
+func (tv *TextView) SnippetEnd() {
+	if tv.Snippet == nil {
+		return
+	}
+	tv.Snippet = nil
+	if tv.Buf != nil {
+		tv.Buf.Undos.BatchEnd()
+	}
+}
+
+// SnippetMirrors updates the mirrors of the snippet field being edited
+// to its text -- called after each key event while editing a snippet
+func (tv *TextView) SnippetMirrors() {
+	sn := tv.Snippet
+	fld := tv.SnippetField()
+	if fld == nil || fld.Num == 0 {
+		return
+	}
+	tb := tv.Buf
+	txt := ""
+	if tbe := tb.Region(fld.Reg.Start, fld.Reg.End); tbe != nil {
+		txt = string(tbe.ToBytes())
+	}
+	num := fld.Num
+	for i := range sn.Fields {
+		mf := &sn.Fields[i]
+		if !mf.Mirror || mf.Num != num {
+			continue
+		}
+		cur := ""
+		if tbe := tb.Region(mf.Reg.Start, mf.Reg.End); tbe != nil {
+			cur = string(tbe.ToBytes())
+		}
+		if cur == txt {
+			continue
+		}
+		sn.mirroring = i
+		if tbe := tb.DeleteText(mf.Reg.Start, mf.Reg.End, EditSignal); tbe != nil {
+			tv.CursorPos = snippetAdjustPos(tbe, tv.CursorPos, false)
+		}
+		if tbe := tb.InsertText(mf.Reg.Start, []byte(txt), EditSignal); tbe != nil {
+			tv.CursorPos = snippetAdjustPos(tbe, tv.CursorPos, false)
+		}
+		sn.mirroring = -1
+	}
+}
+
+// AdjustSnippet adjusts the regions of the snippet fields for given edit
+// of the buffer -- the field being edited, or the mirror being updated,
+// and the fields containing it, grow with text inserted at their ends
+func (tv *TextView) AdjustSnippet(tbe *textbuf.Edit) {
+	sn := tv.Snippet
+	if sn == nil {
+		return
+	}
+	tgt := sn.mirroring
+	if tgt < 0 && sn.Cur < len(sn.Order) {
+		tgt = sn.Order[sn.Cur]
+	}
+	grow := map[int]bool{}
+	for i := tgt; i >= 0; i = sn.Fields[i].Parent {
+		grow[i] = true
+	}
+	for i := range sn.Fields {
+		fld := &sn.Fields[i]
+		fld.Reg.Start = snippetAdjustPos(tbe, fld.Reg.Start, !grow[i])
+		fld.Reg.End = snippetAdjustPos(tbe, fld.Reg.End, grow[i])
+	}
+	sn.Reg.Start = snippetAdjustPos(tbe, sn.Reg.Start, false)
+	sn.Reg.End = snippetAdjustPos(tbe, sn.Reg.End, true)
+}
+
+// snippetAdjustPos returns given position adjusted for given edit of the
+// buffer -- a position where text is inserted moves with it if after, and
+// a position within deleted text moves to its start
+func snippetAdjustPos(tbe *textbuf.Edit, pos lex.Pos, after bool) lex.Pos {
+	st, ed := tbe.Reg.Start, tbe.Reg.End
+	if tbe.Delete {
+		switch {
+		case pos.IsLess(st) || pos == st:
+			return pos
+		case pos.IsLess(ed):
+			return st
+		}
+		if pos.Ln == ed.Ln {
+			pos.Ch += st.Ch - ed.Ch
+		}
+		pos.Ln -= ed.Ln - st.Ln
+		return pos
+	}
+	if pos.IsLess(st) || (pos == st && !after) {
+		return pos
+	}
+	if pos.Ln == st.Ln {
+		pos.Ch += ed.Ch - st.Ch
+	}
+	pos.Ln += ed.Ln - st.Ln
+	return pos
+}
+
+// SnippetKeyInput handles the keys for editing a snippet, returning true
+// if the key was handled -- Tab and Shift+Tab move between its fields,
+// and Escape, undo, or a key with the cursor outside of the snippet, end
+// it.
+func (tv *TextView) SnippetKeyInput(kf gi.KeyFuns) bool {
+	sn := tv.Snippet
+	if sn == nil {
+		return false
+	}
+	if tv.CursorPos.IsLess(sn.Reg.Start) || sn.Reg.End.IsLess(tv.CursorPos) {
+		tv.SnippetEnd()
+		return false
+	}
+	switch kf {
+	case gi.KeyFunFocusNext:
+		tv.SnippetNext(false)
+		return true
+	case gi.KeyFunFocusPrev:
+		tv.SnippetNext(true)
+		return true
+	case gi.KeyFunAbort, gi.KeyFunUndo, gi.KeyFunRedo:
+		tv.SnippetEnd()
+	}
+	return false
+}
+
+// SnippetVar returns the value of a snippet variable with given name,
+// and false if it is not known -- these are the variables of TextMate
+// and VS Code snippets, for the file and cursor of the view, and the
+// current date and time
+func (tv *TextView) SnippetVar(name string) (string, bool) {
+	tb := tv.Buf
+	fn := string(tb.Filename)
+	ln := tv.CursorPos.Ln
+	now := time.Now()
+	dates := map[string]string{"CURRENT_YEAR": "2006", "CURRENT_YEAR_SHORT": "06", "CURRENT_MONTH": "01",
+		"CURRENT_MONTH_NAME": "January", "CURRENT_MONTH_NAME_SHORT": "Jan", "CURRENT_DATE": "02",
+		"CURRENT_DAY_NAME": "Monday", "CURRENT_DAY_NAME_SHORT": "Mon", "CURRENT_HOUR": "15",
+		"CURRENT_MINUTE": "04", "CURRENT_SECOND": "05"}
+	if lay, has := dates[name]; has {
+		return now.Format(lay), true
+	}
+	switch name {
+	case "TM_FILENAME":
+		if fn == "" {
+			return "", true
+		}
+		return filepath.Base(fn), true
+	case "TM_FILENAME_BASE":
+		if fn == "" {
+			return "", true
+		}
+		bn := filepath.Base(fn)
+		return strings.TrimSuffix(bn, filepath.Ext(bn)), true
+	case "TM_DIRECTORY":
+		if fn == "" {
+			return "", true
+		}
+		return filepath.Dir(fn), true
+	case "TM_FILEPATH":
+		return fn, true
+	case "TM_LINE_INDEX":
+		return strconv.Itoa(ln), true
+	case "TM_LINE_NUMBER":
+		return strconv.Itoa(ln + 1), true
+	case "TM_CURRENT_LINE":
+		return string(tb.Line(ln)), true
+	case "TM_CURRENT_WORD":
+		wr := tv.WordAt()
+		if tbe := tb.Region(wr.Start, wr.End); tbe != nil {
+			return string(tbe.ToBytes()), true
+		}
+		return "", true
+	case "TM_SELECTED_TEXT":
+		if tbe := tv.Selection(); tbe != nil {
+			return string(tbe.ToBytes()), true
+		}
+		return "", true
+	case "CLIPBOARD":
+		return string(tv.clipRead()), true
+	case "LINE_COMMENT":
+		return tb.Opts.CommentLn, true
+	case "CURRENT_SECONDS_UNIX":
+		return strconv.FormatInt(now.Unix(), 10), true
+	case "RANDOM":
+		return fmt.Sprintf("%06d", rand.Intn(1000000)), true
+	case "RANDOM_HEX":
+		return fmt.Sprintf("%06x", rand.Intn(0x1000000)), true
+	}
+	return "", false
+}
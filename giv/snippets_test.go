@@ -0,0 +1,92 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/pi/complete"
+	"github.com/goki/pi/filecat"
+	"github.com/goki/pi/lex"
+)
+
+func TestSnippetMatches(t *testing.T) {
+	var sm Snippets
+	err := json.Unmarshal([]byte(`{"for loop": {"prefix": "for", "body": ["for ${1:i} := 0; $1 < ${2:n}; $1++ {", "\t$0", "}"]},
+		"func": {"prefix": "func", "body": "func $1() {\n\t$0\n}", "description": "function"}}`), &sm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := sm["for loop"].Body; body != "for ${1:i} := 0; $1 < ${2:n}; $1++ {\n\t$0\n}" {
+		t.Errorf("body: %q", body)
+	}
+	AvailSnippets[filecat.Go] = sm
+	defer delete(AvailSnippets, filecat.Go)
+
+	md := AddSnippetMatches(filecat.Go, "\tfo", complete.Matches{})
+	if md.Seed != "fo" || len(md.Matches) != 1 || md.Matches[0].Extra[SnippetCompleteKey] != sm["for loop"].Body {
+		t.Errorf("matches: %+v", md)
+	}
+	md = AddSnippetMatches(filecat.Go, "f", complete.Matches{Seed: "f", Matches: complete.Completions{{Text: "for"}, {Text: "fmt"}}})
+	if len(md.Matches) != 3 || md.Matches[0].Text != "for" || md.Matches[1].Label != "func -- function" || md.Matches[2].Text != "fmt" {
+		t.Errorf("matches: %+v", md.Matches)
+	}
+}
+
+func TestSnippetEditing(t *testing.T) {
+	tb := newTestTextBuf()
+	tb.SetText([]byte("\tfo"))
+	tv := &TextView{}
+	tv.InitName(tv, "text-view")
+	tv.SetBuf(tb)
+	tv.NLines = tb.NumLines() // normally set by layout
+	text := func() string {
+		return strings.Join(tb.Strings(false), "\n")
+	}
+	typ := func(s string) {
+		tv.InsertAtCursor([]byte(s))
+		tv.SnippetMirrors() // done after each key event
+	}
+
+	tv.InsertSnippet("for ${1:i} := 0; $1 < ${2:n}; $1++ { // $TM_LINE_NUMBER\n\t$0\n}", lex.Pos{Ch: 1}, lex.Pos{Ch: 3})
+	if exp := "\tfor i := 0; i < n; i++ { // 1\n\t\t\n\t}"; text() != exp {
+		t.Fatalf("inserted: %q, expected: %q", text(), exp)
+	}
+	if sel := string(tv.Selection().ToBytes()); sel != "i" || tv.CursorPos != (lex.Pos{Ch: 6}) {
+		t.Errorf("first field: %q at %v", sel, tv.CursorPos)
+	}
+	typ("j")
+	typ("k")
+	if exp := "\tfor jk := 0; jk < n; jk++ { // 1"; tb.Strings(false)[0] != exp {
+		t.Errorf("mirrored: %q, expected: %q", tb.Strings(false)[0], exp)
+	}
+	if tv.CursorPos != (lex.Pos{Ch: 7}) {
+		t.Errorf("cursor after mirror: %v", tv.CursorPos)
+	}
+	tv.SnippetKeyInput(gi.KeyFunFocusNext)
+	if sel := string(tv.Selection().ToBytes()); sel != "n" {
+		t.Errorf("second field: %q", sel)
+	}
+	typ("10")
+	tv.SnippetKeyInput(gi.KeyFunFocusPrev)
+	if sel := string(tv.Selection().ToBytes()); sel != "jk" {
+		t.Errorf("back to first field: %q", sel)
+	}
+	tv.SnippetKeyInput(gi.KeyFunFocusNext)
+	tv.SnippetKeyInput(gi.KeyFunFocusNext)
+	if tv.Snippet != nil || tv.CursorPos != (lex.Pos{Ln: 1, Ch: 2}) {
+		t.Errorf("final field: %v", tv.CursorPos)
+	}
+	if exp := "\tfor jk := 0; jk < 10; jk++ { // 1\n\t\t\n\t}"; text() != exp {
+		t.Errorf("edited: %q, expected: %q", text(), exp)
+	}
+	tb.Undo()
+	if text() != "\tfo" {
+		t.Errorf("undo: %q", text())
+	}
+}
@@ -46,6 +46,12 @@ func (sv *StructView) Disconnect() {
 	sv.ViewSig.DisconnectAll()
 }
 
+func (sv *StructView) AccessInfo() gi.AccessInfo {
+	ai := sv.Frame.AccessInfo()
+	ai.Role = gi.AccessForm
+	return ai
+}
+
 var StructViewProps = ki.Props{
 	"EnumType:Flag":    gi.KiT_NodeFlags,
 	"background-color": &gi.Prefs.Colors.Background,
@@ -380,7 +386,9 @@ func StructViewFieldTags(vv ValueView, lbl *gi.Label, widg gi.Node2D, isInact bo
 	hasDef, _, defStr = StructViewFieldDefTag(vv, lbl)
 	if ttip, has := vv.Tag("desc"); has {
 		lbl.Tooltip = defStr + ttip
+		widg.SetProp("access-desc", gi.AccessPlainText(ttip))
 	}
+	widg.SetProp("access-name", gi.AccessPlainText(lbl.Text))
 	return
 }
 
@@ -62,6 +62,12 @@ var _ SliceViewer = (*TableView)(nil)
 // re-styling during re-render
 type TableViewStyleFunc func(tv *TableView, slice interface{}, widg gi.Node2D, row, col int, vv ValueView)
 
+func (tv *TableView) AccessInfo() gi.AccessInfo {
+	ai := tv.SliceViewBase.AccessInfo()
+	ai.Role = gi.AccessTable
+	return ai
+}
+
 // SetSlice sets the source slice that we are viewing -- rebuilds the children
 // to represent this slice (does Update if already viewing).
 func (tv *TableView) SetSlice(sl interface{}) {
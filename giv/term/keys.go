@@ -0,0 +1,119 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package term
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/goki/gi/oswin/key"
+)
+
+// keySeqs are the xterm sequences for special keys: the final character
+// of the CSI sequence, or the number before ~ -- arrows, home and end are
+// ESC O sequences in application cursor mode
+var keySeqs = map[key.Codes]string{
+	key.CodeUpArrow:       "A",
+	key.CodeDownArrow:     "B",
+	key.CodeRightArrow:    "C",
+	key.CodeLeftArrow:     "D",
+	key.CodeHome:          "H",
+	key.CodeEnd:           "F",
+	key.CodeInsert:        "2~",
+	key.CodeDeleteForward: "3~",
+	key.CodePageUp:        "5~",
+	key.CodePageDown:      "6~",
+	key.CodeF1:            "P",
+	key.CodeF2:            "Q",
+	key.CodeF3:            "R",
+	key.CodeF4:            "S",
+	key.CodeF5:            "15~",
+	key.CodeF6:            "17~",
+	key.CodeF7:            "18~",
+	key.CodeF8:            "19~",
+	key.CodeF9:            "20~",
+	key.CodeF10:           "21~",
+	key.CodeF11:           "23~",
+	key.CodeF12:           "24~",
+}
+
+// KeyBytes returns the input bytes that a terminal sends for a key with
+// given code, rune and modifier bits (as in key.Event), as xterm does --
+// appCursor is the application cursor keys mode of the screen.  Returns
+// nil for keys that send nothing, e.g., modifier keys alone.
+func KeyBytes(code key.Codes, r rune, mods int32, appCursor bool) []byte {
+	shift := key.HasAnyModifierBits(mods, key.Shift)
+	ctrl := key.HasAnyModifierBits(mods, key.Control)
+	alt := key.HasAnyModifierBits(mods, key.Alt, key.Meta)
+	xmod := 1 // xterm modifier parameter
+	if shift {
+		xmod++
+	}
+	if alt {
+		xmod += 2
+	}
+	if ctrl {
+		xmod += 4
+	}
+	var b []byte
+	switch code {
+	case key.CodeReturnEnter, key.CodeKeypadEnter:
+		b = []byte{'\r'}
+	case key.CodeDeleteBackspace:
+		b = []byte{0x7f}
+		if ctrl {
+			b = []byte{'\b'}
+		}
+	case key.CodeTab:
+		b = []byte{'\t'}
+		if shift {
+			return []byte("\x1b[Z")
+		}
+	case key.CodeEscape:
+		b = []byte{0x1b}
+	}
+	if b == nil {
+		if seq, has := keySeqs[code]; has {
+			num := len(seq) > 1
+			switch {
+			case xmod > 1 && num:
+				return []byte(fmt.Sprintf("\x1b[%s;%d~", seq[:len(seq)-1], xmod))
+			case xmod > 1:
+				return []byte(fmt.Sprintf("\x1b[1;%d%s", xmod, seq))
+			case num:
+				return []byte("\x1b[" + seq)
+			case appCursor || code >= key.CodeF1 && code <= key.CodeF4:
+				return []byte("\x1bO" + seq)
+			default:
+				return []byte("\x1b[" + seq)
+			}
+		}
+	}
+	if b == nil {
+		if r <= 0 || r == utf8.RuneError {
+			return nil
+		}
+		if ctrl {
+			switch {
+			case r >= 'a' && r <= 'z':
+				r -= 'a' - 1
+			case r >= '@' && r <= '_': // includes A-Z, [, \, ], ^
+				r -= '@'
+			case r == ' ' || r == '2':
+				r = 0
+			case r == '/':
+				r = 0x1f
+			case r == '?':
+				r = 0x7f
+			}
+		}
+		b = make([]byte, utf8.RuneLen(r))
+		utf8.EncodeRune(b, r)
+	}
+	if alt {
+		b = append([]byte{0x1b}, b...)
+	}
+	return b
+}
@@ -0,0 +1,397 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package term
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// parser states
+const (
+	stGround  = iota
+	stEsc     // after ESC
+	stEscChar // after ESC and a character set designator, e.g., ESC (
+	stCSI     // control sequence: ESC [
+	stOSC     // operating system command: ESC ]
+	stOSCEsc  // ESC within an OSC, which starts the string terminator
+	stString  // other strings that are ignored: DCS, SOS, PM, APC
+	stStrEsc  // ESC within an ignored string
+)
+
+// parser interprets the output of a command as text and escape sequences,
+// keeping its state across writes
+type parser struct {
+	state int
+	utf   []byte // incomplete UTF-8 character at the end of the last write
+	args  []byte // parameters and intermediate characters of the sequence
+	osc   []byte // text of an OSC sequence
+}
+
+// maxSeqLen is the maximum length of the parameters of an escape sequence
+// -- longer sequences are malformed and ignored
+const maxSeqLen = 4096
+
+// parse interprets given bytes, updating the screen
+func (ps *parser) parse(sc *Screen, b []byte) {
+	if len(ps.utf) > 0 {
+		b = append(ps.utf, b...)
+		ps.utf = nil
+	}
+	for len(b) > 0 {
+		c := b[0]
+		if ps.state == stGround && c >= 0x80 {
+			if !utf8.FullRune(b) {
+				ps.utf = append([]byte{}, b...)
+				return
+			}
+			r, sz := utf8.DecodeRune(b)
+			sc.put(r)
+			b = b[sz:]
+			continue
+		}
+		b = b[1:]
+		ps.parseByte(sc, c)
+	}
+}
+
+// parseByte interprets one byte, which is not part of a UTF-8 character
+// in the ground state
+func (ps *parser) parseByte(sc *Screen, c byte) {
+	switch ps.state {
+	case stOSC, stOSCEsc:
+		ps.parseOSC(sc, c)
+		return
+	case stString, stStrEsc:
+		switch {
+		case c == 0x07 || (ps.state == stStrEsc && c == '\\'):
+			ps.state = stGround
+		case c == 0x1b:
+			ps.state = stStrEsc
+		default:
+			ps.state = stString
+		}
+		return
+	}
+	if c < 0x20 || c == 0x7f { // control characters also work within sequences
+		ps.control(sc, c)
+		return
+	}
+	switch ps.state {
+	case stGround:
+		sc.put(rune(c))
+	case stEsc:
+		ps.esc(sc, c)
+	case stEscChar:
+		ps.state = stGround // character sets are not supported
+	case stCSI:
+		if c >= 0x40 && c <= 0x7e {
+			ps.state = stGround
+			ps.csi(sc, string(ps.args), c)
+			return
+		}
+		if len(ps.args) < maxSeqLen {
+			ps.args = append(ps.args, c)
+		}
+	}
+}
+
+// control interprets a control character
+func (ps *parser) control(sc *Screen, c byte) {
+	switch c {
+	case 0x1b:
+		ps.state = stEsc
+		ps.args = ps.args[:0]
+	case 0x18, 0x1a: // CAN, SUB cancel a sequence
+		ps.state = stGround
+	case 0x07:
+		if sc.Bell != nil {
+			sc.Bell()
+		}
+	case '\b':
+		if sc.Cur.Col > 0 {
+			sc.Cur.Col--
+		}
+		sc.Cur.wrapNext = false
+	case '\t':
+		sc.tab(1)
+	case '\n', '\v', '\f':
+		sc.lineFeed()
+		if sc.Modes.NewLine {
+			sc.Cur.Col = 0
+		}
+	case '\r':
+		sc.Cur.Col = 0
+		sc.Cur.wrapNext = false
+	}
+}
+
+// esc interprets the character after ESC
+func (ps *parser) esc(sc *Screen, c byte) {
+	ps.state = stGround
+	switch c {
+	case '[':
+		ps.state = stCSI
+	case ']':
+		ps.state = stOSC
+		ps.osc = ps.osc[:0]
+	case 'P', 'X', '^', '_':
+		ps.state = stString
+	case '(', ')', '*', '+', '-', '.', '/', '#', '%', ' ':
+		ps.state = stEscChar
+	case '7':
+		sc.saved = sc.Cur
+	case '8':
+		sc.restoreCursor()
+	case 'D':
+		sc.lineFeed()
+	case 'E':
+		sc.lineFeed()
+		sc.Cur.Col = 0
+	case 'H':
+		sc.Tabs[sc.Cur.Col] = true
+	case 'M':
+		sc.reverseLineFeed()
+	case 'c':
+		sc.Reset()
+	case '=':
+		sc.Modes.AppKeypad = true
+	case '>':
+		sc.Modes.AppKeypad = false
+	}
+}
+
+// parseOSC collects the text of an OSC sequence, which ends with BEL or
+// ESC \, and sets the title for OSC 0 and 2
+func (ps *parser) parseOSC(sc *Screen, c byte) {
+	if ps.state == stOSCEsc {
+		ps.state = stGround
+		if c != '\\' { // not a string terminator: a new escape sequence
+			ps.esc(sc, c)
+			return
+		}
+	} else {
+		switch c {
+		case 0x1b:
+			ps.state = stOSCEsc
+			return
+		case 0x07:
+			ps.state = stGround
+		default:
+			if len(ps.osc) < maxSeqLen {
+				ps.osc = append(ps.osc, c)
+			}
+			return
+		}
+	}
+	s := string(ps.osc)
+	if i := strings.IndexByte(s, ';'); i >= 0 && (s[:i] == "0" || s[:i] == "2") {
+		sc.Title = s[i+1:]
+		sc.Changed = true
+	}
+}
+
+// csiParams parses the numeric parameters of a control sequence, with
+// the private mode marker (e.g., ?) if any
+func csiParams(args string) (priv byte, ps []int) {
+	if len(args) > 0 && args[0] >= '<' && args[0] <= '?' {
+		priv = args[0]
+		args = args[1:]
+	}
+	args = strings.TrimRight(args, " !\"#$%&'()*+,-./") // intermediate characters
+	if args == "" {
+		return
+	}
+	for _, a := range strings.Split(strings.Replace(args, ":", ";", -1), ";") {
+		n, _ := strconv.Atoi(a) // missing parameters are 0
+		ps = append(ps, n)
+	}
+	return
+}
+
+// param returns parameter i, or def if it is missing or 0
+func param(ps []int, i, def int) int {
+	if i < len(ps) && ps[i] > 0 {
+		return ps[i]
+	}
+	return def
+}
+
+// csi interprets a control sequence with given parameters and final
+// character
+func (ps *parser) csi(sc *Screen, args string, fin byte) {
+	priv, pm := csiParams(args)
+	if priv != 0 && priv != '?' && fin != 'c' {
+		return // other private sequences are not supported
+	}
+	n := param(pm, 0, 1)
+	switch fin {
+	case '@':
+		sc.insertChars(n)
+	case 'A':
+		sc.moveRel(-n, 0)
+	case 'B', 'e':
+		sc.moveRel(n, 0)
+	case 'C', 'a':
+		sc.moveRel(0, n)
+	case 'D':
+		sc.moveRel(0, -n)
+	case 'E':
+		sc.moveRel(n, 0)
+		sc.Cur.Col = 0
+	case 'F':
+		sc.moveRel(-n, 0)
+		sc.Cur.Col = 0
+	case 'G', '`':
+		sc.Cur.Col = clampInt(n-1, 0, sc.Cols-1)
+		sc.Cur.wrapNext = false
+	case 'H', 'f':
+		sc.MoveTo(n-1, param(pm, 1, 1)-1)
+	case 'I':
+		sc.tab(n)
+	case 'Z':
+		sc.tab(-n)
+	case 'J':
+		sc.eraseInDisplay(param(pm, 0, 0))
+	case 'K':
+		sc.eraseInLine(param(pm, 0, 0))
+	case 'L':
+		sc.insertLines(n)
+	case 'M':
+		sc.deleteLines(n)
+	case 'P':
+		sc.deleteChars(n)
+	case 'S':
+		sc.ScrollUp(n)
+	case 'T':
+		sc.ScrollDown(n)
+	case 'X':
+		sc.eraseChars(n)
+	case 'b':
+		if sc.lastR != 0 {
+			for i := 0; i < n && i < sc.Cols*sc.Rows; i++ {
+				sc.put(sc.lastR)
+			}
+		}
+	case 'c':
+		if priv == 0 && param(pm, 0, 0) == 0 {
+			sc.respond("\x1b[?62;22c") // VT220 with ANSI color
+		}
+	case 'd':
+		sc.MoveTo(n-1, sc.Cur.Col)
+	case 'g':
+		switch param(pm, 0, 0) {
+		case 0:
+			sc.Tabs[sc.Cur.Col] = false
+		case 3:
+			for i := range sc.Tabs {
+				sc.Tabs[i] = false
+			}
+		}
+	case 'h', 'l':
+		for _, m := range pm {
+			sc.setMode(priv == '?', m, fin == 'h')
+		}
+	case 'm':
+		if priv == 0 {
+			sc.Cur.Attr = ApplySGR(sc.Cur.Attr, pm)
+		}
+	case 'n':
+		switch param(pm, 0, 0) {
+		case 5:
+			sc.respond("\x1b[0n")
+		case 6:
+			row := sc.Cur.Row
+			if sc.Modes.Origin {
+				row -= sc.Top
+			}
+			sc.respond(fmt.Sprintf("\x1b[%d;%dR", row+1, sc.Cur.Col+1))
+		}
+	case 'r':
+		if priv != 0 {
+			return
+		}
+		top := param(pm, 0, 1) - 1
+		bot := param(pm, 1, sc.Rows) - 1
+		bot = clampInt(bot, 0, sc.Rows-1)
+		if top < bot {
+			sc.Top, sc.Bot = top, bot
+			sc.MoveTo(0, 0)
+		}
+	case 's':
+		sc.saved = sc.Cur
+	case 'u':
+		sc.restoreCursor()
+	}
+}
+
+// clampInt limits v to the range from mn to mx
+func clampInt(v, mn, mx int) int {
+	if v < mn {
+		return mn
+	}
+	if v > mx {
+		return mx
+	}
+	return v
+}
+
+// restoreCursor restores the cursor saved by ESC 7 or CSI s, within the
+// current screen size
+func (sc *Screen) restoreCursor() {
+	sc.Cur = sc.saved
+	sc.Cur.Row = clampInt(sc.Cur.Row, 0, sc.Rows-1)
+	sc.Cur.Col = clampInt(sc.Cur.Col, 0, sc.Cols-1)
+}
+
+// setMode sets or resets an ANSI mode, or a DEC private mode
+func (sc *Screen) setMode(dec bool, m int, on bool) {
+	if !dec {
+		switch m {
+		case 4:
+			sc.Modes.Insert = on
+		case 20:
+			sc.Modes.NewLine = on
+		}
+		return
+	}
+	switch m {
+	case 1:
+		sc.Modes.AppCursor = on
+	case 6:
+		sc.Modes.Origin = on
+		sc.MoveTo(0, 0)
+	case 7:
+		sc.Modes.AutoWrap = on
+	case 25:
+		sc.Modes.CursorVisible = on
+	case 47, 1047:
+		if !on && sc.AltScreen && m == 1047 {
+			sc.eraseInDisplay(2)
+		}
+		cur := sc.Cur
+		sc.SetAltScreen(on)
+		sc.Cur = cur
+		sc.Cur.Row = clampInt(sc.Cur.Row, 0, sc.Rows-1)
+	case 1048:
+		if on {
+			sc.saved = sc.Cur
+		} else {
+			sc.restoreCursor()
+		}
+	case 1049:
+		if on {
+			sc.SetAltScreen(true)
+			sc.eraseInDisplay(2)
+		} else {
+			sc.SetAltScreen(false)
+		}
+	case 2004:
+		sc.Modes.BracketedPaste = on
+	}
+	sc.Changed = true
+}
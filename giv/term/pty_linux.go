@@ -0,0 +1,108 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux
+
+package term
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+// ioctl calls the ioctl system call on given file with a pointer argument
+func ioctl(f *os.File, req uintptr, arg unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// openPTY opens a new pseudo-terminal, returning its master side and the
+// path of its slave side
+func openPTY() (*os.File, string, error) {
+	ptm, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	unlock := int32(0)
+	if err := ioctl(ptm, syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		ptm.Close()
+		return nil, "", fmt.Errorf("term.StartPTY: unlocking pty: %v", err)
+	}
+	var n uint32
+	if err := ioctl(ptm, syscall.TIOCGPTN, unsafe.Pointer(&n)); err != nil {
+		ptm.Close()
+		return nil, "", fmt.Errorf("term.StartPTY: getting pty number: %v", err)
+	}
+	return ptm, fmt.Sprintf("/dev/pts/%d", n), nil
+}
+
+// StartPTY starts given command with its stdin, stdout and stderr
+// connected to a new pseudo-terminal of given size, as the controlling
+// terminal of a new session, and returns the master side of the terminal,
+// which reads the output of the command and writes its input.  TERM is set
+// to xterm-256color unless the command environment already sets it.
+func StartPTY(cmd *exec.Cmd, rows, cols int) (*os.File, error) {
+	ptm, pts, err := openPTY()
+	if err != nil {
+		return nil, err
+	}
+	if err := SetSize(ptm, rows, cols); err != nil {
+		ptm.Close()
+		return nil, err
+	}
+	tty, err := os.OpenFile(pts, os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		ptm.Close()
+		return nil, err
+	}
+	defer tty.Close() // the command has its own copy
+	cmd.Stdin = tty
+	cmd.Stdout = tty
+	cmd.Stderr = tty
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	hasTerm := false
+	for _, ev := range cmd.Env {
+		if len(ev) > 5 && ev[:5] == "TERM=" {
+			hasTerm = true
+			break
+		}
+	}
+	if !hasTerm {
+		cmd.Env = append(cmd.Env, "TERM=xterm-256color")
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 0 // stdin of the child
+	if err := cmd.Start(); err != nil {
+		ptm.Close()
+		return nil, err
+	}
+	return ptm, nil
+}
+
+// winsize is the terminal size for the TIOCSWINSZ ioctl
+type winsize struct {
+	Rows, Cols, XPixel, YPixel uint16
+}
+
+// SetSize sets the size of the pseudo-terminal with given master side,
+// which sends SIGWINCH to the command running in it
+func SetSize(ptm *os.File, rows, cols int) error {
+	ws := winsize{Rows: uint16(rows), Cols: uint16(cols)}
+	if err := ioctl(ptm, syscall.TIOCSWINSZ, unsafe.Pointer(&ws)); err != nil {
+		return fmt.Errorf("term.SetSize: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux
+
+package term
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestStartPTY(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "stty size; test -t 1 && echo tty")
+	ptm, err := StartPTY(cmd, 12, 34)
+	if err != nil {
+		t.Skipf("no pty: %v", err)
+	}
+	defer ptm.Close()
+	out, _ := ioutil.ReadAll(ptm) // ends with EIO when the command exits
+	cmd.Wait()
+	sc := NewScreen(5, 40)
+	sc.Write(out)
+	if got := sc.Text(0, sc.NLines()); !strings.HasPrefix(got, "12 34\ntty") {
+		t.Errorf("output: %q", got)
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package term
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// StartPTY starts given command in a new pseudo-terminal -- only supported
+// on linux so far
+func StartPTY(cmd *exec.Cmd, rows, cols int) (*os.File, error) {
+	return nil, errors.New("term.StartPTY: pseudo-terminals not supported on: " + runtime.GOOS)
+}
+
+// SetSize sets the size of the pseudo-terminal -- only supported on linux
+// so far
+func SetSize(ptm *os.File, rows, cols int) error {
+	return errors.New("term.SetSize: pseudo-terminals not supported on: " + runtime.GOOS)
+}
@@ -0,0 +1,677 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package term is a terminal emulator: it interprets the ANSI / VT100 /
+// xterm escape sequences in the output of a command into a Screen of
+// character cells with colors and attributes, with scrollback history,
+// and runs commands under a pseudo-terminal.  The giv.TermView widget
+// renders a Screen.
+package term
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/goki/ki/ints"
+)
+
+// Color is the color of a terminal cell: ColorDefault, one of the 256
+// indexed colors, or an RGB color
+type Color uint32
+
+// ColorDefault is the default foreground or background color of the terminal
+const ColorDefault Color = 0
+
+const (
+	colorIndexed = 1 << 24
+	colorRGB     = 2 << 24
+)
+
+// IndexColor returns indexed color i: 0-7 standard, 8-15 bright, 16-231
+// the 6x6x6 color cube, and 232-255 the gray ramp
+func IndexColor(i int) Color {
+	return Color(colorIndexed | (i & 0xFF))
+}
+
+// RGBColor returns the given RGB color
+func RGBColor(r, g, b uint8) Color {
+	return Color(colorRGB | uint32(r)<<16 | uint32(g)<<8 | uint32(b))
+}
+
+// Palette16 are the RGB values of the 16 standard and bright colors
+var Palette16 = [16][3]uint8{
+	{0, 0, 0}, {205, 49, 49}, {13, 188, 121}, {229, 229, 16},
+	{36, 114, 200}, {188, 63, 188}, {17, 168, 205}, {229, 229, 229},
+	{102, 102, 102}, {241, 76, 76}, {35, 209, 139}, {245, 245, 67},
+	{59, 142, 234}, {214, 112, 214}, {41, 184, 219}, {255, 255, 255},
+}
+
+// RGB returns the red, green and blue values of the color -- false for
+// the default color
+func (c Color) RGB() (r, g, b uint8, ok bool) {
+	switch c &^ 0xFFFFFF {
+	case colorRGB:
+		return uint8(c >> 16), uint8(c >> 8), uint8(c), true
+	case colorIndexed:
+		i := int(c & 0xFF)
+		switch {
+		case i < 16:
+			p := Palette16[i]
+			return p[0], p[1], p[2], true
+		case i < 232:
+			i -= 16
+			lvl := func(v int) uint8 {
+				if v == 0 {
+					return 0
+				}
+				return uint8(55 + v*40)
+			}
+			return lvl(i / 36), lvl((i / 6) % 6), lvl(i % 6), true
+		default:
+			v := uint8(8 + (i-232)*10)
+			return v, v, v, true
+		}
+	}
+	return 0, 0, 0, false
+}
+
+// Hex returns the color as #rrggbb -- empty for the default color
+func (c Color) Hex() string {
+	r, g, b, ok := c.RGB()
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+// AttrFlags are the text attributes of a terminal cell, as bit flags
+type AttrFlags uint8
+
+const (
+	AttrBold AttrFlags = 1 << iota
+	AttrDim
+	AttrItalic
+	AttrUnderline
+	AttrBlink
+	AttrReverse
+	AttrHidden
+	AttrStrike
+)
+
+// Attr is the colors and attributes of a terminal cell
+type Attr struct {
+	Fg    Color     `desc:"foreground color"`
+	Bg    Color     `desc:"background color"`
+	Flags AttrFlags `desc:"text attributes"`
+}
+
+// Has returns true if the attributes include given flag
+func (at Attr) Has(fl AttrFlags) bool {
+	return at.Flags&fl != 0
+}
+
+// Cell is one character cell of a terminal screen
+type Cell struct {
+	R    rune `desc:"the character -- 0 for a blank cell"`
+	Attr Attr `desc:"colors and attributes of the cell"`
+}
+
+// Line is one line of terminal cells
+type Line struct {
+	Cells   []Cell `desc:"the cells of the line"`
+	Wrapped bool   `desc:"the line continues on the next line, because the text was wrapped at the end of the line"`
+}
+
+// String returns the text of the line, without trailing blanks
+func (ln *Line) String() string {
+	var b strings.Builder
+	nb := 0
+	for _, c := range ln.Cells {
+		if c.R == 0 || c.R == ' ' {
+			nb++
+			continue
+		}
+		for ; nb > 0; nb-- {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(c.R)
+	}
+	return b.String()
+}
+
+// Cursor is the position of the cursor on the screen, with the attributes
+// for new text
+type Cursor struct {
+	Row, Col int
+	Attr     Attr
+
+	// next character wraps to the next line -- the cursor stays on the last
+	// column after writing to it, as in xterm
+	wrapNext bool
+}
+
+// Modes are the terminal modes set by the command
+type Modes struct {
+	AutoWrap       bool `desc:"wrap text at the end of the line"`
+	CursorVisible  bool `desc:"show the cursor"`
+	AppCursor      bool `desc:"application cursor keys: arrow keys send ESC O sequences"`
+	AppKeypad      bool `desc:"application keypad"`
+	BracketedPaste bool `desc:"pasted text is bracketed by ESC [200~ and ESC [201~"`
+	Insert         bool `desc:"insert mode: new characters shift the rest of the line right"`
+	Origin         bool `desc:"cursor positions are relative to the scroll region"`
+	NewLine        bool `desc:"line feed also returns the cursor to the first column"`
+}
+
+// MaxScrollback is the default maximum number of lines of scrollback
+// history kept for a new Screen
+var MaxScrollback = 10000
+
+// Screen is the state of a terminal: the lines of the screen, the
+// cursor, and the scrollback history of lines that scrolled off the top
+// of the screen.  It is written to with the output of a command, which is
+// interpreted as text and escape sequences.  The Screen is not safe for
+// concurrent use -- its user must lock it.
+type Screen struct {
+	Rows       int       `desc:"number of rows of the screen"`
+	Cols       int       `desc:"number of columns of the screen"`
+	Lines      []*Line   `desc:"the lines of the current screen -- the main or the alternate screen"`
+	History    []*Line   `desc:"scrollback history of lines that scrolled off the top of the main screen, oldest first"`
+	MaxHistory int       `desc:"maximum number of lines of scrollback history"`
+	Cur        Cursor    `desc:"the cursor"`
+	Modes      Modes     `desc:"the terminal modes"`
+	Top        int       `desc:"top row of the scroll region"`
+	Bot        int       `desc:"bottom row of the scroll region (inclusive)"`
+	AltScreen  bool      `desc:"the alternate screen is shown, e.g., by full-screen programs -- it has no scrollback"`
+	Title      string    `desc:"window title set by the command"`
+	Tabs       []bool    `desc:"tab stops, for each column"`
+	Out        io.Writer `desc:"where responses to queries of the terminal are written, e.g., the cursor position -- typically the pty"`
+	Bell       func()    `desc:"optional function called for the bell character"`
+	Changed    bool      `desc:"set when anything changes -- the user clears it after rendering"`
+
+	mainLines []*Line // main screen lines while the alternate screen is shown
+	saved     Cursor  // cursor saved by ESC 7 / CSI s
+	altSaved  Cursor  // main screen cursor saved when switching to the alternate screen
+	lastR     rune    // last printed character, for REP
+	parser    parser
+}
+
+// NewScreen returns a new empty screen of given size
+func NewScreen(rows, cols int) *Screen {
+	sc := &Screen{MaxHistory: MaxScrollback}
+	sc.Rows = ints.MaxInt(rows, 1)
+	sc.Cols = ints.MaxInt(cols, 1)
+	sc.Reset()
+	return sc
+}
+
+// Reset resets the screen to its initial state, keeping the history
+func (sc *Screen) Reset() {
+	sc.AltScreen = false
+	sc.mainLines = nil
+	sc.Lines = make([]*Line, sc.Rows)
+	for i := range sc.Lines {
+		sc.Lines[i] = sc.newLine()
+	}
+	sc.Cur = Cursor{}
+	sc.saved = Cursor{}
+	sc.Modes = Modes{AutoWrap: true, CursorVisible: true}
+	sc.Top = 0
+	sc.Bot = sc.Rows - 1
+	sc.resetTabs()
+	sc.Changed = true
+}
+
+// resetTabs sets tab stops every 8 columns
+func (sc *Screen) resetTabs() {
+	sc.Tabs = make([]bool, sc.Cols)
+	for i := 8; i < sc.Cols; i += 8 {
+		sc.Tabs[i] = true
+	}
+}
+
+// newLine returns a new blank line, with the current background color
+func (sc *Screen) newLine() *Line {
+	ln := &Line{Cells: make([]Cell, sc.Cols)}
+	sc.blank(ln.Cells)
+	return ln
+}
+
+// blank blanks given cells, with the current background color
+func (sc *Screen) blank(cs []Cell) {
+	bl := Cell{Attr: Attr{Bg: sc.Cur.Attr.Bg}}
+	for i := range cs {
+		cs[i] = bl
+	}
+}
+
+// Write interprets given output of a command, which can end in the middle
+// of an escape sequence or UTF-8 character -- implements io.Writer
+func (sc *Screen) Write(b []byte) (int, error) {
+	sc.parser.parse(sc, b)
+	return len(b), nil
+}
+
+// NLines returns the total number of lines: the history plus the screen
+func (sc *Screen) NLines() int {
+	return len(sc.History) + sc.Rows
+}
+
+// Line returns line ln counting from the start of the history, where the
+// screen starts at line len(History)
+func (sc *Screen) Line(ln int) *Line {
+	nh := len(sc.History)
+	if ln < nh {
+		return sc.History[ln]
+	}
+	return sc.Lines[ln-nh]
+}
+
+// Text returns the text from line st to ed (exclusive), counting from the
+// start of the history, with lines wrapped at the end of the screen joined
+func (sc *Screen) Text(st, ed int) string {
+	var b strings.Builder
+	for ln := st; ln < ed; ln++ {
+		l := sc.Line(ln)
+		b.WriteString(l.String())
+		if !l.Wrapped && ln < ed-1 {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// TextRegion returns the text from column stc of line st to column edc
+// (exclusive) of line ed, counting lines from the start of the history,
+// with lines wrapped at the end of the screen joined
+func (sc *Screen) TextRegion(st, stc, ed, edc int) string {
+	var b strings.Builder
+	for ln := st; ln <= ed && ln < sc.NLines(); ln++ {
+		l := sc.Line(ln)
+		rs := []rune(l.String())
+		c0, c1 := 0, len(rs)
+		if ln == st {
+			c0 = ints.MinInt(stc, len(rs))
+		}
+		if ln == ed {
+			c1 = ints.MinInt(edc, len(rs))
+		}
+		if c0 < c1 {
+			b.WriteString(string(rs[c0:c1]))
+		}
+		if ln < ed && !l.Wrapped {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// Find returns the line and column of the next occurrence of given text,
+// ignoring case, searching backward (toward the start of the history) or
+// forward from just before or after column col of line ln -- matches do not
+// span lines.  Returns false if not found.
+func (sc *Screen) Find(find string, ln, col int, back bool) (int, int, bool) {
+	fr := []rune(strings.ToLower(find))
+	if len(fr) == 0 {
+		return 0, 0, false
+	}
+	nln := sc.NLines()
+	for i := 0; i <= nln; i++ {
+		l := ln - i
+		if !back {
+			l = ln + i
+		}
+		if l < 0 || l >= nln {
+			continue
+		}
+		lr := []rune(strings.ToLower(sc.Line(l).String()))
+		for c := 0; c+len(fr) <= len(lr); c++ {
+			ci := c
+			if back {
+				ci = len(lr) - len(fr) - c
+			}
+			if l == ln && ((back && ci >= col) || (!back && ci <= col)) {
+				continue
+			}
+			if string(lr[ci:ci+len(fr)]) == string(fr) {
+				return l, ci, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// ClearHistory deletes the scrollback history
+func (sc *Screen) ClearHistory() {
+	sc.History = nil
+	sc.Changed = true
+}
+
+// Resize changes the size of the screen: lines are truncated or padded to
+// the new number of columns, and if there are fewer rows, lines at the top
+// of the main screen move to the history so that the cursor stays visible
+func (sc *Screen) Resize(rows, cols int) {
+	rows = ints.MaxInt(rows, 1)
+	cols = ints.MaxInt(cols, 1)
+	if rows == sc.Rows && cols == sc.Cols {
+		return
+	}
+	resize := func(lns []*Line) []*Line {
+		for _, ln := range lns {
+			if cols < len(ln.Cells) {
+				ln.Cells = ln.Cells[:cols]
+				ln.Wrapped = false
+			} else {
+				for len(ln.Cells) < cols {
+					ln.Cells = append(ln.Cells, Cell{})
+				}
+			}
+		}
+		return lns
+	}
+	sc.Cols = cols
+	sc.Lines = resize(sc.Lines)
+	if sc.mainLines != nil {
+		sc.mainLines = resize(sc.mainLines)
+	}
+	fit := func(lns []*Line, cur *Cursor, hist bool) []*Line {
+		if rows < len(lns) {
+			del := ints.MaxInt(0, ints.MinInt(len(lns)-rows, cur.Row-rows+1))
+			if hist {
+				sc.addHistory(lns[:del]...)
+			}
+			lns = lns[del : del+rows]
+			cur.Row -= del
+		}
+		for len(lns) < rows {
+			lns = append(lns, &Line{Cells: make([]Cell, cols)})
+		}
+		cur.Row = ints.MinInt(cur.Row, rows-1)
+		cur.Col = ints.MinInt(cur.Col, cols-1)
+		cur.wrapNext = false
+		return lns
+	}
+	if sc.AltScreen {
+		sc.Lines = fit(sc.Lines, &sc.Cur, false)
+		sc.mainLines = fit(sc.mainLines, &sc.altSaved, true)
+	} else {
+		sc.Lines = fit(sc.Lines, &sc.Cur, true)
+	}
+	sc.Rows = rows
+	sc.Top = 0
+	sc.Bot = rows - 1
+	sc.resetTabs()
+	sc.Changed = true
+}
+
+// addHistory adds given lines to the end of the scrollback history,
+// dropping the oldest lines beyond MaxHistory
+func (sc *Screen) addHistory(lns ...*Line) {
+	if sc.MaxHistory <= 0 || len(lns) == 0 {
+		return
+	}
+	sc.History = append(sc.History, lns...)
+	if over := len(sc.History) - sc.MaxHistory; over > 0 {
+		copy(sc.History, sc.History[over:])
+		sc.History = sc.History[:sc.MaxHistory]
+	}
+}
+
+// SetAltScreen switches to the alternate screen, or back to the main
+// screen, saving and restoring the cursor of the main screen
+func (sc *Screen) SetAltScreen(alt bool) {
+	if alt == sc.AltScreen {
+		return
+	}
+	if alt {
+		sc.altSaved = sc.Cur
+		sc.mainLines = sc.Lines
+		sc.Lines = make([]*Line, sc.Rows)
+		for i := range sc.Lines {
+			sc.Lines[i] = sc.newLine()
+		}
+	} else {
+		sc.Lines = sc.mainLines
+		sc.mainLines = nil
+		sc.Cur = sc.altSaved
+	}
+	sc.AltScreen = alt
+	sc.Changed = true
+}
+
+////////////////////////////////////////////////////////////////////////////////////////
+//  Screen operations
+
+// put writes a character at the cursor and advances it
+func (sc *Screen) put(r rune) {
+	if sc.Cur.wrapNext {
+		if sc.Modes.AutoWrap {
+			sc.Lines[sc.Cur.Row].Wrapped = true
+			sc.Cur.Col = 0
+			sc.lineFeed()
+		}
+		sc.Cur.wrapNext = false
+	}
+	cs := sc.Lines[sc.Cur.Row].Cells
+	if sc.Modes.Insert {
+		copy(cs[sc.Cur.Col+1:], cs[sc.Cur.Col:])
+	}
+	cs[sc.Cur.Col] = Cell{R: r, Attr: sc.Cur.Attr}
+	sc.lastR = r
+	if sc.Cur.Col == sc.Cols-1 {
+		sc.Cur.wrapNext = true
+	} else {
+		sc.Cur.Col++
+	}
+	sc.Changed = true
+}
+
+// lineFeed moves the cursor down one line, scrolling the scroll region up
+// at its bottom
+func (sc *Screen) lineFeed() {
+	switch {
+	case sc.Cur.Row == sc.Bot:
+		sc.ScrollUp(1)
+	case sc.Cur.Row < sc.Rows-1:
+		sc.Cur.Row++
+	}
+	sc.Cur.wrapNext = false
+}
+
+// reverseLineFeed moves the cursor up one line, scrolling the scroll
+// region down at its top
+func (sc *Screen) reverseLineFeed() {
+	switch {
+	case sc.Cur.Row == sc.Top:
+		sc.ScrollDown(1)
+	case sc.Cur.Row > 0:
+		sc.Cur.Row--
+	}
+	sc.Cur.wrapNext = false
+}
+
+// ScrollUp scrolls the lines of the scroll region up by n lines, with
+// blank lines at the bottom -- lines scrolled off the top of the full main
+// screen go to the history
+func (sc *Screen) ScrollUp(n int) {
+	n = ints.MinInt(n, sc.Bot-sc.Top+1)
+	if n <= 0 {
+		return
+	}
+	if sc.Top == 0 && !sc.AltScreen {
+		sc.addHistory(sc.Lines[:n]...)
+	}
+	rg := sc.Lines[sc.Top : sc.Bot+1]
+	copy(rg, rg[n:])
+	for i := len(rg) - n; i < len(rg); i++ {
+		rg[i] = sc.newLine()
+	}
+	sc.Changed = true
+}
+
+// ScrollDown scrolls the lines of the scroll region down by n lines, with
+// blank lines at the top
+func (sc *Screen) ScrollDown(n int) {
+	n = ints.MinInt(n, sc.Bot-sc.Top+1)
+	if n <= 0 {
+		return
+	}
+	rg := sc.Lines[sc.Top : sc.Bot+1]
+	copy(rg[n:], rg)
+	for i := 0; i < n; i++ {
+		rg[i] = sc.newLine()
+	}
+	sc.Changed = true
+}
+
+// MoveTo moves the cursor to given row and column, limited to the screen,
+// or to the scroll region in origin mode, where row is relative to it
+func (sc *Screen) MoveTo(row, col int) {
+	top, bot := 0, sc.Rows-1
+	if sc.Modes.Origin {
+		top, bot = sc.Top, sc.Bot
+		row += top
+	}
+	sc.Cur.Row = ints.MaxInt(top, ints.MinInt(row, bot))
+	sc.Cur.Col = ints.MaxInt(0, ints.MinInt(col, sc.Cols-1))
+	sc.Cur.wrapNext = false
+}
+
+// moveRel moves the cursor by given rows and columns, stopping at the
+// scroll region if it starts within it
+func (sc *Screen) moveRel(drow, dcol int) {
+	top, bot := 0, sc.Rows-1
+	if sc.Cur.Row >= sc.Top && sc.Cur.Row <= sc.Bot {
+		top, bot = sc.Top, sc.Bot
+	}
+	sc.Cur.Row = ints.MaxInt(top, ints.MinInt(sc.Cur.Row+drow, bot))
+	sc.Cur.Col = ints.MaxInt(0, ints.MinInt(sc.Cur.Col+dcol, sc.Cols-1))
+	sc.Cur.wrapNext = false
+}
+
+// tab moves the cursor to the next tab stop, or back n tab stops if n < 0
+func (sc *Screen) tab(n int) {
+	for ; n > 0; n-- {
+		c := sc.Cur.Col + 1
+		for c < sc.Cols-1 && !sc.Tabs[c] {
+			c++
+		}
+		sc.Cur.Col = ints.MinInt(c, sc.Cols-1)
+	}
+	for ; n < 0; n++ {
+		c := sc.Cur.Col - 1
+		for c > 0 && !sc.Tabs[c] {
+			c--
+		}
+		sc.Cur.Col = ints.MaxInt(c, 0)
+	}
+	sc.Cur.wrapNext = false
+}
+
+// eraseInLine erases part of the cursor line: 0 from the cursor to the
+// end, 1 from the start to the cursor, 2 the whole line
+func (sc *Screen) eraseInLine(mode int) {
+	ln := sc.Lines[sc.Cur.Row]
+	cs := ln.Cells
+	switch mode {
+	case 0:
+		sc.blank(cs[sc.Cur.Col:])
+		ln.Wrapped = false
+	case 1:
+		sc.blank(cs[:sc.Cur.Col+1])
+	case 2:
+		sc.blank(cs)
+		ln.Wrapped = false
+	}
+	sc.Changed = true
+}
+
+// eraseInDisplay erases part of the screen: 0 from the cursor to the
+// end, 1 from the start to the cursor, 2 the whole screen, 3 the history
+func (sc *Screen) eraseInDisplay(mode int) {
+	switch mode {
+	case 0:
+		sc.eraseInLine(0)
+		for i := sc.Cur.Row + 1; i < sc.Rows; i++ {
+			sc.Lines[i] = sc.newLine()
+		}
+	case 1:
+		sc.eraseInLine(1)
+		for i := 0; i < sc.Cur.Row; i++ {
+			sc.Lines[i] = sc.newLine()
+		}
+	case 2:
+		for i := range sc.Lines {
+			sc.Lines[i] = sc.newLine()
+		}
+	case 3:
+		sc.ClearHistory()
+	}
+	sc.Changed = true
+}
+
+// insertLines inserts n blank lines at the cursor, within the scroll region
+func (sc *Screen) insertLines(n int) {
+	if sc.Cur.Row < sc.Top || sc.Cur.Row > sc.Bot {
+		return
+	}
+	top := sc.Top
+	sc.Top = sc.Cur.Row
+	sc.ScrollDown(n)
+	sc.Top = top
+	sc.Cur.Col = 0
+	sc.Cur.wrapNext = false
+}
+
+// deleteLines deletes n lines at the cursor, within the scroll region
+func (sc *Screen) deleteLines(n int) {
+	if sc.Cur.Row < sc.Top || sc.Cur.Row > sc.Bot {
+		return
+	}
+	top := sc.Top
+	sc.Top = sc.Cur.Row
+	alt := sc.AltScreen
+	sc.AltScreen = true // deleted lines do not go to the history
+	sc.ScrollUp(n)
+	sc.AltScreen = alt
+	sc.Top = top
+	sc.Cur.Col = 0
+	sc.Cur.wrapNext = false
+}
+
+// insertChars inserts n blank characters at the cursor
+func (sc *Screen) insertChars(n int) {
+	cs := sc.Lines[sc.Cur.Row].Cells[sc.Cur.Col:]
+	n = ints.MinInt(n, len(cs))
+	copy(cs[n:], cs)
+	sc.blank(cs[:n])
+	sc.Cur.wrapNext = false
+	sc.Changed = true
+}
+
+// deleteChars deletes n characters at the cursor, shifting the rest of
+// the line left
+func (sc *Screen) deleteChars(n int) {
+	cs := sc.Lines[sc.Cur.Row].Cells[sc.Cur.Col:]
+	n = ints.MinInt(n, len(cs))
+	copy(cs, cs[n:])
+	sc.blank(cs[len(cs)-n:])
+	sc.Cur.wrapNext = false
+	sc.Changed = true
+}
+
+// eraseChars erases n characters at the cursor
+func (sc *Screen) eraseChars(n int) {
+	cs := sc.Lines[sc.Cur.Row].Cells[sc.Cur.Col:]
+	sc.blank(cs[:ints.MinInt(n, len(cs))])
+	sc.Cur.wrapNext = false
+	sc.Changed = true
+}
+
+// respond writes a response to a query of the terminal to Out
+func (sc *Screen) respond(s string) {
+	if sc.Out != nil {
+		io.WriteString(sc.Out, s)
+	}
+}
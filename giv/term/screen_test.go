@@ -0,0 +1,192 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package term
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/goki/gi/oswin/key"
+)
+
+// screenText returns the lines of the screen, without trailing blanks
+func screenText(sc *Screen) string {
+	lns := make([]string, sc.Rows)
+	for i, ln := range sc.Lines {
+		lns[i] = ln.String()
+	}
+	return strings.Join(lns, "|")
+}
+
+func TestScreenText(t *testing.T) {
+	sc := NewScreen(3, 10)
+	sc.Write([]byte("hello\r\nworld"))
+	if got := screenText(sc); got != "hello|world|" {
+		t.Errorf("text: %q", got)
+	}
+	// carriage return overwrites, as for progress bars
+	sc.Write([]byte("\r 50%\r100%"))
+	if got := sc.Lines[1].String(); got != "100%d" {
+		t.Errorf("overwritten: %q", got)
+	}
+	// cursor movement and erase
+	sc.Write([]byte("\x1b[1;3HX\x1b[2;1H\x1b[K\x1b[3;5Hend"))
+	if got := screenText(sc); got != "heXlo||    end" {
+		t.Errorf("moved: %q", got)
+	}
+	// a UTF-8 character split across writes
+	sc.Write([]byte("\x1b[H\xc3"))
+	sc.Write([]byte("\xa9"))
+	if got := sc.Lines[0].String(); got != "éeXlo" {
+		t.Errorf("utf8: %q", got)
+	}
+	// an escape sequence split across writes
+	sc.Write([]byte("\x1b[2"))
+	sc.Write([]byte("J"))
+	if got := screenText(sc); got != "||" {
+		t.Errorf("cleared: %q", got)
+	}
+}
+
+func TestScreenScroll(t *testing.T) {
+	sc := NewScreen(3, 5)
+	sc.Write([]byte("1\r\n2\r\n3\r\n4\r\n5"))
+	if got := screenText(sc); got != "3|4|5" {
+		t.Errorf("scrolled: %q", got)
+	}
+	if len(sc.History) != 2 || sc.History[0].String() != "1" {
+		t.Errorf("history: %d", len(sc.History))
+	}
+	if got := sc.Text(0, sc.NLines()); got != "1\n2\n3\n4\n5" {
+		t.Errorf("all text: %q", got)
+	}
+	// wrapping at the end of the line
+	sc.Write([]byte("\r\nabcdefg"))
+	if got := sc.Text(sc.NLines()-2, sc.NLines()); got != "abcdefg" {
+		t.Errorf("wrapped: %q", got)
+	}
+	// scroll region: lines scroll within it, without going to the history
+	nh := len(sc.History)
+	sc.Write([]byte("\x1b[2J\x1b[HA\r\nB\r\nC\x1b[2;3r\x1b[3;1H\nD"))
+	if got := screenText(sc); got != "A|C|D" {
+		t.Errorf("region: %q", got)
+	}
+	if len(sc.History) != nh {
+		t.Errorf("region added history: %d != %d", len(sc.History), nh)
+	}
+	sc.Write([]byte("\x1b[r\x1b[1;1H\x1bM"))
+	if got := screenText(sc); got != "|A|C" {
+		t.Errorf("reverse index: %q", got)
+	}
+	sc.Write([]byte("\x1b[2;1H\x1b[M"))
+	if got := screenText(sc); got != "|C|" {
+		t.Errorf("delete line: %q", got)
+	}
+}
+
+func TestScreenAlt(t *testing.T) {
+	sc := NewScreen(2, 5)
+	sc.Write([]byte("main"))
+	sc.Write([]byte("\x1b[?1049h\x1b[Hfull\x1b[?25l"))
+	if !sc.AltScreen || sc.Lines[0].String() != "full" || sc.Modes.CursorVisible {
+		t.Errorf("alt: %q", screenText(sc))
+	}
+	sc.Write([]byte("\x1b[?1049l\x1b[?25h"))
+	if sc.AltScreen || sc.Lines[0].String() != "main" || sc.Cur.Col != 4 {
+		t.Errorf("main: %q, %+v", screenText(sc), sc.Cur)
+	}
+	var out bytes.Buffer
+	sc.Out = &out
+	sc.Write([]byte("\x1b[6n\x1b]0;title\x07"))
+	if out.String() != "\x1b[1;5R" || sc.Title != "title" {
+		t.Errorf("report: %q, title: %q", out.String(), sc.Title)
+	}
+	sc.Resize(1, 3)
+	if sc.Rows != 1 || sc.Lines[0].String() != "mai" || sc.Cur.Col != 2 {
+		t.Errorf("resize: %q, %+v", screenText(sc), sc.Cur)
+	}
+	sc.Resize(3, 6)
+	if sc.Rows != 3 || len(sc.Lines[2].Cells) != 6 {
+		t.Errorf("resize: %d", sc.Rows)
+	}
+}
+
+func TestSGR(t *testing.T) {
+	at := ApplySGR(Attr{}, []int{1, 31, 48, 5, 200})
+	if !at.Has(AttrBold) || at.Fg != IndexColor(1) || at.Bg != IndexColor(200) {
+		t.Errorf("attr: %+v", at)
+	}
+	at = ApplySGR(at, []int{22, 38, 2, 1, 2, 3, 49})
+	if at.Has(AttrBold) || at.Fg.Hex() != "#010203" || at.Bg != ColorDefault {
+		t.Errorf("attr: %+v", at)
+	}
+	txt, mu := MarkupSGR([]byte("a \x1b[32mok\x1b[0m <b>\x1b[K"))
+	if string(txt) != "a ok <b>" {
+		t.Errorf("txt: %q", txt)
+	}
+	if exp := `a <span style="color:#0dbc79">ok</span> &lt;b&gt;`; string(mu) != exp {
+		t.Errorf("markup: %q", mu)
+	}
+	txt, _ = MarkupSGR([]byte(" 10%\r 50%\r100%\r"))
+	if string(txt) != "100%" {
+		t.Errorf("progress: %q", txt)
+	}
+
+	sc := NewScreen(1, 10)
+	sc.Write([]byte("\x1b[7mr\x1b[27m x"))
+	if mu := string(sc.Lines[0].Markup("#000000", "#ffffff")); mu != `<span style="color:#ffffff;background-color:#000000">r</span> x` {
+		t.Errorf("line markup: %q", mu)
+	}
+}
+
+func TestKeyBytes(t *testing.T) {
+	var ctrl, alt int32
+	key.SetModifierBits(&ctrl, key.Control)
+	key.SetModifierBits(&alt, key.Alt)
+	tests := []struct {
+		code key.Codes
+		r    rune
+		mods int32
+		app  bool
+		exp  string
+	}{
+		{key.CodeA, 'a', 0, false, "a"},
+		{key.CodeC, 'c', ctrl, false, "\x03"},
+		{key.CodeB, 'b', alt, false, "\x1bb"},
+		{key.CodeReturnEnter, '\r', 0, false, "\r"},
+		{key.CodeDeleteBackspace, 0, 0, false, "\x7f"},
+		{key.CodeUpArrow, -1, 0, false, "\x1b[A"},
+		{key.CodeUpArrow, -1, 0, true, "\x1bOA"},
+		{key.CodeRightArrow, -1, ctrl, false, "\x1b[1;5C"},
+		{key.CodePageUp, -1, 0, false, "\x1b[5~"},
+		{key.CodeF1, -1, 0, false, "\x1bOP"},
+		{key.CodeLeftShift, -1, 0, false, ""},
+	}
+	for _, ts := range tests {
+		if got := string(KeyBytes(ts.code, ts.r, ts.mods, ts.app)); got != ts.exp {
+			t.Errorf("key %v %q: %q, expected: %q", ts.code, ts.r, got, ts.exp)
+		}
+	}
+}
+
+func TestScreenFind(t *testing.T) {
+	sc := NewScreen(2, 10)
+	sc.Write([]byte("one foo\r\ntwo Foo\r\nthree"))
+	ln, col, ok := sc.Find("foo", sc.NLines(), 0, true)
+	if !ok || ln != 1 || col != 4 {
+		t.Errorf("find: %d:%d %v", ln, col, ok)
+	}
+	ln, col, ok = sc.Find("foo", ln, col, true)
+	if !ok || ln != 0 || col != 4 {
+		t.Errorf("find previous: %d:%d %v", ln, col, ok)
+	}
+	if _, _, ok = sc.Find("foo", ln, col, true); ok {
+		t.Errorf("found before the first")
+	}
+	if got := sc.TextRegion(0, 4, 1, 3); got != "foo\ntwo" {
+		t.Errorf("region: %q", got)
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package term
+
+import (
+	"bytes"
+	"html"
+	"strings"
+)
+
+// ApplySGR returns given attributes as modified by the parameters of an
+// SGR (select graphic rendition) escape sequence: ESC [ ... m
+func ApplySGR(at Attr, ps []int) Attr {
+	if len(ps) == 0 {
+		return Attr{}
+	}
+	for i := 0; i < len(ps); i++ {
+		p := ps[i]
+		switch {
+		case p == 0:
+			at = Attr{}
+		case p == 1:
+			at.Flags |= AttrBold
+		case p == 2:
+			at.Flags |= AttrDim
+		case p == 3:
+			at.Flags |= AttrItalic
+		case p == 4:
+			at.Flags |= AttrUnderline
+		case p == 5 || p == 6:
+			at.Flags |= AttrBlink
+		case p == 7:
+			at.Flags |= AttrReverse
+		case p == 8:
+			at.Flags |= AttrHidden
+		case p == 9:
+			at.Flags |= AttrStrike
+		case p == 21 || p == 22:
+			at.Flags &^= AttrBold | AttrDim
+		case p == 23:
+			at.Flags &^= AttrItalic
+		case p == 24:
+			at.Flags &^= AttrUnderline
+		case p == 25:
+			at.Flags &^= AttrBlink
+		case p == 27:
+			at.Flags &^= AttrReverse
+		case p == 28:
+			at.Flags &^= AttrHidden
+		case p == 29:
+			at.Flags &^= AttrStrike
+		case p >= 30 && p <= 37:
+			at.Fg = IndexColor(p - 30)
+		case p == 38 || p == 48:
+			var c Color
+			var ok bool
+			c, i, ok = extColor(ps, i)
+			if ok {
+				if p == 38 {
+					at.Fg = c
+				} else {
+					at.Bg = c
+				}
+			}
+		case p == 39:
+			at.Fg = ColorDefault
+		case p >= 40 && p <= 47:
+			at.Bg = IndexColor(p - 40)
+		case p == 49:
+			at.Bg = ColorDefault
+		case p >= 90 && p <= 97:
+			at.Fg = IndexColor(p - 90 + 8)
+		case p >= 100 && p <= 107:
+			at.Bg = IndexColor(p - 100 + 8)
+		}
+	}
+	return at
+}
+
+// extColor returns the extended color of the SGR 38 or 48 parameter at
+// index i: 5;n for indexed colors or 2;r;g;b for RGB colors, and the index
+// of its last parameter
+func extColor(ps []int, i int) (Color, int, bool) {
+	if i+1 >= len(ps) {
+		return ColorDefault, i, false
+	}
+	switch ps[i+1] {
+	case 5:
+		if i+2 < len(ps) {
+			return IndexColor(ps[i+2]), i + 2, true
+		}
+	case 2:
+		if i+4 < len(ps) {
+			return RGBColor(uint8(ps[i+2]), uint8(ps[i+3]), uint8(ps[i+4])), i + 4, true
+		}
+	}
+	return ColorDefault, len(ps), false
+}
+
+// Style returns the html style for the attributes, e.g., for a span
+// element, with given default foreground and background colors, which are
+// used for reversed colors -- empty if the attributes are the default
+func (at Attr) Style(defFg, defBg string) string {
+	fg, bg := at.Fg.Hex(), at.Bg.Hex()
+	if at.Fg < colorIndexed+8 && at.Fg >= colorIndexed && at.Has(AttrBold) {
+		fg = IndexColor(int(at.Fg-colorIndexed) + 8).Hex() // bold standard colors are bright
+	}
+	if at.Has(AttrReverse) {
+		if fg == "" {
+			fg = defFg
+		}
+		if bg == "" {
+			bg = defBg
+		}
+		fg, bg = bg, fg
+	}
+	if at.Has(AttrHidden) {
+		fg = bg
+		if fg == "" {
+			fg = defBg
+		}
+	}
+	var sty []string
+	if fg != "" {
+		sty = append(sty, "color:"+fg)
+	}
+	if bg != "" {
+		sty = append(sty, "background-color:"+bg)
+	}
+	if at.Has(AttrBold) {
+		sty = append(sty, "font-weight:bold")
+	}
+	if at.Has(AttrItalic) {
+		sty = append(sty, "font-style:italic")
+	}
+	switch {
+	case at.Has(AttrUnderline):
+		sty = append(sty, "text-decoration:underline")
+	case at.Has(AttrStrike):
+		sty = append(sty, "text-decoration:line-through")
+	}
+	return strings.Join(sty, ";")
+}
+
+// markupWriter writes html-escaped text in spans for its attributes
+type markupWriter struct {
+	buf          bytes.Buffer
+	defFg, defBg string
+	sty          string // style of the current span
+}
+
+// write writes given text with given attributes
+func (mw *markupWriter) write(txt string, at Attr) {
+	if txt == "" {
+		return
+	}
+	sty := at.Style(mw.defFg, mw.defBg)
+	if sty != mw.sty {
+		mw.end()
+		if sty != "" {
+			mw.buf.WriteString(`<span style="` + sty + `">`)
+		}
+		mw.sty = sty
+	}
+	mw.buf.WriteString(html.EscapeString(txt))
+}
+
+// end ends the current span
+func (mw *markupWriter) end() {
+	if mw.sty != "" {
+		mw.buf.WriteString("</span>")
+		mw.sty = ""
+	}
+}
+
+// Markup returns the line as html: the html-escaped text, with spans for
+// the colors and attributes of the cells.  Blank cells are spaces, up to
+// the last cell that is not a blank with the default background.
+// defFg and defBg are the default colors, for reversed colors.
+func (ln *Line) Markup(defFg, defBg string) []byte {
+	mw := markupWriter{defFg: defFg, defBg: defBg}
+	n := len(ln.Cells)
+	for n > 0 && (ln.Cells[n-1].R == 0 || ln.Cells[n-1].R == ' ') && ln.Cells[n-1].Attr.Bg == ColorDefault && !ln.Cells[n-1].Attr.Has(AttrReverse) {
+		n--
+	}
+	var sb strings.Builder
+	var at Attr
+	for i, c := range ln.Cells[:n] {
+		if i > 0 && c.Attr != at {
+			mw.write(sb.String(), at)
+			sb.Reset()
+		}
+		at = c.Attr
+		if c.R == 0 {
+			sb.WriteByte(' ')
+		} else {
+			sb.WriteRune(c.R)
+		}
+	}
+	mw.write(sb.String(), at)
+	mw.end()
+	return mw.buf.Bytes()
+}
+
+// MarkupSGR translates the SGR color and attribute escape sequences in
+// given line of output into html markup, returning the text without any
+// escape sequences, and the html-escaped text with spans for the colors
+// and attributes.  Other escape sequences are removed, and a carriage
+// return discards the text before it, as when it is shown on a terminal,
+// e.g., for progress bars.
+func MarkupSGR(line []byte) (txt, markup []byte) {
+	if i := bytes.LastIndexByte(bytes.TrimRight(line, "\r"), '\r'); i >= 0 {
+		line = line[i+1:]
+	}
+	line = bytes.TrimRight(line, "\r")
+	var tb bytes.Buffer
+	mw := markupWriter{}
+	var at Attr
+	for len(line) > 0 {
+		i := bytes.IndexByte(line, 0x1b)
+		if i < 0 {
+			i = len(line)
+		}
+		tb.Write(line[:i])
+		mw.write(string(line[:i]), at)
+		line = line[i:]
+		if len(line) == 0 {
+			break
+		}
+		n := seqLen(line)
+		if n >= 3 && line[1] == '[' && line[n-1] == 'm' {
+			priv, ps := csiParams(string(line[2 : n-1]))
+			if priv == 0 {
+				at = ApplySGR(at, ps)
+			}
+		}
+		line = line[n:]
+	}
+	mw.end()
+	return tb.Bytes(), mw.buf.Bytes()
+}
+
+// seqLen returns the length of the escape sequence at the start of b,
+// which starts with ESC -- the rest of b if it is incomplete
+func seqLen(b []byte) int {
+	if len(b) < 2 {
+		return len(b)
+	}
+	switch b[1] {
+	case '[':
+		for i := 2; i < len(b); i++ {
+			if b[i] >= 0x40 && b[i] <= 0x7e {
+				return i + 1
+			}
+		}
+	case ']':
+		for i := 2; i < len(b); i++ {
+			switch {
+			case b[i] == 0x07:
+				return i + 1
+			case b[i] == 0x1b && i+1 < len(b) && b[i+1] == '\\':
+				return i + 2
+			}
+		}
+	case '(', ')', '*', '+', '#', '%':
+		if len(b) > 2 {
+			return 3
+		}
+	default:
+		return 2
+	}
+	return len(b)
+}
@@ -0,0 +1,699 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/term"
+	"github.com/goki/gi/oswin"
+	"github.com/goki/gi/oswin/cursor"
+	"github.com/goki/gi/oswin/key"
+	"github.com/goki/gi/oswin/mimedata"
+	"github.com/goki/gi/oswin/mouse"
+	"github.com/goki/gi/units"
+	"github.com/goki/ki/ints"
+	"github.com/goki/ki/ki"
+	"github.com/goki/ki/kit"
+	"github.com/goki/mat32"
+	"github.com/goki/pi/filecat"
+	"github.com/goki/pi/lex"
+)
+
+// TermViewRenderMSec is the number of milliseconds that the output of the
+// command is collected for, before the terminal is rendered
+var TermViewRenderMSec = 20
+
+// TermViewScrollLines is the number of lines scrolled back into the
+// history for each step of the mouse wheel
+var TermViewScrollLines = 3
+
+// TermView is a terminal emulator widget, which runs a command under a
+// pseudo-terminal (only on linux so far), and shows its output as
+// interpreted by a term.Screen, with the colors and cursor movement of
+// ANSI / VT100 / xterm escape sequences -- full-screen programs such as
+// editors work.  Key chords are sent to the command as terminal input,
+// and the size of the terminal follows the size of the widget.  The mouse
+// wheel and Shift+PageUp / PageDown scroll back in the history, dragging
+// selects text, Ctrl+Shift+C and Ctrl+Shift+V copy and paste, and the
+// context menu can find text in the history.
+type TermView struct {
+	gi.WidgetBase
+	Screen    *term.Screen `json:"-" xml:"-" desc:"the screen of the terminal, with the history -- must be locked with Mu"`
+	Cmd       *exec.Cmd    `json:"-" xml:"-" desc:"the command running in the terminal"`
+	PTY       *os.File     `json:"-" xml:"-" desc:"the master side of the pseudo-terminal of the command, which reads its output and writes its input"`
+	ScrollOff int          `json:"-" xml:"-" desc:"number of lines scrolled back into the history -- 0 shows the current screen"`
+	SelStart  lex.Pos      `json:"-" xml:"-" desc:"start of the selection, as line counting from the start of the history, and column"`
+	SelEnd    lex.Pos      `json:"-" xml:"-" desc:"end of the selection (exclusive) -- same as SelStart for no selection"`
+	Find      string       `json:"-" xml:"-" desc:"text last searched for in the history"`
+	FindPos   lex.Pos      `json:"-" xml:"-" desc:"position of the last match of Find -- Ln is -1 if there is none"`
+	CharSize  mat32.Vec2   `json:"-" xml:"-" desc:"size of one character cell, from the font"`
+	Mu        sync.Mutex   `json:"-" xml:"-" view:"-" desc:"mutex protecting the Screen, which is written by the goroutine reading the output of the command"`
+	rowRender gi.TextRender
+	rendTimer *time.Timer
+	selecting bool
+}
+
+var KiT_TermView = kit.Types.AddType(&TermView{}, TermViewProps)
+
+// AddNewTermView adds a new terminal view to given parent node, with given name.
+func AddNewTermView(parent ki.Ki, name string) *TermView {
+	tv := parent.AddNewChild(KiT_TermView, name).(*TermView)
+	tv.Screen = term.NewScreen(24, 80)
+	tv.FindPos.Ln = -1
+	return tv
+}
+
+func (tv *TermView) CopyFieldsFrom(frm interface{}) {
+	fr := frm.(*TermView)
+	tv.WidgetBase.CopyFieldsFrom(&fr.WidgetBase)
+}
+
+func (tv *TermView) Disconnect() {
+	tv.Kill()
+	tv.WidgetBase.Disconnect()
+}
+
+func (tv *TermView) AccessInfo() gi.AccessInfo {
+	ai := tv.WidgetBase.AccessInfo()
+	ai.Role = gi.AccessDocument
+	if tv.Screen != nil {
+		tv.Mu.Lock()
+		ai.Name = tv.Screen.Title
+		tv.Mu.Unlock()
+	}
+	return ai
+}
+
+// Start starts given command in the terminal, with a new pseudo-terminal
+// of the size of the terminal, and starts reading its output.  When the
+// command exits, a message with its exit status is shown.
+func (tv *TermView) Start(cmd *exec.Cmd) error {
+	if tv.Screen == nil {
+		tv.Screen = term.NewScreen(24, 80)
+	}
+	tv.Kill()
+	tv.Mu.Lock()
+	ptm, err := term.StartPTY(cmd, tv.Screen.Rows, tv.Screen.Cols)
+	if err != nil {
+		tv.Mu.Unlock()
+		return err
+	}
+	tv.Cmd = cmd
+	tv.PTY = ptm
+	tv.Screen.Out = ptm
+	tv.Mu.Unlock()
+	go tv.ReadOutput(cmd, ptm)
+	return nil
+}
+
+// StartShell starts the shell of the user, given by the SHELL environment
+// variable, in given directory (if non-empty)
+func (tv *TermView) StartShell(dir string) error {
+	sh := os.Getenv("SHELL")
+	if sh == "" {
+		sh = "/bin/sh"
+	}
+	cmd := exec.Command(sh)
+	cmd.Dir = dir
+	return tv.Start(cmd)
+}
+
+// ReadOutput reads the output of given command from the pseudo-terminal
+// until it exits, writing it to the Screen -- runs in its own goroutine
+func (tv *TermView) ReadOutput(cmd *exec.Cmd, ptm *os.File) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := ptm.Read(buf)
+		if n > 0 {
+			tv.Mu.Lock()
+			nh := len(tv.Screen.History)
+			tv.Screen.Write(buf[:n])
+			if tv.ScrollOff > 0 { // keep the same history lines in view
+				tv.ScrollOff = ints.MinInt(tv.ScrollOff+len(tv.Screen.History)-nh, len(tv.Screen.History))
+			}
+			tv.Mu.Unlock()
+			tv.RenderLater()
+		}
+		if err != nil { // EIO when the command exits
+			break
+		}
+	}
+	err := cmd.Wait()
+	tv.Mu.Lock()
+	if tv.Cmd == cmd {
+		msg := "process exited"
+		if err != nil {
+			msg = err.Error()
+		}
+		fmt.Fprintf(tv.Screen, "\x1b[0m\r\n[%s]\r\n", msg)
+		tv.Screen.Out = nil
+		tv.Cmd = nil
+		tv.PTY = nil
+	}
+	tv.Mu.Unlock()
+	ptm.Close()
+	tv.RenderLater()
+}
+
+// Kill kills the command running in the terminal, if any
+func (tv *TermView) Kill() {
+	tv.Mu.Lock()
+	defer tv.Mu.Unlock()
+	if tv.Cmd != nil && tv.Cmd.Process != nil {
+		tv.Cmd.Process.Kill()
+	}
+}
+
+// IsRunning returns true if a command is running in the terminal
+func (tv *TermView) IsRunning() bool {
+	tv.Mu.Lock()
+	defer tv.Mu.Unlock()
+	return tv.PTY != nil
+}
+
+// Input sends given bytes to the command as terminal input, e.g., as typed
+func (tv *TermView) Input(b []byte) {
+	tv.Mu.Lock()
+	ptm := tv.PTY
+	tv.Mu.Unlock()
+	if ptm == nil {
+		return
+	}
+	ptm.Write(b)
+}
+
+// Resize sets the size of the terminal, in rows and columns, and the size
+// of the pseudo-terminal, which notifies the command
+func (tv *TermView) Resize(rows, cols int) {
+	tv.Mu.Lock()
+	defer tv.Mu.Unlock()
+	if tv.Screen == nil || (rows == tv.Screen.Rows && cols == tv.Screen.Cols) {
+		return
+	}
+	tv.Screen.Resize(rows, cols)
+	tv.ScrollOff = ints.MinInt(tv.ScrollOff, len(tv.Screen.History))
+	if tv.PTY != nil {
+		term.SetSize(tv.PTY, tv.Screen.Rows, tv.Screen.Cols)
+	}
+}
+
+// ResizeToAlloc sets the size of the terminal from the allocated size of
+// the widget and the size of the characters of its font
+func (tv *TermView) ResizeToAlloc() {
+	st := &tv.Sty
+	st.Font.OpenFont(&st.UnContext)
+	tv.CharSize.X = st.Font.Face.Metrics.Ch
+	tv.CharSize.Y = st.Font.Face.Metrics.Height
+	if tv.CharSize.X <= 0 || tv.CharSize.Y <= 0 {
+		return
+	}
+	sz := tv.LayState.Alloc.Size.SubScalar(2 * st.BoxSpace())
+	rows := int(sz.Y / tv.CharSize.Y)
+	cols := int(sz.X / tv.CharSize.X)
+	if rows < 1 || cols < 1 {
+		return
+	}
+	tv.Resize(rows, cols)
+}
+
+// Clear clears the history, and the screen if no command is running
+func (tv *TermView) Clear() {
+	tv.Mu.Lock()
+	tv.Screen.ClearHistory()
+	if tv.PTY == nil {
+		tv.Screen.Reset()
+	}
+	tv.ScrollOff = 0
+	tv.SelStart, tv.SelEnd = lex.Pos{}, lex.Pos{}
+	tv.FindPos.Ln = -1
+	tv.Mu.Unlock()
+	tv.RenderTerm()
+}
+
+// ScrollBy scrolls the view by given number of lines, back into the
+// history for positive numbers
+func (tv *TermView) ScrollBy(n int) {
+	tv.Mu.Lock()
+	tv.ScrollOff = ints.MaxInt(0, ints.MinInt(tv.ScrollOff+n, len(tv.Screen.History)))
+	tv.Mu.Unlock()
+	tv.RenderTerm()
+}
+
+// ScrollToLine scrolls the view so that given line, counting from the
+// start of the history, is visible -- must be called under Mu lock
+func (tv *TermView) ScrollToLine(ln int) {
+	nh := len(tv.Screen.History)
+	top := nh - tv.ScrollOff
+	switch {
+	case ln < top:
+		tv.ScrollOff = nh - ln
+	case ln >= top+tv.Screen.Rows:
+		tv.ScrollOff = ints.MaxInt(0, nh-(ln-tv.Screen.Rows+1))
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+//  Selection, Copy / Paste and Find
+
+// HasSelection returns true if there is selected text
+func (tv *TermView) HasSelection() bool {
+	return tv.SelStart != tv.SelEnd
+}
+
+// selRegion returns the selection in order
+func (tv *TermView) selRegion() (st, ed lex.Pos) {
+	st, ed = tv.SelStart, tv.SelEnd
+	if ed.IsLess(st) {
+		st, ed = ed, st
+	}
+	return
+}
+
+// Selection returns the selected text
+func (tv *TermView) Selection() string {
+	if !tv.HasSelection() {
+		return ""
+	}
+	st, ed := tv.selRegion()
+	tv.Mu.Lock()
+	defer tv.Mu.Unlock()
+	return tv.Screen.TextRegion(st.Ln, st.Ch, ed.Ln, ed.Ch)
+}
+
+// Copy copies the selected text to the clipboard
+func (tv *TermView) Copy() {
+	txt := tv.Selection()
+	if txt == "" {
+		return
+	}
+	oswin.TheApp.ClipBoard(tv.ParentWindow().OSWin).Write(mimedata.NewText(txt))
+}
+
+// Paste sends the text on the clipboard to the command as input, with
+// line breaks as returns, and bracketed if the command enabled that
+func (tv *TermView) Paste() {
+	data := oswin.TheApp.ClipBoard(tv.ParentWindow().OSWin).Read([]string{filecat.TextPlain})
+	if data == nil {
+		return
+	}
+	txt := strings.Replace(string(data.TypeData(filecat.TextPlain)), "\r\n", "\r", -1)
+	txt = strings.Replace(txt, "\n", "\r", -1)
+	tv.Mu.Lock()
+	brack := tv.Screen.Modes.BracketedPaste
+	tv.Mu.Unlock()
+	if brack {
+		txt = "\x1b[200~" + txt + "\x1b[201~"
+	}
+	tv.Input([]byte(txt))
+}
+
+// FindNext finds the next occurrence of given text in the history and
+// screen, ignoring case, searching backward from the last match (or from
+// the end) -- or forward if back is false -- and selects it and scrolls to
+// it.  Returns false if not found.
+func (tv *TermView) FindNext(find string, back bool) bool {
+	if find != tv.Find {
+		tv.Find = find
+		tv.FindPos.Ln = -1
+	}
+	tv.Mu.Lock()
+	ln, col := tv.FindPos.Ln, tv.FindPos.Ch
+	if ln < 0 {
+		ln, col = tv.Screen.NLines(), 0
+		if !back {
+			ln = -1
+		}
+	}
+	fln, fcol, ok := tv.Screen.Find(find, ln, col, back)
+	if ok {
+		tv.FindPos = lex.Pos{Ln: fln, Ch: fcol}
+		tv.SelStart = tv.FindPos
+		tv.SelEnd = lex.Pos{Ln: fln, Ch: fcol + len([]rune(find))}
+		tv.ScrollToLine(fln)
+	}
+	tv.Mu.Unlock()
+	tv.RenderTerm()
+	return ok
+}
+
+// FindPrompt prompts for text to find in the history, and finds it
+func (tv *TermView) FindPrompt() {
+	gi.StringPromptDialog(tv.Viewport, tv.Find, "Find..",
+		gi.DlgOpts{Title: "Find in Terminal", Prompt: "Text to find in the terminal history, ignoring case -- searches back from the end"},
+		tv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			dlg := send.(*gi.Dialog)
+			if sig != int64(gi.DialogAccepted) {
+				return
+			}
+			find := gi.StringPromptDialogValue(dlg)
+			if find == "" {
+				return
+			}
+			tvv := recv.Embed(KiT_TermView).(*TermView)
+			tvv.FindPos.Ln = -1
+			tvv.FindNext(find, true)
+		})
+}
+
+// MakeContextMenu builds the terminal context menu
+func (tv *TermView) MakeContextMenu(m *gi.Menu) {
+	ac := m.AddAction(gi.ActOpts{Label: "Copy"},
+		tv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			recv.Embed(KiT_TermView).(*TermView).Copy()
+		})
+	ac.SetActiveState(tv.HasSelection())
+	ac = m.AddAction(gi.ActOpts{Label: "Paste"},
+		tv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			recv.Embed(KiT_TermView).(*TermView).Paste()
+		})
+	ac.SetActiveState(tv.IsRunning())
+	m.AddSeparator("sep-find")
+	m.AddAction(gi.ActOpts{Label: "Find..."},
+		tv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			recv.Embed(KiT_TermView).(*TermView).FindPrompt()
+		})
+	ac = m.AddAction(gi.ActOpts{Label: "Find Previous"},
+		tv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			tvv := recv.Embed(KiT_TermView).(*TermView)
+			tvv.FindNext(tvv.Find, true)
+		})
+	ac.SetActiveState(tv.Find != "")
+	ac = m.AddAction(gi.ActOpts{Label: "Find Next"},
+		tv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			tvv := recv.Embed(KiT_TermView).(*TermView)
+			tvv.FindNext(tvv.Find, false)
+		})
+	ac.SetActiveState(tv.Find != "")
+	m.AddSeparator("sep-clear")
+	m.AddAction(gi.ActOpts{Label: "Clear History"},
+		tv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			recv.Embed(KiT_TermView).(*TermView).Clear()
+		})
+}
+
+///////////////////////////////////////////////////////////////////////////////
+//  Rendering
+
+// RenderLater renders the terminal after TermViewRenderMSec, to combine
+// fast output into one update -- can be called from any goroutine
+func (tv *TermView) RenderLater() {
+	tv.Mu.Lock()
+	defer tv.Mu.Unlock()
+	if tv.rendTimer != nil {
+		return
+	}
+	tv.rendTimer = time.AfterFunc(time.Duration(TermViewRenderMSec)*time.Millisecond, func() {
+		tv.Mu.Lock()
+		tv.rendTimer = nil
+		tv.Mu.Unlock()
+		tv.RenderTerm()
+	})
+}
+
+// RenderTerm renders the terminal and uploads it to the window
+func (tv *TermView) RenderTerm() {
+	if tv == nil || tv.This() == nil || tv.Viewport == nil {
+		return
+	}
+	if !tv.This().(gi.Node2D).IsVisible() {
+		return
+	}
+	rs := tv.Render()
+	rs.PushBounds(tv.VpBBox)
+	wupdt := tv.TopUpdateStart()
+	tv.RenderTermInBounds()
+	tv.PopBounds()
+	tv.Viewport.This().(gi.Viewport).VpUploadRegion(tv.VpBBox, tv.WinBBox)
+	tv.TopUpdateEnd(wupdt)
+}
+
+// hexColor returns the color as #rrggbb, without alpha
+func hexColor(c gi.Color) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// RenderTermInBounds renders the visible lines of the terminal, with the
+// selection and the cursor -- after PushBounds has already been called
+func (tv *TermView) RenderTermInBounds() {
+	rs := tv.Render()
+	rs.Lock()
+	defer rs.Unlock()
+	pc := &rs.Paint
+	st := &tv.Sty
+	tv.Mu.Lock()
+	defer tv.Mu.Unlock()
+	sc := tv.Screen
+	pc.FillBox(rs, mat32.NewVec2FmPoint(tv.VpBBox.Min), mat32.NewVec2FmPoint(tv.VpBBox.Size()), &st.Font.BgColor)
+	if tv.CharSize.Y <= 0 {
+		return
+	}
+	pos := tv.LayState.Alloc.Pos.AddScalar(st.BoxSpace())
+	fg, bg := hexColor(st.Font.Color), hexColor(st.Font.BgColor.Color)
+	top := len(sc.History) - tv.ScrollOff
+	sst, sed := tv.selRegion()
+	for r := 0; r < sc.Rows; r++ {
+		ln := top + r
+		lp := pos
+		lp.Y += float32(r) * tv.CharSize.Y
+		if tv.HasSelection() && ln >= sst.Ln && ln <= sed.Ln {
+			c0, c1 := 0, sc.Cols
+			if ln == sst.Ln {
+				c0 = sst.Ch
+			}
+			if ln == sed.Ln {
+				c1 = sed.Ch
+			}
+			if c1 > c0 {
+				pc.FillBoxColor(rs, mat32.Vec2{lp.X + float32(c0)*tv.CharSize.X, lp.Y}, mat32.Vec2{float32(c1-c0) * tv.CharSize.X, tv.CharSize.Y}, gi.Prefs.Colors.Select)
+			}
+		}
+		if ln == len(sc.History)+sc.Cur.Row && sc.Modes.CursorVisible && tv.ScrollOff == 0 {
+			clr := gi.Prefs.Colors.Highlight
+			if tv.HasFocus() {
+				clr = st.Font.Color
+				clr.A = 128
+			}
+			pc.FillBoxColor(rs, mat32.Vec2{lp.X + float32(sc.Cur.Col)*tv.CharSize.X, lp.Y}, tv.CharSize, clr)
+		}
+		mu := sc.Line(ln).Markup(fg, bg)
+		if len(mu) == 0 {
+			continue
+		}
+		tv.rowRender.SetHTMLPre(mu, &st.Font, &st.Text, &st.UnContext, tv.CSSAgg)
+		tv.rowRender.LayoutStdLR(&st.Text, &st.Font, &st.UnContext, mat32.Vec2{float32(sc.Cols+1) * tv.CharSize.X, tv.CharSize.Y})
+		tv.rowRender.Render(rs, lp)
+	}
+	sc.Changed = false
+}
+
+func (tv *TermView) Style2D() {
+	tv.SetFlag(int(gi.CanFocus))
+	if _, has := tv.Props["font-family"]; !has {
+		tv.SetProp("font-family", gi.Prefs.MonoFont)
+	}
+	tv.WidgetBase.Style2D()
+}
+
+func (tv *TermView) Layout2D(parBBox image.Rectangle, iter int) bool {
+	redo := tv.WidgetBase.Layout2D(parBBox, iter)
+	tv.ResizeToAlloc()
+	return redo
+}
+
+func (tv *TermView) Render2D() {
+	if tv.FullReRenderIfNeeded() {
+		return
+	}
+	if tv.PushBounds() {
+		tv.This().(gi.Node2D).ConnectEvents2D()
+		tv.RenderTermInBounds()
+		tv.Render2DChildren()
+		tv.PopBounds()
+	} else {
+		tv.DisconnectAllEvents(gi.RegPri)
+	}
+}
+
+func (tv *TermView) FocusChanged2D(change gi.FocusChanges) {
+	switch change {
+	case gi.FocusLost, gi.FocusGot:
+		tv.UpdateSig()
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+//  Events
+
+// PointToCell returns the line, counting from the start of the history,
+// and the column of the character cell at given window position
+func (tv *TermView) PointToCell(pt image.Point) lex.Pos {
+	tv.BBoxMu.RLock()
+	vpt := mat32.NewVec2FmPoint(pt.Sub(tv.WinBBox.Min).Add(tv.VpBBox.Min))
+	tv.BBoxMu.RUnlock()
+	rel := vpt.Sub(tv.LayState.Alloc.Pos.AddScalar(tv.Sty.BoxSpace()))
+	tv.Mu.Lock()
+	defer tv.Mu.Unlock()
+	sc := tv.Screen
+	if tv.CharSize.X <= 0 || tv.CharSize.Y <= 0 {
+		return lex.Pos{}
+	}
+	row := ints.MaxInt(0, ints.MinInt(int(rel.Y/tv.CharSize.Y), sc.Rows-1))
+	col := ints.MaxInt(0, ints.MinInt(int(rel.X/tv.CharSize.X+0.5), sc.Cols))
+	return lex.Pos{Ln: len(sc.History) - tv.ScrollOff + row, Ch: col}
+}
+
+// KeyInput handles a key chord: copy, paste and scrolling keys, and
+// otherwise sends it to the command as terminal input
+func (tv *TermView) KeyInput(kt *key.ChordEvent) {
+	switch kt.Chord() {
+	case "Control+Shift+C", "Meta+C":
+		kt.SetProcessed()
+		tv.Copy()
+		return
+	case "Control+Shift+V", "Meta+V":
+		kt.SetProcessed()
+		tv.Paste()
+		return
+	case "Shift+PageUp":
+		kt.SetProcessed()
+		tv.ScrollBy(tv.Screen.Rows - 1)
+		return
+	case "Shift+PageDown":
+		kt.SetProcessed()
+		tv.ScrollBy(-(tv.Screen.Rows - 1))
+		return
+	}
+	tv.Mu.Lock()
+	running := tv.PTY != nil
+	app := tv.Screen.Modes.AppCursor
+	tv.Mu.Unlock()
+	if !running {
+		return
+	}
+	b := term.KeyBytes(kt.Code, kt.Rune, kt.Modifiers, app)
+	if len(b) == 0 {
+		return
+	}
+	kt.SetProcessed()
+	tv.Mu.Lock()
+	scrolled := tv.ScrollOff > 0 || tv.HasSelection()
+	tv.ScrollOff = 0
+	tv.SelEnd = tv.SelStart
+	tv.Mu.Unlock()
+	if scrolled {
+		tv.RenderTerm()
+	}
+	tv.Input(b)
+}
+
+// TermViewEvents connects the mouse and key events
+func (tv *TermView) TermViewEvents() {
+	tv.ConnectEvent(oswin.MouseEvent, gi.RegPri, func(recv, send ki.Ki, sig int64, d interface{}) {
+		me := d.(*mouse.Event)
+		tvv := recv.Embed(KiT_TermView).(*TermView)
+		me.SetProcessed()
+		if !tvv.HasFocus() {
+			tvv.GrabFocus()
+		}
+		switch {
+		case me.Button == mouse.Left && me.Action == mouse.Press:
+			pos := tvv.PointToCell(me.Where)
+			tvv.SelStart, tvv.SelEnd = pos, pos
+			tvv.selecting = true
+			tvv.RenderTerm()
+		case me.Button == mouse.Left && me.Action == mouse.Release:
+			tvv.selecting = false
+		case me.Button == mouse.Middle && me.Action == mouse.Press:
+			tvv.Paste()
+		case me.Button == mouse.Right && me.Action == mouse.Press:
+			tvv.EmitContextMenuSignal()
+			tvv.This().(gi.Node2D).ContextMenu()
+		}
+	})
+	tv.ConnectEvent(oswin.MouseDragEvent, gi.RegPri, func(recv, send ki.Ki, sig int64, d interface{}) {
+		me := d.(*mouse.DragEvent)
+		tvv := recv.Embed(KiT_TermView).(*TermView)
+		if !tvv.selecting {
+			return
+		}
+		me.SetProcessed()
+		tvv.SelEnd = tvv.PointToCell(me.Where)
+		tvv.RenderTerm()
+	})
+	tv.ConnectEvent(oswin.MouseScrollEvent, gi.RegPri, func(recv, send ki.Ki, sig int64, d interface{}) {
+		me := d.(*mouse.ScrollEvent)
+		me.SetProcessed()
+		tvv := recv.Embed(KiT_TermView).(*TermView)
+		del := me.NonZeroDelta(false)
+		switch {
+		case del < 0:
+			tvv.ScrollBy(TermViewScrollLines)
+		case del > 0:
+			tvv.ScrollBy(-TermViewScrollLines)
+		}
+	})
+	tv.ConnectEvent(oswin.MouseFocusEvent, gi.RegPri, func(recv, send ki.Ki, sig int64, d interface{}) {
+		me := d.(*mouse.FocusEvent)
+		me.SetProcessed()
+		tvv := recv.Embed(KiT_TermView).(*TermView)
+		if me.Action == mouse.Enter {
+			oswin.TheApp.Cursor(tvv.ParentWindow().OSWin).PushIfNot(cursor.IBeam)
+		} else {
+			oswin.TheApp.Cursor(tvv.ParentWindow().OSWin).PopIf(cursor.IBeam)
+		}
+	})
+	tv.ConnectEvent(oswin.KeyChordEvent, gi.RegPri, func(recv, send ki.Ki, sig int64, d interface{}) {
+		tvv := recv.Embed(KiT_TermView).(*TermView)
+		tvv.KeyInput(d.(*key.ChordEvent))
+	})
+}
+
+func (tv *TermView) ConnectEvents2D() {
+	tv.TermViewEvents()
+}
+
+var TermViewProps = ki.Props{
+	"EnumType:Flag":    gi.KiT_NodeFlags,
+	"min-width":        units.NewCh(20),
+	"min-height":       units.NewEm(5),
+	"width":            units.NewCh(80),
+	"height":           units.NewEm(24),
+	"padding":          units.NewPx(2),
+	"margin":           units.NewPx(2),
+	"border-width":     units.NewPx(1),
+	"border-color":     &gi.Prefs.Colors.Border,
+	"border-style":     gi.BorderSolid,
+	"white-space":      gi.WhiteSpacePre,
+	"color":            &gi.Prefs.Colors.Font,
+	"background-color": &gi.Prefs.Colors.Background,
+}
+
+// TermViewDialog opens a dialog with a terminal running given command --
+// the command is killed when the dialog is closed
+func TermViewDialog(avp *gi.Viewport2D, cmd *exec.Cmd, opts DlgOpts) (*TermView, error) {
+	dlg := gi.NewStdDialog(opts.ToGiOpts(), opts.Ok, opts.Cancel)
+	frame := dlg.Frame()
+	_, prIdx := dlg.PromptWidget(frame)
+	tv := frame.InsertNewChild(KiT_TermView, prIdx+1, "term-view").(*TermView)
+	tv.Viewport = dlg.Embed(gi.KiT_Viewport2D).(*gi.Viewport2D)
+	tv.Screen = term.NewScreen(24, 80)
+	tv.FindPos.Ln = -1
+	tv.SetStretchMax()
+	if err := tv.Start(cmd); err != nil {
+		return nil, err
+	}
+	dlg.UpdateEndNoSig(true)
+	dlg.Open(0, 0, avp, nil)
+	tv.GrabFocus()
+	return tv, nil
+}
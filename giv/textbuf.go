@@ -6,17 +6,25 @@ package giv
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/fswatch"
+	"github.com/goki/gi/giv/lsp"
 	"github.com/goki/gi/giv/textbuf"
+	"github.com/goki/gi/giv/vfs"
 	"github.com/goki/gi/histyle"
 	"github.com/goki/gi/units"
 	"github.com/goki/ki/indent"
@@ -49,37 +57,49 @@ var TextBufDiffRevertDiffs = 20
 // text is entered in the line
 var TextBufMarkupDelayMSec = 1000
 
+// TextBufMaxMarkupBytes is the size of text above which the entire buffer
+// is not parsed for syntax highlighting (see MarkupAllLines) -- each line
+// is then highlighted on its own as it is viewed, using chroma.
+var TextBufMaxMarkupBytes = 10000000
+
+// TextBufOpenTaskBytes is the size of files above which FileNode.OpenBuf
+// reads and decodes them in a Task of the window (see TextBuf.OpenTask),
+// so that opening them does not block it.
+var TextBufOpenTaskBytes = 4000000
+
 // TextBuf is a buffer of text, which can be viewed by TextView(s).  It holds
-// the raw text lines (in original string and rune formats, and marked-up from
-// syntax highlighting), and sends signals for making edits to the text and
+// the text in a piece table (see Store), from which lines are read as
+// needed, and the syntax highlighting tags of the lines, from which the
+// markup of the lines that are viewed is generated (see LineMarkup).  It no
+// longer has the per-line Lines, LineBytes, Tags, HiTags and Markup fields,
+// nor ByteOffs and TotalBytes: code that used them must call the methods of
+// the same names, which are deprecated, or preferably Line, BytesLine,
+// LineHiTags, LineMarkup and ByteOff.  It
+// sends signals for making edits to the text and
 // coordinating those edits across multiple views.  Views always only view a
 // single buffer, so they directly call methods on the buffer to drive
 // updates, which are then broadcast.  It also has methods for loading and
 // saving buffers to files.  Unlike GUI Widgets, its methods are generally
 // signaling, without an explicit Action suffix.  Internally, the buffer
-// represents new lines using \n = LF, but saving and loading can deal with
-// Windows/DOS CRLF format.
+// represents text as UTF-8 with new lines using \n = LF, and files in
+// other encodings or with CRLF or CR line endings are converted when
+// loading and saving -- see Opts.Encoding and Opts.LineEnds.
 type TextBuf struct {
 	ki.Node
 	Txt              []byte              `json:"-" xml:"text" desc:"the current value of the entire text being edited -- using []byte slice for greater efficiency"`
+	Store            *textbuf.PieceTable `json:"-" xml:"-" desc:"the live lines of text, each ending in a newline, as a piece table that refers directly to the originally loaded text until edited -- this is the only storage of the text being edited, from which lines are read as needed (see Line and BytesLine), and Txt is generated"`
 	Autosave         bool                `desc:"if true, auto-save file after changes (in a separate routine)"`
 	Opts             textbuf.Opts        `desc:"options for how text editing / viewing works"`
 	Filename         gi.FileName         `json:"-" xml:"-" desc:"filename of file last loaded or saved"`
 	Info             FileInfo            `desc:"full info about file"`
+	FS               vfs.FS              `view:"-" json:"-" xml:"-" desc:"file system of the file -- nil for the OS -- language servers, version control, undo history and watching are only available for files in the OS"`
 	PiState          pi.FileStates       `desc:"Pi parsing state info for file"`
 	Hi               HiMarkup            `desc:"syntax highlighting markup parameters (language, style, etc)"`
 	NLines           int                 `json:"-" xml:"-" desc:"number of lines"`
 	LineIcons        map[int]string      `desc:"icons for given lines -- use SetLineIcon and DeleteLineIcon"`
 	LineColors       map[int]gi.Color    `desc:"special line number colors given lines -- use SetLineColor and DeleteLineColor"`
 	Icons            map[string]*gi.Icon `json:"-" xml:"-" desc:"icons for each LineIcons being used"`
-	Lines            [][]rune            `json:"-" xml:"-" desc:"the live lines of text being edited, with latest modifications -- encoded as runes per line, which is necessary for one-to-one rune / glyph rendering correspondence -- all TextPos positions etc are in *rune* indexes, not byte indexes!"`
-	LineBytes        [][]byte            `json:"-" xml:"-" desc:"the live lines of text being edited, with latest modifications -- encoded in bytes per line translated from Lines, and used for input to markup -- essential to use Lines and not LineBytes when dealing with TextPos positions, which are in runes"`
-	Tags             []lex.Line          `json:"extra custom tagged regions for each line"`
-	HiTags           []lex.Line          `json:"syntax highlighting tags -- auto-generated"`
-	Markup           [][]byte            `json:"-" xml:"-" desc:"marked-up version of the edit text lines, after being run through the syntax highlighting process etc -- this is what is actually rendered"`
 	MarkupEdits      []*textbuf.Edit     `json:"-" xml:"-" desc:"edits that have been made since last full markup"`
-	ByteOffs         []int               `json:"-" xml:"-" desc:"offsets for start of each line in Txt []byte slice -- this is NOT updated with edits -- call SetByteOffs to set it when needed -- used for re-generating the Txt in LinesToBytes, and set on initial open in BytesToLines"`
-	TotalBytes       int                 `json:"-" xml:"-" desc:"total bytes in document -- see ByteOffs for when it is updated"`
 	LinesMu          sync.RWMutex        `json:"-" xml:"-" desc:"mutex for updating lines"`
 	MarkupMu         sync.RWMutex        `json:"-" xml:"-" desc:"mutex for updating markup"`
 	MarkupDelayTimer *time.Timer         `json:"-" xml:"-" desc:"markup delay timer"`
@@ -87,10 +107,22 @@ type TextBuf struct {
 	TextBufSig       ki.Signal           `json:"-" xml:"-" view:"-" desc:"signal for buffer -- see TextBufSignals for the types"`
 	Views            []*TextView         `json:"-" xml:"-" desc:"the TextViews that are currently viewing this buffer"`
 	Undos            textbuf.Undo        `json:"-" xml:"-" desc:"undo manager"`
+	FileHash         string              `json:"-" xml:"-" desc:"hash of the contents of the file when last opened or saved, which identifies its saved undo history -- see SaveUndoHist"`
 	PosHistory       []lex.Pos           `json:"-" xml:"-" desc:"history of cursor positions -- can move back through them"`
 	Complete         *gi.Complete        `json:"-" xml:"-" desc:"functions and data for text completion"`
 	Spell            *gi.Spell           `json:"-" xml:"-" desc:"functions and data for spelling correction"`
+	LSP              *lsp.Client         `json:"-" xml:"-" desc:"language server client for the file, if any -- see StartLSP"`
+	Collab           *TextBufCollab      `json:"-" xml:"-" desc:"collaborative editing session of the buffer, if any -- see HostCollab and JoinCollab"`
+	FileWatch        *fswatch.Watch      `json:"-" xml:"-" desc:"watch of the directory of the file for changes made by other programs, if watching -- see WatchFile"`
+	LSPVersion       int                 `json:"-" xml:"-" desc:"version of the text last sent to the language server"`
+	Diags            []lsp.Diagnostic    `json:"-" xml:"-" desc:"current diagnostics for the file from the language server, in its positions -- use DiagMu"`
+	DiagMu           sync.Mutex          `json:"-" xml:"-" desc:"mutex for Diags, which are set from the language server goroutine"`
+	Changes          *textbuf.Changes    `json:"-" xml:"-" desc:"how the lines differ from the version of the file in version control, shown next to the line numbers -- see SetVcsBase and UpdateVcs -- use LinesMu"`
+	Blame            []VcsBlameLine      `json:"-" xml:"-" desc:"who last changed each line of the version of the file in version control, shown at the end of the cursor line -- see UpdateVcs -- use LinesMu"`
+	Opening          *gi.Task            `json:"-" xml:"-" desc:"task reading the file being opened in the background, if any -- see OpenTask"`
+	OpeningFuncs     []func(err error)   `json:"-" xml:"-" view:"-" desc:"functions to call when the file being opened by OpenTask has been opened"`
 	CurView          *TextView           `json:"-" xml:"-" desc:"current textview -- e.g., the one that initiated Complete or Correct process -- update cursor position in this view -- is reset to nil after usage always"`
+	lineState        textBufLines        // syntax highlighting tags, custom tags and given markup of the lines that have any -- use MarkupMu
 }
 
 var KiT_TextBuf = kit.Types.AddType(&TextBuf{}, TextBufProps)
@@ -100,6 +132,7 @@ func (tb *TextBuf) Disconnect() {
 	tb.TextBufSig.DisconnectAll()
 	tb.DeleteSpell()
 	tb.DeleteCompleter()
+	tb.DeleteLSP()
 }
 
 var TextBufProps = ki.Props{
@@ -191,39 +224,30 @@ func (tb *TextBuf) ClearChanged() {
 	tb.ClearFlag(int(TextBufChanged))
 }
 
-// SetText sets the text to given bytes
+// SetText sets the text to given bytes, which are copied
 func (tb *TextBuf) SetText(txt []byte) {
 	tb.Defaults()
-	tb.Txt = txt
+	tb.Txt = append([]byte(nil), txt...)
 	tb.BytesToLines()
+	tb.LSPSync()
+	tb.CollabSync()
 	tb.InitialMarkup()
 	tb.Refresh()
 	tb.ReMarkup()
 }
 
-// SetTextLines sets the text to given lines of bytes
-// if cpy is true, make a copy of bytes -- otherwise use
+// SetTextLines sets the text to given lines of bytes -- they are always
+// copied, so cpy is ignored
 func (tb *TextBuf) SetTextLines(lns [][]byte, cpy bool) {
 	tb.Defaults()
-	tb.LinesMu.Lock()
-	tb.NLines = len(lns)
-	tb.LinesMu.Unlock()
-	tb.New(tb.NLines)
-	tb.LinesMu.Lock()
-	bo := 0
-	for ln, txt := range lns {
-		tb.ByteOffs[ln] = bo
-		tb.Lines[ln] = bytes.Runes(txt)
-		if cpy {
-			tb.LineBytes[ln] = make([]byte, len(txt))
-			copy(tb.LineBytes[ln], txt)
-		} else {
-			tb.LineBytes[ln] = txt
-		}
-		tb.Markup[ln] = HTMLEscapeRunes(tb.Lines[ln])
-		bo += len(txt) + 1 // lf
+	if len(lns) == 0 {
+		lns = [][]byte{nil}
 	}
-	tb.TotalBytes = bo
+	tb.setStore(textbuf.NewPieceTable(append(bytes.Join(lns, []byte("\n")), '\n')))
+	tb.LinesMu.Lock()
+	tb.LSPSyncImpl(true)
+	tb.CollabSyncImpl()
+	tb.ChangesUpdateImpl()
 	tb.LinesMu.Unlock()
 	tb.LinesToBytes()
 	tb.InitialMarkup()
@@ -266,34 +290,169 @@ func (tb *TextBuf) IsValidLine(ln int) bool {
 	return true
 }
 
-// Line is the concurrent-safe accessor to specific Line of Lines runes
+// Line is the concurrent-safe accessor to the runes of given line, which
+// are decoded from the Store -- all TextPos positions etc are in *rune*
+// indexes, not byte indexes!
 func (tb *TextBuf) Line(ln int) []rune {
 	tb.LinesMu.RLock()
 	defer tb.LinesMu.RUnlock()
+	return tb.LineImpl(ln)
+}
+
+// LineImpl returns the runes of given line, or nil if it is out of range.
+// Must be called under LinesMu lock.
+func (tb *TextBuf) LineImpl(ln int) []rune {
 	if ln >= tb.NLines || ln < 0 {
 		return nil
 	}
-	return tb.Lines[ln]
+	return bytes.Runes(tb.Store.Line(ln))
 }
 
-// LineLen is the concurrent-safe accessor to length of specific Line of Lines runes
+// LineLen is the concurrent-safe accessor to the number of runes of given line
 func (tb *TextBuf) LineLen(ln int) int {
 	tb.LinesMu.RLock()
 	defer tb.LinesMu.RUnlock()
+	return tb.LineLenImpl(ln)
+}
+
+// LineLenImpl returns the number of runes of given line.  Must be called
+// under LinesMu lock.
+func (tb *TextBuf) LineLenImpl(ln int) int {
 	if ln >= tb.NLines || ln < 0 {
 		return 0
 	}
-	return len(tb.Lines[ln])
+	return utf8.RuneCount(tb.Store.Line(ln))
 }
 
-// BytesLine is the concurrent-safe accessor to specific Line of LineBytes
+// BytesLine is the concurrent-safe accessor to the bytes of given line,
+// which refer directly to the Store where possible, so they must not be
+// modified
 func (tb *TextBuf) BytesLine(ln int) []byte {
 	tb.LinesMu.RLock()
 	defer tb.LinesMu.RUnlock()
 	if ln >= tb.NLines || ln < 0 {
 		return nil
 	}
-	return tb.LineBytes[ln]
+	return tb.Store.Line(ln)
+}
+
+// LinesImpl returns the runes of the lines from st to ed (exclusive),
+// limited to the valid lines -- for functions that look at a window of
+// lines.  Must be called under LinesMu lock.
+func (tb *TextBuf) LinesImpl(st, ed int) [][]rune {
+	st = ints.MaxInt(st, 0)
+	ed = ints.MinInt(ed, tb.NLines)
+	if st >= ed {
+		return nil
+	}
+	lns := make([][]rune, ed-st)
+	for ln := st; ln < ed; ln++ {
+		lns[ln-st] = tb.LineImpl(ln)
+	}
+	return lns
+}
+
+// The following replace the fields of the per-line copies of the text and
+// its markup that TextBuf used to have, which are no longer kept, as the
+// Store is the only storage of the text -- each returns a copy for all of
+// the lines, which takes as much memory as those fields did, so they should
+// not be used for large texts.
+
+// Lines returns the runes of all of the lines.
+//
+// Deprecated: was a field holding all of the lines -- use Line, or
+// LinesImpl for a range of lines.
+func (tb *TextBuf) Lines() [][]rune {
+	tb.LinesMu.RLock()
+	defer tb.LinesMu.RUnlock()
+	return tb.LinesImpl(0, tb.NLines)
+}
+
+// LineBytes returns the bytes of all of the lines.
+//
+// Deprecated: was a field holding all of the lines -- use BytesLine, or
+// Store (a textbuf.ByteLines) directly.
+func (tb *TextBuf) LineBytes() [][]byte {
+	tb.LinesMu.RLock()
+	defer tb.LinesMu.RUnlock()
+	lns := make([][]byte, tb.NLines)
+	for ln := range lns {
+		lns[ln] = append([]byte(nil), tb.Store.Line(ln)...)
+	}
+	return lns
+}
+
+// HiTags returns the syntax highlighting tags of all of the lines.
+//
+// Deprecated: was a field -- use LineHiTags.
+func (tb *TextBuf) HiTags() []lex.Line {
+	nln := tb.NumLines()
+	tags := make([]lex.Line, nln)
+	for ln := range tags {
+		tags[ln] = tb.LineHiTags(ln)
+	}
+	return tags
+}
+
+// Tags returns the extra custom tags of all of the lines.
+//
+// Deprecated: was a field -- use TagAt, AddTag and RemoveTag.
+func (tb *TextBuf) Tags() []lex.Line {
+	tb.LinesMu.RLock()
+	defer tb.LinesMu.RUnlock()
+	tb.MarkupMu.RLock()
+	defer tb.MarkupMu.RUnlock()
+	tags := make([]lex.Line, tb.NLines)
+	for ln := range tags {
+		tags[ln] = tb.lineState.tags(ln)
+	}
+	return tags
+}
+
+// Markup returns the markup of all of the lines.
+//
+// Deprecated: was a field -- use LineMarkup, which generates the markup of
+// a line as it is needed.
+func (tb *TextBuf) Markup() [][]byte {
+	nln := tb.NumLines()
+	mu := make([][]byte, nln)
+	for ln := range mu {
+		mu[ln] = tb.LineMarkup(ln)
+	}
+	return mu
+}
+
+// ByteOffs returns the byte offsets of the starts of all of the lines.
+//
+// Deprecated: was a field that had to be updated with SetByteOffs -- use
+// ByteOff, which is always up to date.
+func (tb *TextBuf) ByteOffs() []int {
+	tb.LinesMu.RLock()
+	defer tb.LinesMu.RUnlock()
+	offs := make([]int, tb.NLines)
+	for ln := range offs {
+		offs[ln] = tb.Store.LineStart(ln)
+	}
+	return offs
+}
+
+// TotalBytes returns the total number of bytes of the text.
+//
+// Deprecated: was a field that had to be updated with SetByteOffs -- use
+// Store.Len.
+func (tb *TextBuf) TotalBytes() int {
+	tb.LinesMu.RLock()
+	defer tb.LinesMu.RUnlock()
+	if tb.Store == nil {
+		return 0
+	}
+	return tb.Store.Len()
+}
+
+// SetByteOffs does nothing.
+//
+// Deprecated: ByteOff and Store.Len are always up to date.
+func (tb *TextBuf) SetByteOffs() {
 }
 
 // SetHiStyle sets the highlighting style -- needs to be protected by mutex
@@ -324,48 +483,42 @@ func (tb *TextBuf) SetInactive(inactive bool) {
 	tb.Undos.Off = inactive
 }
 
-// todo: use https://github.com/andybalholm/crlf to deal with cr/lf etc --
-// internally just use lf = \n
-
 // New initializes a new buffer with n blank lines
 func (tb *TextBuf) New(nlines int) {
-	tb.Defaults()
 	nlines = ints.MaxInt(nlines, 1)
+	tb.setStore(textbuf.NewPieceTable(bytes.Repeat([]byte("\n"), nlines)))
+	tb.Refresh()
+}
+
+// setStore initializes the buffer with given Store of text, in which each
+// line ends in a newline, resetting the undo history and the markup
+func (tb *TextBuf) setStore(st *textbuf.PieceTable) {
+	tb.Defaults()
 	tb.LinesMu.Lock()
 	tb.MarkupMu.Lock()
 	tb.Undos.Reset()
-	tb.Lines = make([][]rune, nlines)
-	tb.LineBytes = make([][]byte, nlines)
-	tb.Tags = make([]lex.Line, nlines)
-	tb.HiTags = make([]lex.Line, nlines)
-	tb.Markup = make([][]byte, nlines)
-
-	if cap(tb.ByteOffs) >= nlines {
-		tb.ByteOffs = tb.ByteOffs[:nlines]
-	} else {
-		tb.ByteOffs = make([]int, nlines)
-	}
-
-	if nlines == 1 { // this is used for a new blank doc
-		tb.ByteOffs[0] = 0 // by definition
-		tb.Lines[0] = []rune("")
-		tb.LineBytes[0] = []byte("")
-		tb.Markup[0] = []byte("")
-	}
-
-	tb.NLines = nlines
+	tb.Store = st
+	tb.NLines = st.NumLines()
+	tb.lineState = nil
+	tb.MarkupEdits = nil
 
 	tb.PiState.SetSrc(string(tb.Filename), "", tb.Info.Sup)
+	tb.Hi.LinesOnly = st.Len() > TextBufMaxMarkupBytes
 	tb.Hi.Init(&tb.Info, &tb.PiState)
 
 	tb.MarkupMu.Unlock()
 	tb.LinesMu.Unlock()
-	tb.Refresh()
+}
+
+// FileSys returns the file system of the file, which is the OS if FS is nil
+func (tb *TextBuf) FileSys() vfs.FS {
+	return vfs.OrOS(tb.FS)
 }
 
 // Stat gets info about the file, including highlighting language
 func (tb *TextBuf) Stat() error {
 	tb.ClearFlag(int(TextBufFileModOk))
+	tb.Info.FS = tb.FS
 	err := tb.Info.InitFile(string(tb.Filename))
 	if err != nil {
 		return err
@@ -396,7 +549,7 @@ func (tb *TextBuf) FileModCheck() bool {
 	if tb.HasFlag(int(TextBufFileModOk)) {
 		return false
 	}
-	info, err := os.Stat(string(tb.Filename))
+	info, err := tb.FileSys().Stat(string(tb.Filename))
 	if err != nil {
 		return false
 	}
@@ -423,14 +576,82 @@ func (tb *TextBuf) FileModCheck() bool {
 // Open loads text from a file into the buffer
 func (tb *TextBuf) Open(filename gi.FileName) error {
 	tb.Defaults()
-	err := tb.OpenFile(filename)
+	return tb.opened(filename, tb.OpenFile(filename))
+}
+
+// OpenTask loads text from a file into the buffer like Open, but reads and
+// decodes it in a Task of the window of the first view of the buffer, or
+// the focused window (see gi.TaskWindow), so that large files do not block
+// it -- the buffer is then updated on the event goroutine of the window,
+// and fun, if non-nil, is called there after it has been opened, or with
+// the error if that failed or the task was cancelled.  If there is no
+// window, the file is opened synchronously, returning a nil task.
+func (tb *TextBuf) OpenTask(filename gi.FileName, fun func(err error)) *gi.Task {
+	if fun != nil {
+		tb.OpeningFuncs = append(tb.OpeningFuncs, fun)
+	}
+	if tb.Opening != nil {
+		return tb.Opening
+	}
+	win := gi.TaskWindow(tb.ViewportFromView())
+	if win == nil {
+		tb.openedFuncs(tb.Open(filename))
+		return nil
+	}
+	fsys := tb.FileSys()
+	var tf textBufFile
+	tb.Opening = win.RunTask("Opening "+DirAndFile(string(filename)), func(ctx context.Context, tk *gi.Task) error {
+		raw, err := readFileTask(ctx, tk, fsys, string(filename))
+		if err != nil {
+			return err
+		}
+		tf = decodeTextBufFile(raw)
+		return nil
+	}, func(tk *gi.Task) {
+		tb.Opening = nil
+		if tk.Err != nil {
+			tb.openedFuncs(tb.opened(filename, tk.Err))
+			return
+		}
+		tb.Defaults()
+		tb.setFile(filename, tf)
+		tb.openedFuncs(tb.opened(filename, nil))
+	})
+	return tb.Opening
+}
+
+// openedFuncs calls and resets the OpeningFuncs with given error
+func (tb *TextBuf) openedFuncs(err error) {
+	funs := tb.OpeningFuncs
+	tb.OpeningFuncs = nil
+	for _, fun := range funs {
+		fun(err)
+	}
+}
+
+// opened finishes opening given file into the buffer after its text has
+// been loaded, or reports the error if it could not be
+func (tb *TextBuf) opened(filename gi.FileName, err error) error {
 	if err != nil {
-		vp := tb.ViewportFromView()
-		gi.PromptDialog(vp, gi.DlgOpts{Title: "File could not be Opened", Prompt: err.Error()}, gi.AddOk, gi.NoCancel, nil, nil)
-		log.Println(err)
+		if err != context.Canceled {
+			vp := tb.ViewportFromView()
+			gi.PromptDialog(vp, gi.DlgOpts{Title: "File could not be Opened", Prompt: err.Error()}, gi.AddOk, gi.NoCancel, nil, nil)
+			log.Println(err)
+		}
 		return err
 	}
 	tb.SetName(string(filename))
+	tb.OpenUndoHist()
+	if TextBufLSP && tb.LSP == nil && tb.LSPLang() != "" {
+		if err := tb.StartLSP(); err != nil {
+			log.Println(err)
+		}
+	}
+	if TextBufWatch || tb.FileWatch != nil {
+		if err := tb.WatchFile(); err != nil {
+			log.Println(err)
+		}
+	}
 
 	tb.InitialMarkup()
 	tb.Refresh()
@@ -441,16 +662,73 @@ func (tb *TextBuf) Open(filename gi.FileName) error {
 // OpenFile just loads a file into the buffer -- doesn't do any markup or
 // notification -- for temp bufs
 func (tb *TextBuf) OpenFile(filename gi.FileName) error {
-	fp, err := os.Open(string(filename))
+	raw, err := vfs.ReadFile(tb.FileSys(), string(filename))
 	if err != nil {
 		return err
 	}
-	tb.Txt, err = ioutil.ReadAll(fp)
-	fp.Close()
+	tb.setFile(filename, decodeTextBufFile(raw))
+	return nil
+}
+
+// textBufFile is the decoded text of a file, for loading into a TextBuf
+type textBufFile struct {
+	txt  []byte
+	enc  textbuf.Encodings
+	le   textbuf.LineEnds
+	hash string
+}
+
+// decodeTextBufFile decodes the raw bytes of a file
+func decodeTextBufFile(raw []byte) textBufFile {
+	tf := textBufFile{hash: textbuf.ContentHash(raw)}
+	tf.txt, tf.enc, tf.le = textbuf.DecodeText(raw)
+	return tf
+}
+
+// setFile sets the text of the buffer to that of given decoded file
+func (tb *TextBuf) setFile(filename gi.FileName, tf textBufFile) {
+	tb.Txt, tb.Opts.Encoding, tb.Opts.LineEnds = tf.txt, tf.enc, tf.le
 	tb.Filename = filename
+	tb.FileHash = tf.hash
 	tb.Stat()
 	tb.BytesToLines()
-	return nil
+	tb.LSPSync()
+}
+
+// readFileTask reads the named file from given file system in a Task,
+// reporting its progress and returning early if it is cancelled
+func readFileTask(ctx context.Context, tk *gi.Task, fsys vfs.FS, name string) ([]byte, error) {
+	fsys = vfs.OrOS(fsys)
+	fi, err := fsys.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sz := int(fi.Size())
+	b := make([]byte, 0, sz+1)
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if len(b) == cap(b) {
+			b = append(b, 0)[:len(b)]
+		}
+		n, err := f.Read(b[len(b):cap(b)])
+		b = b[:len(b)+n]
+		if sz > 0 {
+			tk.SetProgress(float32(len(b)) / float32(sz))
+		}
+		if err == io.EOF {
+			return b, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
 }
 
 // Revert re-opens text from current file, if filename set -- returns false if
@@ -466,6 +744,8 @@ func (tb *TextBuf) Revert() bool {
 	if tb.NLines < TextBufDiffRevertLines {
 		ob := &TextBuf{}
 		ob.InitName(ob, "revert-tmp")
+		ob.Hi.Style = tb.Hi.Style
+		ob.FS = tb.FS
 		err := ob.OpenFile(tb.Filename)
 		if err != nil {
 			vp := tb.ViewportFromView()
@@ -476,6 +756,8 @@ func (tb *TextBuf) Revert() bool {
 			return false
 		}
 		tb.Stat() // "own" the new file..
+		tb.FileHash = ob.FileHash
+		tb.Opts.Encoding, tb.Opts.LineEnds = ob.Opts.Encoding, ob.Opts.LineEnds
 		if ob.NLines < TextBufDiffRevertLines {
 			diffs := tb.DiffBufs(ob)
 			if len(diffs) < TextBufDiffRevertDiffs {
@@ -486,6 +768,7 @@ func (tb *TextBuf) Revert() bool {
 	}
 	if !didDiff {
 		tb.OpenFile(tb.Filename)
+		tb.OpenUndoHist()
 	}
 	tb.ClearChanged()
 	tb.AutoSaveDelete()
@@ -499,26 +782,24 @@ func (tb *TextBuf) Revert() bool {
 func (tb *TextBuf) SaveAsFunc(filename gi.FileName, afterFunc func(canceled bool)) {
 	// todo: filemodcheck!
 	tb.EditDone()
-	if _, err := os.Stat(string(filename)); os.IsNotExist(err) {
-		tb.SaveFile(filename)
+	saved := func(err error) {
 		if afterFunc != nil {
-			afterFunc(false)
+			afterFunc(err != nil)
 		}
+	}
+	if _, err := tb.FileSys().Stat(string(filename)); os.IsNotExist(err) {
+		tb.SaveFileFunc(filename, saved)
 	} else {
 		vp := tb.ViewportFromView()
 		gi.ChoiceDialog(vp, gi.DlgOpts{Title: "File Exists, Overwrite?",
 			Prompt: fmt.Sprintf("File already exists, overwrite?  File: %v", filename)},
 			[]string{"Cancel", "Overwrite"},
 			tb.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
-				cancel := false
 				switch sig {
 				case 0:
-					cancel = true
+					saved(ErrSaveCanceled)
 				case 1:
-					tb.SaveFile(filename)
-				}
-				if afterFunc != nil {
-					afterFunc(cancel)
+					tb.SaveFileFunc(filename, saved)
 				}
 			})
 	}
@@ -530,28 +811,115 @@ func (tb *TextBuf) SaveAs(filename gi.FileName) {
 	tb.SaveAsFunc(filename, nil)
 }
 
-// SaveFile writes current buffer to file, with no prompting, etc
+// ErrSavePending is returned by the save methods when they are waiting for
+// the user to decide how to save the file in a dialog -- the file is then
+// saved or not later, as reported to the function given to SaveFunc or
+// SaveFileFunc.
+var ErrSavePending = errors.New("giv.TextBuf: saving is waiting for the user")
+
+// ErrSaveCanceled is passed to the function given to SaveFunc or
+// SaveFileFunc when the user decides not to save the file
+var ErrSaveCanceled = errors.New("giv.TextBuf: saving was canceled")
+
+// SaveFile writes current buffer to file, in the encoding and line endings
+// of Opts, with no prompting, etc -- except that if the text has characters
+// that can't be represented in the encoding, the user is asked whether to
+// save in UTF-8 instead (which is done without a view to ask), in which
+// case it returns ErrSavePending -- see SaveFileFunc to know when it is
+// done.
 func (tb *TextBuf) SaveFile(filename gi.FileName) error {
-	err := ioutil.WriteFile(string(filename), tb.Txt, 0644)
+	return tb.SaveFileFunc(filename, nil)
+}
+
+// SaveFileFunc is SaveFile calling given function, if non-nil, once the
+// file is saved (with nil), can't be (with the error), or the user decides
+// not to save it (with ErrSaveCanceled) -- it returns the same error, or
+// ErrSavePending if it is waiting for the user.
+func (tb *TextBuf) SaveFileFunc(filename gi.FileName, fun func(err error)) error {
+	done := func(err error) error {
+		if fun != nil {
+			fun(err)
+		}
+		return err
+	}
+	b, nbad := textbuf.EncodeText(tb.Txt, tb.Opts.Encoding, tb.Opts.LineEnds)
+	if nbad > 0 {
+		vp := tb.ViewportFromView()
+		if vp == nil {
+			log.Printf("giv.TextBuf: %d characters can not be represented in encoding: %v -- saving as UTF-8: %v\n", nbad, tb.Opts.Encoding.Name(), filename)
+			tb.Opts.Encoding = textbuf.UTF8
+			return tb.SaveFileFunc(filename, fun)
+		}
+		gi.ChoiceDialog(vp, gi.DlgOpts{Title: "Characters Not Representable in Encoding",
+			Prompt: fmt.Sprintf("%d characters in the text can not be represented in the %v encoding of the file -- save it as UTF-8 instead, or replace them with '?'?  File: %v", nbad, tb.Opts.Encoding.Name(), filename)},
+			[]string{"Save as UTF-8", "Save with Replacements", "Cancel"},
+			tb.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+				switch sig {
+				case 0:
+					tb.Opts.Encoding = textbuf.UTF8
+					tb.SaveFileFunc(filename, fun)
+				case 1:
+					done(tb.SaveFileBytes(filename, b))
+				default:
+					done(ErrSaveCanceled)
+				}
+			})
+		return ErrSavePending
+	}
+	return done(tb.SaveFileBytes(filename, b))
+}
+
+// SaveFileBytes writes given bytes, which are the encoded text of the
+// buffer, to file, with no prompting, etc
+func (tb *TextBuf) SaveFileBytes(filename gi.FileName, b []byte) error {
+	err := vfs.WriteFile(tb.FileSys(), string(filename), b, 0644)
 	if err != nil {
 		gi.PromptDialog(nil, gi.DlgOpts{Title: "Could not Save to File", Prompt: err.Error()}, gi.AddOk, gi.NoCancel, nil, nil)
 		log.Println(err)
 	} else {
+		renamed := tb.LSP != nil && filename != tb.Filename
+		if renamed { // the server knows the file by name
+			tb.DeleteLSP()
+		}
 		tb.Filename = filename
+		tb.FileHash = textbuf.ContentHash(b)
 		tb.SetName(string(filename))
 		tb.Stat()
+		tb.SaveUndoHist()
+		if tb.FileWatch != nil {
+			tb.WatchFile()
+		}
+		if renamed {
+			tb.StartLSP()
+		} else if tb.LSP != nil {
+			tb.LSP.DidSave(tb.LSPURI())
+		}
 	}
 	return err
 }
 
 // Save saves the current text into current Filename associated with this
-// buffer
+// buffer -- if the file has changed on disk, or has characters that can't
+// be saved in its encoding, the user is asked what to do, and it returns
+// ErrSavePending -- see SaveFunc to know when it is done.
 func (tb *TextBuf) Save() error {
+	return tb.SaveFunc(nil)
+}
+
+// SaveFunc is Save calling given function, if non-nil, once the file is
+// saved (with nil), can't be (with the error), or is not saved because of
+// the decision of the user (with ErrSaveCanceled) -- it returns the same
+// error, or ErrSavePending if it is waiting for the user.
+func (tb *TextBuf) SaveFunc(fun func(err error)) error {
 	if tb.Filename == "" {
-		return fmt.Errorf("giv.TextBuf: filename is empty for Save")
+		err := fmt.Errorf("giv.TextBuf: filename is empty for Save")
+		if fun != nil {
+			fun(err)
+		}
+		return err
 	}
 	tb.EditDone()
-	info, err := os.Stat(string(tb.Filename))
+	info, err := tb.FileSys().Stat(string(tb.Filename))
 	if err == nil && info.ModTime() != time.Time(tb.Info.ModTime) {
 		vp := tb.ViewportFromView()
 		gi.ChoiceDialog(vp, gi.DlgOpts{Title: "File Changed on Disk",
@@ -564,11 +932,63 @@ func (tb *TextBuf) Save() error {
 				case 1:
 					tb.Revert()
 				case 2:
-					tb.SaveFile(tb.Filename)
+					tb.SaveFileFunc(tb.Filename, fun)
+					return
+				}
+				if fun != nil { // this file was not saved
+					fun(ErrSaveCanceled)
 				}
 			})
+		return ErrSavePending
+	}
+	return tb.SaveFileFunc(tb.Filename, fun)
+}
+
+// SetEncoding sets the encoding to use when saving the file, marking the
+// buffer as changed -- returns the number of characters in the text that
+// can not be represented in it
+func (tb *TextBuf) SetEncoding(enc textbuf.Encodings) int {
+	if enc == tb.Opts.Encoding {
+		return 0
+	}
+	tb.Opts.Encoding = enc
+	tb.SetChanged()
+	_, nbad := textbuf.Encode(tb.LinesToBytesCopy(), enc)
+	return nbad
+}
+
+// SetLineEnds sets the line endings to use when saving the file, marking
+// the buffer as changed
+func (tb *TextBuf) SetLineEnds(le textbuf.LineEnds) {
+	if le == tb.Opts.LineEnds {
+		return
+	}
+	tb.Opts.LineEnds = le
+	tb.SetChanged()
+}
+
+// ReopenEncoding re-opens the file, interpreting it as being in given
+// encoding instead of the detected one -- any changes are lost
+func (tb *TextBuf) ReopenEncoding(enc textbuf.Encodings) error {
+	if tb.Filename == "" {
+		return fmt.Errorf("giv.TextBuf: filename is empty for ReopenEncoding")
+	}
+	raw, err := vfs.ReadFile(tb.FileSys(), string(tb.Filename))
+	if err != nil {
+		return err
 	}
-	return tb.SaveFile(tb.Filename)
+	txt := textbuf.Decode(raw, enc)
+	tb.Opts.LineEnds = textbuf.DetectLineEnds(txt)
+	tb.Opts.Encoding = enc
+	tb.Txt = textbuf.NormLineEnds(txt)
+	tb.BytesToLines()
+	tb.LSPSync()
+	tb.ClearChanged()
+	tb.AutoSaveDelete()
+	tb.InitialMarkup()
+	tb.Refresh()
+	tb.ReMarkup()
+	return nil
 }
 
 // Close closes the buffer -- prompts to save if changes, and disconnects from views
@@ -583,8 +1003,13 @@ func (tb *TextBuf) Close(afterFun func(canceled bool)) bool {
 				tb.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
 					switch sig {
 					case 0:
-						tb.Save()
-						tb.Close(afterFun) // 2nd time through won't prompt
+						tb.SaveFunc(func(err error) {
+							if err == nil {
+								tb.Close(afterFun) // 2nd time through won't prompt
+							} else if afterFun != nil {
+								afterFun(true)
+							}
+						})
 					case 1:
 						tb.ClearChanged()
 						tb.AutoSaveDelete()
@@ -618,6 +1043,10 @@ func (tb *TextBuf) Close(afterFun func(canceled bool)) bool {
 	// for _, tve := range tb.Views {
 	// 	tve.SetBuf(nil) // automatically disconnects signals, views
 	// }
+	tb.SaveUndoHist()
+	tb.DeleteLSP()
+	tb.LeaveCollab()
+	tb.UnwatchFile()
 	tb.New(1)
 	tb.Filename = ""
 	tb.ClearChanged()
@@ -662,7 +1091,7 @@ func (tb *TextBuf) AutoSave() error {
 	tb.SetFlag(int(TextBufAutoSaving))
 	asfn := tb.AutoSaveFilename()
 	b := tb.LinesToBytesCopy()
-	err := ioutil.WriteFile(asfn, b, 0644)
+	err := vfs.WriteFile(tb.FileSys(), asfn, b, 0644)
 	if err != nil {
 		log.Printf("giv.TextBuf: Could not AutoSave file: %v, error: %v\n", asfn, err)
 	}
@@ -673,14 +1102,14 @@ func (tb *TextBuf) AutoSave() error {
 // AutoSaveDelete deletes any existing autosave file
 func (tb *TextBuf) AutoSaveDelete() {
 	asfn := tb.AutoSaveFilename()
-	os.Remove(asfn)
+	tb.FileSys().Remove(asfn)
 }
 
 // AutoSaveCheck checks if an autosave file exists -- logic for dealing with
 // it is left to larger app -- call this before opening a file
 func (tb *TextBuf) AutoSaveCheck() bool {
 	asfn := tb.AutoSaveFilename()
-	if _, err := os.Stat(asfn); os.IsNotExist(err) {
+	if _, err := tb.FileSys().Stat(asfn); os.IsNotExist(err) {
 		return false // does not exist
 	}
 	return true
@@ -697,7 +1126,7 @@ func (tb *TextBuf) EndPos() lex.Pos {
 	if tb.NLines == 0 {
 		return lex.PosZero
 	}
-	ed := lex.Pos{tb.NLines - 1, len(tb.Lines[tb.NLines-1])}
+	ed := lex.Pos{tb.NLines - 1, tb.LineLenImpl(tb.NLines - 1)}
 	return ed
 }
 
@@ -752,9 +1181,11 @@ func (tb *TextBuf) AppendTextMarkup(text []byte, markup []byte, signal bool) *te
 		log.Printf("TextBuf AppendTextMarkup: markup text less than appended text: is: %v, should be: %v\n", len(msplt), sz)
 		el = ints.MinInt(st+len(msplt)-1, el)
 	}
+	tb.MarkupMu.Lock()
 	for ln := st; ln <= el; ln++ {
-		tb.Markup[ln] = msplt[ln-st]
+		tb.lineState.setMarkup(ln, msplt[ln-st])
 	}
+	tb.MarkupMu.Unlock()
 	if signal {
 		tb.TextBufSig.Emit(tb.This(), int64(TextBufInsert), tbe)
 	}
@@ -783,7 +1214,9 @@ func (tb *TextBuf) AppendTextLineMarkup(text []byte, markup []byte, signal bool)
 		efft = tcpy
 	}
 	tbe := tb.InsertText(ed, efft, false)
-	tb.Markup[tbe.Reg.Start.Ln] = markup
+	tb.MarkupMu.Lock()
+	tb.lineState.setMarkup(tbe.Reg.Start.Ln, markup)
+	tb.MarkupMu.Unlock()
 	if signal {
 		tb.TextBufSig.Emit(tb.This(), int64(TextBufInsert), tbe)
 	}
@@ -875,16 +1308,6 @@ func (tb *TextBuf) AddFileNode(fn *FileNode) {
 /////////////////////////////////////////////////////////////////////////////
 //   Accessing Text
 
-// SetByteOffs sets the byte offsets for each line into the raw text
-func (tb *TextBuf) SetByteOffs() {
-	bo := 0
-	for ln, txt := range tb.LineBytes {
-		tb.ByteOffs[ln] = bo
-		bo += len(txt) + 1 // lf
-	}
-	tb.TotalBytes = bo
-}
-
 // LinesToBytes converts current Lines back to the Txt slice of bytes.
 func (tb *TextBuf) LinesToBytes() {
 	tb.LinesMu.RLock()
@@ -897,9 +1320,7 @@ func (tb *TextBuf) LinesToBytes() {
 		return
 	}
 
-	txt := bytes.Join(tb.LineBytes, []byte("\n"))
-	txt = append(txt, '\n')
-	tb.Txt = txt
+	tb.Txt = tb.Store.Bytes()
 }
 
 // LinesToBytesCopy converts current Lines into a separate text byte copy --
@@ -909,39 +1330,45 @@ func (tb *TextBuf) LinesToBytesCopy() []byte {
 	tb.LinesMu.RLock()
 	defer tb.LinesMu.RUnlock()
 
-	txt := bytes.Join(tb.LineBytes, []byte("\n"))
-	txt = append(txt, '\n')
-	return txt
+	if tb.Store == nil {
+		return []byte("\n")
+	}
+	return tb.Store.Bytes()
 }
 
-// BytesToLines converts current Txt bytes into lines, and initializes markup
-// with raw text
+// BytesToLines sets the lines of the buffer to the current Txt bytes, which
+// are used directly as the original text of the Store, so they must not be
+// modified afterward.  Nothing is copied or decoded, and markup is
+// generated lazily as lines are viewed, so that this is fast even for very
+// large texts.
 func (tb *TextBuf) BytesToLines() {
-	if len(tb.Txt) == 0 {
-		tb.New(1)
-		return
-	}
-	tb.LinesMu.Lock()
-	lns := bytes.Split(tb.Txt, []byte("\n"))
-	tb.NLines = len(lns)
-	if len(lns[tb.NLines-1]) == 0 { // lines have lf at end typically
-		tb.NLines--
-		lns = lns[:tb.NLines]
+	st := textbuf.NewPieceTable(tb.Txt)
+	if len(tb.Txt) == 0 || tb.Txt[len(tb.Txt)-1] != '\n' { // lines have lf at end typically
+		st.Insert(st.Len(), []byte("\n"))
 	}
-	tb.LinesMu.Unlock()
-	tb.New(tb.NLines)
+	tb.setStore(st)
 	tb.LinesMu.Lock()
-	bo := 0
-	for ln, txt := range lns {
-		tb.ByteOffs[ln] = bo
-		tb.Lines[ln] = bytes.Runes(txt)
-		tb.LineBytes[ln] = make([]byte, len(txt))
-		copy(tb.LineBytes[ln], txt)
-		tb.Markup[ln] = HTMLEscapeRunes(tb.Lines[ln])
-		bo += len(txt) + 1 // lf
-	}
-	tb.TotalBytes = bo
+	tb.ChangesUpdateImpl()
 	tb.LinesMu.Unlock()
+	tb.Refresh()
+}
+
+// ByteOff returns the byte offset within Store of given position, which
+// must be valid.  Must be called under LinesMu lock.
+func (tb *TextBuf) ByteOff(pos lex.Pos) int {
+	lb := tb.Store.Line(pos.Ln)
+	off := 0
+	for ch := 0; ch < pos.Ch && off < len(lb); ch++ {
+		_, sz := utf8.DecodeRune(lb[off:])
+		off += sz
+	}
+	return tb.Store.LineStart(pos.Ln) + off
+}
+
+// StoreLine replaces the text of given line in Store with given runes, for
+// edits that modify lines in place.  Must be called under LinesMu lock.
+func (tb *TextBuf) StoreLine(ln int, txt []rune) {
+	tb.Store.Replace(tb.Store.LineStart(ln), len(tb.Store.Line(ln)), []byte(string(txt)))
 }
 
 // Strings returns the current text as []string array.
@@ -950,8 +1377,8 @@ func (tb *TextBuf) Strings(addNewLn bool) []string {
 	tb.LinesMu.RLock()
 	defer tb.LinesMu.RUnlock()
 	str := make([]string, tb.NLines)
-	for i, l := range tb.Lines {
-		str[i] = string(l)
+	for i := range str {
+		str[i] = string(tb.Store.Line(i))
 		if addNewLn {
 			str[i] += "\n"
 		}
@@ -962,16 +1389,30 @@ func (tb *TextBuf) Strings(addNewLn bool) []string {
 // Search looks for a string (no regexp) within buffer,
 // with given case-sensitivity, returning number of occurrences
 // and specific match position list. column positions are in runes.
+// Lines are searched one at a time, so that the text is not all decoded.
 func (tb *TextBuf) Search(find []byte, ignoreCase, lexItems bool) (int, []textbuf.Match) {
 	tb.LinesMu.RLock()
 	defer tb.LinesMu.RUnlock()
+	fr := bytes.Runes(find)
+	if len(fr) == 0 {
+		return 0, nil
+	}
 	if lexItems {
 		tb.MarkupMu.RLock()
 		defer tb.MarkupMu.RUnlock()
-		return textbuf.SearchLexItems(tb.Lines, tb.HiTags, find, ignoreCase)
-	} else {
-		return textbuf.SearchRuneLines(tb.Lines, find, ignoreCase)
 	}
+	var matches []textbuf.Match
+	for ln := 0; ln < tb.NLines; ln++ {
+		if lexItems {
+			hi := tb.lineState.hi(ln)
+			if len(hi) > 0 {
+				matches = textbuf.SearchLexLine(matches, tb.LineImpl(ln), hi, ln, fr, ignoreCase)
+			}
+		} else {
+			matches = textbuf.SearchRuneLine(matches, tb.LineImpl(ln), ln, fr, ignoreCase)
+		}
+	}
+	return len(matches), matches
 }
 
 // SearchRegexp looks for a string (regexp) within buffer,
@@ -980,7 +1421,16 @@ func (tb *TextBuf) Search(find []byte, ignoreCase, lexItems bool) (int, []textbu
 func (tb *TextBuf) SearchRegexp(re *regexp.Regexp) (int, []textbuf.Match) {
 	tb.LinesMu.RLock()
 	defer tb.LinesMu.RUnlock()
-	return textbuf.SearchByteLinesRegexp(tb.LineBytes, re)
+	return tb.SearchRegexpImpl(re)
+}
+
+// SearchRegexpImpl is SearchRegexp for use under LinesMu lock
+func (tb *TextBuf) SearchRegexpImpl(re *regexp.Regexp) (int, []textbuf.Match) {
+	var matches []textbuf.Match
+	for ln := 0; ln < tb.NLines; ln++ {
+		matches = textbuf.SearchByteLineRegexp(matches, tb.Store.Line(ln), ln, re)
+	}
+	return len(matches), matches
 }
 
 // BraceMatch finds the brace, bracket, or parens that is the partner
@@ -990,7 +1440,43 @@ func (tb *TextBuf) BraceMatch(r rune, st lex.Pos) (en lex.Pos, found bool) {
 	defer tb.LinesMu.RUnlock()
 	tb.MarkupMu.RLock()
 	defer tb.MarkupMu.RUnlock()
-	return lex.BraceMatch(tb.Lines, tb.HiTags, r, st, TextBufMaxScopeLines)
+	// only the lines within TextBufMaxScopeLines are looked at
+	wst := ints.MaxInt(st.Ln-TextBufMaxScopeLines, 0)
+	src, tags := tb.linesWindow(wst, st.Ln+TextBufMaxScopeLines+1)
+	st.Ln -= wst
+	en, found = lex.BraceMatch(src, tags, r, st, TextBufMaxScopeLines)
+	if found {
+		en.Ln += wst
+	}
+	return
+}
+
+// linesWindow returns the runes and syntax highlighting tags of the lines
+// from st to ed (exclusive), limited to the valid lines.  Must be called
+// under LinesMu and MarkupMu locks.
+func (tb *TextBuf) linesWindow(st, ed int) ([][]rune, []lex.Line) {
+	st = ints.MaxInt(st, 0)
+	src := tb.LinesImpl(st, ed)
+	tags := make([]lex.Line, len(src))
+	for i := range tags {
+		tags[i] = tb.lineState.hi(st + i)
+	}
+	return src, tags
+}
+
+// FoldRegions returns the regions of the buffer that can be folded, based
+// on braces etc in the markup tags and on indentation -- see
+// textbuf.FoldRegions.  Texts larger than TextBufMaxMarkupBytes have none.
+func (tb *TextBuf) FoldRegions() []textbuf.Region {
+	tb.LinesMu.RLock()
+	defer tb.LinesMu.RUnlock()
+	if tb.Store.Len() > TextBufMaxMarkupBytes {
+		return nil
+	}
+	tb.MarkupMu.RLock()
+	defer tb.MarkupMu.RUnlock()
+	src, tags := tb.linesWindow(0, tb.NLines)
+	return textbuf.FoldRegions(src, tags, tb.Opts.TabSize)
 }
 
 /////////////////////////////////////////////////////////////////////////////
@@ -1007,13 +1493,12 @@ func (tb *TextBuf) ValidPos(pos lex.Pos) lex.Pos {
 	if pos.Ln < 0 {
 		pos.Ln = 0
 	}
-	if pos.Ln >= len(tb.Lines) {
-		pos.Ln = len(tb.Lines) - 1
-		pos.Ch = len(tb.Lines[pos.Ln])
+	if pos.Ln >= tb.NLines {
+		pos.Ln = tb.NLines - 1
+		pos.Ch = tb.LineLenImpl(pos.Ln)
 		return pos
 	}
-	pos.Ln = ints.MinInt(pos.Ln, len(tb.Lines)-1)
-	llen := len(tb.Lines[pos.Ln])
+	llen := tb.LineLenImpl(pos.Ln)
 	pos.Ch = ints.MinInt(pos.Ch, llen)
 	if pos.Ch < 0 {
 		pos.Ch = 0
@@ -1076,27 +1561,18 @@ func (tb *TextBuf) DeleteTextImpl(st, ed lex.Pos) *textbuf.Edit {
 		return nil
 	}
 	tbe.Delete = true
+	tb.LSPChangeImpl(st, ed, nil)
+	tb.CollabChangeImpl(st, ed, nil)
+	stoff := tb.ByteOff(st)
+	tb.Store.Delete(stoff, tb.ByteOff(ed)-stoff)
+	tb.NLines = tb.Store.NumLines()
 	if ed.Ln == st.Ln {
-		tb.Lines[st.Ln] = append(tb.Lines[st.Ln][:st.Ch], tb.Lines[st.Ln][ed.Ch:]...)
 		tb.LinesEdited(tbe)
 	} else {
-		// first get chars on start and end
-		stln := st.Ln + 1
-		cpln := st.Ln
-		tb.Lines[st.Ln] = tb.Lines[st.Ln][:st.Ch]
-		eoedl := len(tb.Lines[ed.Ln][ed.Ch:])
-		var eoed []rune
-		if eoedl > 0 { // save it
-			eoed = make([]rune, eoedl)
-			copy(eoed, tb.Lines[ed.Ln][ed.Ch:])
-		}
-		tb.Lines = append(tb.Lines[:stln], tb.Lines[ed.Ln+1:]...)
-		if eoed != nil {
-			tb.Lines[cpln] = append(tb.Lines[cpln], eoed...)
-		}
-		tb.NLines = len(tb.Lines)
 		tb.LinesDeleted(tbe)
 	}
+	tb.ChangesEditImpl(st.Ln, ed.Ln+1, 1)
+	tb.LSPSyncImpl(false)
 	return tbe
 }
 
@@ -1140,12 +1616,15 @@ func (tb *TextBuf) DeleteTextRectImpl(st, ed lex.Pos) *textbuf.Edit {
 	}
 	tbe.Delete = true
 	for ln := st.Ln; ln <= ed.Ln; ln++ {
-		ls := tb.Lines[ln]
+		ls := tb.LineImpl(ln)
 		if len(ls) > st.Ch {
-			tb.Lines[ln] = append(ls[:st.Ch], ls[ed.Ch:]...) // should be ok even if shorter?
+			tb.StoreLine(ln, append(ls[:st.Ch], ls[ints.MinInt(ed.Ch, len(ls)):]...))
 		}
 	}
 	tb.LinesEdited(tbe)
+	tb.ChangesEditImpl(st.Ln, ed.Ln+1, ed.Ln+1-st.Ln)
+	tb.LSPSyncImpl(true)
+	tb.CollabSyncImpl()
 	return tbe
 }
 
@@ -1160,7 +1639,7 @@ func (tb *TextBuf) InsertText(st lex.Pos, text []byte, signal bool) *textbuf.Edi
 	st = tb.ValidPos(st)
 	tb.FileModCheck() // will just revert changes if shouldn't have changed
 	tb.SetChanged()
-	if len(tb.Lines) == 0 {
+	if tb.NumLines() == 0 {
 		tb.New(1)
 	}
 	tb.LinesMu.Lock()
@@ -1179,50 +1658,25 @@ func (tb *TextBuf) InsertText(st lex.Pos, text []byte, signal bool) *textbuf.Edi
 // InsertTextImpl does the raw insert of new text at given starting position, returning
 // a new Edit with timestamp of Now.  LinesMu must be locked surrounding this call.
 func (tb *TextBuf) InsertTextImpl(st lex.Pos, text []byte) *textbuf.Edit {
-	lns := bytes.Split(text, []byte("\n"))
-	sz := len(lns)
-	rs := bytes.Runes(lns[0])
-	rsz := len(rs)
+	sz := bytes.Count(text, []byte("\n")) + 1
 	ed := st
 	var tbe *textbuf.Edit
+	tb.LSPChangeImpl(st, st, text)
+	tb.CollabChangeImpl(st, st, text)
+	tb.Store.Insert(tb.ByteOff(st), text)
+	tb.NLines = tb.Store.NumLines()
 	if sz == 1 {
-		nt := append(tb.Lines[st.Ln], rs...) // first append to end to extend capacity
-		copy(nt[st.Ch+rsz:], nt[st.Ch:])     // move stuff to end
-		copy(nt[st.Ch:], rs)                 // copy into position
-		tb.Lines[st.Ln] = nt
-		ed.Ch += rsz
+		ed.Ch += utf8.RuneCount(text)
 		tbe = tb.RegionImpl(st, ed)
 		tb.LinesEdited(tbe)
 	} else {
-		if tb.Lines[st.Ln] == nil {
-			tb.Lines[st.Ln] = []rune("")
-		}
-		eostl := len(tb.Lines[st.Ln][st.Ch:]) // end of starting line
-		var eost []rune
-		if eostl > 0 { // save it
-			eost = make([]rune, eostl)
-			copy(eost, tb.Lines[st.Ln][st.Ch:])
-		}
-		tb.Lines[st.Ln] = append(tb.Lines[st.Ln][:st.Ch], rs...)
-		nsz := sz - 1
-		tmp := make([][]rune, nsz)
-		for i := 1; i < sz; i++ {
-			tmp[i-1] = bytes.Runes(lns[i])
-		}
-		stln := st.Ln + 1
-		nt := append(tb.Lines, tmp...) // first append to end to extend capacity
-		copy(nt[stln+nsz:], nt[stln:]) // move stuff to end
-		copy(nt[stln:], tmp)           // copy into position
-		tb.Lines = nt
-		tb.NLines = len(tb.Lines)
-		ed.Ln += nsz
-		ed.Ch = len(tb.Lines[ed.Ln])
-		if eost != nil {
-			tb.Lines[ed.Ln] = append(tb.Lines[ed.Ln], eost...)
-		}
+		ed.Ln += sz - 1
+		ed.Ch = utf8.RuneCount(text[bytes.LastIndexByte(text, '\n')+1:])
 		tbe = tb.RegionImpl(st, ed)
 		tb.LinesInserted(tbe)
 	}
+	tb.ChangesEditImpl(st.Ln, st.Ln+1, sz)
+	tb.LSPSyncImpl(false)
 	return tbe
 }
 
@@ -1269,14 +1723,11 @@ func (tb *TextBuf) InsertTextRectImpl(tbe *textbuf.Edit) *textbuf.Edit {
 		return nil
 	}
 	// make sure there are enough lines -- add as needed
-	cln := len(tb.Lines)
-	if cln == 0 {
-		tb.New(nlns)
-	} else if cln <= ed.Ln {
+	cln := tb.NLines
+	if cln <= ed.Ln {
 		nln := (1 + ed.Ln) - cln
-		tmp := make([][]rune, nln)
-		tb.Lines = append(tb.Lines, tmp...) // first append to end to extend capacity
-		tb.NLines = len(tb.Lines)
+		tb.Store.Insert(tb.Store.Len(), bytes.Repeat([]byte("\n"), nln))
+		tb.NLines = tb.Store.NumLines()
 		ie := &textbuf.Edit{}
 		ie.Reg.Start.Ln = cln - 1
 		ie.Reg.End.Ln = ed.Ln
@@ -1285,7 +1736,7 @@ func (tb *TextBuf) InsertTextRectImpl(tbe *textbuf.Edit) *textbuf.Edit {
 	nch := (ed.Ch - st.Ch)
 	for i := 0; i < nlns; i++ {
 		ln := st.Ln + i
-		lr := tb.Lines[ln]
+		lr := tb.LineImpl(ln)
 		ir := tbe.Text[i]
 		if len(lr) < st.Ch {
 			lr = append(lr, runes.Repeat([]rune(" "), st.Ch-len(lr))...)
@@ -1293,12 +1744,15 @@ func (tb *TextBuf) InsertTextRectImpl(tbe *textbuf.Edit) *textbuf.Edit {
 		nt := append(lr, ir...)          // first append to end to extend capacity
 		copy(nt[st.Ch+nch:], nt[st.Ch:]) // move stuff to end
 		copy(nt[st.Ch:], ir)             // copy into position
-		tb.Lines[ln] = nt
+		tb.StoreLine(ln, nt)
 	}
 	re := tbe.Clone()
 	re.Delete = false
 	re.Reg.TimeNow()
 	tb.LinesEdited(re)
+	tb.ChangesUpdateImpl() // lines may have been added
+	tb.LSPSyncImpl(true)
+	tb.CollabSyncImpl()
 	return re
 }
 
@@ -1325,41 +1779,10 @@ func (tb *TextBuf) RegionImpl(st, ed lex.Pos) *textbuf.Edit {
 		return nil
 	}
 	tbe := &textbuf.Edit{Reg: textbuf.NewRegionPos(st, ed)}
-	if ed.Ln == st.Ln {
-		sz := ed.Ch - st.Ch
-		tbe.Text = make([][]rune, 1)
-		tbe.Text[0] = make([]rune, sz)
-		copy(tbe.Text[0][:sz], tb.Lines[st.Ln][st.Ch:ed.Ch])
-	} else {
-		// first get chars on start and end
-		if ed.Ln >= len(tb.Lines) {
-			ed.Ln = len(tb.Lines) - 1
-			ed.Ch = len(tb.Lines[ed.Ln])
-		}
-		nlns := (ed.Ln - st.Ln) + 1
-		tbe.Text = make([][]rune, nlns)
-		stln := st.Ln
-		if st.Ch > 0 {
-			ec := len(tb.Lines[st.Ln])
-			sz := ec - st.Ch
-			if sz > 0 {
-				tbe.Text[0] = make([]rune, sz)
-				copy(tbe.Text[0][0:sz], tb.Lines[st.Ln][st.Ch:])
-			}
-			stln++
-		}
-		edln := ed.Ln
-		if ed.Ch < len(tb.Lines[ed.Ln]) {
-			tbe.Text[ed.Ln-st.Ln] = make([]rune, ed.Ch)
-			copy(tbe.Text[ed.Ln-st.Ln], tb.Lines[ed.Ln][:ed.Ch])
-			edln--
-		}
-		for ln := stln; ln <= edln; ln++ {
-			ti := ln - st.Ln
-			sz := len(tb.Lines[ln])
-			tbe.Text[ti] = make([]rune, sz)
-			copy(tbe.Text[ti], tb.Lines[ln])
-		}
+	lns := bytes.Split(tb.Store.Slice(tb.ByteOff(st), tb.ByteOff(ed)), []byte("\n"))
+	tbe.Text = make([][]rune, len(lns))
+	for i, l := range lns {
+		tbe.Text[i] = bytes.Runes(l)
 	}
 	return tbe
 }
@@ -1398,7 +1821,7 @@ func (tb *TextBuf) RegionRectImpl(st, ed lex.Pos) *textbuf.Edit {
 	tbe.Text = make([][]rune, nlns)
 	for i := 0; i < nlns; i++ {
 		ln := st.Ln + i
-		lr := tb.Lines[ln]
+		lr := tb.LineImpl(ln)
 		ll := len(lr)
 		var txt []rune
 		if ll > st.Ch {
@@ -1454,18 +1877,13 @@ func (tb *TextBuf) SavePosHistory(pos lex.Pos) bool {
 // unlocks the Markup mutex.  Must be called under Lines mutex lock.
 func (tb *TextBuf) LinesEdited(tbe *textbuf.Edit) {
 	tb.MarkupMu.Lock()
-	st, ed := tbe.Reg.Start.Ln, tbe.Reg.End.Ln
-	for ln := st; ln <= ed; ln++ {
-		tb.LineBytes[ln] = []byte(string(tb.Lines[ln]))
-		tb.Markup[ln] = HTMLEscapeRunes(tb.Lines[ln])
-	}
-	tb.MarkupLines(st, ed)
+	tb.MarkupLines(tbe.Reg.Start.Ln, tbe.Reg.End.Ln)
 	tb.MarkupMu.Unlock()
 	tb.StartDelayedReMarkup()
 }
 
-// LinesInserted inserts new lines in Markup corresponding to lines
-// inserted in Lines text.  Locks and unlocks the Markup mutex, and
+// LinesInserted inserts new lines in the markup state corresponding to
+// lines inserted in the text.  Locks and unlocks the Markup mutex, and
 // must be called under lines mutex
 func (tb *TextBuf) LinesInserted(tbe *textbuf.Edit) {
 	stln := tbe.Reg.Start.Ln + 1
@@ -1473,63 +1891,20 @@ func (tb *TextBuf) LinesInserted(tbe *textbuf.Edit) {
 
 	tb.MarkupMu.Lock()
 	tb.MarkupEdits = append(tb.MarkupEdits, tbe)
-
-	// LineBytes
-	tmplb := make([][]byte, nsz)
-	nlb := append(tb.LineBytes, tmplb...)
-	copy(nlb[stln+nsz:], nlb[stln:])
-	copy(nlb[stln:], tmplb)
-	tb.LineBytes = nlb
-
-	// Markup
-	tmpmu := make([][]byte, nsz)
-	nmu := append(tb.Markup, tmpmu...) // first append to end to extend capacity
-	copy(nmu[stln+nsz:], nmu[stln:])   // move stuff to end
-	copy(nmu[stln:], tmpmu)            // copy into position
-	tb.Markup = nmu
-
-	// Tags
-	tmptg := make([]lex.Line, nsz)
-	ntg := append(tb.Tags, tmptg...)
-	copy(ntg[stln+nsz:], ntg[stln:])
-	copy(ntg[stln:], tmptg)
-	tb.Tags = ntg
-
-	// HiTags
-	tmpht := make([]lex.Line, nsz)
-	nht := append(tb.HiTags, tmpht...)
-	copy(nht[stln+nsz:], nht[stln:])
-	copy(nht[stln:], tmpht)
-	tb.HiTags = nht
-
-	// ByteOffs -- maintain mem updt
-	tmpof := make([]int, nsz)
-	nof := append(tb.ByteOffs, tmpof...)
-	copy(nof[stln+nsz:], nof[stln:])
-	copy(nof[stln:], tmpof)
-	tb.ByteOffs = nof
+	tb.lineState.inserted(stln, nsz)
 
 	if tb.Hi.UsingPi() {
 		pfs := tb.PiState.Done()
 		pfs.Src.LinesInserted(stln, nsz)
 	}
-
-	st, ed := tbe.Reg.Start.Ln, tbe.Reg.End.Ln
-	bo := tb.ByteOffs[st]
-	for ln := st; ln <= ed; ln++ {
-		tb.LineBytes[ln] = []byte(string(tb.Lines[ln]))
-		tb.Markup[ln] = HTMLEscapeRunes(tb.Lines[ln])
-		tb.ByteOffs[ln] = bo
-		bo += len(tb.LineBytes[ln]) + 1
-	}
-	tb.MarkupLines(st, ed)
+	tb.MarkupLines(tbe.Reg.Start.Ln, tbe.Reg.End.Ln)
 	tb.MarkupMu.Unlock()
 	tb.StartDelayedReMarkup()
 }
 
-// LinesDeleted deletes lines in Markup corresponding to lines
-// deleted in Lines text.  Locks and unlocks the Markup mutex, and
-// must be called under lines mutex.
+// LinesDeleted deletes lines in the markup state corresponding to lines
+// deleted in the text.  Locks and unlocks the Markup mutex, and must be
+// called under lines mutex.
 func (tb *TextBuf) LinesDeleted(tbe *textbuf.Edit) {
 	tb.MarkupMu.Lock()
 
@@ -1537,22 +1912,14 @@ func (tb *TextBuf) LinesDeleted(tbe *textbuf.Edit) {
 
 	stln := tbe.Reg.Start.Ln
 	edln := tbe.Reg.End.Ln
-
-	tb.LineBytes = append(tb.LineBytes[:stln], tb.LineBytes[edln:]...)
-	tb.Markup = append(tb.Markup[:stln], tb.Markup[edln:]...)
-	tb.Tags = append(tb.Tags[:stln], tb.Tags[edln:]...)
-	tb.HiTags = append(tb.HiTags[:stln], tb.HiTags[edln:]...)
-	tb.ByteOffs = append(tb.ByteOffs[:stln], tb.ByteOffs[edln:]...)
+	tb.lineState.deleted(stln, edln)
 
 	if tb.Hi.UsingPi() {
 		pfs := tb.PiState.Done()
 		pfs.Src.LinesDeleted(stln, edln)
 	}
 
-	st := tbe.Reg.Start.Ln
-	tb.LineBytes[st] = []byte(string(tb.Lines[st]))
-	tb.Markup[st] = HTMLEscapeRunes(tb.Lines[st])
-	tb.MarkupLines(st, st)
+	tb.MarkupLines(stln, stln)
 	tb.MarkupMu.Unlock()
 	tb.StartDelayedReMarkup()
 }
@@ -1564,22 +1931,76 @@ func (tb *TextBuf) LinesDeleted(tbe *textbuf.Edit) {
 func (tb *TextBuf) MarkupLine(ln int) {
 	tb.LinesMu.Lock()
 	tb.MarkupMu.Lock()
-
-	if ln >= 0 && ln < len(tb.Markup) {
-		tb.LineBytes[ln] = []byte(string(tb.Lines[ln]))
-		tb.Markup[ln] = HTMLEscapeRunes(tb.Lines[ln])
-		tb.MarkupLines(ln, ln)
-	}
+	tb.MarkupLines(ln, ln)
 	tb.MarkupMu.Unlock()
 	tb.LinesMu.Unlock()
 }
 
+// LineMarkup is the concurrent-safe accessor to the markup of given line,
+// which is generated from its current highlighting tags, unless markup was
+// given for it (see AppendTextMarkup) -- markup is thus only generated for
+// the lines that are viewed, and is not kept.
+func (tb *TextBuf) LineMarkup(ln int) []byte {
+	tb.LinesMu.RLock()
+	defer tb.LinesMu.RUnlock()
+	tb.MarkupMu.Lock()
+	defer tb.MarkupMu.Unlock()
+	if ln < 0 || ln >= tb.NLines {
+		return nil
+	}
+	txt := tb.LineImpl(ln)
+	bl := tb.lineState.get(ln)
+	if bl != nil && bl.markup != nil {
+		return bl.markup
+	}
+	if !tb.Hi.HasHi() {
+		return HTMLEscapeRunes(txt)
+	}
+	var tags lex.Line
+	if bl != nil {
+		tags = bl.tags
+	}
+	return tb.Hi.MarkupLine(txt, tb.lineHiImpl(ln, txt), tags)
+}
+
+// LineHiTags is the concurrent-safe accessor to the syntax highlighting
+// tags of given line
+func (tb *TextBuf) LineHiTags(ln int) lex.Line {
+	tb.LinesMu.RLock()
+	defer tb.LinesMu.RUnlock()
+	tb.MarkupMu.Lock()
+	defer tb.MarkupMu.Unlock()
+	if ln < 0 || ln >= tb.NLines {
+		return nil
+	}
+	return tb.lineHiImpl(ln, nil)
+}
+
+// lineHiImpl returns the syntax highlighting tags of given line, with
+// given text if non-nil -- lines of texts that are highlighted one line at
+// a time (see TextBufMaxMarkupBytes) are highlighted here, unless they were
+// edited.  Must be called under LinesMu and MarkupMu locks.
+func (tb *TextBuf) lineHiImpl(ln int, txt []rune) lex.Line {
+	if bl := tb.lineState.get(ln); bl != nil && bl.hi != nil {
+		return bl.hi
+	}
+	if !tb.Hi.LinesOnly || !tb.Hi.HasHi() {
+		return nil
+	}
+	if txt == nil {
+		txt = tb.LineImpl(ln)
+	}
+	hi, _ := tb.Hi.MarkupTagsLine(ln, txt)
+	return hi
+}
+
 // IsMarkingUp is true if the MarkupAllLines process is currently running
 func (tb *TextBuf) IsMarkingUp() bool {
 	return tb.HasFlag(int(TextBufMarkingUp))
 }
 
-// InitialMarkup does the first-pass markup on the file
+// InitialMarkup does the first-pass markup on the file -- lines of texts
+// larger than TextBufMaxMarkupBytes are highlighted as they are viewed.
 func (tb *TextBuf) InitialMarkup() {
 	if tb.Hi.UsingPi() {
 		fs := tb.PiState.Done() // initialize
@@ -1628,21 +2049,34 @@ func (tb *TextBuf) StopDelayedReMarkup() {
 	}
 }
 
-// ReMarkup runs re-markup on text in background
+// ReMarkup runs re-markup on text in background, unless it is larger than
+// TextBufMaxMarkupBytes
 func (tb *TextBuf) ReMarkup() {
-	if !tb.Hi.HasHi() || tb.NLines == 0 {
+	if !tb.Hi.HasHi() || tb.NumLines() == 0 {
 		return
 	}
-	if tb.IsMarkingUp() {
+	if tb.IsMarkingUp() || tb.TooLargeToMarkup() {
 		return
 	}
 	go tb.MarkupAllLines(-1)
 }
 
+// ErrMarkupTooLarge is returned by MarkupAllLines for texts larger than
+// TextBufMaxMarkupBytes, which it does not mark up
+var ErrMarkupTooLarge = errors.New("giv.TextBuf: text is too large to mark up all lines -- lines are highlighted as they are viewed")
+
+// TooLargeToMarkup returns true if the text is larger than
+// TextBufMaxMarkupBytes, so MarkupAllLines does not mark it up
+func (tb *TextBuf) TooLargeToMarkup() bool {
+	tb.LinesMu.RLock()
+	defer tb.LinesMu.RUnlock()
+	return tb.Store != nil && tb.Store.Len() > TextBufMaxMarkupBytes
+}
+
 // AdjustedTags updates tag positions for edits
 // must be called under MarkupMu lock
 func (tb *TextBuf) AdjustedTags(ln int) lex.Line {
-	return tb.AdjustedTagsImpl(tb.Tags[ln], ln)
+	return tb.AdjustedTagsImpl(tb.lineState.tags(ln), ln)
 }
 
 // AdjustedTagsImpl updates tag positions for edits, for given list of tags
@@ -1668,13 +2102,19 @@ func (tb *TextBuf) AdjustedTagsImpl(tags lex.Line, ln int) lex.Line {
 // MarkupAllLines does syntax highlighting markup for all lines in buffer,
 // calling MarkupMu mutex when setting the marked-up lines with the result --
 // designed to be called in a separate goroutine.
-// if maxLines > 0 then it specifies a maximum number of lines (for InitialMarkup)
-func (tb *TextBuf) MarkupAllLines(maxLines int) {
-	if !tb.Hi.HasHi() || tb.NLines == 0 {
-		return
+// if maxLines > 0 then it specifies a maximum number of lines (for InitialMarkup).
+// The markup itself is generated as lines are viewed, in LineMarkup.
+// Texts larger than TextBufMaxMarkupBytes are not marked up, returning
+// ErrMarkupTooLarge -- their lines are highlighted as they are viewed.
+func (tb *TextBuf) MarkupAllLines(maxLines int) error {
+	if !tb.Hi.HasHi() || tb.NumLines() == 0 {
+		return nil
+	}
+	if tb.TooLargeToMarkup() {
+		return ErrMarkupTooLarge
 	}
 	if tb.IsMarkingUp() {
-		return
+		return nil
 	}
 	tb.SetFlag(int(TextBufMarkingUp))
 
@@ -1685,9 +2125,8 @@ func (tb *TextBuf) MarkupAllLines(maxLines int) {
 	var txt []byte
 	if maxLines > 0 {
 		tb.LinesMu.RLock()
-		mln := ints.MinInt(maxLines, len(tb.LineBytes))
-		txt = bytes.Join(tb.LineBytes[:mln], []byte("\n"))
-		txt = append(txt, '\n')
+		txt = tb.Store.Slice(0, tb.Store.LineStart(maxLines))
+		txt = append([]byte(nil), txt...)
 		tb.LinesMu.RUnlock()
 	} else {
 		txt = tb.LinesToBytesCopy()
@@ -1695,14 +2134,14 @@ func (tb *TextBuf) MarkupAllLines(maxLines int) {
 	mtags, err := tb.Hi.MarkupTagsAll(txt) // does full parse, outside of markup lock
 	if err != nil {
 		tb.ClearFlag(int(TextBufMarkingUp))
-		return
+		return err
 	}
 
 	// by this point mtags could be out of sync with deletes that have happened
 	tb.LinesMu.Lock()
 	tb.MarkupMu.Lock()
 
-	maxln := ints.MinInt(len(tb.Markup), tb.NLines)
+	maxln := tb.NLines
 	if maxLines > 0 {
 		maxln = ints.MinInt(maxln, maxLines)
 	}
@@ -1722,12 +2161,9 @@ func (tb *TextBuf) MarkupAllLines(maxLines int) {
 			}
 		}
 		tb.MarkupEdits = nil
-		// if maxln > 1 && len(pfs.Src.Lexs)-1 != maxln {
-		// 	fmt.Printf("error: markup out of sync: %v != %v len(Lexs)\n", maxln, len(pfs.Src.Lexs)-1)
-		// }
-		for ln := 0; ln < maxln; ln++ {
-			tb.HiTags[ln] = pfs.LexLine(ln) // does clone, combines comments too
-		}
+		tb.lineState.setAllHi(maxln, func(ln int) lex.Line {
+			return pfs.LexLine(ln) // does clone, combines comments too
+		})
 	} else {
 		// first update mtags with any changes since it was generated
 		for _, tbe := range tb.MarkupEdits {
@@ -1746,60 +2182,57 @@ func (tb *TextBuf) MarkupAllLines(maxLines int) {
 			}
 		}
 		tb.MarkupEdits = nil
-		// if maxln > 0 && len(mtags) != maxln {
-		// 	fmt.Printf("error: markup out of sync: %v != %v len(mtags)\n", maxln, len(mtags))
-		// }
-		for ln := 0; ln < maxln; ln++ {
-			tb.HiTags[ln] = mtags[ln] // chroma tags are freshly allocated
-		}
+		tb.lineState.setAllHi(maxln, func(ln int) lex.Line {
+			if ln < len(mtags) {
+				return mtags[ln] // chroma tags are freshly allocated
+			}
+			return nil
+		})
 	}
-	for ln := 0; ln < maxln; ln++ {
-		tb.Tags[ln] = tb.AdjustedTags(ln)
-		tb.Markup[ln] = tb.Hi.MarkupLine(tb.Lines[ln], tb.HiTags[ln], tb.Tags[ln])
+	for i := range tb.lineState {
+		bl := &tb.lineState[i]
+		if bl.ln < maxln && len(bl.tags) > 0 {
+			bl.tags = tb.AdjustedTagsImpl(bl.tags, bl.ln)
+		}
 	}
 	tb.MarkupMu.Unlock()
 	tb.LinesMu.Unlock()
 	tb.ClearFlag(int(TextBufMarkingUp))
 	tb.TextBufSig.Emit(tb.This(), int64(TextBufMarkUpdt), tb.Txt)
+	return nil
 }
 
-// MarkupFromTags does syntax highlighting markup using existing HiTags without
-// running new tagging -- for special case where tagging is under external
-// control
+// MarkupFromTags signals that the markup should be updated from the
+// existing highlighting tags without running new tagging -- for special
+// case where tagging is under external control
 func (tb *TextBuf) MarkupFromTags() {
-	tb.MarkupMu.Lock()
-	// getting the lock means we are in control of the flag
-	tb.SetFlag(int(TextBufMarkingUp))
-
-	maxln := ints.MinInt(len(tb.HiTags), tb.NLines)
-	for ln := 0; ln < maxln; ln++ {
-		tb.Markup[ln] = tb.Hi.MarkupLine(tb.Lines[ln], tb.HiTags[ln], nil)
-	}
-	tb.MarkupMu.Unlock()
-	tb.ClearFlag(int(TextBufMarkingUp))
 	tb.TextBufSig.Emit(tb.This(), int64(TextBufMarkUpdt), tb.Txt)
 }
 
-// MarkupLines generates markup of given range of lines. end is *inclusive*
-// line.  returns true if all lines were marked up successfully.  This does
-// NOT lock the MarkupMu mutex (done at outer loop)
+// MarkupLines updates the highlighting tags of given range of lines, and
+// clears any markup given for them.  end is *inclusive* line.  returns
+// true if all lines were marked up successfully.  This does NOT lock the
+// MarkupMu mutex (done at outer loop)
 func (tb *TextBuf) MarkupLines(st, ed int) bool {
-	if !tb.Hi.HasHi() || tb.NLines == 0 {
-		return false
-	}
 	if ed >= tb.NLines {
 		ed = tb.NLines - 1
 	}
+	for ln := st; ln <= ed; ln++ {
+		tb.lineState.setMarkup(ln, nil)
+	}
+	if !tb.Hi.HasHi() || tb.NLines == 0 {
+		return false
+	}
 
 	allgood := true
 	for ln := st; ln <= ed; ln++ {
-		ltxt := tb.Lines[ln]
-		mt, err := tb.Hi.MarkupTagsLine(ln, ltxt)
+		mt, err := tb.Hi.MarkupTagsLine(ln, tb.LineImpl(ln))
 		if err == nil {
-			tb.HiTags[ln] = mt
-			tb.Markup[ln] = tb.Hi.MarkupLine(ltxt, mt, tb.AdjustedTags(ln))
+			tb.lineState.set(ln, func(bl *textBufLine) {
+				bl.hi = mt
+				bl.tags = tb.AdjustedTagsImpl(bl.tags, ln)
+			})
 		} else {
-			tb.Markup[ln] = HTMLEscapeRunes(ltxt)
 			allgood = false
 		}
 	}
@@ -1973,12 +2406,14 @@ func (tb *TextBuf) AddTag(ln, st, ed int, tag token.Tokens) {
 	tb.MarkupMu.Lock()
 	tr := lex.NewLex(token.KeyToken{Tok: tag}, st, ed)
 	tr.Time.Now()
-	if len(tb.Tags[ln]) == 0 {
-		tb.Tags[ln] = append(tb.Tags[ln], tr)
-	} else {
-		tb.Tags[ln] = tb.AdjustedTags(ln) // must re-adjust before adding new ones!
-		tb.Tags[ln].AddSort(tr)
-	}
+	tb.lineState.set(ln, func(bl *textBufLine) {
+		if len(bl.tags) == 0 {
+			bl.tags = append(bl.tags, tr)
+		} else {
+			bl.tags = tb.AdjustedTagsImpl(bl.tags, ln) // must re-adjust before adding new ones!
+			bl.tags.AddSort(tr)
+		}
+	})
 	tb.MarkupMu.Unlock()
 	tb.MarkupLinesLock(ln, ln)
 }
@@ -1995,8 +2430,9 @@ func (tb *TextBuf) TagAt(pos lex.Pos) (reg lex.Lex, ok bool) {
 	if !tb.IsValidLine(pos.Ln) {
 		return
 	}
-	tb.Tags[pos.Ln] = tb.AdjustedTags(pos.Ln) // re-adjust for current info
-	for _, t := range tb.Tags[pos.Ln] {
+	tags := tb.AdjustedTags(pos.Ln) // re-adjust for current info
+	tb.lineState.setTags(pos.Ln, tags)
+	for _, t := range tags {
 		if t.St >= pos.Ch && t.Ed < pos.Ch {
 			return t, true
 		}
@@ -2011,18 +2447,19 @@ func (tb *TextBuf) RemoveTag(pos lex.Pos, tag token.Tokens) (reg lex.Lex, ok boo
 		return
 	}
 	tb.MarkupMu.Lock()
-	tb.Tags[pos.Ln] = tb.AdjustedTags(pos.Ln) // re-adjust for current info
-	for i, t := range tb.Tags[pos.Ln] {
+	tags := tb.AdjustedTags(pos.Ln) // re-adjust for current info
+	for i, t := range tags {
 		if t.ContainsPos(pos.Ch) {
 			if tag > 0 && t.Tok.Tok != tag {
 				continue
 			}
-			tb.Tags[pos.Ln].DeleteIdx(i)
+			tags.DeleteIdx(i)
 			reg = t
 			ok = true
 			break
 		}
 	}
+	tb.lineState.setTags(pos.Ln, tags)
 	tb.MarkupMu.Unlock()
 	if ok {
 		tb.MarkupLinesLock(pos.Ln, pos.Ln)
@@ -2033,22 +2470,21 @@ func (tb *TextBuf) RemoveTag(pos lex.Pos, tag token.Tokens) (reg lex.Lex, ok boo
 // HiTagAtPos returns the highlighting (markup) lexical tag at given position
 // using current Markup tags, and index, -- could be nil if none or out of range
 func (tb *TextBuf) HiTagAtPos(pos lex.Pos) (*lex.Lex, int) {
-	tb.MarkupMu.Lock()
-	defer tb.MarkupMu.Unlock()
 	if !tb.IsValidLine(pos.Ln) {
 		return nil, -1
 	}
-	return tb.HiTags[pos.Ln].AtPos(pos.Ch)
+	hi := tb.LineHiTags(pos.Ln)
+	return hi.AtPos(pos.Ch)
 }
 
 // LexString returns the string associated with given Lex (Tag) at given line
 func (tb *TextBuf) LexString(ln int, lx *lex.Lex) string {
 	tb.LinesMu.RLock()
 	defer tb.LinesMu.RUnlock()
-	if !tb.IsValidLine(ln) {
+	if ln < 0 || ln >= tb.NLines {
 		return ""
 	}
-	rns := tb.Lines[ln][lx.St:lx.Ed]
+	rns := tb.LineImpl(ln)[lx.St:lx.Ed]
 	return string(rns)
 }
 
@@ -2057,13 +2493,11 @@ func (tb *TextBuf) LexString(ln int, lx *lex.Lex) string {
 // which are used for object paths -- used for e.g., debugger to pull out
 // variable expressions that can be evaluated.
 func (tb *TextBuf) LexObjPathString(ln int, lx *lex.Lex) string {
-	tb.LinesMu.RLock()
-	defer tb.LinesMu.RUnlock()
 	if !tb.IsValidLine(ln) {
 		return ""
 	}
-	stlx := lex.ObjPathAt(tb.HiTags[ln], lx)
-	rns := tb.Lines[ln][stlx.St:lx.Ed]
+	stlx := lex.ObjPathAt(tb.LineHiTags(ln), lx)
+	rns := tb.Line(ln)[stlx.St:lx.Ed]
 	return string(rns)
 }
 
@@ -2184,9 +2618,7 @@ func (tb *TextBuf) IndentLine(ln, ind int) *textbuf.Edit {
 		ichr = indent.Space
 	}
 
-	tb.LinesMu.RLock()
-	curind, _ := lex.LineIndent(tb.Lines[ln], tabSz)
-	tb.LinesMu.RUnlock()
+	curind, _ := lex.LineIndent(tb.Line(ln), tabSz)
 	if ind > curind {
 		return tb.InsertText(lex.Pos{Ln: ln}, indent.Bytes(ichr, ind-curind, tabSz), EditSignal)
 	} else if ind < curind {
@@ -2209,10 +2641,13 @@ func (tb *TextBuf) AutoIndent(ln int) (tbe *textbuf.Edit, indLev, chPos int) {
 	tb.MarkupMu.RLock()
 	lp, _ := pi.LangSupport.Props(tb.PiState.Sup)
 	var pInd, delInd int
+	// only the lines within TextBufMaxScopeLines before are looked at
+	wst := ints.MaxInt(ln-TextBufMaxScopeLines, 0)
+	src, tags := tb.linesWindow(wst, ln+1)
 	if lp != nil && lp.Lang != nil {
-		pInd, delInd, _, _ = lp.Lang.IndentLine(&tb.PiState, tb.Lines, tb.HiTags, ln, tabSz)
+		pInd, delInd, _, _ = lp.Lang.IndentLine(&tb.PiState, src, tags, ln-wst, tabSz)
 	} else {
-		pInd, delInd, _, _ = lex.BracketIndentLine(tb.Lines, tb.HiTags, ln, tabSz)
+		pInd, delInd, _, _ = lex.BracketIndentLine(src, tags, ln-wst, tabSz)
 	}
 	tb.MarkupMu.RUnlock()
 	tb.LinesMu.RUnlock()
@@ -2245,8 +2680,6 @@ func (tb *TextBuf) CommentStart(ln int) int {
 	if comst == "" {
 		return -1
 	}
-	tb.LinesMu.RLock()
-	defer tb.LinesMu.RUnlock()
 	return runes.Index(tb.Line(ln), []rune(comst))
 }
 
@@ -2264,9 +2697,7 @@ func (tb *TextBuf) InComment(pos lex.Pos) bool {
 
 // LineCommented returns true if the given line is a full-comment line (i.e., starts with a comment)
 func (tb *TextBuf) LineCommented(ln int) bool {
-	tb.MarkupMu.RLock()
-	defer tb.MarkupMu.RUnlock()
-	tags := tb.HiTags[ln]
+	tags := tb.LineHiTags(ln)
 	if len(tags) == 0 {
 		return false
 	}
@@ -2284,9 +2715,7 @@ func (tb *TextBuf) CommentRegion(st, ed int) {
 	tabSz := tb.Opts.TabSize
 
 	ch := 0
-	tb.LinesMu.RLock()
-	ind, _ := lex.LineIndent(tb.Lines[st], tabSz)
-	tb.LinesMu.RUnlock()
+	ind, _ := lex.LineIndent(tb.Line(st), tabSz)
 
 	if ind > 0 {
 		if tb.Opts.SpaceIndent {
@@ -2320,7 +2749,7 @@ func (tb *TextBuf) CommentRegion(st, ed int) {
 		if doCom {
 			tb.InsertText(lex.Pos{Ln: ln, Ch: ch}, []byte(comst), EditSignal)
 			if comed != "" {
-				lln := len(tb.Lines[ln])
+				lln := tb.LineLen(ln)
 				tb.InsertText(lex.Pos{Ln: ln, Ch: lln}, []byte(comed), EditSignal)
 			}
 		} else {
@@ -2347,7 +2776,7 @@ func (tb *TextBuf) JoinParaLines(stLn, edLn int) {
 
 	curEd := edLn                      // current end of region being joined == last blank line
 	for ln := edLn; ln >= stLn; ln-- { // reverse order
-		lb := tb.LineBytes[ln]
+		lb := tb.BytesLine(ln)
 		lbt := bytes.TrimSpace(lb)
 		if len(lbt) == 0 || ln == stLn {
 			if ln < curEd-1 {
@@ -2359,9 +2788,12 @@ func (tb *TextBuf) JoinParaLines(stLn, edLn int) {
 				if curEd == edLn {
 					ep.Ln = curEd
 				}
-				eln := tb.Lines[ep.Ln]
-				ep.Ch = len(eln)
-				tlb := bytes.Join(tb.LineBytes[stp.Ln:ep.Ln+1], []byte(" "))
+				ep.Ch = tb.LineLen(ep.Ln)
+				lns := make([][]byte, 0, ep.Ln+1-stp.Ln)
+				for pl := stp.Ln; pl <= ep.Ln; pl++ {
+					lns = append(lns, tb.BytesLine(pl))
+				}
+				tlb := bytes.Join(lns, []byte(" "))
 				tb.ReplaceText(stp, ep, stp, string(tlb), EditSignal, ReplaceNoMatchCase)
 			}
 			curEd = ln
@@ -2427,6 +2859,14 @@ func (tb *TextBuf) CompleteText(s string) {
 	}
 	c := tb.Complete.GetCompletion(s)
 	pos := lex.Pos{tb.Complete.SrcLn, tb.Complete.SrcCh}
+	if body, has := c.Extra[SnippetCompleteKey]; has && tb.CurView != nil {
+		st = pos
+		st.Ch -= len(tb.Complete.Seed)
+		tv := tb.CurView
+		tb.CurView = nil
+		tv.InsertSnippet(body, st, pos)
+		return
+	}
 	ed := tb.Complete.EditFunc(tb.Complete.Context, tbes, tb.Complete.SrcCh, c, tb.Complete.Seed)
 	if ed.ForwardDelete > 0 {
 		delEn := lex.Pos{tb.Complete.SrcLn, tb.Complete.SrcCh + ed.ForwardDelete}
@@ -2536,11 +2976,7 @@ func (tb *TextBuf) SpellCheckLineErrs(ln int) lex.Line {
 	if !tb.IsValidLine(ln) {
 		return nil
 	}
-	tb.LinesMu.RLock()
-	defer tb.LinesMu.RUnlock()
-	tb.MarkupMu.RLock()
-	defer tb.MarkupMu.RUnlock()
-	return spell.CheckLexLine(tb.Lines[ln], tb.HiTags[ln])
+	return spell.CheckLexLine(tb.Line(ln), tb.LineHiTags(ln))
 }
 
 // SpellCheckLineTag runs spell check on given line, and sets Tags for any
@@ -2556,12 +2992,273 @@ func (tb *TextBuf) SpellCheckLineTag(ln int) {
 	for _, t := range ser {
 		ntgs.AddSort(t)
 	}
-	tb.Tags[ln] = ntgs
+	tb.lineState.setTags(ln, ntgs)
 	tb.MarkupMu.Unlock()
 	tb.MarkupLinesLock(ln, ln)
 	tb.StartDelayedReMarkup()
 }
 
+///////////////////////////////////////////////////////////////////////////////
+//    Language Server
+
+// TextBufLSP causes Open to start a language server for the file, if one
+// is configured for its language in lsp.ServerConfigs -- see StartLSP
+var TextBufLSP = false
+
+// TextBufLSPLangs are the LSP language identifiers for supported file
+// types, used to select the server in lsp.ServerConfigs
+var TextBufLSPLangs = map[filecat.Supported]string{
+	filecat.Go:         "go",
+	filecat.Python:     "python",
+	filecat.C:          "c",
+	filecat.Rust:       "rust",
+	filecat.JavaScript: "javascript",
+	filecat.Java:       "java",
+	filecat.Bash:       "shellscript",
+	filecat.TeX:        "latex",
+	filecat.Html:       "html",
+	filecat.Css:        "css",
+	filecat.Json:       "json",
+	filecat.Yaml:       "yaml",
+}
+
+// LSPLang returns the LSP language identifier for the file, or "" if
+// there is none -- as for files that are not in the OS
+func (tb *TextBuf) LSPLang() string {
+	if !vfs.IsOS(tb.FS) {
+		return ""
+	}
+	lang := TextBufLSPLangs[tb.Info.Sup]
+	if lang == "c" {
+		switch strings.ToLower(filepath.Ext(string(tb.Filename))) {
+		case ".cpp", ".cc", ".cxx", ".hpp", ".hh", ".hxx":
+			lang = "cpp"
+		}
+	}
+	return lang
+}
+
+// LSPURI returns the URI that identifies the file to the language server
+func (tb *TextBuf) LSPURI() string {
+	return lsp.FileURI(string(tb.Filename))
+}
+
+// StartLSP starts the language server for the language of the file, in the
+// workspace root directory of the file (see lsp.FindRoot), or connects to
+// it if it is already running, and uses it for this buffer
+func (tb *TextBuf) StartLSP() error {
+	lang := tb.LSPLang()
+	if lang == "" || tb.Filename == "" {
+		return fmt.Errorf("giv.TextBuf: no language server for file: %v", tb.Filename)
+	}
+	cl, err := lsp.TheServers.Client(lsp.FindRoot(string(tb.Filename)), lang)
+	if err != nil {
+		return err
+	}
+	return tb.SetLSP(cl, lang)
+}
+
+// SetLSP sets the language server client to use for this buffer, opening
+// the file in the server with given language identifier.  Completion and
+// lookup then use the server, and its diagnostics are shown in the views.
+func (tb *TextBuf) SetLSP(cl *lsp.Client, langID string) error {
+	tb.DeleteLSP()
+	uri := tb.LSPURI()
+	tb.LinesMu.Lock()
+	tb.LSP = cl
+	tb.LSPVersion = 1
+	err := cl.DidOpen(uri, langID, tb.LSPVersion, tb.Store.Bytes())
+	tb.LinesMu.Unlock()
+	cl.OnDiagnostics(uri, func(diags []lsp.Diagnostic) {
+		gi.PostUIFunc(nil, func() { // views update on the event goroutine
+			if tb.LSP != cl {
+				return
+			}
+			tb.DiagMu.Lock()
+			tb.Diags = diags
+			tb.DiagMu.Unlock()
+			tb.TextBufSig.Emit(tb.This(), int64(TextBufMarkUpdt), nil)
+		})
+	})
+	if lsp.Has(cl.Caps.CompletionProvider) {
+		tb.SetCompleter(tb, CompleteLSP, CompleteEditLSP, LookupLSP)
+	}
+	return err
+}
+
+// DeleteLSP stops using the language server for this buffer, closing the
+// file in the server -- the server keeps running for other files
+func (tb *TextBuf) DeleteLSP() {
+	if tb.LSP == nil {
+		return
+	}
+	uri := tb.LSPURI()
+	tb.LSP.OnDiagnostics(uri, nil)
+	tb.LSP.DidClose(uri)
+	tb.LinesMu.Lock()
+	tb.LSP = nil
+	tb.LinesMu.Unlock()
+	tb.DiagMu.Lock()
+	tb.Diags = nil
+	tb.DiagMu.Unlock()
+	if tb.Complete != nil && tb.Complete.Context == tb {
+		tb.DeleteCompleter()
+		if tb.Info.Sup != filecat.NoSupport {
+			tb.SetCompleter(&tb.PiState, CompletePi, CompleteEditPi, LookupPi)
+		}
+	}
+}
+
+// LSPPos returns the language server position for given position.
+// Must be called under LinesMu lock.
+func (tb *TextBuf) LSPPos(pos lex.Pos) lsp.Position {
+	lp := lsp.Position{Line: pos.Ln}
+	if pos.Ln >= 0 && pos.Ln < tb.NLines {
+		l := tb.LineImpl(pos.Ln)
+		lp.Character = lsp.UTF16Len(l[:ints.MinInt(ints.MaxInt(pos.Ch, 0), len(l))])
+	}
+	return lp
+}
+
+// PosFromLSP returns the position for given language server position,
+// limited to a valid position.  Must be called under LinesMu lock.
+func (tb *TextBuf) PosFromLSP(lp lsp.Position) lex.Pos {
+	if tb.NLines == 0 || lp.Line < 0 {
+		return lex.PosZero
+	}
+	if lp.Line >= tb.NLines {
+		ln := tb.NLines - 1
+		return lex.Pos{Ln: ln, Ch: tb.LineLenImpl(ln)}
+	}
+	return lex.Pos{Ln: lp.Line, Ch: lsp.RuneIdx(tb.LineImpl(lp.Line), lp.Character)}
+}
+
+// RegionFromLSP returns the region for given language server range.
+// Must be called under LinesMu lock.
+func (tb *TextBuf) RegionFromLSP(rng lsp.Range) textbuf.Region {
+	return textbuf.NewRegionPos(tb.PosFromLSP(rng.Start), tb.PosFromLSP(rng.End))
+}
+
+// LSPChangeImpl sends the change replacing the text between given positions
+// with given text to the language server, if it accepts incremental changes
+// -- called before the change is made.  Must be called under LinesMu lock.
+func (tb *TextBuf) LSPChangeImpl(st, ed lex.Pos, text []byte) {
+	if tb.LSP == nil || !tb.LSP.IncrementalSync() {
+		return
+	}
+	tb.LSPVersion++
+	rng := lsp.Range{Start: tb.LSPPos(st), End: tb.LSPPos(ed)}
+	tb.LSP.DidChange(tb.LSPURI(), tb.LSPVersion, lsp.TextDocumentContentChangeEvent{Range: &rng, Text: string(text)})
+}
+
+// LSPSyncImpl sends the full text to the language server if all is true or
+// if the server does not accept incremental changes -- called after
+// changes are made.  Must be called under LinesMu lock.
+func (tb *TextBuf) LSPSyncImpl(all bool) {
+	if tb.LSP == nil || (!all && tb.LSP.IncrementalSync()) {
+		return
+	}
+	tb.LSPVersion++
+	tb.LSP.DidChange(tb.LSPURI(), tb.LSPVersion, lsp.TextDocumentContentChangeEvent{Text: string(tb.Store.Bytes())})
+}
+
+// LSPSync sends the full text to the language server, after the entire
+// text has been set
+func (tb *TextBuf) LSPSync() {
+	if tb.LSP == nil {
+		return
+	}
+	tb.LinesMu.Lock()
+	tb.LSPSyncImpl(true)
+	tb.LinesMu.Unlock()
+}
+
+// DiagnosticsAt returns the language server diagnostics whose region
+// includes given position
+func (tb *TextBuf) DiagnosticsAt(pos lex.Pos) []lsp.Diagnostic {
+	tb.DiagMu.Lock()
+	diags := tb.Diags
+	tb.DiagMu.Unlock()
+	var ds []lsp.Diagnostic
+	tb.LinesMu.RLock()
+	defer tb.LinesMu.RUnlock()
+	for _, d := range diags {
+		reg := tb.RegionFromLSP(d.Range)
+		if !pos.IsLess(reg.Start) && !reg.End.IsLess(pos) {
+			ds = append(ds, d)
+		}
+	}
+	return ds
+}
+
+// LSPApplyEdits applies given language server edits to the text, which
+// all refer to the text before any of them are applied
+func (tb *TextBuf) LSPApplyEdits(edits []lsp.TextEdit) {
+	regs := make([]textbuf.Region, len(edits))
+	tb.LinesMu.RLock()
+	for i, ed := range edits {
+		regs[i] = tb.RegionFromLSP(ed.Range)
+	}
+	tb.LinesMu.RUnlock()
+	idxs := make([]int, len(edits))
+	for i := range idxs {
+		idxs[i] = i
+	}
+	sort.SliceStable(idxs, func(i, j int) bool { // last first, so others stay valid
+		return regs[idxs[j]].Start.IsLess(regs[idxs[i]].Start)
+	})
+	for _, i := range idxs {
+		tb.ReplaceText(regs[i].Start, regs[i].End, regs[i].Start, edits[i].NewText, true, false)
+	}
+}
+
+// LSPRename renames the symbol at given position to given new name, using
+// the language server.  The edits to this file are made in the buffer, and
+// those to other files in the workspace are saved directly to the files,
+// in the file system of this file, keeping their encoding and line endings.
+func (tb *TextBuf) LSPRename(pos lex.Pos, newName string) error {
+	if tb.LSP == nil {
+		return fmt.Errorf("giv.TextBuf: no language server for file: %v", tb.Filename)
+	}
+	tb.LinesMu.RLock()
+	lp := tb.LSPPos(pos)
+	tb.LinesMu.RUnlock()
+	uri := tb.LSPURI()
+	we, err := tb.LSP.Rename(uri, lp, newName)
+	if err != nil {
+		return err
+	}
+	for fu, eds := range we.Edits() {
+		if fu == uri {
+			tb.LSPApplyEdits(eds)
+			continue
+		}
+		if err := tb.lspRenameFile(lsp.URIFile(fu), eds); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lspRenameFile applies given rename edits to given other file
+func (tb *TextBuf) lspRenameFile(fn string, eds []lsp.TextEdit) error {
+	fsys := tb.FileSys()
+	fi, err := fsys.Stat(fn)
+	if err != nil {
+		return err
+	}
+	b, err := vfs.ReadFile(fsys, fn)
+	if err != nil {
+		return err
+	}
+	txt, enc, le := textbuf.DecodeText(b)
+	b, nbad := textbuf.EncodeText(lsp.ApplyEdits(txt, eds), enc, le)
+	if nbad > 0 {
+		return fmt.Errorf("giv.TextBuf: renamed text in file: %v cannot be represented in its encoding: %v", fn, enc)
+	}
+	return vfs.WriteFile(fsys, fn, b, fi.Mode())
+}
+
 ///////////////////////////////////////////////////////////////////
 //  Diff
 
@@ -2598,24 +3295,33 @@ func (tb *TextBuf) PatchFromBuf(ob *TextBuf, diffs textbuf.Diffs, signal bool) b
 	mods := false
 	for i := sz - 1; i >= 0; i-- { // go in reverse so changes are valid!
 		df := diffs[i]
-		switch df.Tag {
-		case 'r':
-			tb.DeleteText(lex.Pos{Ln: df.I1}, lex.Pos{Ln: df.I2}, signal)
-			// fmt.Printf("patch rep del: %v %v\n", tbe.Reg, string(tbe.ToBytes()))
-			ot := ob.Region(lex.Pos{Ln: df.J1}, lex.Pos{Ln: df.J2})
-			tb.InsertText(lex.Pos{Ln: df.I1}, ot.ToBytes(), signal)
-			// fmt.Printf("patch rep ins: %v %v\n", tbe.Reg, string(tbe.ToBytes()))
-			mods = true
-		case 'd':
-			tb.DeleteText(lex.Pos{Ln: df.I1}, lex.Pos{Ln: df.I2}, signal)
+		if df.Tag == 'e' {
+			continue
+		}
+		st, ed := lex.Pos{Ln: df.I1}, lex.Pos{Ln: df.I2}
+		ost, oed := lex.Pos{Ln: df.J1}, lex.Pos{Ln: df.J2}
+		if df.I2 >= tb.NLines { // the last line has no newline -- change from end of prior line
+			ed = tb.EndPos()
+			oed = ob.EndPos()
+			if df.I1 >= tb.NLines || df.J1 >= ob.NLines {
+				if df.I1 > 0 {
+					st = lex.Pos{Ln: df.I1 - 1, Ch: tb.LineLen(df.I1 - 1)}
+				}
+				if df.J1 > 0 {
+					ost = lex.Pos{Ln: df.J1 - 1, Ch: ob.LineLen(df.J1 - 1)}
+				}
+			}
+		}
+		if df.Tag == 'r' || df.Tag == 'd' {
+			tb.DeleteText(st, ed, signal)
 			// fmt.Printf("patch del: %v %v\n", tbe.Reg, string(tbe.ToBytes()))
-			mods = true
-		case 'i':
-			ot := ob.Region(lex.Pos{Ln: df.J1}, lex.Pos{Ln: df.J2})
-			tb.InsertText(lex.Pos{Ln: df.I1}, ot.ToBytes(), signal)
+		}
+		if df.Tag == 'r' || df.Tag == 'i' {
+			ot := ob.Region(ost, oed)
+			tb.InsertText(st, ot.ToBytes(), signal)
 			// fmt.Printf("patch ins: %v %v\n", tbe.Reg, string(tbe.ToBytes()))
-			mods = true
 		}
+		mods = true
 	}
 	return mods
 }
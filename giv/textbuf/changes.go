@@ -0,0 +1,192 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package textbuf
+
+import (
+	"sort"
+
+	"github.com/goki/ki/ints"
+	"github.com/goki/ki/kit"
+)
+
+// LineChanges are the ways that a line of text can differ from a base
+// version of the text, e.g., the version last committed to version control
+type LineChanges int32
+
+const (
+	// LineUnchanged is a line that is the same as in the base
+	LineUnchanged LineChanges = iota
+
+	// LineAdded is a line that was added relative to the base
+	LineAdded
+
+	// LineModified is a line that replaced one or more base lines
+	LineModified
+
+	// LineDeleted is a line just after one or more base lines that were deleted
+	LineDeleted
+
+	// LineChangesN is the number of line changes
+	LineChangesN
+)
+
+//go:generate stringer -type=LineChanges
+
+var KiT_LineChanges = kit.Enums.AddEnum(LineChangesN, kit.NotBitFlag, nil)
+
+func (ev LineChanges) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *LineChanges) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+// ChangeHunk is a contiguous range of lines that differ from the base:
+// the base lines from BaseSt to BaseEd were replaced by the lines from St
+// to Ed (exclusive), where either range is empty for added or deleted lines
+type ChangeHunk struct {
+	Change LineChanges `desc:"how the lines were changed"`
+	BaseSt int         `desc:"starting line in the base"`
+	BaseEd int         `desc:"ending line in the base (exclusive)"`
+	St     int         `desc:"starting line in the current text"`
+	Ed     int         `desc:"ending line in the current text (exclusive) -- same as St for deleted lines"`
+}
+
+// Changes records how the current lines of a text differ from a base
+// version, as a sorted list of hunks, which can be updated incrementally
+// for each edit of the text
+type Changes struct {
+	Base  []string     `desc:"lines of the base version of the text"`
+	Hunks []ChangeHunk `desc:"the changed lines, in order"`
+}
+
+// NewChanges returns the changes of given current lines relative to the
+// base lines
+func NewChanges(base []string, cur ByteLines) *Changes {
+	ch := &Changes{Base: base}
+	ch.Update(cur)
+	return ch
+}
+
+// Update re-computes all of the changes for given current lines
+func (ch *Changes) Update(cur ByteLines) {
+	ch.Hunks = ch.diffHunks(0, len(ch.Base), 0, cur.NumLines(), cur)
+}
+
+// diffHunks returns the hunks for the differences between the base lines
+// from bst to bed and the current lines from st to ed
+func (ch *Changes) diffHunks(bst, bed, st, ed int, cur ByteLines) []ChangeHunk {
+	// skip lines that are the same at the start and end -- for typical
+	// edits, that leaves only a few lines to diff
+	for bst < bed && st < ed && ch.Base[bst] == string(cur.Line(st)) {
+		bst++
+		st++
+	}
+	for bed > bst && ed > st && ch.Base[bed-1] == string(cur.Line(ed-1)) {
+		bed--
+		ed--
+	}
+	switch {
+	case bst == bed && st == ed:
+		return nil
+	case bst == bed:
+		return []ChangeHunk{{Change: LineAdded, BaseSt: bst, BaseEd: bed, St: st, Ed: ed}}
+	case st == ed:
+		return []ChangeHunk{{Change: LineDeleted, BaseSt: bst, BaseEd: bed, St: st, Ed: ed}}
+	}
+	cs := make([]string, ed-st)
+	for i := range cs {
+		cs[i] = string(cur.Line(st + i))
+	}
+	var hks []ChangeHunk
+	for _, df := range DiffLines(ch.Base[bst:bed], cs) {
+		hk := ChangeHunk{BaseSt: bst + df.I1, BaseEd: bst + df.I2, St: st + df.J1, Ed: st + df.J2}
+		switch df.Tag {
+		case 'r':
+			hk.Change = LineModified
+		case 'i':
+			hk.Change = LineAdded
+		case 'd':
+			hk.Change = LineDeleted
+		default:
+			continue
+		}
+		hks = append(hks, hk)
+	}
+	return hks
+}
+
+// Edit updates the changes for an edit of the text that replaced the lines
+// from st to ed (exclusive, in the text before the edit) with nln lines,
+// given all of the lines after the edit.  Only the edited lines, extended
+// to include any hunks that they touch, are compared with the base again.
+func (ch *Changes) Edit(cur ByteLines, st, ed, nln int) {
+	dln := nln - (ed - st)
+	i0 := sort.Search(len(ch.Hunks), func(i int) bool { return ch.Hunks[i].Ed >= st })
+	i1 := i0
+	for i1 < len(ch.Hunks) && ch.Hunks[i1].St <= ed {
+		i1++
+	}
+	off := 0 // base line minus current line, before the range
+	if i0 > 0 {
+		hk := ch.Hunks[i0-1]
+		off = hk.BaseEd - hk.Ed
+	}
+	rst, red := st, ed
+	if i1 > i0 {
+		rst = ints.MinInt(rst, ch.Hunks[i0].St)
+		red = ints.MaxInt(red, ch.Hunks[i1-1].Ed)
+	}
+	bst := rst + off
+	bed := red + off
+	if i1 > i0 {
+		hk := ch.Hunks[i1-1]
+		bed = red + hk.BaseEd - hk.Ed
+	}
+	nred := red + dln
+	nhks := ch.diffHunks(bst, bed, rst, nred, cur)
+	for i := i1; i < len(ch.Hunks); i++ {
+		ch.Hunks[i].St += dln
+		ch.Hunks[i].Ed += dln
+	}
+	hks := make([]ChangeHunk, 0, len(ch.Hunks)-(i1-i0)+len(nhks))
+	hks = append(hks, ch.Hunks[:i0]...)
+	hks = append(hks, nhks...)
+	ch.Hunks = append(hks, ch.Hunks[i1:]...)
+}
+
+// HunkAt returns the index of the hunk for given current line: the one
+// that contains it, or the deleted lines just before it -- false if the
+// line is unchanged
+func (ch *Changes) HunkAt(ln int) (int, bool) {
+	i := sort.Search(len(ch.Hunks), func(i int) bool { return ch.Hunks[i].Ed > ln || ch.Hunks[i].St >= ln })
+	for ; i < len(ch.Hunks) && ch.Hunks[i].St <= ln; i++ {
+		hk := ch.Hunks[i]
+		if ln < hk.Ed || (hk.St == hk.Ed && hk.St == ln) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// LineChange returns how given current line was changed -- LineDeleted
+// means that the line is unchanged, but base lines before it were deleted
+func (ch *Changes) LineChange(ln int) LineChanges {
+	i, ok := ch.HunkAt(ln)
+	if !ok {
+		return LineUnchanged
+	}
+	return ch.Hunks[i].Change
+}
+
+// BaseLine returns the line in the base for given current line, or -1 if
+// the line was added or modified
+func (ch *Changes) BaseLine(ln int) int {
+	i := sort.Search(len(ch.Hunks), func(i int) bool { return ch.Hunks[i].Ed > ln || ch.Hunks[i].St > ln })
+	if i < len(ch.Hunks) && ch.Hunks[i].St <= ln && ln < ch.Hunks[i].Ed {
+		return -1
+	}
+	if i == 0 {
+		return ln
+	}
+	hk := ch.Hunks[i-1]
+	return ln + hk.BaseEd - hk.Ed
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package textbuf
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/goki/ki/ints"
+)
+
+func linesBytes(s string) [][]byte {
+	var lns [][]byte
+	for _, ln := range strings.Split(s, "\n") {
+		lns = append(lns, []byte(ln))
+	}
+	return lns
+}
+
+// checkHunks checks that the unchanged lines between the hunks are the
+// same in the base and the current lines
+func checkHunks(t *testing.T, ch *Changes, cur [][]byte) {
+	t.Helper()
+	bl, cl := 0, 0
+	for i, hk := range ch.Hunks {
+		if hk.St < cl || hk.BaseSt < bl || hk.St-cl != hk.BaseSt-bl {
+			t.Fatalf("hunk %d out of order: %+v, after base: %d cur: %d", i, hk, bl, cl)
+		}
+		for ; cl < hk.St; cl, bl = cl+1, bl+1 {
+			if ch.Base[bl] != string(cur[cl]) {
+				t.Fatalf("line %d not the same as base line %d before hunk %d: %q != %q", cl, bl, i, cur[cl], ch.Base[bl])
+			}
+		}
+		bl, cl = hk.BaseEd, hk.Ed
+	}
+	if len(cur)-cl != len(ch.Base)-bl {
+		t.Fatalf("unchanged lines at end differ: cur: %d base: %d", len(cur)-cl, len(ch.Base)-bl)
+	}
+	for ; cl < len(cur); cl, bl = cl+1, bl+1 {
+		if ch.Base[bl] != string(cur[cl]) {
+			t.Fatalf("line %d not the same as base line %d at end", cl, bl)
+		}
+	}
+}
+
+func TestChanges(t *testing.T) {
+	base := strings.Split("a\nb\nc\nd\ne\nf\ng", "\n")
+	cur := linesBytes("a\nB\nc\nx\ny\nd\nf\ng")
+	ch := NewChanges(base, LinesBytes(cur))
+	checkHunks(t, ch, cur)
+	exp := []LineChanges{LineUnchanged, LineModified, LineUnchanged, LineAdded, LineAdded, LineUnchanged, LineDeleted, LineUnchanged}
+	for ln, lc := range exp {
+		if got := ch.LineChange(ln); got != lc {
+			t.Errorf("line %d: %v, expected: %v", ln, got, lc)
+		}
+	}
+	if bl := ch.BaseLine(6); bl != 5 {
+		t.Errorf("base line of 6: %d", bl)
+	}
+	if bl := ch.BaseLine(1); bl != -1 {
+		t.Errorf("base line of modified line: %d", bl)
+	}
+
+	// modify the line after the deleted one, and undo the modified line
+	cur[6] = []byte("F")
+	ch.Edit(LinesBytes(cur), 6, 7, 1)
+	checkHunks(t, ch, cur)
+	if lc := ch.LineChange(6); lc != LineModified {
+		t.Errorf("edited line: %v", lc)
+	}
+	cur[1] = []byte("b")
+	ch.Edit(LinesBytes(cur), 1, 2, 1)
+	checkHunks(t, ch, cur)
+	if lc := ch.LineChange(1); lc != LineUnchanged {
+		t.Errorf("restored line: %v", lc)
+	}
+
+	// random edits must keep the hunks consistent
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		st := rnd.Intn(len(cur) + 1)
+		ed := st
+		if st < len(cur) {
+			ed = st + rnd.Intn(ints.MinInt(3, len(cur)-st)+1)
+		}
+		nln := rnd.Intn(3)
+		var nw [][]byte
+		for j := 0; j < nln; j++ {
+			if rnd.Intn(2) == 0 {
+				nw = append(nw, []byte(base[rnd.Intn(len(base))]))
+			} else {
+				nw = append(nw, []byte(fmt.Sprintf("n%d", i)))
+			}
+		}
+		ncur := append(append(append([][]byte{}, cur[:st]...), nw...), cur[ed:]...)
+		cur = ncur
+		ch.Edit(LinesBytes(cur), st, ed, nln)
+		checkHunks(t, ch, cur)
+	}
+}
@@ -119,15 +119,14 @@ func (te *Edit) AdjustPos(pos lex.Pos, del AdjustPosDel) lex.Pos {
 				return lex.PosErr
 			}
 		}
-		// this means pos.Ln == te.Reg.End.Ln, Ch >= end
-		if dl == 0 {
-			pos.Ch -= (te.Reg.End.Ch - te.Reg.Start.Ch)
-		} else {
-			pos.Ch -= te.Reg.End.Ch
-		}
+		// this means pos.Ln == te.Reg.End.Ln, Ch >= end, so it moves to
+		// the start line, after the start
+		pos.Ln = te.Reg.Start.Ln
+		pos.Ch = te.Reg.Start.Ch + pos.Ch - te.Reg.End.Ch
 	} else {
-		if dl == 0 {
-			pos.Ch += (te.Reg.End.Ch - te.Reg.Start.Ch)
+		if pos.Ln == te.Reg.Start.Ln { // after the start on its line, so moves to after the end
+			pos.Ln = te.Reg.End.Ln
+			pos.Ch = te.Reg.End.Ch + pos.Ch - te.Reg.Start.Ch
 		} else {
 			pos.Ln += dl
 		}
@@ -0,0 +1,36 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package textbuf
+
+import (
+	"testing"
+
+	"github.com/goki/pi/lex"
+)
+
+func TestEditAdjustPos(t *testing.T) {
+	reg := func(sl, sc, el, ec int) Region {
+		return Region{Start: lex.Pos{Ln: sl, Ch: sc}, End: lex.Pos{Ln: el, Ch: ec}}
+	}
+	tests := []struct {
+		name     string
+		te       Edit
+		pos, exp lex.Pos
+	}{
+		{"insert before", Edit{Reg: reg(1, 2, 1, 5)}, lex.Pos{Ln: 1, Ch: 1}, lex.Pos{Ln: 1, Ch: 1}},
+		{"insert on line", Edit{Reg: reg(1, 2, 1, 5)}, lex.Pos{Ln: 1, Ch: 4}, lex.Pos{Ln: 1, Ch: 7}},
+		{"insert lines on line", Edit{Reg: reg(1, 2, 3, 5)}, lex.Pos{Ln: 1, Ch: 4}, lex.Pos{Ln: 3, Ch: 7}},
+		{"insert lines after", Edit{Reg: reg(1, 2, 3, 5)}, lex.Pos{Ln: 2, Ch: 4}, lex.Pos{Ln: 4, Ch: 4}},
+		{"delete on line", Edit{Reg: reg(1, 2, 1, 5), Delete: true}, lex.Pos{Ln: 1, Ch: 7}, lex.Pos{Ln: 1, Ch: 4}},
+		{"delete lines to line", Edit{Reg: reg(1, 2, 3, 5), Delete: true}, lex.Pos{Ln: 3, Ch: 7}, lex.Pos{Ln: 1, Ch: 4}},
+		{"delete lines after", Edit{Reg: reg(1, 2, 3, 5), Delete: true}, lex.Pos{Ln: 4, Ch: 7}, lex.Pos{Ln: 2, Ch: 7}},
+		{"delete within", Edit{Reg: reg(1, 2, 3, 5), Delete: true}, lex.Pos{Ln: 2, Ch: 7}, lex.Pos{Ln: 3, Ch: 5}},
+	}
+	for _, tt := range tests {
+		if got := tt.te.AdjustPos(tt.pos, AdjustPosDelEnd); got != tt.exp {
+			t.Errorf("%s: got %v, expected %v", tt.name, got, tt.exp)
+		}
+	}
+}
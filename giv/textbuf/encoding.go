@@ -0,0 +1,412 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package textbuf
+
+import (
+	"bytes"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/ki/kit"
+)
+
+// Encodings are the character encodings of text files -- text is always
+// UTF-8 in a TextBuf, and is converted when reading and writing files
+type Encodings int32
+
+const (
+	// UTF8 is UTF-8 without a byte order mark
+	UTF8 Encodings = iota
+
+	// UTF8BOM is UTF-8 starting with a byte order mark
+	UTF8BOM
+
+	// UTF16LE is little-endian UTF-16 without a byte order mark
+	UTF16LE
+
+	// UTF16LEBOM is little-endian UTF-16 starting with a byte order mark
+	UTF16LEBOM
+
+	// UTF16BE is big-endian UTF-16 without a byte order mark
+	UTF16BE
+
+	// UTF16BEBOM is big-endian UTF-16 starting with a byte order mark
+	UTF16BEBOM
+
+	// Latin1 is ISO-8859-1, the 8-bit encoding of western european
+	// languages, whose codes are the first 256 unicode characters
+	Latin1
+
+	// Latin9 is ISO-8859-15, which is Latin1 with the euro sign and a few
+	// other replacements
+	Latin9
+
+	// Windows1252 is the 8-bit windows encoding of western european
+	// languages, which is Latin1 with printable characters in 0x80-0x9F
+	Windows1252
+
+	EncodingsN
+)
+
+//go:generate stringer -type=Encodings
+
+var KiT_Encodings = kit.Enums.AddEnum(EncodingsN, kit.NotBitFlag, gi.StylePropProps)
+
+func (ev Encodings) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *Encodings) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+var encodingNames = [...]string{"UTF-8", "UTF-8 BOM", "UTF-16 LE", "UTF-16 LE BOM", "UTF-16 BE", "UTF-16 BE BOM", "ISO-8859-1", "ISO-8859-15", "Windows-1252"}
+
+// Name returns the standard name of the encoding, for display
+func (ev Encodings) Name() string {
+	if ev < 0 || ev >= EncodingsN {
+		return ev.String()
+	}
+	return encodingNames[ev]
+}
+
+// Is8Bit returns true for the 8-bit encodings, which can only represent
+// 256 different characters
+func (ev Encodings) Is8Bit() bool {
+	return ev >= Latin1 && ev < EncodingsN
+}
+
+// LineEnds are the line endings of text files -- lines always end in LF in
+// a TextBuf, and are converted when reading and writing files
+type LineEnds int32
+
+const (
+	// LF is a line feed, used on unix and mac
+	LF LineEnds = iota
+
+	// CRLF is a carriage return followed by a line feed, used on windows
+	CRLF
+
+	// CR is a carriage return, used on classic mac
+	CR
+
+	LineEndsN
+)
+
+//go:generate stringer -type=LineEnds
+
+var KiT_LineEnds = kit.Enums.AddEnum(LineEndsN, kit.NotBitFlag, gi.StylePropProps)
+
+func (ev LineEnds) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *LineEnds) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+var lineEndBytes = [...][]byte{[]byte("\n"), []byte("\r\n"), []byte("\r")}
+
+// Bytes returns the bytes that end a line
+func (ev LineEnds) Bytes() []byte {
+	if ev < 0 || ev >= LineEndsN {
+		return lineEndBytes[LF]
+	}
+	return lineEndBytes[ev]
+}
+
+// byte order marks
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// encodingSample is the number of bytes at the start of a file used to
+// detect UTF-16 without a byte order mark
+const encodingSample = 4096
+
+// DetectEncoding returns the encoding of given file contents, from its
+// byte order mark if it has one, and otherwise from the pattern of zero
+// bytes for UTF-16, and whether it is valid UTF-8.  Other text is
+// Windows1252 if it has any of its extra characters, and Latin1 otherwise.
+func DetectEncoding(b []byte) Encodings {
+	switch {
+	case bytes.HasPrefix(b, bomUTF8):
+		return UTF8BOM
+	case bytes.HasPrefix(b, bomUTF16LE):
+		return UTF16LEBOM
+	case bytes.HasPrefix(b, bomUTF16BE):
+		return UTF16BEBOM
+	}
+	if enc, ok := detectUTF16(b); ok {
+		return enc
+	}
+	if utf8.Valid(b) {
+		return UTF8
+	}
+	for _, c := range b {
+		if c >= 0x80 && c <= 0x9F && windows1252[c-0x80] != rune(c) {
+			return Windows1252
+		}
+	}
+	return Latin1
+}
+
+// detectUTF16 detects UTF-16 text without a byte order mark, in which
+// most characters are typically ASCII, with a zero byte as the high byte
+func detectUTF16(b []byte) (Encodings, bool) {
+	n := len(b)
+	if n > encodingSample {
+		n = encodingSample
+	}
+	n &^= 1
+	if n < 4 {
+		return UTF8, false
+	}
+	even, odd := 0, 0
+	for i := 0; i < n; i += 2 {
+		if b[i] == 0 {
+			even++
+		}
+		if b[i+1] == 0 {
+			odd++
+		}
+	}
+	npr := n / 2
+	switch {
+	case odd*10 > npr*3 && even*20 < npr:
+		return UTF16LE, true
+	case even*10 > npr*3 && odd*20 < npr:
+		return UTF16BE, true
+	}
+	return UTF8, false
+}
+
+// windows1252 has the characters for codes 0x80-0x9F in Windows1252 --
+// the unused codes map to the same control characters as in Latin1
+var windows1252 = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+// latin9 has the characters of Latin9 that differ from Latin1
+var latin9 = map[byte]rune{
+	0xA4: 0x20AC, 0xA6: 0x0160, 0xA8: 0x0161, 0xB4: 0x017D,
+	0xB8: 0x017E, 0xBC: 0x0152, 0xBD: 0x0153, 0xBE: 0x0178,
+}
+
+// charmap is the decoding table for an 8-bit encoding, with the reverse
+// map for encoding the characters outside of Latin1
+type charmap struct {
+	dec [256]rune
+	enc map[rune]byte
+}
+
+var charmaps = map[Encodings]*charmap{}
+
+func init() {
+	for _, enc := range []Encodings{Latin1, Latin9, Windows1252} {
+		cm := &charmap{enc: map[rune]byte{}}
+		for i := range cm.dec {
+			cm.dec[i] = rune(i)
+		}
+		switch enc {
+		case Latin9:
+			for c, r := range latin9 {
+				cm.dec[c] = r
+			}
+		case Windows1252:
+			for i, r := range windows1252 {
+				cm.dec[0x80+i] = r
+			}
+		}
+		for i, r := range cm.dec {
+			if r != rune(i) {
+				cm.enc[r] = byte(i)
+				cm.enc[rune(i)] = 0 // not representable
+			}
+		}
+		charmaps[enc] = cm
+	}
+}
+
+// encode returns the byte for given rune, and false if it can't be represented
+func (cm *charmap) encode(r rune) (byte, bool) {
+	if c, has := cm.enc[r]; has {
+		return c, c != 0
+	}
+	if r < 256 {
+		return byte(r), true
+	}
+	return '?', false
+}
+
+// IsUTF16 returns true for the UTF-16 encodings
+func (ev Encodings) IsUTF16() bool {
+	return ev >= UTF16LE && ev <= UTF16BEBOM
+}
+
+// IsLittleEndian returns true for the little-endian UTF-16 encodings
+func (ev Encodings) IsLittleEndian() bool {
+	return ev == UTF16LE || ev == UTF16LEBOM
+}
+
+// Decode returns given file contents in given encoding converted to UTF-8,
+// without any byte order mark, which is removed if the file starts with
+// one even for the encodings without one -- it is b itself for UTF-8
+// without one
+func Decode(b []byte, enc Encodings) []byte {
+	switch {
+	case enc == UTF8:
+		return b
+	case enc == UTF8BOM:
+		return bytes.TrimPrefix(b, bomUTF8)
+	case enc.IsUTF16():
+		le := enc.IsLittleEndian()
+		if le {
+			b = bytes.TrimPrefix(b, bomUTF16LE)
+		} else {
+			b = bytes.TrimPrefix(b, bomUTF16BE)
+		}
+		u16 := make([]uint16, len(b)/2)
+		for i := range u16 {
+			if le {
+				u16[i] = uint16(b[2*i]) | uint16(b[2*i+1])<<8
+			} else {
+				u16[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+			}
+		}
+		return []byte(string(utf16.Decode(u16)))
+	}
+	cm, ok := charmaps[enc]
+	if !ok {
+		return b
+	}
+	ub := make([]byte, 0, len(b)+len(b)/8)
+	for _, c := range b {
+		if c < utf8.RuneSelf {
+			ub = append(ub, c)
+		} else {
+			ub = AppendRune(ub, cm.dec[c])
+		}
+	}
+	return ub
+}
+
+// Encode returns given UTF-8 text converted to given encoding, starting
+// with a byte order mark if the encoding has one.  Characters that can't
+// be represented in the encoding are replaced with '?', and nbad is the
+// number of them.
+func Encode(txt []byte, enc Encodings) (b []byte, nbad int) {
+	switch {
+	case enc == UTF8:
+		return txt, 0
+	case enc == UTF8BOM:
+		return append(append([]byte{}, bomUTF8...), txt...), 0
+	case enc.IsUTF16():
+		le := enc.IsLittleEndian()
+		u16 := utf16.Encode(bytes.Runes(txt))
+		b = make([]byte, 0, 2+2*len(u16))
+		switch enc {
+		case UTF16LEBOM:
+			b = append(b, bomUTF16LE...)
+		case UTF16BEBOM:
+			b = append(b, bomUTF16BE...)
+		}
+		for _, u := range u16 {
+			if le {
+				b = append(b, byte(u), byte(u>>8))
+			} else {
+				b = append(b, byte(u>>8), byte(u))
+			}
+		}
+		return b, 0
+	}
+	cm, ok := charmaps[enc]
+	if !ok {
+		return txt, 0
+	}
+	b = make([]byte, 0, len(txt))
+	for len(txt) > 0 {
+		r, sz := utf8.DecodeRune(txt)
+		txt = txt[sz:]
+		c, ok := cm.encode(r)
+		if !ok {
+			c = '?'
+			nbad++
+		}
+		b = append(b, c)
+	}
+	return b, nbad
+}
+
+// AppendRune appends the UTF-8 encoding of given rune to given bytes
+func AppendRune(b []byte, r rune) []byte {
+	var rb [utf8.UTFMax]byte
+	n := utf8.EncodeRune(rb[:], r)
+	return append(b, rb[:n]...)
+}
+
+// DetectLineEnds returns the line endings used most in given text, which
+// is LF if it has no lines
+func DetectLineEnds(b []byte) LineEnds {
+	nlf, ncrlf, ncr := 0, 0, 0
+	for i, c := range b {
+		switch {
+		case c == '\n' && i > 0 && b[i-1] == '\r':
+			ncrlf++
+		case c == '\n':
+			nlf++
+		case c == '\r' && (i+1 == len(b) || b[i+1] != '\n'):
+			ncr++
+		}
+	}
+	switch {
+	case ncrlf > nlf && ncrlf >= ncr:
+		return CRLF
+	case ncr > nlf && ncr > ncrlf:
+		return CR
+	}
+	return LF
+}
+
+// NormLineEnds returns given text with CRLF and CR line endings converted
+// to LF -- it is b itself if it has no CR's
+func NormLineEnds(b []byte) []byte {
+	if bytes.IndexByte(b, '\r') < 0 {
+		return b
+	}
+	nb := make([]byte, 0, len(b))
+	for i, c := range b {
+		if c == '\r' {
+			if i+1 < len(b) && b[i+1] == '\n' {
+				continue
+			}
+			c = '\n'
+		}
+		nb = append(nb, c)
+	}
+	return nb
+}
+
+// SetLineEnds returns given text with LF line endings converted to given
+// line endings -- it is b itself for LF
+func SetLineEnds(b []byte, le LineEnds) []byte {
+	if le == LF || le < 0 || le >= LineEndsN {
+		return b
+	}
+	return bytes.Replace(b, lineEndBytes[LF], lineEndBytes[le], -1)
+}
+
+// DecodeText returns given file contents as UTF-8 text with LF line
+// endings, with the detected encoding and line endings of the file
+func DecodeText(b []byte) (txt []byte, enc Encodings, le LineEnds) {
+	enc = DetectEncoding(b)
+	txt = Decode(b, enc)
+	le = DetectLineEnds(txt)
+	return NormLineEnds(txt), enc, le
+}
+
+// EncodeText returns given UTF-8 text with LF line endings converted to
+// given encoding and line endings, for saving to a file -- nbad is the
+// number of characters that can't be represented in the encoding, which
+// are replaced with '?'
+func EncodeText(txt []byte, enc Encodings, le LineEnds) (b []byte, nbad int) {
+	return Encode(SetLineEnds(txt, le), enc)
+}
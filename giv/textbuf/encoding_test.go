@@ -0,0 +1,70 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package textbuf
+
+import (
+	"testing"
+)
+
+func TestEncodings(t *testing.T) {
+	txt := "naïve café\nœuvre: 5€\n"
+	for enc := UTF8; enc < EncodingsN; enc++ {
+		for le := LF; le < LineEndsN; le++ {
+			b, nbad := EncodeText([]byte(txt), enc, le)
+			if enc == Latin1 {
+				if nbad != 2 { // œ and €
+					t.Errorf("%v: expected 2 unrepresentable, got: %d", enc, nbad)
+				}
+				continue
+			}
+			if nbad != 0 {
+				t.Errorf("%v: expected all representable, got: %d", enc, nbad)
+			}
+			dt, denc, dle := DecodeText(b)
+			if enc == Latin9 { // can't be told apart from Latin1
+				dt = NormLineEnds(Decode(b, enc))
+				denc = enc
+			}
+			if denc != enc || dle != le {
+				t.Errorf("%v %v: detected as: %v %v", enc, le, denc, dle)
+			}
+			if string(dt) != txt {
+				t.Errorf("%v %v: decoded text: %q", enc, le, dt)
+			}
+		}
+	}
+}
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		b   []byte
+		enc Encodings
+	}{
+		{[]byte("plain ascii\n"), UTF8},
+		{[]byte("h\x00i\x00!\x00\n\x00"), UTF16LE}, // no byte order mark
+		{[]byte("\x00h\x00i\x00!\x00\n"), UTF16BE},
+		{[]byte("\xff\xfeh\x00i\x00"), UTF16LEBOM},
+		{[]byte("\xfe\xff\x00h\x00i"), UTF16BEBOM},
+		{[]byte("caf\xe9\n"), Latin1},
+		{[]byte("\x93quoted\x94\n"), Windows1252},
+	}
+	for _, tst := range tests {
+		if enc := DetectEncoding(tst.b); enc != tst.enc {
+			t.Errorf("DetectEncoding(%q): got %v, expected %v", tst.b, enc, tst.enc)
+		}
+	}
+	if dt := Decode([]byte("\x93quoted\x94"), Windows1252); string(dt) != "“quoted”" {
+		t.Errorf("Windows1252 decode: %q", dt)
+	}
+	if b, _ := Encode([]byte("hi"), UTF16LE); string(b) != "h\x00i\x00" {
+		t.Errorf("UTF16LE encode without byte order mark: %q", b)
+	}
+	if b, _ := Encode([]byte("hi"), UTF16BEBOM); string(b) != "\xfe\xff\x00h\x00i" {
+		t.Errorf("UTF16BEBOM encode: %q", b)
+	}
+	if nt := NormLineEnds([]byte("a\r\nb\rc\n")); string(nt) != "a\nb\nc\n" {
+		t.Errorf("NormLineEnds: %q", nt)
+	}
+}
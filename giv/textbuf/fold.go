@@ -0,0 +1,155 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package textbuf
+
+import (
+	"sort"
+
+	"github.com/goki/pi/lex"
+	"github.com/goki/pi/token"
+)
+
+// FoldMinComments is the minimum number of consecutive comment-only lines
+// that can be folded as a block
+var FoldMinComments = 3
+
+// FoldRegions returns the regions of given lines that can be folded, in
+// order of starting line, with at most one region per starting line.  The
+// Start line of each region is the header line that remains visible when
+// the region is folded, and the lines after it, through End.Ln (inclusive),
+// are hidden.  Regions are computed from matching braces, brackets and
+// parens spanning multiple lines (using the tags to ignore those within
+// comments and strings, leaving the closing line visible), from blocks of
+// consecutive comment lines, and, for lines that start no other region,
+// from the indentation of the lines that follow.
+func FoldRegions(lines [][]rune, tags []lex.Line, tabSz int) []Region {
+	nln := len(lines)
+	ends := make(map[int]Region, nln/4)
+	add := func(st, stch, ed int) {
+		if ed <= st {
+			return
+		}
+		if cur, has := ends[st]; has && cur.End.Ln >= ed {
+			return
+		}
+		ends[st] = Region{Start: lex.Pos{Ln: st, Ch: stch}, End: lex.Pos{Ln: ed, Ch: len(lines[ed])}}
+	}
+
+	type brace struct {
+		ln, ch int
+		r      rune
+	}
+	var stack []brace
+	cmSt := -1
+	for ln := 0; ln <= nln; ln++ {
+		var txt []rune
+		var tln lex.Line
+		if ln < nln {
+			txt = lines[ln]
+			if ln < len(tags) {
+				tln = tags[ln]
+			}
+		}
+		if ln < nln && foldCommentLine(txt, tln) {
+			if cmSt < 0 {
+				cmSt = ln
+			}
+			continue
+		}
+		if cmSt >= 0 {
+			if ln-cmSt >= FoldMinComments {
+				add(cmSt, 0, ln-1)
+			}
+			cmSt = -1
+		}
+		for i, r := range txt {
+			switch r {
+			case '{', '[', '(':
+				if foldIgnoreAt(tln, i) {
+					continue
+				}
+				stack = append(stack, brace{ln, i, r})
+			case '}', ']', ')':
+				if foldIgnoreAt(tln, i) {
+					continue
+				}
+				match, _ := lex.BracePair(r)
+				for len(stack) > 0 {
+					br := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					if br.r == match {
+						add(br.ln, br.ch, ln-1)
+						break
+					}
+				}
+			}
+		}
+	}
+
+	for ln := 0; ln < nln; ln++ {
+		if _, has := ends[ln]; has {
+			continue
+		}
+		if foldBlank(lines[ln]) {
+			continue
+		}
+		ind, _ := lex.LineIndent(lines[ln], tabSz)
+		ed := ln
+		for nl := ln + 1; nl < nln; nl++ {
+			if foldBlank(lines[nl]) {
+				continue
+			}
+			nind, _ := lex.LineIndent(lines[nl], tabSz)
+			if nind <= ind {
+				break
+			}
+			ed = nl // ends at last non-blank line, so trailing blanks are visible
+		}
+		add(ln, 0, ed)
+	}
+
+	regs := make([]Region, 0, len(ends))
+	for _, reg := range ends {
+		regs = append(regs, reg)
+	}
+	sort.Slice(regs, func(i, j int) bool {
+		return regs[i].Start.Ln < regs[j].Start.Ln
+	})
+	return regs
+}
+
+// foldIgnoreAt returns true if the character at given position is within
+// a comment or string according to the tags
+func foldIgnoreAt(tln lex.Line, ch int) bool {
+	lx, _ := tln.AtPos(ch)
+	if lx == nil {
+		return false
+	}
+	tk := lx.Tok.Tok
+	return tk.Cat() == token.Comment || tk.InSubCat(token.LitStr)
+}
+
+// foldCommentLine returns true if given line is only a comment
+func foldCommentLine(txt []rune, tln lex.Line) bool {
+	if len(tln) == 0 {
+		return false
+	}
+	for _, lx := range tln {
+		if lx.Tok.Tok.Cat() != token.Comment {
+			return false
+		}
+	}
+	return !foldBlank(txt)
+}
+
+// foldBlank returns true if given line is empty or only whitespace
+func foldBlank(txt []rune) bool {
+	for _, r := range txt {
+		if !lex.IsWhiteSpace(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package textbuf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goki/pi/lex"
+	"github.com/goki/pi/token"
+)
+
+func TestFoldRegions(t *testing.T) {
+	src := `// comment one
+// comment two
+// comment three
+func f(a int) {
+	s := "{"
+	if a > 0 {
+		a++
+	}
+}
+
+section
+	item one
+	item two
+
+end`
+	var lines [][]rune
+	for _, l := range strings.Split(src, "\n") {
+		lines = append(lines, []rune(l))
+	}
+	tags := make([]lex.Line, len(lines))
+	for ln := 0; ln < 3; ln++ {
+		tags[ln].AddLex(token.KeyToken{Tok: token.Comment}, 0, len(lines[ln]))
+	}
+	tags[4].AddLex(token.KeyToken{Tok: token.LitStr}, 6, 9)
+
+	regs := FoldRegions(lines, tags, 4)
+	exp := [][2]int{{0, 2}, {3, 7}, {5, 6}, {10, 12}}
+	if len(regs) != len(exp) {
+		t.Fatalf("got %d regions, expected %d: %v", len(regs), len(exp), regs)
+	}
+	for i, reg := range regs {
+		if reg.Start.Ln != exp[i][0] || reg.End.Ln != exp[i][1] {
+			t.Errorf("region %d: got lines %d-%d, expected %d-%d", i, reg.Start.Ln, reg.End.Ln, exp[i][0], exp[i][1])
+		}
+	}
+	if regs[1].Start.Ch != 14 {
+		t.Errorf("brace region start ch: got %d, expected 14", regs[1].Start.Ch)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package textbuf
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// IgnoreRule is one glob pattern from a .gitignore file, or a list of
+// files to include or exclude from a search.  Patterns follow the
+// .gitignore conventions: * and ? do not match a /, ** matches across
+// directories, a trailing / only matches directories, and a pattern with
+// a / anywhere but at the end is relative to the directory of the rule --
+// otherwise it matches a file or directory name at any depth.
+type IgnoreRule struct {
+	Dir     string `desc:"directory of the rule, as a slash-separated path relative to the root of the files, or empty for the root"`
+	Pattern string `desc:"the glob pattern, as written"`
+	Negate  bool   `desc:"pattern was prefixed with ! -- a match re-includes a path excluded by an earlier rule"`
+	DirOnly bool   `desc:"pattern ended with / -- only matches directories"`
+	re      *regexp.Regexp
+}
+
+// NewIgnoreRule returns a new rule for given pattern, from a .gitignore
+// file in given directory (slash-separated, relative to the root, empty
+// for the root).  Returns nil for blank lines and comments.
+func NewIgnoreRule(pat, dir string) *IgnoreRule {
+	pat = strings.TrimRight(pat, " \t\r")
+	if pat == "" || strings.HasPrefix(pat, "#") {
+		return nil
+	}
+	ir := &IgnoreRule{Dir: strings.Trim(dir, "/"), Pattern: pat}
+	if strings.HasPrefix(pat, "!") {
+		ir.Negate = true
+		pat = pat[1:]
+	}
+	pat = strings.TrimPrefix(pat, `\`) // escaped leading ! or #
+	if strings.HasSuffix(pat, "/") {
+		ir.DirOnly = true
+		pat = strings.TrimRight(pat, "/")
+	}
+	if pat == "" {
+		return nil
+	}
+	anchored := strings.Contains(pat, "/")
+	pat = strings.TrimPrefix(pat, "/")
+	rs := GlobToRegexp(pat)
+	if !anchored {
+		rs = `(?:.*/)?` + rs
+	}
+	re, err := regexp.Compile(`^` + rs + `$`)
+	if err != nil {
+		return nil
+	}
+	ir.re = re
+	return ir
+}
+
+// Match returns true if the rule matches given slash-separated path,
+// relative to the root of the files.
+func (ir *IgnoreRule) Match(fpath string, isDir bool) bool {
+	if ir.DirOnly && !isDir {
+		return false
+	}
+	if ir.Dir != "" {
+		if !strings.HasPrefix(fpath, ir.Dir+"/") {
+			return false
+		}
+		fpath = fpath[len(ir.Dir)+1:]
+	}
+	return ir.re.MatchString(fpath)
+}
+
+// GlobToRegexp returns a regular expression (without anchors) for given
+// .gitignore-style glob pattern
+func GlobToRegexp(pat string) string {
+	var sb strings.Builder
+	sz := len(pat)
+	for i := 0; i < sz; i++ {
+		c := pat[i]
+		switch c {
+		case '*':
+			if i+1 < sz && pat[i+1] == '*' {
+				i++
+				if i+1 < sz && pat[i+1] == '/' {
+					i++
+					sb.WriteString(`(?:.*/)?`)
+				} else {
+					sb.WriteString(`.*`)
+				}
+			} else {
+				sb.WriteString(`[^/]*`)
+			}
+		case '?':
+			sb.WriteString(`[^/]`)
+		case '[':
+			ed := strings.IndexByte(pat[i+1:], ']')
+			if ed < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			cls := pat[i+1 : i+1+ed]
+			if strings.HasPrefix(cls, "!") {
+				cls = "^" + cls[1:]
+			}
+			sb.WriteString("[" + strings.Replace(cls, `\`, `\\`, -1) + "]")
+			i += ed + 1
+		case '\\':
+			if i+1 < sz {
+				i++
+				sb.WriteString(regexp.QuoteMeta(pat[i : i+1]))
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(pat[i : i+1]))
+		}
+	}
+	return sb.String()
+}
+
+// Ignore is a list of rules, e.g., from the .gitignore files in a tree
+// of files, where later rules take precedence over earlier ones.
+type Ignore []*IgnoreRule
+
+// Parse adds rules from given .gitignore file contents, for the file in
+// given directory (slash-separated, relative to the root)
+func (ig *Ignore) Parse(b []byte, dir string) {
+	scan := bufio.NewScanner(bytes.NewReader(b))
+	for scan.Scan() {
+		if ir := NewIgnoreRule(scan.Text(), dir); ir != nil {
+			*ig = append(*ig, ir)
+		}
+	}
+}
+
+// OpenFile adds rules from given .gitignore file, in given directory
+// (slash-separated, relative to the root) -- returns error if the file
+// can not be read.
+func (ig *Ignore) OpenFile(filename, dir string) error {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	ig.Parse(b, dir)
+	return nil
+}
+
+// AddPatterns adds rules for given glob patterns, relative to the root
+func (ig *Ignore) AddPatterns(pats ...string) {
+	for _, pat := range pats {
+		if ir := NewIgnoreRule(pat, ""); ir != nil {
+			*ig = append(*ig, ir)
+		}
+	}
+}
+
+// Ignored returns true if given slash-separated path, relative to the
+// root, is matched by the rules -- i.e., the last rule matching it is not
+// negated.  Directories above the path are not checked, so when walking
+// the files, directories that are ignored should be skipped.
+func (ig Ignore) Ignored(fpath string, isDir bool) bool {
+	fpath = path.Clean(strings.TrimPrefix(fpath, "/"))
+	for i := len(ig) - 1; i >= 0; i-- {
+		if ig[i].Match(fpath, isDir) {
+			return !ig[i].Negate
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package textbuf
+
+import (
+	"testing"
+)
+
+func TestIgnore(t *testing.T) {
+	var ig Ignore
+	ig.Parse([]byte("# comment\n*.o\nbuild/\n/top.txt\ndoc/**/*.html\n!keep.o\n"), "")
+	ig.Parse([]byte("local.txt\n"), "sub")
+	tests := []struct {
+		path  string
+		isDir bool
+		ign   bool
+	}{
+		{"a.o", false, true},
+		{"x/y/a.o", false, true},
+		{"keep.o", false, false},
+		{"build", true, true},
+		{"x/build", true, true},
+		{"build", false, false}, // not a dir
+		{"top.txt", false, true},
+		{"x/top.txt", false, false},
+		{"doc/a.html", false, true},
+		{"doc/x/y/a.html", false, true},
+		{"x/doc/a.html", false, false},
+		{"sub/local.txt", false, true},
+		{"sub/x/local.txt", false, true},
+		{"local.txt", false, false},
+		{"main.go", false, false},
+	}
+	for _, tst := range tests {
+		if ign := ig.Ignored(tst.path, tst.isDir); ign != tst.ign {
+			t.Errorf("Ignored(%q, %v): got %v, expected %v", tst.path, tst.isDir, ign, tst.ign)
+		}
+	}
+}
+
+func TestReplaceMatches(t *testing.T) {
+	src := [][]byte{[]byte("foo(a) foo(bb)"), []byte("no match"), []byte("x foo(c)")}
+	re, err := SearchRegexpFor(`foo\((\w+)\)`, true, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ms := SearchByteLinesRegexp(src, re)
+	if len(ms) != 3 {
+		t.Fatalf("expected 3 matches, got: %d", len(ms))
+	}
+	repls := ReplaceMatches(src, re, ms, []byte("bar[$1]"), true)
+	regs := make([]Region, len(ms))
+	for i, m := range ms {
+		regs[i] = m.Reg
+	}
+	if n := ReplaceLines(src, regs, repls); n != 3 {
+		t.Errorf("expected 3 replaced, got: %d", n)
+	}
+	exp := []string{"bar[a] bar[bb]", "no match", "x bar[c]"}
+	for i, ln := range src {
+		if string(ln) != exp[i] {
+			t.Errorf("line %d: got %q, expected %q", i, ln, exp[i])
+		}
+	}
+
+	re, _ = SearchRegexpFor("a.b", false, true, true)
+	if _, ms := SearchByteLinesRegexp([][]byte{[]byte("A.B a.bc axb a.b")}, re); len(ms) != 2 {
+		t.Errorf("literal whole word ignore case: expected 2 matches, got: %v", ms)
+	}
+}
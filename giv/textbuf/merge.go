@@ -0,0 +1,306 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package textbuf
+
+import (
+	"strings"
+
+	"github.com/goki/ki/kit"
+)
+
+// MergeChoices are the versions of the text in a three-way merge, and
+// the ways that a conflict between them can be resolved
+type MergeChoices int32
+
+const (
+	// MergeUnresolved is a conflict that has not been resolved -- it is
+	// shown with conflict markers
+	MergeUnresolved MergeChoices = iota
+
+	// MergeOurs is our version
+	MergeOurs
+
+	// MergeTheirs is their version
+	MergeTheirs
+
+	// MergeBoth is our version followed by theirs
+	MergeBoth
+
+	// MergeBase is the common base version, discarding both changes
+	MergeBase
+
+	// MergeChoicesN is the number of merge choices
+	MergeChoicesN
+)
+
+//go:generate stringer -type=MergeChoices
+
+var KiT_MergeChoices = kit.Enums.AddEnum(MergeChoicesN, kit.NotBitFlag, nil)
+
+func (ev MergeChoices) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *MergeChoices) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+// Conflict markers, as written by version control systems
+var (
+	MergeMarkerOurs   = "<<<<<<<"
+	MergeMarkerBase   = "|||||||"
+	MergeMarkerSep    = "======="
+	MergeMarkerTheirs = ">>>>>>>"
+)
+
+// MergeChunk is a chunk of lines in a three-way merge of the base, ours
+// and theirs versions of a text: either lines that merge cleanly, or a
+// conflict where ours and theirs both changed the base lines differently.
+type MergeChunk struct {
+	Base     []string     `desc:"lines of the base version"`
+	Ours     []string     `desc:"lines of our version"`
+	Theirs   []string     `desc:"lines of their version"`
+	Conflict bool         `desc:"ours and theirs both changed the base lines, differently"`
+	Choice   MergeChoices `desc:"how the conflict is resolved"`
+}
+
+// Version returns the lines of given version of the chunk
+func (mc *MergeChunk) Version(ver MergeChoices) []string {
+	switch ver {
+	case MergeOurs:
+		return mc.Ours
+	case MergeTheirs:
+		return mc.Theirs
+	case MergeBoth:
+		lns := make([]string, 0, len(mc.Ours)+len(mc.Theirs))
+		lns = append(lns, mc.Ours...)
+		return append(lns, mc.Theirs...)
+	case MergeBase:
+		return mc.Base
+	}
+	return nil
+}
+
+// IsChanged returns true if the lines of given version (MergeOurs or
+// MergeTheirs) differ from the base
+func (mc *MergeChunk) IsChanged(ver MergeChoices) bool {
+	return !equalLines(mc.Version(ver), mc.Base)
+}
+
+// Merge is a three-way merge of the base, ours and theirs versions of a
+// text, as a sequence of chunks
+type Merge struct {
+	Chunks      []*MergeChunk `desc:"the chunks of the merge, in order"`
+	HasBase     bool          `desc:"the base lines of conflicts are known -- not the case for conflict markers without base sections"`
+	OursLabel   string        `desc:"label for our version in conflict markers"`
+	BaseLabel   string        `desc:"label for the base version in conflict markers"`
+	TheirsLabel string        `desc:"label for their version in conflict markers"`
+}
+
+// equalLines returns true if the lines are the same
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// matchLines returns, for each line of a, the index of the same line in b
+// according to the diff between them, or -1 if it was deleted or changed
+func matchLines(a, b []string) []int {
+	mi := make([]int, len(a))
+	for i := range mi {
+		mi[i] = -1
+	}
+	for _, df := range DiffLines(a, b) {
+		if df.Tag != 'e' {
+			continue
+		}
+		for i := df.I1; i < df.I2; i++ {
+			mi[i] = df.J1 + (i - df.I1)
+		}
+	}
+	return mi
+}
+
+// Merge3 returns the three-way merge of ours and theirs, which were both
+// derived from base.  Lines that are unchanged in both versions separate
+// the chunks that are changed: where only one version changed, or both
+// changed the same way, the change merges cleanly -- otherwise it is a
+// conflict.
+func Merge3(base, ours, theirs []string) *Merge {
+	mg := &Merge{HasBase: true, OursLabel: "ours", BaseLabel: "base", TheirsLabel: "theirs"}
+	mo := matchLines(base, ours)
+	mt := matchLines(base, theirs)
+	nb := len(base)
+	i, j, k := 0, 0, 0
+	for {
+		b := i
+		for b < nb && !(mo[b] >= j && mt[b] >= k) {
+			b++
+		}
+		jo, ko := len(ours), len(theirs)
+		if b < nb {
+			jo, ko = mo[b], mt[b]
+		}
+		if b > i || jo > j || ko > k {
+			mg.addChunk(base[i:b], ours[j:jo], theirs[k:ko])
+		}
+		if b >= nb {
+			break
+		}
+		e := b + 1
+		for e < nb && mo[e] == jo+(e-b) && mt[e] == ko+(e-b) {
+			e++
+		}
+		mg.Chunks = append(mg.Chunks, &MergeChunk{Base: base[b:e], Ours: ours[jo : jo+(e-b)], Theirs: theirs[ko : ko+(e-b)]})
+		i, j, k = e, jo+(e-b), ko+(e-b)
+	}
+	return mg
+}
+
+// addChunk adds a chunk of changed lines
+func (mg *Merge) addChunk(base, ours, theirs []string) {
+	mc := &MergeChunk{Base: base, Ours: ours, Theirs: theirs}
+	if !equalLines(ours, base) && !equalLines(theirs, base) && !equalLines(ours, theirs) {
+		mc.Conflict = true
+	}
+	mg.Chunks = append(mg.Chunks, mc)
+}
+
+// HasConflictMarkers returns true if given lines contain conflict markers
+func HasConflictMarkers(lines []string) bool {
+	st := false
+	for _, ln := range lines {
+		switch {
+		case strings.HasPrefix(ln, MergeMarkerOurs):
+			st = true
+		case st && strings.HasPrefix(ln, MergeMarkerTheirs):
+			return true
+		}
+	}
+	return false
+}
+
+// ParseConflicts returns the merge represented by given lines of text
+// containing conflict markers, as written by version control systems --
+// the base lines of conflicts are known if all of them have base sections
+// (the diff3 style).  Returns false if there are no complete conflicts.
+func ParseConflicts(lines []string) (*Merge, bool) {
+	mg := &Merge{HasBase: true}
+	var same []string
+	var cur *MergeChunk
+	sect := MergeUnresolved // section of conflict: MergeOurs, MergeBase, MergeTheirs
+	nconf := 0
+	for _, ln := range lines {
+		switch {
+		case sect == MergeUnresolved && strings.HasPrefix(ln, MergeMarkerOurs):
+			if len(same) > 0 {
+				mg.Chunks = append(mg.Chunks, &MergeChunk{Base: same, Ours: same, Theirs: same})
+				same = nil
+			}
+			if mg.OursLabel == "" {
+				mg.OursLabel = strings.TrimSpace(ln[len(MergeMarkerOurs):])
+			}
+			cur = &MergeChunk{Conflict: true}
+			sect = MergeOurs
+		case sect == MergeOurs && strings.HasPrefix(ln, MergeMarkerBase):
+			if mg.BaseLabel == "" {
+				mg.BaseLabel = strings.TrimSpace(ln[len(MergeMarkerBase):])
+			}
+			sect = MergeBase
+		case (sect == MergeOurs || sect == MergeBase) && ln == MergeMarkerSep:
+			if sect == MergeOurs {
+				mg.HasBase = false
+			}
+			sect = MergeTheirs
+		case sect == MergeTheirs && strings.HasPrefix(ln, MergeMarkerTheirs):
+			if mg.TheirsLabel == "" {
+				mg.TheirsLabel = strings.TrimSpace(ln[len(MergeMarkerTheirs):])
+			}
+			mg.Chunks = append(mg.Chunks, cur)
+			cur = nil
+			sect = MergeUnresolved
+			nconf++
+		case sect == MergeOurs:
+			cur.Ours = append(cur.Ours, ln)
+		case sect == MergeBase:
+			cur.Base = append(cur.Base, ln)
+		case sect == MergeTheirs:
+			cur.Theirs = append(cur.Theirs, ln)
+		default:
+			same = append(same, ln)
+		}
+	}
+	if cur != nil { // incomplete conflict: keep its lines as is
+		same = append(same, mg.ChunkLines(cur)...)
+	}
+	if len(same) > 0 {
+		mg.Chunks = append(mg.Chunks, &MergeChunk{Base: same, Ours: same, Theirs: same})
+	}
+	if nconf == 0 {
+		return nil, false
+	}
+	return mg, true
+}
+
+// ChunkLines returns the merged lines of given chunk: the changed
+// version for a chunk that merges cleanly, and for a conflict, the version
+// given by its Choice, or the conflict with markers if unresolved
+func (mg *Merge) ChunkLines(mc *MergeChunk) []string {
+	if !mc.Conflict {
+		if equalLines(mc.Ours, mc.Base) {
+			return mc.Theirs
+		}
+		return mc.Ours
+	}
+	if mc.Choice != MergeUnresolved {
+		return mc.Version(mc.Choice)
+	}
+	lns := make([]string, 0, len(mc.Ours)+len(mc.Base)+len(mc.Theirs)+4)
+	lns = append(lns, strings.TrimSpace(MergeMarkerOurs+" "+mg.OursLabel))
+	lns = append(lns, mc.Ours...)
+	if mg.HasBase {
+		lns = append(lns, strings.TrimSpace(MergeMarkerBase+" "+mg.BaseLabel))
+		lns = append(lns, mc.Base...)
+	}
+	lns = append(lns, MergeMarkerSep)
+	lns = append(lns, mc.Theirs...)
+	return append(lns, strings.TrimSpace(MergeMarkerTheirs+" "+mg.TheirsLabel))
+}
+
+// Result returns the lines of the merged text, with the conflicts as
+// given by their Choice
+func (mg *Merge) Result() []string {
+	var lns []string
+	for _, mc := range mg.Chunks {
+		lns = append(lns, mg.ChunkLines(mc)...)
+	}
+	return lns
+}
+
+// Version returns the lines of the full text of given version:
+// MergeBase, MergeOurs or MergeTheirs
+func (mg *Merge) Version(ver MergeChoices) []string {
+	var lns []string
+	for _, mc := range mg.Chunks {
+		lns = append(lns, mc.Version(ver)...)
+	}
+	return lns
+}
+
+// NConflicts returns the number of conflicts, and how many of them are
+// unresolved
+func (mg *Merge) NConflicts() (nconf, nunres int) {
+	for _, mc := range mg.Chunks {
+		if mc.Conflict {
+			nconf++
+			if mc.Choice == MergeUnresolved {
+				nunres++
+			}
+		}
+	}
+	return
+}
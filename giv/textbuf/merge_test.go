@@ -0,0 +1,56 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package textbuf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMerge3(t *testing.T) {
+	base := strings.Split("a\nb\nc\nd\ne\nf", "\n")
+	ours := strings.Split("a\nB\nc\nd\ne\nF1", "\n")
+	theirs := strings.Split("a\nb\nc\nD\ne\nF2\ng", "\n")
+	mg := Merge3(base, ours, theirs)
+	if nc, nu := mg.NConflicts(); nc != 1 || nu != 1 {
+		t.Fatalf("expected 1 unresolved conflict, got: %d %d", nc, nu)
+	}
+	exp := "a\nB\nc\nD\ne\n<<<<<<< ours\nF1\n||||||| base\nf\n=======\nF2\ng\n>>>>>>> theirs"
+	if res := strings.Join(mg.Result(), "\n"); res != exp {
+		t.Errorf("merge result:\n%s\nexpected:\n%s", res, exp)
+	}
+	for _, ver := range []MergeChoices{MergeBase, MergeOurs, MergeTheirs} {
+		src := map[MergeChoices][]string{MergeBase: base, MergeOurs: ours, MergeTheirs: theirs}[ver]
+		if !equalLines(mg.Version(ver), src) {
+			t.Errorf("%v version: %v", ver, mg.Version(ver))
+		}
+	}
+	for _, mc := range mg.Chunks {
+		if mc.Conflict {
+			mc.Choice = MergeBoth
+		}
+	}
+	if res := strings.Join(mg.Result(), "\n"); res != "a\nB\nc\nD\ne\nF1\nF2\ng" {
+		t.Errorf("resolved as both: %q", res)
+	}
+
+	pm, ok := ParseConflicts(strings.Split(exp, "\n"))
+	if !ok || !pm.HasBase || pm.OursLabel != "ours" || pm.TheirsLabel != "theirs" {
+		t.Fatalf("ParseConflicts: %v %+v", ok, pm)
+	}
+	if res := strings.Join(pm.Result(), "\n"); res != exp {
+		t.Errorf("parsed merge result:\n%s", res)
+	}
+	if !equalLines(pm.Version(MergeTheirs), strings.Split("a\nB\nc\nD\ne\nF2\ng", "\n")) {
+		t.Errorf("parsed theirs version: %v", pm.Version(MergeTheirs))
+	}
+	pm, ok = ParseConflicts([]string{"x", "<<<<<<< HEAD", "1", "=======", "2", ">>>>>>> branch", "y"})
+	if !ok || pm.HasBase || len(pm.Chunks) != 3 || pm.TheirsLabel != "branch" {
+		t.Errorf("ParseConflicts without base: %v %+v", ok, pm)
+	}
+	if _, ok := ParseConflicts([]string{"no", "conflicts"}); ok {
+		t.Errorf("ParseConflicts found conflicts in plain text")
+	}
+}
@@ -15,9 +15,11 @@ import (
 // conditionalize editing of a given text file
 type Opts struct {
 	gi.EditorPrefs `desc:"editor prefs from gogi prefs"`
-	CommentLn      string `desc:"character(s) that start a single-line comment -- if empty then multi-line comment syntax will be used"`
-	CommentSt      string `desc:"character(s) that start a multi-line comment or one that requires both start and end"`
-	CommentEd      string `desc:"character(s) that end a multi-line comment or one that requires both start and end"`
+	CommentLn      string    `desc:"character(s) that start a single-line comment -- if empty then multi-line comment syntax will be used"`
+	CommentSt      string    `desc:"character(s) that start a multi-line comment or one that requires both start and end"`
+	CommentEd      string    `desc:"character(s) that end a multi-line comment or one that requires both start and end"`
+	Encoding       Encodings `desc:"character encoding of the file, detected when it is opened and used when it is saved -- text is always UTF-8 in the buffer"`
+	LineEnds       LineEnds  `desc:"line endings of the file, detected when it is opened and used when it is saved -- lines always end in LF in the buffer"`
 }
 
 // CommentStrs returns the comment start and end strings, using line-based CommentLn first if set
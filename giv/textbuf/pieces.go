@@ -0,0 +1,383 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package textbuf
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+)
+
+// ByteLines is a source of lines of text, which are read one at a time as
+// needed, so that the lines of a large text do not all have to be in memory
+// as separate slices -- PieceTable is the main implementation.  The
+// returned lines must not be modified.
+type ByteLines interface {
+	// NumLines returns the number of lines
+	NumLines() int
+
+	// Line returns the text of given line, without the ending newline
+	Line(ln int) []byte
+}
+
+// LinesBytes is a ByteLines for lines of bytes that are all in memory
+type LinesBytes [][]byte
+
+// NumLines returns the number of lines
+func (lb LinesBytes) NumLines() int {
+	return len(lb)
+}
+
+// Line returns the text of given line
+func (lb LinesBytes) Line(ln int) []byte {
+	return lb[ln]
+}
+
+// PieceTable represents text as a sequence of pieces, each of which refers
+// to a span of either the original text, which is never modified, or of an
+// append-only buffer holding all inserted text.  Edits thus only modify the
+// (typically short) list of pieces, and opening a large text does not copy
+// it.  Lines are indexed lazily: the positions of newlines in each
+// underlying buffer are only found as far as needed to locate requested
+// lines.  Reading methods are safe to call concurrently with each other,
+// but edits must be serialized with respect to all other calls.
+type PieceTable struct {
+	orig     ptBuf
+	add      ptBuf
+	pieces   []piece
+	cumBytes []int // starting byte offset of each piece, plus total at end
+	cumLines []int // number of newlines before each piece, plus total at end
+	ncum     int   // number of leading valid entries in cum slices
+	size     int
+	nlines   int // total number of newlines
+	mu       sync.Mutex
+}
+
+// piece is one span of text in a PieceTable
+type piece struct {
+	add bool // in add buffer, else in orig
+	off int  // starting byte offset in buffer
+	len int  // number of bytes
+	nl  int  // number of newlines
+}
+
+// ptBuf is one of the underlying buffers of a PieceTable, with a lazily
+// built index of the positions of its newlines
+type ptBuf struct {
+	data []byte
+	nls  []int // positions of newlines in data[:scan]
+	scan int
+	nnl  int // number of newlines in data if known, to allocate nls once
+}
+
+// NewPieceTable returns a new PieceTable with given original text, which
+// is used directly and must not be modified afterward.
+func NewPieceTable(txt []byte) *PieceTable {
+	pt := &PieceTable{}
+	pt.orig.data = txt
+	pt.size = len(txt)
+	if pt.size > 0 {
+		pt.nlines = bytes.Count(txt, []byte("\n"))
+		pt.orig.nnl = pt.nlines
+		pt.pieces = []piece{{off: 0, len: pt.size, nl: pt.nlines}}
+	}
+	return pt
+}
+
+// Len returns the total number of bytes of text
+func (pt *PieceTable) Len() int {
+	return pt.size
+}
+
+// NumLines returns the number of lines of text -- each newline ends a
+// line, and any text after the last newline counts as a final line.
+func (pt *PieceTable) NumLines() int {
+	if pt.size == 0 || pt.endsInNewline() {
+		return pt.nlines
+	}
+	return pt.nlines + 1
+}
+
+// NumPieces returns the current number of pieces that the text is split into
+func (pt *PieceTable) NumPieces() int {
+	return len(pt.pieces)
+}
+
+// Bytes returns a copy of the entire text
+func (pt *PieceTable) Bytes() []byte {
+	txt := make([]byte, 0, pt.size)
+	for _, p := range pt.pieces {
+		txt = append(txt, pt.pieceBytes(p)...)
+	}
+	return txt
+}
+
+// Slice returns the text between given starting and ending byte offsets.
+// If the text is all within one piece, the returned slice directly refers
+// to the underlying buffer, so it must not be modified.
+func (pt *PieceTable) Slice(st, ed int) []byte {
+	if st < 0 {
+		st = 0
+	}
+	if ed > pt.size {
+		ed = pt.size
+	}
+	if st >= ed {
+		return nil
+	}
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	i := pt.pieceAt(st)
+	p := pt.pieces[i]
+	rel := st - pt.cumBytes[i]
+	if ed <= pt.cumBytes[i]+p.len {
+		data := pt.buf(p.add).data
+		bst := p.off + rel
+		bed := bst + ed - st
+		return data[bst:bed:bed]
+	}
+	txt := make([]byte, 0, ed-st)
+	txt = append(txt, pt.pieceBytes(p)[rel:]...)
+	for i++; i < len(pt.pieces) && pt.cumBytes[i] < ed; i++ {
+		pb := pt.pieceBytes(pt.pieces[i])
+		if n := ed - pt.cumBytes[i]; n < len(pb) {
+			pb = pb[:n]
+		}
+		txt = append(txt, pb...)
+	}
+	return txt
+}
+
+// LineStart returns the byte offset of the start of given line -- 0 for
+// ln <= 0, and Len() for lines beyond the last one.
+func (pt *PieceTable) LineStart(ln int) int {
+	if ln <= 0 {
+		return 0
+	}
+	if ln > pt.nlines {
+		return pt.size
+	}
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	pt.updateCum()
+	np := len(pt.pieces)
+	i := sort.Search(np, func(i int) bool {
+		return pt.cumLines[i+1] >= ln
+	})
+	p := pt.pieces[i]
+	pos := pt.buf(p.add).newline(p.off, ln-pt.cumLines[i])
+	return pt.cumBytes[i] + (pos - p.off) + 1
+}
+
+// Line returns the text of given line, without the ending newline, or nil
+// if the line is out of range.  As with Slice, the result must not be
+// modified.
+func (pt *PieceTable) Line(ln int) []byte {
+	if ln < 0 || ln >= pt.NumLines() {
+		return nil
+	}
+	st := pt.LineStart(ln)
+	ed := pt.size
+	if ln < pt.nlines {
+		ed = pt.LineStart(ln+1) - 1
+	}
+	return pt.Slice(st, ed)
+}
+
+// Insert inserts given text at given byte offset
+func (pt *PieceTable) Insert(off int, txt []byte) {
+	if len(txt) == 0 {
+		return
+	}
+	if off < 0 {
+		off = 0
+	}
+	if off > pt.size {
+		off = pt.size
+	}
+	aoff := len(pt.add.data)
+	pt.add.data = append(pt.add.data, txt...)
+	nl := bytes.Count(txt, []byte("\n"))
+	i := pt.splitAt(off)
+	if i > 0 {
+		prv := &pt.pieces[i-1]
+		if prv.add && prv.off+prv.len == aoff { // continues last insert, e.g., typing
+			prv.len += len(txt)
+			prv.nl += nl
+			pt.invalidate(i - 1)
+			pt.size += len(txt)
+			pt.nlines += nl
+			return
+		}
+	}
+	pt.pieces = append(pt.pieces, piece{})
+	copy(pt.pieces[i+1:], pt.pieces[i:])
+	pt.pieces[i] = piece{add: true, off: aoff, len: len(txt), nl: nl}
+	pt.invalidate(i)
+	pt.size += len(txt)
+	pt.nlines += nl
+}
+
+// Delete deletes n bytes of text starting at given byte offset
+func (pt *PieceTable) Delete(off, n int) {
+	if off < 0 {
+		n += off
+		off = 0
+	}
+	if off+n > pt.size {
+		n = pt.size - off
+	}
+	if n <= 0 {
+		return
+	}
+	i := pt.splitAt(off)
+	j := pt.splitAt(off + n)
+	nl := 0
+	for _, p := range pt.pieces[i:j] {
+		nl += p.nl
+	}
+	pt.pieces = append(pt.pieces[:i], pt.pieces[j:]...)
+	pt.invalidate(i)
+	pt.size -= n
+	pt.nlines -= nl
+}
+
+// Replace replaces n bytes of text starting at given byte offset with
+// given text
+func (pt *PieceTable) Replace(off, n int, txt []byte) {
+	pt.Delete(off, n)
+	pt.Insert(off, txt)
+}
+
+// endsInNewline returns true if the last byte of text is a newline
+func (pt *PieceTable) endsInNewline() bool {
+	np := len(pt.pieces)
+	if np == 0 {
+		return false
+	}
+	p := pt.pieces[np-1]
+	return pt.buf(p.add).data[p.off+p.len-1] == '\n'
+}
+
+// buf returns the add or orig buffer
+func (pt *PieceTable) buf(add bool) *ptBuf {
+	if add {
+		return &pt.add
+	}
+	return &pt.orig
+}
+
+// pieceBytes returns the text of given piece
+func (pt *PieceTable) pieceBytes(p piece) []byte {
+	return pt.buf(p.add).data[p.off : p.off+p.len]
+}
+
+// invalidate marks the cumulative offsets after piece i as needing update
+func (pt *PieceTable) invalidate(i int) {
+	if pt.ncum > i+1 {
+		pt.ncum = i + 1
+	}
+}
+
+// updateCum updates the cumulative offsets of pieces as needed
+func (pt *PieceTable) updateCum() {
+	np := len(pt.pieces)
+	if pt.ncum == np+1 {
+		return
+	}
+	if cap(pt.cumBytes) < np+1 {
+		cb := make([]int, np+1, 2*np+2)
+		copy(cb, pt.cumBytes[:pt.ncum])
+		pt.cumBytes = cb
+		cl := make([]int, np+1, 2*np+2)
+		copy(cl, pt.cumLines[:pt.ncum])
+		pt.cumLines = cl
+	} else {
+		pt.cumBytes = pt.cumBytes[:np+1]
+		pt.cumLines = pt.cumLines[:np+1]
+	}
+	if pt.ncum == 0 {
+		pt.cumBytes[0] = 0
+		pt.cumLines[0] = 0
+		pt.ncum = 1
+	}
+	for i := pt.ncum - 1; i < np; i++ {
+		pt.cumBytes[i+1] = pt.cumBytes[i] + pt.pieces[i].len
+		pt.cumLines[i+1] = pt.cumLines[i] + pt.pieces[i].nl
+	}
+	pt.ncum = np + 1
+}
+
+// pieceAt returns the index of the piece containing given byte offset,
+// which must be < size.  Must be called under mutex lock.
+func (pt *PieceTable) pieceAt(off int) int {
+	pt.updateCum()
+	return sort.Search(len(pt.pieces), func(i int) bool {
+		return pt.cumBytes[i+1] > off
+	})
+}
+
+// splitAt ensures that a piece starts at given byte offset, splitting the
+// piece containing it as needed, and returns the index of that piece
+// (which is the number of pieces if off is at the end).
+func (pt *PieceTable) splitAt(off int) int {
+	if off >= pt.size {
+		return len(pt.pieces)
+	}
+	i := pt.pieceAt(off)
+	rel := off - pt.cumBytes[i]
+	if rel == 0 {
+		return i
+	}
+	p := pt.pieces[i]
+	lp := piece{add: p.add, off: p.off, len: rel}
+	rp := piece{add: p.add, off: p.off + rel, len: p.len - rel}
+	if lp.len <= rp.len { // count the smaller side
+		lp.nl = bytes.Count(pt.pieceBytes(lp), []byte("\n"))
+		rp.nl = p.nl - lp.nl
+	} else {
+		rp.nl = bytes.Count(pt.pieceBytes(rp), []byte("\n"))
+		lp.nl = p.nl - rp.nl
+	}
+	pt.pieces = append(pt.pieces, piece{})
+	copy(pt.pieces[i+2:], pt.pieces[i+1:])
+	pt.pieces[i] = lp
+	pt.pieces[i+1] = rp
+	pt.invalidate(i)
+	return i + 1
+}
+
+// newline returns the position of the k'th (starting at 1) newline at or
+// after given offset, which must exist, extending the index as needed
+func (b *ptBuf) newline(off, k int) int {
+	if b.nls == nil && b.nnl > 0 {
+		b.nls = make([]int, 0, b.nnl)
+	}
+	b.indexTo(off)
+	i := sort.SearchInts(b.nls, off)
+	for len(b.nls) < i+k && b.scan < len(b.data) {
+		p := bytes.IndexByte(b.data[b.scan:], '\n')
+		if p < 0 {
+			b.scan = len(b.data)
+			break
+		}
+		b.nls = append(b.nls, b.scan+p)
+		b.scan += p + 1
+	}
+	return b.nls[i+k-1]
+}
+
+// indexTo extends the index of newlines through given offset
+func (b *ptBuf) indexTo(off int) {
+	for b.scan < off {
+		p := bytes.IndexByte(b.data[b.scan:off], '\n')
+		if p < 0 {
+			b.scan = off
+			return
+		}
+		b.nls = append(b.nls, b.scan+p)
+		b.scan += p + 1
+	}
+}
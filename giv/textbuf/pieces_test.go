@@ -0,0 +1,96 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package textbuf
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/goki/ki/ints"
+)
+
+// checkPieceTable checks that all lines of the piece table match the reference text
+func checkPieceTable(t *testing.T, pt *PieceTable, ref []byte) {
+	t.Helper()
+	if got := pt.Bytes(); !bytes.Equal(got, ref) {
+		t.Fatalf("text mismatch:\ngot: %q\nexp: %q", got, ref)
+	}
+	lns := bytes.Split(ref, []byte("\n"))
+	if len(lns[len(lns)-1]) == 0 {
+		lns = lns[:len(lns)-1]
+	}
+	if pt.NumLines() != len(lns) {
+		t.Fatalf("NumLines: got %d, expected %d", pt.NumLines(), len(lns))
+	}
+	off := 0
+	for ln, l := range lns {
+		if st := pt.LineStart(ln); st != off {
+			t.Fatalf("LineStart(%d): got %d, expected %d", ln, st, off)
+		}
+		if got := pt.Line(ln); !bytes.Equal(got, l) {
+			t.Fatalf("Line(%d): got %q, expected %q", ln, got, l)
+		}
+		off += len(l) + 1
+	}
+}
+
+func TestPieceTable(t *testing.T) {
+	ref := []byte("one\ntwo\n\nfour\nfive")
+	pt := NewPieceTable(append([]byte{}, ref...))
+	checkPieceTable(t, pt, ref)
+
+	rnd := rand.New(rand.NewSource(1))
+	ins := [][]byte{[]byte("x"), []byte("\n"), []byte("ab\ncd"), []byte("\n\n"), []byte("long line of text")}
+	for i := 0; i < 500; i++ {
+		off := rnd.Intn(len(ref) + 1)
+		if rnd.Intn(3) == 0 && len(ref) > 0 {
+			n := rnd.Intn(ints.MinInt(len(ref)-off, 8) + 1)
+			pt.Delete(off, n)
+			ref = append(ref[:off:off], ref[off+n:]...)
+		} else {
+			txt := ins[rnd.Intn(len(ins))]
+			pt.Insert(off, txt)
+			nref := append([]byte{}, ref[:off]...)
+			nref = append(nref, txt...)
+			ref = append(nref, ref[off:]...)
+		}
+		checkPieceTable(t, pt, ref)
+	}
+}
+
+// largeText returns text with given number of lines of typical code length
+func largeText(nlines int) []byte {
+	var b bytes.Buffer
+	for ln := 0; ln < nlines; ln++ {
+		fmt.Fprintf(&b, "\tfmt.Printf(\"line number: %d with some more text\\n\", %d)\n", ln, ln)
+	}
+	return b.Bytes()
+}
+
+func BenchmarkPieceTableOpen(b *testing.B) {
+	txt := largeText(1000000)
+	b.SetBytes(int64(len(txt)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pt := NewPieceTable(txt)
+		pt.Line(pt.NumLines() / 2)
+	}
+}
+
+func BenchmarkPieceTableEdit(b *testing.B) {
+	txt := largeText(1000000)
+	pt := NewPieceTable(txt)
+	rnd := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ln := rnd.Intn(pt.NumLines() - 1)
+		off := pt.LineStart(ln) + 4
+		pt.Insert(off, []byte("edit\n"))
+		pt.Delete(pt.LineStart(ln+1), 2)
+		pt.Line(ln)
+	}
+}
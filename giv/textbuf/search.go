@@ -7,6 +7,7 @@ package textbuf
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"io"
 	"log"
 	"os"
@@ -69,33 +70,42 @@ const (
 // and specific match position list.  Column positions are in runes.
 func SearchRuneLines(src [][]rune, find []byte, ignoreCase bool) (int, []Match) {
 	fr := bytes.Runes(find)
-	fsz := len(fr)
-	if fsz == 0 {
+	if len(fr) == 0 {
 		return 0, nil
 	}
-	cnt := 0
 	var matches []Match
 	for ln, rn := range src {
-		sz := len(rn)
-		ci := 0
-		for ci < sz {
-			var i int
-			if ignoreCase {
-				i = runes.IndexFold(rn[ci:], fr)
-			} else {
-				i = runes.Index(rn[ci:], fr)
-			}
-			if i < 0 {
-				break
-			}
-			i += ci
-			ci = i + fsz
-			mat := NewMatch(rn, i, ci, ln)
-			matches = append(matches, mat)
-			cnt++
+		matches = SearchRuneLine(matches, rn, ln, fr, ignoreCase)
+	}
+	return len(matches), matches
+}
+
+// SearchRuneLine appends to matches the matches of a string (no regexp),
+// given as runes, within given line of runes, which is line ln, with given
+// case-sensitivity -- for searching lines one at a time.  Column positions
+// are in runes.
+func SearchRuneLine(matches []Match, rn []rune, ln int, fr []rune, ignoreCase bool) []Match {
+	fsz := len(fr)
+	if fsz == 0 {
+		return matches
+	}
+	sz := len(rn)
+	ci := 0
+	for ci < sz {
+		var i int
+		if ignoreCase {
+			i = runes.IndexFold(rn[ci:], fr)
+		} else {
+			i = runes.Index(rn[ci:], fr)
+		}
+		if i < 0 {
+			break
 		}
+		i += ci
+		ci = i + fsz
+		matches = append(matches, NewMatch(rn, i, ci, ln))
 	}
-	return cnt, matches
+	return matches
 }
 
 // SearchLexItems looks for a string (no regexp),
@@ -104,37 +114,44 @@ func SearchRuneLines(src [][]rune, find []byte, ignoreCase bool) (int, []Match)
 // and specific match position list.  Column positions are in runes.
 func SearchLexItems(src [][]rune, lexs []lex.Line, find []byte, ignoreCase bool) (int, []Match) {
 	fr := bytes.Runes(find)
-	fsz := len(fr)
-	if fsz == 0 {
+	if len(fr) == 0 {
 		return 0, nil
 	}
-	cnt := 0
 	var matches []Match
 	mx := ints.MinInt(len(src), len(lexs))
 	for ln := 0; ln < mx; ln++ {
-		rln := src[ln]
-		lxln := lexs[ln]
-		for _, lx := range lxln {
-			sz := lx.Ed - lx.St
-			if sz != fsz {
-				continue
-			}
-			rn := rln[lx.St:lx.Ed]
-			var i int
-			if ignoreCase {
-				i = runes.IndexFold(rn, fr)
-			} else {
-				i = runes.Index(rn, fr)
-			}
-			if i < 0 {
-				continue
-			}
-			mat := NewMatch(rln, lx.St, lx.Ed, ln)
-			matches = append(matches, mat)
-			cnt++
+		matches = SearchLexLine(matches, src[ln], lexs[ln], ln, fr, ignoreCase)
+	}
+	return len(matches), matches
+}
+
+// SearchLexLine appends to matches the lexically tagged items of given line
+// of runes, which is line ln, that are a string (no regexp), given as runes,
+// with given case-sensitivity -- for searching lines one at a time.  Column
+// positions are in runes.
+func SearchLexLine(matches []Match, rln []rune, lxln lex.Line, ln int, fr []rune, ignoreCase bool) []Match {
+	fsz := len(fr)
+	if fsz == 0 {
+		return matches
+	}
+	for _, lx := range lxln {
+		sz := lx.Ed - lx.St
+		if sz != fsz {
+			continue
+		}
+		rn := rln[lx.St:lx.Ed]
+		var i int
+		if ignoreCase {
+			i = runes.IndexFold(rn, fr)
+		} else {
+			i = runes.Index(rn, fr)
+		}
+		if i < 0 {
+			continue
 		}
+		matches = append(matches, NewMatch(rln, lx.St, lx.Ed, ln))
 	}
-	return cnt, matches
+	return matches
 }
 
 // Search looks for a string (no regexp) from an io.Reader input stream,
@@ -253,30 +270,120 @@ func SearchFileRegexp(filename string, re *regexp.Regexp) (int, []Match) {
 // with given case-sensitivity returning number of occurrences
 // and specific match position list.  Column positions are in runes.
 func SearchByteLinesRegexp(src [][]byte, re *regexp.Regexp) (int, []Match) {
-	cnt := 0
 	var matches []Match
 	for ln, b := range src {
-		fi := re.FindAllIndex(b, -1)
-		if fi == nil {
+		matches = SearchByteLineRegexp(matches, b, ln, re)
+	}
+	return len(matches), matches
+}
+
+// SearchByteLineRegexp appends to matches the matches of a regexp within
+// given line of bytes, which is line ln -- for searching lines one at a
+// time.  Column positions are in runes.
+func SearchByteLineRegexp(matches []Match, b []byte, ln int, re *regexp.Regexp) []Match {
+	fi := re.FindAllIndex(b, -1)
+	if fi == nil {
+		return matches
+	}
+	sz := len(b)
+	ri := make([]int, sz+1) // byte indexes to rune indexes
+	rn := make([]rune, 0, sz)
+	for i, w := 0, 0; i < sz; i += w {
+		r, wd := utf8.DecodeRune(b[i:])
+		w = wd
+		ri[i] = len(rn)
+		rn = append(rn, r)
+	}
+	ri[sz] = len(rn)
+	for _, f := range fi {
+		matches = append(matches, NewMatch(rn, ri[f[0]], ri[f[1]], ln))
+	}
+	return matches
+}
+
+// SearchRegexpFor returns a regexp for searching for given text, which is
+// a regular expression if useRegexp is true, and literal text otherwise.
+// wholeWord only matches at word boundaries, and ignoreCase matches
+// regardless of case.
+func SearchRegexpFor(find string, useRegexp, wholeWord, ignoreCase bool) (*regexp.Regexp, error) {
+	if find == "" {
+		return nil, errors.New("textbuf.SearchRegexpFor: nothing to find")
+	}
+	if !useRegexp {
+		find = regexp.QuoteMeta(find)
+	}
+	if wholeWord {
+		find = `\b(?:` + find + `)\b`
+	}
+	if ignoreCase {
+		find = `(?i)` + find
+	}
+	return regexp.Compile(find)
+}
+
+// ReplaceMatches returns the replacement text for each of given matches
+// within the lines of src, which must have been found by
+// SearchByteLinesRegexp with given regexp on the same lines.  If expand is
+// true, $1 etc in repl are expanded using the submatches of each match, as
+// in regexp.Expand -- otherwise repl is used literally.
+func ReplaceMatches(src [][]byte, re *regexp.Regexp, matches []Match, repl []byte, expand bool) [][]byte {
+	repls := make([][]byte, len(matches))
+	var sms [][]int
+	cln := -1
+	mi := 0
+	for i, m := range matches {
+		if !expand {
+			repls[i] = repl
 			continue
 		}
-		sz := len(b)
-		ri := make([]int, sz+1) // byte indexes to rune indexes
-		rn := make([]rune, 0, sz)
-		for i, w := 0, 0; i < sz; i += w {
-			r, wd := utf8.DecodeRune(b[i:])
-			w = wd
-			ri[i] = len(rn)
-			rn = append(rn, r)
+		ln := m.Reg.Start.Ln
+		if ln >= len(src) {
+			repls[i] = repl
+			continue
 		}
-		ri[sz] = len(rn)
-		for _, f := range fi {
-			st := f[0]
-			ed := f[1]
-			mat := NewMatch(rn, ri[st], ri[ed], ln)
-			matches = append(matches, mat)
-			cnt++
+		if ln != cln {
+			sms = re.FindAllSubmatchIndex(src[ln], -1)
+			cln = ln
+			mi = 0
+		}
+		if mi < len(sms) {
+			repls[i] = re.Expand(nil, repl, src[ln], sms[mi])
+		} else {
+			repls[i] = repl
 		}
+		mi++
 	}
-	return cnt, matches
+	return repls
+}
+
+// ReplaceLines replaces the text at given regions of src with the
+// corresponding repls, returning the number of replacements made.  Regions
+// must each be within a single line, with column positions in runes, and
+// in order without overlapping, as returned by the Search functions.
+// Only the lines with replacements are changed in src.
+func ReplaceLines(src [][]byte, regs []Region, repls [][]byte) int {
+	n := 0
+	for i := len(regs) - 1; i >= 0; {
+		ln := regs[i].Start.Ln
+		if ln >= len(src) {
+			i--
+			continue
+		}
+		rn := bytes.Runes(src[ln])
+		for ; i >= 0 && regs[i].Start.Ln == ln; i-- {
+			st := regs[i].Start.Ch
+			ed := regs[i].End.Ch
+			if st > ed || ed > len(rn) {
+				continue
+			}
+			nrn := make([]rune, 0, len(rn)+len(repls[i]))
+			nrn = append(nrn, rn[:st]...)
+			nrn = append(nrn, bytes.Runes(repls[i])...)
+			nrn = append(nrn, rn[ed:]...)
+			rn = nrn
+			n++
+		}
+		src[ln] = []byte(string(rn))
+	}
+	return n
 }
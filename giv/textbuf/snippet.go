@@ -0,0 +1,265 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package textbuf
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/goki/pi/lex"
+)
+
+// SnippetField is a field of an expanded snippet: a tab stop, which can
+// have placeholder text or a list of choices
+type SnippetField struct {
+	Num     int      `desc:"tab stop number -- fields are visited in order of number, with 0, the final cursor position, last"`
+	Reg     Region   `desc:"region of the text of the field, relative to the start of the snippet: lines count from 0, and the characters of the first line from the start of the snippet"`
+	Choices []string `desc:"choices for the text of the field, if any"`
+	Mirror  bool     `desc:"the field mirrors the text of the first field with the same number, which is edited"`
+	Parent  int      `desc:"index of the field that contains this one in its placeholder, or -1 if none"`
+}
+
+// ExpandSnippet expands the body of a snippet, which has the syntax of
+// TextMate and VS Code snippets: $1 or ${1:placeholder} for tab stops, ${1|one,two|}
+// for a choice, $0 for the final cursor position, $NAME or ${NAME:default}
+// for variables, with values returned by vars (which can be nil), and \ to
+// escape $ } and \.  Lines after the first are indented with indent, and
+// tabs at the start of lines are replaced with tab, for the indentation
+// of the buffer.  Returns the text and its fields, in order of position
+// (with fields before those they contain), where later fields with the number of an earlier one mirror it, and have
+// the same text.  There is always a field 0, at the end by default.
+func ExpandSnippet(body, indent, tab string, vars func(name string) (string, bool)) (string, []SnippetField) {
+	nodes, _ := parseSnippet([]rune(body), 0, false)
+	se := &snipExpander{indent: indent, tab: tab, vars: vars, defs: map[int][]snipNode{}, seen: map[int]bool{}, lineSt: true}
+	se.findDefs(nodes)
+	se.render(nodes, true, -1)
+	if !se.seen[0] {
+		pos := se.pos()
+		se.fields = append(se.fields, SnippetField{Num: 0, Reg: Region{Start: pos, End: pos}, Parent: -1})
+	}
+	return se.sb.String(), se.fields
+}
+
+// snipNode is a node of a parsed snippet body: text, a field or a variable
+type snipNode struct {
+	text    string     // text, if not a field or variable
+	num     int        // field number, or -1 if not a field
+	name    string     // variable name, if a variable
+	kids    []snipNode // placeholder of a field or default of a variable
+	choices []string   // choices of a field
+}
+
+// parseSnippet parses the snippet body from index i, until a } that ends
+// a placeholder if inPlace, returning the nodes and the index after them
+func parseSnippet(rs []rune, i int, inPlace bool) ([]snipNode, int) {
+	var nodes []snipNode
+	var txt []rune
+	addText := func() {
+		if len(txt) > 0 {
+			nodes = append(nodes, snipNode{text: string(txt), num: -1})
+			txt = nil
+		}
+	}
+	for i < len(rs) {
+		r := rs[i]
+		switch {
+		case r == '\\' && i+1 < len(rs) && strings.ContainsRune(`$}\`, rs[i+1]):
+			txt = append(txt, rs[i+1])
+			i += 2
+		case r == '}' && inPlace:
+			addText()
+			return nodes, i
+		case r == '$' && i+1 < len(rs) && unicode.IsDigit(rs[i+1]):
+			addText()
+			num, ni := snipNumber(rs, i+1)
+			nodes = append(nodes, snipNode{num: num})
+			i = ni
+		case r == '$' && i+1 < len(rs) && snipNameStart(rs[i+1]):
+			addText()
+			name, ni := snipName(rs, i+1)
+			nodes = append(nodes, snipNode{num: -1, name: name})
+			i = ni
+		case r == '$' && i+2 < len(rs) && rs[i+1] == '{' && (unicode.IsDigit(rs[i+2]) || snipNameStart(rs[i+2])):
+			addText()
+			nd, ni := parseSnippetBraced(rs, i+2)
+			nodes = append(nodes, nd)
+			i = ni
+		default:
+			txt = append(txt, r)
+			i++
+		}
+	}
+	addText()
+	return nodes, i
+}
+
+// parseSnippetBraced parses a field or variable in braces from index i,
+// after the ${, returning the node and the index after the closing }
+func parseSnippetBraced(rs []rune, i int) (snipNode, int) {
+	nd := snipNode{num: -1}
+	if unicode.IsDigit(rs[i]) {
+		nd.num, i = snipNumber(rs, i)
+	} else {
+		nd.name, i = snipName(rs, i)
+	}
+	if i >= len(rs) {
+		return nd, i
+	}
+	switch rs[i] {
+	case ':':
+		nd.kids, i = parseSnippet(rs, i+1, true)
+	case '|':
+		if nd.num < 0 {
+			break
+		}
+		var ch []rune
+		for i++; i < len(rs); i++ {
+			r := rs[i]
+			if r == '\\' && i+1 < len(rs) && strings.ContainsRune(`,|\$}`, rs[i+1]) {
+				ch = append(ch, rs[i+1])
+				i++
+				continue
+			}
+			if r == ',' || r == '|' {
+				nd.choices = append(nd.choices, string(ch))
+				ch = nil
+				if r == '|' {
+					i++
+					break
+				}
+				continue
+			}
+			ch = append(ch, r)
+		}
+	case '/': // transforms are not supported: the text is not transformed
+		for esc := false; i < len(rs) && (esc || rs[i] != '}'); i++ {
+			esc = !esc && rs[i] == '\\'
+		}
+	}
+	for i < len(rs) && rs[i] != '}' { // skip anything unexpected
+		i++
+	}
+	return nd, i + 1
+}
+
+// snipNumber returns the number at index i, and the index after it
+func snipNumber(rs []rune, i int) (int, int) {
+	n := 0
+	for ; i < len(rs) && unicode.IsDigit(rs[i]); i++ {
+		n = n*10 + int(rs[i]-'0')
+	}
+	return n, i
+}
+
+// snipNameStart returns true if r starts a variable name
+func snipNameStart(r rune) bool {
+	return r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+// snipName returns the variable name at index i, and the index after it
+func snipName(rs []rune, i int) (string, int) {
+	st := i
+	for ; i < len(rs) && (snipNameStart(rs[i]) || unicode.IsDigit(rs[i])); i++ {
+	}
+	return string(rs[st:i]), i
+}
+
+// snipExpander renders parsed snippet nodes into text and fields
+type snipExpander struct {
+	indent, tab string
+	vars        func(name string) (string, bool)
+	defs        map[int][]snipNode // placeholder for each field number
+	seen        map[int]bool       // field numbers already rendered
+	sb          strings.Builder
+	ln, ch      int  // current position, relative to the start
+	lineSt      bool // at the start of a line, where tabs are indentation
+	fields      []SnippetField
+}
+
+// findDefs finds the placeholder for each field number: the first one
+// with placeholder text or choices
+func (se *snipExpander) findDefs(nodes []snipNode) {
+	for _, nd := range nodes {
+		if nd.num < 0 {
+			se.findDefs(nd.kids)
+			continue
+		}
+		if _, has := se.defs[nd.num]; has {
+			continue
+		}
+		switch {
+		case len(nd.choices) > 0:
+			se.defs[nd.num] = []snipNode{{text: nd.choices[0], num: -1}}
+		case len(nd.kids) > 0:
+			se.defs[nd.num] = nd.kids
+			se.findDefs(nd.kids)
+		}
+	}
+}
+
+// pos returns the current position
+func (se *snipExpander) pos() lex.Pos {
+	return lex.Pos{Ln: se.ln, Ch: se.ch}
+}
+
+// write writes text, indenting lines after the first
+func (se *snipExpander) write(txt string) {
+	for _, r := range txt {
+		switch {
+		case r == '\n':
+			se.sb.WriteRune('\n')
+			se.sb.WriteString(se.indent)
+			se.ln++
+			se.ch = len([]rune(se.indent))
+			se.lineSt = true
+		case r == '\t' && se.lineSt:
+			se.sb.WriteString(se.tab)
+			se.ch += len([]rune(se.tab))
+		default:
+			se.sb.WriteRune(r)
+			se.ch++
+			se.lineSt = false
+		}
+	}
+}
+
+// render renders nodes, within the field at index par, recording their
+// fields if record -- fields within a mirror are not recorded
+func (se *snipExpander) render(nodes []snipNode, record bool, par int) {
+	for _, nd := range nodes {
+		switch {
+		case nd.num >= 0:
+			mirror := se.seen[nd.num]
+			se.seen[nd.num] = true
+			if !record {
+				se.render(se.defs[nd.num], false, par)
+				continue
+			}
+			idx := len(se.fields)
+			fld := SnippetField{Num: nd.num, Reg: Region{Start: se.pos()}, Mirror: mirror, Parent: par}
+			if !mirror {
+				fld.Choices = nd.choices
+			}
+			se.fields = append(se.fields, fld)
+			se.render(se.defs[nd.num], !mirror, idx)
+			se.fields[idx].Reg.End = se.pos()
+		case nd.name != "":
+			val, ok := "", false
+			if se.vars != nil {
+				val, ok = se.vars(nd.name)
+			}
+			switch {
+			case val != "":
+				se.write(val)
+			case len(nd.kids) > 0:
+				se.render(nd.kids, record, par)
+			case !ok:
+				se.write(nd.name) // unknown variables are their names
+			}
+		default:
+			se.write(nd.text)
+		}
+	}
+}
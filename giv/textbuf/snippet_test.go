@@ -0,0 +1,62 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package textbuf
+
+import (
+	"testing"
+
+	"github.com/goki/pi/lex"
+)
+
+func TestExpandSnippet(t *testing.T) {
+	vars := func(name string) (string, bool) {
+		switch name {
+		case "TM_FILENAME":
+			return "main.go", true
+		case "TM_SELECTED_TEXT":
+			return "", true
+		}
+		return "", false
+	}
+	txt, flds := ExpandSnippet("for ${1:i} := 0; $1 < ${2:n}; $1++ {\n\t$0\n}", "  ", "\t", vars)
+	if exp := "for i := 0; i < n; i++ {\n  \t\n  }"; txt != exp {
+		t.Errorf("text: %q, expected: %q", txt, exp)
+	}
+	type fld struct {
+		num    int
+		st, ed lex.Pos
+		mirror bool
+	}
+	exp := []fld{
+		{1, lex.Pos{Ch: 4}, lex.Pos{Ch: 5}, false},
+		{1, lex.Pos{Ch: 12}, lex.Pos{Ch: 13}, true},
+		{2, lex.Pos{Ch: 16}, lex.Pos{Ch: 17}, false},
+		{1, lex.Pos{Ch: 19}, lex.Pos{Ch: 20}, true},
+		{0, lex.Pos{Ln: 1, Ch: 3}, lex.Pos{Ln: 1, Ch: 3}, false},
+	}
+	if len(flds) != len(exp) {
+		t.Fatalf("fields: %+v", flds)
+	}
+	for i, e := range exp {
+		f := flds[i]
+		if f.Num != e.num || f.Reg.Start != e.st || f.Reg.End != e.ed || f.Mirror != e.mirror {
+			t.Errorf("field %d: %+v, expected: %+v", i, f, e)
+		}
+	}
+
+	txt, flds = ExpandSnippet(`// $TM_FILENAME ${TM_SELECTED_TEXT:none} $NOPE \$1 ${1|a,b\,c|} ${2:x ${3:y}}`, "", "\t", vars)
+	if exp := "// main.go none NOPE $1 a x y"; txt != exp {
+		t.Errorf("text: %q, expected: %q", txt, exp)
+	}
+	if len(flds) != 4 || len(flds[0].Choices) != 2 || flds[0].Choices[1] != "b,c" {
+		t.Fatalf("fields: %+v", flds)
+	}
+	if flds[1].Num != 2 || flds[2].Num != 3 || flds[2].Parent != 1 || flds[1].Reg.Start.Ch != 26 || flds[1].Reg.End.Ch != 29 {
+		t.Errorf("nested fields: %+v", flds)
+	}
+	if flds[3].Num != 0 || flds[3].Reg.Start.Ch != 29 {
+		t.Errorf("final field: %+v", flds[3])
+	}
+}
@@ -26,13 +26,38 @@ type Undo struct {
 	UndoStack []*Edit    `desc:"undo stack of *undo* edits -- added to whenever an Undo is done -- for emacs-style undo"`
 	Pos       int        `desc:"undo position in stack"`
 	Group     int        `desc:"group counter"`
+	Batch     int        `desc:"if > 0, all edits are saved in the current group regardless of their timing -- see BatchStart"`
 	Mu        sync.Mutex `json:"-" xml:"-" desc:"mutex protecting all updates"`
 }
 
 // NewGroup increments the Group counter so subsequent undos will be grouped separately
+// -- does nothing within a BatchStart batch
 func (un *Undo) NewGroup() {
 	un.Mu.Lock()
-	un.Group++
+	if un.Batch == 0 {
+		un.Group++
+	}
+	un.Mu.Unlock()
+}
+
+// BatchStart starts a new group that all edits are saved in, regardless of
+// their timing, until the matching BatchEnd -- e.g., for the same edit made
+// at multiple cursors.  Batches can be nested.
+func (un *Undo) BatchStart() {
+	un.Mu.Lock()
+	if un.Batch == 0 {
+		un.Group++
+	}
+	un.Batch++
+	un.Mu.Unlock()
+}
+
+// BatchEnd ends a batch of edits started by BatchStart
+func (un *Undo) BatchEnd() {
+	un.Mu.Lock()
+	if un.Batch > 0 {
+		un.Batch--
+	}
 	un.Mu.Unlock()
 }
 
@@ -45,7 +70,7 @@ func (un *Undo) Reset() {
 }
 
 // Save saves given edit to undo stack, with current group marker unless timer interval
-// exceeds UndoGroupDelayMSec since last item.
+// exceeds UndoGroupDelayMSec since last item, outside of a BatchStart batch.
 func (un *Undo) Save(tbe *Edit) {
 	if un.Off {
 		return
@@ -58,7 +83,7 @@ func (un *Undo) Save(tbe *Edit) {
 		}
 		un.Stack = un.Stack[:un.Pos]
 	}
-	if len(un.Stack) > 0 {
+	if len(un.Stack) > 0 && un.Batch == 0 {
 		since := tbe.Reg.SinceMSec(&un.Stack[len(un.Stack)-1].Reg)
 		if since > UndoGroupDelayMSec {
 			un.Group++
@@ -220,3 +245,114 @@ func (un *Undo) AdjustReg(reg Region) Region {
 	}
 	return reg
 }
+
+// Rebase adjusts the edits on the undo stack for given edit, which has been
+// made to the text without being saved on the stack, e.g., an edit made by
+// another user in collaborative editing, so that undo then undoes just the
+// saved edits, where they now are.  Saved edits that overlap the given edit
+// can no longer be undone, and are removed along with the edits before
+// them, as are any edits that have been undone, for redo.
+func (un *Undo) Rebase(tbe *Edit) {
+	if un.Off || tbe == nil {
+		return
+	}
+	un.Mu.Lock()
+	defer un.Mu.Unlock()
+	un.Stack = un.Stack[:un.Pos]
+	un.UndoStack = nil
+	st, ed, del := tbe.Reg.Start, tbe.Reg.End, tbe.Delete
+	cut := -1
+	// go back through the stack, with st, ed in the text after each edit
+	for i := len(un.Stack) - 1; i >= 0 && cut < 0; i-- {
+		re := un.Stack[i]
+		a, b := re.Reg.Start, re.Reg.End
+		switch {
+		case re.Rect:
+			cut = i
+		case !re.Delete && !del: // text at a, b is after the edit if st is after b
+			switch {
+			case !a.IsLess(st):
+				re.Reg.Start, re.Reg.End = insertShift(a, st, ed), insertShift(b, st, ed)
+			case !st.IsLess(b):
+				st, ed = moveInsert(st, ed, deleteShift(st, a, b))
+			default:
+				cut = i
+			}
+		case !re.Delete:
+			switch {
+			case !a.IsLess(ed):
+				re.Reg.Start, re.Reg.End = deleteShift(a, st, ed), deleteShift(b, st, ed)
+			case !st.IsLess(b):
+				st, ed = deleteShift(st, a, b), deleteShift(ed, a, b)
+			default:
+				cut = i
+			}
+		default: // deleted text is restored at a
+			b = textEnd(a, re.Text)
+			switch {
+			case !del && !a.IsLess(st):
+				re.Reg.Start = insertShift(a, st, ed)
+			case !del:
+				st, ed = moveInsert(st, ed, insertShift(st, a, b))
+			case !a.IsLess(ed):
+				re.Reg.Start = deleteShift(a, st, ed)
+			case !st.IsLess(a):
+				st, ed = insertShift(st, a, b), insertShift(ed, a, b)
+			default:
+				cut = i
+			}
+			re.Reg.End = textEnd(re.Reg.Start, re.Text)
+		}
+	}
+	if cut >= 0 {
+		un.Stack = un.Stack[cut+1:]
+	}
+	un.Pos = len(un.Stack)
+}
+
+// textEnd returns the end of given text starting at st
+func textEnd(st lex.Pos, txt [][]rune) lex.Pos {
+	switch len(txt) {
+	case 0:
+		return st
+	case 1:
+		return lex.Pos{Ln: st.Ln, Ch: st.Ch + len(txt[0])}
+	}
+	return lex.Pos{Ln: st.Ln + len(txt) - 1, Ch: len(txt[len(txt)-1])}
+}
+
+// insertShift returns given position, at or after st, moved for text
+// inserted from st to ed
+func insertShift(pos, st, ed lex.Pos) lex.Pos {
+	if pos.Ln == st.Ln {
+		pos.Ch += ed.Ch - st.Ch
+	}
+	pos.Ln += ed.Ln - st.Ln
+	return pos
+}
+
+// deleteShift returns given position, at or after ed, moved for the text
+// deleted from st to ed
+func deleteShift(pos, st, ed lex.Pos) lex.Pos {
+	if pos.Ln == ed.Ln {
+		pos.Ch += st.Ch - ed.Ch
+	}
+	pos.Ln -= ed.Ln - st.Ln
+	return pos
+}
+
+// moveInsert returns the region of text inserted from st to ed, moved to
+// start at nst
+func moveInsert(st, ed, nst lex.Pos) (lex.Pos, lex.Pos) {
+	if ed.Ln == st.Ln {
+		return nst, lex.Pos{Ln: nst.Ln, Ch: nst.Ch + ed.Ch - st.Ch}
+	}
+	return nst, lex.Pos{Ln: nst.Ln + ed.Ln - st.Ln, Ch: ed.Ch}
+}
+
+// Edits returns a copy of the undo stack, and the undo position in it
+func (un *Undo) Edits() ([]*Edit, int) {
+	un.Mu.Lock()
+	defer un.Mu.Unlock()
+	return append([]*Edit(nil), un.Stack...), un.Pos
+}
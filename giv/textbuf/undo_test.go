@@ -0,0 +1,140 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package textbuf
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/goki/pi/lex"
+)
+
+func TestUndoBatch(t *testing.T) {
+	var un Undo
+	st := time.Now()
+	save := func(msec int) *Edit {
+		tbe := &Edit{}
+		tbe.Reg.Time.SetTime(st.Add(time.Duration(msec) * time.Millisecond))
+		un.Save(tbe)
+		return tbe
+	}
+	e0 := save(0)
+	e1 := save(10 * UndoGroupDelayMSec)
+	if e1.Group == e0.Group {
+		t.Errorf("edits far apart in time should be in different groups")
+	}
+	un.BatchStart()
+	e2 := save(20 * UndoGroupDelayMSec)
+	e3 := save(30 * UndoGroupDelayMSec)
+	un.BatchEnd()
+	if e2.Group == e1.Group {
+		t.Errorf("a batch should start a new group")
+	}
+	if e3.Group != e2.Group {
+		t.Errorf("edits in a batch should be in the same group: %d != %d", e3.Group, e2.Group)
+	}
+	e4 := save(40 * UndoGroupDelayMSec)
+	if e4.Group == e3.Group {
+		t.Errorf("edit after the batch should be in a new group")
+	}
+}
+
+func TestUndoRebase(t *testing.T) {
+	pos := func(ln, ch int) lex.Pos { return lex.Pos{Ln: ln, Ch: ch} }
+	edit := func(st, ed lex.Pos, del bool, txt ...string) *Edit {
+		tbe := &Edit{Reg: NewRegionPos(st, ed), Delete: del}
+		for _, s := range txt {
+			tbe.Text = append(tbe.Text, []rune(s))
+		}
+		return tbe
+	}
+	var un Undo
+	r1 := edit(pos(0, 0), pos(0, 2), false, "ab")
+	r2 := edit(pos(0, 2), pos(0, 4), false, "cd")
+	un.Save(r1)
+	un.Save(r2)
+	un.Rebase(edit(pos(0, 4), pos(0, 5), false, "X"))
+	if r1.Reg.End != pos(0, 2) || r2.Reg.End != pos(0, 4) {
+		t.Errorf("insert after: %v %v", r1.Reg, r2.Reg)
+	}
+	un.Rebase(edit(pos(0, 0), pos(0, 1), false, "Y"))
+	if r1.Reg.Start != pos(0, 1) || r2.Reg.Start != pos(0, 3) || r2.Reg.End != pos(0, 5) {
+		t.Errorf("insert before: %v %v", r1.Reg, r2.Reg)
+	}
+	un.Rebase(edit(pos(0, 0), pos(0, 1), true, "Y"))
+	if r1.Reg.Start != pos(0, 0) || r2.Reg.Start != pos(0, 2) {
+		t.Errorf("delete before: %v %v", r1.Reg, r2.Reg)
+	}
+	un.Rebase(edit(pos(0, 3), pos(0, 4), false, "Z"))
+	if len(un.Stack) != 0 || un.Pos != 0 {
+		t.Errorf("insert within the last edit should remove the edits: %d", len(un.Stack))
+	}
+
+	r3 := edit(pos(1, 2), pos(1, 4), true, "de")
+	un.Save(r3)
+	un.Rebase(edit(pos(0, 1), pos(1, 1), false, "", "q"))
+	if r3.Reg.Start != pos(2, 2) || r3.Reg.End != pos(2, 4) {
+		t.Errorf("lines inserted before a delete: %v", r3.Reg)
+	}
+	un.Rebase(edit(pos(2, 0), pos(2, 1), true, "x"))
+	if r3.Reg.Start != pos(2, 1) || len(un.Stack) != 1 {
+		t.Errorf("delete before a delete: %v", r3.Reg)
+	}
+}
+
+func TestUndoHist(t *testing.T) {
+	var un Undo
+	now := time.Now()
+	save := func(days int, txt string, group bool) {
+		if group {
+			un.NewGroup()
+		}
+		tbe := &Edit{Text: [][]rune{[]rune(txt), nil}}
+		tbe.Reg.Time.SetTime(now.AddDate(0, 0, days))
+		un.Save(tbe)
+	}
+	save(-10, "a", true)
+	save(-10, "b", false)
+	save(-5, "c", true)
+	save(-1, "d", true)
+	save(0, "e", false)
+	un.UndoPop()
+
+	uh := un.Hist(0, 0, time.Time{})
+	if len(uh.Edits) != 5 || uh.Pos != 4 {
+		t.Errorf("full history: %d edits, pos: %d", len(uh.Edits), uh.Pos)
+	}
+	if uh = un.Hist(0, 4, time.Time{}); len(uh.Edits) != 2 || uh.Pos != 1 || uh.Edits[0].Text[0] != "d" {
+		t.Errorf("history limited in size: %+v", uh)
+	}
+	if uh = un.Hist(0, 8, time.Time{}); len(uh.Edits) != 3 || uh.Pos != 2 || uh.Edits[0].Text[0] != "c" {
+		t.Errorf("history limited in size, within a group: %+v", uh)
+	}
+	uh = un.Hist(4, 0, now.AddDate(0, 0, -7)) // whole groups are dropped
+	if len(uh.Edits) != 3 || uh.Pos != 2 || uh.Edits[0].Text[0] != "c" {
+		t.Errorf("limited history: %+v", uh)
+	}
+	fnm := UndoHistFilename(t.TempDir(), "file.go")
+	if err := uh.SaveJSON(fnm); err != nil {
+		t.Fatal(err)
+	}
+	var ouh UndoHist
+	if err := ouh.OpenJSON(fnm); err != nil {
+		t.Fatal(err)
+	}
+	var oun Undo
+	oun.SetHist(&ouh)
+	if len(oun.Stack) != 3 || oun.Pos != 2 || oun.Group != un.Group {
+		t.Fatalf("restored: %d edits, pos: %d", len(oun.Stack), oun.Pos)
+	}
+	tbe := oun.Stack[2]
+	if string(tbe.ToBytes()) != "e\n" || tbe.Group != un.Stack[4].Group || !tbe.Reg.Time.Time().Equal(un.Stack[4].Reg.Time.Time()) {
+		t.Errorf("restored edit: %+v", tbe)
+	}
+	if filepath.Ext(fnm) != ".json" || UndoHistFilename("x", "file.go") == UndoHistFilename("x", "other.go") {
+		t.Errorf("history filename: %v", fnm)
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package textbuf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode/utf8"
+)
+
+// UndoHist is the undo history of a file, which is saved between sessions
+// in a file of its own (see UndoHistFilename), and restored when the file
+// is opened again if it has not been changed in the meantime.  The saved
+// history has the text of all the edits in it, so it is limited in the
+// number of edits, their size, and their age -- see Undo.Hist.
+type UndoHist struct {
+	Path  string      `desc:"path of the file that the history is for"`
+	Hash  string      `desc:"hash of the contents of the file when the history was saved -- see ContentHash"`
+	Pos   int         `desc:"undo position in the edits -- the edits after it have been undone, and can be redone"`
+	Group int         `desc:"group counter"`
+	Edits []*HistEdit `desc:"the edits, in the order they were made"`
+}
+
+// HistEdit is an Edit as saved in an UndoHist, with the text as strings
+type HistEdit struct {
+	Reg    Region   `desc:"region for the edit -- see Edit"`
+	Text   []string `json:",omitempty" desc:"text deleted or inserted, in lines"`
+	Group  int      `desc:"grouping number, for grouping edits in Undo"`
+	Delete bool     `json:",omitempty" desc:"action is a deletion, otherwise an insertion"`
+	Rect   bool     `json:",omitempty" desc:"this is a rectangular region -- see Edit"`
+}
+
+// NewHistEdit returns the HistEdit for given edit
+func NewHistEdit(tbe *Edit) *HistEdit {
+	he := &HistEdit{Reg: tbe.Reg, Group: tbe.Group, Delete: tbe.Delete, Rect: tbe.Rect}
+	if tbe.Text != nil {
+		he.Text = make([]string, len(tbe.Text))
+		for i, l := range tbe.Text {
+			he.Text[i] = string(l)
+		}
+	}
+	return he
+}
+
+// Edit returns the Edit that the HistEdit is for
+func (he *HistEdit) Edit() *Edit {
+	tbe := &Edit{Reg: he.Reg, Group: he.Group, Delete: he.Delete, Rect: he.Rect}
+	if he.Text != nil {
+		tbe.Text = make([][]rune, len(he.Text))
+		for i, l := range he.Text {
+			tbe.Text[i] = []rune(l)
+		}
+	}
+	return tbe
+}
+
+// Hist returns the history of the undo stack, with at most max edits (all
+// if max <= 0), with at most maxBytes bytes of text (any if maxBytes <= 0),
+// made at or after since (all if it is zero) -- edits are dropped in whole
+// groups, from the oldest.  Edits that have been undone are kept for redo,
+// unless the edits before them are dropped.
+func (un *Undo) Hist(max, maxBytes int, since time.Time) *UndoHist {
+	un.Mu.Lock()
+	defer un.Mu.Unlock()
+	n := len(un.Stack)
+	st := 0
+	if max > 0 && n > max {
+		st = n - max
+	}
+	if !since.IsZero() {
+		for st < n && un.Stack[st].Reg.Time.Time().Before(since) {
+			st++
+		}
+	}
+	if maxBytes > 0 {
+		sz := 0
+		for i := n - 1; i >= st; i-- {
+			if sz += un.Stack[i].textBytes(); sz > maxBytes {
+				st = i + 1
+				break
+			}
+		}
+	}
+	for st > 0 && st < n && un.Stack[st].Group == un.Stack[st-1].Group {
+		st++
+	}
+	pos := un.Pos - st
+	if pos < 0 { // could not redo these
+		st, pos = n, 0
+	}
+	uh := &UndoHist{Pos: pos, Group: un.Group}
+	for _, tbe := range un.Stack[st:] {
+		uh.Edits = append(uh.Edits, NewHistEdit(tbe))
+	}
+	return uh
+}
+
+// textBytes returns the number of bytes of the text of the edit, in UTF-8
+// with a newline after each line but the last
+func (tbe *Edit) textBytes() int {
+	sz := 0
+	for i, l := range tbe.Text {
+		if i > 0 {
+			sz++
+		}
+		for _, r := range l {
+			sz += utf8.RuneLen(r)
+		}
+	}
+	return sz
+}
+
+// SetHist sets the undo stack from given history, which must be for the
+// current text
+func (un *Undo) SetHist(uh *UndoHist) {
+	un.Mu.Lock()
+	defer un.Mu.Unlock()
+	un.Stack = make([]*Edit, len(uh.Edits))
+	for i, he := range uh.Edits {
+		un.Stack[i] = he.Edit()
+	}
+	un.Pos = uh.Pos
+	if un.Pos < 0 || un.Pos > len(un.Stack) {
+		un.Pos = len(un.Stack)
+	}
+	un.Group = uh.Group
+	un.UndoStack = nil
+}
+
+// ContentHash returns the hash of given file contents, which identifies
+// the version of the file that an UndoHist is for
+func ContentHash(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+// UndoHistFilename returns the name of the file in given directory in
+// which the undo history for the file at given path is saved
+func UndoHistFilename(dir, path string) string {
+	if ap, err := filepath.Abs(path); err == nil {
+		path = ap
+	}
+	h := sha256.Sum256([]byte(path))
+	return filepath.Join(dir, hex.EncodeToString(h[:16])+".json")
+}
+
+// OpenJSON opens the undo history from a JSON-formatted file
+func (uh *UndoHist) OpenJSON(filename string) error {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	*uh = UndoHist{}
+	return json.Unmarshal(b, uh)
+}
+
+// SaveJSON saves the undo history to a JSON-formatted file
+func (uh *UndoHist) SaveJSON(filename string) error {
+	b, err := json.Marshal(uh)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, b, 0644)
+}
+
+// CleanUndoHists deletes the undo history files in given directory that
+// were last saved before given time
+func CleanUndoHists(dir string, before time.Time) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, fi := range fis {
+		if filepath.Ext(fi.Name()) == ".json" && fi.ModTime().Before(before) {
+			os.Remove(filepath.Join(dir, fi.Name()))
+		}
+	}
+}
@@ -0,0 +1,378 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/lsp"
+	"github.com/goki/gi/giv/textbuf"
+	"github.com/goki/gi/giv/vfs"
+	"github.com/goki/gi/histyle"
+	"github.com/goki/pi/lex"
+)
+
+// largeBufText returns text with given number of lines of typical code length
+func largeBufText(nlines int) []byte {
+	var b bytes.Buffer
+	for ln := 0; ln < nlines; ln++ {
+		fmt.Fprintf(&b, "\tfmt.Printf(\"line number: %d with some more text\\n\", %d)\n", ln, ln)
+	}
+	return b.Bytes()
+}
+
+// newTestTextBuf returns a new TextBuf with the highlighting style set, so
+// that Defaults does not require the GUI app for loading styles
+func newTestTextBuf() *TextBuf {
+	tb := NewTextBuf()
+	tb.Hi.Style = histyle.StyleDefault
+	return tb
+}
+
+func TestTextBufPieces(t *testing.T) {
+	tb := newTestTextBuf()
+	tb.SetText([]byte("one\ntwo\nthree\nfour"))
+	tb.InsertText(lex.Pos{Ln: 1, Ch: 1}, []byte("x\ny"), EditNoSignal)
+	tb.DeleteText(lex.Pos{Ln: 2, Ch: 2}, lex.Pos{Ln: 3, Ch: 3}, EditNoSignal)
+	tb.InsertText(tb.EndPos(), []byte("\nfive"), EditNoSignal)
+	exp := "one\ntx\nywee\nfour\nfive\n"
+	if got := string(tb.LinesToBytesCopy()); got != exp {
+		t.Errorf("text after edits:\ngot: %q\nexp: %q", got, exp)
+	}
+	if got := string(tb.BytesLine(2)); got != "ywee" {
+		t.Errorf("BytesLine(2): got %q, expected %q", got, "ywee")
+	}
+	for tb.Undo() != nil {
+	}
+	exp = "one\ntwo\nthree\nfour\n"
+	if got := string(tb.LinesToBytesCopy()); got != exp {
+		t.Errorf("text after undo:\ngot: %q\nexp: %q", got, exp)
+	}
+	if got := string(tb.Line(2)); got != "three" {
+		t.Errorf("Line(2): got %q, expected %q", got, "three")
+	}
+	if lns, offs := tb.Lines(), tb.ByteOffs(); len(lns) != 4 || string(lns[3]) != "four" || offs[3] != 14 || tb.TotalBytes() != 19 {
+		t.Errorf("deprecated accessors: %q %v %v", lns, offs, tb.TotalBytes())
+	}
+}
+
+func TestTextBufSetTextCopy(t *testing.T) {
+	txt := []byte("one\ntwo\n")
+	tb := newTestTextBuf()
+	tb.SetText(txt)
+	copy(txt, "ONE")
+	if got := string(tb.Line(0)); got != "one" {
+		t.Errorf("text changed with the given slice: %q", got)
+	}
+}
+
+func TestTextBufMarkupTooLarge(t *testing.T) {
+	defer func(mx int) { TextBufMaxMarkupBytes = mx }(TextBufMaxMarkupBytes)
+	TextBufMaxMarkupBytes = 100
+	tb := openTestPiBuf(t, "large.go", string(largeBufText(10)))
+	if err := tb.MarkupAllLines(-1); err != ErrMarkupTooLarge {
+		t.Errorf("MarkupAllLines: got %v, expected ErrMarkupTooLarge", err)
+	}
+	if len(tb.lineState) != 0 {
+		t.Errorf("state kept for %d lines without any", len(tb.lineState))
+	}
+	if len(tb.LineHiTags(5)) == 0 {
+		t.Errorf("line not highlighted as viewed")
+	}
+	if mu := string(tb.LineMarkup(5)); !strings.Contains(mu, "<span") {
+		t.Errorf("line not marked up as viewed: %q", mu)
+	}
+}
+
+func TestTextBufLineState(t *testing.T) {
+	var tl textBufLines
+	mu := []byte("x")
+	for _, ln := range []int{5, 1, 3, 8} {
+		tl.setMarkup(ln, mu)
+	}
+	tl.inserted(3, 2)    // 1 5 7 10
+	tl.deleted(4, 6)     // 1 5 8
+	tl.setMarkup(1, nil) // 5 8
+	var got []int
+	for _, bl := range tl {
+		got = append(got, bl.ln)
+	}
+	if fmt.Sprint(got) != "[5 8]" {
+		t.Errorf("lines with state: got %v, expected [5 8]", got)
+	}
+	tl.setAllHi(6, func(ln int) lex.Line {
+		if ln == 2 {
+			return lex.Line{lex.Lex{St: 0, Ed: 1}}
+		}
+		return nil
+	})
+	if len(tl) != 3 || tl.hi(2) == nil || tl.get(5) == nil || tl.get(8) == nil {
+		t.Errorf("lines after setAllHi: %v", tl)
+	}
+}
+
+// testLSPServer is a fake language server that tracks the text of the
+// document, reports a diagnostic for each "bad" word, and renames the
+// words "one" and "three", and the first word of the other file
+type testLSPServer struct {
+	conn  *lsp.Conn
+	mu    sync.Mutex
+	text  string
+	other string
+}
+
+func (ts *testLSPServer) Text() string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.text
+}
+
+func (ts *testLSPServer) handle(method string, params json.RawMessage) (interface{}, error) {
+	var p struct {
+		TextDocument   lsp.TextDocumentItem                 `json:"textDocument"`
+		ContentChanges []lsp.TextDocumentContentChangeEvent `json:"contentChanges"`
+		NewName        string                               `json:"newName"`
+	}
+	json.Unmarshal(params, &p)
+	switch method {
+	case "initialize":
+		return map[string]interface{}{"capabilities": map[string]interface{}{"textDocumentSync": lsp.SyncIncremental, "renameProvider": true}}, nil
+	case "textDocument/didOpen", "textDocument/didChange":
+		ts.mu.Lock()
+		if method == "textDocument/didOpen" {
+			ts.text = p.TextDocument.Text
+		}
+		for _, ch := range p.ContentChanges {
+			if ch.Range == nil {
+				ts.text = ch.Text
+			} else {
+				ts.text = string(lsp.ApplyEdits([]byte(ts.text), []lsp.TextEdit{{Range: *ch.Range, NewText: ch.Text}}))
+			}
+		}
+		var diags []lsp.Diagnostic
+		for ln, l := range strings.Split(ts.text, "\n") {
+			if ch := strings.Index(l, "bad"); ch >= 0 {
+				diags = append(diags, lsp.Diagnostic{Range: lsp.Range{Start: lsp.Position{Line: ln, Character: ch}, End: lsp.Position{Line: ln, Character: ch + 3}}, Message: "bad word"})
+			}
+		}
+		ts.mu.Unlock()
+		ts.conn.Notify("textDocument/publishDiagnostics", lsp.PublishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: diags})
+	case "textDocument/rename":
+		we := lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{p.TextDocument.URI: {
+			{Range: lsp.Range{Start: lsp.Position{Line: 0}, End: lsp.Position{Line: 0, Character: 3}}, NewText: p.NewName},
+			{Range: lsp.Range{Start: lsp.Position{Line: 2}, End: lsp.Position{Line: 2, Character: 5}}, NewText: p.NewName},
+		}}}
+		if ts.other != "" {
+			we.Changes[ts.other] = []lsp.TextEdit{{Range: lsp.Range{Start: lsp.Position{Line: 0}, End: lsp.Position{Line: 0, Character: 3}}, NewText: p.NewName}}
+		}
+		return we, nil
+	}
+	return nil, nil
+}
+
+// waitFor waits for given condition to become true, meanwhile running the
+// functions posted to run on the event goroutine, as there is no window
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for st := time.Now(); ; time.Sleep(time.Millisecond) {
+		gi.RunPendingUIFuncs()
+		if cond() {
+			return
+		}
+		if time.Since(st) > 5*time.Second {
+			t.Fatalf("timed out waiting for %v", what)
+		}
+	}
+}
+
+func TestTextBufLSP(t *testing.T) {
+	cc, sc := net.Pipe()
+	ts := &testLSPServer{other: lsp.FileURI("/root/other.go")}
+	ts.conn = lsp.NewConn(sc, ts.handle)
+	cl := lsp.NewClient(cc, "/root")
+	if err := cl.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	tb := newTestTextBuf()
+	tb.SetText([]byte("one\ntwo\nthree\n"))
+	tb.Filename = gi.FileName("/root/test.go")
+	tb.FS = newTestDirFS(t, map[string]string{"other.go": "\xff\xfeo\x00n\x00e\x00\r\x00\n\x00"})
+	if err := tb.SetLSP(cl, "go"); err != nil {
+		t.Fatal(err)
+	}
+	synced := func() bool { return ts.Text() == string(tb.LinesToBytesCopy()) }
+	waitFor(t, "didOpen", synced)
+
+	tb.InsertText(lex.Pos{Ln: 1, Ch: 1}, []byte("😀x\ny"), EditNoSignal)
+	tb.DeleteText(lex.Pos{Ln: 1, Ch: 2}, lex.Pos{Ln: 2, Ch: 1}, EditNoSignal) // across emoji
+	tb.InsertText(lex.Pos{Ln: 1, Ch: 1}, []byte(" bad "), EditNoSignal)
+	tb.DeleteTextRect(lex.Pos{Ln: 0, Ch: 0}, lex.Pos{Ln: 1, Ch: 1}, EditNoSignal)
+	waitFor(t, "didChange", synced)
+	if got := string(tb.LinesToBytesCopy()); got != "ne\n bad 😀wo\nthree\n" {
+		t.Errorf("text after edits: %q", got)
+	}
+
+	waitFor(t, "diagnostics", func() bool { return len(tb.DiagnosticsAt(lex.Pos{Ln: 1, Ch: 2})) == 1 })
+	if ds := tb.DiagnosticsAt(lex.Pos{Ln: 1, Ch: 6}); len(ds) != 0 {
+		t.Errorf("unexpected diagnostics after the bad word: %v", ds)
+	}
+
+	if err := tb.LSPRename(lex.Pos{}, "uno"); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(tb.LinesToBytesCopy()); got != "uno\n bad 😀wo\nuno\n" {
+		t.Errorf("text after rename: %q", got)
+	}
+	if b, _ := vfs.ReadFile(tb.FS, "/root/other.go"); string(b) != "\xff\xfeu\x00n\x00o\x00\r\x00\n\x00" {
+		t.Errorf("other file after rename: %q", b)
+	}
+	waitFor(t, "rename didChange", synced)
+	for tb.Undo() != nil {
+	}
+	waitFor(t, "undo didChange", synced)
+	if got := ts.Text(); got != "one\ntwo\nthree\n" {
+		t.Errorf("server text after undo: %q", got)
+	}
+	tb.DeleteLSP()
+	cl.Shutdown()
+}
+
+func TestTextBufVcsChanges(t *testing.T) {
+	tb := newTestTextBuf()
+	tb.SetText([]byte("one\ntwo\nthree\nfour\n"))
+	tb.SetVcsBase([]byte("one\n2\nthree\nfour\nfive\n"))
+	exp := []textbuf.LineChanges{textbuf.LineUnchanged, textbuf.LineModified, textbuf.LineUnchanged, textbuf.LineUnchanged, textbuf.LineDeleted}
+	for ln, lc := range exp {
+		if got := tb.LineChange(ln); got != lc {
+			t.Errorf("line %d: %v, expected: %v", ln, got, lc)
+		}
+	}
+
+	tb.InsertText(lex.Pos{Ln: 2, Ch: 5}, []byte("\nnew"), EditNoSignal)
+	if lc := tb.LineChange(3); lc != textbuf.LineAdded {
+		t.Errorf("inserted line: %v", lc)
+	}
+	if lc := tb.LineChange(5); lc != textbuf.LineDeleted {
+		t.Errorf("deleted at end after insert: %v", lc)
+	}
+	for _, ln := range []int{5, 3, 1} { // deleted at end, added, modified
+		if !tb.RevertChange(ln) {
+			t.Errorf("no change to revert at line: %d", ln)
+		}
+	}
+	if got := string(tb.Text()); got != "one\n2\nthree\nfour\nfive\n" {
+		t.Errorf("text after reverting changes: %q", got)
+	}
+	if len(tb.Changes.Hunks) != 0 {
+		t.Errorf("changes after reverting: %+v", tb.Changes.Hunks)
+	}
+	tb.Undo()
+	if lc := tb.LineChange(1); lc != textbuf.LineModified {
+		t.Errorf("undo of revert: %v", lc)
+	}
+
+	bls := ParseGitBlame([]byte("4f1a2b3c 1 1 1\nauthor Jo Coder\nauthor-mail <jo@example.com>\nauthor-time 1577836800\nauthor-tz +0000\nsummary fix things\nfilename a.go\n\tone\n" +
+		"4f1a2b3c 2 2\nauthor Jo Coder\nauthor-time 1577836800\nsummary fix things\nfilename a.go\n\ttwo\n"))
+	if len(bls) != 2 || bls[1].Rev != "4f1a2b3c" || bls[0].Author != "Jo Coder" || bls[0].Summary != "fix things" || bls[0].Date.Unix() != 1577836800 {
+		t.Errorf("parsed blame: %+v", bls)
+	}
+	tb.SetText([]byte("zero\none\ntwo\n"))
+	tb.SetVcsBase([]byte("one\ntwo\n"))
+	tb.Blame = bls
+	if bl, ok := tb.BlameAt(1); !ok || bl.Summary != "fix things" {
+		t.Errorf("blame of line after inserted one: %+v %v", bl, ok)
+	}
+	if _, ok := tb.BlameAt(0); ok {
+		t.Errorf("blame of inserted line")
+	}
+	tb.SetVcsBase(nil)
+	if _, ok := tb.BlameAt(1); ok {
+		t.Errorf("blame without changes to match lines")
+	}
+}
+
+func BenchmarkTextBufOpenLarge(b *testing.B) {
+	txt := largeBufText(2000000)
+	b.SetBytes(int64(len(txt)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tb := newTestTextBuf()
+		tb.SetText(txt)
+		tb.Line(tb.NumLines() / 2)
+	}
+}
+
+func BenchmarkTextBufEditLarge(b *testing.B) {
+	tb := newTestTextBuf()
+	tb.SetText(largeBufText(2000000))
+	nln := tb.NumLines()
+	rnd := rand.New(rand.NewSource(1))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ln := rnd.Intn(nln - 2)
+		tb.InsertText(lex.Pos{Ln: ln, Ch: 4}, []byte("edit\n"), EditNoSignal)
+		tb.DeleteText(lex.Pos{Ln: ln, Ch: 4}, lex.Pos{Ln: ln + 1}, EditNoSignal)
+	}
+}
+
+func TestTextBufPatchFromBuf(t *testing.T) {
+	texts := []string{"", "one\n", "one\nmore\n", "one\nmore", "zero\none\nmore\n", "one\nother\n", "more\n"}
+	for _, from := range texts {
+		for _, to := range texts {
+			tb := newTestTextBuf()
+			tb.SetText([]byte(from))
+			ob := newTestTextBuf()
+			ob.SetText([]byte(to))
+			tb.PatchFromBuf(ob, tb.DiffBufs(ob), false)
+			if got, exp := strings.Join(tb.Strings(false), "\n"), strings.Join(ob.Strings(false), "\n"); got != exp {
+				t.Errorf("patch %q to %q: got %q", from, to, got)
+			}
+		}
+	}
+}
+
+func TestTextBufSaveEncoding(t *testing.T) {
+	if gi.TheIconMgr == nil {
+		gi.TheIconMgr = &testIconMgr{}
+	}
+	fsys := newTestDirFS(t, map[string]string{"le.xyz": "o\x00n\x00e\x00\r\x00\n\x00", "l1.xyz": "caf\xe9\n"})
+	for _, tt := range []struct {
+		file   string
+		enc    textbuf.Encodings
+		insert string
+		exp    string
+	}{
+		{"le.xyz", textbuf.UTF16LE, "x", "x\x00o\x00n\x00e\x00\r\x00\n\x00"}, // no byte order mark added
+		{"l1.xyz", textbuf.Latin1, "€", "€caf\xc3\xa9\n"},                    // saved as UTF-8 without a view to ask
+	} {
+		tb := newTestTextBuf()
+		tb.FS = fsys
+		if err := tb.OpenFile(gi.FileName("/root/" + tt.file)); err != nil {
+			t.Fatal(err)
+		}
+		if tb.Opts.Encoding != tt.enc {
+			t.Errorf("%v: encoding %v, expected %v", tt.file, tb.Opts.Encoding, tt.enc)
+		}
+		tb.InsertText(lex.Pos{}, []byte(tt.insert), EditNoSignal)
+		var saved error = ErrSavePending
+		if err := tb.SaveFunc(func(err error) { saved = err }); err != nil || saved != nil {
+			t.Errorf("%v: save: %v %v", tt.file, err, saved)
+		}
+		if b, _ := vfs.ReadFile(fsys, "/root/"+tt.file); string(b) != tt.exp {
+			t.Errorf("%v: saved %q, expected %q", tt.file, b, tt.exp)
+		}
+	}
+}
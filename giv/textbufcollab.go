@@ -0,0 +1,549 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/collab"
+	"github.com/goki/gi/giv/textbuf"
+	"github.com/goki/ki/ints"
+	"github.com/goki/mat32"
+	"github.com/goki/pi/lex"
+)
+
+/////////////////////////////////////////////////////////////////////////////
+//   Collaborative Editing
+
+// TextBufCollab is the state of a TextBuf in a collaborative editing
+// session, in which the edits of all users are sent through a
+// collab.Server to the others -- see TextBuf.HostCollab and JoinCollab.
+// All of its state is protected by the LinesMu lock of the buffer.
+type TextBufCollab struct {
+	Name     string               `desc:"name of the user of this buffer, shown to the others"`
+	Site     int                  `desc:"unique number of this buffer in the session"`
+	Conn     collab.Conn          `desc:"connection to the server"`
+	Client   *collab.Client       `desc:"state of the ops of this buffer relative to the server"`
+	Server   *collab.Server       `desc:"server of the session, if this buffer is hosting it"`
+	peers    map[int]*collab.Peer // other users, with selections in the text of this buffer
+	doc      []rune               // the text, as of the last change sent or applied
+	applying bool                 // applying an op from the server -- not sent back
+	sel      collab.Sel           // our selection, as last set
+	selPend  bool                 // selection waits until our ops are acknowledged
+	out      chan *collab.Msg     // messages to send, by the writer goroutine
+	win      *gi.Window           // window whose event goroutine handles the messages received
+	failed   bool                 // a message received could not be handled -- the rest are ignored
+}
+
+// CollabPeer is another user in the collaborative editing session of a
+// buffer, with their selection in the text of the buffer
+type CollabPeer struct {
+	Site   int     `desc:"unique number of the user in the session"`
+	Name   string  `desc:"name of the user"`
+	Anchor lex.Pos `desc:"start of the selection, where it was made from"`
+	Head   lex.Pos `desc:"end of the selection, where the cursor is -- same as Anchor if nothing is selected"`
+}
+
+// HostCollab starts a collaborative editing session for the buffer, with
+// its current text, joining it as the user of given name.  If addr is
+// non-empty, it is the TCP network address on which other users can join
+// it (see DialCollab), e.g., ":7412".  The session lasts until the host
+// leaves it -- see LeaveCollab.
+func (tb *TextBuf) HostCollab(addr, name string) (*collab.Server, error) {
+	tb.LinesMu.RLock()
+	sv := collab.NewServer(string(tb.CollabTextImpl()))
+	tb.LinesMu.RUnlock()
+	if addr != "" {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		go sv.Listen(ln)
+	}
+	ca, cb := collab.Pipe()
+	go sv.Serve(cb)
+	if err := tb.JoinCollab(ca, name); err != nil {
+		sv.Close()
+		return nil, err
+	}
+	tb.LinesMu.Lock()
+	if tb.Collab != nil {
+		tb.Collab.Server = sv
+	}
+	tb.LinesMu.Unlock()
+	return sv, nil
+}
+
+// DialCollab joins the collaborative editing session hosted at given TCP
+// network address, e.g., "localhost:7412", as the user of given name --
+// see JoinCollab
+func (tb *TextBuf) DialCollab(addr, name string) error {
+	conn, err := collab.Dial(addr)
+	if err != nil {
+		return err
+	}
+	return tb.JoinCollab(conn, name)
+}
+
+// JoinCollab joins the collaborative editing session of the server on
+// given connection, as the user of given name.  The text of the buffer is
+// set to that of the session, and then edits of the buffer are sent to the
+// other users, and theirs are applied to it, along with showing their
+// selections in the views, until the connection is closed.
+func (tb *TextBuf) JoinCollab(conn collab.Conn, name string) error {
+	tb.LeaveCollab()
+	if err := conn.Send(&collab.Msg{Type: collab.MsgJoin, Name: name}); err != nil {
+		conn.Close()
+		return err
+	}
+	msg, err := conn.Recv()
+	if err == nil && msg.Type != collab.MsgInit {
+		err = fmt.Errorf("giv.TextBuf JoinCollab: expected init from server, got: %v", msg.Type)
+	}
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	tb.LinesMu.RLock()
+	same := string(tb.CollabTextImpl()) == msg.Text
+	tb.LinesMu.RUnlock()
+	if !same {
+		tb.SetText([]byte(msg.Text))
+	}
+	tc := &TextBufCollab{Name: name, Site: msg.Site, Conn: conn, Client: collab.NewClient(msg.Rev), peers: make(map[int]*collab.Peer), out: make(chan *collab.Msg, collab.PipeBuffer)}
+	if vp := tb.ViewportFromView(); vp != nil {
+		tc.win = vp.Win
+	}
+	for i := range msg.Peers {
+		pr := msg.Peers[i]
+		tc.peers[pr.Site] = &pr
+	}
+	tb.LinesMu.Lock()
+	tc.doc = tb.CollabTextImpl()
+	if string(tc.doc) != msg.Text {
+		tb.LinesMu.Unlock()
+		conn.Close()
+		return fmt.Errorf("giv.TextBuf JoinCollab: text of buffer does not match that of the session")
+	}
+	tb.Collab = tc
+	tb.LinesMu.Unlock()
+	go tc.write()
+	go tb.CollabRecv(tc)
+	tb.TextBufSig.Emit(tb.This(), int64(TextBufMarkUpdt), nil)
+	return nil
+}
+
+// LeaveCollab leaves the collaborative editing session of the buffer, if
+// any, closing the session if this buffer is hosting it
+func (tb *TextBuf) LeaveCollab() {
+	tb.LinesMu.Lock()
+	tc := tb.Collab
+	tb.Collab = nil
+	tb.LinesMu.Unlock()
+	if tc == nil {
+		return
+	}
+	tc.Conn.Close()
+	if tc.Server != nil {
+		tc.Server.Close()
+	}
+	tb.TextBufSig.Emit(tb.This(), int64(TextBufMarkUpdt), nil)
+}
+
+// InCollab returns true if the buffer is in a collaborative editing session
+func (tb *TextBuf) InCollab() bool {
+	tb.LinesMu.RLock()
+	defer tb.LinesMu.RUnlock()
+	return tb.Collab != nil
+}
+
+// CollabPeers returns the other users in the collaborative editing session
+// of the buffer, in order of joining, with their selections
+func (tb *TextBuf) CollabPeers() []CollabPeer {
+	tb.LinesMu.RLock()
+	defer tb.LinesMu.RUnlock()
+	tc := tb.Collab
+	if tc == nil {
+		return nil
+	}
+	prs := make([]CollabPeer, 0, len(tc.peers))
+	for _, pr := range tc.peers {
+		prs = append(prs, CollabPeer{Site: pr.Site, Name: pr.Name, Anchor: tb.CollabPosImpl(pr.Sel.Anchor), Head: tb.CollabPosImpl(pr.Sel.Head)})
+	}
+	sort.Slice(prs, func(i, j int) bool {
+		return prs[i].Site < prs[j].Site
+	})
+	return prs
+}
+
+// CollabSetSel sets the selection of this user that is shown to the others
+// in the collaborative editing session, if any, from the anchor where it
+// was started to the head at the cursor
+func (tb *TextBuf) CollabSetSel(anchor, head lex.Pos) {
+	tb.LinesMu.Lock()
+	defer tb.LinesMu.Unlock()
+	tc := tb.Collab
+	if tc == nil {
+		return
+	}
+	sel := collab.Sel{Anchor: tb.CollabOffImpl(anchor), Head: tb.CollabOffImpl(head)}
+	if sel == tc.sel {
+		return
+	}
+	tc.sel = sel
+	tc.sendSel()
+}
+
+// CollabOffImpl returns the offset in the text of the session for given
+// position.  Must be called under LinesMu lock.
+func (tb *TextBuf) CollabOffImpl(pos lex.Pos) int {
+	off := 0
+	for ln := 0; ln < pos.Ln && ln < tb.NLines; ln++ {
+		off += utf8.RuneCount(tb.Store.Line(ln)) + 1
+	}
+	return off + pos.Ch
+}
+
+// CollabPosImpl returns the position for given offset in the text of the
+// session, limited to a valid position.  Must be called under LinesMu lock.
+func (tb *TextBuf) CollabPosImpl(off int) lex.Pos {
+	if tb.NLines == 0 || off < 0 {
+		return lex.PosZero
+	}
+	for ln := 0; ln < tb.NLines; ln++ {
+		ll := tb.LineLenImpl(ln)
+		if off <= ll {
+			return lex.Pos{Ln: ln, Ch: off}
+		}
+		off -= ll + 1
+	}
+	ln := tb.NLines - 1
+	return lex.Pos{Ln: ln, Ch: tb.LineLenImpl(ln)}
+}
+
+// CollabTextImpl returns the text of the buffer as it is in the session,
+// with lines separated by newlines.  Must be called under LinesMu lock.
+func (tb *TextBuf) CollabTextImpl() []rune {
+	txt := tb.Store.Bytes()
+	return bytes.Runes(txt[:len(txt)-1]) // each line ends in a newline in Store
+}
+
+// CollabChangeImpl sends the change replacing the text between given
+// positions with given text to the other users in the collaborative
+// editing session, if any -- called before the change is made.  Must be
+// called under LinesMu lock.
+func (tb *TextBuf) CollabChangeImpl(st, ed lex.Pos, text []byte) {
+	tc := tb.Collab
+	if tc == nil || tc.applying {
+		return
+	}
+	off := tb.CollabOffImpl(st)
+	tc.local(collab.ReplaceOp(len(tc.doc), off, tb.CollabOffImpl(ed)-off, string(text)))
+}
+
+// CollabSyncImpl sends the changes to the text since the last change that
+// was sent to the other users in the collaborative editing session, if any,
+// for changes that are not sent by CollabChangeImpl -- called after the
+// changes are made.  Must be called under LinesMu lock.
+func (tb *TextBuf) CollabSyncImpl() {
+	tc := tb.Collab
+	if tc == nil || tc.applying {
+		return
+	}
+	txt := tb.CollabTextImpl()
+	pre := 0
+	for pre < len(txt) && pre < len(tc.doc) && txt[pre] == tc.doc[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(txt)-pre && suf < len(tc.doc)-pre && txt[len(txt)-1-suf] == tc.doc[len(tc.doc)-1-suf] {
+		suf++
+	}
+	if pre == len(txt) && pre == len(tc.doc) {
+		return
+	}
+	tc.local(collab.ReplaceOp(len(tc.doc), pre, len(tc.doc)-pre-suf, string(txt[pre:len(txt)-suf])))
+}
+
+// CollabSync sends the changes to the text after the entire text has been
+// set -- see CollabSyncImpl
+func (tb *TextBuf) CollabSync() {
+	tb.LinesMu.Lock()
+	tb.CollabSyncImpl()
+	tb.LinesMu.Unlock()
+}
+
+// CollabRecv receives the messages from the server of the session, until
+// the connection is closed, and has them handled on the event goroutine
+// of the window of the buffer (see gi.PostUIFunc), in the order received:
+// applying the ops of the other users to the buffer and updating their
+// selections
+func (tb *TextBuf) CollabRecv(tc *TextBufCollab) {
+	for {
+		msg, err := tc.Conn.Recv()
+		if err != nil {
+			break
+		}
+		gi.PostUIFunc(tc.win, func() {
+			if tc.failed {
+				return
+			}
+			if err := tb.collabHandle(tc, msg); err != nil {
+				log.Printf("giv.TextBuf Collab: %v\n", err)
+				tc.failed = true
+				tc.Conn.Close() // ends CollabRecv
+			}
+		})
+	}
+	gi.PostUIFunc(tc.win, func() {
+		tb.LinesMu.Lock()
+		if tb.Collab == tc {
+			tb.Collab = nil
+		}
+		close(tc.out)
+		tb.LinesMu.Unlock()
+		tc.Conn.Close()
+		tb.TextBufSig.Emit(tb.This(), int64(TextBufMarkUpdt), nil)
+	})
+}
+
+// collabHandle handles a message from the server
+func (tb *TextBuf) collabHandle(tc *TextBufCollab, msg *collab.Msg) error {
+	switch msg.Type {
+	case collab.MsgOp:
+		if msg.Op == nil {
+			return fmt.Errorf("giv.TextBuf Collab: op message without op")
+		}
+		return tb.collabApply(tc, msg.Op)
+	case collab.MsgAck:
+		tb.LinesMu.Lock()
+		op, err := tc.Client.ServerAck()
+		if err == nil {
+			if op != nil {
+				tc.send(&collab.Msg{Type: collab.MsgOp, Rev: tc.Client.Rev, Op: op})
+			} else if tc.selPend {
+				tc.sendSel()
+			}
+		}
+		tb.LinesMu.Unlock()
+		return err
+	case collab.MsgSel:
+		if msg.Sel == nil {
+			return fmt.Errorf("giv.TextBuf Collab: selection message without selection")
+		}
+		tb.LinesMu.Lock()
+		pr, has := tc.peers[msg.Site]
+		if !has {
+			pr = &collab.Peer{Site: msg.Site}
+			tc.peers[msg.Site] = pr
+		}
+		if msg.Name != "" {
+			pr.Name = msg.Name
+		}
+		pr.Sel = collab.Sel{Anchor: tc.Client.TransformIndex(msg.Sel.Anchor), Head: tc.Client.TransformIndex(msg.Sel.Head)}
+		tb.LinesMu.Unlock()
+	case collab.MsgLeave:
+		tb.LinesMu.Lock()
+		delete(tc.peers, msg.Site)
+		tb.LinesMu.Unlock()
+	default:
+		return nil
+	}
+	tb.TextBufSig.Emit(tb.This(), int64(TextBufMarkUpdt), nil)
+	return nil
+}
+
+// collabApply applies an op from the server, made by another user, to the
+// buffer, as edits that are not saved for undo -- the saved edits are
+// adjusted for them instead
+func (tb *TextBuf) collabApply(tc *TextBufCollab, sop *collab.Op) error {
+	tb.LinesMu.Lock()
+	op, err := tc.Client.ApplyServer(sop)
+	var doc []rune
+	if err == nil {
+		doc, err = op.Apply(tc.doc)
+	}
+	if err != nil {
+		tb.LinesMu.Unlock()
+		return err
+	}
+	var tbes []*textbuf.Edit
+	tc.applying = true
+	off := 0
+	for _, c := range op.Comps {
+		var tbe *textbuf.Edit
+		switch {
+		case c.IsRetain():
+			off += c.Retain
+		case c.IsDelete():
+			tbe = tb.DeleteTextImpl(tb.CollabPosImpl(off), tb.CollabPosImpl(off+c.Delete))
+		default:
+			tbe = tb.InsertTextImpl(tb.CollabPosImpl(off), []byte(c.Insert))
+			off += utf8.RuneCountInString(c.Insert)
+		}
+		if tbe != nil {
+			tb.Undos.Rebase(tbe)
+			tbes = append(tbes, tbe)
+		}
+	}
+	tc.applying = false
+	tc.doc = doc
+	tc.transform(op)
+	tb.LinesMu.Unlock()
+	if len(tbes) == 0 {
+		return nil
+	}
+	tb.SetChanged()
+	for _, tbe := range tbes {
+		if tbe.Delete {
+			tb.TextBufSig.Emit(tb.This(), int64(TextBufDelete), tbe)
+		} else {
+			tb.TextBufSig.Emit(tb.This(), int64(TextBufInsert), tbe)
+		}
+	}
+	return nil
+}
+
+// local records an op made to the buffer, sending it to the server if
+// there is no op waiting to be acknowledged
+func (tc *TextBufCollab) local(op *collab.Op) {
+	doc, err := op.Apply(tc.doc)
+	if err == nil {
+		tc.doc = doc
+		tc.transform(op)
+		op, err = tc.Client.ApplyLocal(op)
+	}
+	if err != nil {
+		log.Printf("giv.TextBuf Collab: %v\n", err)
+		return
+	}
+	if op != nil {
+		tc.send(&collab.Msg{Type: collab.MsgOp, Rev: tc.Client.Rev, Op: op})
+	}
+}
+
+// transform adjusts the selections for given op made to the buffer
+func (tc *TextBufCollab) transform(op *collab.Op) {
+	for _, pr := range tc.peers {
+		pr.Sel = pr.Sel.Transform(op)
+	}
+	tc.sel = tc.sel.Transform(op)
+}
+
+// sendSel sends our selection, which can only be done when the server has
+// all of our ops, otherwise it is sent when it does
+func (tc *TextBufCollab) sendSel() {
+	tc.selPend = !tc.Client.Synced()
+	if !tc.selPend {
+		sel := tc.sel
+		tc.send(&collab.Msg{Type: collab.MsgSel, Rev: tc.Client.Rev, Sel: &sel})
+	}
+}
+
+// send queues a message for sending to the server
+func (tc *TextBufCollab) send(msg *collab.Msg) {
+	tc.out <- msg
+}
+
+// write sends the queued messages, until the out channel is closed
+func (tc *TextBufCollab) write() {
+	for msg := range tc.out {
+		if tc.Conn.Send(msg) != nil {
+			break
+		}
+	}
+	for range tc.out { // drain if the connection failed
+	}
+}
+
+// TextViewCollabColors are the colors of the cursors and selections of the
+// other users in a collaborative editing session, by their number in the
+// session
+var TextViewCollabColors = []string{"#e91e63", "#2196f3", "#4caf50", "#ff9800", "#9c27b0", "#009688"}
+
+// CollabColor returns the color for the user of given number in the
+// collaborative editing session -- see TextViewCollabColors
+func CollabColor(site int) gi.Color {
+	clr, _ := gi.ColorFromString(TextViewCollabColors[ints.MaxInt(site-1, 0)%len(TextViewCollabColors)], nil)
+	return clr
+}
+
+// CollabSel sends the cursor and selection to the other users in the
+// collaborative editing session of the buffer, if any
+func (tv *TextView) CollabSel() {
+	if tv.Buf == nil {
+		return
+	}
+	anchor := tv.CursorPos
+	if tv.HasSelection() {
+		if tv.CursorPos == tv.SelectReg.Start {
+			anchor = tv.SelectReg.End
+		} else {
+			anchor = tv.SelectReg.Start
+		}
+	}
+	tv.Buf.CollabSetSel(anchor, tv.CursorPos)
+}
+
+// RenderCollabSels renders the selections of the other users in the
+// collaborative editing session of the buffer, in a shade of their color
+func (tv *TextView) RenderCollabSels() {
+	if tv.Buf == nil {
+		return
+	}
+	sty := &tv.Sty
+	for _, pr := range tv.Buf.CollabPeers() {
+		if pr.Anchor == pr.Head {
+			continue
+		}
+		clr := CollabColor(pr.Site)
+		var bg gi.ColorSpec
+		bg.SetColor(sty.Font.BgColor.Color.Blend(30, clr))
+		tv.RenderRegionBoxSty(textbuf.NewRegionPos(pr.Anchor, pr.Head), sty, &bg)
+	}
+}
+
+// RenderCollabCursors renders the cursors of the other users in the
+// collaborative editing session of the buffer that are within given
+// lines, in their color, with their name above them
+func (tv *TextView) RenderCollabCursors(stln, edln int) {
+	if tv.Buf == nil {
+		return
+	}
+	rs := tv.Render()
+	sty := &tv.Sty
+	spc := sty.BoxSpace()
+	for _, pr := range tv.Buf.CollabPeers() {
+		ln := pr.Head.Ln
+		if ln < stln || ln > edln || tv.IsLineHidden(ln) {
+			continue
+		}
+		clr := CollabColor(pr.Site)
+		pos := tv.CharStartPos(pr.Head)
+		rs.Paint.FillBoxColor(rs, pos, mat32.Vec2{X: 2, Y: tv.FontHeight}, clr)
+		if pr.Name == "" {
+			continue
+		}
+		fst := sty.Font
+		fst.BgColor.SetColor(nil)
+		fst.Color = sty.Font.BgColor.Color
+		tv.collabRender.SetString(pr.Name, &fst, &sty.UnContext, &sty.Text, true, 0, 0)
+		sz := tv.collabRender.Size
+		pos.Y -= tv.LineHeight
+		if ln == 0 || tv.IsLineHidden(ln-1) { // no room above
+			pos.Y += 2 * tv.LineHeight
+		}
+		rs.Paint.FillBoxColor(rs, pos, mat32.Vec2{X: sz.X + 2*spc, Y: tv.LineHeight}, clr)
+		pos.X += spc
+		pos.Y += mat32.FromFixed(sty.Font.Face.Face.Metrics().Ascent) - mat32.FromFixed(sty.Font.Face.Face.Metrics().Descent)
+		tv.collabRender.Render(rs, pos)
+	}
+}
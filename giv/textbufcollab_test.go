@@ -0,0 +1,85 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goki/gi/giv/collab"
+	"github.com/goki/pi/lex"
+)
+
+func TestTextBufCollab(t *testing.T) {
+	text := func(tb *TextBuf) string {
+		return strings.Join(tb.Strings(false), "\n")
+	}
+	host := newTestTextBuf()
+	host.SetText([]byte("func main() {\n}\n"))
+	sv, err := host.HostCollab("", "host")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer host.LeaveCollab()
+
+	guest := newTestTextBuf()
+	guest.SetText([]byte("old text"))
+	ca, cb := collab.Pipe()
+	go sv.Serve(cb)
+	if err := guest.JoinCollab(ca, "guest"); err != nil {
+		t.Fatal(err)
+	}
+	if text(guest) != text(host) {
+		t.Fatalf("joined text: %q, expected: %q", text(guest), text(host))
+	}
+	waitFor(t, "peers", func() bool {
+		hp, gp := host.CollabPeers(), guest.CollabPeers()
+		return len(hp) == 1 && hp[0].Name == "guest" && len(gp) == 1 && gp[0].Name == "host"
+	})
+
+	// concurrent edits on both sides
+	host.InsertText(lex.Pos{Ln: 1}, []byte("\tprintln(\"hi\")\n"), true)
+	guest.InsertText(lex.Pos{Ln: 0, Ch: 13}, []byte(" // entry"), true)
+	guest.DeleteText(lex.Pos{Ln: 0, Ch: 5}, lex.Pos{Ln: 0, Ch: 9}, true)
+	guest.InsertText(lex.Pos{Ln: 0, Ch: 5}, []byte("start"), true)
+	exp := "func start() { // entry\n\tprintln(\"hi\")\n}"
+	waitFor(t, "convergence", func() bool {
+		return text(host) == exp && text(guest) == exp
+	})
+	if sv.Text() != exp {
+		t.Errorf("server text: %q", sv.Text())
+	}
+
+	// selections are shown to the other
+	guest.CollabSetSel(lex.Pos{Ln: 1, Ch: 1}, lex.Pos{Ln: 1, Ch: 8})
+	waitFor(t, "selection", func() bool {
+		hp := host.CollabPeers()
+		return len(hp) == 1 && hp[0].Anchor == (lex.Pos{Ln: 1, Ch: 1}) && hp[0].Head == (lex.Pos{Ln: 1, Ch: 8})
+	})
+	host.InsertText(lex.Pos{Ln: 0}, []byte("// main\n"), true)
+	waitFor(t, "moved selection", func() bool {
+		hp := host.CollabPeers()
+		return text(guest) == text(host) && len(hp) == 1 && hp[0].Head == (lex.Pos{Ln: 2, Ch: 8})
+	})
+
+	// undo only undoes the edits of the buffer (grouped in time), where
+	// they now are
+	guest.Undo()
+	exp = "// main\nfunc main() {\n\tprintln(\"hi\")\n}"
+	waitFor(t, "undo", func() bool {
+		return text(host) == exp && text(guest) == exp
+	})
+
+	guest.LeaveCollab()
+	waitFor(t, "leave", func() bool {
+		return len(host.CollabPeers()) == 0
+	})
+	guest.InsertText(lex.Pos{}, []byte("x"), true)
+	time.Sleep(20 * time.Millisecond)
+	if text(host) != exp {
+		t.Errorf("edit after leaving was sent: %q", text(host))
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"sort"
+
+	"github.com/goki/pi/lex"
+)
+
+// textBufLine is the highlighting and markup state of one line of a TextBuf
+type textBufLine struct {
+	ln     int      // line number
+	hi     lex.Line // syntax highlighting tags
+	tags   lex.Line // extra custom tags
+	markup []byte   // markup given for the line, used instead of generating it
+}
+
+// empty returns true if the line has no state to keep
+func (bl *textBufLine) empty() bool {
+	return len(bl.hi) == 0 && len(bl.tags) == 0 && bl.markup == nil
+}
+
+// textBufLines is the highlighting and markup state of the lines of a
+// TextBuf that have any, sorted by line -- lines without any are not
+// stored, so that it takes memory only in proportion to the highlighted
+// lines, and not to the lines of the text.
+type textBufLines []textBufLine
+
+// find returns the index of the state of given line, or where it would be
+// inserted, and whether it is there
+func (tl textBufLines) find(ln int) (int, bool) {
+	i := sort.Search(len(tl), func(i int) bool { return tl[i].ln >= ln })
+	return i, i < len(tl) && tl[i].ln == ln
+}
+
+// get returns the state of given line, or nil if it has none -- it must not
+// be kept across changes of the lines
+func (tl textBufLines) get(ln int) *textBufLine {
+	if i, ok := tl.find(ln); ok {
+		return &tl[i]
+	}
+	return nil
+}
+
+// hi returns the syntax highlighting tags of given line
+func (tl textBufLines) hi(ln int) lex.Line {
+	if bl := tl.get(ln); bl != nil {
+		return bl.hi
+	}
+	return nil
+}
+
+// tags returns the extra custom tags of given line
+func (tl textBufLines) tags(ln int) lex.Line {
+	if bl := tl.get(ln); bl != nil {
+		return bl.tags
+	}
+	return nil
+}
+
+// set calls given function to update the state of given line, adding or
+// removing the line as needed
+func (tl *textBufLines) set(ln int, fun func(bl *textBufLine)) {
+	i, ok := tl.find(ln)
+	if ok {
+		fun(&(*tl)[i])
+		if (*tl)[i].empty() {
+			*tl = append((*tl)[:i], (*tl)[i+1:]...)
+		}
+		return
+	}
+	bl := textBufLine{ln: ln}
+	fun(&bl)
+	if bl.empty() {
+		return
+	}
+	*tl = append(*tl, textBufLine{})
+	copy((*tl)[i+1:], (*tl)[i:])
+	(*tl)[i] = bl
+}
+
+// setHi sets the syntax highlighting tags of given line
+func (tl *textBufLines) setHi(ln int, hi lex.Line) {
+	tl.set(ln, func(bl *textBufLine) { bl.hi = hi })
+}
+
+// setTags sets the extra custom tags of given line
+func (tl *textBufLines) setTags(ln int, tags lex.Line) {
+	tl.set(ln, func(bl *textBufLine) { bl.tags = tags })
+}
+
+// setMarkup sets the markup given for given line -- nil to generate it
+func (tl *textBufLines) setMarkup(ln int, markup []byte) {
+	tl.set(ln, func(bl *textBufLine) { bl.markup = markup })
+}
+
+// setAllHi sets the syntax highlighting tags of all lines before given
+// line to those returned by given function
+func (tl *textBufLines) setAllHi(nln int, hi func(ln int) lex.Line) {
+	old := *tl
+	nl := make(textBufLines, 0, len(old))
+	oi := 0
+	for ln := 0; ln < nln; ln++ {
+		bl := textBufLine{ln: ln}
+		if oi < len(old) && old[oi].ln == ln {
+			bl = old[oi]
+			oi++
+		}
+		bl.hi = hi(ln)
+		if !bl.empty() {
+			nl = append(nl, bl)
+		}
+	}
+	*tl = append(nl, old[oi:]...)
+}
+
+// inserted updates the lines for n lines inserted before given line
+func (tl textBufLines) inserted(stln, n int) {
+	i, _ := tl.find(stln)
+	for ; i < len(tl); i++ {
+		tl[i].ln += n
+	}
+}
+
+// deleted updates the lines for the lines from st to ed (exclusive) being
+// deleted
+func (tl *textBufLines) deleted(st, ed int) {
+	i, _ := tl.find(st)
+	j, _ := tl.find(ed)
+	*tl = append((*tl)[:i], (*tl)[j:]...)
+	n := ed - st
+	for k := i; k < len(*tl); k++ {
+		(*tl)[k].ln -= n
+	}
+}
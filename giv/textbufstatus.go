@@ -0,0 +1,164 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"fmt"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/textbuf"
+	"github.com/goki/gi/units"
+	"github.com/goki/ki/ki"
+	"github.com/goki/ki/kit"
+)
+
+// TextBufStatus is a status indicator for a TextBuf, showing the encoding
+// and line endings of its file, with menus of actions to convert them --
+// typically placed below the TextView for the buffer.
+type TextBufStatus struct {
+	gi.Layout
+	Buf *TextBuf `json:"-" xml:"-" desc:"the text buffer that the status is for"`
+}
+
+var KiT_TextBufStatus = kit.Types.AddType(&TextBufStatus{}, TextBufStatusProps)
+
+// AddNewTextBufStatus adds a new status indicator to given parent node,
+// with given name.
+func AddNewTextBufStatus(parent ki.Ki, name string) *TextBufStatus {
+	return parent.AddNewChild(KiT_TextBufStatus, name).(*TextBufStatus)
+}
+
+// TextBufStatusProps are style properties for TextBufStatus
+var TextBufStatusProps = ki.Props{
+	"EnumType:Flag": gi.KiT_NodeFlags,
+	"max-width":     -1,
+	"spacing":       units.NewEx(1),
+}
+
+// SetBuf sets the buffer that the status is for, and updates the status
+// whenever the buffer gets new text or is saved
+func (ts *TextBufStatus) SetBuf(tb *TextBuf) {
+	if ts.Buf != nil {
+		ts.Buf.TextBufSig.Disconnect(ts.This())
+	}
+	ts.Buf = tb
+	if tb != nil {
+		tb.TextBufSig.Connect(ts.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			tsf := recv.Embed(KiT_TextBufStatus).(*TextBufStatus)
+			switch TextBufSignals(sig) {
+			case TextBufNew, TextBufDone, TextBufClosed:
+				tsf.UpdateStatus()
+			}
+		})
+	}
+	ts.Config()
+}
+
+// Config configures the widgets of the status
+func (ts *TextBufStatus) Config() {
+	ts.Lay = gi.LayoutHoriz
+	config := kit.TypeAndNameList{}
+	config.Add(gi.KiT_Stretch, "stretch")
+	config.Add(gi.KiT_MenuButton, "encoding")
+	config.Add(gi.KiT_MenuButton, "line-ends")
+	mods, updt := ts.ConfigChildren(config, ki.UniqueNames)
+	if mods {
+		for _, nm := range []string{"encoding", "line-ends"} {
+			mb := ts.ChildByName(nm, 1).(*gi.MenuButton)
+			mb.SetProp("padding", units.NewPx(2))
+			mb.SetProp("margin", units.NewPx(2))
+		}
+	}
+	ts.UpdateStatus()
+	ts.UpdateEnd(updt)
+}
+
+// EncodingButton returns the menu button showing the encoding
+func (ts *TextBufStatus) EncodingButton() *gi.MenuButton {
+	return ts.ChildByName("encoding", 1).(*gi.MenuButton)
+}
+
+// LineEndsButton returns the menu button showing the line endings
+func (ts *TextBufStatus) LineEndsButton() *gi.MenuButton {
+	return ts.ChildByName("line-ends", 2).(*gi.MenuButton)
+}
+
+// UpdateStatus updates the status display and menus from the buffer
+func (ts *TextBufStatus) UpdateStatus() {
+	if !ts.HasChildren() {
+		return
+	}
+	updt := ts.UpdateStart()
+	defer ts.UpdateEnd(updt)
+	emb := ts.EncodingButton()
+	lmb := ts.LineEndsButton()
+	emb.ResetMenu()
+	lmb.ResetMenu()
+	tb := ts.Buf
+	if tb == nil {
+		emb.SetText("")
+		lmb.SetText("")
+		return
+	}
+	emb.SetText(tb.Opts.Encoding.Name())
+	emb.Tooltip = "character encoding of the file -- convert to another encoding for saving, or reopen the file in another encoding"
+	lmb.SetText(tb.Opts.LineEnds.String())
+	lmb.Tooltip = "line endings of the file -- convert to other line endings for saving"
+	for enc := textbuf.UTF8; enc < textbuf.EncodingsN; enc++ {
+		ac := emb.Menu.AddAction(gi.ActOpts{Label: "Convert to " + enc.Name(), Data: enc},
+			ts.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+				tsf := recv.Embed(KiT_TextBufStatus).(*TextBufStatus)
+				tsf.ConvertEncoding(data.(textbuf.Encodings))
+			})
+		ac.SetInactiveState(enc == tb.Opts.Encoding)
+	}
+	emb.Menu.AddSeparator("sep-reopen")
+	for enc := textbuf.UTF8; enc < textbuf.EncodingsN; enc++ {
+		ac := emb.Menu.AddAction(gi.ActOpts{Label: "Reopen as " + enc.Name(), Data: enc},
+			ts.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+				tsf := recv.Embed(KiT_TextBufStatus).(*TextBufStatus)
+				tsf.ReopenEncoding(data.(textbuf.Encodings))
+			})
+		ac.SetInactiveState(tb.Filename == "")
+	}
+	for le := textbuf.LF; le < textbuf.LineEndsN; le++ {
+		ac := lmb.Menu.AddAction(gi.ActOpts{Label: "Convert to " + le.String(), Data: le},
+			ts.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+				tsf := recv.Embed(KiT_TextBufStatus).(*TextBufStatus)
+				tsf.Buf.SetLineEnds(data.(textbuf.LineEnds))
+				tsf.UpdateStatus()
+			})
+		ac.SetInactiveState(le == tb.Opts.LineEnds)
+	}
+}
+
+// ConvertEncoding sets the encoding for saving the buffer, warning if
+// there are characters in it that can not be represented in the encoding
+func (ts *TextBufStatus) ConvertEncoding(enc textbuf.Encodings) {
+	if nbad := ts.Buf.SetEncoding(enc); nbad > 0 {
+		gi.PromptDialog(ts.Viewport, gi.DlgOpts{Title: "Characters Not Representable in Encoding",
+			Prompt: fmt.Sprintf("%d characters in the text can not be represented in the %v encoding -- you will be asked what to do when saving", nbad, enc.Name())},
+			gi.AddOk, gi.NoCancel, nil, nil)
+	}
+	ts.UpdateStatus()
+}
+
+// ReopenEncoding re-opens the file of the buffer in given encoding, asking
+// first if there are changes, which are lost
+func (ts *TextBufStatus) ReopenEncoding(enc textbuf.Encodings) {
+	tb := ts.Buf
+	if !tb.IsChanged() {
+		tb.ReopenEncoding(enc)
+		return
+	}
+	gi.ChoiceDialog(ts.Viewport, gi.DlgOpts{Title: "Reopen and Lose Changes?",
+		Prompt: fmt.Sprintf("Reopening the file as %v loses the changes made to it -- reopen anyway?  File: %v", enc.Name(), tb.Filename)},
+		[]string{"Reopen", "Cancel"},
+		ts.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			if sig == 0 {
+				tb.ReopenEncoding(enc)
+			}
+		})
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/textbuf"
+	"github.com/goki/gi/giv/vfs"
+	"github.com/goki/gi/oswin"
+	"github.com/goki/ki/ki"
+)
+
+/////////////////////////////////////////////////////////////////////////////
+//   Undo History
+
+// PrefsUndoHistDir is the directory in GoGi standard prefs directory in
+// which the undo histories of files are saved -- see TextBuf.SaveUndoHist.
+// Each is saved in a JSON file named by a hash of the absolute path of the
+// file (see textbuf.UndoHistFilename), and is deleted when the file is
+// saved with no edits left to undo or redo, or when it has not been saved
+// for UndoHistDays (checked whenever a history is saved).  Turning the
+// UndoHist option off leaves the saved histories in place -- delete the
+// directory to remove them.
+var PrefsUndoHistDir = "undo"
+
+// TextBufUndoHistDir is the directory in which the undo histories of files
+// are saved, if set -- otherwise PrefsUndoHistDir is used
+var TextBufUndoHistDir = ""
+
+// UndoHistDir returns the directory in which the undo histories of files
+// are saved, or "" if there is none
+func UndoHistDir() string {
+	if TextBufUndoHistDir != "" {
+		return TextBufUndoHistDir
+	}
+	if oswin.TheApp == nil {
+		return ""
+	}
+	return filepath.Join(oswin.TheApp.GoGiPrefsDir(), PrefsUndoHistDir)
+}
+
+// SaveUndoHist saves the undo history of the file, if the UndoHist option
+// is on (it is off by default, as the history has the text of all the
+// edits) and the text is the same as that of the file when it was last
+// opened or saved (see FileHash), so that it is restored when the file is
+// opened again, unchanged -- see OpenUndoHist.  It is limited to the
+// UndoHistMax most recent edits, with at most UndoHistKB of text, made in
+// the last UndoHistDays, and the histories of other files that have not
+// been saved in UndoHistDays are deleted -- see UndoHistDir.
+func (tb *TextBuf) SaveUndoHist() error {
+	dir := UndoHistDir()
+	if !tb.Opts.UndoHist || tb.Filename == "" || tb.FileHash == "" || dir == "" || tb.Undos.Off || !vfs.IsOS(tb.FS) {
+		return nil
+	}
+	b, _ := textbuf.EncodeText(tb.LinesToBytesCopy(), tb.Opts.Encoding, tb.Opts.LineEnds)
+	if textbuf.ContentHash(b) != tb.FileHash {
+		return nil
+	}
+	var since time.Time
+	if tb.Opts.UndoHistDays > 0 {
+		since = time.Now().AddDate(0, 0, -tb.Opts.UndoHistDays)
+		textbuf.CleanUndoHists(dir, since)
+	}
+	fnm := textbuf.UndoHistFilename(dir, string(tb.Filename))
+	uh := tb.Undos.Hist(tb.Opts.UndoHistMax, tb.Opts.UndoHistKB*1024, since)
+	if len(uh.Edits) == 0 {
+		os.Remove(fnm)
+		return nil
+	}
+	uh.Path, _ = filepath.Abs(string(tb.Filename))
+	uh.Hash = tb.FileHash
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Println(err)
+		return err
+	}
+	err := uh.SaveJSON(fnm)
+	if err != nil {
+		log.Println(err)
+	}
+	return err
+}
+
+// OpenUndoHist restores the undo history of the file saved by
+// SaveUndoHist, if the UndoHist option is on and the file has not changed
+// since -- called when the file is opened
+func (tb *TextBuf) OpenUndoHist() error {
+	dir := UndoHistDir()
+	if !tb.Opts.UndoHist || tb.Filename == "" || dir == "" || tb.Undos.Off || !vfs.IsOS(tb.FS) {
+		return nil
+	}
+	uh := &textbuf.UndoHist{}
+	err := uh.OpenJSON(textbuf.UndoHistFilename(dir, string(tb.Filename)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if path, _ := filepath.Abs(string(tb.Filename)); uh.Path != path || uh.Hash != tb.FileHash {
+		return nil // changed since
+	}
+	tb.Undos.SetHist(uh)
+	return nil
+}
+
+// UndoState is a state of the text that can be returned to by undo or redo,
+// after a group of edits -- see UndoStates
+type UndoState struct {
+	When  string `width:"16" desc:"when the last of the edits was made"`
+	Edits string `width:"60" desc:"the edits that result in this state"`
+	Pos   int    `view:"-" desc:"position in the undo stack of this state"`
+}
+
+// UndoStates returns the states of the text that can be returned to by
+// undo or redo, starting with the original text, and the index of the
+// current state
+func (tb *TextBuf) UndoStates() ([]UndoState, int) {
+	stack, pos := tb.Undos.Edits()
+	sts := []UndoState{{Edits: "original text"}}
+	cur := 0
+	for st := 0; st < len(stack); {
+		ed := st + 1
+		for ed < len(stack) && stack[ed].Group == stack[st].Group {
+			ed++
+		}
+		us := UndoState{When: stack[ed-1].Reg.Time.Time().Format("Jan 2 15:04:05"), Edits: UndoEditDesc(stack[st]), Pos: ed}
+		if ed-st > 1 {
+			us.Edits += fmt.Sprintf(" (+%d more)", ed-st-1)
+		}
+		if st < pos {
+			cur = len(sts)
+		}
+		sts = append(sts, us)
+		st = ed
+	}
+	return sts, cur
+}
+
+// UndoEditDesc returns a short description of given edit, for the undo
+// history
+func UndoEditDesc(tbe *textbuf.Edit) string {
+	txt := strings.Replace(string(tbe.ToBytes()), "\n", "⏎", -1)
+	if rs := []rune(txt); len(rs) > 30 {
+		txt = string(rs[:30]) + "…"
+	}
+	verb := "insert"
+	if tbe.Delete {
+		verb = "delete"
+	}
+	return fmt.Sprintf("%v %q at %d:%d", verb, txt, tbe.Reg.Start.Ln+1, tbe.Reg.Start.Ch+1)
+}
+
+// UndoTo undoes or redoes edits to return the text to the state at given
+// position in the undo stack, which must be after a group of edits -- see
+// UndoStates
+func (tb *TextBuf) UndoTo(pos int) {
+	for tb.Undos.Pos > pos {
+		if tb.Undo() == nil {
+			break
+		}
+	}
+	for tb.Undos.Pos < pos {
+		if tb.Redo() == nil {
+			break
+		}
+	}
+}
+
+// UndoHistDialog opens a dialog showing the states of the text of the
+// buffer that can be returned to by undo or redo, and returns to the
+// state that is selected
+func UndoHistDialog(avp *gi.Viewport2D, tb *TextBuf) *gi.Dialog {
+	sts, cur := tb.UndoStates()
+	return TableViewSelectDialog(avp, &sts, DlgOpts{Title: "Undo History", Prompt: "Select the state of the text to return to"}, cur, nil,
+		tb.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			if sig != int64(gi.DialogAccepted) {
+				return
+			}
+			ddlg := send.Embed(gi.KiT_Dialog).(*gi.Dialog)
+			if idx := TableViewSelectDialogValue(ddlg); idx >= 0 && idx < len(sts) {
+				tb.UndoTo(sts[idx].Pos)
+			}
+		})
+}
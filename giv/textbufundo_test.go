@@ -0,0 +1,82 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/pi/lex"
+)
+
+// testIconMgr is an icon manager without icons, for opening files, which
+// looks up their icons, without loading the svg package
+type testIconMgr struct{}
+
+func (im *testIconMgr) IsValid(iconName string) bool               { return false }
+func (im *testIconMgr) SetIcon(ic *gi.Icon, iconName string) error { return nil }
+func (im *testIconMgr) IconList(alphaSort bool) []gi.IconName      { return nil }
+
+func TestTextBufUndoHist(t *testing.T) {
+	if gi.TheIconMgr == nil {
+		gi.TheIconMgr = &testIconMgr{}
+	}
+	TextBufUndoHistDir = t.TempDir()
+	defer func() { TextBufUndoHistDir = "" }()
+	fnm := gi.FileName(filepath.Join(t.TempDir(), "file.xyz"))
+	if err := ioutil.WriteFile(string(fnm), []byte("one\ntwo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	open := func() *TextBuf {
+		tb := newTestTextBuf()
+		tb.Opts.UndoHist = true
+		if err := tb.Open(fnm); err != nil {
+			t.Fatal(err)
+		}
+		return tb
+	}
+	text := func(tb *TextBuf) string {
+		return strings.Join(tb.Strings(false), "\n")
+	}
+
+	tb := open()
+	tb.InsertText(lex.Pos{Ln: 0, Ch: 3}, []byte(" 1"), true)
+	tb.Undos.NewGroup()
+	tb.DeleteText(lex.Pos{Ln: 1}, lex.Pos{Ln: 1, Ch: 3}, true)
+	tb.InsertText(lex.Pos{Ln: 1}, []byte("2"), true)
+	if err := tb.Save(); err != nil {
+		t.Fatal(err)
+	}
+	tb.Close(nil)
+
+	tb = open()
+	sts, cur := tb.UndoStates()
+	if len(sts) != 3 || cur != 2 || sts[1].Edits != `insert " 1" at 1:4` || sts[2].Edits != `delete "two" at 2:1 (+1 more)` {
+		t.Errorf("states: %+v, current: %d", sts, cur)
+	}
+	tb.UndoTo(sts[0].Pos)
+	if text(tb) != "one\ntwo" {
+		t.Errorf("restored undo: %q", text(tb))
+	}
+	tb.UndoTo(sts[1].Pos)
+	if text(tb) != "one 1\ntwo" {
+		t.Errorf("redo to state: %q", text(tb))
+	}
+	if _, cur = tb.UndoStates(); cur != 1 {
+		t.Errorf("current state: %d", cur)
+	}
+	tb.Close(nil) // the text differs from the file, so the history is kept
+
+	if err := ioutil.WriteFile(string(fnm), []byte("changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tb = open()
+	if sts, _ = tb.UndoStates(); len(sts) != 1 {
+		t.Errorf("history of changed file: %+v", sts)
+	}
+}
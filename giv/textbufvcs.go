@@ -0,0 +1,262 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/vcs"
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/textbuf"
+	"github.com/goki/pi/lex"
+	"github.com/goki/vci"
+)
+
+// VcsBlameLine is who last changed a line of a file in version control
+type VcsBlameLine struct {
+	Rev     string    `desc:"revision of the last change"`
+	Author  string    `desc:"author of the last change"`
+	Date    time.Time `desc:"date of the last change"`
+	Summary string    `desc:"first line of the commit message of the last change"`
+}
+
+// String returns the blame as author, date and summary
+func (bl *VcsBlameLine) String() string {
+	return fmt.Sprintf("%s, %s: %s", bl.Author, bl.Date.Format("2006-01-02"), bl.Summary)
+}
+
+// VcsBlame returns who last changed each line of the last committed version
+// of given file in given repository -- only supported for git so far.
+func VcsBlame(repo vci.Repo, fname string) ([]VcsBlameLine, error) {
+	if repo.Vcs() != vcs.Git {
+		return nil, fmt.Errorf("giv.VcsBlame: line by line blame not supported for: %v", repo.Vcs())
+	}
+	out, err := repo.RunFromDir("git", "blame", "--line-porcelain", "HEAD", "--", vci.RelPath(repo, fname))
+	if err != nil {
+		return nil, err
+	}
+	return ParseGitBlame(out), nil
+}
+
+// ParseGitBlame parses the output of git blame --line-porcelain into the
+// blame for each line
+func ParseGitBlame(out []byte) []VcsBlameLine {
+	var bls []VcsBlameLine
+	var cur VcsBlameLine
+	scan := bufio.NewScanner(bytes.NewReader(out))
+	scan.Buffer(nil, 1024*1024)
+	for scan.Scan() {
+		ln := scan.Text()
+		if strings.HasPrefix(ln, "\t") { // the line itself, which ends the entry
+			bls = append(bls, cur)
+			cur = VcsBlameLine{}
+			continue
+		}
+		key, val := ln, ""
+		if sp := strings.IndexByte(ln, ' '); sp >= 0 {
+			key, val = ln[:sp], ln[sp+1:]
+		}
+		switch {
+		case cur.Rev == "":
+			cur.Rev = key
+		case key == "author":
+			cur.Author = val
+		case key == "author-time":
+			if sec, err := strconv.ParseInt(val, 10, 64); err == nil {
+				cur.Date = time.Unix(sec, 0)
+			}
+		case key == "summary":
+			cur.Summary = val
+		}
+	}
+	return bls
+}
+
+// SetVcsBase sets the text of the version of the file in version control,
+// relative to which the changes of the lines are shown next to the line
+// numbers -- nil turns the changes off.  The changes are updated
+// incrementally as the text is edited.
+func (tb *TextBuf) SetVcsBase(base []byte) {
+	tb.LinesMu.Lock()
+	if base == nil {
+		tb.Changes = nil
+	} else {
+		txt, _, _ := textbuf.DecodeText(base)
+		lns := textbuf.BytesToLineStrings(txt, false)
+		if len(txt) > 0 && txt[len(txt)-1] == '\n' { // same as the lines of the buffer
+			lns = lns[:len(lns)-1]
+		}
+		tb.Changes = textbuf.NewChanges(lns, tb.Store)
+	}
+	tb.LinesMu.Unlock()
+	tb.RefreshViews()
+}
+
+// UpdateVcs gets the last committed version of the file from given
+// repository for the changes shown next to the line numbers, if the
+// VcsChanges option is on, and who last changed each line, for the inline
+// blame of the cursor line, if the VcsBlame option is on -- the blame is
+// obtained in the background, and shown in the views once it is there.
+// Files not in the repository have no changes.
+func (tb *TextBuf) UpdateVcs(repo vci.Repo) error {
+	fname := string(tb.Filename)
+	if repo == nil || fname == "" {
+		return nil
+	}
+	var win *gi.Window
+	if vp := tb.ViewportFromView(); vp != nil {
+		win = vp.Win
+	}
+	if tb.Opts.VcsChanges {
+		base, err := repo.FileContents(fname, "")
+		if err != nil {
+			tb.SetVcsBase(nil)
+			return err
+		}
+		tb.SetVcsBase(base)
+	}
+	if tb.Opts.VcsBlame {
+		go func() {
+			bls, err := VcsBlame(repo, fname)
+			if err != nil {
+				return
+			}
+			gi.PostUIFunc(win, func() {
+				tb.LinesMu.Lock()
+				tb.Blame = bls
+				tb.LinesMu.Unlock()
+				tb.RefreshViews()
+			})
+		}()
+	}
+	return nil
+}
+
+// ChangesEditImpl updates the Changes for an edit that replaced the lines
+// from st to ed (exclusive) with nln lines.  Must be called under LinesMu
+// lock, after the Store is updated.
+func (tb *TextBuf) ChangesEditImpl(st, ed, nln int) {
+	if tb.Changes == nil {
+		return
+	}
+	tb.Changes.Edit(tb.Store, st, ed, nln)
+}
+
+// ChangesUpdateImpl updates all of the Changes, for entirely new text.
+// Must be called under LinesMu lock.
+func (tb *TextBuf) ChangesUpdateImpl() {
+	if tb.Changes == nil {
+		return
+	}
+	tb.Changes.Update(tb.Store)
+}
+
+// LineChange returns how given line differs from the version of the file
+// in version control
+func (tb *TextBuf) LineChange(ln int) textbuf.LineChanges {
+	tb.LinesMu.RLock()
+	defer tb.LinesMu.RUnlock()
+	if tb.Changes == nil {
+		return textbuf.LineUnchanged
+	}
+	return tb.Changes.LineChange(ln)
+}
+
+// ChangeHunkAt returns the change to the version of the file in version
+// control at given line: the base lines, and the current lines that
+// replaced them -- false if the line is unchanged
+func (tb *TextBuf) ChangeHunkAt(ln int) (hk textbuf.ChangeHunk, base, cur []string, ok bool) {
+	tb.LinesMu.RLock()
+	defer tb.LinesMu.RUnlock()
+	if tb.Changes == nil {
+		return
+	}
+	i, ok := tb.Changes.HunkAt(ln)
+	if !ok {
+		return
+	}
+	hk = tb.Changes.Hunks[i]
+	base = tb.Changes.Base[hk.BaseSt:hk.BaseEd]
+	cur = make([]string, hk.Ed-hk.St)
+	for i := range cur {
+		cur[i] = string(tb.Store.Line(hk.St + i))
+	}
+	return
+}
+
+// ChangeText returns a description of the change to the version of the file
+// in version control at given line, as the deleted base lines prefixed
+// with - and the current lines prefixed with + -- empty if unchanged
+func (tb *TextBuf) ChangeText(ln int) string {
+	hk, base, cur, ok := tb.ChangeHunkAt(ln)
+	if !ok {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v: %d lines at %d\n", hk.Change, len(base)+len(cur), hk.St+1)
+	for _, l := range base {
+		b.WriteString("- " + l + "\n")
+	}
+	for _, l := range cur {
+		b.WriteString("+ " + l + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// RevertChange reverts the change to the version of the file in version
+// control at given line, as one edit that can be undone -- returns false
+// if the line is unchanged
+func (tb *TextBuf) RevertChange(ln int) bool {
+	hk, base, _, ok := tb.ChangeHunkAt(ln)
+	if !ok {
+		return false
+	}
+	st := lex.Pos{Ln: hk.St}
+	ed := lex.Pos{Ln: hk.Ed}
+	txt := strings.Join(base, "\n")
+	if len(base) > 0 {
+		txt += "\n"
+	}
+	if nln := tb.NumLines(); hk.Ed >= nln { // at the end, without a final newline
+		ed = tb.EndPos()
+		txt = strings.TrimSuffix(txt, "\n")
+		if hk.St >= nln {
+			st = ed
+			txt = "\n" + txt
+		} else if hk.St > 0 && len(base) == 0 { // delete the newline before
+			st = lex.Pos{Ln: hk.St - 1, Ch: tb.LineLen(hk.St - 1)}
+		}
+	}
+	tb.Undos.BatchStart()
+	if txt == "" {
+		tb.DeleteText(st, ed, EditSignal)
+	} else {
+		tb.ReplaceText(st, ed, st, txt, EditSignal, false)
+	}
+	tb.Undos.BatchEnd()
+	return true
+}
+
+// BlameAt returns who last changed given line, if the VcsBlame option is on
+// and the line is unchanged from the version of the file in version control
+// -- the Changes are needed to know which line that is after edits, so
+// there is no blame without them (i.e., if the VcsChanges option is off).
+func (tb *TextBuf) BlameAt(ln int) (VcsBlameLine, bool) {
+	tb.LinesMu.RLock()
+	defer tb.LinesMu.RUnlock()
+	if len(tb.Blame) == 0 || tb.Changes == nil {
+		return VcsBlameLine{}, false
+	}
+	bl := tb.Changes.BaseLine(ln)
+	if bl < 0 || bl >= len(tb.Blame) {
+		return VcsBlameLine{}, false
+	}
+	return tb.Blame[bl], true
+}
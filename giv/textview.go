@@ -5,15 +5,22 @@
 package giv
 
 import (
+	"bytes"
 	"fmt"
+	"html"
 	"image"
 	"image/draw"
+	"io/ioutil"
 	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 	"unicode"
 
+	"github.com/goki/gi/giv/lsp"
 	"github.com/goki/gi/giv/textbuf"
 	"github.com/goki/gi/histyle"
 	"github.com/goki/gi/oswin/cursor"
@@ -53,6 +60,7 @@ type TextView struct {
 	LineNoDigs             int                       `json:"-" xml:"-" desc:"number of line number digits needed"`
 	LineNoOff              float32                   `json:"-" xml:"-" desc:"horizontal offset for start of text after line numbers"`
 	LineNoRender           gi.TextRender             `json:"-" xml:"-" desc:"render for line numbers"`
+	MinimapOff             float32                   `json:"-" xml:"-" desc:"width of the minimap at the right side of the view, if it is shown -- see Minimap option"`
 	LinesSize              image.Point               `json:"-" xml:"-" desc:"total size of all lines as rendered"`
 	RenderSz               mat32.Vec2                `json:"-" xml:"-" desc:"size params to use in render call"`
 	CursorPos              lex.Pos                   `json:"-" xml:"-" desc:"current cursor position"`
@@ -64,10 +72,15 @@ type TextView struct {
 	PrevSelectReg          textbuf.Region            `json:"-" xml:"-" desc:"previous selection region, that was actually rendered -- needed to update render"`
 	Highlights             []textbuf.Region          `json:"-" xml:"-" desc:"highlighted regions, e.g., for search results"`
 	Scopelights            []textbuf.Region          `json:"-" xml:"-" desc:"highlighted regions, specific to scope markers"`
+	Folds                  []textbuf.Region          `json:"-" xml:"-" desc:"folded regions, in order of starting line -- the lines after the Start line, through the End line, are hidden -- adjusted for edits to the buffer"`
+	FoldHidden             []bool                    `json:"-" xml:"-" view:"-" desc:"for each line, true if it is hidden in a folded region -- computed from Folds"`
+	MultiCursors           []TextViewCursor          `json:"-" xml:"-" desc:"additional cursors beyond the main CursorPos, each with its own selection -- typing, deleting, pasting and cursor movement are applied at all of them -- see AddCursorBelow etc"`
 	SelectMode             bool                      `json:"-" xml:"-" desc:"if true, select text as cursor moves"`
 	ForceComplete          bool                      `json:"-" xml:"-" desc:"if true, complete regardless of any disqualifying reasons"`
 	ISearch                ISearch                   `json:"-" xml:"-" desc:"interactive search data"`
 	QReplace               QReplace                  `json:"-" xml:"-" desc:"query replace data"`
+	Vim                    VimState                  `json:"-" xml:"-" desc:"vim-style modal editing state -- used when the active key map is a vim map, see gi.ActiveKeyMapVim"`
+	Snippet                *TextViewSnippet          `json:"-" xml:"-" desc:"snippet being edited, if any -- see InsertSnippet"`
 	TextViewSig            ki.Signal                 `json:"-" xml:"-" view:"-" desc:"signal for text view -- see TextViewSignals for the types"`
 	LinkSig                ki.Signal                 `json:"-" xml:"-" view:"-" desc:"signal for clicking on a link -- data is a string of the URL -- if nobody receiving this signal, calls TextLinkHandler then URLHandler"`
 	StateStyles            [TextViewStatesN]gi.Style `json:"-" xml:"-" desc:"normal style and focus style"`
@@ -77,9 +90,21 @@ type TextView struct {
 	BlinkOn                bool                      `json:"-" xml:"-" desc:"oscillates between on and off for blinking"`
 	CursorMu               sync.Mutex                `json:"-" xml:"-" view:"-" desc:"mutex protecting cursor rendering -- shared between blink and main code"`
 	HasLinks               bool                      `json:"-" xml:"-" desc:"at least one of the renders has links -- determines if we set the cursor for hand movements"`
+	lineMarked             []bool                    // true for lines whose render has their markup -- see MarkupLines
 	lastRecenter           int
 	lastAutoInsert         rune
 	lastFilename           gi.FileName
+	foldRegs               []textbuf.Region
+	foldRegsOk             bool
+	multiSprites           int
+	colSelOn               bool
+	colSelStart            lex.Pos
+	minimapDrag            bool
+	minimapDragTop         int
+	overviewMarks          []gi.ScrollMark
+	blameRender            gi.TextRender
+	vimRender              gi.TextRender
+	collabRender           gi.TextRender
 }
 
 var KiT_TextView = kit.Types.AddType(&TextView{}, TextViewProps)
@@ -95,6 +120,20 @@ func (tv *TextView) Disconnect() {
 	tv.LinkSig.DisconnectAll()
 }
 
+// AccessInfo returns the accessibility info for the text view -- the text
+// itself is not included, as it can be large -- get it from the Buf
+func (tv *TextView) AccessInfo() gi.AccessInfo {
+	ai := tv.WidgetBase.AccessInfo()
+	ai.Role = gi.AccessDocument
+	ai.SetState(true, gi.AccessMultiLine)
+	ai.SetState(tv.IsInactive(), gi.AccessReadOnly)
+	ai.SetState(false, gi.AccessInactive)
+	if tv.Buf != nil {
+		ai.Name = string(tv.Buf.Filename)
+	}
+	return ai
+}
+
 var TextViewProps = ki.Props{
 	"EnumType:Flag":    KiT_TextViewFlags,
 	"white-space":      gi.WhiteSpacePreWrap,
@@ -305,6 +344,10 @@ func (tv *TextView) Clear() {
 func (tv *TextView) ResetState() {
 	tv.SelectReset()
 	tv.Highlights = nil
+	tv.Folds = nil
+	tv.FoldHidden = nil
+	tv.foldRegsOk = false
+	tv.MultiCursors = nil
 	tv.ISearch.On = false
 	tv.QReplace.On = false
 	if tv.Buf == nil || tv.lastFilename != tv.Buf.Filename { // don't reset if reopening..
@@ -363,6 +406,14 @@ func (tv *TextView) LinesInserted(tbe *textbuf.Edit) {
 	copy(nof[stln:], tmpof)
 	tv.Offs = nof
 
+	if stln <= len(tv.lineMarked) {
+		tmpmk := make([]bool, nsz)
+		nmk := append(tv.lineMarked, tmpmk...)
+		copy(nmk[stln+nsz:], nmk[stln:])
+		copy(nmk[stln:], tmpmk)
+		tv.lineMarked = nmk
+	}
+
 	tv.NLines += nsz
 
 	tv.LayoutLines(tbe.Reg.Start.Ln, tbe.Reg.End.Ln, false)
@@ -377,6 +428,9 @@ func (tv *TextView) LinesDeleted(tbe *textbuf.Edit) {
 
 	tv.Renders = append(tv.Renders[:stln], tv.Renders[edln:]...)
 	tv.Offs = append(tv.Offs[:stln], tv.Offs[edln:]...)
+	if edln <= len(tv.lineMarked) {
+		tv.lineMarked = append(tv.lineMarked[:stln], tv.lineMarked[edln:]...)
+	}
 
 	tv.NLines -= dsz
 
@@ -387,6 +441,15 @@ func (tv *TextView) LinesDeleted(tbe *textbuf.Edit) {
 // TextViewBufSigRecv receives a signal from the buffer and updates view accordingly
 func TextViewBufSigRecv(rvwki ki.Ki, sbufki ki.Ki, sig int64, data interface{}) {
 	tv := rvwki.Embed(KiT_TextView).(*TextView)
+	switch TextBufSignals(sig) {
+	case TextBufInsert, TextBufDelete:
+		tbe := data.(*textbuf.Edit) // adjust even if not visible
+		tv.AdjustFolds(tbe)
+		tv.AdjustMultiCursors(tbe)
+		tv.AdjustSnippet(tbe)
+	case TextBufMarkUpdt:
+		tv.foldRegsOk = false
+	}
 	if !tv.This().(gi.Node2D).IsVisible() {
 		return
 	}
@@ -411,7 +474,7 @@ func TextViewBufSigRecv(rvwki ki.Ki, sbufki ki.Ki, sig int64, data interface{})
 				// fmt.Printf("tv %v line insert rerend %v - %v\n", tv.Nm, tbe.Reg.Start, tbe.Reg.End)
 				tv.RenderAllLines()
 			} else {
-				// fmt.Printf("tv %v line insert no rerend %v - %v.  markup: %v\n", tv.Nm, tbe.Reg.Start, tbe.Reg.End, len(tv.Buf.HiTags[tbe.Reg.Start.Ln]))
+				// fmt.Printf("tv %v line insert no rerend %v - %v\n", tv.Nm, tbe.Reg.Start, tbe.Reg.End)
 				tv.RenderLines(tbe.Reg.Start.Ln, tbe.Reg.End.Ln)
 			}
 		}
@@ -479,7 +542,7 @@ func (tv *TextView) RenderSize() mat32.Vec2 {
 		tv.RenderSz = sz
 		// fmt.Printf("fallback rendersz: %v\n", tv.RenderSz)
 	}
-	tv.RenderSz.X -= tv.LineNoOff
+	tv.RenderSz.X -= tv.LineNoOff + tv.MinimapOff
 	// fmt.Printf("rendersz: %v\n", tv.RenderSz)
 	return tv.RenderSz
 }
@@ -498,8 +561,9 @@ func (tv *TextView) HiStyle() {
 }
 
 // LayoutAllLines generates TextRenders of lines from our TextBuf, from the
-// Markup version of the source, and returns whether the current rendered size
-// is different from what it was previously
+// plain text of the lines -- their markup is only used as they are viewed
+// (see MarkupLines) -- and returns whether the current rendered size is
+// different from what it was previously
 func (tv *TextView) LayoutAllLines(inLayout bool) bool {
 	if inLayout && tv.HasFlag(int(TextViewInReLayout)) {
 		return false
@@ -524,6 +588,8 @@ func (tv *TextView) LayoutAllLines(inLayout bool) bool {
 
 	tv.NLines = tv.Buf.NumLines()
 	nln := tv.NLines
+	tv.foldRegsOk = false
+	tv.UpdateFoldHidden()
 	if cap(tv.Renders) >= nln {
 		tv.Renders = tv.Renders[:nln]
 	} else {
@@ -534,31 +600,26 @@ func (tv *TextView) LayoutAllLines(inLayout bool) bool {
 	} else {
 		tv.Offs = make([]float32, nln)
 	}
+	if cap(tv.lineMarked) >= nln {
+		tv.lineMarked = tv.lineMarked[:nln]
+	} else {
+		tv.lineMarked = make([]bool, nln)
+	}
 
 	tv.VisSizes()
 	sz := tv.RenderSz
 
 	// fmt.Printf("rendersize: %v\n", sz)
-	sty := &tv.Sty
-	fst := sty.Font
-	fst.BgColor.SetColor(nil)
 	off := float32(0)
 	mxwd := sz.X // always start with our render size
 
-	tv.Buf.MarkupMu.RLock()
 	tv.HasLinks = false
 	for ln := 0; ln < nln; ln++ {
-		tv.Renders[ln].SetHTMLPre(tv.Buf.Markup[ln], &fst, &sty.Text, &sty.UnContext, tv.CSS)
-		tv.Renders[ln].LayoutStdLR(&sty.Text, &sty.Font, &sty.UnContext, sz)
-		if !tv.HasLinks && len(tv.Renders[ln].Links) > 0 {
-			tv.HasLinks = true
-		}
+		tv.LayoutLine(ln, false)
 		tv.Offs[ln] = off
-		lsz := mat32.Max(tv.Renders[ln].Size.Y, tv.LineHeight)
-		off += lsz
+		off += tv.LineDispHeight(ln)
 		mxwd = mat32.Max(mxwd, tv.Renders[ln].Size.X)
 	}
-	tv.Buf.MarkupMu.RUnlock()
 
 	extraHalf := tv.LineHeight * 0.5 * float32(tv.VisSize.Y)
 	nwSz := mat32.Vec2{mxwd, off + extraHalf}.ToPointCeil()
@@ -575,8 +636,8 @@ func (tv *TextView) SetSize() bool {
 	sty := &tv.Sty
 	spc := sty.BoxSpace()
 	rndsz := tv.RenderSz
-	rndsz.X += tv.LineNoOff
-	netsz := mat32.Vec2{float32(tv.LinesSize.X) + tv.LineNoOff, float32(tv.LinesSize.Y)}
+	rndsz.X += tv.LineNoOff + tv.MinimapOff
+	netsz := mat32.Vec2{float32(tv.LinesSize.X) + tv.LineNoOff + tv.MinimapOff, float32(tv.LinesSize.Y)}
 	cursz := tv.LayState.Alloc.Size.SubScalar(2 * spc)
 	if cursz.X < 10 || cursz.Y < 10 {
 		nwsz := netsz.Max(rndsz)
@@ -631,27 +692,18 @@ func (tv *TextView) LayoutLines(st, ed int, isDel bool) bool {
 	if tv.Buf == nil || tv.Buf.NumLines() == 0 {
 		return false
 	}
-	sty := &tv.Sty
-	fst := sty.Font
-	fst.BgColor.SetColor(nil)
 	mxwd := float32(tv.LinesSize.X)
 	rerend := false
 
-	tv.Buf.MarkupMu.RLock()
 	for ln := st; ln <= ed; ln++ {
 		curspans := len(tv.Renders[ln].Spans)
-		tv.Renders[ln].SetHTMLPre(tv.Buf.Markup[ln], &fst, &sty.Text, &sty.UnContext, tv.CSS)
-		tv.Renders[ln].LayoutStdLR(&sty.Text, &sty.Font, &sty.UnContext, tv.RenderSz)
-		if !tv.HasLinks && len(tv.Renders[ln].Links) > 0 {
-			tv.HasLinks = true
-		}
+		tv.LayoutLine(ln, ln < len(tv.lineMarked) && tv.lineMarked[ln]) // marked lines are viewed
 		nwspans := len(tv.Renders[ln].Spans)
 		if nwspans != curspans && (nwspans > 1 || curspans > 1) {
 			rerend = true
 		}
 		mxwd = mat32.Max(mxwd, tv.Renders[ln].Size.X)
 	}
-	tv.Buf.MarkupMu.RUnlock()
 
 	// update all offsets to end of text
 	if rerend || isDel || st != ed {
@@ -662,8 +714,7 @@ func (tv *TextView) LayoutLines(st, ed int, isDel bool) bool {
 		off := tv.Offs[ofst]
 		for ln := ofst; ln < tv.NLines; ln++ {
 			tv.Offs[ln] = off
-			lsz := mat32.Max(tv.Renders[ln].Size.Y, tv.LineHeight)
-			off += lsz
+			off += tv.LineDispHeight(ln)
 		}
 		extraHalf := tv.LineHeight * 0.5 * float32(tv.VisSize.Y)
 		nwSz := mat32.Vec2{mxwd, off + extraHalf}.ToPointCeil()
@@ -676,6 +727,68 @@ func (tv *TextView) LayoutLines(st, ed int, isDel bool) bool {
 	return rerend
 }
 
+// LayoutLine generates the render of given line, from its markup if markup
+// is true, and otherwise from its plain text.  Generating the markup can
+// take a while for large texts, so it is only done for the lines that are
+// viewed -- see MarkupLines.
+func (tv *TextView) LayoutLine(ln int, markup bool) {
+	sty := &tv.Sty
+	fst := sty.Font
+	fst.BgColor.SetColor(nil)
+	var txt []byte
+	if markup {
+		txt = tv.Buf.LineMarkup(ln)
+	} else {
+		txt = HTMLEscapeRunes(tv.Buf.Line(ln))
+	}
+	tv.Renders[ln].SetHTMLPre(txt, &fst, &sty.Text, &sty.UnContext, tv.CSS)
+	tv.Renders[ln].LayoutStdLR(&sty.Text, &sty.Font, &sty.UnContext, tv.RenderSz)
+	if !tv.HasLinks && len(tv.Renders[ln].Links) > 0 {
+		tv.HasLinks = true
+	}
+	if ln < len(tv.lineMarked) {
+		tv.lineMarked[ln] = markup
+	}
+}
+
+// MarkupLines generates the renders of the lines from st to ed (inclusive)
+// from their markup, for those that only have their plain text so far --
+// it is called for the visible lines as they are rendered.  Returns true if
+// that changed the number of display lines (e.g., from word-wrap) of any of
+// them, in which case the offsets of all following lines are updated, and
+// the visible lines must be recomputed.
+func (tv *TextView) MarkupLines(st, ed int) bool {
+	if tv.Buf == nil {
+		return false
+	}
+	rest := -1
+	mxwd := float32(tv.LinesSize.X)
+	for ln := st; ln <= ed && ln < len(tv.lineMarked); ln++ {
+		if tv.lineMarked[ln] || tv.IsLineHidden(ln) {
+			continue
+		}
+		curspans := len(tv.Renders[ln].Spans)
+		tv.LayoutLine(ln, true)
+		nwspans := len(tv.Renders[ln].Spans)
+		if rest < 0 && nwspans != curspans && (nwspans > 1 || curspans > 1) {
+			rest = ln
+		}
+		mxwd = mat32.Max(mxwd, tv.Renders[ln].Size.X)
+	}
+	if rest < 0 {
+		return false
+	}
+	off := tv.Offs[rest]
+	for ln := rest; ln < tv.NLines; ln++ {
+		tv.Offs[ln] = off
+		off += tv.LineDispHeight(ln)
+	}
+	extraHalf := tv.LineHeight * 0.5 * float32(tv.VisSize.Y)
+	nwSz := mat32.Vec2{mxwd, off + extraHalf}.ToPointCeil()
+	tv.ResizeIfNeeded(nwSz)
+	return true
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 //  Cursor Navigation
 
@@ -693,9 +806,10 @@ func (tv *TextView) ValidateCursor() {
 	}
 }
 
-// WrappedLines returns the number of wrapped lines (spans) for given line number
+// WrappedLines returns the number of wrapped lines (spans) for given line
+// number -- zero if it is hidden in a folded region
 func (tv *TextView) WrappedLines(ln int) int {
-	if ln >= len(tv.Renders) {
+	if ln >= len(tv.Renders) || tv.IsLineHidden(ln) {
 		return 0
 	}
 	return len(tv.Renders[ln].Spans)
@@ -722,6 +836,8 @@ func (tv *TextView) SetCursor(pos lex.Pos) {
 	cpln := tv.CursorPos.Ln
 	tv.ClearScopelights()
 	tv.CursorPos = tv.Buf.ValidPos(pos)
+	tv.UnfoldLine(tv.CursorPos.Ln) // reveal if hidden, e.g., from search or jump
+
 	if cpln != tv.CursorPos.Ln && tv.HasLineNos() { // update cursor position highlight
 		rs := tv.Render()
 		rs.PushBounds(tv.VpBBox)
@@ -731,7 +847,12 @@ func (tv *TextView) SetCursor(pos lex.Pos) {
 		rs.Unlock()
 		tv.Viewport.Render.PopBounds()
 	}
+	if cpln != tv.CursorPos.Ln && tv.Buf.Opts.VcsBlame { // move the blame to the new line
+		tv.RenderLines(cpln, cpln)
+		tv.RenderLines(tv.CursorPos.Ln, tv.CursorPos.Ln)
+	}
 	tv.Buf.MarkupLine(tv.CursorPos.Ln)
+	tv.CollabSel()
 	tv.CursorMovedSig()
 	txt := tv.Buf.Line(tv.CursorPos.Ln)
 	ch := tv.CursorPos.Ch
@@ -843,6 +964,7 @@ func (tv *TextView) SelectRegUpdate(pos lex.Pos) {
 		tv.SelectReg.Start = tv.SelectStart
 		tv.SelectReg.End = pos
 	}
+	tv.CollabSel()
 }
 
 // CursorSelect updates selection based on cursor movements, given starting
@@ -864,9 +986,9 @@ func (tv *TextView) CursorForward(steps int) {
 	for i := 0; i < steps; i++ {
 		tv.CursorPos.Ch++
 		if tv.CursorPos.Ch > tv.Buf.LineLen(tv.CursorPos.Ln) {
-			if tv.CursorPos.Ln < tv.NLines-1 {
+			if nln := tv.NextVisibleLine(tv.CursorPos.Ln + 1); nln < tv.NLines {
 				tv.CursorPos.Ch = 0
-				tv.CursorPos.Ln++
+				tv.CursorPos.Ln = nln
 			} else {
 				tv.CursorPos.Ch = tv.Buf.LineLen(tv.CursorPos.Ln)
 			}
@@ -916,9 +1038,9 @@ func (tv *TextView) CursorForwardWord(steps int) {
 			}
 			tv.CursorPos.Ch = ch
 		} else {
-			if tv.CursorPos.Ln < tv.NLines-1 {
+			if nln := tv.NextVisibleLine(tv.CursorPos.Ln + 1); nln < tv.NLines {
 				tv.CursorPos.Ch = 0
-				tv.CursorPos.Ln++
+				tv.CursorPos.Ln = nln
 			} else {
 				tv.CursorPos.Ch = tv.Buf.LineLen(tv.CursorPos.Ln)
 			}
@@ -958,9 +1080,9 @@ func (tv *TextView) CursorDown(steps int) {
 			}
 		}
 		if !gotwrap {
-			pos.Ln++
+			pos.Ln = tv.NextVisibleLine(pos.Ln + 1)
 			if pos.Ln >= tv.NLines {
-				pos.Ln = tv.NLines - 1
+				pos.Ln = tv.PrevVisibleLine(tv.NLines - 1)
 				break
 			}
 			mxlen := ints.MinInt(tv.Buf.LineLen(pos.Ln), tv.CursorCol)
@@ -988,6 +1110,7 @@ func (tv *TextView) CursorPageDown(steps int) {
 		if tv.CursorPos.Ln >= tv.NLines {
 			tv.CursorPos.Ln = tv.NLines - 1
 		}
+		tv.CursorPos.Ln = tv.PrevVisibleLine(tv.CursorPos.Ln)
 		tv.CursorPos.Ch = ints.MinInt(tv.Buf.LineLen(tv.CursorPos.Ln), tv.CursorCol)
 		tv.ScrollCursorToTop()
 		tv.RenderCursor(true)
@@ -1006,7 +1129,7 @@ func (tv *TextView) CursorBackward(steps int) {
 		tv.CursorPos.Ch--
 		if tv.CursorPos.Ch < 0 {
 			if tv.CursorPos.Ln > 0 {
-				tv.CursorPos.Ln--
+				tv.CursorPos.Ln = tv.PrevVisibleLine(tv.CursorPos.Ln - 1)
 				tv.CursorPos.Ch = tv.Buf.LineLen(tv.CursorPos.Ln)
 			} else {
 				tv.CursorPos.Ch = 0
@@ -1061,7 +1184,7 @@ func (tv *TextView) CursorBackwardWord(steps int) {
 			tv.CursorPos.Ch = ch
 		} else {
 			if tv.CursorPos.Ln > 0 {
-				tv.CursorPos.Ln--
+				tv.CursorPos.Ln = tv.PrevVisibleLine(tv.CursorPos.Ln - 1)
 				tv.CursorPos.Ch = tv.Buf.LineLen(tv.CursorPos.Ln)
 			} else {
 				tv.CursorPos.Ch = 0
@@ -1098,6 +1221,7 @@ func (tv *TextView) CursorUp(steps int) {
 				pos.Ln = 0
 				break
 			}
+			pos.Ln = tv.PrevVisibleLine(pos.Ln)
 			if wln := tv.WrappedLines(pos.Ln); wln > 1 { // just entered end of wrapped line
 				si := wln - 1
 				ri := tv.CursorCol
@@ -1130,6 +1254,7 @@ func (tv *TextView) CursorPageUp(steps int) {
 		if tv.CursorPos.Ln <= 0 {
 			tv.CursorPos.Ln = 0
 		}
+		tv.CursorPos.Ln = tv.PrevVisibleLine(tv.CursorPos.Ln)
 		tv.CursorPos.Ch = ints.MinInt(tv.Buf.LineLen(tv.CursorPos.Ln), tv.CursorCol)
 		tv.ScrollCursorToBottom()
 		tv.RenderCursor(true)
@@ -1364,17 +1489,16 @@ func (tv *TextView) CursorTranspose() {
 	}
 	ppos := pos
 	ppos.Ch--
-	tv.Buf.LinesMu.Lock()
-	lln := len(tv.Buf.Lines[pos.Ln])
+	txt := tv.Buf.Line(pos.Ln)
+	lln := len(txt)
 	end := false
 	if pos.Ch >= lln {
 		end = true
 		pos.Ch = lln - 1
 		ppos.Ch = lln - 2
 	}
-	chr := tv.Buf.Lines[pos.Ln][pos.Ch]
-	pchr := tv.Buf.Lines[pos.Ln][ppos.Ch]
-	tv.Buf.LinesMu.Unlock()
+	chr := txt[pos.Ch]
+	pchr := txt[ppos.Ch]
 	repl := string([]rune{chr, pchr})
 	pos.Ch++
 	tv.Buf.ReplaceText(ppos, pos, ppos, repl, EditSignal, ReplaceMatchCase)
@@ -1868,10 +1992,18 @@ func (tv *TextView) QReplacePrompt() {
 	}
 	QReplaceDialog(tv.Viewport, find, tv.QReplace.LexItems, gi.DlgOpts{Title: "Query-Replace", Prompt: "Enter strings for find and replace, then select Ok -- with dialog dismissed press <b>y</b> to replace current match, <b>n</b> to skip, <b>Enter</b> or <b>q</b> to quit, <b>!</b> to replace-all remaining"}, tv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
 		dlg := send.(*gi.Dialog)
-		if sig == int64(gi.DialogAccepted) {
-			find, repl, lexItems := QReplaceDialogValues(dlg)
-			tv.QReplaceStart(find, repl, lexItems)
+		win := tv.ParentWindow()
+		if sig != int64(gi.DialogAccepted) {
+			if win != nil {
+				win.EventMgr.RecordMacroCmd("")
+			}
+			return
+		}
+		find, repl, lexItems := QReplaceDialogValues(dlg)
+		if win != nil {
+			win.EventMgr.RecordMacroCmd("QReplace", find, repl, strconv.FormatBool(lexItems))
 		}
+		tv.QReplaceStart(find, repl, lexItems)
 	})
 }
 
@@ -1988,6 +2120,27 @@ func (tv *TextView) QReplaceKeyInput(kt *key.ChordEvent) {
 	}
 }
 
+// KeyMacroCmd satisfies the gi.KeyMacroTarget interface, performing
+// commands recorded in keyboard macros: "QReplace" find, replace, lexItems
+func (tv *TextView) KeyMacroCmd(cmd string, args []string) bool {
+	switch cmd {
+	case "QReplace":
+		if len(args) != 3 {
+			return false
+		}
+		lexItems, _ := strconv.ParseBool(args[2])
+		tv.QReplaceStart(args[0], args[1], lexItems)
+		return true
+	}
+	return false
+}
+
+// KeyMacroAtEnd satisfies the gi.KeyMacroTarget interface, returning true
+// when the cursor is on the last line, which stops repeated macro playback
+func (tv *TextView) KeyMacroAtEnd() bool {
+	return tv.CursorPos.Ln >= tv.NLines-1
+}
+
 // QReplaceCancel cancels QReplace mode
 func (tv *TextView) QReplaceCancel() {
 	if !tv.QReplace.On {
@@ -2268,6 +2421,7 @@ func (tv *TextView) SelectReset() {
 	edln := tv.SelectReg.End.Ln
 	tv.SelectReg = textbuf.RegionNil
 	tv.PrevSelectReg = textbuf.RegionNil
+	tv.CollabSel()
 	tv.RenderLines(stln, edln)
 }
 
@@ -2355,7 +2509,23 @@ func (tv *TextView) PasteHist() {
 }
 
 // Cut cuts any selected text and adds it to the clipboard, also returns cut text
+// -- with multiple cursors, the text selected at each of them is cut, and put
+// on the clipboard one per line, and the last cut is returned
 func (tv *TextView) Cut() *textbuf.Edit {
+	if tv.HasMultiCursors() {
+		cb := tv.MultiSelectionBytes()
+		var cut *textbuf.Edit
+		tv.MultiCursorDo(true, func(ci int) {
+			if tv.HasSelection() {
+				org := tv.SelectReg.Start
+				cut = tv.DeleteSelection()
+				tv.SetCursorShow(org)
+			}
+		})
+		oswin.TheApp.ClipBoard(tv.ParentWindow().OSWin).Write(mimedata.NewTextBytes(cb))
+		TextViewClipHistAdd(cb)
+		return cut
+	}
 	if !tv.HasSelection() {
 		return nil
 	}
@@ -2382,8 +2552,21 @@ func (tv *TextView) DeleteSelection() *textbuf.Edit {
 }
 
 // Copy copies any selected text to the clipboard, and returns that text,
-// optionally resetting the current selection
+// optionally resetting the current selection -- with multiple cursors, the
+// text selected at each of them is put on the clipboard, one per line
 func (tv *TextView) Copy(reset bool) *textbuf.Edit {
+	if tv.HasMultiCursors() {
+		tbe := tv.Selection()
+		cb := tv.MultiSelectionBytes()
+		TextViewClipHistAdd(cb)
+		oswin.TheApp.ClipBoard(tv.ParentWindow().OSWin).Write(mimedata.NewTextBytes(cb))
+		if reset {
+			tv.MultiCursorDo(false, func(ci int) {
+				tv.SelectReset()
+			})
+		}
+		return tbe
+	}
 	tbe := tv.Selection()
 	if tbe == nil {
 		return nil
@@ -2400,13 +2583,24 @@ func (tv *TextView) Copy(reset bool) *textbuf.Edit {
 	return tbe
 }
 
-// Paste inserts text from the clipboard at current cursor position
+// Paste inserts text from the clipboard at current cursor position -- with
+// multiple cursors, it is inserted at each of them, unless it has one line
+// per cursor, in which case each gets its own line
 func (tv *TextView) Paste() {
 	wupdt := tv.TopUpdateStart()
 	defer tv.TopUpdateEnd(wupdt)
 	data := oswin.TheApp.ClipBoard(tv.ParentWindow().OSWin).Read([]string{filecat.TextPlain})
 	if data != nil {
-		tv.InsertAtCursor(data.TypeData(filecat.TextPlain))
+		txt := data.TypeData(filecat.TextPlain)
+		lns := bytes.Split(txt, []byte("\n"))
+		ncur := len(tv.MultiCursors) + 1
+		tv.MultiCursorDo(true, func(ci int) {
+			if ncur > 1 && len(lns) == ncur {
+				tv.InsertAtCursor(lns[ci])
+			} else {
+				tv.InsertAtCursor(txt)
+			}
+		})
 		tv.SavePosHistory(tv.CursorPos)
 	}
 }
@@ -2545,6 +2739,11 @@ func (tv *TextView) MakeContextMenu(m *gi.Menu) {
 				txf.Paste()
 			})
 		ac.SetInactiveState(oswin.TheApp.ClipBoard(tv.ParentWindow().OSWin).IsEmpty())
+		m.AddAction(gi.ActOpts{Label: "Undo History..."},
+			tv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+				txf := recv.Embed(KiT_TextView).(*TextView)
+				UndoHistDialog(txf.Viewport, txf.Buf)
+			})
 	} else {
 		ac = m.AddAction(gi.ActOpts{Label: "Clear"},
 			tv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
@@ -2552,115 +2751,1279 @@ func (tv *TextView) MakeContextMenu(m *gi.Menu) {
 				txf.Clear()
 			})
 	}
+	if tv.Buf != nil && tv.Buf.LSP != nil {
+		m.AddSeparator("sep-lsp")
+		m.AddAction(gi.ActOpts{Label: "Go To Definition"},
+			tv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+				txf := recv.Embed(KiT_TextView).(*TextView)
+				txf.LSPDefinition()
+			})
+		m.AddAction(gi.ActOpts{Label: "Find References"},
+			tv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+				txf := recv.Embed(KiT_TextView).(*TextView)
+				txf.LSPReferences()
+			})
+		if !tv.IsInactive() {
+			m.AddAction(gi.ActOpts{Label: "Rename Symbol"},
+				tv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+					txf := recv.Embed(KiT_TextView).(*TextView)
+					txf.LSPRenamePrompt()
+				})
+		}
+	}
 }
 
 ///////////////////////////////////////////////////////////////////////////////
-//    Complete and Spell
+//    Language Server
 
-// OfferComplete pops up a menu of possible completions
-func (tv *TextView) OfferComplete() {
-	if tv.Buf.Complete == nil || tv.ISearch.On || tv.QReplace.On || tv.IsInactive() {
+// TextViewDiagColors are the colors of the underlines of language server
+// diagnostics, by severity
+var TextViewDiagColors = map[lsp.DiagnosticSeverity]string{
+	lsp.SeverityError:       "red",
+	lsp.SeverityWarning:     "orange",
+	lsp.SeverityInformation: "blue",
+	lsp.SeverityHint:        "grey",
+}
+
+// RenderDiagnostics renders a wavy underline below the regions of the
+// language server diagnostics, in the color for their severity
+// -- always called within context of outer RenderLines or RenderAllLines
+func (tv *TextView) RenderDiagnostics(stln, edln int) {
+	tb := tv.Buf
+	tb.DiagMu.Lock()
+	diags := tb.Diags
+	tb.DiagMu.Unlock()
+	if len(diags) == 0 {
 		return
 	}
-	tv.Buf.Complete.Cancel()
-	if !tv.Buf.Opts.Completion && !tv.ForceComplete {
+	rs := tv.Render()
+	pc := &rs.Paint
+	ex := float32(tv.VpBBox.Max.X)
+	for _, d := range diags {
+		tb.LinesMu.RLock()
+		reg := tb.RegionFromLSP(d.Range)
+		tb.LinesMu.RUnlock()
+		if reg.Start.Ln > edln || reg.End.Ln < stln {
+			continue
+		}
+		sev := d.Severity
+		if sev == 0 {
+			sev = lsp.SeverityError
+		}
+		clr, err := gi.ColorFromString(TextViewDiagColors[sev], nil)
+		if err != nil {
+			continue
+		}
+		pc.StrokeStyle.SetColor(clr)
+		pc.StrokeStyle.Width.Dots = 1
+		for ln := ints.MaxInt(reg.Start.Ln, stln); ln <= ints.MinInt(reg.End.Ln, edln); ln++ {
+			if tv.IsLineHidden(ln) {
+				continue
+			}
+			st := lex.Pos{Ln: ln}
+			if ln == reg.Start.Ln {
+				st.Ch = reg.Start.Ch
+			}
+			ed := lex.Pos{Ln: ln, Ch: tb.LineLen(ln)}
+			if ln == reg.End.Ln {
+				ed.Ch = reg.End.Ch
+			}
+			spos := tv.CharStartPos(st)
+			epos := tv.CharStartPos(ed)
+			if epos.Y > spos.Y { // wrapped -- underline to end of first row
+				epos.X = ex
+			}
+			if epos.X-spos.X < tv.FontHeight*0.5 { // empty region: mark one char
+				epos.X = spos.X + tv.FontHeight*0.5
+			}
+			y := spos.Y + tv.LineHeight - 1
+			up := true
+			pc.MoveTo(rs, spos.X, y)
+			for x := spos.X + 2; x <= epos.X; x += 2 {
+				if up {
+					pc.LineTo(rs, x, y-2)
+				} else {
+					pc.LineTo(rs, x, y)
+				}
+				up = !up
+			}
+			pc.Stroke(rs)
+		}
+	}
+}
+
+// HoverEvent shows a tooltip with the change since the last commit to
+// version control for the change marker under the mouse, or with the
+// language server diagnostics, or otherwise its hover information, for the
+// text under the mouse -- falling back on the Tooltip of the view
+func (tv *TextView) HoverEvent() {
+	tv.ConnectEvent(oswin.MouseHoverEvent, gi.RegPri, func(recv, send ki.Ki, sig int64, d interface{}) {
+		me := d.(*mouse.HoverEvent)
+		txf := recv.Embed(KiT_TextView).(*TextView)
+		txt := ""
+		if txf.Buf != nil {
+			pt := txf.PointToRelPos(me.Pos())
+			pos := txf.PixelToCursor(pt)
+			if txf.InChangeMarker(pt) {
+				cln, _ := txf.ChangeLine(pos.Ln)
+				txt = html.EscapeString(txf.Buf.ChangeText(cln))
+			} else if txf.Buf.LSP != nil {
+				txt = txf.LSPHoverText(pos)
+			}
+		}
+		if txt == "" {
+			txt = txf.Tooltip
+		}
+		if txt != "" {
+			me.SetProcessed()
+			pos := me.Pos()
+			pos.Y += 10
+			gi.PopupTooltip(txt, pos.X, pos.Y, txf.ViewportSafe(), txf.Nm)
+		}
+	})
+}
+
+// LSPHoverText returns the tooltip text for given position, which is the
+// messages of any language server diagnostics there, and otherwise the
+// hover information from the server for the text there
+func (tv *TextView) LSPHoverText(pos lex.Pos) string {
+	tb := tv.Buf
+	if ds := tb.DiagnosticsAt(pos); len(ds) > 0 {
+		msgs := make([]string, len(ds))
+		for i, d := range ds {
+			msgs[i] = d.Severity.String() + ": " + d.Message
+			if d.Source != "" {
+				msgs[i] = d.Source + " " + msgs[i]
+			}
+		}
+		return html.EscapeString(strings.Join(msgs, "\n"))
+	}
+	if !lsp.Has(tb.LSP.Caps.HoverProvider) || pos.Ch >= tb.LineLen(pos.Ln) {
+		return ""
+	}
+	tb.LinesMu.RLock()
+	lp := tb.LSPPos(pos)
+	tb.LinesMu.RUnlock()
+	hv, _ := tb.LSP.Hover(tb.LSPURI(), lp)
+	return html.EscapeString(hv)
+}
+
+// LSPSignatureHelp shows the signature of the function being called at
+// the cursor in a tooltip, from the language server
+func (tv *TextView) LSPSignatureHelp() {
+	tb := tv.Buf
+	if tb.LSP == nil || !lsp.Has(tb.LSP.Caps.SignatureHelpProvider) {
 		return
 	}
-	if tv.Buf.InComment(tv.CursorPos) || tv.Buf.InLitString(tv.CursorPos) {
+	tb.LinesMu.RLock()
+	lp := tb.LSPPos(tv.CursorPos)
+	tb.LinesMu.RUnlock()
+	sh, err := tb.LSP.SignatureHelp(tb.LSPURI(), lp)
+	if err != nil || sh == nil || len(sh.Signatures) == 0 {
 		return
 	}
-
-	tv.Buf.Complete.SrcLn = tv.CursorPos.Ln
-	tv.Buf.Complete.SrcCh = tv.CursorPos.Ch
-	st := lex.Pos{tv.CursorPos.Ln, 0}
-	en := lex.Pos{tv.CursorPos.Ln, tv.CursorPos.Ch}
-	tbe := tv.Buf.Region(st, en)
-	var s string
-	if tbe != nil {
-		s = string(tbe.ToBytes())
-		s = strings.TrimLeft(s, " \t") // trim ' ' and '\t'
+	si := ints.MinInt(ints.MaxInt(sh.ActiveSignature, 0), len(sh.Signatures)-1)
+	sg := sh.Signatures[si]
+	txt := sg.Label
+	if sg.Documentation != "" {
+		txt += "\n\n" + string(sg.Documentation)
 	}
-
-	//	count := tv.Buf.ByteOffs[tv.CursorPos.Ln] + tv.CursorPos.Ch
 	cpos := tv.CharStartPos(tv.CursorPos).ToPoint() // physical location
-	cpos.X += 5
-	cpos.Y += 10
-	tv.Buf.SetByteOffs() // make sure the pos offset is updated!!
+	cpos.Y -= int(2 * tv.LineHeight)
+	gi.PopupTooltip(html.EscapeString(txt), cpos.X, cpos.Y, tv.Viewport, tv.Nm)
+}
+
+// LSPDefinition goes to the definition of the symbol at the cursor, using
+// the language server -- see LookupLSP
+func (tv *TextView) LSPDefinition() {
+	if tv.Buf.LSP == nil {
+		return
+	}
 	tv.Buf.CurView = tv
-	tv.Buf.Complete.Show(s, tv.CursorPos.Ln, tv.CursorPos.Ch, tv.Viewport, cpos, tv.ForceComplete)
+	wrd := tv.Buf.Region(tv.WordAt().Start, tv.WordAt().End)
+	txt := ""
+	if wrd != nil {
+		txt = string(wrd.ToBytes())
+	}
+	LookupLSP(tv.Buf, txt, tv.CursorPos.Ln, tv.CursorPos.Ch)
+	tv.Buf.CurView = nil
 }
 
-// CancelComplete cancels any pending completion -- call this when new events
-// have moved beyond any prior completion scenario
-func (tv *TextView) CancelComplete() {
-	tv.ForceComplete = false
-	if tv.Buf == nil {
+// LSPReferences shows all the references to the symbol at the cursor in a
+// dialog, using the language server
+func (tv *TextView) LSPReferences() {
+	tb := tv.Buf
+	if tb.LSP == nil {
 		return
 	}
-	if tv.Buf.Complete == nil {
+	tb.LinesMu.RLock()
+	lp := tb.LSPPos(tv.CursorPos)
+	tb.LinesMu.RUnlock()
+	locs, err := tb.LSP.References(tb.LSPURI(), lp, true)
+	if err != nil {
+		gi.PromptDialog(tv.Viewport, gi.DlgOpts{Title: "Find References Failed", Prompt: err.Error()}, gi.AddOk, gi.NoCancel, nil, nil)
 		return
 	}
-	if tv.Buf.Complete.Cancel() {
-		tv.Buf.CurView = nil
+	flns := make(map[string][][]byte)
+	var buf bytes.Buffer
+	for _, loc := range locs {
+		fn := lsp.URIFile(loc.URI)
+		lns, has := flns[fn]
+		if !has {
+			txt, _ := ioutil.ReadFile(fn)
+			lns = bytes.Split(txt, []byte("\n"))
+			flns[fn] = lns
+		}
+		ln := loc.Range.Start.Line
+		ltxt := ""
+		if ln < len(lns) {
+			ltxt = string(bytes.TrimSpace(lns[ln]))
+		}
+		if rel, err := filepath.Rel(tb.LSP.Root, fn); err == nil {
+			fn = rel
+		}
+		fmt.Fprintf(&buf, "%v:%d:%d: %v\n", fn, ln+1, loc.Range.Start.Character+1, ltxt)
+	}
+	wrd := tv.Buf.Region(tv.WordAt().Start, tv.WordAt().End)
+	title := "References"
+	if wrd != nil {
+		title += ": " + string(wrd.ToBytes())
 	}
+	TextViewDialog(tv.Viewport, buf.Bytes(), DlgOpts{Title: title, Prompt: fmt.Sprintf("%d references", len(locs))})
 }
 
-// Lookup attempts to lookup symbol at current location, popping up a window
-// if something is found
-func (tv *TextView) Lookup() {
-	if tv.Buf.Complete == nil || tv.ISearch.On || tv.QReplace.On || tv.IsInactive() {
+// LSPRenamePrompt prompts for a new name for the symbol at the cursor, and
+// renames it throughout the workspace using the language server -- see
+// TextBuf.LSPRename
+func (tv *TextView) LSPRenamePrompt() {
+	if tv.Buf.LSP == nil {
 		return
 	}
-
-	var ln int
-	var ch int
-	if tv.HasSelection() {
-		ln = tv.SelectReg.Start.Ln
-		if tv.SelectReg.End.Ln != ln {
-			return // no multiline selections for lookup
-		}
-		ch = tv.SelectReg.End.Ch
-	} else {
-		ln = tv.CursorPos.Ln
-		if tv.IsWordEnd(tv.CursorPos) {
-			ch = tv.CursorPos.Ch
-		} else {
-			ch = tv.WordAt().End.Ch
-		}
+	pos := tv.CursorPos
+	wrd := tv.Buf.Region(tv.WordAt().Start, tv.WordAt().End)
+	cur := ""
+	if wrd != nil {
+		cur = string(wrd.ToBytes())
 	}
-	tv.Buf.Complete.SrcLn = ln
-	tv.Buf.Complete.SrcCh = ch
-	st := lex.Pos{tv.CursorPos.Ln, 0}
-	en := lex.Pos{tv.CursorPos.Ln, ch}
+	gi.StringPromptDialog(tv.Viewport, cur, "New name..",
+		gi.DlgOpts{Title: "Rename Symbol", Prompt: "New name for the symbol, everywhere it is used"},
+		tv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+			dlg := send.(*gi.Dialog)
+			if sig == int64(gi.DialogAccepted) {
+				name := gi.StringPromptDialogValue(dlg)
+				if name == "" || name == cur {
+					return
+				}
+				if err := tv.Buf.LSPRename(pos, name); err != nil {
+					gi.PromptDialog(tv.Viewport, gi.DlgOpts{Title: "Rename Failed", Prompt: err.Error()}, gi.AddOk, gi.NoCancel, nil, nil)
+				}
+			}
+		})
+}
 
-	tbe := tv.Buf.Region(st, en)
-	var s string
-	if tbe != nil {
-		s = string(tbe.ToBytes())
-		s = strings.TrimLeft(s, " \t") // trim ' ' and '\t'
+///////////////////////////////////////////////////////////////////////////////
+//    Minimap and Overview Ruler
+
+// TextViewMinimapWidth is the width in pixels of the minimap shown at the
+// right side of the view when the Minimap option is on
+var TextViewMinimapWidth = float32(100)
+
+// TextViewMinimapLineHeight is the height in pixels of each line in the
+// minimap -- each character is one pixel wide
+var TextViewMinimapLineHeight = 2
+
+// TextViewOverviewHighlightColor is the color of the marks on the overview
+// ruler for the Highlights, e.g., search matches
+var TextViewOverviewHighlightColor = "orange"
+
+// MinimapBBox returns the bounding box of the minimap, in viewport coordinates
+func (tv *TextView) MinimapBBox() image.Rectangle {
+	bb := tv.VpBBox
+	bb.Min.X = ints.MaxInt(bb.Min.X, bb.Max.X-int(tv.MinimapOff))
+	return bb
+}
+
+// InMinimap returns true if given point in window coordinates (e.g., from a
+// mouse event) is within the minimap
+func (tv *TextView) InMinimap(pt image.Point) bool {
+	if tv.MinimapOff == 0 {
+		return false
 	}
+	return pt.In(tv.MinimapBBox().Add(tv.WinBBox.Min.Sub(tv.VpBBox.Min)))
+}
 
-	//	count := tv.Buf.ByteOffs[tv.CursorPos.Ln] + tv.CursorPos.Ch
-	cpos := tv.CharStartPos(tv.CursorPos).ToPoint() // physical location
-	cpos.X += 5
-	cpos.Y += 10
-	tv.Buf.SetByteOffs() // make sure the pos offset is updated!!
-	tv.Buf.CurView = tv
-	tv.Buf.Complete.Lookup(s, tv.CursorPos.Ln, tv.CursorPos.Ch, tv.Viewport, cpos, tv.ForceComplete)
+// MinimapTopLine returns the first line shown in the minimap, and the number
+// of lines that fit in it -- when the buffer has more lines than that, the
+// minimap scrolls proportionally along with the view
+func (tv *TextView) MinimapTopLine() (top, nln int) {
+	nln = tv.MinimapBBox().Dy() / TextViewMinimapLineHeight
+	if tv.NLines <= nln || nln == 0 {
+		return 0, nln
+	}
+	st := tv.FirstVisibleLine(0)
+	nvis := tv.LastVisibleLine(st) - st + 1
+	if rng := tv.NLines - nvis; rng > 0 {
+		top = int(float32(st) / float32(rng) * float32(tv.NLines-nln))
+	}
+	top = ints.MinInt(ints.MaxInt(top, 0), tv.NLines-nln)
+	return
 }
 
-// ISpellKeyInput locates the word to spell check based on cursor position and
-// the key input, then passes the text region to SpellCheck
-func (tv *TextView) ISpellKeyInput(kt *key.ChordEvent) {
-	if !tv.Buf.IsSpellEnabled(tv.CursorPos) {
+// RenderMinimap renders the minimap, showing each character of the lines as
+// a pixel in its syntax highlighting color, with the visible lines marked
+// -- always called within context of outer RenderLines or RenderAllLines
+func (tv *TextView) RenderMinimap() {
+	if tv.MinimapOff == 0 || tv.Buf == nil || tv.NLines == 0 {
+		return
+	}
+	rs := tv.Render()
+	img := rs.Image
+	bb := tv.MinimapBBox().Intersect(img.Bounds())
+	if bb.Empty() {
 		return
 	}
+	sty := &tv.Sty
+	bg := sty.Font.BgColor.Color
+	draw.Draw(img, bb, &image.Uniform{bg.Highlight(5)}, image.ZP, draw.Src)
+	lh := TextViewMinimapLineHeight
+	top, nln := tv.MinimapTopLine()
+	st := tv.FirstVisibleLine(0)
+	ed := tv.LastVisibleLine(st)
+	vis := image.Rect(bb.Min.X, bb.Min.Y+(st-top)*lh, bb.Max.X, bb.Min.Y+(ed+1-top)*lh)
+	draw.Draw(img, vis.Intersect(bb), &image.Uniform{bg.Highlight(20)}, image.ZP, draw.Src)
+
+	tb := tv.Buf
+	tb.LinesMu.RLock()
+	defer tb.LinesMu.RUnlock()
+	tb.MarkupMu.Lock() // needed for highlighting tags
+	defer tb.MarkupMu.Unlock()
+	hs := tb.Hi.HiStyle
+	tabSz := ints.MaxInt(tb.Opts.TabSize, 1)
+	edln := ints.MinInt(top+nln, tb.NLines)
+	for ln := top; ln < edln; ln++ {
+		y := bb.Min.Y + (ln-top)*lh
+		txt := tb.LineImpl(ln)
+		tags := tb.lineHiImpl(ln, txt)
+		col := 0
+		ti := 0
+		lti := -1
+		clr := sty.Font.Color
+		for ci, r := range txt {
+			if r == '\t' {
+				col = (col/tabSz + 1) * tabSz
+				continue
+			}
+			x := bb.Min.X + 2 + col
+			col++
+			if x >= bb.Max.X {
+				break
+			}
+			if unicode.IsSpace(r) {
+				continue
+			}
+			for ti < len(tags) && tags[ti].Ed <= ci {
+				ti++
+			}
+			if ti != lti {
+				lti = ti
+				clr = sty.Font.Color
+				if hs != nil && ti < len(tags) && tags[ti].St <= ci {
+					if se := hs.Tag(tags[ti].Tok.Tok); !se.Color.IsNil() {
+						clr = se.Color
+					}
+				}
+			}
+			for yi := 0; yi < lh-1 || yi == 0; yi++ {
+				img.Set(x, y+yi, clr)
+			}
+		}
+	}
+}
 
-	isDoc := tv.Buf.Info.Cat == filecat.Doc
-	tp := tv.CursorPos
+// MinimapScrollTo scrolls the view to center the line at given point in
+// window coordinates within the minimap -- when dragging, the minimap
+// position at the start of the drag is used, so the view follows the mouse
+func (tv *TextView) MinimapScrollTo(pt image.Point, drag bool) {
+	if tv.NLines == 0 {
+		return
+	}
+	top, _ := tv.MinimapTopLine()
+	if drag {
+		top = tv.minimapDragTop
+	} else {
+		tv.minimapDragTop = top
+	}
+	wbb := tv.MinimapBBox().Add(tv.WinBBox.Min.Sub(tv.VpBBox.Min))
+	ln := top + (pt.Y-wbb.Min.Y)/TextViewMinimapLineHeight
+	ln = ints.MinInt(ints.MaxInt(ln, 0), tv.NLines-1)
+	tv.ScrollToVertCenter(int(tv.CharStartPos(lex.Pos{Ln: ln}).Y))
+}
 
-	kf := gi.KeyFun(kt.Chord())
-	switch kf {
+// UpdateOverviewMarks updates the marks on the vertical scrollbar of the
+// parent layout, which serves as an overview ruler showing where the line
+// colors (e.g., from DiffView), highlights, selection and diagnostics are in
+// the whole buffer -- the scrollbar is re-rendered if the marks changed.
+// Clicking on a mark in the scrollbar jumps to it.
+func (tv *TextView) UpdateOverviewMarks() {
+	ly := tv.ParentScrollLayout()
+	if ly == nil || !ly.HasScroll[mat32.Y] || ly.Scrolls[mat32.Y] == nil || tv.Buf == nil || tv.NLines == 0 {
+		return
+	}
+	sc := ly.Scrolls[mat32.Y]
+	off := sc.Value - float32(ly.VpBBox.Min.Y) // from view to scroll value
+	var marks []gi.ScrollMark
+	add := func(st, ed int, clr gi.Color) {
+		st = ints.MinInt(ints.MaxInt(st, 0), tv.NLines-1)
+		ed = ints.MinInt(ints.MaxInt(ed, st), tv.NLines-1)
+		y := tv.CharStartPos(lex.Pos{Ln: st}).Y
+		ey := tv.CharStartPos(lex.Pos{Ln: ed}).Y + tv.LineDispHeight(ed)
+		marks = append(marks, gi.ScrollMark{Val: y + off, Size: ey - y, Color: clr})
+	}
+	tb := tv.Buf
+	tb.DiagMu.Lock()
+	diags := tb.Diags
+	tb.DiagMu.Unlock()
+	tb.LinesMu.RLock()
+	lns := make([]int, 0, len(tb.LineColors))
+	for ln := range tb.LineColors {
+		lns = append(lns, ln)
+	}
+	sort.Ints(lns)
+	for _, ln := range lns {
+		add(ln, ln, tb.LineColors[ln])
+	}
+	diagRegs := make([]textbuf.Region, len(diags))
+	for i := range diags {
+		diagRegs[i] = tb.RegionFromLSP(diags[i].Range)
+	}
+	tb.LinesMu.RUnlock()
+	hclr, _ := gi.ColorFromString(TextViewOverviewHighlightColor, nil)
+	for _, reg := range tv.Highlights {
+		add(reg.Start.Ln, reg.End.Ln, hclr)
+	}
+	if tv.HasSelection() {
+		add(tv.SelectReg.Start.Ln, tv.SelectReg.End.Ln, gi.Prefs.Colors.Select)
+	}
+	for i, d := range diags {
+		sev := d.Severity
+		if sev == 0 {
+			sev = lsp.SeverityError
+		}
+		if clr, err := gi.ColorFromString(TextViewDiagColors[sev], nil); err == nil {
+			add(diagRegs[i].Start.Ln, diagRegs[i].End.Ln, clr)
+		}
+	}
+	if len(marks) == len(tv.overviewMarks) {
+		same := true
+		for i := range marks {
+			if marks[i] != tv.overviewMarks[i] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return
+		}
+	}
+	tv.overviewMarks = marks
+	sc.Marks = marks
+	tv.SetFlag(int(TextViewRenderScrolls))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+//    Version Control Changes and Blame
+
+// TextViewChangeColors are the colors of the markers next to the line
+// numbers for the lines changed since the last commit to version control
+var TextViewChangeColors = map[textbuf.LineChanges]string{
+	textbuf.LineAdded:    "green",
+	textbuf.LineModified: "blue",
+	textbuf.LineDeleted:  "red",
+}
+
+// TextViewChangeMarkerWidth is the width in pixels of the change markers
+var TextViewChangeMarkerWidth = float32(3)
+
+// ChangeLine returns how given line was changed since the last commit to
+// version control, and the line of the change, which is the line after the
+// end for the last line, if lines were deleted after it
+func (tv *TextView) ChangeLine(ln int) (int, textbuf.LineChanges) {
+	lc := tv.Buf.LineChange(ln)
+	if lc == textbuf.LineUnchanged && ln == tv.NLines-1 {
+		if tv.Buf.LineChange(ln+1) == textbuf.LineDeleted {
+			return ln + 1, textbuf.LineDeleted
+		}
+	}
+	return ln, lc
+}
+
+// ChangeMarkerX returns the horizontal position of the change markers, in
+// the last column of the line numbers, relative to the view
+func (tv *TextView) ChangeMarkerX() float32 {
+	return tv.Sty.BoxSpace() + float32(tv.LineNoDigs+2)*tv.Sty.Font.Face.Metrics.Ch
+}
+
+// InChangeMarker returns true if given point, relative to the upper left
+// of the text area, is in the area of the change markers
+func (tv *TextView) InChangeMarker(pt image.Point) bool {
+	if !tv.HasLineNos() || tv.Buf == nil || tv.Buf.Changes == nil {
+		return false
+	}
+	return pt.X >= int(tv.ChangeMarkerX()) && pt.X < int(tv.LineNoOff)
+}
+
+// RenderChangeMarker renders the marker for the change of given line since
+// the last commit to version control, given the box of its line number:
+// a bar for added and modified lines, and a tick at the top for lines that
+// were deleted before it -- called within context of RenderLineNo
+func (tv *TextView) RenderChangeMarker(ln int, sbox, ebox mat32.Vec2) {
+	if tv.Buf.Changes == nil {
+		return
+	}
+	cln, lc := tv.ChangeLine(ln)
+	if lc == textbuf.LineUnchanged {
+		return
+	}
+	clr, err := gi.ColorFromString(TextViewChangeColors[lc], nil)
+	if err != nil {
+		return
+	}
+	rs := &tv.Viewport.Render
+	pc := &rs.Paint
+	w := TextViewChangeMarkerWidth
+	pos := mat32.Vec2{float32(tv.VpBBox.Min.X) + tv.ChangeMarkerX(), sbox.Y}
+	if lc == textbuf.LineDeleted {
+		if cln > ln {
+			pos.Y = ebox.Y - w
+		}
+		pc.FillBoxColor(rs, pos, mat32.Vec2{3 * w, w}, clr)
+		return
+	}
+	pc.FillBoxColor(rs, pos, mat32.Vec2{w, ebox.Y - sbox.Y}, clr)
+}
+
+// ChangeMenu pops up a menu for the change since the last commit to
+// version control at given line, to revert it or view the diffs of the file
+func (tv *TextView) ChangeMenu(ln int) {
+	var men gi.Menu
+	men.AddAction(gi.ActOpts{Label: "Revert Change", Data: ln}, tv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+		txf := recv.Embed(KiT_TextView).(*TextView)
+		txf.Buf.RevertChange(data.(int))
+	})
+	men.AddAction(gi.ActOpts{Label: "Diff File"}, tv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
+		txf := recv.Embed(KiT_TextView).(*TextView)
+		txf.DiffChanges()
+	})
+	pos := tv.ContextMenuPos()
+	gi.PopupMenu(men, pos.X, pos.Y, tv.ViewportSafe(), tv.Nm+"-change-menu")
+}
+
+// DiffChanges shows the changes of the text since the last commit to
+// version control in a DiffViewDialog
+func (tv *TextView) DiffChanges() {
+	tb := tv.Buf
+	tb.LinesMu.RLock()
+	if tb.Changes == nil {
+		tb.LinesMu.RUnlock()
+		return
+	}
+	base := tb.Changes.Base
+	tb.LinesMu.RUnlock()
+	fnm := string(tb.Filename)
+	DiffViewDialog(tv.ViewportSafe(), base, tb.Strings(false), fnm, fnm, "HEAD", "", DlgOpts{Title: "Changes: " + DirAndFile(fnm)})
+}
+
+// RenderBlame renders who last changed the cursor line, when, and why, at
+// the end of the line, if the VcsBlame option is on and the line is in the
+// given range -- always called within context of outer RenderLines or
+// RenderAllLines
+func (tv *TextView) RenderBlame(stln, edln int) {
+	ln := tv.CursorPos.Ln
+	if !tv.Buf.Opts.VcsBlame || ln < stln || ln > edln || tv.IsLineHidden(ln) {
+		return
+	}
+	bl, ok := tv.Buf.BlameAt(ln)
+	if !ok {
+		return
+	}
+	sty := &tv.Sty
+	fst := sty.Font
+	fst.BgColor.SetColor(nil)
+	fst.Color = sty.Font.Color.Highlight(50)
+	tv.blameRender.SetString(bl.String(), &fst, &sty.UnContext, &sty.Text, true, 0, 0)
+	pos := tv.CharStartPos(lex.Pos{Ln: ln, Ch: tv.Buf.LineLen(ln)})
+	pos.X += 4 * sty.Font.Face.Metrics.Ch
+	pos.Y += mat32.FromFixed(sty.Font.Face.Face.Metrics().Ascent) - mat32.FromFixed(sty.Font.Face.Face.Metrics().Descent)
+	tv.blameRender.Render(tv.Render(), pos)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+//    Folding
+
+// TextViewFoldMarkers are the markers shown after the line numbers for lines
+// that start a region that can be folded, when it is open and when folded
+var TextViewFoldMarkers = [2]string{"▼", "►"}
+
+// FoldRegions returns the regions that can be folded, computed from the
+// buffer when it has changed -- see TextBuf.FoldRegions
+func (tv *TextView) FoldRegions() []textbuf.Region {
+	if tv.Buf == nil {
+		return nil
+	}
+	if !tv.foldRegsOk {
+		tv.foldRegs = tv.Buf.FoldRegions()
+		tv.foldRegsOk = true
+	}
+	return tv.foldRegs
+}
+
+// FoldRegionAt returns the region that can be folded starting at given line,
+// and false if there is none
+func (tv *TextView) FoldRegionAt(ln int) (textbuf.Region, bool) {
+	frs := tv.FoldRegions()
+	i := sort.Search(len(frs), func(i int) bool {
+		return frs[i].Start.Ln >= ln
+	})
+	if i < len(frs) && frs[i].Start.Ln == ln {
+		return frs[i], true
+	}
+	return textbuf.RegionNil, false
+}
+
+// FoldIdx returns the index in Folds of the folded region starting at
+// given line, or -1 if not folded
+func (tv *TextView) FoldIdx(ln int) int {
+	for i, f := range tv.Folds {
+		if f.Start.Ln == ln {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsFolded returns true if there is a folded region starting at given line
+func (tv *TextView) IsFolded(ln int) bool {
+	return tv.FoldIdx(ln) >= 0
+}
+
+// IsLineHidden returns true if given line is hidden in a folded region
+func (tv *TextView) IsLineHidden(ln int) bool {
+	return ln >= 0 && ln < len(tv.FoldHidden) && tv.FoldHidden[ln]
+}
+
+// NextVisibleLine returns the first line at or after given line that is not
+// hidden in a folded region, or NLines if there are none
+func (tv *TextView) NextVisibleLine(ln int) int {
+	for ln < tv.NLines && tv.IsLineHidden(ln) {
+		ln++
+	}
+	return ln
+}
+
+// PrevVisibleLine returns the last line at or before given line that is not
+// hidden in a folded region -- the starting line of a fold is always visible
+func (tv *TextView) PrevVisibleLine(ln int) int {
+	for ln > 0 && tv.IsLineHidden(ln) {
+		ln--
+	}
+	return ln
+}
+
+// LineDispHeight returns the height of given line as displayed -- zero if
+// it is hidden in a folded region
+func (tv *TextView) LineDispHeight(ln int) float32 {
+	if tv.IsLineHidden(ln) {
+		return 0
+	}
+	return mat32.Max(tv.Renders[ln].Size.Y, tv.LineHeight)
+}
+
+// FoldAt folds the region starting at given line, or else the innermost
+// unfolded region containing it -- returns false if none
+func (tv *TextView) FoldAt(ln int) bool {
+	reg, ok := tv.FoldRegionAt(ln)
+	if !ok || tv.IsFolded(ln) {
+		ok = false
+		for _, fr := range tv.FoldRegions() {
+			if fr.Start.Ln > ln {
+				break
+			}
+			if fr.Start.Ln < ln && fr.End.Ln >= ln && !tv.IsFolded(fr.Start.Ln) {
+				reg = fr // later ones are inner
+				ok = true
+			}
+		}
+	}
+	if !ok {
+		return false
+	}
+	tv.Folds = append(tv.Folds, reg)
+	tv.FoldsUpdated()
+	if tv.IsLineHidden(tv.CursorPos.Ln) {
+		tv.SetCursorShow(lex.Pos{Ln: reg.Start.Ln, Ch: tv.Buf.LineLen(reg.Start.Ln)})
+	}
+	return true
+}
+
+// UnfoldAt unfolds the folded region starting at given line, or else the
+// innermost folded region containing it -- returns false if none
+func (tv *TextView) UnfoldAt(ln int) bool {
+	fi := tv.FoldIdx(ln)
+	if fi < 0 {
+		for i, f := range tv.Folds {
+			if f.Start.Ln < ln && f.End.Ln >= ln {
+				fi = i // sorted, so later ones are inner
+			}
+		}
+	}
+	if fi < 0 {
+		return false
+	}
+	tv.Folds = append(tv.Folds[:fi], tv.Folds[fi+1:]...)
+	tv.FoldsUpdated()
+	return true
+}
+
+// FoldToggle folds or unfolds the region starting at given line
+func (tv *TextView) FoldToggle(ln int) bool {
+	if tv.IsFolded(ln) {
+		return tv.UnfoldAt(ln)
+	}
+	return tv.FoldAt(ln)
+}
+
+// FoldAll folds all the regions that can be folded, including those nested
+// within others
+func (tv *TextView) FoldAll() {
+	frs := tv.FoldRegions()
+	tv.Folds = make([]textbuf.Region, len(frs))
+	copy(tv.Folds, frs)
+	tv.FoldsUpdated()
+	if tv.IsLineHidden(tv.CursorPos.Ln) {
+		tv.SetCursorShow(lex.Pos{Ln: tv.PrevVisibleLine(tv.CursorPos.Ln)})
+	}
+}
+
+// UnfoldAll unfolds all the folded regions
+func (tv *TextView) UnfoldAll() {
+	if len(tv.Folds) == 0 {
+		return
+	}
+	tv.Folds = nil
+	tv.FoldsUpdated()
+}
+
+// UnfoldLine unfolds all the folded regions that hide given line, so that it
+// is visible -- returns false if it was not hidden
+func (tv *TextView) UnfoldLine(ln int) bool {
+	if !tv.IsLineHidden(ln) {
+		return false
+	}
+	nf := 0
+	for _, f := range tv.Folds {
+		if f.Start.Ln < ln && f.End.Ln >= ln {
+			continue
+		}
+		tv.Folds[nf] = f
+		nf++
+	}
+	tv.Folds = tv.Folds[:nf]
+	tv.FoldsUpdated()
+	return true
+}
+
+// FoldsUpdated updates the view after changes to Folds
+func (tv *TextView) FoldsUpdated() {
+	sort.Slice(tv.Folds, func(i, j int) bool {
+		return tv.Folds[i].Start.Ln < tv.Folds[j].Start.Ln
+	})
+	tv.UpdateFoldHidden()
+	tv.LayoutOffs()
+	tv.RenderAllLines()
+}
+
+// UpdateFoldHidden updates FoldHidden from the Folds
+func (tv *TextView) UpdateFoldHidden() {
+	nln := tv.NLines
+	if tv.Buf != nil {
+		nln = tv.Buf.NumLines()
+	}
+	if len(tv.Folds) == 0 {
+		tv.FoldHidden = nil
+		return
+	}
+	if cap(tv.FoldHidden) >= nln {
+		tv.FoldHidden = tv.FoldHidden[:nln]
+		for i := range tv.FoldHidden {
+			tv.FoldHidden[i] = false
+		}
+	} else {
+		tv.FoldHidden = make([]bool, nln)
+	}
+	for _, f := range tv.Folds {
+		ed := ints.MinInt(f.End.Ln, nln-1)
+		for ln := f.Start.Ln + 1; ln <= ed; ln++ {
+			tv.FoldHidden[ln] = true
+		}
+	}
+}
+
+// AdjustFolds adjusts the folded regions for given edit of the buffer,
+// removing any that no longer hide any lines
+func (tv *TextView) AdjustFolds(tbe *textbuf.Edit) {
+	tv.foldRegsOk = false
+	if len(tv.Folds) == 0 {
+		return
+	}
+	nf := 0
+	for _, f := range tv.Folds {
+		f = tbe.AdjustReg(f)
+		if f.IsNil() || f.End.Ln <= f.Start.Ln {
+			continue
+		}
+		tv.Folds[nf] = f
+		nf++
+	}
+	tv.Folds = tv.Folds[:nf]
+	tv.UpdateFoldHidden()
+}
+
+// LayoutOffs updates the line offsets and overall size, e.g., after lines
+// have been folded or unfolded, without laying out the lines again
+func (tv *TextView) LayoutOffs() {
+	if tv.Buf == nil || len(tv.Offs) != tv.NLines || len(tv.Renders) != tv.NLines {
+		return
+	}
+	off := float32(0)
+	for ln := 0; ln < tv.NLines; ln++ {
+		tv.Offs[ln] = off
+		off += tv.LineDispHeight(ln)
+	}
+	extraHalf := tv.LineHeight * 0.5 * float32(tv.VisSize.Y)
+	nwSz := mat32.Vec2{float32(tv.LinesSize.X), off + extraHalf}.ToPointCeil()
+	tv.ResizeIfNeeded(nwSz)
+}
+
+// InFoldMarker returns true if given point, relative to the upper left of
+// the text area, is in the area of the fold markers after the line numbers
+func (tv *TextView) InFoldMarker(pt image.Point) bool {
+	if !tv.HasLineNos() {
+		return false
+	}
+	mst := int(tv.Sty.BoxSpace() + float32(tv.LineNoDigs+1)*tv.Sty.Font.Face.Metrics.Ch)
+	return pt.X >= mst && pt.X < int(tv.LineNoOff)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+//    Multiple Cursors
+
+// TextViewCursor is the state of one of the additional cursors of a
+// TextView, for editing at multiple places at once
+type TextViewCursor struct {
+	Pos           lex.Pos        `desc:"cursor position"`
+	Col           int            `desc:"desired cursor column -- see TextView.CursorCol"`
+	SelectStart   lex.Pos        `desc:"starting point for selection"`
+	SelectReg     textbuf.Region `desc:"selection region"`
+	PrevSelectReg textbuf.Region `desc:"previous selection region, that was actually rendered"`
+}
+
+// HasSelection returns whether there is a selected region of text
+func (tc *TextViewCursor) HasSelection() bool {
+	return tc.SelectReg.Start.IsLess(tc.SelectReg.End)
+}
+
+// CursorState returns the state of the main cursor of the view
+func (tv *TextView) CursorState() TextViewCursor {
+	return TextViewCursor{Pos: tv.CursorPos, Col: tv.CursorCol, SelectStart: tv.SelectStart, SelectReg: tv.SelectReg, PrevSelectReg: tv.PrevSelectReg}
+}
+
+// SetCursorState sets the main cursor of the view to given state, without
+// rendering anything
+func (tv *TextView) SetCursorState(tc TextViewCursor) {
+	tv.CursorPos = tc.Pos
+	tv.CursorCol = tc.Col
+	tv.SelectStart = tc.SelectStart
+	tv.SelectReg = tc.SelectReg
+	tv.PrevSelectReg = tc.PrevSelectReg
+}
+
+// HasMultiCursors returns true if there are additional cursors beyond the
+// main one
+func (tv *TextView) HasMultiCursors() bool {
+	return len(tv.MultiCursors) > 0
+}
+
+// MultiCursorDo calls given function for each of the cursors, including the
+// main one, in order of their position in the buffer, with the main cursor
+// and selection of the view set to each one in turn -- ci is the index of
+// the cursor in that order.  If edit is true, all the edits made by the
+// function are saved as one undo group.  Cursors that end up at the same
+// position are merged.
+func (tv *TextView) MultiCursorDo(edit bool, fun func(ci int)) {
+	if !tv.HasMultiCursors() {
+		fun(0)
+		return
+	}
+	wupdt := tv.TopUpdateStart()
+	defer tv.TopUpdateEnd(wupdt)
+	if edit {
+		bufUpdt, winUpdt, autoSave := tv.Buf.BatchUpdateStart()
+		defer tv.Buf.BatchUpdateEnd(bufUpdt, winUpdt, autoSave)
+		tv.Buf.Undos.BatchStart()
+		defer tv.Buf.Undos.BatchEnd()
+	}
+	// all the cursors are in MultiCursors while processing, so they are all
+	// adjusted for the edits -- see AdjustMultiCursors
+	mi := len(tv.MultiCursors)
+	tv.MultiCursors = append(tv.MultiCursors, tv.CursorState())
+	ord := make([]int, mi+1)
+	for i := range ord {
+		ord[i] = i
+	}
+	sort.SliceStable(ord, func(i, j int) bool {
+		return tv.MultiCursors[ord[i]].Pos.IsLess(tv.MultiCursors[ord[j]].Pos)
+	})
+	lastAI := tv.lastAutoInsert
+	for ci, i := range ord {
+		tv.SetCursorState(tv.MultiCursors[i])
+		tv.lastAutoInsert = lastAI
+		fun(ci)
+		tv.MultiCursors[i] = tv.CursorState()
+	}
+	tv.SetCursorState(tv.MultiCursors[mi])
+	tv.MultiCursors = tv.MultiCursors[:mi]
+	tv.MergeMultiCursors()
+	tv.RenderCursor(true)
+}
+
+// MergeMultiCursors removes any additional cursors at the same position as
+// the main cursor or another cursor
+func (tv *TextView) MergeMultiCursors() {
+	n := 0
+	for _, tc := range tv.MultiCursors {
+		dup := tc.Pos == tv.CursorPos
+		for j := 0; j < n && !dup; j++ {
+			dup = tv.MultiCursors[j].Pos == tc.Pos
+		}
+		if dup {
+			continue
+		}
+		tv.MultiCursors[n] = tc
+		n++
+	}
+	tv.MultiCursors = tv.MultiCursors[:n]
+}
+
+// ClearMultiCursors removes all the additional cursors and their selections,
+// leaving just the main cursor
+func (tv *TextView) ClearMultiCursors() {
+	if !tv.HasMultiCursors() {
+		return
+	}
+	tv.MultiCursors = nil
+	tv.RenderAllLines()
+	tv.RenderCursor(true)
+}
+
+// AdjustMultiCursors adjusts the additional cursors for given edit of the
+// buffer
+func (tv *TextView) AdjustMultiCursors(tbe *textbuf.Edit) {
+	for i := range tv.MultiCursors {
+		tc := &tv.MultiCursors[i]
+		tc.Pos = tbe.AdjustPos(tc.Pos, textbuf.AdjustPosDelStart)
+		tc.SelectStart = tbe.AdjustPos(tc.SelectStart, textbuf.AdjustPosDelStart)
+		if tc.SelectReg != textbuf.RegionNil {
+			tc.SelectReg = tbe.AdjustReg(tc.SelectReg)
+		}
+		tc.PrevSelectReg = textbuf.RegionNil
+	}
+}
+
+// AddCursorAbove adds a cursor on the line above the top-most cursor, at the
+// column of the main cursor -- returns false if already at the top
+func (tv *TextView) AddCursorAbove() bool {
+	return tv.addCursorLine(-1)
+}
+
+// AddCursorBelow adds a cursor on the line below the bottom-most cursor, at
+// the column of the main cursor -- returns false if already at the bottom
+func (tv *TextView) AddCursorBelow() bool {
+	return tv.addCursorLine(1)
+}
+
+// addCursorLine adds a cursor beyond the cursors in given direction
+func (tv *TextView) addCursorLine(dir int) bool {
+	ln := tv.CursorPos.Ln
+	for _, tc := range tv.MultiCursors {
+		if (dir < 0 && tc.Pos.Ln < ln) || (dir > 0 && tc.Pos.Ln > ln) {
+			ln = tc.Pos.Ln
+		}
+	}
+	if dir < 0 {
+		if ln <= 0 {
+			return false
+		}
+		ln = tv.PrevVisibleLine(ln - 1)
+	} else {
+		ln = tv.NextVisibleLine(ln + 1)
+		if ln >= tv.NLines {
+			return false
+		}
+	}
+	pos := lex.Pos{Ln: ln, Ch: ints.MinInt(tv.CursorCol, tv.Buf.LineLen(ln))}
+	tv.MultiCursors = append(tv.MultiCursors, TextViewCursor{Pos: pos, Col: tv.CursorCol, SelectStart: pos, SelectReg: textbuf.RegionNil, PrevSelectReg: textbuf.RegionNil})
+	tv.RenderCursor(true)
+	return true
+}
+
+// AddCursorNext adds a cursor at the next occurrence of the selected text
+// after the main cursor, wrapping around to the start, with that text
+// selected, and makes it the main cursor.  If nothing is selected, the word
+// at the cursor is selected first.  Returns false if there is no other
+// occurrence.
+func (tv *TextView) AddCursorNext() bool {
+	wupdt := tv.TopUpdateStart()
+	defer tv.TopUpdateEnd(wupdt)
+	if !tv.HasSelection() {
+		if !tv.SelectWord() || !tv.HasSelection() {
+			return false
+		}
+		tv.SetCursorShow(tv.SelectReg.End)
+		tv.SetCursorCol(tv.CursorPos)
+		tv.RenderSelectLines()
+		return true
+	}
+	_, matches := tv.Buf.Search(tv.Selection().ToBytes(), false, false)
+	nm := len(matches)
+	if nm == 0 {
+		return false
+	}
+	st := sort.Search(nm, func(i int) bool {
+		return !matches[i].Reg.Start.IsLess(tv.SelectReg.End)
+	})
+	for i := 0; i < nm; i++ {
+		reg := matches[(st+i)%nm].Reg
+		if tv.SelectReg.Start == reg.Start {
+			continue
+		}
+		got := false
+		for _, tc := range tv.MultiCursors {
+			if tc.SelectReg.Start == reg.Start {
+				got = true
+				break
+			}
+		}
+		if got {
+			continue
+		}
+		tv.MultiCursors = append(tv.MultiCursors, tv.CursorState())
+		tv.SelectStart = reg.Start
+		tv.SelectReg = reg
+		tv.PrevSelectReg = textbuf.RegionNil
+		tv.SetCursorShow(reg.End)
+		tv.SetCursorCol(tv.CursorPos)
+		tv.RenderAllLines()
+		return true
+	}
+	return false
+}
+
+// SelectColumn selects a rectangular column of text from st to ed, with one
+// cursor per line, each selecting from the st to the ed character column
+// (limited to the length of the line) -- the main cursor is on the ed line
+func (tv *TextView) SelectColumn(st, ed lex.Pos) {
+	wupdt := tv.TopUpdateStart()
+	defer tv.TopUpdateEnd(wupdt)
+	tv.MultiCursors = tv.MultiCursors[:0]
+	dir := 1
+	if ed.Ln < st.Ln {
+		dir = -1
+	}
+	var main TextViewCursor
+	for ln := st.Ln; ln != ed.Ln+dir; ln += dir {
+		if tv.IsLineHidden(ln) {
+			continue
+		}
+		sz := tv.Buf.LineLen(ln)
+		tc := TextViewCursor{Pos: lex.Pos{Ln: ln, Ch: ints.MinInt(ed.Ch, sz)}, Col: ed.Ch, SelectReg: textbuf.RegionNil, PrevSelectReg: textbuf.RegionNil}
+		tc.SelectStart = lex.Pos{Ln: ln, Ch: ints.MinInt(st.Ch, sz)}
+		if tc.Pos.IsLess(tc.SelectStart) {
+			tc.SelectReg = textbuf.NewRegionPos(tc.Pos, tc.SelectStart)
+		} else if tc.SelectStart.IsLess(tc.Pos) {
+			tc.SelectReg = textbuf.NewRegionPos(tc.SelectStart, tc.Pos)
+		}
+		if ln == ed.Ln {
+			main = tc
+		} else {
+			tv.MultiCursors = append(tv.MultiCursors, tc)
+		}
+	}
+	tv.SelectMode = false
+	tv.SelectStart = main.SelectStart
+	tv.SelectReg = main.SelectReg
+	tv.SetCursor(main.Pos)
+	tv.CursorCol = main.Col
+	tv.RenderAllLines()
+	tv.RenderCursor(true)
+}
+
+// MultiSelectionBytes returns the selected text of all of the cursors, in
+// order of their position, one per line
+func (tv *TextView) MultiSelectionBytes() []byte {
+	var sels [][]byte
+	tv.MultiCursorDo(false, func(ci int) {
+		var sb []byte
+		if sel := tv.Selection(); sel != nil {
+			sb = sel.ToBytes()
+		}
+		sels = append(sels, sb)
+	})
+	return bytes.Join(sels, []byte("\n"))
+}
+
+// MultiCursorKeyFun returns true if given key function is applied at all
+// of the cursors when there are multiple cursors -- others clear the
+// additional cursors
+func (tv *TextView) MultiCursorKeyFun(kf gi.KeyFuns) bool {
+	switch kf {
+	case gi.KeyFunNil, gi.KeyFunMoveRight, gi.KeyFunWordRight, gi.KeyFunMoveLeft, gi.KeyFunWordLeft,
+		gi.KeyFunMoveUp, gi.KeyFunMoveDown, gi.KeyFunHome, gi.KeyFunEnd,
+		gi.KeyFunBackspace, gi.KeyFunDelete, gi.KeyFunBackspaceWord, gi.KeyFunDeleteWord,
+		gi.KeyFunCut, gi.KeyFunCopy, gi.KeyFunPaste, gi.KeyFunEnter, gi.KeyFunFocusNext,
+		gi.KeyFunAddCursorAbove, gi.KeyFunAddCursorBelow, gi.KeyFunAddCursorNext:
+		return true
+	}
+	return false
+}
+
+// MultiCursorSpriteName returns the name of the sprite for the additional
+// cursor at given index
+func (tv *TextView) MultiCursorSpriteName(idx int) string {
+	return fmt.Sprintf("%v-%d", tv.CursorSpriteName(), idx)
+}
+
+// RenderMultiCursors renders the additional cursors on or off, as sprites,
+// and turns off the sprites of cursors that have been removed -- called by
+// RenderCursor
+func (tv *TextView) RenderMultiCursors(win *gi.Window, on bool) {
+	for i, tc := range tv.MultiCursors {
+		sp := tv.CursorSpriteNamed(tv.MultiCursorSpriteName(i))
+		sp.Geom.Pos = tv.CharStartPos(tc.Pos).ToPointFloor()
+		if on && !tv.IsLineHidden(tc.Pos.Ln) && sp.Geom.Pos.In(tv.VpBBox) {
+			win.ActivateSprite(sp.Name)
+		} else {
+			win.InactivateSprite(sp.Name)
+		}
+	}
+	for i := len(tv.MultiCursors); i < tv.multiSprites; i++ {
+		win.InactivateSprite(tv.MultiCursorSpriteName(i))
+	}
+	tv.multiSprites = len(tv.MultiCursors)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+//    Complete and Spell
+
+// OfferComplete pops up a menu of possible completions
+func (tv *TextView) OfferComplete() {
+	if tv.Buf.Complete == nil || tv.ISearch.On || tv.QReplace.On || tv.IsInactive() || tv.HasMultiCursors() {
+		return
+	}
+	tv.Buf.Complete.Cancel()
+	if !tv.Buf.Opts.Completion && !tv.ForceComplete {
+		return
+	}
+	if tv.Buf.InComment(tv.CursorPos) || tv.Buf.InLitString(tv.CursorPos) {
+		return
+	}
+
+	tv.Buf.Complete.SrcLn = tv.CursorPos.Ln
+	tv.Buf.Complete.SrcCh = tv.CursorPos.Ch
+	st := lex.Pos{tv.CursorPos.Ln, 0}
+	en := lex.Pos{tv.CursorPos.Ln, tv.CursorPos.Ch}
+	tbe := tv.Buf.Region(st, en)
+	var s string
+	if tbe != nil {
+		s = string(tbe.ToBytes())
+		s = strings.TrimLeft(s, " \t") // trim ' ' and '\t'
+	}
+
+	cpos := tv.CharStartPos(tv.CursorPos).ToPoint() // physical location
+	cpos.X += 5
+	cpos.Y += 10
+	tv.Buf.CurView = tv
+	tv.Buf.Complete.Show(s, tv.CursorPos.Ln, tv.CursorPos.Ch, tv.Viewport, cpos, tv.ForceComplete)
+}
+
+// CancelComplete cancels any pending completion -- call this when new events
+// have moved beyond any prior completion scenario
+func (tv *TextView) CancelComplete() {
+	tv.ForceComplete = false
+	if tv.Buf == nil {
+		return
+	}
+	if tv.Buf.Complete == nil {
+		return
+	}
+	if tv.Buf.Complete.Cancel() {
+		tv.Buf.CurView = nil
+	}
+}
+
+// Lookup attempts to lookup symbol at current location, popping up a window
+// if something is found
+func (tv *TextView) Lookup() {
+	if tv.Buf.Complete == nil || tv.ISearch.On || tv.QReplace.On || tv.IsInactive() {
+		return
+	}
+
+	var ln int
+	var ch int
+	if tv.HasSelection() {
+		ln = tv.SelectReg.Start.Ln
+		if tv.SelectReg.End.Ln != ln {
+			return // no multiline selections for lookup
+		}
+		ch = tv.SelectReg.End.Ch
+	} else {
+		ln = tv.CursorPos.Ln
+		if tv.IsWordEnd(tv.CursorPos) {
+			ch = tv.CursorPos.Ch
+		} else {
+			ch = tv.WordAt().End.Ch
+		}
+	}
+	tv.Buf.Complete.SrcLn = ln
+	tv.Buf.Complete.SrcCh = ch
+	st := lex.Pos{tv.CursorPos.Ln, 0}
+	en := lex.Pos{tv.CursorPos.Ln, ch}
+
+	tbe := tv.Buf.Region(st, en)
+	var s string
+	if tbe != nil {
+		s = string(tbe.ToBytes())
+		s = strings.TrimLeft(s, " \t") // trim ' ' and '\t'
+	}
+
+	cpos := tv.CharStartPos(tv.CursorPos).ToPoint() // physical location
+	cpos.X += 5
+	cpos.Y += 10
+	tv.Buf.CurView = tv
+	tv.Buf.Complete.Lookup(s, tv.CursorPos.Ln, tv.CursorPos.Ch, tv.Viewport, cpos, tv.ForceComplete)
+}
+
+// ISpellKeyInput locates the word to spell check based on cursor position and
+// the key input, then passes the text region to SpellCheck
+func (tv *TextView) ISpellKeyInput(kt *key.ChordEvent) {
+	if !tv.Buf.IsSpellEnabled(tv.CursorPos) {
+		return
+	}
+
+	isDoc := tv.Buf.Info.Cat == filecat.Doc
+	tp := tv.CursorPos
+
+	kf := gi.KeyFun(kt.Chord())
+	switch kf {
 	case gi.KeyFunMoveUp:
 		if isDoc {
 			tv.Buf.SpellCheckLineTag(tp.Ln)
@@ -3176,6 +4539,7 @@ func (tv *TextView) RenderCursor(on bool) {
 		win.InactivateSprite(sp.Name)
 	}
 	sp.Geom.Pos = tv.CharStartPos(tv.CursorPos).ToPointFloor()
+	tv.RenderMultiCursors(win, on)
 	win.RenderOverlays() // needs an explicit call!
 	win.UpdateSig()      // publish
 }
@@ -3190,12 +4554,17 @@ func (tv *TextView) CursorSpriteName() string {
 // only rendered once with a vertical bar, and just activated and inactivated
 // depending on render status.
 func (tv *TextView) CursorSprite() *gi.Sprite {
+	return tv.CursorSpriteNamed(tv.CursorSpriteName())
+}
+
+// CursorSpriteNamed returns the cursor sprite of given name, creating it if
+// needed -- additional cursors each have their own sprite
+func (tv *TextView) CursorSpriteNamed(spnm string) *gi.Sprite {
 	win := tv.ParentWindow()
 	if win == nil {
 		return nil
 	}
 	sty := &tv.StateStyles[TextViewActive]
-	spnm := tv.CursorSpriteName()
 	sp, ok := win.SpriteByName(spnm)
 	if !ok {
 		bbsz := image.Point{int(math32.Ceil(tv.CursorWidth.Dots)), int(math32.Ceil(tv.FontHeight))}
@@ -3230,8 +4599,6 @@ func (tv *TextView) RenderDepthBg(stln, edln int) {
 	if !tv.Buf.Opts.DepthColor || tv.IsInactive() || !tv.HasFocus() || !tv.IsFocusActive() {
 		return
 	}
-	tv.Buf.MarkupMu.RLock() // needed for HiTags access
-	defer tv.Buf.MarkupMu.RUnlock()
 	sty := &tv.Sty
 	cspec := sty.Font.BgColor
 	bg := cspec.Color
@@ -3239,18 +4606,18 @@ func (tv *TextView) RenderDepthBg(stln, edln int) {
 	nclrs := len(TextViewDepthColors)
 	lstdp := 0
 	for ln := stln; ln <= edln; ln++ {
+		if tv.IsLineHidden(ln) {
+			continue
+		}
 		lst := tv.CharStartPos(lex.Pos{Ln: ln}).Y // note: charstart pos includes descent
-		led := lst + math32.Max(tv.Renders[ln].Size.Y, tv.LineHeight)
+		led := lst + tv.LineDispHeight(ln)
 		if int(math32.Ceil(led)) < tv.VpBBox.Min.Y {
 			continue
 		}
 		if int(math32.Floor(lst)) > tv.VpBBox.Max.Y {
 			continue
 		}
-		if ln >= len(tv.Buf.HiTags) { // may be out of sync
-			continue
-		}
-		ht := tv.Buf.HiTags[ln]
+		ht := tv.Buf.LineHiTags(ln)
 		lsted := 0
 		for ti := range ht {
 			lx := &ht[ti]
@@ -3278,6 +4645,11 @@ func (tv *TextView) RenderDepthBg(stln, edln int) {
 // RenderSelect renders the selection region as a selected background color
 // -- always called within context of outer RenderLines or RenderAllLines
 func (tv *TextView) RenderSelect() {
+	for i := range tv.MultiCursors {
+		if tc := &tv.MultiCursors[i]; tc.HasSelection() {
+			tv.RenderRegionBox(tc.SelectReg, TextViewSel)
+		}
+	}
 	if !tv.HasSelection() {
 		return
 	}
@@ -3360,6 +4732,16 @@ func (tv *TextView) RenderRegionBox(reg textbuf.Region, state TextViewStates) {
 func (tv *TextView) RenderRegionBoxSty(reg textbuf.Region, sty *gi.Style, bgclr *gi.ColorSpec) {
 	st := reg.Start
 	ed := reg.End
+	if tv.IsLineHidden(st.Ln) { // clip to visible lines
+		st = lex.Pos{Ln: tv.NextVisibleLine(st.Ln)}
+	}
+	if tv.IsLineHidden(ed.Ln) {
+		ed.Ln = tv.PrevVisibleLine(ed.Ln)
+		ed.Ch = tv.Buf.LineLen(ed.Ln)
+	}
+	if st.Ln >= tv.NLines || ed.Ln < st.Ln {
+		return
+	}
 	spos := tv.CharStartPos(st)
 	epos := tv.CharStartPos(ed)
 	epos.Y += tv.LineHeight
@@ -3404,6 +4786,9 @@ func (tv *TextView) RenderRegionBoxSty(reg textbuf.Region, sty *gi.Style, bgclr
 
 // RenderRegionToEnd renders a region in given style and background color, to end of line from start
 func (tv *TextView) RenderRegionToEnd(st lex.Pos, sty *gi.Style, bgclr *gi.ColorSpec) {
+	if tv.IsLineHidden(st.Ln) {
+		return
+	}
 	spos := tv.CharStartPos(st)
 	epos := spos
 	epos.Y += tv.LineHeight
@@ -3459,6 +4844,18 @@ func (tv *TextView) VisSizes() {
 		tv.ClearFlag(int(TextViewHasLineNos))
 		tv.LineNoOff = 0
 	}
+	mm := false
+	if tv.Buf != nil {
+		mm = tv.Buf.Opts.Minimap
+	}
+	if mp, has := tv.Props["minimap"]; has {
+		mm, _ = kit.ToBool(mp)
+	}
+	if mm {
+		tv.MinimapOff = TextViewMinimapWidth
+	} else {
+		tv.MinimapOff = 0
+	}
 	tv.RenderSize()
 }
 
@@ -3486,30 +4883,20 @@ func (tv *TextView) RenderAllLines() {
 func (tv *TextView) RenderAllLinesInBounds() {
 	// fmt.Printf("render all: %v\n", tv.Nm)
 	rs := tv.Render()
-	rs.Lock()
-	pc := &rs.Paint
 	sty := &tv.Sty
 	tv.VisSizes()
-	pos := mat32.NewVec2FmPoint(tv.VpBBox.Min)
-	epos := mat32.NewVec2FmPoint(tv.VpBBox.Max)
-	pc.FillBox(rs, pos, epos.Sub(pos), &sty.Font.BgColor)
-	pos = tv.RenderStartPos()
-	stln := -1
-	edln := -1
-	for ln := 0; ln < tv.NLines; ln++ {
-		lst := pos.Y + tv.Offs[ln]
-		led := lst + math32.Max(tv.Renders[ln].Size.Y, tv.LineHeight)
-		if int(math32.Ceil(led)) < tv.VpBBox.Min.Y {
-			continue
-		}
-		if int(math32.Floor(lst)) > tv.VpBBox.Max.Y {
-			continue
-		}
-		if stln < 0 {
-			stln = ln
-		}
-		edln = ln
+	pos := tv.RenderStartPos()
+	stln, edln := tv.VisibleLines(pos)
+	if stln >= 0 && tv.MarkupLines(stln, edln) {
+		pos = tv.RenderStartPos()
+		stln, edln = tv.VisibleLines(pos)
+		tv.MarkupLines(stln, edln) // any newly visible ones
 	}
+	rs.Lock()
+	pc := &rs.Paint
+	spos := mat32.NewVec2FmPoint(tv.VpBBox.Min)
+	epos := mat32.NewVec2FmPoint(tv.VpBBox.Max)
+	pc.FillBox(rs, spos, epos.Sub(spos), &sty.Font.BgColor)
 
 	if stln < 0 || edln < 0 { // shouldn't happen.
 		rs.Unlock()
@@ -3519,13 +4906,16 @@ func (tv *TextView) RenderAllLinesInBounds() {
 	if tv.HasLineNos() {
 		tv.RenderLineNosBoxAll()
 		for ln := stln; ln <= edln; ln++ {
-			tv.RenderLineNo(ln, false, false) // don't re-render std fill boxes, no separate vp upload
+			if !tv.IsLineHidden(ln) {
+				tv.RenderLineNo(ln, false, false) // don't re-render std fill boxes, no separate vp upload
+			}
 		}
 	}
 
 	tv.RenderDepthBg(stln, edln)
 	tv.RenderHighlights(stln, edln)
 	tv.RenderScopelights(stln, edln)
+	tv.RenderCollabSels()
 	tv.RenderSelect()
 	if tv.HasLineNos() {
 		tbb := tv.VpBBox
@@ -3535,16 +4925,49 @@ func (tv *TextView) RenderAllLinesInBounds() {
 		rs.Lock()
 	}
 	for ln := stln; ln <= edln; ln++ {
+		if tv.IsLineHidden(ln) {
+			continue
+		}
 		lst := pos.Y + tv.Offs[ln]
 		lp := pos
 		lp.Y = lst
 		lp.X += tv.LineNoOff
 		tv.Renders[ln].Render(rs, lp) // not top pos -- already has baseline offset
 	}
+	tv.RenderDiagnostics(stln, edln)
+	tv.RenderBlame(stln, edln)
+	tv.RenderCollabCursors(stln, edln)
+	tv.RenderVimMode()
 	rs.Unlock()
 	if tv.HasLineNos() {
 		rs.PopBounds()
 	}
+	rs.Lock()
+	tv.RenderMinimap()
+	rs.Unlock()
+	tv.UpdateOverviewMarks()
+}
+
+// VisibleLines returns the range of lines (inclusive) that are visible
+// within the view, with given starting render position -- -1 if none are
+func (tv *TextView) VisibleLines(pos mat32.Vec2) (stln, edln int) {
+	stln = -1
+	edln = -1
+	for ln := 0; ln < tv.NLines; ln++ {
+		lst := pos.Y + tv.Offs[ln]
+		led := lst + tv.LineDispHeight(ln)
+		if int(math32.Ceil(led)) < tv.VpBBox.Min.Y {
+			continue
+		}
+		if int(math32.Floor(lst)) > tv.VpBBox.Max.Y {
+			continue
+		}
+		if stln < 0 {
+			stln = ln
+		}
+		edln = ln
+	}
+	return
 }
 
 // RenderLineNosBoxAll renders the background for the line numbers in a darker shade
@@ -3637,6 +5060,16 @@ func (tv *TextView) RenderLineNo(ln int, defFill bool, vpUpload bool) {
 	pos.X = float32(tv.VpBBox.Min.X) + spc
 
 	tv.LineNoRender.Render(rs, pos)
+	if _, ok := tv.FoldRegionAt(ln); ok {
+		mi := 0
+		if tv.IsFolded(ln) {
+			mi = 1
+		}
+		tv.LineNoRender.SetString(TextViewFoldMarkers[mi], &fst, &sty.UnContext, &sty.Text, true, 0, 0)
+		pos.X += float32(tv.LineNoDigs+1) * sty.Font.Face.Metrics.Ch
+		tv.LineNoRender.Render(rs, pos)
+	}
+	tv.RenderChangeMarker(ln, sbox, ebox)
 	// todo: need an SvgRender interface that just takes an svg file or object
 	// and renders it to a given bitmap, and then just keep that around.
 	// if icnm, ok := tv.Buf.LineIcons[ln]; ok {
@@ -3705,8 +5138,11 @@ func (tv *TextView) RenderLines(st, ed int) bool {
 	visSt := -1
 	visEd := -1
 	for ln := st; ln <= ed; ln++ {
+		if tv.IsLineHidden(ln) {
+			continue
+		}
 		lst := tv.CharStartPos(lex.Pos{Ln: ln}).Y // note: charstart pos includes descent
-		led := lst + math32.Max(tv.Renders[ln].Size.Y, tv.LineHeight)
+		led := lst + tv.LineDispHeight(ln)
 		if int(math32.Ceil(led)) < tv.VpBBox.Min.Y {
 			continue
 		}
@@ -3723,6 +5159,12 @@ func (tv *TextView) RenderLines(st, ed int) bool {
 		lp.Y = led
 		boxMax = lp
 	}
+	if visSt >= 0 && tv.MarkupLines(visSt, visEd) { // following lines moved
+		tv.PopBounds()
+		tv.TopUpdateEnd(wupdt)
+		tv.RenderAllLines()
+		return true
+	}
 	if !(visSt < 0 && visEd < 0) {
 		rs.Lock()
 		boxMin.X = float32(tv.VpBBox.Min.X) // go all the way
@@ -3733,12 +5175,15 @@ func (tv *TextView) RenderLines(st, ed int) bool {
 		tv.RenderDepthBg(visSt, visEd)
 		tv.RenderHighlights(visSt, visEd)
 		tv.RenderScopelights(visSt, visEd)
+		tv.RenderCollabSels()
 		tv.RenderSelect()
 		tv.RenderLineNosBox(visSt, visEd)
 
 		if tv.HasLineNos() {
 			for ln := visSt; ln <= visEd; ln++ {
-				tv.RenderLineNo(ln, true, false)
+				if !tv.IsLineHidden(ln) {
+					tv.RenderLineNo(ln, true, false)
+				}
 			}
 			tbb := tv.VpBBox
 			tbb.Min.X += int(tv.LineNoOff)
@@ -3747,23 +5192,38 @@ func (tv *TextView) RenderLines(st, ed int) bool {
 			rs.Lock()
 		}
 		for ln := visSt; ln <= visEd; ln++ {
+			if tv.IsLineHidden(ln) {
+				continue
+			}
 			lst := pos.Y + tv.Offs[ln]
 			lp := pos
 			lp.Y = lst
 			lp.X += tv.LineNoOff
 			tv.Renders[ln].Render(rs, lp) // not top pos -- already has baseline offset
 		}
+		tv.RenderDiagnostics(visSt, visEd)
+		tv.RenderBlame(visSt, visEd)
+		tv.RenderCollabCursors(visSt, visEd)
+		tv.RenderVimMode()
 		rs.Unlock()
 		if tv.HasLineNos() {
 			rs.PopBounds()
 		}
+		rs.Lock()
+		tv.RenderMinimap()
+		rs.Unlock()
 
 		tBBox := image.Rectangle{boxMin.ToPointFloor(), boxMax.ToPointCeil()}
 		vprel := tBBox.Min.Sub(tv.VpBBox.Min)
 		tWinBBox := tv.WinBBox.Add(vprel)
 		vp.This().(gi.Viewport).VpUploadRegion(tBBox, tWinBBox)
 		// fmt.Printf("tbbox: %v  twinbbox: %v\n", tBBox, tWinBBox)
+		if tv.MinimapOff > 0 {
+			mbb := tv.MinimapBBox()
+			vp.This().(gi.Viewport).VpUploadRegion(mbb, tv.WinBBox.Add(mbb.Min.Sub(tv.VpBBox.Min)))
+		}
 	}
+	tv.UpdateOverviewMarks()
 	tv.PopBounds()
 	tv.RenderScrolls()
 	tv.TopUpdateEnd(wupdt)
@@ -3838,7 +5298,7 @@ func (tv *TextView) PixelToCursor(pt image.Point) lex.Pos {
 		for ln := stln; ln < tv.NLines; ln++ {
 			ls := tv.CharStartPos(lex.Pos{Ln: ln}).Y - yoff
 			es := ls
-			es += math32.Max(tv.Renders[ln].Size.Y, tv.LineHeight)
+			es += tv.LineDispHeight(ln)
 			if pt.Y >= int(math32.Floor(ls)) && pt.Y < int(math32.Ceil(es)) {
 				got = true
 				cln = ln
@@ -4047,6 +5507,20 @@ func (tv *TextView) KeyInput(kt *key.ChordEvent) {
 		return
 	}
 
+	if tv.Snippet != nil {
+		defer tv.SnippetMirrors()
+		if tv.SnippetKeyInput(kf) {
+			tv.CancelComplete()
+			kt.SetProcessed()
+			return
+		}
+	}
+
+	if gi.ActiveKeyMapVim && !tv.IsInactive() && !tv.ISearch.On && !tv.QReplace.On && tv.VimKeyInput(kt) {
+		kt.SetProcessed()
+		return
+	}
+
 	// cancelAll cancels search, completer, and..
 	cancelAll := func() {
 		tv.CancelComplete()
@@ -4056,6 +5530,10 @@ func (tv *TextView) KeyInput(kt *key.ChordEvent) {
 		tv.lastAutoInsert = 0
 	}
 
+	if tv.HasMultiCursors() && !tv.MultiCursorKeyFun(kf) {
+		tv.ClearMultiCursors()
+	}
+
 	if kf != gi.KeyFunRecenter { // always start at centering
 		tv.lastRecenter = 0
 	}
@@ -4072,41 +5550,53 @@ func (tv *TextView) KeyInput(kt *key.ChordEvent) {
 	case gi.KeyFunMoveRight:
 		cancelAll()
 		kt.SetProcessed()
-		tv.ShiftSelect(kt)
-		tv.CursorForward(1)
-		tv.ShiftSelectExtend(kt)
+		tv.MultiCursorDo(false, func(ci int) {
+			tv.ShiftSelect(kt)
+			tv.CursorForward(1)
+			tv.ShiftSelectExtend(kt)
+		})
 		tv.ISpellKeyInput(kt)
 	case gi.KeyFunWordRight:
 		cancelAll()
 		kt.SetProcessed()
-		tv.ShiftSelect(kt)
-		tv.CursorForwardWord(1)
-		tv.ShiftSelectExtend(kt)
+		tv.MultiCursorDo(false, func(ci int) {
+			tv.ShiftSelect(kt)
+			tv.CursorForwardWord(1)
+			tv.ShiftSelectExtend(kt)
+		})
 	case gi.KeyFunMoveLeft:
 		cancelAll()
 		kt.SetProcessed()
-		tv.ShiftSelect(kt)
-		tv.CursorBackward(1)
-		tv.ShiftSelectExtend(kt)
+		tv.MultiCursorDo(false, func(ci int) {
+			tv.ShiftSelect(kt)
+			tv.CursorBackward(1)
+			tv.ShiftSelectExtend(kt)
+		})
 	case gi.KeyFunWordLeft:
 		cancelAll()
 		kt.SetProcessed()
-		tv.ShiftSelect(kt)
-		tv.CursorBackwardWord(1)
-		tv.ShiftSelectExtend(kt)
+		tv.MultiCursorDo(false, func(ci int) {
+			tv.ShiftSelect(kt)
+			tv.CursorBackwardWord(1)
+			tv.ShiftSelectExtend(kt)
+		})
 	case gi.KeyFunMoveUp:
 		cancelAll()
 		kt.SetProcessed()
-		tv.ShiftSelect(kt)
-		tv.CursorUp(1)
-		tv.ShiftSelectExtend(kt)
+		tv.MultiCursorDo(false, func(ci int) {
+			tv.ShiftSelect(kt)
+			tv.CursorUp(1)
+			tv.ShiftSelectExtend(kt)
+		})
 		tv.ISpellKeyInput(kt)
 	case gi.KeyFunMoveDown:
 		cancelAll()
 		kt.SetProcessed()
-		tv.ShiftSelect(kt)
-		tv.CursorDown(1)
-		tv.ShiftSelectExtend(kt)
+		tv.MultiCursorDo(false, func(ci int) {
+			tv.ShiftSelect(kt)
+			tv.CursorDown(1)
+			tv.ShiftSelectExtend(kt)
+		})
 		tv.ISpellKeyInput(kt)
 	case gi.KeyFunPageUp:
 		cancelAll()
@@ -4123,15 +5613,19 @@ func (tv *TextView) KeyInput(kt *key.ChordEvent) {
 	case gi.KeyFunHome:
 		cancelAll()
 		kt.SetProcessed()
-		tv.ShiftSelect(kt)
-		tv.CursorStartLine()
-		tv.ShiftSelectExtend(kt)
+		tv.MultiCursorDo(false, func(ci int) {
+			tv.ShiftSelect(kt)
+			tv.CursorStartLine()
+			tv.ShiftSelectExtend(kt)
+		})
 	case gi.KeyFunEnd:
 		cancelAll()
 		kt.SetProcessed()
-		tv.ShiftSelect(kt)
-		tv.CursorEndLine()
-		tv.ShiftSelectExtend(kt)
+		tv.MultiCursorDo(false, func(ci int) {
+			tv.ShiftSelect(kt)
+			tv.CursorEndLine()
+			tv.ShiftSelectExtend(kt)
+		})
 	case gi.KeyFunDocHome:
 		cancelAll()
 		kt.SetProcessed()
@@ -4178,6 +5672,34 @@ func (tv *TextView) KeyInput(kt *key.ChordEvent) {
 		cancelAll()
 		kt.SetProcessed()
 		tv.JumpToLinePrompt()
+	case gi.KeyFunFold:
+		cancelAll()
+		kt.SetProcessed()
+		tv.FoldAt(tv.CursorPos.Ln)
+	case gi.KeyFunUnfold:
+		cancelAll()
+		kt.SetProcessed()
+		tv.UnfoldAt(tv.CursorPos.Ln)
+	case gi.KeyFunFoldAll:
+		cancelAll()
+		kt.SetProcessed()
+		tv.FoldAll()
+	case gi.KeyFunUnfoldAll:
+		cancelAll()
+		kt.SetProcessed()
+		tv.UnfoldAll()
+	case gi.KeyFunAddCursorAbove:
+		cancelAll()
+		kt.SetProcessed()
+		tv.AddCursorAbove()
+	case gi.KeyFunAddCursorBelow:
+		cancelAll()
+		kt.SetProcessed()
+		tv.AddCursorBelow()
+	case gi.KeyFunAddCursorNext:
+		cancelAll()
+		kt.SetProcessed()
+		tv.AddCursorNext()
 	case gi.KeyFunHistPrev:
 		cancelAll()
 		kt.SetProcessed()
@@ -4232,7 +5754,9 @@ func (tv *TextView) KeyInput(kt *key.ChordEvent) {
 			tv.ISearchBackspace()
 		} else {
 			kt.SetProcessed()
-			tv.CursorBackspace(1)
+			tv.MultiCursorDo(true, func(ci int) {
+				tv.CursorBackspace(1)
+			})
 			tv.ISpellKeyInput(kt)
 			tv.OfferComplete()
 		}
@@ -4243,16 +5767,22 @@ func (tv *TextView) KeyInput(kt *key.ChordEvent) {
 	case gi.KeyFunDelete:
 		cancelAll()
 		kt.SetProcessed()
-		tv.CursorDelete(1)
+		tv.MultiCursorDo(true, func(ci int) {
+			tv.CursorDelete(1)
+		})
 		tv.ISpellKeyInput(kt)
 	case gi.KeyFunBackspaceWord:
 		cancelAll()
 		kt.SetProcessed()
-		tv.CursorBackspaceWord(1)
+		tv.MultiCursorDo(true, func(ci int) {
+			tv.CursorBackspaceWord(1)
+		})
 	case gi.KeyFunDeleteWord:
 		cancelAll()
 		kt.SetProcessed()
-		tv.CursorDeleteWord(1)
+		tv.MultiCursorDo(true, func(ci int) {
+			tv.CursorDeleteWord(1)
+		})
 	case gi.KeyFunCut:
 		cancelAll()
 		kt.SetProcessed()
@@ -4296,27 +5826,7 @@ func (tv *TextView) KeyInput(kt *key.ChordEvent) {
 		cancelAll()
 		if !kt.HasAnyModifier(key.Control, key.Meta) {
 			kt.SetProcessed()
-			if tv.Buf.Opts.AutoIndent {
-				bufUpdt, winUpdt, autoSave := tv.Buf.BatchUpdateStart()
-				lp, _ := pi.LangSupport.Props(tv.Buf.PiState.Sup)
-				if lp != nil && lp.Lang != nil && lp.HasFlag(pi.ReAutoIndent) {
-					// only re-indent current line for supported types
-					tbe, _, _ := tv.Buf.AutoIndent(tv.CursorPos.Ln) // reindent current line
-					if tbe != nil {
-						// go back to end of line!
-						npos := lex.Pos{Ln: tv.CursorPos.Ln, Ch: tv.Buf.LineLen(tv.CursorPos.Ln)}
-						tv.SetCursor(npos)
-					}
-				}
-				tv.InsertAtCursor([]byte("\n"))
-				tbe, _, cpos := tv.Buf.AutoIndent(tv.CursorPos.Ln)
-				if tbe != nil {
-					tv.SetCursorShow(lex.Pos{Ln: tbe.Reg.End.Ln, Ch: cpos})
-				}
-				tv.Buf.BatchUpdateEnd(bufUpdt, winUpdt, autoSave)
-			} else {
-				tv.InsertAtCursor([]byte("\n"))
-			}
+			tv.MultiCursorDo(true, func(ci int) { tv.KeyInputEnter() })
 			tv.ISpellKeyInput(kt)
 		}
 		// todo: KeFunFocusPrev -- unindent
@@ -4324,17 +5834,9 @@ func (tv *TextView) KeyInput(kt *key.ChordEvent) {
 		cancelAll()
 		if !kt.HasAnyModifier(key.Control, key.Meta) {
 			kt.SetProcessed()
-			wupdt := tv.TopUpdateStart()
-			lasttab := tv.HasFlag(int(TextViewLastWasTabAI))
-			if !lasttab && tv.CursorPos.Ch == 0 && tv.Buf.Opts.AutoIndent {
-				_, _, cpos := tv.Buf.AutoIndent(tv.CursorPos.Ln)
-				tv.CursorPos.Ch = cpos
-				tv.RenderCursor(true)
-				gotTabAI = true
-			} else {
-				tv.InsertAtCursor(indent.Bytes(tv.Buf.Opts.IndentChar(), 1, tv.Sty.Text.TabSize))
-			}
-			tv.TopUpdateEnd(wupdt)
+			tv.MultiCursorDo(true, func(ci int) {
+				gotTabAI = tv.KeyInputTab()
+			})
 			tv.ISpellKeyInput(kt)
 		}
 	case gi.KeyFunFocusPrev: // shift-tab
@@ -4355,7 +5857,11 @@ func (tv *TextView) KeyInput(kt *key.ChordEvent) {
 	case gi.KeyFunNil:
 		if unicode.IsPrint(kt.Rune) {
 			if !kt.HasAnyModifier(key.Control, key.Meta) {
-				tv.KeyInputInsertRune(kt)
+				if tv.ISearch.On || tv.QReplace.On {
+					tv.KeyInputInsertRune(kt)
+				} else {
+					tv.MultiCursorDo(true, func(ci int) { tv.KeyInputInsertRune(kt) })
+				}
 			}
 		}
 		if unicode.IsSpace(kt.Rune) {
@@ -4366,6 +5872,49 @@ func (tv *TextView) KeyInput(kt *key.ChordEvent) {
 	tv.SetFlagState(gotTabAI, int(TextViewLastWasTabAI))
 }
 
+// KeyInputEnter handles the enter key, inserting a new line at the cursor,
+// with auto-indent if that option is on
+func (tv *TextView) KeyInputEnter() {
+	if tv.Buf.Opts.AutoIndent {
+		bufUpdt, winUpdt, autoSave := tv.Buf.BatchUpdateStart()
+		lp, _ := pi.LangSupport.Props(tv.Buf.PiState.Sup)
+		if lp != nil && lp.Lang != nil && lp.HasFlag(pi.ReAutoIndent) {
+			// only re-indent current line for supported types
+			tbe, _, _ := tv.Buf.AutoIndent(tv.CursorPos.Ln) // reindent current line
+			if tbe != nil {
+				// go back to end of line!
+				npos := lex.Pos{Ln: tv.CursorPos.Ln, Ch: tv.Buf.LineLen(tv.CursorPos.Ln)}
+				tv.SetCursor(npos)
+			}
+		}
+		tv.InsertAtCursor([]byte("\n"))
+		tbe, _, cpos := tv.Buf.AutoIndent(tv.CursorPos.Ln)
+		if tbe != nil {
+			tv.SetCursorShow(lex.Pos{Ln: tbe.Reg.End.Ln, Ch: cpos})
+		}
+		tv.Buf.BatchUpdateEnd(bufUpdt, winUpdt, autoSave)
+	} else {
+		tv.InsertAtCursor([]byte("\n"))
+	}
+}
+
+// KeyInputTab handles the tab key, which auto-indents the line if at the
+// start of it (and the last key was not such a tab), and otherwise inserts
+// an indent -- returns true if it auto-indented
+func (tv *TextView) KeyInputTab() bool {
+	wupdt := tv.TopUpdateStart()
+	defer tv.TopUpdateEnd(wupdt)
+	lasttab := tv.HasFlag(int(TextViewLastWasTabAI))
+	if !lasttab && tv.CursorPos.Ch == 0 && tv.Buf.Opts.AutoIndent {
+		_, _, cpos := tv.Buf.AutoIndent(tv.CursorPos.Ln)
+		tv.CursorPos.Ch = cpos
+		tv.RenderCursor(true)
+		return true
+	}
+	tv.InsertAtCursor(indent.Bytes(tv.Buf.Opts.IndentChar(), 1, tv.Sty.Text.TabSize))
+	return false
+}
+
 // KeyInputInsertBra handle input of opening bracket-like entity (paren, brace, bracket)
 func (tv *TextView) KeyInputInsertBra(kt *key.ChordEvent) {
 	bufUpdt, winUpdt, autoSave := tv.Buf.BatchUpdateStart()
@@ -4451,6 +6000,9 @@ func (tv *TextView) KeyInputInsertRune(kt *key.ChordEvent) {
 				tv.OfferComplete()
 			}
 		}
+		if kt.Rune == '(' || kt.Rune == ',' {
+			tv.LSPSignatureHelp()
+		}
 		if kt.Rune == '}' || kt.Rune == ')' || kt.Rune == ']' {
 			cp := tv.CursorPos
 			np := cp
@@ -4538,13 +6090,32 @@ func (tv *TextView) MouseEvent(me *mouse.Event) {
 	if tv.Buf == nil || tv.Buf.NumLines() == 0 {
 		return
 	}
+	if me.Button == mouse.Left && me.Action == mouse.Press {
+		tv.minimapDrag = tv.InMinimap(me.Where)
+		if tv.minimapDrag {
+			tv.MinimapScrollTo(me.Where, false)
+			return
+		}
+	} else if me.Action == mouse.Release && tv.minimapDrag {
+		tv.minimapDrag = false
+		return
+	}
+	if tv.InMinimap(me.Where) {
+		return
+	}
 	pt := tv.PointToRelPos(me.Pos())
 	newPos := tv.PixelToCursor(pt)
 	switch me.Button {
 	case mouse.Left:
 		if me.Action == mouse.Press {
 			me.SetProcessed()
-			if _, got := tv.OpenLinkAt(newPos); got {
+			tv.colSelOn = false
+			tv.ClearMultiCursors()
+			if cln, lc := tv.ChangeLine(newPos.Ln); lc != textbuf.LineUnchanged && tv.InChangeMarker(pt) {
+				tv.ChangeMenu(cln)
+			} else if tv.InFoldMarker(pt) {
+				tv.FoldToggle(newPos.Ln)
+			} else if _, got := tv.OpenLinkAt(newPos); got {
 			} else {
 				tv.SetCursorFromMouse(pt, newPos, me.SelectMode())
 				tv.SavePosHistory(tv.CursorPos)
@@ -4627,11 +6198,23 @@ func (tv *TextView) MouseDragEvent() {
 		me := d.(*mouse.DragEvent)
 		me.SetProcessed()
 		txf := recv.Embed(KiT_TextView).(*TextView)
-		if !txf.SelectMode {
-			txf.SelectModeToggle()
+		if txf.minimapDrag {
+			txf.MinimapScrollTo(me.Where, true)
+			return
 		}
 		pt := txf.PointToRelPos(me.Pos())
 		newPos := txf.PixelToCursor(pt)
+		if me.HasAnyModifier(key.Alt) { // column selection
+			if !txf.colSelOn {
+				txf.colSelOn = true
+				txf.colSelStart = txf.CursorPos
+			}
+			txf.SelectColumn(txf.colSelStart, newPos)
+			return
+		}
+		if !txf.SelectMode {
+			txf.SelectModeToggle()
+		}
 		txf.SetCursorFromMouse(pt, newPos, mouse.SelectOne)
 	})
 }
@@ -4655,7 +6238,7 @@ func (tv *TextView) MouseFocusEvent() {
 
 // TextViewEvents sets connections between mouse and key events and actions
 func (tv *TextView) TextViewEvents() {
-	tv.HoverTooltipEvent()
+	tv.HoverEvent()
 	tv.MouseMoveEvent()
 	tv.MouseDragEvent()
 	tv.ConnectEvent(oswin.MouseEvent, gi.RegPri, func(recv, send ki.Ki, sig int64, d interface{}) {
@@ -4805,6 +6388,7 @@ func (tv *TextView) Render2D() {
 		}
 
 		tv.RenderAllLinesInBounds()
+		tv.RenderScrolls() // in case overview marks changed
 		if tv.HasFocus() && tv.IsFocusActive() {
 			// fmt.Printf("tv render: %v  start cursor\n", tv.Nm)
 			tv.StartCursor()
@@ -4833,6 +6417,9 @@ func (tv *TextView) FocusChanged2D(change gi.FocusChanges) {
 		tv.ClearFlag(int(TextViewFocusActive))
 		// tv.EditDone()
 		tv.StopCursor() // make sure no cursor
+		if win := tv.ParentWindow(); win != nil {
+			tv.RenderMultiCursors(win, false)
+		}
 		tv.UpdateSig()
 		// fmt.Printf("lost focus: %v\n", tv.Nm)
 	case gi.FocusGot:
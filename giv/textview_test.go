@@ -0,0 +1,257 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"testing"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/textbuf"
+	"github.com/goki/pi/lex"
+)
+
+// foldTestText has a brace region that can be folded at line 0, hiding
+// lines 1-2, and one at line 5, hiding line 6
+const foldTestText = "func f() {\n\ta := 1\n\tb := 2\n}\nxxxxyyyy\nfunc g() {\n\tc := 3\n}"
+
+// newFoldTestView returns a TextView on a buffer with given text, with
+// renders as if each line were one display line of height 10, except for
+// the given lines, which are wrapped after their first 4 runes -- Offs are
+// not set, as the positions of the cursor need fonts
+func newFoldTestView(t *testing.T, txt string, wrapped ...int) *TextView {
+	t.Helper()
+	tb := newTestTextBuf()
+	tb.SetText([]byte(txt))
+	tv := &TextView{}
+	tv.InitName(tv, "text-view")
+	tv.SetBuf(tb)
+	tv.NLines = tb.NumLines() // normally set by layout
+	tv.LineHeight = 10
+	tv.Renders = make([]gi.TextRender, tv.NLines)
+	for ln := range tv.Renders {
+		txt := tb.Line(ln)
+		spans := [][]rune{txt}
+		for _, wl := range wrapped {
+			if wl == ln {
+				spans = [][]rune{txt[:4], txt[4:]}
+			}
+		}
+		for _, sp := range spans {
+			tv.Renders[ln].Spans = append(tv.Renders[ln].Spans, gi.SpanRender{Text: sp, Render: make([]gi.RuneRender, len(sp))})
+		}
+		tv.Renders[ln].Size.Y = 10 * float32(len(spans))
+	}
+	return tv
+}
+
+// foldTestHidden returns the hidden lines of the view
+func foldTestHidden(tv *TextView) []int {
+	var hid []int
+	for ln := 0; ln < tv.NLines; ln++ {
+		if tv.IsLineHidden(ln) {
+			hid = append(hid, ln)
+		}
+	}
+	return hid
+}
+
+func TestTextViewFoldCursor(t *testing.T) {
+	tv := newFoldTestView(t, foldTestText)
+	if !tv.FoldAt(0) || !tv.FoldAt(6) { // inside region at 5
+		t.Fatalf("no regions to fold: %v", tv.FoldRegions())
+	}
+	if got := foldTestHidden(tv); !intsEqual(got, []int{1, 2, 6}) {
+		t.Fatalf("hidden: %v", got)
+	}
+	if tv.WrappedLines(1) != 0 || tv.WrappedLines(3) != 1 {
+		t.Errorf("wrapped lines of hidden line: %d", tv.WrappedLines(1))
+	}
+	tests := []struct {
+		name string
+		st   lex.Pos
+		move func()
+		exp  lex.Pos
+	}{
+		{"down", lex.Pos{Ln: 0, Ch: 1}, func() { tv.CursorDown(1) }, lex.Pos{Ln: 3, Ch: 1}},
+		{"up", lex.Pos{Ln: 3, Ch: 0}, func() { tv.CursorUp(1) }, lex.Pos{Ln: 0, Ch: 0}},
+		{"down to end", lex.Pos{Ln: 4, Ch: 0}, func() { tv.CursorDown(3) }, lex.Pos{Ln: 7, Ch: 0}},
+		{"forward", lex.Pos{Ln: 0, Ch: 10}, func() { tv.CursorForward(1) }, lex.Pos{Ln: 3, Ch: 0}},
+		{"backward", lex.Pos{Ln: 3, Ch: 0}, func() { tv.CursorBackward(1) }, lex.Pos{Ln: 0, Ch: 10}},
+		{"forward word", lex.Pos{Ln: 5, Ch: 10}, func() { tv.CursorForwardWord(1) }, lex.Pos{Ln: 7, Ch: 0}},
+		{"backward word", lex.Pos{Ln: 7, Ch: 0}, func() { tv.CursorBackwardWord(1) }, lex.Pos{Ln: 5, Ch: 10}},
+	}
+	for _, tt := range tests {
+		tv.CursorPos = tt.st
+		tv.CursorCol = tt.st.Ch
+		tt.move()
+		if tv.CursorPos != tt.exp {
+			t.Errorf("%s: cursor at %v, expected %v", tt.name, tv.CursorPos, tt.exp)
+		}
+	}
+	if len(tv.Folds) != 2 {
+		t.Errorf("moving unfolded: %v", tv.Folds)
+	}
+
+	// setting the cursor in a folded region unfolds it
+	tv.SetCursor(lex.Pos{Ln: 2})
+	if tv.IsFolded(0) || !tv.IsFolded(5) || tv.CursorPos.Ln != 2 {
+		t.Errorf("set cursor in fold: %v %v", tv.Folds, tv.CursorPos)
+	}
+	// folding the region of the cursor moves it to the end of the header
+	tv.FoldAt(2)
+	if tv.CursorPos != (lex.Pos{Ln: 0, Ch: 10}) {
+		t.Errorf("cursor after folding: %v", tv.CursorPos)
+	}
+	tv.UnfoldAll()
+	if tv.IsLineHidden(1) || tv.IsLineHidden(6) {
+		t.Errorf("lines hidden after unfold all: %v", foldTestHidden(tv))
+	}
+}
+
+func TestTextViewFoldAdjust(t *testing.T) {
+	tv := newFoldTestView(t, foldTestText)
+	tb := tv.Buf
+	tv.FoldAt(0)
+	tv.FoldAt(5)
+	edit := func(name string, ed func(), exp [][2]int) {
+		t.Helper()
+		ed()
+		tv.NLines = tb.NumLines() // normally set by layout
+		var got [][2]int
+		for _, f := range tv.Folds {
+			got = append(got, [2]int{f.Start.Ln, f.End.Ln})
+		}
+		if len(got) != len(exp) {
+			t.Errorf("%s: folds %v, expected %v", name, got, exp)
+			return
+		}
+		for i := range got {
+			if got[i] != exp[i] {
+				t.Errorf("%s: folds %v, expected %v", name, got, exp)
+				return
+			}
+		}
+	}
+	edit("insert before", func() {
+		tb.InsertText(lex.Pos{}, []byte("// top\n"), EditSignal)
+	}, [][2]int{{1, 3}, {6, 7}})
+	if got := foldTestHidden(tv); !intsEqual(got, []int{2, 3, 7}) {
+		t.Errorf("hidden after insert: %v", got)
+	}
+	edit("insert within", func() {
+		tb.InsertText(lex.Pos{Ln: 3}, []byte("\td := 4\n"), EditSignal)
+	}, [][2]int{{1, 4}, {7, 8}})
+	edit("insert in header", func() {
+		tb.InsertText(lex.Pos{Ln: 1, Ch: 0}, []byte("x"), EditSignal)
+	}, [][2]int{{1, 4}, {7, 8}})
+	edit("delete before", func() {
+		tb.DeleteText(lex.Pos{}, lex.Pos{Ln: 1}, EditSignal)
+	}, [][2]int{{0, 3}, {6, 7}})
+	edit("delete within", func() {
+		tb.DeleteText(lex.Pos{Ln: 1}, lex.Pos{Ln: 3}, EditSignal)
+	}, [][2]int{{0, 1}, {4, 5}})
+	edit("delete all hidden", func() {
+		tb.DeleteText(lex.Pos{Ln: 4, Ch: 10}, lex.Pos{Ln: 5, Ch: 6}, EditSignal)
+	}, [][2]int{{0, 1}})
+	if got := foldTestHidden(tv); !intsEqual(got, []int{1}) || tv.NLines != 6 {
+		t.Errorf("hidden after deletes: %v of %d", got, tv.NLines)
+	}
+	if tb.Undo() == nil || len(tv.Folds) != 1 {
+		t.Errorf("undo: %v", tv.Folds)
+	}
+}
+
+func TestTextViewFoldWrapped(t *testing.T) {
+	tv := newFoldTestView(t, foldTestText+"\nzzzzwwww", 1, 4, 8)
+	tv.FoldAt(0)
+	tv.Offs = make([]float32, tv.NLines)
+	tv.LayoutOffs()
+	exp := []float32{0, 10, 10, 10, 20, 40, 50, 60, 70}
+	for ln, off := range tv.Offs {
+		if off != exp[ln] {
+			t.Errorf("offsets: %v, expected %v", tv.Offs, exp)
+			break
+		}
+	}
+	tv.Offs = nil // positions of the cursor need fonts
+	if tv.LineDispHeight(1) != 0 || tv.LineDispHeight(4) != 20 {
+		t.Errorf("line heights: %v %v", tv.LineDispHeight(1), tv.LineDispHeight(4))
+	}
+	tests := []struct {
+		name string
+		st   lex.Pos
+		move func()
+		exp  lex.Pos
+	}{
+		{"down into wrapped", lex.Pos{Ln: 3, Ch: 1}, func() { tv.CursorDown(1) }, lex.Pos{Ln: 4, Ch: 1}},
+		{"up out of wrapped", lex.Pos{Ln: 4, Ch: 1}, func() { tv.CursorUp(1) }, lex.Pos{Ln: 3, Ch: 1}},
+		{"up over wrapped fold", lex.Pos{Ln: 3, Ch: 0}, func() { tv.CursorUp(1) }, lex.Pos{Ln: 0, Ch: 0}},
+		{"down over wrapped fold", lex.Pos{Ln: 0, Ch: 2}, func() { tv.CursorDown(1) }, lex.Pos{Ln: 3, Ch: 1}},
+		{"up into end of wrapped", lex.Pos{Ln: 5, Ch: 2}, func() { tv.CursorUp(1) }, lex.Pos{Ln: 4, Ch: 6}},
+	}
+	for _, tt := range tests {
+		tv.CursorPos = tt.st
+		tv.CursorCol = tt.st.Ch
+		tt.move()
+		if tv.CursorPos != tt.exp {
+			t.Errorf("%s: cursor at %v, expected %v", tt.name, tv.CursorPos, tt.exp)
+		}
+	}
+
+	// a wrapped line as the last visible line before a fold at the end
+	tv.FoldAt(5)
+	tv.CursorPos, tv.CursorCol = lex.Pos{Ln: 4, Ch: 5}, 1
+	tv.CursorDown(1)
+	if tv.CursorPos != (lex.Pos{Ln: 5, Ch: 1}) {
+		t.Errorf("down from wrapped to fold header: %v", tv.CursorPos)
+	}
+	tv.CursorDown(1)
+	if tv.CursorPos != (lex.Pos{Ln: 7, Ch: 1}) {
+		t.Errorf("down over fold: %v", tv.CursorPos)
+	}
+	tv.CursorDown(1)
+	if tv.CursorPos != (lex.Pos{Ln: 8, Ch: 1}) {
+		t.Errorf("down into wrapped: %v", tv.CursorPos)
+	}
+	if reg := tv.Folds[0]; reg != (textbuf.Region{Start: lex.Pos{Ln: 0, Ch: 9}, End: lex.Pos{Ln: 2, Ch: 7}}) {
+		t.Errorf("fold region: %v", reg)
+	}
+}
+
+func TestTextViewKeyMacroPrompt(t *testing.T) {
+	w := &gi.Window{}
+	w.InitName(w, "macro-win")
+	w.EventMgr.Master = w
+	w.Viewport = gi.NewViewport2D(100, 100)
+	w.Viewport.InitName(w.Viewport, "macro-vp")
+	w.Viewport.Win = w
+	w.MasterVLay = gi.AddNewLayout(w.Viewport, "main-vlay", gi.LayoutVert)
+	w.SetFlag(int(gi.WinFlagGotFocus))
+	tb := newTestTextBuf()
+	tb.SetText([]byte("a\nb\nc\n\n"))
+	tv := AddNewTextView(w.MasterVLay, "text-view")
+	tv.SetBuf(tb)
+	tv.Viewport = w.Viewport  // normally set by Init2D
+	tv.NLines = tb.NumLines() // normally set by layout
+	tv.ConnectEvents2D()
+	w.EventMgr.SetFocus(tv)
+
+	defer func(km *gi.KeyMap, kmn gi.KeyMapName) { gi.ActiveKeyMap, gi.ActiveKeyMapName = km, kmn }(gi.ActiveKeyMap, gi.ActiveKeyMapName)
+	gi.SetActiveKeyMapName(gi.DefaultKeyMap)
+	defer func(kms gi.KeyMacros) { gi.AvailKeyMacros = kms }(gi.AvailKeyMacros)
+	gi.AvailKeyMacros = nil
+	gi.AvailKeyMacros.Add(&gi.KeyMacro{Name: gi.LastKeyMacroName, Steps: []gi.KeyMacroStep{{Chord: "x"}, {Chord: "DownArrow"}, {Chord: "Control+A"}}})
+	w.KeyMacroPlayAccept(gi.LastKeyMacroName, 0, "copy") // to end
+	if got := string(tb.LinesToBytesCopy()); got != "a\nb\nc\n\n" {
+		t.Errorf("macro played before the prompt closed: %q", got)
+	}
+	if gi.AvailKeyMacros.MacroByName("copy") == nil {
+		t.Errorf("macro not saved under new name")
+	}
+	gi.RunPendingUIFuncs()
+	if got := string(tb.LinesToBytesCopy()); got != "xa\nxb\nxc\n\n" {
+		t.Errorf("text after playing to end: %q", got)
+	}
+}
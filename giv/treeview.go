@@ -269,6 +269,25 @@ func (tv *TreeView) Label() string {
 	return tv.SrcNode.Name()
 }
 
+// AccessInfo returns the accessibility info for the tree item, with its
+// Label as name, its level in the tree and its expanded state
+func (tv *TreeView) AccessInfo() gi.AccessInfo {
+	ai := tv.PartsWidgetBase.AccessInfo()
+	ai.Role = gi.AccessTreeItem
+	if tv.SrcNode != nil {
+		ai.Name = gi.AccessPlainText(tv.Label())
+	}
+	ai.Level = 1
+	if tv.RootView != nil && tv.RootView != tv {
+		ai.Level += tv.ParentLevel(tv.RootView.This())
+	}
+	if tv.HasChildren() {
+		ai.SetState(true, gi.AccessExpandable)
+		ai.SetState(!tv.IsClosed(), gi.AccessExpanded)
+	}
+	return ai
+}
+
 // UpdateInactive updates the Inactive state based on SrcNode -- returns true if
 // inactive.  The inactivity of individual nodes only affects display properties
 // typically, and not overall functional behavior, which is controlled by
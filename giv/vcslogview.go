@@ -149,7 +149,7 @@ func (lv *VCSLogView) ConfigToolBar() {
 		tb.AddAction(gi.ActOpts{Label: "Diff", Icon: "file-sheet", Tooltip: "Show the diffs between two revisions -- if blank, A is current HEAD, and B is current working copy"}, lv.This(),
 			func(recv, send ki.Ki, sig int64, data interface{}) {
 				lvv := recv.Embed(KiT_VCSLogView).(*VCSLogView)
-				DiffViewDialogFromRevs(lvv.ViewportSafe(), lvv.Repo, lvv.File, nil, lvv.RevA, lvv.RevB)
+				DiffViewDialogFromRevsTask(lvv.ViewportSafe(), lvv.Repo, lvv.File, nil, lvv.RevA, lvv.RevB)
 			})
 
 		cba.ButtonSig.Connect(lv.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
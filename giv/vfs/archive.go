@@ -0,0 +1,154 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vfs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ArchiveFS is the read-only file system of the files in a zip or tar
+// archive, for browsing them, and extracting them (see Extract).  The files
+// of zip archives are read as they are opened, and those of tar archives,
+// which can only be read in order, when the archive is opened.
+type ArchiveFS struct {
+	*MemFS
+	closer io.Closer
+}
+
+// OpenZip opens the zip archive at given path in the OS -- Close closes it
+func OpenZip(path string) (*ArchiveFS, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	a := newZipFS(&zr.Reader)
+	a.closer = zr
+	return a, nil
+}
+
+// NewZipFS returns the file system of the zip archive read from r, of
+// given size
+func NewZipFS(r io.ReaderAt, size int64) (*ArchiveFS, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return newZipFS(zr), nil
+}
+
+func newZipFS(zr *zip.Reader) *ArchiveFS {
+	a := &ArchiveFS{MemFS: NewMemFS()}
+	for _, zf := range zr.File {
+		zf := zf
+		fi := zf.FileInfo()
+		mf := &memFile{mode: fi.Mode(), mod: fi.ModTime()}
+		if !fi.IsDir() {
+			mf.size = fi.Size()
+			mf.open = zf.Open
+		}
+		a.add(zf.Name, mf)
+	}
+	a.readOnly = true
+	return a
+}
+
+// OpenTar opens the tar archive at given path in the OS, which can be
+// compressed with gzip
+func OpenTar(path string) (*ArchiveFS, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewTarFS(f)
+}
+
+// NewTarFS returns the file system of the tar archive read from r, which
+// can be compressed with gzip
+func NewTarFS(r io.Reader) (*ArchiveFS, error) {
+	br := bufio.NewReader(r)
+	if hd, _ := br.Peek(2); len(hd) == 2 && hd[0] == 0x1f && hd[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		r = gr
+	} else {
+		r = br
+	}
+	a := &ArchiveFS{MemFS: NewMemFS()}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		fi := hdr.FileInfo()
+		mf := &memFile{mode: fi.Mode(), mod: fi.ModTime()}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+		case tar.TypeReg, tar.TypeRegA:
+			mf.data, err = ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			mf.size = int64(len(mf.data))
+		default: // links, devices etc are not files here
+			continue
+		}
+		a.add(hdr.Name, mf)
+	}
+	a.readOnly = true
+	return a, nil
+}
+
+// IsArchive returns true if the file at given path is a zip or tar
+// archive, by its extension, that can be opened with OpenArchive
+func IsArchive(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".zip", ".jar", ".tar", ".tgz":
+		return true
+	case ".gz":
+		return strings.HasSuffix(strings.ToLower(path), ".tar.gz")
+	}
+	return false
+}
+
+// OpenArchive opens the zip or tar archive at given path in the OS, by its
+// extension -- see IsArchive
+func OpenArchive(path string) (*ArchiveFS, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".zip", ".jar":
+		return OpenZip(path)
+	}
+	return OpenTar(path)
+}
+
+// Extract extracts the files of the archive into directory dir of given
+// file system, which is created if it does not exist
+func (a *ArchiveFS) Extract(dfs FS, dir string) error {
+	return CopyAll(dfs, dir, a, Clean(""))
+}
+
+// Close closes the archive, if it is open
+func (a *ArchiveFS) Close() error {
+	if a.closer == nil {
+		return nil
+	}
+	return a.closer.Close()
+}
@@ -0,0 +1,272 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vfs
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemFS is a file system in memory, e.g., for tests, or for files that
+// are made by the program -- it is safe for concurrent use
+type MemFS struct {
+	mu       sync.RWMutex
+	files    map[string]*memFile
+	readOnly bool
+}
+
+// memFile is a file or directory in a MemFS
+type memFile struct {
+	name string
+	data []byte
+	open func() (io.ReadCloser, error) // if set, opens the data, which is not in memory
+	size int64
+	mode os.FileMode
+	mod  time.Time
+}
+
+// NewMemFS returns a new, empty, in-memory file system
+func NewMemFS() *MemFS {
+	m := &MemFS{files: make(map[string]*memFile)}
+	m.files[Clean("")] = &memFile{name: string(filepath.Separator), mode: os.ModeDir | 0755, mod: time.Now()}
+	return m
+}
+
+// memInfo is the os.FileInfo of a memFile
+type memInfo memFile
+
+func (fi *memInfo) Name() string       { return fi.name }
+func (fi *memInfo) Size() int64        { return fi.size }
+func (fi *memInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *memInfo) ModTime() time.Time { return fi.mod }
+func (fi *memInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *memInfo) Sys() interface{}   { return nil }
+
+func (mf *memFile) info() os.FileInfo {
+	fi := memInfo(*mf)
+	return &fi
+}
+
+// lookup returns the file at given path, which must be clean, or a
+// PathError for given op
+func (m *MemFS) lookup(op, path string) (*memFile, error) {
+	mf, has := m.files[path]
+	if !has {
+		return nil, &os.PathError{Op: op, Path: path, Err: os.ErrNotExist}
+	}
+	return mf, nil
+}
+
+// checkDir returns an error for given op if the directory that given path
+// is in does not exist
+func (m *MemFS) checkDir(op, path string) error {
+	dir, err := m.lookup(op, filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	if !dir.mode.IsDir() {
+		return &os.PathError{Op: op, Path: path, Err: os.ErrNotExist}
+	}
+	return nil
+}
+
+func (m *MemFS) Open(name string) (io.ReadCloser, error) {
+	path := Clean(name)
+	m.mu.RLock()
+	mf, err := m.lookup("open", path)
+	m.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if mf.mode.IsDir() {
+		return nil, &os.PathError{Op: "read", Path: path, Err: errIsDir}
+	}
+	if mf.open != nil {
+		return mf.open()
+	}
+	return ioutil.NopCloser(bytes.NewReader(mf.data)), nil
+}
+
+// memWriter writes the contents of a file in a MemFS when it is closed
+type memWriter struct {
+	bytes.Buffer
+	m    *MemFS
+	path string
+}
+
+func (mw *memWriter) Close() error {
+	mw.m.mu.Lock()
+	defer mw.m.mu.Unlock()
+	mf, has := mw.m.files[mw.path]
+	if !has { // removed meanwhile
+		return &os.PathError{Op: "close", Path: mw.path, Err: os.ErrNotExist}
+	}
+	mf.data = mw.Bytes()
+	mf.size = int64(len(mf.data))
+	mf.mod = time.Now()
+	return nil
+}
+
+func (m *MemFS) Create(name string, perm os.FileMode) (io.WriteCloser, error) {
+	path := Clean(name)
+	if m.readOnly {
+		return nil, &os.PathError{Op: "open", Path: path, Err: ErrReadOnly}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkDir("open", path); err != nil {
+		return nil, err
+	}
+	if mf, has := m.files[path]; has {
+		if mf.mode.IsDir() {
+			return nil, &os.PathError{Op: "open", Path: path, Err: errIsDir}
+		}
+		mf.data, mf.open, mf.size, mf.mod = nil, nil, 0, time.Now()
+	} else {
+		m.files[path] = &memFile{name: filepath.Base(path), mode: perm.Perm(), mod: time.Now()}
+	}
+	return &memWriter{m: m, path: path}, nil
+}
+
+func (m *MemFS) Stat(name string) (os.FileInfo, error) {
+	path := Clean(name)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	mf, err := m.lookup("stat", path)
+	if err != nil {
+		return nil, err
+	}
+	return mf.info(), nil
+}
+
+func (m *MemFS) ReadDir(name string) ([]os.FileInfo, error) {
+	path := Clean(name)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	dir, err := m.lookup("open", path)
+	if err != nil {
+		return nil, err
+	}
+	if !dir.mode.IsDir() {
+		return nil, &os.PathError{Op: "readdirent", Path: path, Err: errNotDir}
+	}
+	var fis []os.FileInfo
+	for fp, mf := range m.files {
+		if fp != path && filepath.Dir(fp) == path {
+			fis = append(fis, mf.info())
+		}
+	}
+	sortInfos(fis)
+	return fis, nil
+}
+
+func (m *MemFS) Mkdir(name string, perm os.FileMode) error {
+	path := Clean(name)
+	if m.readOnly {
+		return &os.PathError{Op: "mkdir", Path: path, Err: ErrReadOnly}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkDir("mkdir", path); err != nil {
+		return err
+	}
+	if _, has := m.files[path]; has {
+		return &os.PathError{Op: "mkdir", Path: path, Err: os.ErrExist}
+	}
+	m.files[path] = &memFile{name: filepath.Base(path), mode: os.ModeDir | perm.Perm(), mod: time.Now()}
+	return nil
+}
+
+// hasKids returns true if the directory at given path has files in it
+func (m *MemFS) hasKids(path string) bool {
+	for fp := range m.files {
+		if fp != path && filepath.Dir(fp) == path {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemFS) Remove(name string) error {
+	path := Clean(name)
+	if m.readOnly {
+		return &os.PathError{Op: "remove", Path: path, Err: ErrReadOnly}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mf, err := m.lookup("remove", path)
+	if err != nil {
+		return err
+	}
+	if path == Clean("") || (mf.mode.IsDir() && m.hasKids(path)) {
+		return &os.PathError{Op: "remove", Path: path, Err: errNotEmpty}
+	}
+	delete(m.files, path)
+	return nil
+}
+
+func (m *MemFS) Rename(oldname, newname string) error {
+	opath, npath := Clean(oldname), Clean(newname)
+	if m.readOnly {
+		return &os.LinkError{Op: "rename", Old: opath, New: npath, Err: ErrReadOnly}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mf, has := m.files[opath]
+	if !has {
+		return &os.LinkError{Op: "rename", Old: opath, New: npath, Err: os.ErrNotExist}
+	}
+	if opath == npath {
+		return nil
+	}
+	sep := string(filepath.Separator)
+	if opath == Clean("") || strings.HasPrefix(npath, opath+sep) {
+		return &os.LinkError{Op: "rename", Old: opath, New: npath, Err: os.ErrInvalid}
+	}
+	if err := m.checkDir("rename", npath); err != nil {
+		return err
+	}
+	if nf, has := m.files[npath]; has && nf.mode.IsDir() {
+		if !mf.mode.IsDir() || m.hasKids(npath) {
+			return &os.LinkError{Op: "rename", Old: opath, New: npath, Err: os.ErrExist}
+		}
+	}
+	moved := make(map[string]*memFile)
+	for fp, f := range m.files {
+		if fp == opath || strings.HasPrefix(fp, opath+sep) {
+			moved[npath+fp[len(opath):]] = f
+			delete(m.files, fp)
+		}
+	}
+	for fp, f := range moved {
+		m.files[fp] = f
+	}
+	mf.name = filepath.Base(npath)
+	return nil
+}
+
+// add adds a file at given path, with the directories it is in, for
+// building the file system without checks, e.g., from an archive
+func (m *MemFS) add(path string, mf *memFile) {
+	path = Clean(path)
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if _, has := m.files[dir]; has {
+			break
+		}
+		m.files[dir] = &memFile{name: filepath.Base(dir), mode: os.ModeDir | 0755, mod: mf.mod}
+	}
+	if old, has := m.files[path]; has && old.mode.IsDir() && mf.mode.IsDir() {
+		old.mode, old.mod = mf.mode, mf.mod // implicit dir already added
+		return
+	}
+	mf.name = filepath.Base(path)
+	m.files[path] = mf
+}
@@ -0,0 +1,260 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vfs
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// OverlayFS is a file system with a writable Upper layer over a Lower one,
+// which is not changed: files are read from Upper if they are there and
+// otherwise from Lower, and all changes are made in Upper, copying files and
+// directories up from Lower as needed.  Files of Lower that are removed are
+// hidden, in memory, e.g., to edit the files of an archive, in memory or in
+// a directory, with the archive as Lower.
+type OverlayFS struct {
+	Upper FS
+	Lower FS
+	mu    sync.Mutex
+	hid   map[string]bool
+}
+
+// NewOverlayFS returns a new overlay file system of upper over lower
+func NewOverlayFS(upper, lower FS) *OverlayFS {
+	return &OverlayFS{Upper: OrOS(upper), Lower: OrOS(lower), hid: make(map[string]bool)}
+}
+
+// key returns the path used for hidden files
+func (o *OverlayFS) key(name string) string {
+	if IsOS(o.Lower) {
+		abs, _ := filepath.Abs(name)
+		return abs
+	}
+	return Clean(name)
+}
+
+// hidden returns true if the file of Lower at given path, or a directory
+// it is in, has been removed
+func (o *OverlayFS) hidden(name string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for k := o.key(name); ; k = filepath.Dir(k) {
+		if o.hid[k] {
+			return true
+		}
+		if dk := filepath.Dir(k); dk == k {
+			return false
+		}
+	}
+}
+
+// setHidden sets whether the file of Lower at given path is hidden
+func (o *OverlayFS) setHidden(name string, hide bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if hide {
+		o.hid[o.key(name)] = true
+	} else {
+		delete(o.hid, o.key(name))
+	}
+}
+
+// lower returns the info of the file in Lower at given path, if it is not
+// hidden
+func (o *OverlayFS) lower(name string) (os.FileInfo, error) {
+	if o.hidden(name) {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	return o.Lower.Stat(name)
+}
+
+func (o *OverlayFS) Open(name string) (io.ReadCloser, error) {
+	if f, err := o.Upper.Open(name); err == nil || !os.IsNotExist(err) {
+		return f, err
+	}
+	if _, err := o.lower(name); err != nil {
+		return nil, err
+	}
+	return o.Lower.Open(name)
+}
+
+func (o *OverlayFS) Stat(name string) (os.FileInfo, error) {
+	if fi, err := o.Upper.Stat(name); err == nil || !os.IsNotExist(err) {
+		return fi, err
+	}
+	return o.lower(name)
+}
+
+func (o *OverlayFS) ReadDir(name string) ([]os.FileInfo, error) {
+	ufis, uerr := o.Upper.ReadDir(name)
+	if uerr != nil && !os.IsNotExist(uerr) {
+		return nil, uerr
+	}
+	var lfis []os.FileInfo
+	lerr := uerr
+	if fi, err := o.lower(name); err == nil && fi.IsDir() {
+		lfis, lerr = o.Lower.ReadDir(name)
+	}
+	if uerr != nil && lerr != nil {
+		return nil, uerr
+	}
+	has := make(map[string]bool, len(ufis))
+	fis := ufis
+	for _, fi := range ufis {
+		has[fi.Name()] = true
+	}
+	for _, fi := range lfis {
+		if !has[fi.Name()] && !o.hidden(filepath.Join(name, fi.Name())) {
+			fis = append(fis, fi)
+		}
+	}
+	sortInfos(fis)
+	return fis, nil
+}
+
+// copyUpDir makes the directory at given path in Upper, and those it is
+// in, if they are directories of the overlay
+func (o *OverlayFS) copyUpDir(dir string) error {
+	if fi, err := o.Upper.Stat(dir); err == nil && fi.IsDir() {
+		return nil
+	}
+	fi, err := o.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return &os.PathError{Op: "mkdir", Path: dir, Err: errNotDir}
+	}
+	if pdir := filepath.Dir(dir); pdir != dir {
+		if err := o.copyUpDir(pdir); err != nil {
+			return err
+		}
+	}
+	err = o.Upper.Mkdir(dir, fi.Mode().Perm())
+	if err != nil && os.IsExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (o *OverlayFS) Create(name string, perm os.FileMode) (io.WriteCloser, error) {
+	if err := o.copyUpDir(filepath.Dir(name)); err != nil {
+		return nil, err
+	}
+	if fi, err := o.lower(name); err == nil {
+		if fi.IsDir() {
+			return nil, &os.PathError{Op: "open", Path: name, Err: errIsDir}
+		}
+		perm = fi.Mode().Perm()
+	}
+	f, err := o.Upper.Create(name, perm)
+	if err == nil {
+		o.setHidden(name, false)
+	}
+	return f, err
+}
+
+func (o *OverlayFS) Mkdir(name string, perm os.FileMode) error {
+	if _, err := o.Stat(name); err == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+	if err := o.copyUpDir(filepath.Dir(name)); err != nil {
+		return err
+	}
+	if err := o.Upper.Mkdir(name, perm); err != nil {
+		return err
+	}
+	if o.hidden(name) { // the removed directory stays removed, even if Lower has it
+		if lfis, err := o.Lower.ReadDir(name); err == nil {
+			for _, fi := range lfis {
+				o.setHidden(filepath.Join(name, fi.Name()), true)
+			}
+		}
+		o.setHidden(name, false)
+	}
+	return nil
+}
+
+func (o *OverlayFS) Remove(name string) error {
+	fi, err := o.Stat(name)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		if fis, err := o.ReadDir(name); err == nil && len(fis) > 0 {
+			return &os.PathError{Op: "remove", Path: name, Err: errNotEmpty}
+		}
+	}
+	if err := o.Upper.Remove(name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if _, err := o.lower(name); err == nil {
+		o.setHidden(name, true)
+	}
+	return nil
+}
+
+func (o *OverlayFS) Rename(oldname, newname string) error {
+	fi, err := o.Stat(oldname)
+	if err != nil {
+		return err
+	}
+	if err := o.copyUpDir(filepath.Dir(newname)); err != nil {
+		return err
+	}
+	if _, err := o.lower(oldname); err == nil { // copy up what is in Lower
+		if fi.IsDir() {
+			err = o.copyUpAll(oldname)
+		} else if _, uerr := o.Upper.Stat(oldname); os.IsNotExist(uerr) {
+			err = CopyFile(o.Upper, oldname, o.Lower, oldname, fi.Mode().Perm())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := o.Upper.Rename(oldname, newname); err != nil {
+		return err
+	}
+	if _, err := o.lower(oldname); err == nil {
+		o.setHidden(oldname, true)
+	}
+	if o.hidden(newname) {
+		o.setHidden(newname, false)
+		o.hideUnder(newname)
+	}
+	return nil
+}
+
+// copyUpAll copies the directory at given path, and all in it, to Upper
+func (o *OverlayFS) copyUpAll(dir string) error {
+	return Walk(o, dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return o.copyUpDir(path)
+		}
+		if _, err := o.Upper.Stat(path); err == nil {
+			return nil
+		}
+		return CopyFile(o.Upper, path, o.Lower, path, fi.Mode().Perm())
+	})
+}
+
+// hideUnder hides the files of Lower that are under given directory, which
+// has been replaced
+func (o *OverlayFS) hideUnder(dir string) {
+	k := o.key(dir) + string(filepath.Separator)
+	Walk(o.Lower, dir, func(path string, fi os.FileInfo, err error) error {
+		if err == nil && strings.HasPrefix(o.key(path), k) {
+			o.setHidden(path, true)
+		}
+		return nil
+	})
+}
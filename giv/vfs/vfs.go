@@ -0,0 +1,331 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package vfs provides a file system interface, FS, through which the file
+// views, trees and text buffers of GoGi access files, so that they can show
+// and edit files that are not in the OS file system: files in memory
+// (MemFS), in zip and tar archives (ArchiveFS), and in a writable layer over
+// another file system (OverlayFS).
+//
+// Paths are in the form of the OS (see path/filepath), and are absolute in
+// the file systems other than the OS, with the root at the separator, e.g.,
+// "/dir/file.go" -- see Abs.
+package vfs
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FS is a file system
+type FS interface {
+	// Open opens the named file for reading
+	Open(name string) (io.ReadCloser, error)
+
+	// Create creates the named file, or truncates it if it exists, for
+	// writing, with given permissions if it is created -- the directory it
+	// is in must exist
+	Create(name string, perm os.FileMode) (io.WriteCloser, error)
+
+	// Stat returns the info of the named file, following symbolic links
+	Stat(name string) (os.FileInfo, error)
+
+	// ReadDir returns the infos of the files in the named directory,
+	// sorted by name
+	ReadDir(name string) ([]os.FileInfo, error)
+
+	// Mkdir creates the named directory -- the directory it is in must
+	// exist
+	Mkdir(name string, perm os.FileMode) error
+
+	// Remove removes the named file or empty directory
+	Remove(name string) error
+
+	// Rename renames (moves) a file or directory, replacing newname if it
+	// is a file
+	Rename(oldname, newname string) error
+}
+
+// ErrReadOnly is returned when writing to a read-only file system
+var ErrReadOnly = errors.New("vfs: read-only file system")
+
+var (
+	errNotEmpty = errors.New("directory not empty")
+	errIsDir    = errors.New("is a directory")
+	errNotDir   = errors.New("not a directory")
+)
+
+/////////////////////////////////////////////////////////////////////////////
+//   OS
+
+// OSFS is the file system of the OS, using the os package
+type OSFS struct{}
+
+// OS is the file system of the OS
+var OS FS = OSFS{}
+
+func (OSFS) Open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+func (OSFS) Create(name string, perm os.FileMode) (io.WriteCloser, error) {
+	return os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, perm)
+}
+
+func (OSFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (OSFS) ReadDir(name string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(name)
+}
+
+func (OSFS) Mkdir(name string, perm os.FileMode) error {
+	return os.Mkdir(name, perm)
+}
+
+func (OSFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (OSFS) Rename(oldname, newname string) error {
+	return os.Rename(oldname, newname)
+}
+
+// IsOS returns true if given file system is that of the OS -- nil is taken
+// to be the OS throughout
+func IsOS(fsys FS) bool {
+	if fsys == nil {
+		return true
+	}
+	_, is := fsys.(OSFS)
+	return is
+}
+
+// OrOS returns given file system, or OS if it is nil
+func OrOS(fsys FS) FS {
+	if fsys == nil {
+		return OS
+	}
+	return fsys
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//   Utilities
+
+// Abs returns the absolute form of given path in given file system -- for
+// the OS, as filepath.Abs, and otherwise the cleaned path from the root
+func Abs(fsys FS, name string) (string, error) {
+	if IsOS(fsys) {
+		return filepath.Abs(name)
+	}
+	return Clean(name), nil
+}
+
+// Clean returns the cleaned, absolute form of given path in a file system
+// other than the OS, with the root at the separator
+func Clean(name string) string {
+	return filepath.Clean(string(filepath.Separator) + name)
+}
+
+// EvalSymlinks returns the path of given file after following any symbolic
+// links, which only the OS has, or an error if it does not exist
+func EvalSymlinks(fsys FS, name string) (string, error) {
+	if IsOS(fsys) {
+		return filepath.EvalSymlinks(name)
+	}
+	if _, err := fsys.Stat(name); err != nil {
+		return name, err
+	}
+	return Clean(name), nil
+}
+
+// ReadFile reads the named file
+func ReadFile(fsys FS, name string) ([]byte, error) {
+	if IsOS(fsys) {
+		return ioutil.ReadFile(name)
+	}
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+// WriteFile writes data to the named file, creating it with given
+// permissions if it does not exist
+func WriteFile(fsys FS, name string, data []byte, perm os.FileMode) error {
+	if IsOS(fsys) {
+		return ioutil.WriteFile(name, data, perm)
+	}
+	f, err := fsys.Create(name, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// MkdirAll creates the named directory, and the ones it is in, as needed
+func MkdirAll(fsys FS, name string, perm os.FileMode) error {
+	if IsOS(fsys) {
+		return os.MkdirAll(name, perm)
+	}
+	name = Clean(name)
+	if fi, err := fsys.Stat(name); err == nil {
+		if fi.IsDir() {
+			return nil
+		}
+		return &os.PathError{Op: "mkdir", Path: name, Err: errNotDir}
+	}
+	if dir := filepath.Dir(name); dir != name {
+		if err := MkdirAll(fsys, dir, perm); err != nil {
+			return err
+		}
+	}
+	err := fsys.Mkdir(name, perm)
+	if err != nil && os.IsExist(err) {
+		return nil
+	}
+	return err
+}
+
+// RemoveAll removes the named file, or directory and all that it contains
+func RemoveAll(fsys FS, name string) error {
+	if IsOS(fsys) {
+		return os.RemoveAll(name)
+	}
+	fi, err := fsys.Stat(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if fi.IsDir() {
+		fis, err := fsys.ReadDir(name)
+		if err != nil {
+			return err
+		}
+		for _, cfi := range fis {
+			if err := RemoveAll(fsys, filepath.Join(name, cfi.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return fsys.Remove(name)
+}
+
+// Walk walks the tree of files at root, calling walkFn for each, in the
+// manner of filepath.Walk
+func Walk(fsys FS, root string, walkFn filepath.WalkFunc) error {
+	if IsOS(fsys) {
+		return filepath.Walk(root, walkFn)
+	}
+	fi, err := fsys.Stat(root)
+	if err != nil {
+		err = walkFn(root, nil, err)
+	} else {
+		err = walk(fsys, root, fi, walkFn)
+	}
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+func walk(fsys FS, path string, fi os.FileInfo, walkFn filepath.WalkFunc) error {
+	if !fi.IsDir() {
+		return walkFn(path, fi, nil)
+	}
+	fis, err := fsys.ReadDir(path)
+	err1 := walkFn(path, fi, err)
+	if err != nil || err1 != nil {
+		return err1
+	}
+	for _, cfi := range fis {
+		err = walk(fsys, filepath.Join(path, cfi.Name()), cfi, walkFn)
+		if err != nil && (!cfi.IsDir() || err != filepath.SkipDir) {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopyFile copies the contents of file src in file system sfs to file dst
+// in file system dfs, creating it with permissions perm if it does not
+// exist.  In the OS, the copy is atomic: if it fails, dst is preserved.
+func CopyFile(dfs FS, dst string, sfs FS, src string, perm os.FileMode) error {
+	in, err := OrOS(sfs).Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if !IsOS(dfs) {
+		out, err := dfs.Create(dst, perm)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, in)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(dst), "")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, in)
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), perm); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// CopyAll copies the file or directory src in file system sfs, and all
+// that it contains, to dst in file system dfs, e.g., to extract the files
+// of an archive
+func CopyAll(dfs FS, dst string, sfs FS, src string) error {
+	sfs = OrOS(sfs)
+	return Walk(sfs, src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		dpath := filepath.Join(dst, rel)
+		if fi.IsDir() {
+			return MkdirAll(dfs, dpath, fi.Mode().Perm()|0700)
+		}
+		return CopyFile(dfs, dpath, sfs, path, fi.Mode().Perm())
+	})
+}
+
+// sortInfos sorts given file infos by name
+func sortInfos(fis []os.FileInfo) {
+	sort.Slice(fis, func(i, j int) bool {
+		return fis[i].Name() < fis[j].Name()
+	})
+}
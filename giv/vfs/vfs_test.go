@@ -0,0 +1,209 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vfs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testFiles are the files used in the tests, by path, with "/" for dirs
+var testFiles = map[string]string{
+	"a.txt":         "one",
+	"dir/":          "",
+	"dir/b.go":      "package b",
+	"dir/sub/c.txt": "three",
+}
+
+// listFS returns the files in the file system, as path: contents, with
+// directories ending in the separator
+func listFS(t *testing.T, fsys FS, root string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := Walk(fsys, root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if fi.IsDir() {
+			files[rel+"/"] = ""
+			return nil
+		}
+		b, err := ReadFile(fsys, path)
+		files[rel] = string(b)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func expectFiles(t *testing.T, what string, fsys FS, root string, exp map[string]string) {
+	t.Helper()
+	if got := listFS(t, fsys, root); !reflect.DeepEqual(got, exp) {
+		t.Errorf("%v: got: %v\nexpected: %v", what, got, exp)
+	}
+}
+
+func writeFiles(t *testing.T, fsys FS, root string, files map[string]string) {
+	t.Helper()
+	for fp, txt := range files {
+		path := filepath.Join(root, filepath.FromSlash(fp))
+		if strings.HasSuffix(fp, "/") {
+			if err := MkdirAll(fsys, path, 0755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := MkdirAll(fsys, filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteFile(fsys, path, []byte(txt), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func withDirs(files map[string]string) map[string]string {
+	all := make(map[string]string)
+	for fp, txt := range files {
+		all[fp] = txt
+		for dir := filepath.Dir(strings.TrimSuffix(fp, "/")); dir != "."; dir = filepath.Dir(dir) {
+			all[filepath.ToSlash(dir)+"/"] = ""
+		}
+	}
+	return all
+}
+
+func testFS(t *testing.T, fsys FS, root string) {
+	writeFiles(t, fsys, root, testFiles)
+	expectFiles(t, "written", fsys, root, withDirs(testFiles))
+	path := func(fp string) string {
+		return filepath.Join(root, filepath.FromSlash(fp))
+	}
+	if err := fsys.Remove(path("dir")); err == nil {
+		t.Errorf("removed non-empty dir")
+	}
+	if _, err := fsys.Create(path("none/x.txt"), 0644); !os.IsNotExist(err) {
+		t.Errorf("created in missing dir: %v", err)
+	}
+	if err := fsys.Rename(path("dir"), path("moved")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.Rename(path("a.txt"), path("moved/a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.Remove(path("moved/b.go")); err != nil {
+		t.Fatal(err)
+	}
+	fis, err := fsys.ReadDir(path("moved"))
+	if err != nil || len(fis) != 2 || fis[0].Name() != "a.txt" || fis[1].Name() != "sub" || !fis[1].IsDir() {
+		t.Errorf("read dir: %v %v", fis, err)
+	}
+	expectFiles(t, "changed", fsys, root, map[string]string{"moved/": "", "moved/a.txt": "one", "moved/sub/": "", "moved/sub/c.txt": "three"})
+	if err := RemoveAll(fsys, path("moved")); err != nil {
+		t.Fatal(err)
+	}
+	expectFiles(t, "removed", fsys, root, map[string]string{})
+}
+
+func TestOSFS(t *testing.T) {
+	testFS(t, OS, t.TempDir())
+}
+
+func TestMemFS(t *testing.T) {
+	testFS(t, NewMemFS(), "/")
+	m := NewMemFS()
+	if fi, err := m.Stat("/"); err != nil || !fi.IsDir() {
+		t.Errorf("root: %v %v", fi, err)
+	}
+	if err := m.Remove("/"); err == nil {
+		t.Errorf("removed root")
+	}
+}
+
+func TestArchiveFS(t *testing.T) {
+	var zb bytes.Buffer
+	zw := zip.NewWriter(&zb)
+	var tb bytes.Buffer
+	gw := gzip.NewWriter(&tb)
+	tw := tar.NewWriter(gw)
+	for fp, txt := range testFiles {
+		w, _ := zw.Create(fp)
+		w.Write([]byte(txt))
+		hdr := &tar.Header{Name: fp, Mode: 0644, Size: int64(len(txt)), Typeflag: tar.TypeReg}
+		if strings.HasSuffix(fp, "/") {
+			hdr.Mode, hdr.Typeflag = 0755, tar.TypeDir
+		}
+		tw.WriteHeader(hdr)
+		tw.Write([]byte(txt))
+	}
+	zw.Close()
+	tw.Close()
+	gw.Close()
+
+	za, err := NewZipFS(bytes.NewReader(zb.Bytes()), int64(zb.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ta, err := NewTarFS(&tb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range []*ArchiveFS{za, ta} {
+		expectFiles(t, "archive", a, "/", withDirs(testFiles))
+		if err := WriteFile(a, "/a.txt", []byte("two"), 0644); err == nil {
+			t.Errorf("wrote to archive")
+		}
+		m := NewMemFS()
+		if err := a.Extract(m, "/x"); err != nil {
+			t.Fatal(err)
+		}
+		expectFiles(t, "extracted", m, "/x", withDirs(testFiles))
+	}
+}
+
+func TestOverlayFS(t *testing.T) {
+	lower := NewMemFS()
+	writeFiles(t, lower, "/", testFiles)
+	upper := NewMemFS()
+	o := NewOverlayFS(upper, lower)
+	expectFiles(t, "overlay", o, "/", withDirs(testFiles))
+
+	WriteFile(o, "/dir/sub/c.txt", []byte("changed"), 0644)
+	WriteFile(o, "/dir/d.txt", []byte("new"), 0644)
+	o.Remove("/a.txt")
+	if err := o.Rename("/dir/b.go", "/b.go"); err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string]string{"b.go": "package b", "dir/": "", "dir/d.txt": "new", "dir/sub/": "", "dir/sub/c.txt": "changed"}
+	expectFiles(t, "changed", o, "/", exp)
+	expectFiles(t, "lower", lower, "/", withDirs(testFiles))
+
+	// a removed dir stays removed when made again
+	if err := RemoveAll(o, "/dir"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Mkdir("/dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	expectFiles(t, "made again", o, "/", map[string]string{"b.go": "package b", "dir/": ""})
+	if err := o.Rename("/dir", "/dir2"); err != nil {
+		t.Fatal(err)
+	}
+	expectFiles(t, "renamed", o, "/", map[string]string{"b.go": "package b", "dir2/": ""})
+}
@@ -0,0 +1,1974 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/giv/textbuf"
+	"github.com/goki/gi/oswin"
+	"github.com/goki/gi/oswin/key"
+	"github.com/goki/gi/oswin/mimedata"
+	"github.com/goki/ki/ints"
+	"github.com/goki/ki/kit"
+	"github.com/goki/mat32"
+	"github.com/goki/pi/filecat"
+	"github.com/goki/pi/lex"
+)
+
+// The vim-style modal editing of TextView is used when the active key map
+// has its Vim flag set (e.g., LinuxVim, MacVim) -- see gi.ActiveKeyMapVim.
+// In Insert mode, keys are processed according to the regular key map, as
+// are all keys with Control or Meta modifiers that are not vim commands.
+// Search patterns and :s patterns are Go regexp patterns -- they are case
+// insensitive if they are all lower case, as with vim smartcase.
+
+// VimModes are the modes of vim-style modal editing in a TextView
+type VimModes int32
+
+const (
+	// VimNormal is the mode where keys are commands, motions and operators
+	VimNormal VimModes = iota
+
+	// VimInsert is the mode where keys insert text, using the regular key map
+	VimInsert
+
+	// VimVisual is the mode where motions extend a selection of characters
+	VimVisual
+
+	// VimVisualLine is the mode where motions extend a selection of whole lines
+	VimVisualLine
+
+	// VimCommand is the mode of typing a : command or / ? search pattern
+	VimCommand
+
+	VimModesN
+)
+
+//go:generate stringer -type=VimModes
+
+var KiT_VimModes = kit.Enums.AddEnumAltLower(VimModesN, kit.NotBitFlag, nil, "Vim")
+
+func (ev VimModes) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *VimModes) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+// VimRegister is the text saved in a vim register by a yank or delete
+type VimRegister struct {
+	Text  []byte `desc:"text of the register"`
+	Lines bool   `desc:"text is whole lines, which are put above or below the cursor line"`
+}
+
+// VimRegisters are the registers shared by all text views: " is the
+// unnamed register, a-z are named registers (A-Z append to them), 0 has the
+// last yank, 1-9 the last deletes of lines, and - the last small delete.
+// The unnamed register is also written to the clipboard, and the + and *
+// registers read and write the clipboard.
+var VimRegisters = map[rune]*VimRegister{}
+
+// VimState is the state of vim-style modal editing for a TextView
+type VimState struct {
+	Mode       VimModes         `desc:"current mode"`
+	Count      int              `desc:"count typed before the current command or motion -- 0 if none"`
+	Op         string           `desc:"pending operator waiting for a motion: d c y > < g~ gu gU"`
+	Pending    string           `desc:"pending prefix keys of a command, e.g., g, f, i for a text object, or \" for a register"`
+	Reg        rune             `desc:"register selected with \"x for the current command -- 0 for the unnamed register"`
+	CmdLine    string           `desc:"command line being typed in Command mode, starting with : / or ?"`
+	VisStart   lex.Pos          `desc:"start of the selection in Visual modes -- the cursor is the other end"`
+	Search     string           `desc:"last search pattern, used by n and N"`
+	Msg        string           `desc:"message shown in the mode indicator, e.g., from a : command"`
+	opCount    int              // count typed before the operator
+	searchBack bool             // last search was backward
+	findCh     rune             // last f t F T character
+	findKind   rune             // last f t F T command
+	lastChange []key.ChordEvent // keys of the last change, for .
+	rec        []key.ChordEvent // keys of the current command
+	recording  bool             // recording keys into rec
+	replaying  bool             // replaying lastChange
+	insBatch   bool             // undo batch is open for the current insert
+}
+
+// idle returns true if no command is in progress
+func (vs *VimState) idle() bool {
+	return vs.Count == 0 && vs.Op == "" && vs.Pending == "" && vs.Reg == 0
+}
+
+// reset resets the state of the current command
+func (vs *VimState) reset() {
+	vs.Count = 0
+	vs.opCount = 0
+	vs.Op = ""
+	vs.Pending = ""
+	vs.Reg = 0
+}
+
+// count returns the total count for the current command, including any
+// count typed before the operator -- at least 1
+func (vs *VimState) count() int {
+	return ints.MaxInt(vs.Count, 1) * ints.MaxInt(vs.opCount, 1)
+}
+
+// hasCount returns true if a count was typed for the current command
+func (vs *VimState) hasCount() bool {
+	return vs.Count > 0 || vs.opCount > 0
+}
+
+// vimEsc is the key rune for Escape (and Control+[)
+const vimEsc = 0x1b
+
+// vimKey returns the rune for a key event in Normal and Visual modes:
+// the character typed, with arrow keys mapped to h j k l, and control
+// characters for Control+letter -- -1 for keys that are not vim commands
+func vimKey(kt *key.ChordEvent) rune {
+	if key.HasAnyModifierBits(kt.Modifiers, key.Meta) {
+		return -1
+	}
+	switch kt.Code {
+	case key.CodeEscape:
+		return vimEsc
+	case key.CodeReturnEnter, key.CodeKeypadEnter:
+		return '\r'
+	case key.CodeDeleteBackspace:
+		return '\b'
+	case key.CodeTab:
+		return '\t'
+	case key.CodeLeftArrow:
+		return 'h'
+	case key.CodeRightArrow:
+		return 'l'
+	case key.CodeUpArrow:
+		return 'k'
+	case key.CodeDownArrow:
+		return 'j'
+	case key.CodeHome:
+		return '0'
+	case key.CodeEnd:
+		return '$'
+	}
+	if key.HasAnyModifierBits(kt.Modifiers, key.Control) {
+		switch {
+		case kt.Rune >= 'a' && kt.Rune <= 'z':
+			return kt.Rune - 'a' + 1
+		case kt.Rune == '[':
+			return vimEsc
+		}
+		return -1
+	}
+	if kt.Rune > 0 && unicode.IsPrint(kt.Rune) {
+		return kt.Rune
+	}
+	return -1
+}
+
+// VimKeyInput processes a key event according to the current vim mode,
+// returning true if it was used -- keys typed in Insert mode are not used,
+// except for Escape, and are processed by the regular KeyInput
+func (tv *TextView) VimKeyInput(kt *key.ChordEvent) bool {
+	vs := &tv.Vim
+	if vs.Mode == VimInsert {
+		if vs.recording && !vs.replaying {
+			vs.rec = append(vs.rec, *kt)
+		}
+		if vimKey(kt) == vimEsc {
+			tv.VimEndInsert()
+			return true
+		}
+		return false
+	}
+	if vs.Mode == VimCommand {
+		tv.vimCmdLineKey(kt)
+		return true
+	}
+	r := vimKey(kt)
+	if r < 0 || r < ' ' && r != vimEsc && r != '\r' && r != '\b' && r != '\t' && !strings.ContainsRune("\x02\x04\x06\x12\x15", r) {
+		return false // regular key map
+	}
+	if vs.idle() && !vs.replaying && (vs.Mode == VimNormal || !vs.recording) {
+		vs.rec = nil
+		vs.recording = true
+	}
+	if vs.recording && !vs.replaying {
+		vs.rec = append(vs.rec, *kt)
+	}
+	vs.Msg = ""
+	wupdt := tv.TopUpdateStart()
+	defer tv.TopUpdateEnd(wupdt)
+	tv.vimNormalKey(r)
+	tv.RenderAllLines() // mode indicator
+	return true
+}
+
+// vimDone finishes the current command, which changed the text if changed
+func (tv *TextView) vimDone(changed bool) {
+	vs := &tv.Vim
+	vs.reset()
+	if vs.replaying {
+		return
+	}
+	if changed {
+		if vs.Mode == VimInsert {
+			return // recorded through the end of the insert
+		}
+		vs.lastChange = vs.rec
+	} else if vs.Mode == VimVisual || vs.Mode == VimVisualLine {
+		return // recorded through the visual operator
+	}
+	vs.rec = nil
+	vs.recording = false
+}
+
+// VimSetMode sets the vim mode, e.g., to start in Insert mode
+func (tv *TextView) VimSetMode(mode VimModes) {
+	vs := &tv.Vim
+	if vs.Mode == mode {
+		return
+	}
+	if (vs.Mode == VimVisual || vs.Mode == VimVisualLine) && mode != VimVisual && mode != VimVisualLine {
+		tv.SelectReset()
+	}
+	vs.Mode = mode
+	switch mode {
+	case VimVisual, VimVisualLine:
+		if !tv.HasSelection() {
+			vs.VisStart = tv.CursorPos
+		}
+		tv.vimUpdateSelect()
+	case VimInsert:
+		if !vs.insBatch && tv.Buf != nil {
+			tv.Buf.Undos.BatchStart()
+			vs.insBatch = true
+		}
+	}
+	tv.RenderAllLines()
+}
+
+// VimEndInsert ends Insert mode, returning to Normal mode
+func (tv *TextView) VimEndInsert() {
+	vs := &tv.Vim
+	vs.Mode = VimNormal
+	if vs.insBatch {
+		tv.Buf.Undos.BatchEnd()
+		vs.insBatch = false
+	}
+	if vs.recording && !vs.replaying {
+		vs.lastChange = vs.rec
+		vs.rec = nil
+		vs.recording = false
+	}
+	pos := tv.CursorPos
+	if pos.Ch > 0 {
+		pos.Ch--
+	}
+	tv.vimSetCursor(pos)
+	tv.RenderAllLines()
+}
+
+// VimStatus returns the text of the mode indicator: the mode, any message,
+// the command line, or the keys of a pending command
+func (tv *TextView) VimStatus() string {
+	vs := &tv.Vim
+	switch {
+	case vs.Mode == VimCommand:
+		return vs.CmdLine
+	case vs.Msg != "":
+		return vs.Msg
+	case vs.Mode == VimInsert:
+		return "-- INSERT --"
+	case vs.Mode == VimVisual:
+		return "-- VISUAL --"
+	case vs.Mode == VimVisualLine:
+		return "-- VISUAL LINE --"
+	}
+	st := ""
+	if vs.Reg != 0 {
+		st = `"` + string(vs.Reg)
+	}
+	if vs.opCount > 0 {
+		st += strconv.Itoa(vs.opCount)
+	}
+	st += vs.Op
+	if vs.Count > 0 {
+		st += strconv.Itoa(vs.Count)
+	}
+	return st + vs.Pending
+}
+
+// RenderVimMode renders the vim mode indicator at the lower right of the view
+func (tv *TextView) RenderVimMode() {
+	if !gi.ActiveKeyMapVim || tv.IsInactive() || !tv.HasFocus() {
+		return
+	}
+	st := tv.VimStatus()
+	if st == "" {
+		return
+	}
+	sty := &tv.Sty
+	spc := sty.BoxSpace()
+	fst := sty.Font
+	fst.BgColor.SetColor(nil)
+	tv.vimRender.SetString(st, &fst, &sty.UnContext, &sty.Text, true, 0, 0)
+	rs := tv.Render()
+	sz := tv.vimRender.Size
+	pos := mat32.NewVec2FmPoint(tv.VpBBox.Max)
+	pos.X -= tv.MinimapOff + sz.X + 2*spc
+	pos.Y -= tv.LineHeight + spc
+	rs.Paint.FillBoxColor(rs, pos.Sub(mat32.Vec2{X: spc, Y: 0}), mat32.Vec2{X: sz.X + 2*spc, Y: tv.LineHeight}, sty.Font.BgColor.Color.Highlight(10))
+	pos.Y += mat32.FromFixed(sty.Font.Face.Face.Metrics().Ascent) - mat32.FromFixed(sty.Font.Face.Face.Metrics().Descent)
+	tv.vimRender.Render(rs, pos)
+}
+
+// vimSetCursor sets the cursor, which is on a character, not past the end
+// of the line, except in Insert mode
+func (tv *TextView) vimSetCursor(pos lex.Pos) {
+	pos = tv.Buf.ValidPos(pos)
+	if tv.Vim.Mode != VimInsert {
+		if ll := tv.Buf.LineLen(pos.Ln); pos.Ch >= ll {
+			pos.Ch = ints.MaxInt(ll-1, 0)
+		}
+	}
+	tv.SetCursorShow(pos)
+	if tv.Vim.Mode == VimVisual || tv.Vim.Mode == VimVisualLine {
+		tv.vimUpdateSelect()
+	}
+}
+
+// vimVisualRegion returns the region selected in the Visual modes, with an
+// exclusive end, and true if it is whole lines
+func (tv *TextView) vimVisualRegion() (st, ed lex.Pos, lines bool) {
+	st, ed = tv.Vim.VisStart, tv.CursorPos
+	if ed.IsLess(st) {
+		st, ed = ed, st
+	}
+	if tv.Vim.Mode == VimVisualLine {
+		return lex.Pos{Ln: st.Ln}, lex.Pos{Ln: ed.Ln, Ch: tv.Buf.LineLen(ed.Ln)}, true
+	}
+	ed.Ch = ints.MinInt(ed.Ch+1, tv.Buf.LineLen(ed.Ln))
+	return st, ed, false
+}
+
+// vimUpdateSelect updates the selection to the Visual mode region
+func (tv *TextView) vimUpdateSelect() {
+	st, ed, _ := tv.vimVisualRegion()
+	tv.SelectReg = textbuf.NewRegionPos(st, ed)
+	tv.RenderSelectLines()
+}
+
+// vimNormalKey processes key r in Normal and Visual modes
+func (tv *TextView) vimNormalKey(r rune) {
+	vs := &tv.Vim
+	tb := tv.Buf
+	visual := vs.Mode == VimVisual || vs.Mode == VimVisualLine
+	if r == vimEsc {
+		if visual && vs.idle() {
+			tv.VimSetMode(VimNormal)
+		}
+		vs.reset()
+		vs.rec = nil
+		vs.recording = false
+		return
+	}
+	if vs.Pending != "" {
+		tv.vimPendingKey(r)
+		return
+	}
+	if r >= '1' && r <= '9' || r == '0' && vs.Count > 0 {
+		vs.Count = vs.Count*10 + int(r-'0')
+		return
+	}
+	if vs.Op != "" && string(r) == vs.Op[len(vs.Op)-1:] { // dd cc yy >> << g~~ guu gUU
+		n := vs.count()
+		ln := tv.CursorPos.Ln
+		edln := ints.MinInt(ln+n-1, tb.NumLines()-1)
+		tv.vimOperate(vs.Op, lex.Pos{Ln: ln}, lex.Pos{Ln: edln}, true)
+		tv.vimDone(vs.Op != "y")
+		return
+	}
+	if pos, kind, ok := tv.vimMotion(r, tv.CursorPos); ok {
+		tv.vimMoveOrOperate(pos, kind)
+		return
+	}
+	if visual {
+		tv.vimVisualKey(r)
+		return
+	}
+	if vs.Op != "" {
+		if strings.ContainsRune("iafFtTg", r) {
+			vs.Pending = string(r)
+			return
+		}
+		vs.reset() // not a motion
+		return
+	}
+	n := vs.count()
+	cur := tv.CursorPos
+	switch r {
+	case '"', 'g', 'f', 'F', 't', 'T', 'r', 'z':
+		vs.Pending = string(r)
+		return
+	case 'd', 'c', 'y', '>', '<':
+		vs.Op = string(r)
+		vs.opCount, vs.Count = vs.Count, 0
+		return
+	case 'x', 'X', 's', 'D', 'C':
+		op := map[rune]string{'x': "dl", 'X': "dh", 's': "cl", 'D': "d$", 'C': "c$"}[r]
+		vs.Op = op[:1]
+		vs.opCount, vs.Count = vs.Count, 0
+		tv.vimNormalKey(rune(op[1]))
+		return
+	case 'S', 'Y':
+		vs.Op = map[rune]string{'S': "c", 'Y': "y"}[r]
+		vs.opCount, vs.Count = vs.Count, 0
+		tv.vimNormalKey(rune(vs.Op[0]))
+		return
+	case 'i':
+		tv.VimSetMode(VimInsert)
+	case 'a':
+		tv.VimSetMode(VimInsert)
+		tv.vimSetCursor(lex.Pos{Ln: cur.Ln, Ch: ints.MinInt(cur.Ch+1, tb.LineLen(cur.Ln))})
+	case 'I':
+		tv.VimSetMode(VimInsert)
+		tv.vimSetCursor(lex.Pos{Ln: cur.Ln, Ch: vimFirstNonBlank(tb.Line(cur.Ln))})
+	case 'A':
+		tv.VimSetMode(VimInsert)
+		tv.vimSetCursor(lex.Pos{Ln: cur.Ln, Ch: tb.LineLen(cur.Ln)})
+	case 'o', 'O':
+		tv.VimSetMode(VimInsert)
+		tv.vimOpenLine(r == 'O')
+	case 'v':
+		tv.VimSetMode(VimVisual)
+		tv.vimDone(false)
+		return
+	case 'V':
+		tv.VimSetMode(VimVisualLine)
+		tv.vimDone(false)
+		return
+	case 'p', 'P':
+		tv.vimPut(vs.Reg, r == 'P', n)
+	case 'J':
+		tv.vimJoin(cur.Ln, ints.MaxInt(n, 2)-1)
+	case '~':
+		ed := lex.Pos{Ln: cur.Ln, Ch: ints.MinInt(cur.Ch+n, tb.LineLen(cur.Ln))}
+		tv.vimChangeCase("g~", cur, ed)
+		tv.vimSetCursor(ed)
+	case 'u', 0x12: // Control+R is redo
+		for i := 0; i < n; i++ {
+			tbe := tb.Undo()
+			if r != 'u' {
+				tbe = tb.Redo()
+			}
+			if tbe != nil {
+				cur = tbe.Reg.Start
+			}
+		}
+		tv.vimSetCursor(cur)
+		tv.SavePosHistory(tv.CursorPos)
+		tv.vimDone(false)
+		return
+	case '.':
+		vs.reset()
+		tv.VimRepeat(n)
+		return
+	case ':', '/', '?':
+		vs.reset()
+		vs.CmdLine = string(r)
+		if r == ':' && n > 1 {
+			vs.CmdLine = ":.,.+" + strconv.Itoa(n-1)
+		}
+		vs.Mode = VimCommand
+		vs.rec = nil
+		vs.recording = false
+		return
+	case 0x04, 0x06: // Control+D, F
+		tv.CursorPageDown(n)
+		tv.vimSetCursor(tv.CursorPos)
+		tv.vimDone(false)
+		return
+	case 0x15, 0x02: // Control+U, B
+		tv.CursorPageUp(n)
+		tv.vimSetCursor(tv.CursorPos)
+		tv.vimDone(false)
+		return
+	default:
+		tv.vimDone(false)
+		return
+	}
+	tv.vimDone(true)
+}
+
+// vimPendingKey processes key r following the prefix in Pending
+func (tv *TextView) vimPendingKey(r rune) {
+	vs := &tv.Vim
+	tb := tv.Buf
+	cur := tv.CursorPos
+	pend := vs.Pending
+	vs.Pending = ""
+	switch pend {
+	case `"`:
+		vs.Reg = r
+	case "g":
+		switch r {
+		case 'g', 'e', 'E':
+			if pos, kind, ok := tv.vimMotion(-r, cur); ok { // -r is g + r
+				tv.vimMoveOrOperate(pos, kind)
+				return
+			}
+		case '~', 'u', 'U':
+			if vs.Mode == VimVisual || vs.Mode == VimVisualLine {
+				st, ed, lines := tv.vimVisualRegion()
+				tv.VimSetMode(VimNormal)
+				tv.vimOperate("g"+string(r), st, ed, lines)
+				tv.vimDone(true)
+				return
+			}
+			vs.Op = "g" + string(r)
+			vs.opCount, vs.Count = vs.Count, 0
+			return
+		case 'J':
+			tv.vimJoinNoSpace(cur.Ln, ints.MaxInt(vs.count(), 2)-1)
+			tv.vimDone(true)
+			return
+		}
+		vs.reset()
+	case "f", "F", "t", "T":
+		vs.findKind, vs.findCh = rune(pend[0]), r
+		if pos, kind, ok := tv.vimMotion(';', cur); ok {
+			tv.vimMoveOrOperate(pos, kind)
+			return
+		}
+		vs.reset()
+	case "r":
+		n := vs.count()
+		if r == vimEsc || cur.Ch+n > tb.LineLen(cur.Ln) {
+			vs.reset()
+			return
+		}
+		if r == '\r' {
+			r = '\n'
+		}
+		ed := lex.Pos{Ln: cur.Ln, Ch: cur.Ch + n}
+		tb.ReplaceText(cur, ed, cur, strings.Repeat(string(r), n), EditSignal, false)
+		if r == '\n' {
+			tv.vimSetCursor(lex.Pos{Ln: cur.Ln + 1})
+		} else {
+			tv.vimSetCursor(lex.Pos{Ln: cur.Ln, Ch: ed.Ch - 1})
+		}
+		tv.vimDone(true)
+	case "z":
+		switch r {
+		case 'z', '.':
+			tv.ScrollCursorToVertCenter()
+		case 't', '\r':
+			tv.ScrollCursorToTop()
+		case 'b', '-':
+			tv.ScrollCursorToBottom()
+		}
+		vs.reset()
+	case "i", "a":
+		st, ed, lines, ok := vimTextObject(tb, cur, pend == "a", r)
+		if !ok {
+			vs.reset()
+			return
+		}
+		if vs.Mode == VimVisual || vs.Mode == VimVisualLine {
+			if vs.VisStart != cur || !lines && vs.Mode == VimVisualLine {
+				st = vs.VisStart // only extend a selection
+			}
+			vs.VisStart = st
+			ed.Ch--
+			if ed.Ch < 0 && ed.Ln > st.Ln {
+				ed = lex.Pos{Ln: ed.Ln - 1, Ch: tb.LineLen(ed.Ln - 1)}
+			}
+			tv.vimSetCursor(ed)
+			vs.reset()
+			return
+		}
+		op := vs.Op
+		tv.vimOperate(op, st, ed, lines)
+		tv.vimDone(op != "y")
+	}
+}
+
+// vimVisualKey processes key r in the Visual modes, for keys that are not
+// motions
+func (tv *TextView) vimVisualKey(r rune) {
+	vs := &tv.Vim
+	switch r {
+	case '"', 'g', 'f', 'F', 't', 'T', 'i', 'a', 'z':
+		vs.Pending = string(r)
+		return
+	case 'o':
+		vs.VisStart, tv.CursorPos = tv.CursorPos, vs.VisStart
+		tv.vimSetCursor(tv.CursorPos)
+		vs.reset()
+		return
+	case 'v', 'V':
+		mode := VimVisual
+		if r == 'V' {
+			mode = VimVisualLine
+		}
+		if vs.Mode == mode {
+			tv.VimSetMode(VimNormal)
+			tv.vimDone(false)
+			vs.rec, vs.recording = nil, false
+			return
+		}
+		vs.Mode = mode
+		tv.vimUpdateSelect()
+		vs.reset()
+		return
+	case ':':
+		vs.reset()
+		tv.VimSetMode(VimNormal)
+		vs.CmdLine = ":'<,'>"
+		vs.Mode = VimCommand
+		vs.rec, vs.recording = nil, false
+		return
+	}
+	st, ed, lines := tv.vimVisualRegion()
+	if lines {
+		ed = lex.Pos{Ln: ed.Ln}
+	}
+	reg := vs.Reg
+	var op string
+	switch r {
+	case 'd', 'x', 'X', 'D':
+		op = "d"
+		lines = lines || r == 'X' || r == 'D'
+	case 'c', 's', 'S', 'C', 'R':
+		op = "c"
+		lines = lines || r == 'S' || r == 'C' || r == 'R'
+	case 'y', 'Y':
+		op = "y"
+		lines = lines || r == 'Y'
+	case '>', '<', '~':
+		op = string(r)
+		if r == '~' {
+			op = "g~"
+		}
+	case 'u', 'U':
+		op = "g" + string(r)
+	case 'J':
+		tv.VimSetMode(VimNormal)
+		tv.vimJoin(st.Ln, ints.MaxInt(ed.Ln-st.Ln, 1))
+		tv.vimDone(true)
+		return
+	case 'p', 'P':
+		rg := tv.vimGetReg(reg)
+		tv.VimSetMode(VimNormal)
+		if rg == nil {
+			tv.vimDone(false)
+			return
+		}
+		tb := tv.Buf
+		tb.Undos.BatchStart()
+		tv.vimOperate("d", st, ed, lines)
+		atEnd := lines && st.Ln >= tb.NumLines() // deleted the last lines
+		if lines && !rg.Lines {
+			if atEnd {
+				tb.InsertText(tb.EndPos(), []byte("\n"), EditSignal)
+			} else {
+				tb.InsertText(lex.Pos{Ln: st.Ln}, []byte("\n"), EditSignal)
+			}
+			tv.vimSetCursor(lex.Pos{Ln: st.Ln})
+			atEnd = false
+		}
+		before := lines && !atEnd || !lines && tv.CursorPos.Ch == st.Ch && st.Ch < tb.LineLen(st.Ln)
+		VimRegisters['"'] = rg // put the text replaced, not the text deleted
+		tv.vimPut('"', before, vs.count())
+		tb.Undos.BatchEnd()
+		tv.vimDone(true)
+		return
+	default:
+		vs.reset()
+		return
+	}
+	tv.VimSetMode(VimNormal)
+	if lines {
+		tv.vimOperate(op, lex.Pos{Ln: st.Ln}, lex.Pos{Ln: ed.Ln}, true)
+	} else {
+		if op == ">" || op == "<" {
+			tv.vimOperate(op, st, ed, true)
+		} else {
+			tv.vimOperate(op, st, ed, false)
+		}
+	}
+	tv.vimDone(op != "y")
+}
+
+// vimMoveOrOperate moves the cursor to pos, the target of a motion of
+// given kind, or applies the pending operator from the cursor to pos
+func (tv *TextView) vimMoveOrOperate(pos lex.Pos, kind vimMotionKind) {
+	vs := &tv.Vim
+	if vs.Op == "" {
+		if kind != vimMotionVertical {
+			tv.vimSetCursor(pos)
+			tv.SetCursorCol(tv.CursorPos)
+		} else {
+			tv.vimSetCursor(pos)
+		}
+		tv.vimDone(false)
+		return
+	}
+	st, ed := tv.CursorPos, pos
+	if ed.IsLess(st) {
+		st, ed = ed, st
+	}
+	lines := kind == vimMotionLines || kind == vimMotionVertical
+	if kind == vimMotionInclusive {
+		ed.Ch = ints.MinInt(ed.Ch+1, tv.Buf.LineLen(ed.Ln))
+	}
+	op := vs.Op
+	tv.vimOperate(op, st, ed, lines)
+	tv.vimDone(op != "y")
+}
+
+// vimMotionKind is the kind of region covered by a motion
+type vimMotionKind int
+
+const (
+	// vimMotionExclusive does not include the character at the end position
+	vimMotionExclusive vimMotionKind = iota
+
+	// vimMotionInclusive includes the character at the end position
+	vimMotionInclusive
+
+	// vimMotionLines covers whole lines
+	vimMotionLines
+
+	// vimMotionVertical covers whole lines, and keeps the cursor column
+	vimMotionVertical
+)
+
+// vimMotion returns the target of motion key r from pos, for the current
+// count, and its kind -- false if r is not a motion.  The motions that
+// start with g are given as -r, e.g., -'g' for gg.
+func (tv *TextView) vimMotion(r rune, pos lex.Pos) (lex.Pos, vimMotionKind, bool) {
+	vs := &tv.Vim
+	tb := tv.Buf
+	n := vs.count()
+	nln := tb.NumLines()
+	line := tb.Line(pos.Ln)
+	switch r {
+	case 'h', '\b':
+		pos.Ch = ints.MaxInt(pos.Ch-n, 0)
+		return pos, vimMotionExclusive, true
+	case 'l', ' ':
+		mx := len(line)
+		if vs.Op == "" {
+			mx = ints.MaxInt(mx-1, 0)
+		}
+		pos.Ch = ints.MinInt(pos.Ch+n, mx)
+		return pos, vimMotionExclusive, true
+	case 'j', 'k':
+		if r == 'j' {
+			pos.Ln = ints.MinInt(pos.Ln+n, nln-1)
+		} else {
+			pos.Ln = ints.MaxInt(pos.Ln-n, 0)
+		}
+		pos.Ch = ints.MinInt(tv.CursorCol, ints.MaxInt(tb.LineLen(pos.Ln)-1, 0))
+		return pos, vimMotionVertical, true
+	case '\r', '+', '-':
+		if r == '-' {
+			pos.Ln = ints.MaxInt(pos.Ln-n, 0)
+		} else {
+			pos.Ln = ints.MinInt(pos.Ln+n, nln-1)
+		}
+		return lex.Pos{Ln: pos.Ln, Ch: vimFirstNonBlank(tb.Line(pos.Ln))}, vimMotionLines, true
+	case '0':
+		return lex.Pos{Ln: pos.Ln}, vimMotionExclusive, true
+	case '^':
+		return lex.Pos{Ln: pos.Ln, Ch: vimFirstNonBlank(line)}, vimMotionExclusive, true
+	case '$':
+		pos.Ln = ints.MinInt(pos.Ln+n-1, nln-1)
+		pos.Ch = ints.MaxInt(tb.LineLen(pos.Ln)-1, 0)
+		if tb.LineLen(pos.Ln) == 0 {
+			return pos, vimMotionExclusive, true
+		}
+		return pos, vimMotionInclusive, true
+	case '|':
+		pos.Ch = ints.MinInt(n-1, ints.MaxInt(len(line)-1, 0))
+		return pos, vimMotionExclusive, true
+	case 'w', 'W':
+		st := pos
+		for i := 0; i < n; i++ {
+			pos = vimNextWord(tb, pos, r == 'W')
+		}
+		if vs.Op == "c" && st.Ch < len(line) && !unicode.IsSpace(line[st.Ch]) { // cw is ce
+			pos = st
+			for i := 0; i < n; i++ {
+				pos = vimEndWord(tb, pos, r == 'W', i == 0)
+			}
+			return pos, vimMotionInclusive, true
+		}
+		if vs.Op != "" && pos.Ln > st.Ln { // the last word of a line stops at its end
+			ln := pos.Ln - 1
+			if pos.Ch > 0 || tb.LineLen(pos.Ln) == 0 && pos.Ln == nln-1 {
+				ln = pos.Ln
+			}
+			if ln > st.Ln || ln == st.Ln {
+				pos = lex.Pos{Ln: ln, Ch: tb.LineLen(ln)}
+			}
+		}
+		return pos, vimMotionExclusive, true
+	case 'b', 'B':
+		for i := 0; i < n; i++ {
+			pos = vimPrevWord(tb, pos, r == 'B')
+		}
+		return pos, vimMotionExclusive, true
+	case 'e', 'E':
+		for i := 0; i < n; i++ {
+			pos = vimEndWord(tb, pos, r == 'E', false)
+		}
+		return pos, vimMotionInclusive, true
+	case -'e', -'E':
+		for i := 0; i < n; i++ {
+			pos = vimPrevEndWord(tb, pos, r == -'E')
+		}
+		return pos, vimMotionInclusive, true
+	case 'G', -'g':
+		ln := nln - 1
+		if vs.hasCount() || r == -'g' {
+			ln = ints.MinInt(ints.MaxInt(n-1, 0), nln-1)
+		}
+		return lex.Pos{Ln: ln, Ch: vimFirstNonBlank(tb.Line(ln))}, vimMotionLines, true
+	case '%':
+		if p, ok := vimMatchBracket(tb, pos); ok {
+			return p, vimMotionInclusive, true
+		}
+		return pos, vimMotionExclusive, false
+	case '{', '}':
+		for i := 0; i < n; i++ {
+			pos = vimParagraph(tb, pos, r == '{')
+		}
+		return pos, vimMotionExclusive, true
+	case ';', ',':
+		if vs.findKind == 0 {
+			return pos, vimMotionExclusive, false
+		}
+		kind := vs.findKind
+		if r == ',' {
+			kind = map[rune]rune{'f': 'F', 'F': 'f', 't': 'T', 'T': 't'}[kind]
+		}
+		ch, ok := vimFindInLine(line, pos.Ch, vs.findCh, kind, n)
+		if !ok {
+			return pos, vimMotionExclusive, vs.Op == "" // no move, but used
+		}
+		pos.Ch = ch
+		if kind == 'f' || kind == 't' {
+			return pos, vimMotionInclusive, true
+		}
+		return pos, vimMotionExclusive, true
+	case 'n', 'N', '*', '#':
+		if r == '*' || r == '#' {
+			st, ed := vimWordAt(line, pos.Ch)
+			if st == ed {
+				return pos, vimMotionExclusive, false
+			}
+			vs.Search = `\b` + regexp.QuoteMeta(string(line[st:ed])) + `\b`
+			vs.searchBack = r == '#'
+			if r == '#' {
+				pos.Ch = st
+			}
+		}
+		back := vs.searchBack != (r == 'N')
+		re, err := vimRegexp(vs.Search)
+		if err != nil || vs.Search == "" {
+			vs.Msg = "No previous search pattern"
+			return pos, vimMotionExclusive, false
+		}
+		for i := 0; i < n; i++ {
+			p, ok := vimSearch(tb, pos, re, back)
+			if !ok {
+				vs.Msg = "Pattern not found: " + vs.Search
+				return pos, vimMotionExclusive, false
+			}
+			pos = p
+		}
+		return pos, vimMotionExclusive, true
+	}
+	return pos, vimMotionExclusive, false
+}
+
+// vimOperate applies operator op to the region from st to ed, which is
+// exclusive, or to the whole lines from st.Ln to ed.Ln if lines
+func (tv *TextView) vimOperate(op string, st, ed lex.Pos, lines bool) {
+	tb := tv.Buf
+	if ed.IsLess(st) {
+		st, ed = ed, st
+	}
+	if op == "c" {
+		tv.VimSetMode(VimInsert) // starts the undo batch for the change
+	}
+	tb.Undos.BatchStart()
+	defer tb.Undos.BatchEnd()
+	if lines {
+		txt := vimLinesText(tb, st.Ln, ed.Ln)
+		switch op {
+		case "d":
+			tv.vimSetReg(txt, true, false)
+			dst, ded := lex.Pos{Ln: st.Ln}, lex.Pos{Ln: ed.Ln + 1}
+			if ed.Ln+1 >= tb.NumLines() {
+				ded = lex.Pos{Ln: ed.Ln, Ch: tb.LineLen(ed.Ln)}
+				if st.Ln > 0 {
+					dst = lex.Pos{Ln: st.Ln - 1, Ch: tb.LineLen(st.Ln - 1)}
+				}
+			}
+			tb.DeleteText(dst, ded, EditSignal)
+			ln := ints.MinInt(st.Ln, tb.NumLines()-1)
+			tv.vimSetCursor(lex.Pos{Ln: ln, Ch: vimFirstNonBlank(tb.Line(ln))})
+		case "c":
+			tv.vimSetReg(txt, true, false)
+			nb := vimFirstNonBlank(tb.Line(st.Ln))
+			if nb == tb.LineLen(st.Ln) && tb.Opts.AutoIndent {
+				nb = 0
+			}
+			tb.DeleteText(lex.Pos{Ln: st.Ln, Ch: nb}, lex.Pos{Ln: ed.Ln, Ch: tb.LineLen(ed.Ln)}, EditSignal)
+			if nb == 0 && tb.Opts.AutoIndent {
+				_, _, nb = tb.AutoIndent(st.Ln)
+			}
+			tv.vimSetCursor(lex.Pos{Ln: st.Ln, Ch: nb})
+		case "y":
+			tv.vimSetReg(txt, true, true)
+			if tv.CursorPos.Ln != st.Ln {
+				tv.vimSetCursor(lex.Pos{Ln: st.Ln, Ch: tv.CursorPos.Ch})
+			}
+		case ">", "<":
+			tv.vimIndent(st.Ln, ed.Ln, op == ">")
+		default:
+			tv.vimChangeCase(op, lex.Pos{Ln: st.Ln}, lex.Pos{Ln: ed.Ln, Ch: tb.LineLen(ed.Ln)})
+			tv.vimSetCursor(lex.Pos{Ln: st.Ln, Ch: tv.CursorPos.Ch})
+		}
+		return
+	}
+	var txt []byte
+	if st != ed {
+		if tbe := tb.Region(st, ed); tbe != nil {
+			txt = tbe.ToBytes()
+		}
+	}
+	switch op {
+	case "d", "c":
+		if st != ed {
+			tv.vimSetReg(txt, false, false)
+			tb.DeleteText(st, ed, EditSignal)
+		}
+		tv.vimSetCursor(st)
+	case "y":
+		tv.vimSetReg(txt, false, true)
+		tv.vimSetCursor(st)
+	case ">", "<":
+		if ed.Ch == 0 && ed.Ln > st.Ln {
+			ed.Ln--
+		}
+		tv.vimIndent(st.Ln, ed.Ln, op == ">")
+	default:
+		tv.vimChangeCase(op, st, ed)
+		tv.vimSetCursor(st)
+	}
+}
+
+// vimIndent indents the lines from stln to edln by the count of tab stops,
+// or unindents them
+func (tv *TextView) vimIndent(stln, edln int, in bool) {
+	tb := tv.Buf
+	n := tv.Vim.count()
+	if !in {
+		n = -n
+	}
+	for ln := stln; ln <= edln; ln++ {
+		if tb.LineLen(ln) == 0 {
+			continue
+		}
+		ind, _ := lex.LineIndent(tb.Line(ln), tb.Opts.TabSize)
+		tb.IndentLine(ln, ints.MaxInt(ind+n, 0))
+	}
+	tv.vimSetCursor(lex.Pos{Ln: stln, Ch: vimFirstNonBlank(tb.Line(stln))})
+}
+
+// vimChangeCase toggles the case of the text from st to ed for op g~, or
+// changes it to lower case for gu, or upper case for gU
+func (tv *TextView) vimChangeCase(op string, st, ed lex.Pos) {
+	tbe := tv.Buf.Region(st, ed)
+	if tbe == nil {
+		return
+	}
+	txt := []rune(string(tbe.ToBytes()))
+	for i, r := range txt {
+		switch {
+		case op == "gu" || op == "g~" && unicode.IsUpper(r):
+			txt[i] = unicode.ToLower(r)
+		case op == "gU" || op == "g~":
+			txt[i] = unicode.ToUpper(r)
+		}
+	}
+	tv.Buf.ReplaceText(st, ed, st, string(txt), EditSignal, false)
+}
+
+// vimOpenLine opens a new line below the cursor line, or above it, with
+// auto-indent, and moves the cursor to it
+func (tv *TextView) vimOpenLine(above bool) {
+	tb := tv.Buf
+	ln := tv.CursorPos.Ln
+	if above {
+		tb.InsertText(lex.Pos{Ln: ln}, []byte("\n"), EditSignal)
+	} else {
+		tb.InsertText(lex.Pos{Ln: ln, Ch: tb.LineLen(ln)}, []byte("\n"), EditSignal)
+		ln++
+	}
+	ch := 0
+	if tb.Opts.AutoIndent {
+		_, _, ch = tb.AutoIndent(ln)
+	}
+	tv.vimSetCursor(lex.Pos{Ln: ln, Ch: ch})
+}
+
+// vimJoin joins n lines after line ln to it, separated by a space, with
+// their indentation removed
+func (tv *TextView) vimJoin(ln, n int) {
+	tb := tv.Buf
+	tb.Undos.BatchStart()
+	defer tb.Undos.BatchEnd()
+	for i := 0; i < n && ln+1 < tb.NumLines(); i++ {
+		ll := tb.LineLen(ln)
+		nxt := tb.Line(ln + 1)
+		nb := vimFirstNonBlank(nxt)
+		sep := " "
+		if nb == len(nxt) || nxt[nb] == ')' || ll == 0 || unicode.IsSpace(tb.Line(ln)[ll-1]) {
+			sep = ""
+		}
+		tb.ReplaceText(lex.Pos{Ln: ln, Ch: ll}, lex.Pos{Ln: ln + 1, Ch: nb}, lex.Pos{Ln: ln, Ch: ll}, sep, EditSignal, false)
+		tv.vimSetCursor(lex.Pos{Ln: ln, Ch: ll})
+	}
+}
+
+// vimJoinNoSpace joins n lines after line ln to it, as they are
+func (tv *TextView) vimJoinNoSpace(ln, n int) {
+	tb := tv.Buf
+	tb.Undos.BatchStart()
+	defer tb.Undos.BatchEnd()
+	for i := 0; i < n && ln+1 < tb.NumLines(); i++ {
+		ll := tb.LineLen(ln)
+		tb.DeleteText(lex.Pos{Ln: ln, Ch: ll}, lex.Pos{Ln: ln + 1}, EditSignal)
+		tv.vimSetCursor(lex.Pos{Ln: ln, Ch: ll})
+	}
+}
+
+// vimPut puts the text of register r after the cursor, or before it, n times
+func (tv *TextView) vimPut(r rune, before bool, n int) {
+	tb := tv.Buf
+	rg := tv.vimGetReg(r)
+	if rg == nil || len(rg.Text) == 0 {
+		return
+	}
+	txt := bytes.Repeat(rg.Text, n)
+	cur := tv.CursorPos
+	tb.Undos.BatchStart()
+	defer tb.Undos.BatchEnd()
+	if rg.Lines {
+		if !bytes.HasSuffix(txt, []byte("\n")) {
+			txt = append(txt, '\n')
+		}
+		ln := cur.Ln
+		if !before {
+			ln++
+		}
+		if ln >= tb.NumLines() { // after the last line
+			ll := tb.LineLen(ln - 1)
+			tb.InsertText(lex.Pos{Ln: ln - 1, Ch: ll}, append([]byte("\n"), txt[:len(txt)-1]...), EditSignal)
+		} else {
+			tb.InsertText(lex.Pos{Ln: ln}, txt, EditSignal)
+		}
+		tv.vimSetCursor(lex.Pos{Ln: ln, Ch: vimFirstNonBlank(tb.Line(ln))})
+		return
+	}
+	pos := cur
+	if !before && tb.LineLen(cur.Ln) > 0 {
+		pos.Ch++
+	}
+	tbe := tb.InsertText(pos, txt, EditSignal)
+	if tbe == nil {
+		return
+	}
+	ed := tbe.Reg.End
+	if ed.Ch > 0 {
+		ed.Ch--
+	}
+	tv.vimSetCursor(ed)
+}
+
+// vimSetReg saves text in the register for the current command, and in the
+// unnamed register and the clipboard, as the result of a yank or a delete
+func (tv *TextView) vimSetReg(txt []byte, lines, yank bool) {
+	r := tv.Vim.Reg
+	if r == '_' { // black hole
+		return
+	}
+	rg := &VimRegister{Text: append([]byte(nil), txt...), Lines: lines}
+	switch {
+	case r >= 'A' && r <= 'Z':
+		lr := unicode.ToLower(r)
+		if old := VimRegisters[lr]; old != nil {
+			atxt := append([]byte(nil), old.Text...)
+			if lines && !old.Lines {
+				atxt = append(atxt, '\n')
+			}
+			rg = &VimRegister{Text: append(atxt, txt...), Lines: old.Lines || lines}
+		}
+		VimRegisters[lr] = rg
+	case r != 0 && r != '"':
+		VimRegisters[r] = rg
+	case yank:
+		VimRegisters['0'] = rg
+	case lines || bytes.ContainsRune(txt, '\n'):
+		for i := '9'; i > '1'; i-- {
+			VimRegisters[i] = VimRegisters[i-1]
+		}
+		VimRegisters['1'] = rg
+	default:
+		VimRegisters['-'] = rg
+	}
+	VimRegisters['"'] = rg
+	if r == 0 || r == '"' || r == '+' || r == '*' {
+		TextViewClipHistAdd(rg.Text)
+		tv.clipWrite(rg.Text)
+	}
+}
+
+// vimGetReg returns register r -- the unnamed register, and the + and *
+// registers, return the clipboard text if it was copied from elsewhere
+func (tv *TextView) vimGetReg(r rune) *VimRegister {
+	if r == 0 {
+		r = '"'
+	}
+	if r >= 'A' && r <= 'Z' {
+		r = unicode.ToLower(r)
+	}
+	rg := VimRegisters[r]
+	if r == '"' || r == '+' || r == '*' {
+		if cb := tv.clipRead(); len(cb) > 0 && (rg == nil || !bytes.Equal(cb, rg.Text)) {
+			return &VimRegister{Text: cb, Lines: cb[len(cb)-1] == '\n'}
+		}
+	}
+	return rg
+}
+
+// clipWrite writes text to the clipboard, if the view is in a window
+func (tv *TextView) clipWrite(txt []byte) {
+	win := tv.ParentWindow()
+	if oswin.TheApp == nil || win == nil {
+		return
+	}
+	oswin.TheApp.ClipBoard(win.OSWin).Write(mimedata.NewTextBytes(txt))
+}
+
+// clipRead returns the text on the clipboard, if the view is in a window
+func (tv *TextView) clipRead() []byte {
+	win := tv.ParentWindow()
+	if oswin.TheApp == nil || win == nil {
+		return nil
+	}
+	data := oswin.TheApp.ClipBoard(win.OSWin).Read([]string{filecat.TextPlain})
+	if data == nil {
+		return nil
+	}
+	return data.TypeData(filecat.TextPlain)
+}
+
+// VimRepeat repeats the last change n times, as for the . command
+func (tv *TextView) VimRepeat(n int) {
+	vs := &tv.Vim
+	if len(vs.lastChange) == 0 || vs.replaying {
+		return
+	}
+	vs.replaying = true
+	defer func() { vs.replaying = false }()
+	for i := 0; i < n; i++ {
+		for _, ke := range vs.lastChange {
+			kt := ke
+			kt.ClearProcessed()
+			if !tv.VimKeyInput(&kt) {
+				tv.KeyInput(&kt)
+			}
+		}
+	}
+}
+
+// vimCmdLineKey processes a key typed on the command line
+func (tv *TextView) vimCmdLineKey(kt *key.ChordEvent) {
+	vs := &tv.Vim
+	switch {
+	case kt.Code == key.CodeEscape || key.HasAnyModifierBits(kt.Modifiers, key.Control) && kt.Rune == '[':
+		vs.CmdLine = ""
+		vs.Mode = VimNormal
+	case kt.Code == key.CodeReturnEnter || kt.Code == key.CodeKeypadEnter:
+		cmd := vs.CmdLine
+		vs.CmdLine = ""
+		vs.Mode = VimNormal
+		wupdt := tv.TopUpdateStart()
+		tv.VimCommand(cmd)
+		tv.TopUpdateEnd(wupdt)
+	case kt.Code == key.CodeDeleteBackspace:
+		rs := []rune(vs.CmdLine)
+		vs.CmdLine = string(rs[:len(rs)-1])
+		if vs.CmdLine == "" {
+			vs.Mode = VimNormal
+		}
+	case kt.Rune > 0 && unicode.IsPrint(kt.Rune) && !key.HasAnyModifierBits(kt.Modifiers, key.Control, key.Meta):
+		vs.CmdLine += string(kt.Rune)
+	}
+	tv.RenderAllLines()
+}
+
+// VimCommand performs a command line: a : command, or a / or ? search.
+// The : commands are a line number, w [file], q, q!, wq, x, [range]d,
+// [range]y, [range]s/pattern/replacement/[gi], and noh, where range is
+// % for all lines, '<,'> for the visual selection, or line numbers,
+// . for the cursor line and $ for the last line, with + or - offsets.
+func (tv *TextView) VimCommand(cmd string) {
+	vs := &tv.Vim
+	tb := tv.Buf
+	if cmd == "" {
+		return
+	}
+	if cmd[0] == '/' || cmd[0] == '?' {
+		if pat := cmd[1:]; pat != "" {
+			vs.Search = pat
+		}
+		vs.searchBack = cmd[0] == '?'
+		if pos, _, ok := tv.vimMotion('n', tv.CursorPos); ok {
+			tv.vimSetCursor(pos)
+			tv.SetCursorCol(tv.CursorPos)
+			tv.SavePosHistory(tv.CursorPos)
+		}
+		return
+	}
+	cmd = strings.TrimPrefix(cmd, ":")
+	stln, edln, rest, hasRange := tv.vimCmdRange(cmd)
+	rest = strings.TrimSpace(rest)
+	if stln < 0 || edln >= tb.NumLines() || edln < stln {
+		vs.Msg = "Invalid range"
+		return
+	}
+	switch {
+	case rest == "":
+		if hasRange {
+			tv.vimSetCursor(lex.Pos{Ln: edln, Ch: vimFirstNonBlank(tb.Line(edln))})
+			tv.SavePosHistory(tv.CursorPos)
+		}
+	case rest == "w" || rest == "w!" || rest == "wq" || rest == "x":
+		quit := rest == "wq" || rest == "x"
+		if rest != "x" || tb.IsChanged() {
+			err := tb.SaveFunc(func(err error) { // after the user decides, if asked
+				if err != nil {
+					vs.Msg = err.Error()
+					return
+				}
+				vs.Msg = fmt.Sprintf("%q written", tb.Filename)
+				if quit {
+					tv.vimQuit()
+				}
+			})
+			if err == ErrSavePending {
+				vs.Msg = err.Error()
+			}
+			return
+		}
+		if quit {
+			tv.vimQuit()
+		}
+	case strings.HasPrefix(rest, "w "):
+		tb.SaveAs(gi.FileName(strings.TrimSpace(rest[2:])))
+		vs.Msg = fmt.Sprintf("%q written", tb.Filename)
+	case rest == "q" || rest == "q!":
+		if rest == "q" && tb.IsChanged() {
+			vs.Msg = "No write since last change (add ! to override)"
+			return
+		}
+		tv.vimQuit()
+	case rest == "d" || rest == "y":
+		op := rest
+		vs.Reg = 0
+		tv.vimOperate(op, lex.Pos{Ln: stln}, lex.Pos{Ln: edln}, true)
+	case rest[0] == 's' && len(rest) > 1 && !unicode.IsLetter(rune(rest[1])):
+		tv.vimSubstitute(stln, edln, rest[1:])
+	case rest == "noh" || rest == "nohlsearch":
+		tv.ClearHighlights()
+	default:
+		vs.Msg = "Not an editor command: " + rest
+	}
+}
+
+// vimQuit closes the window of the view, as for :q
+func (tv *TextView) vimQuit() {
+	if win := tv.ParentWindow(); win != nil {
+		win.CloseReq()
+	}
+}
+
+// vimCmdRange parses the line range at the start of a : command, returning
+// the lines and the rest of the command -- the cursor line if there is no
+// range, in which case hasRange is false
+func (tv *TextView) vimCmdRange(cmd string) (stln, edln int, rest string, hasRange bool) {
+	cur := tv.CursorPos.Ln
+	last := tv.Buf.NumLines() - 1
+	if strings.HasPrefix(cmd, "%") {
+		return 0, last, cmd[1:], true
+	}
+	if strings.HasPrefix(cmd, "'<,'>") {
+		st, ed, _ := tv.vimVisualRegion()
+		if ed.Ch == 0 && ed.Ln > st.Ln && tv.Vim.Mode != VimVisualLine {
+			ed.Ln--
+		}
+		return st.Ln, ed.Ln, cmd[5:], true
+	}
+	addr := func(s string) (int, string, bool) {
+		ln, has := cur, false
+		switch {
+		case strings.HasPrefix(s, "."):
+			s, has = s[1:], true
+		case strings.HasPrefix(s, "$"):
+			ln, s, has = last, s[1:], true
+		default:
+			i := 0
+			for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+				i++
+			}
+			if i > 0 {
+				n, _ := strconv.Atoi(s[:i])
+				ln, s, has = n-1, s[i:], true
+			}
+		}
+		for len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+			i := 1
+			for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+				i++
+			}
+			off := 1
+			if i > 1 {
+				off, _ = strconv.Atoi(s[1:i])
+			}
+			if s[0] == '-' {
+				off = -off
+			}
+			ln, s, has = ln+off, s[i:], true
+		}
+		return ln, s, has
+	}
+	stln, rest, hasRange = addr(cmd)
+	edln = stln
+	if hasRange && strings.HasPrefix(rest, ",") {
+		edln, rest, _ = addr(rest[1:])
+	}
+	if hasRange && rest == "" { // a line number alone goes to the line
+		stln = ints.MinInt(ints.MaxInt(stln, 0), last)
+		edln = ints.MinInt(ints.MaxInt(edln, 0), last)
+	}
+	return stln, edln, rest, hasRange
+}
+
+// vimSubstitute performs the :s command on lines stln to edln, for args
+// /pattern/replacement/flags, where the replacement uses & for the match
+// and \1 etc for submatches
+func (tv *TextView) vimSubstitute(stln, edln int, args string) {
+	vs := &tv.Vim
+	tb := tv.Buf
+	parts := vimSplitSubst(args)
+	if len(parts) < 2 {
+		vs.Msg = "Invalid substitute: " + args
+		return
+	}
+	pat, rep, flags := parts[0], parts[1], ""
+	if len(parts) > 2 {
+		flags = parts[2]
+	}
+	if pat == "" {
+		pat = vs.Search
+	} else {
+		vs.Search = pat
+	}
+	if strings.Contains(flags, "i") {
+		pat = "(?i)" + pat
+	}
+	re, err := vimRegexp(pat)
+	if err != nil {
+		vs.Msg = err.Error()
+		return
+	}
+	rep = vimReplacement(rep)
+	all := strings.Contains(flags, "g")
+	tb.Undos.BatchStart()
+	defer tb.Undos.BatchEnd()
+	nsub, nln, lastln := 0, 0, -1
+	for ln := stln; ln <= edln; ln++ {
+		line := string(tb.Line(ln))
+		idxs := re.FindAllStringSubmatchIndex(line, -1)
+		if len(idxs) == 0 {
+			continue
+		}
+		if !all {
+			idxs = idxs[:1]
+		}
+		var nl []byte
+		prv := 0
+		for _, idx := range idxs {
+			nl = append(nl, line[prv:idx[0]]...)
+			nl = re.ExpandString(nl, rep, line, idx)
+			prv = idx[1]
+		}
+		nl = append(nl, line[prv:]...)
+		nsub += len(idxs)
+		nln++
+		lastln = ln
+		tb.ReplaceText(lex.Pos{Ln: ln}, lex.Pos{Ln: ln, Ch: len(tb.Line(ln))}, lex.Pos{Ln: ln}, string(nl), EditSignal, false)
+		if nlines := bytes.Count(nl, []byte("\n")); nlines > 0 { // replaced with newlines
+			ln += nlines
+			edln += nlines
+		}
+	}
+	if nsub == 0 {
+		vs.Msg = "Pattern not found: " + parts[0]
+		return
+	}
+	if nln > 1 {
+		vs.Msg = fmt.Sprintf("%d substitutions on %d lines", nsub, nln)
+	}
+	tv.vimSetCursor(lex.Pos{Ln: lastln, Ch: vimFirstNonBlank(tb.Line(lastln))})
+}
+
+// vimSplitSubst splits the arguments of :s at the delimiter that starts
+// them, where a backslash escapes the delimiter
+func vimSplitSubst(args string) []string {
+	if args == "" {
+		return nil
+	}
+	dlm := args[0]
+	var parts []string
+	var cur []byte
+	for i := 1; i < len(args); i++ {
+		c := args[i]
+		switch {
+		case c == '\\' && i+1 < len(args) && args[i+1] == dlm:
+			cur = append(cur, dlm)
+			i++
+		case c == dlm:
+			parts = append(parts, string(cur))
+			cur = nil
+		default:
+			cur = append(cur, c)
+		}
+	}
+	return append(parts, string(cur))
+}
+
+// vimReplacement translates a vim replacement string into a Go regexp
+// template: & is the match, \0 - \9 are submatches, \n is a newline and
+// \& a literal &
+func vimReplacement(rep string) string {
+	var sb strings.Builder
+	for i := 0; i < len(rep); i++ {
+		c := rep[i]
+		switch {
+		case c == '\\' && i+1 < len(rep):
+			i++
+			switch n := rep[i]; {
+			case n >= '0' && n <= '9':
+				sb.WriteString("${" + string(n) + "}")
+			case n == 'n' || n == 'r':
+				sb.WriteByte('\n')
+			case n == '$':
+				sb.WriteString("$$")
+			default:
+				sb.WriteByte(n)
+			}
+		case c == '&':
+			sb.WriteString("${0}")
+		case c == '$':
+			sb.WriteString("$$")
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// vimRegexp compiles a search pattern, which is case insensitive if it is
+// all lower case
+func vimRegexp(pat string) (*regexp.Regexp, error) {
+	if pat == strings.ToLower(pat) && !strings.HasPrefix(pat, "(?") {
+		pat = "(?i)" + pat
+	}
+	return regexp.Compile(pat)
+}
+
+//////////////////////////////////////////////////////////////////////////
+//  Motions and text objects on the buffer
+
+// vimClass returns the class of a rune for word motions: 0 for space, 1 for
+// word characters, and 2 for other characters -- all non-space runes are
+// word characters for the big WORD motions
+func vimClass(r rune, big bool) int {
+	switch {
+	case unicode.IsSpace(r):
+		return 0
+	case big || unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+		return 1
+	}
+	return 2
+}
+
+// vimFirstNonBlank returns the index of the first non-space rune in line,
+// or its length if it is blank
+func vimFirstNonBlank(line []rune) int {
+	for i, r := range line {
+		if !unicode.IsSpace(r) {
+			return i
+		}
+	}
+	return len(line)
+}
+
+// vimNextWord returns the start of the next word after pos, as for w --
+// an empty line is also a word
+func vimNextWord(tb *TextBuf, pos lex.Pos, big bool) lex.Pos {
+	ln, ch := pos.Ln, pos.Ch
+	line := tb.Line(ln)
+	if ch < len(line) {
+		if c := vimClass(line[ch], big); c != 0 {
+			for ch < len(line) && vimClass(line[ch], big) == c {
+				ch++
+			}
+		}
+	}
+	for {
+		if ch >= len(line) {
+			if ln+1 >= tb.NumLines() {
+				return lex.Pos{Ln: ln, Ch: len(line)}
+			}
+			ln++
+			line = tb.Line(ln)
+			ch = 0
+			if len(line) == 0 {
+				return lex.Pos{Ln: ln}
+			}
+			continue
+		}
+		if vimClass(line[ch], big) != 0 {
+			return lex.Pos{Ln: ln, Ch: ch}
+		}
+		ch++
+	}
+}
+
+// vimEndWord returns the end of the word at pos, or of the next word if
+// pos is already at the end of a word (unless stay), as for e
+func vimEndWord(tb *TextBuf, pos lex.Pos, big, stay bool) lex.Pos {
+	ln, ch := pos.Ln, pos.Ch
+	line := tb.Line(ln)
+	if !stay {
+		ch++
+	}
+	for {
+		if ch >= len(line) {
+			if ln+1 >= tb.NumLines() {
+				return lex.Pos{Ln: ln, Ch: ints.MaxInt(len(line)-1, 0)}
+			}
+			ln++
+			line = tb.Line(ln)
+			ch = 0
+			continue
+		}
+		if vimClass(line[ch], big) != 0 {
+			break
+		}
+		ch++
+	}
+	c := vimClass(line[ch], big)
+	for ch+1 < len(line) && vimClass(line[ch+1], big) == c {
+		ch++
+	}
+	return lex.Pos{Ln: ln, Ch: ch}
+}
+
+// vimPrevWord returns the start of the word before pos, as for b
+func vimPrevWord(tb *TextBuf, pos lex.Pos, big bool) lex.Pos {
+	ln, ch := pos.Ln, pos.Ch-1
+	line := tb.Line(ln)
+	for {
+		if ch < 0 {
+			if ln == 0 {
+				return lex.Pos{}
+			}
+			ln--
+			line = tb.Line(ln)
+			if len(line) == 0 {
+				return lex.Pos{Ln: ln}
+			}
+			ch = len(line) - 1
+			continue
+		}
+		if ch < len(line) && vimClass(line[ch], big) != 0 {
+			break
+		}
+		ch--
+	}
+	c := vimClass(line[ch], big)
+	for ch > 0 && vimClass(line[ch-1], big) == c {
+		ch--
+	}
+	return lex.Pos{Ln: ln, Ch: ch}
+}
+
+// vimPrevEndWord returns the end of the word before pos, as for ge
+func vimPrevEndWord(tb *TextBuf, pos lex.Pos, big bool) lex.Pos {
+	ln, ch := pos.Ln, pos.Ch
+	line := tb.Line(ln)
+	if ch < len(line) {
+		if c := vimClass(line[ch], big); c != 0 {
+			for ch >= 0 && vimClass(line[ch], big) == c {
+				ch--
+			}
+		}
+	}
+	for {
+		if ch < 0 {
+			if ln == 0 {
+				return lex.Pos{}
+			}
+			ln--
+			line = tb.Line(ln)
+			ch = len(line) - 1
+			if len(line) == 0 {
+				return lex.Pos{Ln: ln}
+			}
+			continue
+		}
+		if ch < len(line) && vimClass(line[ch], big) != 0 {
+			return lex.Pos{Ln: ln, Ch: ch}
+		}
+		ch--
+	}
+}
+
+// vimParagraph returns the next empty line after pos, or the previous one
+// before it if back, or the end or start of the buffer if there is none
+func vimParagraph(tb *TextBuf, pos lex.Pos, back bool) lex.Pos {
+	ln := pos.Ln
+	nln := tb.NumLines()
+	// skip empty lines at the start
+	for ln >= 0 && ln < nln && tb.LineLen(ln) == 0 {
+		if back {
+			ln--
+		} else {
+			ln++
+		}
+	}
+	for ln >= 0 && ln < nln && tb.LineLen(ln) > 0 {
+		if back {
+			ln--
+		} else {
+			ln++
+		}
+	}
+	switch {
+	case ln < 0:
+		return lex.Pos{}
+	case ln >= nln:
+		return lex.Pos{Ln: nln - 1, Ch: tb.LineLen(nln - 1)}
+	}
+	return lex.Pos{Ln: ln}
+}
+
+// vimFindInLine returns the index of the nth occurrence of rune c in line
+// from ch, for the f F t T commands given as kind
+func vimFindInLine(line []rune, ch int, c rune, kind rune, n int) (int, bool) {
+	back := kind == 'F' || kind == 'T'
+	i := ch
+	if kind == 't' && i+1 < len(line) && line[i+1] == c || kind == 'T' && i > 0 && line[i-1] == c {
+		if back { // repeated t and T skip the adjacent match
+			i--
+		} else {
+			i++
+		}
+	}
+	for n > 0 {
+		if back {
+			i--
+		} else {
+			i++
+		}
+		if i < 0 || i >= len(line) {
+			return ch, false
+		}
+		if line[i] == c {
+			n--
+		}
+	}
+	switch kind {
+	case 't':
+		i--
+	case 'T':
+		i++
+	}
+	return i, true
+}
+
+// vimWordAt returns the start and end of the word at ch in line, for * and #
+func vimWordAt(line []rune, ch int) (st, ed int) {
+	for ch < len(line) && vimClass(line[ch], false) != 1 {
+		ch++
+	}
+	st, ed = ch, ch
+	for st > 0 && vimClass(line[st-1], false) == 1 {
+		st--
+	}
+	for ed < len(line) && vimClass(line[ed], false) == 1 {
+		ed++
+	}
+	return st, ed
+}
+
+// vimMatchBracket returns the position of the bracket matching the first
+// bracket at or after pos in its line, as for %
+func vimMatchBracket(tb *TextBuf, pos lex.Pos) (lex.Pos, bool) {
+	line := tb.Line(pos.Ln)
+	for ch := pos.Ch; ch < len(line); ch++ {
+		match, right := lex.BracePair(line[ch])
+		switch {
+		case match == 0:
+			continue
+		case right:
+			return vimEnclosingBracket(tb, lex.Pos{Ln: pos.Ln, Ch: ch}, match, line[ch])
+		}
+		return vimBracketForward(tb, lex.Pos{Ln: pos.Ln, Ch: ch}, line[ch], match)
+	}
+	return pos, false
+}
+
+// vimSearch returns the position of the next match of re after pos, or
+// before it if back, wrapping around the buffer
+func vimSearch(tb *TextBuf, pos lex.Pos, re *regexp.Regexp, back bool) (lex.Pos, bool) {
+	nln := tb.NumLines()
+	for i := 0; i <= nln; i++ {
+		ln := pos.Ln + i
+		if back {
+			ln = pos.Ln - i
+		}
+		ln = (ln%nln + nln) % nln
+		line := tb.Line(ln)
+		idxs := re.FindAllStringIndex(string(line), -1)
+		var found []int
+		for _, idx := range idxs {
+			ch := len([]rune(string(line)[:idx[0]]))
+			switch {
+			case i == 0 && !back && ch <= pos.Ch, i == 0 && back && ch >= pos.Ch:
+				continue
+			case i == nln && !back && ch > pos.Ch, i == nln && back && ch < pos.Ch:
+				continue
+			}
+			found = append(found, ch)
+			if !back {
+				break
+			}
+		}
+		if len(found) > 0 {
+			return lex.Pos{Ln: ln, Ch: found[len(found)-1]}, true
+		}
+	}
+	return pos, false
+}
+
+// vimTextObject returns the region of the text object at pos for the key r
+// following i (inner) or a (around): w W for words, quotes, brackets
+// (b for parentheses and B for braces) and p for paragraphs -- the end
+// is exclusive, and lines is true for whole lines
+func vimTextObject(tb *TextBuf, pos lex.Pos, around bool, r rune) (st, ed lex.Pos, lines, ok bool) {
+	line := tb.Line(pos.Ln)
+	switch r {
+	case 'w', 'W':
+		if len(line) == 0 {
+			return pos, pos, false, false
+		}
+		big := r == 'W'
+		ch := ints.MinInt(pos.Ch, len(line)-1)
+		c := vimClass(line[ch], big)
+		s, e := ch, ch
+		for s > 0 && vimClass(line[s-1], big) == c {
+			s--
+		}
+		for e < len(line) && vimClass(line[e], big) == c {
+			e++
+		}
+		if around {
+			if c == 0 { // on space: the space and the following word
+				if e < len(line) {
+					c2 := vimClass(line[e], big)
+					for e < len(line) && vimClass(line[e], big) == c2 {
+						e++
+					}
+				}
+			} else {
+				e2 := e
+				for e2 < len(line) && vimClass(line[e2], big) == 0 {
+					e2++
+				}
+				if e2 > e {
+					e = e2
+				} else {
+					for s > 0 && vimClass(line[s-1], big) == 0 {
+						s--
+					}
+				}
+			}
+		}
+		return lex.Pos{Ln: pos.Ln, Ch: s}, lex.Pos{Ln: pos.Ln, Ch: e}, false, true
+	case '"', '\'', '`':
+		s, e := -1, -1
+		for i := ints.MinInt(pos.Ch, len(line)-1); i >= 0; i-- {
+			if line[i] == r {
+				s = i
+				break
+			}
+		}
+		if s >= 0 {
+			n := strings.Count(string(line[:s]), string(r))
+			if n%2 == 1 && s == pos.Ch { // on a closing quote
+				for i := s - 1; i >= 0; i-- {
+					if line[i] == r {
+						e, s = s, i
+						break
+					}
+				}
+			}
+		}
+		if e < 0 {
+			for i := s + 1; i < len(line); i++ {
+				if line[i] == r {
+					e = i
+					break
+				}
+			}
+		}
+		if s < 0 || e < 0 {
+			return pos, pos, false, false
+		}
+		if around {
+			return lex.Pos{Ln: pos.Ln, Ch: s}, lex.Pos{Ln: pos.Ln, Ch: e + 1}, false, true
+		}
+		return lex.Pos{Ln: pos.Ln, Ch: s + 1}, lex.Pos{Ln: pos.Ln, Ch: e}, false, true
+	case 'p':
+		if tb.NumLines() == 0 {
+			return pos, pos, false, false
+		}
+		empty := tb.LineLen(pos.Ln) == 0
+		s, e := pos.Ln, pos.Ln
+		for s > 0 && (tb.LineLen(s-1) == 0) == empty {
+			s--
+		}
+		for e+1 < tb.NumLines() && (tb.LineLen(e+1) == 0) == empty {
+			e++
+		}
+		if around {
+			for e+1 < tb.NumLines() && (tb.LineLen(e+1) == 0) != empty {
+				e++
+			}
+		}
+		return lex.Pos{Ln: s}, lex.Pos{Ln: e}, true, true
+	}
+	open := map[rune]rune{'(': '(', ')': '(', 'b': '(', '[': '[', ']': '[', '{': '{', '}': '{', 'B': '{', '<': '<', '>': '<'}[r]
+	if open == 0 {
+		return pos, pos, false, false
+	}
+	close, _ := lex.BracePair(open)
+	if open == '<' {
+		close = '>'
+	}
+	s, ok := vimEnclosingBracket(tb, pos, open, close)
+	if !ok {
+		return pos, pos, false, false
+	}
+	e, ok := vimBracketForward(tb, s, open, close)
+	if !ok {
+		return pos, pos, false, false
+	}
+	if around {
+		return s, lex.Pos{Ln: e.Ln, Ch: e.Ch + 1}, false, true
+	}
+	s.Ch++
+	if s.Ch == tb.LineLen(s.Ln) && e.Ln > s.Ln { // inner block of lines
+		s = lex.Pos{Ln: s.Ln + 1}
+		if vimFirstNonBlank(tb.Line(e.Ln)) == e.Ch {
+			e = lex.Pos{Ln: e.Ln}
+		}
+	}
+	return s, e, false, true
+}
+
+// vimEnclosingBracket returns the position of the open bracket that
+// encloses pos, or is at pos
+func vimEnclosingBracket(tb *TextBuf, pos lex.Pos, open, close rune) (lex.Pos, bool) {
+	depth := 0
+	for ln := pos.Ln; ln >= 0; ln-- {
+		line := tb.Line(ln)
+		ch := len(line) - 1
+		if ln == pos.Ln {
+			ch = ints.MinInt(pos.Ch, len(line)-1)
+			if ch >= 0 && line[ch] == close {
+				ch-- // on the closing bracket itself
+			}
+		}
+		for ; ch >= 0; ch-- {
+			switch line[ch] {
+			case close:
+				depth--
+			case open:
+				if depth == 0 {
+					return lex.Pos{Ln: ln, Ch: ch}, true
+				}
+				depth++
+			}
+		}
+	}
+	return pos, false
+}
+
+// vimBracketForward returns the position of the close bracket matching the
+// open bracket at pos
+func vimBracketForward(tb *TextBuf, pos lex.Pos, open, close rune) (lex.Pos, bool) {
+	depth := 0
+	for ln := pos.Ln; ln < tb.NumLines(); ln++ {
+		line := tb.Line(ln)
+		ch := 0
+		if ln == pos.Ln {
+			ch = pos.Ch + 1
+		}
+		for ; ch < len(line); ch++ {
+			switch line[ch] {
+			case open:
+				depth++
+			case close:
+				if depth == 0 {
+					return lex.Pos{Ln: ln, Ch: ch}, true
+				}
+				depth--
+			}
+		}
+	}
+	return pos, false
+}
+
+// vimLinesText returns the text of lines stln to edln, each ending in a newline
+func vimLinesText(tb *TextBuf, stln, edln int) []byte {
+	var b []byte
+	for ln := stln; ln <= edln; ln++ {
+		b = append(b, []byte(string(tb.Line(ln)))...)
+		b = append(b, '\n')
+	}
+	return b
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2020, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package giv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goki/gi/oswin/key"
+	"github.com/goki/pi/lex"
+)
+
+// newVimTestView returns a TextView in Normal mode on a buffer with text
+func newVimTestView(txt string) *TextView {
+	tb := newTestTextBuf()
+	tb.SetText([]byte(txt))
+	tv := &TextView{}
+	tv.InitName(tv, "text-view")
+	tv.SetBuf(tb)
+	tv.NLines = tb.NumLines() // normally set by layout
+	return tv
+}
+
+// vimKeys sends keys to the view: each rune is a key, except that
+// <esc> and <cr> are Escape and Enter
+func vimKeys(tv *TextView, keys string) {
+	keys = strings.NewReplacer("<esc>", "\x1b", "<cr>", "\r").Replace(keys)
+	for _, r := range keys {
+		kt := &key.ChordEvent{}
+		switch r {
+		case 0x1b:
+			kt.Code = key.CodeEscape
+		case '\r':
+			kt.Code = key.CodeReturnEnter
+		default:
+			kt.Rune = r
+		}
+		if !tv.VimKeyInput(kt) && tv.Vim.Mode == VimInsert { // regular KeyInput needs a window
+			tv.InsertAtCursor([]byte(string(r)))
+		}
+	}
+}
+
+func vimText(tv *TextView) string {
+	return strings.Join(tv.Buf.Strings(false), "\n")
+}
+
+func TestVimEditing(t *testing.T) {
+	tests := []struct {
+		text, keys, exp string
+		pos             lex.Pos
+	}{
+		{"one two three", "dw", "two three", lex.Pos{}},
+		{"one two three", "w2dw", "one ", lex.Pos{Ch: 3}},
+		{"one two\nthree", "wdw", "one \nthree", lex.Pos{Ch: 3}},
+		{"one two three", "wcwxx<esc>", "one xx three", lex.Pos{Ch: 5}},
+		{"one two three", "$de", "one two thre", lex.Pos{Ch: 11}},
+		{"a\nb\nc\nd", "j2ddp", "a\nd\nb\nc", lex.Pos{Ln: 2}},
+		{"a\nb\nc", "yyjP", "a\na\nb\nc", lex.Pos{Ln: 1}},
+		{"abcd", "x.", "cd", lex.Pos{}},
+		{"one two three four", "dw..", "four", lex.Pos{}},
+		{"foo(a, b) bar", "fadt)", "foo() bar", lex.Pos{Ch: 4}},
+		{"foo(a, (b)) bar", "fbdi(", "foo(a, ()) bar", lex.Pos{Ch: 8}},
+		{"foo(a, (b)) bar", "fada(", "foo bar", lex.Pos{Ch: 3}},
+		{`say "hello there" now`, `fhci"bye<esc>`, `say "bye" now`, lex.Pos{Ch: 7}},
+		{"one two three", "wdiw", "one  three", lex.Pos{Ch: 4}},
+		{"one two three", "wdaw", "one three", lex.Pos{Ch: 4}},
+		{"a\nb\nc", "JJ", "a b c", lex.Pos{Ch: 3}},
+		{"a\n  b", "J", "a b", lex.Pos{Ch: 1}},
+		{"one two", "~~~", "ONE two", lex.Pos{Ch: 3}},
+		{"one two", "gUiw", "ONE two", lex.Pos{}},
+		{"a\nb\nc", "jVjd", "a", lex.Pos{}},
+		{"one two three", "wvlld", "one  three", lex.Pos{Ch: 4}},
+		{"one two", "yiwwviwp", "one one", lex.Pos{Ch: 6}},
+		{"one\ntwo", "yyjVp", "one\none", lex.Pos{Ln: 1}},
+		{"one two", "rx", "xne two", lex.Pos{}},
+		{"one\ntwo", `"ayyj"ap`, "one\ntwo\none", lex.Pos{Ln: 2}},
+		{"one\ntwo", `"ayyj"Ayyk"ap`, "one\none\ntwo\ntwo", lex.Pos{Ln: 1}},
+		{"one", "ox<esc>", "one\nx", lex.Pos{Ln: 1}},
+		{"one\ntwo\nthree", "Gdgg", "", lex.Pos{}},
+		{"x = (a\n  b)", "f(%x", "x = (a\n  b", lex.Pos{Ln: 1, Ch: 2}},
+		{"one\n\ntwo\nthree", "jj}dd", "one\n\ntwo", lex.Pos{Ln: 2}},
+		{"one two one", "*D", "one two ", lex.Pos{Ch: 7}},
+		{"one two", "dwu", "one two", lex.Pos{}},
+		{"a b a b", ":s/a/c/<cr>", "c b a b", lex.Pos{}},
+		{"a b\na b", ":%s/a/(&)/g<cr>", "(a) b\n(a) b", lex.Pos{Ln: 1}},
+		{"ab ab", `:s/(a)b/\1c/g<cr>`, "ac ac", lex.Pos{}},
+		{"one\ntwo\nthree", ":2,3d<cr>", "one", lex.Pos{}},
+		{"a\nfoo\nb\nfoo", "/foo<cr>n", "a\nfoo\nb\nfoo", lex.Pos{Ln: 3}},
+		{"a\n\tb", ">>j<<", "\ta\nb", lex.Pos{Ln: 1}},
+	}
+	for _, ts := range tests {
+		tv := newVimTestView(ts.text)
+		vimKeys(tv, ts.keys)
+		if got := vimText(tv); got != ts.exp {
+			t.Errorf("%q on %q: %q, expected: %q", ts.keys, ts.text, got, ts.exp)
+		}
+		if tv.CursorPos != ts.pos {
+			t.Errorf("%q on %q: cursor %v, expected: %v", ts.keys, ts.text, tv.CursorPos, ts.pos)
+		}
+	}
+}
+
+func TestVimModes(t *testing.T) {
+	tv := newVimTestView("one two")
+	vimKeys(tv, "2d")
+	if st := tv.VimStatus(); st != "2d" {
+		t.Errorf("pending status: %q", st)
+	}
+	vimKeys(tv, "<esc>v")
+	if tv.Vim.Mode != VimVisual || tv.VimStatus() != "-- VISUAL --" {
+		t.Errorf("visual mode: %v", tv.Vim.Mode)
+	}
+	vimKeys(tv, "e")
+	if got := string(tv.Selection().ToBytes()); got != "one" {
+		t.Errorf("visual selection: %q", got)
+	}
+	vimKeys(tv, "y")
+	if tv.Vim.Mode != VimNormal || tv.HasSelection() || string(VimRegisters['0'].Text) != "one" {
+		t.Errorf("yanked: %v %q", tv.Vim.Mode, VimRegisters['0'].Text)
+	}
+	vimKeys(tv, ":q")
+	if tv.Vim.Mode != VimCommand || tv.VimStatus() != ":q" {
+		t.Errorf("command mode: %v %q", tv.Vim.Mode, tv.VimStatus())
+	}
+	vimKeys(tv, "<esc>i")
+	if tv.Vim.Mode != VimInsert || tv.VimStatus() != "-- INSERT --" {
+		t.Errorf("insert mode: %v", tv.Vim.Mode)
+	}
+}
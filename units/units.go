@@ -129,6 +129,18 @@ var UnitNames = [...]string{
 	Dot:  "dot",
 }
 
+// UnitFromName returns the Unit for given UnitNames name (case insensitive),
+// and false if it is not a unit name
+func UnitFromName(nm string) (Unit, bool) {
+	nm = strings.ToLower(nm)
+	for i, un := range UnitNames {
+		if un == nm {
+			return Unit(i), true
+		}
+	}
+	return Px, false
+}
+
 // Context specifies everything about the current context necessary for converting the number
 // into specific display-dependent pixels
 type Context struct {